@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// runCheckConfig implements "minerhq check-config": it loads the config the same
+// way the server does (defaults + file + env), validates it and prints the result.
+func runCheckConfig(args []string) int {
+	fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "path to config file")
+	quiet := fs.Bool("quiet", false, "only report errors, don't print the effective config")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Failed to load config %s: %v\n", *configPath, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Config file not found at %s, checking defaults\n", *configPath)
+		cfg = config.DefaultConfig()
+	}
+
+	failed := false
+	if err := cfg.ApplyEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Environment overrides are invalid:\n%v\n", err)
+		failed = true
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config is invalid:\n%v\n", err)
+		failed = true
+	}
+
+	if !*quiet {
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode config: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	}
+
+	if failed {
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "Config OK")
+	return 0
+}
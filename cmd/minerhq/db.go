@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+const dbUsage = `Usage: minerhq db [-config path] [-db path] <command> [options]
+
+Offline database maintenance. Stop the running MinerHQ instance first.
+
+Commands:
+  vacuum            Compact the database file and reclaim disk space
+  migrate           Apply schema migrations and exit
+  purge -days N     Delete snapshots, shares, pool difficulty changes, hourly
+                    rollups and failed alert deliveries older than N days
+                    (default 30); -dry-run reports what would be deleted
+                    without deleting
+  stats             Print row counts, sizes and daily growth per table
+  integrity-check   Run SQLite's integrity check
+`
+
+// runDB implements the "minerhq db" subcommands and returns the process exit code
+func runDB(args []string) int {
+	fs := flag.NewFlagSet("db", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "path to config file")
+	dbOverride := fs.String("db", "", "path to database file (overrides config)")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, dbUsage) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	dbPath := *dbOverride
+	if dbPath == "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+				return 1
+			}
+			cfg = config.DefaultConfig()
+		}
+		if err := cfg.ApplyEnv(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid environment override: %v\n", err)
+			return 1
+		}
+		dbPath = resolveDBPath(cfg)
+	}
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+
+	// Refuse to create an empty database for anything but migrate
+	if cmd != "migrate" {
+		if _, err := os.Stat(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Database not found at %s: %v\n", dbPath, err)
+			return 1
+		}
+	}
+
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch cmd {
+	case "vacuum":
+		before := fileSize(dbPath)
+		start := time.Now()
+		if err := store.Vacuum(); err != nil {
+			fmt.Fprintf(os.Stderr, "Vacuum failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Vacuum completed in %v (%s -> %s)\n", time.Since(start).Round(time.Millisecond), units.FormatBytes(before), units.FormatBytes(fileSize(dbPath)))
+
+	case "migrate":
+		fmt.Printf("Schema is up to date (%s)\n", dbPath)
+
+	case "purge":
+		purgeFlags := flag.NewFlagSet("purge", flag.ContinueOnError)
+		days := purgeFlags.Int("days", 30, "delete data older than this many days")
+		dryRun := purgeFlags.Bool("dry-run", false, "report what would be deleted without deleting")
+		if err := purgeFlags.Parse(cmdArgs); err != nil {
+			return 2
+		}
+		if *days <= 0 {
+			fmt.Fprintln(os.Stderr, "-days must be greater than 0")
+			return 2
+		}
+		if *dryRun {
+			estimates, err := store.PreviewPurgeOldData(*days)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Purge preview failed: %v\n", err)
+				return 1
+			}
+			for _, e := range estimates {
+				fmt.Printf("  %-20s %12d rows  ~%s\n", e.Table, e.Rows, units.FormatBytes(e.Bytes))
+			}
+			fmt.Printf("Dry run: nothing deleted (data older than %d days)\n", *days)
+			return 0
+		}
+		purged, err := store.PurgeOldData(*days)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Purge failed: %v\n", err)
+			return 1
+		}
+		// Nothing else uses the database, so a full vacuum can't get in the way
+		if _, err := store.Compact(storage.VacuumFull); err != nil {
+			fmt.Fprintf(os.Stderr, "Vacuum failed: %v\n", err)
+			return 1
+		}
+		for _, t := range purged {
+			fmt.Printf("  %-20s %12d rows\n", t.Name, t.Rows)
+		}
+		fmt.Printf("Purged data older than %d days\n", *days)
+
+	case "stats":
+		usage, err := store.GetTableUsage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read stats: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Database: %s (%s)\n", dbPath, units.FormatBytes(fileSize(dbPath)))
+		for _, t := range usage {
+			fmt.Printf("  %-20s %12d rows  %10s  +%d rows/day\n", t.Name, t.Rows, units.FormatBytes(t.Bytes), t.RowsPerDay)
+		}
+
+	case "integrity-check":
+		problems, err := store.IntegrityCheck()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Integrity check failed: %v\n", err)
+			return 1
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			return 1
+		}
+		fmt.Println("ok")
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown db command: %s\n\n", cmd)
+		fs.Usage()
+		return 2
+	}
+
+	return 0
+}
+
+// fileSize returns the size of a file in bytes, or 0 if it can't be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -4,30 +4,60 @@ import (
 	"context"
 	"flag"
 	"log"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/camarigor/miner-hq/internal/agent"
 	"github.com/camarigor/miner-hq/internal/alerts"
 	"github.com/camarigor/miner-hq/internal/api"
 	"github.com/camarigor/miner-hq/internal/collector"
 	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/demo"
+	"github.com/camarigor/miner-hq/internal/export"
+	"github.com/camarigor/miner-hq/internal/mqtt"
+	"github.com/camarigor/miner-hq/internal/plugs"
+	"github.com/camarigor/miner-hq/internal/poolcheck"
+	"github.com/camarigor/miner-hq/internal/poolstats"
 	"github.com/camarigor/miner-hq/internal/pricing"
+	"github.com/camarigor/miner-hq/internal/retention"
+	"github.com/camarigor/miner-hq/internal/scheduler"
 	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/stratumproxy"
+	"github.com/camarigor/miner-hq/internal/tsdb"
+	"github.com/camarigor/miner-hq/internal/units"
+	"github.com/camarigor/miner-hq/internal/watchdog"
 )
 
 func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "db":
+			os.Exit(runDB(os.Args[2:]))
+		case "check-config":
+			os.Exit(runCheckConfig(os.Args[2:]))
+		}
+	}
+
 	// Parse flags
 	configPath := flag.String("config", "config.json", "path to config file")
+	demoMode := flag.Bool("demo", false, "run with simulated miners instead of real hardware")
+	demoMiners := flag.Int("demo-miners", 5, "number of simulated miners in demo mode")
+	readOnly := flag.Bool("read-only", false, "disable all mutating API endpoints")
+	webDir := flag.String("web-dir", os.Getenv("MINERHQ_WEB_DIR"), "serve the web UI from this directory instead of the copy built into the binary (for development)")
 	flag.Parse()
 
 	log.Println("MinerHQ starting...")
 
-	// Ensure /data directory exists for persistence
-	if err := os.MkdirAll("/data", 0755); err != nil {
-		log.Printf("Warning: could not create /data directory: %v", err)
+	// Ensure the config directory (/data in Docker) exists for persistence
+	if err := os.MkdirAll(filepath.Dir(*configPath), 0755); err != nil {
+		log.Printf("Warning: could not create config directory: %v", err)
 	}
 
 	// Load config (use defaults if file doesn't exist)
@@ -36,6 +66,9 @@ func main() {
 		if os.IsNotExist(err) {
 			log.Printf("Config file not found at %s, using defaults", *configPath)
 			cfg = config.DefaultConfig()
+			// Keep the database next to the config, so a binary started
+			// outside Docker doesn't need /data
+			cfg.DBPath = filepath.Join(filepath.Dir(*configPath), "minerhq.db")
 			// Save default config so it persists
 			if saveErr := cfg.Save(*configPath); saveErr != nil {
 				log.Printf("Warning: could not save default config: %v", saveErr)
@@ -45,10 +78,41 @@ func main() {
 		}
 	}
 
+	// Hash any plaintext passwords and API tokens so they don't stay in the config file
+	if changed, err := api.HashConfigPasswords(cfg); err != nil {
+		log.Fatalf("Failed to hash user passwords: %v", err)
+	} else if changed {
+		if err := cfg.Save(*configPath); err != nil {
+			log.Printf("Warning: could not save hashed passwords: %v", err)
+		} else {
+			log.Println("Plaintext user passwords and API tokens hashed and saved to config")
+		}
+	}
+
+	// Settings changed through the API or reloaded on SIGHUP replace cfg;
+	// long-running jobs read the current settings from the manager.
+	// Environment variables and -read-only override file values for this run
+	// only, so they are never saved to the file.
+	settings := config.NewManager(*configPath, cfg)
+	if err := settings.SetOverlay(runtimeOverrides(*readOnly)); err != nil {
+		log.Fatalf("Invalid environment override: %v", err)
+	}
+	cfg = settings.Get()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Config has invalid values:\n%v", err)
+	}
+	if cfg.Server.ReadOnly {
+		log.Println("Read-only mode: mutating API endpoints are disabled")
+	}
+	if cfg.Auth.Enabled {
+		log.Printf("Authentication enabled (%d users, %d API tokens)", len(cfg.Auth.Users), len(cfg.Auth.Tokens))
+	}
+
 	// Determine database path and ensure parent directory exists
-	dbPath := cfg.DBPath
-	if dbPath == "" {
-		dbPath = "minerhq.db"
+	dbPath := resolveDBPath(cfg)
+	if *demoMode {
+		// Keep simulated data out of the real database
+		dbPath = filepath.Join(filepath.Dir(dbPath), "minerhq-demo.db")
 	}
 
 	// Ensure parent directory exists for database file
@@ -67,38 +131,127 @@ func main() {
 	defer store.Close()
 	log.Printf("Database initialized at %s", dbPath)
 
-	// Vacuum database on startup to reclaim space from previous purges
-	if err := store.Vacuum(); err != nil {
-		log.Printf("Warning: database vacuum failed: %v", err)
-	} else {
-		log.Println("Database vacuumed successfully")
+	// Reclaim space from previous purges. Incremental vacuum needs a full
+	// VACUUM once to convert a database created by an older version.
+	switch cfg.Retention.Vacuum {
+	case storage.VacuumFull:
+		compactDatabase(store, cfg.Retention.Vacuum, true)
+	case storage.VacuumOff:
+	default:
+		if converted, err := store.EnableIncrementalVacuum(); err != nil {
+			log.Printf("Warning: could not enable incremental vacuum: %v", err)
+		} else if converted {
+			log.Println("Database converted to incremental vacuum")
+		}
+		compactDatabase(store, cfg.Retention.Vacuum, false)
 	}
 
+	// Seed hall of fame records from existing data before any purge runs
+	if err := store.BackfillRecords(); err != nil {
+		log.Printf("Warning: records backfill failed: %v", err)
+	}
+
+	// Store the last competition period's standings if the server was down
+	// when it ended
+	finalizeCompetition(store, cfg, cfg.Competition.CompetitionPeriod().Start(time.Now()))
+
 	// Initialize pricing service
 	priceSvc := pricing.NewPriceService()
+	configurePricing(priceSvc, cfg)
+	// Keep a price history, refreshed every 15 minutes
+	priceSvc.SetRecorder(store)
+	priceSvc.StartPriceUpdater(15 * time.Minute)
 	// Start block reward updater (once per day)
 	priceSvc.StartBlockRewardUpdater(24 * time.Hour)
-	log.Println("Pricing service started (per-miner coins, on-demand price fetching)")
+	// Exchange rates reconcile energy costs and USD coin prices into the
+	// display currency, once either isn't USD
+	var exchangeRates sync.Once
+	startExchangeRates := func(cfg *config.Config) {
+		if !strings.EqualFold(cfg.Pricing.FiatCurrency, "USD") || !strings.EqualFold(cfg.Energy.Currency, "USD") {
+			exchangeRates.Do(func() { priceSvc.StartExchangeRateUpdater(6 * time.Hour) })
+		}
+	}
+	startExchangeRates(cfg)
+	log.Println("Pricing service started (per-miner coins, prices refreshed every 15 minutes)")
 
 	// Initialize alert engine
-	alertConfig := &alerts.AlertConfig{
-		WebhookURL:          cfg.Alerts.WebhookURL,
-		MinerOfflineSeconds: cfg.Alerts.OfflineMinutes * 60,
-		TempAbove:           cfg.Alerts.TempThresholdC,
-		HashrateDropPercent: cfg.Alerts.HashrateDropPct,
-		FanRPMBelow:         cfg.Alerts.FanRPMBelow,
-		WifiSignalBelow:     cfg.Alerts.WifiSignalBelow,
-		OnShareRejected:     cfg.Alerts.OnShareRejected,
-		OnPoolDisconnected:  cfg.Alerts.OnPoolDisconnected,
-		OnNewBestDiff:       cfg.Alerts.OnNewBestDiff,
-		OnBlockFound:        cfg.Alerts.OnBlockFound,
-		OnNewLeader:         cfg.Alerts.OnNewLeader,
-	}
-	alertEngine := alerts.NewAlertEngine(alertConfig)
+	alertEngine := alerts.NewAlertEngine(alerts.ConfigFrom(cfg))
+	alertEngine.SetStore(store)
+	if overrides, err := store.GetAlertOverrides(); err != nil {
+		log.Printf("Failed to load per-miner alert overrides: %v", err)
+	} else {
+		alertEngine.SetOverrides(overrides)
+	}
+	if names, err := store.GetDisplayNames(); err != nil {
+		log.Printf("Failed to load miner display names: %v", err)
+	} else {
+		alertEngine.SetDisplayNames(names)
+	}
+	if windows, err := store.GetMaintenanceWindows(); err != nil {
+		log.Printf("Failed to load maintenance windows: %v", err)
+	} else {
+		alertEngine.SetMaintenance(windows)
+	}
 	log.Println("Alert engine initialized")
 
 	// Initialize collector (with pricing service for block value tracking)
 	coll := collector.NewCollector(store, priceSvc)
+	coll.SetEnergyRate(func(location string, at time.Time) float64 {
+		return settings.Get().Energy.RateAt(location, at)
+	})
+	configureCollector(coll, nil, cfg)
+	// Confirm found blocks on their coin's block explorer
+	coll.StartBlockVerifier(10 * time.Minute)
+	// Record miners going online and offline for uptime reports
+	coll.StartUptimeTracker(time.Minute)
+	// Read wall power from the miners' smart plugs. The plugs are read from
+	// the settings on every poll, so plugs added in Settings are picked up.
+	plugMgr := plugs.NewManager(settings.Get)
+	plugMgr.Start()
+	coll.SetPlugPower(plugMgr.Power)
+
+	// Relay the miners pointed at MinerHQ to their pools, recording their
+	// shares from the stratum traffic instead of their logs
+	var proxy *stratumproxy.Proxy
+	if cfg.StratumProxy.Enabled {
+		if p, err := stratumproxy.New(cfg.StratumProxy, coll.ProxyShare); err != nil {
+			log.Printf("Stratum proxy not started: %v", err)
+		} else if err := p.Start(); err != nil {
+			log.Printf("Stratum proxy not started: %v", err)
+		} else {
+			proxy = p
+			coll.SetProxied(proxy.Proxied)
+		}
+	}
+
+	// Alert state is kept by IP; a miner that moved starts afresh at its new
+	// address, with its overrides, display name and maintenance windows
+	coll.SetOnMinerMoved(func(oldIP, newIP string) {
+		alertEngine.MinerMoved(oldIP)
+	})
+
+	// In demo mode, start simulated miners and register them like scanned devices
+	if *demoMode {
+		sim := demo.NewSimulator(*demoMiners)
+		addrs, err := sim.Start()
+		if err != nil {
+			log.Fatalf("Failed to start demo miners: %v", err)
+		}
+		defer sim.Stop()
+
+		// Previous demo runs listened on different ports; retire them
+		if existing, err := store.GetMiners(); err == nil {
+			for _, m := range existing {
+				store.RemoveMiner(m.IP)
+			}
+		}
+		for _, addr := range addrs {
+			if err := store.UpsertMiner(&storage.Miner{IP: addr, Hostname: addr, Enabled: true, LastSeen: time.Now()}); err != nil {
+				log.Printf("Warning: could not register demo miner %s: %v", addr, err)
+			}
+		}
+		log.Printf("Demo mode: %d simulated miners running", len(addrs))
+	}
 
 	// Load existing miners and start collecting
 	miners, err := store.GetMiners()
@@ -110,91 +263,217 @@ func main() {
 		minerList := make([]storage.Miner, len(miners))
 		for i, m := range miners {
 			minerList[i] = *m
+			// Fall back to the energy location from the config's miners list
+			if minerList[i].Location == "" {
+				for _, mc := range cfg.Miners {
+					if mc.IP == m.IP {
+						minerList[i].Location = mc.Location
+						break
+					}
+				}
+			}
 		}
 		coll.Start(minerList)
 	}
 
-	// Start data retention cleanup (daily)
+	// Rescan the network for new miners while the scanner is enabled
+	go backgroundScan(settings, store, coll)
+
+	// Check fleet firmware consistency (hourly)
 	go func() {
-		ticker := time.NewTicker(24 * time.Hour)
+		ticker := time.NewTicker(1 * time.Hour)
 		defer ticker.Stop()
 		for range ticker.C {
-			days := cfg.Retention.MetricsRetentionDays
-			if days <= 0 {
-				days = 30
-			}
-			if err := store.PurgeOldData(days); err != nil {
-				log.Printf("Data purge error: %v", err)
-			} else {
-				log.Printf("Purged data older than %d days", days)
-			}
-			// Vacuum to reclaim disk space
-			if err := store.Vacuum(); err != nil {
-				log.Printf("Daily vacuum error: %v", err)
-			} else {
-				log.Println("Daily vacuum completed")
+			miners, err := store.GetMiners()
+			if err != nil {
+				log.Printf("Firmware check error: %v", err)
+				continue
 			}
+			alertEngine.CheckFirmware(collector.CheckFirmwareConsistency(miners))
 		}
 	}()
 
-	// Start hourly snapshot purge (keep only last hour for real-time display)
-	go func() {
-		// Run immediately on startup
-		deletedSnaps, err := store.PurgeOldSnapshots(1)
-		if err != nil {
-			log.Printf("Snapshot purge error: %v", err)
-		} else if deletedSnaps > 0 {
-			log.Printf("Purged %d snapshots older than 1 hour", deletedSnaps)
+	// Start scheduled daily exports
+	var exporter *export.Exporter
+	if cfg.Export.Enabled {
+		exporter = export.NewExporter(store, cfg.Export, cfg.Display.Units())
+		exporter.Start()
+		log.Printf("Daily exports enabled (%s at %s)", cfg.Export.Directory, cfg.Export.Time)
+	}
+	// captureSnapshots stages snapshot rollups for export before they are purged.
+	// The cutoff is a minute inside the retained hour so nothing slips between
+	// capture and purge; captured snapshots are never captured twice.
+	captureSnapshots := func() {
+		if exporter == nil {
+			return
+		}
+		if err := exporter.CaptureSnapshots(time.Now().Add(-time.Hour + time.Minute)); err != nil {
+			log.Printf("Export snapshot capture error: %v", err)
 		}
+	}
 
-		// Then run every hour
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			deletedSnaps, err := store.PurgeOldSnapshots(1)
-			if err != nil {
-				log.Printf("Snapshot purge error: %v", err)
-			} else {
-				log.Printf("Hourly purge: removed %d old snapshots", deletedSnaps)
-			}
+	// Purge each kind of data on its own schedule. Snapshots are rolled up
+	// first so long-term charts keep them, and staged for export.
+	retentionMgr := retention.NewManager(store, settings.Get, func(allowFull bool) {
+		compactDatabase(store, settings.Get().Retention.Vacuum, allowFull)
+	}, retention.Policies(func(cutoff time.Time) {
+		if err := store.UpdateRollups(time.Now()); err != nil {
+			log.Printf("Snapshot rollup error: %v", err)
 		}
-	}()
+		if err := store.UpdateLuck(time.Now()); err != nil {
+			log.Printf("Luck update error: %v", err)
+		}
+		if !settings.Get().Retention.DryRun {
+			captureSnapshots()
+		}
+	}))
+	retentionMgr.Start()
+
+	// Initialize and start HTTP server
+	server := api.NewServer(settings, store, coll, priceSvc, alertEngine)
+	if *webDir != "" {
+		server.SetWebDir(*webDir)
+		log.Printf("Serving the web UI from %s", *webDir)
+	}
+	server.SetRetention(retentionMgr)
 
-	// Start weekly share purge (Sunday at midnight) to preserve weekly best share history
+	// Store the final standings at the end of each competition period
+	// (weekly on Sunday at midnight by default) and send its digest. A
+	// change of period reschedules it.
+	competitionChanged := make(chan struct{}, 1)
 	go func() {
 		for {
+			period := settings.Get().Competition.CompetitionPeriod()
 			now := time.Now()
-			// Calculate next Sunday at midnight
-			daysUntilSunday := (7 - int(now.Weekday())) % 7
-			if daysUntilSunday == 0 && now.Hour() >= 0 {
-				// If it's already Sunday past midnight, wait until next Sunday
-				daysUntilSunday = 7
-			}
-			nextSunday := time.Date(now.Year(), now.Month(), now.Day()+daysUntilSunday, 0, 0, 0, 0, now.Location())
-			waitDuration := nextSunday.Sub(now)
+			next := period.End(now)
+			waitDuration := next.Sub(now)
 
-			log.Printf("Weekly share purge scheduled for %s (in %v)", nextSunday.Format("2006-01-02 15:04:05"), waitDuration.Round(time.Minute))
+			log.Printf("Competition ends %s (in %v)", next.Format("2006-01-02 15:04:05"), waitDuration.Round(time.Minute))
 
-			time.Sleep(waitDuration)
+			timer := time.NewTimer(waitDuration)
+			select {
+			case <-timer.C:
+			case <-competitionChanged:
+				timer.Stop()
+				continue
+			}
 
-			// Purge shares older than 8 days (keeps 7 full days visible in the UI)
-			deleted, err := store.PurgeOldShares(192) // 192 hours = 8 days
-			if err != nil {
-				log.Printf("Weekly share purge error: %v", err)
-			} else {
-				log.Printf("Weekly purge: removed %d old shares", deleted)
+			// The retention manager keeps the period's shares until the next
+			// one ends
+			finalizeCompetition(store, settings.Get(), next)
+			server.SendDigest(period, next)
+		}
+	}()
+
+	// Apply settings changes to the running components
+	settings.Subscribe(func(old, cur *config.Config) {
+		alertEngine.UpdateConfig(alerts.ConfigFrom(cur))
+		configureCollector(coll, old, cur)
+		configurePricing(priceSvc, cur)
+		startExchangeRates(cur)
+		if old.Retention != cur.Retention || old.MinerLogs.RetentionDays != cur.MinerLogs.RetentionDays {
+			retentionMgr.Retune()
+		}
+		if old.Competition != cur.Competition {
+			select {
+			case competitionChanged <- struct{}{}:
+			default:
 			}
-			// Vacuum to reclaim disk space after weekly purge
-			if err := store.Vacuum(); err != nil {
-				log.Printf("Weekly vacuum error: %v", err)
-			} else {
-				log.Println("Weekly vacuum completed")
+		}
+		if sections := config.RestartRequired(old, cur); len(sections) > 0 {
+			log.Printf("Settings changed that apply after a restart: %s", strings.Join(sections, ", "))
+		}
+	})
+
+	// Restart miners that hang while reachable. Runs even when disabled, so
+	// enabling it in Settings takes effect right away.
+	dog := watchdog.New(settings.Get, collector.NewMinerControl().Restart, store, alertEngine.WatchdogAction)
+	dog.Start()
+	server.SetWatchdog(dog)
+
+	// Check the miners' pools from here, so a pool outage alerts before the
+	// miners lose their connections. Runs even when disabled, so enabling it
+	// in Settings takes effect right away. Demo miners' pool doesn't exist.
+	var poolChecker *poolcheck.Checker
+	if !*demoMode {
+		poolChecker = poolcheck.New(settings.Get, store.GetMiners, func(s poolcheck.Status, message string) {
+			names := make([]string, len(s.Miners))
+			for i, m := range s.Miners {
+				names[i] = m.Hostname
 			}
+			alertEngine.PoolCheck(s.Pool, names, !s.Unreachable, message)
+		})
+		poolChecker.Start()
+		server.SetPoolChecker(poolChecker)
+	}
+
+	// Reconcile the pool accounts' worker stats with the miners, so a pool
+	// crediting a miner with much less than it hashes alerts. Runs even when
+	// disabled, like the pool checks; demo miners mine to no account.
+	var poolStats *poolstats.Monitor
+	if !*demoMode {
+		poolStats = poolstats.New(settings.Get, store.GetMiners, coll.LatestSnapshots, func(pool string, w poolstats.Worker, message string) {
+			alertEngine.PoolMismatch(w.MinerIP, w.Hostname, pool, w.Name, w.PoolHashRate, w.LocalHashRate, w.DivergencePct, w.Diverging, message)
+		})
+		poolStats.Start()
+		server.SetPoolStats(poolStats)
+	}
+	if proxy != nil {
+		server.SetStratumProxy(proxy)
+	}
+	server.SetPlugs(plugMgr)
+
+	// Pause or underclock miners in time windows, at expensive tariffs or on
+	// external signals. Runs even when disabled, so enabling it in Settings
+	// restores or schedules miners right away.
+	sched := scheduler.New(settings.Get, store, scheduler.NewFleet(settings.Get, store, coll, plugMgr))
+	sched.Start()
+	server.SetScheduler(sched)
+
+	// Publish miner events and alerts to MQTT
+	var publisher *mqtt.Publisher
+	if cfg.MQTT.Enabled {
+		publisher = mqtt.NewPublisher(cfg.MQTT)
+		publisher.Start()
+		server.SetMQTT(publisher)
+		log.Printf("Publishing events to MQTT broker %s", cfg.MQTT.Broker)
+	}
+	// Read the scheduler's signals, e.g. a solar inverter's surplus, from MQTT
+	var signalSub *mqtt.Subscriber
+	if cfg.MQTT.Enabled && len(cfg.Scheduler.Signals) > 0 {
+		topics := make([]string, 0, len(cfg.Scheduler.Signals))
+		for _, sig := range cfg.Scheduler.Signals {
+			topics = append(topics, sig.Topic)
 		}
-	}()
+		signalSub = mqtt.NewSubscriber(cfg.MQTT, topics, sched.HandleMessage)
+		signalSub.Start()
+	}
+
+	// Forward snapshots to InfluxDB or a Prometheus remote-write endpoint
+	var metricsExporter *tsdb.Exporter
+	if cfg.TSDB.Enabled {
+		metricsExporter = tsdb.NewExporter(cfg.TSDB)
+		metricsExporter.Start()
+		server.SetTSDB(metricsExporter)
+		// The URL may carry credentials in its query, so only the host is logged
+		if u, err := url.Parse(cfg.TSDB.URL); err == nil {
+			log.Printf("Exporting metrics to %s (%s)", u.Host, cfg.TSDB.Format)
+		}
+	}
+	// Forward the local miners' data to a central MinerHQ
+	var forwarder *agent.Forwarder
+	if cfg.Agent.Enabled {
+		forwarder = agent.NewForwarder(cfg.Agent, store.GetMiners)
+		forwarder.Start()
+		server.SetAgent(forwarder)
+		if u, err := url.Parse(cfg.Agent.Central); err == nil {
+			log.Printf("Forwarding miner data to central MinerHQ at %s", u.Host)
+		}
+	}
+	if len(cfg.Sites) > 0 {
+		log.Printf("Accepting data from %d remote sites", len(cfg.Sites))
+	}
 
-	// Initialize and start HTTP server
-	server := api.NewServer(cfg, store, coll, priceSvc, alertEngine)
 	go func() {
 		log.Printf("HTTP server starting on http://%s:%d", cfg.Server.Host, cfg.Server.Port)
 		if err := server.Start(); err != nil {
@@ -204,6 +483,15 @@ func main() {
 
 	log.Println("MinerHQ is running. Press Ctrl+C to stop.")
 
+	// Reload the config file on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(settings)
+		}
+	}()
+
 	// Wait for interrupt
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -215,10 +503,79 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if proxy != nil {
+		proxy.Stop()
+	}
 	coll.Stop()
 	if err := server.Stop(ctx); err != nil {
 		log.Printf("Server shutdown error: %v", err)
 	}
+	if publisher != nil {
+		publisher.Stop()
+	}
+	if signalSub != nil {
+		signalSub.Stop()
+	}
+	if metricsExporter != nil {
+		metricsExporter.Stop()
+	}
+	if forwarder != nil {
+		forwarder.Stop()
+	}
+	dog.Stop()
+	if poolChecker != nil {
+		poolChecker.Stop()
+	}
+	if poolStats != nil {
+		poolStats.Stop()
+	}
+	sched.Stop()
+	plugMgr.Stop()
 
 	log.Println("MinerHQ stopped")
 }
+
+// finalizeCompetition stores the final standings of the competition period
+// ending at end, scored as configured
+func finalizeCompetition(store *storage.SQLiteStorage, cfg *config.Config, end time.Time) {
+	period := cfg.Competition.CompetitionPeriod()
+	start := period.Previous(end)
+	scorer, err := store.CompetitionScorer(cfg.Competition.Scoring, cfg.ExpectedHashrates(), start, end)
+	if err != nil {
+		log.Printf("Competition results error: %v", err)
+		return
+	}
+	placed, err := store.FinalizeCompetition(period.Adjective(), start, end, scorer)
+	if err != nil {
+		log.Printf("Competition results error: %v", err)
+	} else if placed > 0 {
+		log.Printf("Stored final %s competition standings from %s: %d miners", period.Adjective(), start.Format("2006-01-02"), placed)
+	}
+}
+
+// compactDatabase reclaims the disk space freed by a purge with the
+// configured vacuum strategy and truncates the WAL. A full vacuum locks the
+// database while it rewrites the file, so unless allowFull is set only the
+// WAL is truncated with that strategy.
+func compactDatabase(store *storage.SQLiteStorage, strategy string, allowFull bool) {
+	if strategy == storage.VacuumFull && !allowFull {
+		strategy = storage.VacuumOff
+	}
+	start := time.Now()
+	freed, err := store.Compact(strategy)
+	if err != nil {
+		log.Printf("Database compaction error: %v", err)
+	} else if strategy == storage.VacuumFull {
+		log.Printf("Database vacuumed in %v, %s reclaimed", time.Since(start).Round(time.Millisecond), units.FormatBytes(freed))
+	} else if freed > 0 {
+		log.Printf("Database compacted, %s reclaimed", units.FormatBytes(freed))
+	}
+}
+
+// resolveDBPath returns the configured database path, falling back to a local file
+func resolveDBPath(cfg *config.Config) string {
+	if cfg.DBPath == "" {
+		return "minerhq.db"
+	}
+	return cfg.DBPath
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/api"
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/pricing"
+)
+
+// runtimeOverrides returns the overlay of environment overrides and the
+// read-only flag, which apply on top of the config file but aren't saved to it
+func runtimeOverrides(readOnly bool) func(c *config.Config) error {
+	return func(c *config.Config) error {
+		if err := c.ApplyEnv(); err != nil {
+			return err
+		}
+		if readOnly {
+			c.Server.ReadOnly = true
+		}
+		return nil
+	}
+}
+
+// reloadConfig reads the config file again, with environment overrides and
+// the read-only flag applied as at startup, and makes it current. An invalid
+// file is logged and the running settings are kept.
+func reloadConfig(settings *config.Manager) {
+	cfg, err := config.Load(settings.Path())
+	if err != nil {
+		log.Printf("Config reload failed, keeping the running settings: %v", err)
+		return
+	}
+	if changed, err := api.HashConfigPasswords(cfg); err != nil {
+		log.Printf("Config reload failed, keeping the running settings: %v", err)
+		return
+	} else if changed {
+		if err := cfg.Save(settings.Path()); err != nil {
+			log.Printf("Warning: could not save hashed passwords: %v", err)
+		}
+	}
+	overlaid, err := settings.Overlaid(cfg)
+	if err != nil {
+		log.Printf("Config reload failed, keeping the running settings: invalid environment override: %v", err)
+		return
+	}
+	if err := overlaid.Validate(); err != nil {
+		log.Printf("Config reload failed, keeping the running settings:\n%v", err)
+		return
+	}
+
+	if err := settings.Replace(cfg); err != nil {
+		log.Printf("Config reload failed, keeping the running settings: invalid environment override: %v", err)
+		return
+	}
+	log.Printf("Config reloaded from %s", settings.Path())
+}
+
+// configureCollector applies the polling, snapshot sampling and log capture
+// settings to the collector. old is nil at startup; otherwise per-miner poll
+// intervals no longer configured are cleared, and collection is restarted
+// when the polling settings changed.
+func configureCollector(coll *collector.Collector, old, cur *config.Config) {
+	coll.SetSnapshotSampling(cur.Retention.SnapshotEvery, time.Duration(cur.Retention.SnapshotIntervalSecs)*time.Second,
+		time.Duration(cur.Retention.SnapshotUnchangedSecs)*time.Second)
+	coll.SetSnapshotFlushInterval(time.Duration(cur.Retention.SnapshotFlushSecs) * time.Second)
+	coll.SetLogCapture(cur.MinerLogs.BufferLines, cur.MinerLogs.Persist)
+	coll.SetPollInterval(time.Duration(cur.Polling.IntervalSecs)*time.Second, cur.Polling.BackoffAfter, time.Duration(cur.Polling.MaxIntervalSecs)*time.Second)
+	if old != nil && old.Polling != cur.Polling {
+		// Polls already scheduled would still wait out the old interval
+		log.Printf("Polling settings changed, restarting collection")
+		coll.Restart()
+	}
+
+	configured := make(map[string]bool, len(cur.Miners))
+	for _, mc := range cur.Miners {
+		configured[mc.IP] = true
+		coll.SetMinerPollInterval(mc.IP, time.Duration(mc.PollIntervalSecs)*time.Second)
+	}
+	if old != nil {
+		for _, mc := range old.Miners {
+			if !configured[mc.IP] {
+				coll.SetMinerPollInterval(mc.IP, 0)
+			}
+		}
+	}
+}
+
+// configurePricing applies the price provider settings to the price service
+func configurePricing(priceSvc *pricing.PriceService, cfg *config.Config) {
+	priceSvc.SetProviders(pricing.ProviderConfig{
+		Names:           cfg.Pricing.Providers,
+		CoinGeckoAPIKey: cfg.Pricing.CoinGeckoAPIKey,
+		CoinGeckoPro:    cfg.Pricing.CoinGeckoPro,
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/scanner"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// backgroundScan rescans the network every scan interval while the scanner
+// is enabled. Registered miners found at a new IP are moved there. Miners
+// that aren't registered yet are added to storage and the collector when
+// auto-add is on, and only logged otherwise. Paused miners are left alone.
+// Turning the scanner on or changing its interval takes effect right away.
+func backgroundScan(settings *config.Manager, store *storage.SQLiteStorage, coll *collector.Collector) {
+	changed := make(chan struct{}, 1)
+	settings.Subscribe(func(old, cur *config.Config) {
+		if old.Scanner.Enabled != cur.Scanner.Enabled || old.Scanner.ScanInterval != cur.Scanner.ScanInterval {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	sc := scanner.NewScanner()
+	for {
+		cfg := settings.Get()
+		if !cfg.Scanner.Enabled {
+			<-changed
+			continue
+		}
+		log.Printf("Background scan enabled every %s (auto-add: %v)", cfg.Scanner.ScanInterval, cfg.Scanner.AutoAdd)
+
+		ticker := time.NewTicker(cfg.Scanner.ScanInterval)
+	scanning:
+		for {
+			runScan(settings.Get(), sc, store, coll)
+			select {
+			case <-ticker.C:
+			case <-changed:
+				break scanning
+			}
+		}
+		ticker.Stop()
+	}
+}
+
+// runScan performs one background scan
+func runScan(cfg *config.Config, sc *scanner.Scanner, store *storage.SQLiteStorage, coll *collector.Collector) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	d, err := sc.Discover(ctx, cfg.Scanner)
+	if d == nil {
+		log.Printf("Background scan error: %v", err)
+		return
+	}
+	if err != nil {
+		log.Printf("Error probing DHCP leases: %v", err)
+	}
+
+	// Paused miners count as registered, so they aren't added back
+	known, err := store.GetAllMiners()
+	if err != nil {
+		log.Printf("Background scan error: %v", err)
+		return
+	}
+	registered := make(map[string]bool, len(known))
+	for _, m := range known {
+		registered[m.IP] = m.Enabled || m.Paused
+	}
+
+	for _, result := range d.Results {
+		m := result.Miner
+		if registered[m.IP] {
+			continue
+		}
+		if oldIP, err := coll.FollowMAC(m.IP, m.MacAddr); err != nil {
+			log.Printf("Background scan: could not move miner %s: %v", m.MacAddr, err)
+			continue
+		} else if oldIP != "" {
+			log.Printf("Background scan: miner %s (%s) moved from %s", m.IP, m.Hostname, oldIP)
+			continue
+		}
+		if !cfg.Scanner.AutoAdd {
+			log.Printf("Background scan: found unregistered miner %s (%s)", m.IP, m.Hostname)
+			continue
+		}
+
+		// Fall back to the energy location from the config's miners list
+		if m.Location == "" {
+			for _, mc := range cfg.Miners {
+				if mc.IP == m.IP {
+					m.Location = mc.Location
+					break
+				}
+			}
+		}
+		if err := store.UpsertMiner(m); err != nil {
+			log.Printf("Background scan: could not add miner %s: %v", m.IP, err)
+			continue
+		}
+		coll.SetEnergyLocation(m.IP, m.Location)
+		coll.AddMiner(m.IP)
+		log.Printf("Background scan: added miner %s (%s)", m.IP, m.Hostname)
+	}
+}
@@ -0,0 +1,222 @@
+// Package agent forwards the data of a remote site's miners to a central
+// MinerHQ, which stores it as the site's (see collector.Ingest)
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// ingestPath is the central server's endpoint for site batches
+const ingestPath = "/api/sites/ingest"
+
+const (
+	// defaultFlushInterval is how often batches are sent unless configured
+	// otherwise
+	defaultFlushInterval = 5 * time.Second
+
+	// maxPending bounds the shares kept while the central server is
+	// unreachable; beyond it the oldest are dropped
+	maxPending = 10000
+)
+
+// Forwarder batches the snapshots, shares and blocks of the local miners and
+// sends them to the central server every flush interval, with the list of
+// miners. Only the latest snapshot of each miner is sent per batch. Shares and
+// blocks of a failed batch are sent again with the next one, so adding them
+// never blocks the caller.
+type Forwarder struct {
+	cfg      config.AgentConfig
+	url      string
+	client   *http.Client
+	interval time.Duration
+	miners   func() ([]*storage.Miner, error) // The local miners, sent with every batch
+
+	mu        sync.Mutex
+	snapshots map[string]*storage.MinerSnapshot // Latest per miner since the last batch
+	shares    []*storage.Share
+	blocks    []*storage.Block
+	dropped   int // Shares dropped since the last batch
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewForwarder creates a forwarder to the configured central server. miners
+// returns the miners to list in every batch.
+func NewForwarder(cfg config.AgentConfig, miners func() ([]*storage.Miner, error)) *Forwarder {
+	interval := time.Duration(cfg.FlushSecs) * time.Second
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	return &Forwarder{
+		cfg:       cfg,
+		url:       strings.TrimSuffix(cfg.Central, "/") + ingestPath,
+		client:    &http.Client{Timeout: 15 * time.Second},
+		interval:  interval,
+		miners:    miners,
+		snapshots: make(map[string]*storage.MinerSnapshot),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start sends batches in the background
+func (f *Forwarder) Start() {
+	go f.run()
+}
+
+// Stop sends the remaining data and stops the forwarder
+func (f *Forwarder) Stop() {
+	close(f.stop)
+	<-f.done
+}
+
+// AddSnapshot queues a miner's snapshot, replacing any queued one. Snapshots
+// of miners from other sites are not forwarded.
+func (f *Forwarder) AddSnapshot(snap *storage.MinerSnapshot) {
+	if collector.IsRemote(snap.MinerIP) {
+		return
+	}
+	f.mu.Lock()
+	f.snapshots[snap.MinerIP] = snap
+	f.mu.Unlock()
+}
+
+// AddShare queues a share for the next batch
+func (f *Forwarder) AddShare(share *storage.Share) {
+	if collector.IsRemote(share.MinerIP) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shares = append(f.shares, share)
+	f.trimLocked()
+}
+
+// AddBlock queues a found block for the next batch
+func (f *Forwarder) AddBlock(block *storage.Block) {
+	if collector.IsRemote(block.MinerIP) {
+		return
+	}
+	f.mu.Lock()
+	f.blocks = append(f.blocks, block)
+	f.mu.Unlock()
+}
+
+// trimLocked drops the oldest shares beyond maxPending. The caller must hold
+// mu.
+func (f *Forwarder) trimLocked() {
+	if len(f.shares) > maxPending {
+		f.dropped += len(f.shares) - maxPending
+		f.shares = f.shares[len(f.shares)-maxPending:]
+	}
+}
+
+func (f *Forwarder) run() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			f.flush()
+			return
+		case <-ticker.C:
+			f.flush()
+		}
+	}
+}
+
+// flush sends the queued data in one batch. The batch is sent even when
+// empty, so the central server sees the site is up. On failure its shares and
+// blocks are queued again, and its snapshots unless newer ones arrived.
+func (f *Forwarder) flush() {
+	miners, err := f.localMiners()
+	if err != nil {
+		log.Printf("Agent: listing miners failed: %v", err)
+		return
+	}
+
+	f.mu.Lock()
+	batch := &collector.RemoteBatch{Miners: miners, Shares: f.shares, Blocks: f.blocks}
+	for _, snap := range f.snapshots {
+		batch.Snapshots = append(batch.Snapshots, snap)
+	}
+	dropped := f.dropped
+	f.snapshots = make(map[string]*storage.MinerSnapshot)
+	f.shares, f.blocks, f.dropped = nil, nil, 0
+	f.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Agent: central server unreachable for too long, dropped %d shares", dropped)
+	}
+	if err := f.send(batch); err != nil {
+		log.Printf("Agent: sending %d snapshots, %d shares and %d blocks failed: %v",
+			len(batch.Snapshots), len(batch.Shares), len(batch.Blocks), err)
+		f.mu.Lock()
+		for _, snap := range batch.Snapshots {
+			if _, newer := f.snapshots[snap.MinerIP]; !newer {
+				f.snapshots[snap.MinerIP] = snap
+			}
+		}
+		f.shares = append(batch.Shares, f.shares...)
+		f.blocks = append(batch.Blocks, f.blocks...)
+		f.trimLocked()
+		f.mu.Unlock()
+	}
+}
+
+// localMiners returns the miners this site collects from, leaving out those
+// forwarded to it by other sites
+func (f *Forwarder) localMiners() ([]*storage.Miner, error) {
+	all, err := f.miners()
+	if err != nil {
+		return nil, err
+	}
+	miners := make([]*storage.Miner, 0, len(all))
+	for _, m := range all {
+		if m.Site == "" && !collector.IsRemote(m.IP) {
+			miners = append(miners, m)
+		}
+	}
+	return miners, nil
+}
+
+// send posts a batch to the central server with the site's token
+func (f *Forwarder) send(batch *collector.RemoteBatch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+f.cfg.Token)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestForwarder(t *testing.T) {
+	var mu sync.Mutex
+	var batches []collector.RemoteBatch
+	var auth string
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/api/sites/ingest" {
+			http.NotFound(w, r)
+			return
+		}
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var batch collector.RemoteBatch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, batch)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	miners := func() ([]*storage.Miner, error) {
+		return []*storage.Miner{{IP: "10.0.0.2"}, {IP: "10.0.0.9@barn", Site: "barn"}}, nil
+	}
+	f := NewForwarder(config.AgentConfig{Central: srv.URL + "/", Token: "site-token", FlushSecs: 3600}, miners)
+	f.Start()
+
+	f.AddSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.2", HashRate: 1000})
+	f.AddShare(&storage.Share{MinerIP: "10.0.0.2", Difficulty: 5})
+	f.AddShare(&storage.Share{MinerIP: "10.0.0.9@barn", Difficulty: 7})
+	f.flush()
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	f.AddSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.2", HashRate: 1200})
+	f.AddBlock(&storage.Block{MinerIP: "10.0.0.2"})
+	f.Stop()
+
+	if len(batches) != 1 {
+		t.Fatalf("expected one batch delivered, got %d", len(batches))
+	}
+	b := batches[0]
+	if len(b.Miners) != 1 || b.Miners[0].IP != "10.0.0.2" {
+		t.Errorf("expected only the local miner listed, got %+v", b.Miners)
+	}
+	if len(b.Snapshots) != 1 || b.Snapshots[0].HashRate != 1200 {
+		t.Errorf("expected only the newest snapshot sent, got %+v", b.Snapshots)
+	}
+	if len(b.Shares) != 1 || b.Shares[0].Difficulty != 5 || len(b.Blocks) != 1 {
+		t.Errorf("expected the failed batch's share retried with the block, got %+v %+v", b.Shares, b.Blocks)
+	}
+	if auth != "Bearer site-token" {
+		t.Errorf("Authorization = %q, want the site token", auth)
+	}
+}
@@ -3,14 +3,19 @@ package alerts
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/competition"
+	"github.com/camarigor/miner-hq/internal/config"
 	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
 )
 
 // AlertType represents the type of alert
@@ -27,8 +32,28 @@ const (
 	AlertNewBestDiff      AlertType = "new_best_diff"
 	AlertBlockFound       AlertType = "block_found"
 	AlertNewLeader        AlertType = "new_leader"
+	AlertFirmwareMismatch AlertType = "firmware_mismatch"
+	AlertMinerFrozen      AlertType = "miner_frozen"
+	AlertPoolDiffChange   AlertType = "pool_diff_change"
+	AlertRejectRateHigh   AlertType = "reject_rate_high"
+	AlertWatchdogRestart  AlertType = "watchdog_restart"
+	AlertDigest           AlertType = "digest" // Summary of a finished competition period
+	AlertPoolUnreachable  AlertType = "pool_unreachable" // A pool failed MinerHQ's own checks
+	AlertPoolMismatch     AlertType = "pool_hashrate_mismatch" // A pool credits a worker with a hashrate far from the miner's
+
+	// Recoveries, sent when an alerted condition clears
+	AlertMinerOnline     AlertType = "miner_online"
+	AlertPoolReconnected AlertType = "pool_reconnected"
+	AlertTempNormal      AlertType = "temp_normal"
+	AlertPoolReachable   AlertType = "pool_reachable"
+	AlertPoolMatch       AlertType = "pool_hashrate_match"
+
+	alertTest AlertType = "test" // Connectivity test sent from Settings
 )
 
+// cooldownPeriod is how long an alert type stays quiet for a miner after firing
+const cooldownPeriod = 5 * time.Minute
+
 // alertDisplay holds the visual representation for each alert type
 type alertDisplay struct {
 	Emoji string
@@ -48,6 +73,20 @@ var alertDisplayMap = map[AlertType]alertDisplay{
 	AlertNewBestDiff:      {Emoji: "🏆", Title: "New Best Difficulty!", Color: 0x00FF88},
 	AlertBlockFound:       {Emoji: "⛏️", Title: "Block Found!", Color: 0xFFD700},
 	AlertNewLeader:        {Emoji: "👑", Title: "New Weekly Leader!", Color: 0xAA55FF},
+	AlertFirmwareMismatch: {Emoji: "🧩", Title: "Firmware Mismatch", Color: 0xFFAA00},
+	AlertMinerFrozen:      {Emoji: "🧊", Title: "Miner Frozen", Color: 0xFF4444},
+	AlertPoolDiffChange:   {Emoji: "🎚️", Title: "Pool Difficulty Change", Color: 0x00D4FF},
+	AlertRejectRateHigh:   {Emoji: "🚫", Title: "High Rejection Rate", Color: 0xFF6600},
+	AlertWatchdogRestart:  {Emoji: "🐕", Title: "Watchdog Restart", Color: 0xFFAA00},
+	AlertDigest:           {Emoji: "📊", Title: "Fleet Summary", Color: 0x00D4FF},
+	AlertPoolUnreachable:  {Emoji: "📡", Title: "Pool Unreachable", Color: 0xFF4444},
+	AlertPoolMismatch:     {Emoji: "⚖️", Title: "Pool Hashrate Mismatch", Color: 0xFFAA00},
+	AlertMinerOnline:      {Emoji: "🟢", Title: "Miner Back Online", Color: 0x00FF88},
+	AlertPoolReconnected:  {Emoji: "🔗", Title: "Pool Reconnected", Color: 0x00FF88},
+	AlertTempNormal:       {Emoji: "❄️", Title: "Temperature Normal", Color: 0x00FF88},
+	AlertPoolReachable:    {Emoji: "🛰️", Title: "Pool Reachable", Color: 0x00FF88},
+	AlertPoolMatch:        {Emoji: "🤝", Title: "Pool Hashrate Matches", Color: 0x00FF88},
+	alertTest:             {Emoji: "✅", Title: "Test Alert", Color: 0x00FF88},
 }
 
 // getAlertDisplay returns the display properties for an alert type
@@ -62,8 +101,12 @@ func getAlertDisplay(t AlertType) alertDisplay {
 type AlertConfig struct {
 	WebhookURL          string  `json:"webhookUrl"`
 	MinerOfflineSeconds int     `json:"minerOfflineSeconds"`
+	MinerFrozenSeconds  int     `json:"minerFrozenSeconds"`
 	TempAbove           float64 `json:"tempAbove"`
-	HashrateDropPercent float64 `json:"hashrateDropPercent"`
+	HashrateDropPercent float64 `json:"hashrateDropPercent"` // Drop of the 10-minute average against the 10 minutes before
+	HashrateDropChecks  int     `json:"hashrateDropChecks"`  // Consecutive checks the average must stay down
+	PoolDiffChangePct   float64 `json:"poolDiffChangePct"` // 100 = doubled or halved
+	ShareRejectPct      float64 `json:"shareRejectPct"`    // Rejected share of the last 30 minutes' submissions
 	FanRPMBelow         int     `json:"fanRpmBelow"`
 	WifiSignalBelow     int     `json:"wifiSignalBelow"`
 	OnShareRejected     bool    `json:"onShareRejected"`
@@ -71,6 +114,62 @@ type AlertConfig struct {
 	OnNewBestDiff       bool    `json:"onNewBestDiff"`
 	OnBlockFound        bool    `json:"onBlockFound"`
 	OnNewLeader         bool    `json:"onNewLeader"`
+	OnFirmwareMismatch  bool    `json:"onFirmwareMismatch"`
+	OnRecovery          bool    `json:"onRecovery"` // Miner back online, pool reconnected, reachable or matching, temperature normal
+	OnDigest            bool    `json:"onDigest"`   // Summary when a competition period ends
+	OnPoolUnreachable   bool    `json:"onPoolUnreachable"`
+	OnPoolMismatch      bool    `json:"onPoolMismatch"`
+
+	// Matrix room to notify alongside (or instead of) Discord
+	MatrixHomeserver  string `json:"matrixHomeserver"`
+	MatrixAccessToken string `json:"matrixAccessToken"`
+	MatrixRoomID      string `json:"matrixRoomId"`
+
+	// Webhook, Slack, ntfy and Pushover channels, each with its alert types
+	Notifiers []config.NotifierConfig `json:"notifiers"`
+
+	QuietHours config.QuietHoursConfig `json:"quietHours"` // Window that holds back alerts
+
+	Display units.Display `json:"display"` // Units and clock used in alert messages
+
+	Competition        competition.Period `json:"-"` // Period the new leader alert is for
+	CompetitionScoring string             `json:"-"` // How the new leader is scored: raw, expected or average
+	ExpectedHashrates  map[string]float64 `json:"-"` // Configured GH/s by miner IP, for expected scoring
+}
+
+// ConfigFrom builds the alert configuration from the application settings
+func ConfigFrom(cfg *config.Config) *AlertConfig {
+	return &AlertConfig{
+		WebhookURL:          cfg.Alerts.WebhookURL,
+		MinerOfflineSeconds: cfg.Alerts.OfflineMinutes * 60,
+		MinerFrozenSeconds:  cfg.Alerts.FrozenMinutes * 60,
+		TempAbove:           cfg.Alerts.TempThresholdC,
+		HashrateDropPercent: cfg.Alerts.HashrateDropPct,
+		HashrateDropChecks:  cfg.Alerts.HashrateDropChecks,
+		PoolDiffChangePct:   cfg.Alerts.PoolDiffChangePct,
+		ShareRejectPct:      cfg.Alerts.ShareRejectPct,
+		FanRPMBelow:         cfg.Alerts.FanRPMBelow,
+		WifiSignalBelow:     cfg.Alerts.WifiSignalBelow,
+		OnShareRejected:     cfg.Alerts.OnShareRejected,
+		OnPoolDisconnected:  cfg.Alerts.OnPoolDisconnected,
+		OnNewBestDiff:       cfg.Alerts.OnNewBestDiff,
+		OnBlockFound:        cfg.Alerts.OnBlockFound,
+		OnNewLeader:         cfg.Alerts.OnNewLeader,
+		OnFirmwareMismatch:  cfg.Alerts.OnFirmwareMismatch,
+		OnRecovery:          cfg.Alerts.OnRecovery,
+		OnDigest:            cfg.Alerts.OnDigest,
+		OnPoolUnreachable:   cfg.Alerts.OnPoolUnreachable,
+		OnPoolMismatch:      cfg.Alerts.OnPoolMismatch,
+		MatrixHomeserver:    cfg.Alerts.MatrixHomeserver,
+		MatrixAccessToken:   cfg.Alerts.MatrixAccessToken,
+		MatrixRoomID:        cfg.Alerts.MatrixRoomID,
+		Notifiers:           cfg.Alerts.Notifiers,
+		QuietHours:          cfg.Alerts.QuietHours,
+		Display:             cfg.Display.Units(),
+		Competition:         cfg.Competition.CompetitionPeriod(),
+		CompetitionScoring:  cfg.Competition.Scoring,
+		ExpectedHashrates:   cfg.ExpectedHashrates(),
+	}
 }
 
 // Alert represents a triggered alert
@@ -89,135 +188,448 @@ type AlertEngine struct {
 	config        *AlertConfig
 	client        *http.Client
 	lastSeen      map[string]time.Time
-	lastHashrate  map[string]float64
+	hashrate      map[string]*hashrateHistory
+	rejects       map[string]*rejectHistory
+	open          map[string]map[AlertType]*condition // Miner IP -> ongoing problems, see raise
 	lastBestDiff  map[string]float64
+	lastPoolDiff  map[string]float64
 	alertCooldown map[string]time.Time // Prevent alert spam
-	weeklyBestDiff float64
-	weeklyLeader   string
+	firmwareAlerted map[string]string  // Miner IP -> mismatched version already alerted
+	weeklyBest     map[string]periodBest // Miner IP -> best share of the current competition period
+	weeklyLeaderIP string                // Leader identity; hostnames can change mid-week
 	weekStart      time.Time
+	store          *storage.SQLiteStorage // Dead-letter queue for undeliverable alerts
+	retryDelay     time.Duration
+	discordQueue   chan discordItem // Serializes webhook posts, see runDiscordQueue
+	discordLimit   discordRateLimit
+	onAlert        func(Alert) // Called for every sent alert, see SetOnAlert
+	overrides      map[string]*storage.AlertOverrides // Per-miner settings, see SetOverrides
+	names          map[string]string                  // Miner IP -> display name, see SetDisplayNames
 	mu            sync.RWMutex
+
+	holdMu      sync.Mutex // Guards maintenance and held, see hold
+	maintenance []*storage.MaintenanceWindow
+	held        []heldAlert // Alerts waiting for quiet hours to end
 }
 
 // NewAlertEngine creates a new alert engine
 func NewAlertEngine(config *AlertConfig) *AlertEngine {
+	e := newAlertEngine(config)
+	go e.runDiscordQueue()
+	go e.runQuietHours()
+	checkNotifiers(config.Notifiers)
+	return e
+}
+
+// newAlertEngine creates an alert engine without starting its Discord queue
+// and quiet hours workers
+func newAlertEngine(config *AlertConfig) *AlertEngine {
 	return &AlertEngine{
 		config:        config,
 		client:        &http.Client{Timeout: 10 * time.Second},
 		lastSeen:      make(map[string]time.Time),
-		lastHashrate:  make(map[string]float64),
+		hashrate:      make(map[string]*hashrateHistory),
+		rejects:       make(map[string]*rejectHistory),
+		open:          make(map[string]map[AlertType]*condition),
 		lastBestDiff:  make(map[string]float64),
+		lastPoolDiff:  make(map[string]float64),
 		alertCooldown: make(map[string]time.Time),
-		weekStart:     currentWeekStart(),
+		firmwareAlerted: make(map[string]string),
+		weeklyBest:    make(map[string]periodBest),
+		weekStart:     config.Competition.Start(time.Now()),
+		retryDelay:    defaultRetryDelay,
+		discordQueue:  make(chan discordItem, discordQueueSize),
 	}
 }
 
-// currentWeekStart returns the start of the current week (Sunday midnight)
-func currentWeekStart() time.Time {
-	now := time.Now()
-	weekday := int(now.Weekday())
-	return time.Date(now.Year(), now.Month(), now.Day()-weekday, 0, 0, 0, 0, now.Location())
+// SetOnAlert registers a function called with every alert that is sent, e.g.
+// to publish it elsewhere. It is called with the engine locked and must not
+// block.
+func (e *AlertEngine) SetOnAlert(fn func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onAlert = fn
 }
 
-// InitWeeklyLeader seeds the in-memory weekly leader state so that a
-// container restart doesn't trigger a false "new leader" alert.
-func (e *AlertEngine) InitWeeklyLeader(leader string, bestDiff float64) {
+// InitWeeklyLeader seeds the in-memory standings of the current period from
+// its leaderboard so that a container restart doesn't trigger a false "new
+// leader" alert.
+func (e *AlertEngine) InitWeeklyLeader(leaderboard []*storage.LeaderboardEntry) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.weeklyLeader = leader
-	e.weeklyBestDiff = bestDiff
-	e.weekStart = currentWeekStart()
-	if leader != "" {
-		log.Printf("Weekly leader initialized: %s (diff: %.2f)", leader, bestDiff)
+	e.weekStart = e.config.Competition.Start(time.Now())
+	e.weeklyBest = make(map[string]periodBest, len(leaderboard))
+	for _, entry := range leaderboard {
+		e.weeklyBest[entry.MinerIP] = periodBest{diff: entry.BestDiff, hostname: entry.Hostname}
+	}
+	e.weeklyLeaderIP = ""
+	e.weeklyLeaderIP, _ = e.periodLeader()
+	if best, ok := e.weeklyBest[e.weeklyLeaderIP]; ok {
+		log.Printf("Weekly leader initialized: %s (diff: %.2f)", e.minerName(e.weeklyLeaderIP, best.hostname), best.diff)
 	}
 }
 
 // UpdateConfig updates the alert configuration
 func (e *AlertEngine) UpdateConfig(config *AlertConfig) {
+	checkNotifiers(config.Notifiers)
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.config = config
 }
 
-// CheckSnapshot evaluates a snapshot and triggers alerts if needed
+// SetOverrides replaces the per-miner alert overrides, keyed by miner IP
+func (e *AlertEngine) SetOverrides(overrides map[string]*storage.AlertOverrides) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.overrides = overrides
+}
+
+// SetDisplayNames replaces the names miners are called by in alerts, keyed by
+// miner IP. Miners without one are called by their hostname.
+func (e *AlertEngine) SetDisplayNames(names map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.names = names
+}
+
+// minerName returns what a miner is called in alerts. The caller must hold mu.
+func (e *AlertEngine) minerName(minerIP, hostname string) string {
+	if name := e.names[minerIP]; name != "" {
+		return name
+	}
+	return hostname
+}
+
+// SetMinerOverrides replaces one miner's alert overrides. Nil or empty
+// overrides make the miner follow the global settings.
+func (e *AlertEngine) SetMinerOverrides(minerIP string, o *storage.AlertOverrides) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if o == nil || o.IsEmpty() {
+		delete(e.overrides, minerIP)
+		return
+	}
+	if e.overrides == nil {
+		e.overrides = make(map[string]*storage.AlertOverrides)
+	}
+	e.overrides[minerIP] = o
+}
+
+// minerConfig returns the alert configuration for a miner, with its
+// overrides applied. Channels turned off for the miner are left unset.
+func (e *AlertEngine) minerConfig(minerIP string) *AlertConfig {
+	o, ok := e.overrides[minerIP]
+	if !ok {
+		return e.config
+	}
+
+	config := *e.config
+	if o.TempAbove != nil {
+		config.TempAbove = *o.TempAbove
+	}
+	if o.HashrateDropPercent != nil {
+		config.HashrateDropPercent = *o.HashrateDropPercent
+	}
+	if o.PoolDiffChangePct != nil {
+		config.PoolDiffChangePct = *o.PoolDiffChangePct
+	}
+	if o.FanRPMBelow != nil {
+		config.FanRPMBelow = *o.FanRPMBelow
+	}
+	if o.WifiSignalBelow != nil {
+		config.WifiSignalBelow = *o.WifiSignalBelow
+	}
+	if o.MinerOfflineSeconds != nil {
+		config.MinerOfflineSeconds = *o.MinerOfflineSeconds
+	}
+	if o.MinerFrozenSeconds != nil {
+		config.MinerFrozenSeconds = *o.MinerFrozenSeconds
+	}
+	if o.Discord != nil && !*o.Discord {
+		config.WebhookURL = ""
+	}
+	if o.Matrix != nil && !*o.Matrix {
+		config.MatrixHomeserver, config.MatrixAccessToken, config.MatrixRoomID = "", "", ""
+	}
+	return &config
+}
+
+// cooldown returns how long an alert type stays quiet for a miner after firing
+func (e *AlertEngine) cooldown(minerIP string) time.Duration {
+	if o, ok := e.overrides[minerIP]; ok && o.CooldownMinutes != nil {
+		return time.Duration(*o.CooldownMinutes) * time.Minute
+	}
+	return cooldownPeriod
+}
+
+// ResetSession clears the per-miner baselines used for alert evaluation
+// (hashrate, session best difficulty, cooldowns and open conditions) so
+// tracking starts fresh without rebooting the miner. An empty minerIP resets
+// every miner.
+func (e *AlertEngine) ResetSession(minerIP string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.forgetConditions(minerIP)
+
+	if minerIP == "" {
+		e.hashrate = make(map[string]*hashrateHistory)
+		e.rejects = make(map[string]*rejectHistory)
+		e.lastBestDiff = make(map[string]float64)
+		e.lastPoolDiff = make(map[string]float64)
+		e.alertCooldown = make(map[string]time.Time)
+		return
+	}
+
+	delete(e.hashrate, minerIP)
+	delete(e.rejects, minerIP)
+	delete(e.lastBestDiff, minerIP)
+	delete(e.lastPoolDiff, minerIP)
+	for key := range e.alertCooldown {
+		if strings.HasPrefix(key, minerIP+":") {
+			delete(e.alertCooldown, key)
+		}
+	}
+}
+
+// MinerMoved starts a miner that moved from oldIP afresh at its new address,
+// and reloads what moved with it in the store: its overrides, display name
+// and maintenance windows
+func (e *AlertEngine) MinerMoved(oldIP string) {
+	e.ResetSession(oldIP)
+
+	e.mu.RLock()
+	store := e.store
+	e.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	if overrides, err := store.GetAlertOverrides(); err != nil {
+		log.Printf("Failed to reload per-miner alert overrides: %v", err)
+	} else {
+		e.SetOverrides(overrides)
+	}
+	if names, err := store.GetDisplayNames(); err != nil {
+		log.Printf("Failed to reload miner display names: %v", err)
+	} else {
+		e.SetDisplayNames(names)
+	}
+	if windows, err := store.GetMaintenanceWindows(); err != nil {
+		log.Printf("Failed to reload maintenance windows: %v", err)
+	} else {
+		e.SetMaintenance(windows)
+	}
+}
+
+// MinerAlertState is the alert engine's view of one miner: the baselines
+// alerts are evaluated against and the alerts currently in cooldown
+type MinerAlertState struct {
+	LastSeen         time.Time            `json:"lastSeen,omitempty"`
+	HashrateAverage  float64              `json:"hashrateAverage"`  // GH/s over the last 10 minutes, checked for drops
+	HashrateDrops    int                  `json:"hashrateDrops"`    // Consecutive checks the average has been down
+	SessionBestDiff  float64              `json:"sessionBestDiff"`  // Baseline for new best difficulty
+	PoolDifficulty   float64              `json:"poolDifficulty"`   // Baseline for pool difficulty jumps
+	FirmwareMismatch string               `json:"firmwareMismatch"` // Mismatched version already alerted, if any
+	Cooldowns        map[string]time.Time `json:"cooldowns"`        // Alert type -> when it may fire again
+	Open             map[string]time.Time `json:"open"`             // Alert type -> when the ongoing problem began
+
+	Overrides   *storage.AlertOverrides    `json:"overrides,omitempty"`   // Per-miner settings, if any
+	Maintenance *storage.MaintenanceWindow `json:"maintenance,omitempty"` // Window silencing the miner's alerts right now, if any
+}
+
+// MinerState returns the alert state for a miner
+func (e *AlertEngine) MinerState(minerIP string) *MinerAlertState {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	state := &MinerAlertState{
+		LastSeen:         e.lastSeen[minerIP],
+		HashrateAverage:  e.hashrate[minerIP].average(time.Now()),
+		HashrateDrops:    e.hashrate[minerIP].dropCount(),
+		SessionBestDiff:  e.lastBestDiff[minerIP],
+		PoolDifficulty:   e.lastPoolDiff[minerIP],
+		FirmwareMismatch: e.firmwareAlerted[minerIP],
+		Cooldowns:        make(map[string]time.Time),
+		Open:             e.conditionsSince(minerIP),
+		Overrides:        e.overrides[minerIP],
+	}
+	now := time.Now()
+	state.Maintenance = e.maintenanceWindow(minerIP, now)
+	cooldown := e.cooldown(minerIP)
+	for key, last := range e.alertCooldown {
+		alertType, ok := strings.CutPrefix(key, minerIP+":")
+		if until := last.Add(cooldown); ok && until.After(now) {
+			state.Cooldowns[alertType] = until
+		}
+	}
+	return state
+}
+
+// CheckSnapshot evaluates a snapshot against the miner's thresholds, opening
+// and clearing conditions and triggering alerts as needed
 func (e *AlertEngine) CheckSnapshot(snap *storage.MinerSnapshot) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	minerKey := snap.MinerIP
+	config := e.minerConfig(minerKey)
+	name := e.minerName(snap.MinerIP, snap.Hostname)
 
 	// Update last seen
 	e.lastSeen[minerKey] = time.Now()
+	e.clear(snap.MinerIP, name, AlertMinerOffline, onlineMessage)
+
+	// Check frozen data: the API answers but the miner repeats the same readings
+	e.check(config.MinerFrozenSeconds > 0 && snap.FrozenSecs >= int64(config.MinerFrozenSeconds), Alert{
+		Type:      AlertMinerFrozen,
+		MinerIP:   snap.MinerIP,
+		MinerName: name,
+		Message:   fmt.Sprintf("Miner has reported identical data for %v", time.Duration(snap.FrozenSecs)*time.Second),
+		Value:     float64(snap.FrozenSecs),
+		Timestamp: time.Now(),
+	})
 
-	// Check temperature
-	if e.config.TempAbove > 0 && snap.Temperature > e.config.TempAbove {
-		e.sendAlert(Alert{
+	// Check temperature (use the hotter sensor on multi-board units)
+	temp, tempLabel := snap.Temperature, "Temperature"
+	if snap.Temperature2 > temp {
+		temp, tempLabel = snap.Temperature2, "Temperature (sensor 2)"
+	}
+	if config.TempAbove > 0 && temp > config.TempAbove {
+		e.raise(Alert{
 			Type:      AlertTempHigh,
 			MinerIP:   snap.MinerIP,
-			MinerName: snap.Hostname,
-			Message:   fmt.Sprintf("Temperature is %.1f°C (threshold: %.1f°C)", snap.Temperature, e.config.TempAbove),
-			Value:     snap.Temperature,
+			MinerName: name,
+			Message:   fmt.Sprintf("%s is %s (threshold: %s)", tempLabel, config.Display.FormatTemperature(temp), config.Display.FormatTemperature(config.TempAbove)),
+			Value:     temp,
 			Timestamp: time.Now(),
 		})
+	} else if config.TempAbove <= 0 || temp <= config.TempAbove-tempHysteresis {
+		e.clear(snap.MinerIP, name, AlertTempHigh, tempNormalMessage(config, tempLabel, temp))
 	}
 
-	// Check hashrate drop
-	if lastHash, ok := e.lastHashrate[minerKey]; ok && lastHash > 0 {
-		dropPercent := ((lastHash - snap.HashRate) / lastHash) * 100
-		if e.config.HashrateDropPercent > 0 && dropPercent > e.config.HashrateDropPercent {
-			e.sendAlert(Alert{
-				Type:      AlertHashrateDrop,
-				MinerIP:   snap.MinerIP,
-				MinerName: snap.Hostname,
-				Message:   fmt.Sprintf("Hashrate dropped %.1f%% (%.2f GH/s -> %.2f GH/s)", dropPercent, lastHash, snap.HashRate),
-				Value:     dropPercent,
-				Timestamp: time.Now(),
-			})
-		}
+	// Check hashrate drop: the 10-minute average against the 10 minutes
+	// before, for several checks in a row so oscillation doesn't alert
+	now := snap.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	history, ok := e.hashrate[minerKey]
+	if !ok {
+		history = &hashrateHistory{}
+		e.hashrate[minerKey] = history
 	}
-	e.lastHashrate[minerKey] = snap.HashRate
+	history.add(now, snap.HashRate)
+	if current, previous, ok := history.averages(now); ok && previous > 0 {
+		dropPercent := ((previous - current) / previous) * 100
+		if config.HashrateDropPercent > 0 && dropPercent > config.HashrateDropPercent {
+			history.drops++
+		} else {
+			history.drops = 0
+		}
 
-	// Check fan RPM
-	if e.config.FanRPMBelow > 0 && snap.FanRPM < e.config.FanRPMBelow && snap.FanRPM > 0 {
-		e.sendAlert(Alert{
-			Type:      AlertFanLow,
+		checks := config.HashrateDropChecks
+		if checks <= 0 {
+			checks = defaultHashrateDropChecks
+		}
+		e.check(history.drops >= checks, Alert{
+			Type:      AlertHashrateDrop,
 			MinerIP:   snap.MinerIP,
-			MinerName: snap.Hostname,
-			Message:   fmt.Sprintf("Fan RPM is %d (threshold: %d)", snap.FanRPM, e.config.FanRPMBelow),
-			Value:     float64(snap.FanRPM),
+			MinerName: name,
+			Message:   fmt.Sprintf("10-minute average hashrate dropped %.1f%% (%s -> %s)", dropPercent, config.Display.FormatHashrate(previous), config.Display.FormatHashrate(current)),
+			Value:     dropPercent,
 			Timestamp: time.Now(),
 		})
 	}
 
-	// Check WiFi signal
-	if e.config.WifiSignalBelow < 0 && snap.WifiRSSI < e.config.WifiSignalBelow {
-		e.sendAlert(Alert{
-			Type:      AlertWifiWeak,
+	// Check the rejection rate of the last 30 minutes' submitted shares
+	rejects, ok := e.rejects[minerKey]
+	if !ok {
+		rejects = &rejectHistory{}
+		e.rejects[minerKey] = rejects
+	}
+	rejects.add(now, snap.SharesAccept, snap.SharesReject)
+	if rate, submitted, ok := rejects.rate(); ok {
+		e.check(config.ShareRejectPct > 0 && rate > config.ShareRejectPct, Alert{
+			Type:      AlertRejectRateHigh,
 			MinerIP:   snap.MinerIP,
-			MinerName: snap.Hostname,
-			Message:   fmt.Sprintf("WiFi signal is %d dBm (threshold: %d dBm)", snap.WifiRSSI, e.config.WifiSignalBelow),
-			Value:     float64(snap.WifiRSSI),
+			MinerName: name,
+			Message:   fmt.Sprintf("%.1f%% of the last %d shares were rejected (threshold: %.1f%%)", rate, submitted, config.ShareRejectPct),
+			Value:     rate,
 			Timestamp: time.Now(),
 		})
 	}
 
+	// Check pool difficulty jumps (0 means the pool hasn't set one yet)
+	if snap.PoolDiff > 0 {
+		if lastDiff, ok := e.lastPoolDiff[minerKey]; ok && lastDiff > 0 && lastDiff != snap.PoolDiff {
+			jump := poolDiffJumpPercent(lastDiff, snap.PoolDiff)
+			if config.PoolDiffChangePct > 0 && jump >= config.PoolDiffChangePct {
+				direction := "rose"
+				if snap.PoolDiff < lastDiff {
+					direction = "fell"
+				}
+				e.sendAlert(Alert{
+					Type:      AlertPoolDiffChange,
+					MinerIP:   snap.MinerIP,
+					MinerName: name,
+					Message:   fmt.Sprintf("Pool difficulty %s from %s to %s", direction, collector.FormatDifficulty(lastDiff), collector.FormatDifficulty(snap.PoolDiff)),
+					Value:     snap.PoolDiff,
+					Timestamp: time.Now(),
+				})
+			}
+		}
+		e.lastPoolDiff[minerKey] = snap.PoolDiff
+	}
+
+	// Check fan RPM (use the slowest reporting fan; 0 means no sensor)
+	fan, fanLabel := snap.FanRPM, "Fan RPM"
+	if snap.Fan2RPM > 0 && (fan <= 0 || snap.Fan2RPM < fan) {
+		fan, fanLabel = snap.Fan2RPM, "Fan 2 RPM"
+	}
+	e.check(config.FanRPMBelow > 0 && fan < config.FanRPMBelow && fan > 0, Alert{
+		Type:      AlertFanLow,
+		MinerIP:   snap.MinerIP,
+		MinerName: name,
+		Message:   fmt.Sprintf("%s is %d (threshold: %d)", fanLabel, fan, config.FanRPMBelow),
+		Value:     float64(fan),
+		Timestamp: time.Now(),
+	})
+
+	// Check WiFi signal
+	e.check(config.WifiSignalBelow < 0 && snap.WifiRSSI < config.WifiSignalBelow, Alert{
+		Type:      AlertWifiWeak,
+		MinerIP:   snap.MinerIP,
+		MinerName: name,
+		Message:   fmt.Sprintf("WiFi signal is %d dBm (threshold: %d dBm)", snap.WifiRSSI, config.WifiSignalBelow),
+		Value:     float64(snap.WifiRSSI),
+		Timestamp: time.Now(),
+	})
+
 	// Check pool connection
-	if e.config.OnPoolDisconnected && !snap.PoolConnected {
-		e.sendAlert(Alert{
-			Type:      AlertPoolDisconnected,
-			MinerIP:   snap.MinerIP,
-			MinerName: snap.Hostname,
-			Message:   "Pool disconnected",
-			Timestamp: time.Now(),
-		})
+	if !snap.PoolConnected {
+		if config.OnPoolDisconnected {
+			e.raise(Alert{
+				Type:      AlertPoolDisconnected,
+				MinerIP:   snap.MinerIP,
+				MinerName: name,
+				Message:   "Pool disconnected",
+				Timestamp: time.Now(),
+			})
+		}
+	} else {
+		e.clear(snap.MinerIP, name, AlertPoolDisconnected, poolReconnectedMessage)
 	}
 
 	// Check new best difficulty
-	if e.config.OnNewBestDiff {
+	if config.OnNewBestDiff {
 		if lastBest, ok := e.lastBestDiff[minerKey]; ok && snap.BestDiffSess > lastBest {
 			e.sendAlert(Alert{
 				Type:      AlertNewBestDiff,
 				MinerIP:   snap.MinerIP,
-				MinerName: snap.Hostname,
+				MinerName: name,
 				Message:   fmt.Sprintf("New best difficulty: %s", collector.FormatDifficulty(snap.BestDiffSess)),
 				Value:     snap.BestDiffSess,
 				Timestamp: time.Now(),
@@ -227,20 +639,41 @@ func (e *AlertEngine) CheckSnapshot(snap *storage.MinerSnapshot) {
 	e.lastBestDiff[minerKey] = snap.BestDiffSess
 }
 
+// poolDiffJumpPercent returns the size of a difficulty change relative to the
+// smaller value, so doubling and halving both count as 100%
+func poolDiffJumpPercent(from, to float64) float64 {
+	lo, hi := from, to
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return (hi/lo - 1) * 100
+}
+
 // CheckShare evaluates a share for rejected status
 func (e *AlertEngine) CheckShare(share *storage.Share, rejected bool) {
-	if !e.config.OnShareRejected || !rejected {
+	if !rejected {
 		return
 	}
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if !e.minerConfig(share.MinerIP).OnShareRejected {
+		return
+	}
+
+	message := "Share rejected"
+	if share.Difficulty > 0 {
+		message += fmt.Sprintf(" (diff: %s)", collector.FormatDifficulty(share.Difficulty))
+	}
+	if share.RejectReason != "" {
+		message += ": " + share.RejectReason
+	}
 	e.sendAlert(Alert{
 		Type:      AlertShareRejected,
 		MinerIP:   share.MinerIP,
-		MinerName: share.Hostname,
-		Message:   fmt.Sprintf("Share rejected (diff: %s)", collector.FormatDifficulty(share.Difficulty)),
+		MinerName: e.minerName(share.MinerIP, share.Hostname),
+		Message:   message,
 		Value:     share.Difficulty,
 		Timestamp: time.Now(),
 	})
@@ -249,16 +682,13 @@ func (e *AlertEngine) CheckShare(share *storage.Share, rejected bool) {
 // CheckBlock sends an alert when a block is found. No cooldown — blocks are rare events.
 func (e *AlertEngine) CheckBlock(block *storage.Block) {
 	e.mu.RLock()
-	webhookURL := e.config.WebhookURL
-	enabled := e.config.OnBlockFound
+	config := e.minerConfig(block.MinerIP)
+	name := e.minerName(block.MinerIP, block.Hostname)
+	store := e.store
+	onAlert := e.onAlert
 	e.mu.RUnlock()
 
-	if !enabled {
-		return
-	}
-
-	if webhookURL == "" {
-		log.Printf("Alert [block_found] %s: Block found on %s!", block.Hostname, block.CoinSymbol)
+	if !config.OnBlockFound {
 		return
 	}
 
@@ -270,11 +700,11 @@ func (e *AlertEngine) CheckBlock(block *storage.Block) {
 	alert := Alert{
 		Type:      AlertBlockFound,
 		MinerIP:   block.MinerIP,
-		MinerName: block.Hostname,
+		MinerName: name,
 		Message:   fmt.Sprintf("Block found mining %s!", block.CoinSymbol),
 		Timestamp: block.Timestamp,
 		Fields: []map[string]interface{}{
-			{"name": "Miner", "value": block.Hostname, "inline": true},
+			{"name": "Miner", "value": name, "inline": true},
 			{"name": "Coin", "value": block.CoinSymbol, "inline": true},
 			{"name": "Reward", "value": fmt.Sprintf("%.4f %s", block.BlockReward, block.CoinSymbol), "inline": true},
 			{"name": "Value", "value": valueStr, "inline": true},
@@ -282,16 +712,14 @@ func (e *AlertEngine) CheckBlock(block *storage.Block) {
 		},
 	}
 
-	body, err := buildDiscordPayload(alert)
-	if err != nil {
-		log.Printf("Failed to marshal Discord payload: %v", err)
-		return
+	recordAlert(store, alert)
+	if onAlert != nil {
+		onAlert(alert)
 	}
-
-	go e.postWebhook(webhookURL, body)
+	e.deliver(config, alert)
 }
 
-// CheckLeaderChange checks if a share makes a new weekly leader in the best-share competition.
+// CheckLeaderChange checks if a share makes a new leader of the current period in the best-share competition.
 func (e *AlertEngine) CheckLeaderChange(share *storage.Share) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -300,69 +728,105 @@ func (e *AlertEngine) CheckLeaderChange(share *storage.Share) {
 		return
 	}
 
-	// Reset if the week has changed
-	ws := currentWeekStart()
-	if ws.After(e.weekStart) {
-		e.weeklyBestDiff = 0
-		e.weeklyLeader = ""
+	// Reset if the competition period has changed
+	ws := e.config.Competition.Start(time.Now())
+	if !ws.Equal(e.weekStart) {
+		e.weeklyBest = make(map[string]periodBest)
+		e.weeklyLeaderIP = ""
 		e.weekStart = ws
 	}
 
-	if share.Difficulty <= e.weeklyBestDiff {
+	// Only a miner beating its own best can change its score
+	if best, ok := e.weeklyBest[share.MinerIP]; ok && share.Difficulty <= best.diff {
 		return
 	}
+	e.weeklyBest[share.MinerIP] = periodBest{diff: share.Difficulty, hostname: share.Hostname}
 
-	previousLeader := e.weeklyLeader
-	e.weeklyBestDiff = share.Difficulty
-	e.weeklyLeader = share.Hostname
-
-	// Only alert when a *different* miner takes the lead (and there was a previous leader)
-	if previousLeader == "" || previousLeader == share.Hostname {
-		return
-	}
+	previousLeaderIP := e.weeklyLeaderIP
+	leaderIP, scorer := e.periodLeader()
+	e.weeklyLeaderIP = leaderIP
 
-	if e.config.WebhookURL == "" {
-		log.Printf("Alert [new_leader] %s took the lead from %s (diff: %.2f)", share.Hostname, previousLeader, share.Difficulty)
+	// Only alert when the share makes a *different* miner the leader (and there was a previous leader).
+	// Compare by IP so renaming the leader doesn't look like a takeover.
+	if previousLeaderIP == "" || leaderIP != share.MinerIP || previousLeaderIP == share.MinerIP {
 		return
 	}
 
+	name := e.minerName(share.MinerIP, share.Hostname)
+	previousLeader := e.minerName(previousLeaderIP, e.weeklyBest[previousLeaderIP].hostname)
 	alert := Alert{
 		Type:      AlertNewLeader,
 		MinerIP:   share.MinerIP,
-		MinerName: share.Hostname,
-		Message:   fmt.Sprintf("%s is the new weekly leader!", share.Hostname),
+		MinerName: name,
+		Message:   fmt.Sprintf("%s is the new %s leader!", name, e.config.Competition.Adjective()),
 		Timestamp: share.Timestamp,
 		Fields: []map[string]interface{}{
-			{"name": "New Leader", "value": share.Hostname, "inline": true},
+			{"name": "New Leader", "value": name, "inline": true},
 			{"name": "Share Difficulty", "value": collector.FormatDifficulty(share.Difficulty), "inline": true},
 			{"name": "Previous Leader", "value": previousLeader, "inline": true},
 		},
 	}
+	if scorer.Handicapped() {
+		alert.Fields = append(alert.Fields, map[string]interface{}{
+			"name": "Score", "value": collector.FormatDifficulty(scorer.Score(share.MinerIP, share.Difficulty)) + " per TH/s", "inline": true,
+		})
+	}
+	if share.NetworkPct > 0 {
+		alert.Fields = append(alert.Fields, map[string]interface{}{
+			"name": "Of a Block", "value": fmt.Sprintf("%.4g%%", share.NetworkPct), "inline": true,
+		})
+	}
 
-	body, err := buildDiscordPayload(alert)
-	if err != nil {
-		log.Printf("Failed to marshal Discord payload: %v", err)
-		return
+	recordAlert(e.store, alert)
+	if e.onAlert != nil {
+		e.onAlert(alert)
 	}
+	e.deliver(e.minerConfig(share.MinerIP), alert)
+}
 
-	go e.postWebhook(e.config.WebhookURL, body)
+// periodBest is a miner's best share of the current competition period
+type periodBest struct {
+	diff     float64
+	hostname string
 }
 
-// CheckOffline checks for miners that haven't been seen recently
-func (e *AlertEngine) CheckOffline(miners []*storage.Miner) {
-	if e.config.MinerOfflineSeconds <= 0 {
-		return
+// periodLeader returns the IP of the miner leading the current competition
+// period under the configured scoring, and the scorer it was ranked with.
+// The current leader keeps the lead on a tie. The caller must hold mu.
+func (e *AlertEngine) periodLeader() (string, competition.Scorer) {
+	scorer := competition.Scorer{Mode: e.config.CompetitionScoring, Expected: e.config.ExpectedHashrates}
+	if scorer.Handicapped() && e.store != nil {
+		var err error
+		if scorer, err = e.store.CompetitionScorer(scorer.Mode, scorer.Expected, e.weekStart, time.Now()); err != nil {
+			log.Printf("Failed to get average hashrates for the competition: %v", err)
+		}
 	}
 
+	var leader string
+	var top float64
+	for ip, best := range e.weeklyBest {
+		score := scorer.Score(ip, best.diff)
+		if leader == "" || score > top || score == top && (ip == e.weeklyLeaderIP || leader != e.weeklyLeaderIP && ip < leader) {
+			leader, top = ip, score
+		}
+	}
+	return leader, scorer
+}
+
+// CheckOffline checks for miners that haven't been seen recently
+func (e *AlertEngine) CheckOffline(miners []*storage.Miner) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	threshold := time.Duration(e.config.MinerOfflineSeconds) * time.Second
-
 	for _, miner := range miners {
 		if !miner.Enabled {
 			continue
 		}
+		config := e.minerConfig(miner.IP)
+		if config.MinerOfflineSeconds <= 0 {
+			continue
+		}
+		threshold := time.Duration(config.MinerOfflineSeconds) * time.Second
 
 		lastSeen, ok := e.lastSeen[miner.IP]
 		if !ok {
@@ -370,58 +834,248 @@ func (e *AlertEngine) CheckOffline(miners []*storage.Miner) {
 		}
 
 		if time.Since(lastSeen) > threshold {
-			e.sendAlert(Alert{
+			e.raise(Alert{
 				Type:      AlertMinerOffline,
 				MinerIP:   miner.IP,
-				MinerName: miner.Hostname,
-				Message:   fmt.Sprintf("Miner offline for %v", time.Since(lastSeen).Round(time.Second)),
+				MinerName: e.minerName(miner.IP, miner.Hostname),
+				Message:   fmt.Sprintf("Miner offline for %v (last seen %s)", time.Since(lastSeen).Round(time.Second), config.Display.FormatTime(lastSeen)),
 				Timestamp: time.Now(),
 			})
 		}
 	}
 }
 
-// SendTestAlert sends a test message to the configured Discord webhook.
-// It bypasses cooldown and runs synchronously so the caller gets immediate feedback.
-func (e *AlertEngine) SendTestAlert() error {
+// CheckFirmware alerts on miners running a different firmware than the rest
+// of their model group. Each miner/version pair is only alerted once.
+func (e *AlertEngine) CheckFirmware(groups []collector.FirmwareGroup) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.config.OnFirmwareMismatch {
+		return
+	}
+
+	mismatched := make(map[string]bool)
+	for _, g := range groups {
+		for _, o := range g.Outliers {
+			mismatched[o.IP] = true
+			if e.firmwareAlerted[o.IP] == o.Version {
+				continue
+			}
+			e.firmwareAlerted[o.IP] = o.Version
+			e.sendAlert(Alert{
+				Type:      AlertFirmwareMismatch,
+				MinerIP:   o.IP,
+				MinerName: e.minerName(o.IP, o.Hostname),
+				Message:   fmt.Sprintf("Running firmware %s while %d other %s miner(s) run %s", o.Version, g.Versions[g.Expected], g.DeviceModel, g.Expected),
+				Timestamp: time.Now(),
+			})
+		}
+	}
+
+	// Forget miners that have been brought in line so a later regression alerts again
+	for ip := range e.firmwareAlerted {
+		if !mismatched[ip] {
+			delete(e.firmwareAlerted, ip)
+		}
+	}
+}
+
+// WatchdogAction alerts on a restart issued by the watchdog, or on the
+// watchdog giving up on a miner. No cooldown — the watchdog's backoff already
+// spaces its actions out.
+func (e *AlertEngine) WatchdogAction(minerIP, hostname, message string, restarts int) {
 	e.mu.RLock()
-	webhookURL := e.config.WebhookURL
+	config := e.minerConfig(minerIP)
+	name := e.minerName(minerIP, hostname)
+	store := e.store
+	onAlert := e.onAlert
 	e.mu.RUnlock()
 
-	if webhookURL == "" {
-		return fmt.Errorf("webhook URL is not configured")
+	alert := Alert{
+		Type:      AlertWatchdogRestart,
+		MinerIP:   minerIP,
+		MinerName: name,
+		Message:   message,
+		Value:     float64(restarts),
+		Timestamp: time.Now(),
 	}
 
-	payload := map[string]interface{}{
-		"embeds": []map[string]interface{}{
-			{
-				"title":       "✅ Test Alert",
-				"description": "This is a test alert from MinerHQ. If you see this message, your Discord webhook is configured correctly!",
-				"color":       0x00FF88,
-				"timestamp":   time.Now().Format(time.RFC3339),
-				"footer": map[string]string{
-					"text": "MinerHQ Alert System — Test",
-				},
-			},
+	recordAlert(store, alert)
+	if onAlert != nil {
+		onAlert(alert)
+	}
+	e.deliver(config, alert)
+}
+
+// SendDigest sends the summary of a finished competition period to every
+// channel, unless digests are turned off. No cooldown — there is one per
+// period.
+func (e *AlertEngine) SendDigest(message string, fields []map[string]interface{}) {
+	e.mu.RLock()
+	config := e.config
+	store := e.store
+	onAlert := e.onAlert
+	e.mu.RUnlock()
+
+	if !config.OnDigest {
+		return
+	}
+
+	alert := Alert{
+		Type:      AlertDigest,
+		Message:   message,
+		Timestamp: time.Now(),
+		Fields:    fields,
+	}
+
+	recordAlert(store, alert)
+	if onAlert != nil {
+		onAlert(alert)
+	}
+	e.deliver(config, alert)
+}
+
+// PoolCheck alerts when a pool has failed MinerHQ's own checks, or answers
+// them again after it did. miners names the miners configured for the pool.
+// No cooldown — the pool checker only reports changes.
+func (e *AlertEngine) PoolCheck(pool string, miners []string, reachable bool, message string) {
+	e.mu.RLock()
+	config := e.config
+	store := e.store
+	onAlert := e.onAlert
+	e.mu.RUnlock()
+
+	alertType := AlertPoolUnreachable
+	if reachable {
+		alertType = AlertPoolReachable
+	}
+	if (reachable && !config.OnRecovery) || (!reachable && !config.OnPoolUnreachable) {
+		return
+	}
+
+	alert := Alert{
+		Type:      alertType,
+		MinerName: pool,
+		Message:   message,
+		Timestamp: time.Now(),
+		Fields: []map[string]interface{}{
+			{"name": "Pool", "value": pool, "inline": false},
+			{"name": "Miners", "value": strings.Join(miners, ", "), "inline": false},
 		},
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+	recordAlert(store, alert)
+	if onAlert != nil {
+		onAlert(alert)
 	}
+	e.deliver(config, alert)
+}
 
-	resp, err := e.client.Post(webhookURL, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to send webhook: %w", err)
+// PoolMismatch alerts when a pool has credited a miner's worker with a
+// hashrate far from the miner's own for several fetches in a row, or with
+// one close to it again after it did. No cooldown — the pool stats only
+// report changes. divergencePct is the pool's hashrate against the miner's,
+// in % of the miner's.
+func (e *AlertEngine) PoolMismatch(minerIP, hostname, pool, worker string, poolHashRate, minerHashRate, divergencePct float64, mismatch bool, message string) {
+	e.mu.RLock()
+	config := e.minerConfig(minerIP)
+	name := e.minerName(minerIP, hostname)
+	store := e.store
+	onAlert := e.onAlert
+	e.mu.RUnlock()
+
+	alertType := AlertPoolMismatch
+	if !mismatch {
+		alertType = AlertPoolMatch
+	}
+	if (!mismatch && !config.OnRecovery) || (mismatch && !config.OnPoolMismatch) {
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("discord returned status %d", resp.StatusCode)
+	alert := Alert{
+		Type:      alertType,
+		MinerIP:   minerIP,
+		MinerName: name,
+		Message:   message,
+		Value:     divergencePct,
+		Timestamp: time.Now(),
+		Fields: []map[string]interface{}{
+			{"name": "Pool", "value": pool, "inline": true},
+			{"name": "Worker", "value": worker, "inline": true},
+			{"name": "Pool Hashrate", "value": config.Display.FormatHashrate(poolHashRate), "inline": true},
+			{"name": "Miner Hashrate", "value": config.Display.FormatHashrate(minerHashRate), "inline": true},
+		},
 	}
 
-	return nil
+	recordAlert(store, alert)
+	if onAlert != nil {
+		onAlert(alert)
+	}
+	e.deliver(config, alert)
+}
+
+// SendTestAlert sends a test message to the configured Discord webhook,
+// Matrix room and other channels. It bypasses cooldown and runs synchronously
+// so the caller gets immediate feedback. Every channel is tried even if one
+// fails, and the errors of all that failed are returned.
+func (e *AlertEngine) SendTestAlert() error {
+	e.mu.RLock()
+	config := e.config
+	e.mu.RUnlock()
+
+	if config.WebhookURL == "" && !config.matrixConfigured() && len(config.Notifiers) == 0 {
+		return fmt.Errorf("no alert channel is configured")
+	}
+
+	var errs []error
+	if err := e.notifyNow(config, Alert{
+		Type:      alertTest,
+		Message:   "This is a test alert from MinerHQ. If you see this message, this channel is configured correctly!",
+		Timestamp: time.Now(),
+		Fields:    []map[string]interface{}{},
+	}, ""); err != nil {
+		errs = append(errs, err)
+	}
+
+	if config.matrixConfigured() {
+		body, err := buildMatrixMessage(Alert{
+			Type:      alertTest,
+			Message:   "This is a test alert from MinerHQ. If you see this message, your Matrix room is configured correctly!",
+			Timestamp: time.Now(),
+			Fields:    []map[string]interface{}{},
+		}, config.Display)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to marshal Matrix payload: %w", err))
+		} else if err := e.postMatrix(config, newMatrixTxnID(), body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if config.WebhookURL != "" {
+		payload := map[string]interface{}{
+			"embeds": []map[string]interface{}{
+				{
+					"title":       "✅ Test Alert",
+					"description": "This is a test alert from MinerHQ. If you see this message, your Discord webhook is configured correctly!",
+					"color":       0x00FF88,
+					"timestamp":   time.Now().Format(time.RFC3339),
+					"footer": map[string]string{
+						"text": "MinerHQ Alert System — Test",
+					},
+				},
+			},
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to marshal Discord payload: %w", err))
+		} else if err := e.postWebhook(config.WebhookURL, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // validAlertTypes is the set of all supported alert types for test alerts
@@ -436,17 +1090,31 @@ var validAlertTypes = map[AlertType]bool{
 	AlertNewBestDiff:      true,
 	AlertBlockFound:       true,
 	AlertNewLeader:        true,
+	AlertFirmwareMismatch: true,
+	AlertMinerFrozen:      true,
+	AlertPoolDiffChange:   true,
+	AlertRejectRateHigh:   true,
+	AlertWatchdogRestart:  true,
+	AlertDigest:           true,
+	AlertPoolUnreachable:  true,
+	AlertPoolMismatch:     true,
+	AlertMinerOnline:      true,
+	AlertPoolReconnected:  true,
+	AlertTempNormal:       true,
+	AlertPoolReachable:    true,
+	AlertPoolMatch:        true,
 }
 
-// SendTestAlertByType sends a sample alert for the given type.
-// Bypasses cooldown and runs synchronously.
+// SendTestAlertByType sends a sample alert for the given type to every
+// channel that wants it. Bypasses cooldown and runs synchronously; every
+// channel is tried even if one fails.
 func (e *AlertEngine) SendTestAlertByType(alertType string) error {
 	e.mu.RLock()
-	webhookURL := e.config.WebhookURL
+	config := e.config
 	e.mu.RUnlock()
 
-	if webhookURL == "" {
-		return fmt.Errorf("webhook URL is not configured")
+	if config.WebhookURL == "" && !config.matrixConfigured() && len(config.Notifiers) == 0 {
+		return fmt.Errorf("no alert channel is configured")
 	}
 
 	at := AlertType(alertType)
@@ -456,22 +1124,30 @@ func (e *AlertEngine) SendTestAlertByType(alertType string) error {
 
 	alert := buildSampleAlert(at)
 
-	body, err := buildDiscordPayload(alert)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+	var errs []error
+	if err := e.notifyNow(config, alert, at); err != nil {
+		errs = append(errs, err)
 	}
 
-	resp, err := e.client.Post(webhookURL, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to send webhook: %w", err)
+	if config.matrixConfigured() {
+		body, err := buildMatrixMessage(alert, config.Display)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to marshal Matrix payload: %w", err))
+		} else if err := e.postMatrix(config, newMatrixTxnID(), body); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("discord returned status %d", resp.StatusCode)
+	if config.WebhookURL != "" {
+		body, err := buildDiscordPayload(alert)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to marshal Discord payload: %w", err))
+		} else if err := e.postWebhook(config.WebhookURL, body); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // buildSampleAlert creates a realistic sample alert for testing
@@ -490,10 +1166,10 @@ func buildSampleAlert(t AlertType) Alert {
 		base.Message = "Temperature is 72.5°C (threshold: 65.0°C)"
 		base.Value = 72.5
 	case AlertHashrateDrop:
-		base.Message = "Hashrate dropped 45.2% (580.00 GH/s -> 318.00 GH/s)"
+		base.Message = "10-minute average hashrate dropped 45.2% (580.00 GH/s -> 318.00 GH/s)"
 		base.Value = 45.2
 	case AlertShareRejected:
-		base.Message = "Share rejected (diff: 1024.50)"
+		base.Message = "Share rejected (diff: 1.02K): Stale"
 		base.Value = 1024.50
 	case AlertPoolDisconnected:
 		base.Message = "Pool disconnected"
@@ -522,31 +1198,100 @@ func buildSampleAlert(t AlertType) Alert {
 			{"name": "Share Difficulty", "value": "4.29G", "inline": true},
 			{"name": "Previous Leader", "value": "BitAxe-Supra", "inline": true},
 		}
+	case AlertFirmwareMismatch:
+		base.Message = "Running firmware v2.4.1 while 5 other AxeOS (BM1370) miner(s) run v2.5.0"
+	case AlertMinerFrozen:
+		base.Message = "Miner has reported identical data for 5m0s"
+		base.Value = 300
+	case AlertPoolDiffChange:
+		base.Message = "Pool difficulty rose from 1.02K to 8.19K"
+		base.Value = 8192
+	case AlertRejectRateHigh:
+		base.Message = "7.5% of the last 40 shares were rejected (threshold: 5.0%)"
+		base.Value = 7.5
+	case AlertWatchdogRestart:
+		base.Message = "Restarted after 15m0s at 0 GH/s (restart 1 of 3 today)"
+		base.Value = 1
+	case AlertDigest:
+		base.MinerIP, base.MinerName = "", ""
+		base.Message = "Weekly summary from Oct 5 to Oct 11"
+		base.Fields = []map[string]interface{}{
+			{"name": "Shares", "value": "48210", "inline": true},
+			{"name": "Best Share", "value": "4.29G", "inline": true},
+			{"name": "Blocks", "value": "1", "inline": true},
+			{"name": "Winner", "value": "BitAxe-Ultra", "inline": true},
+			{"name": "Energy", "value": "18.4 kWh (2.21 USD)", "inline": true},
+			{"name": "Uptime", "value": "BitAxe-Ultra: 100.0%\nBitAxe-Supra: 97.2% (2 outages)", "inline": false},
+		}
+	case AlertPoolUnreachable:
+		base.MinerIP, base.MinerName = "", "stratum+tcp://public-pool.io:21496"
+		base.Message = "Failed 3 checks in a row from MinerHQ: dial tcp: i/o timeout"
+		base.Fields = []map[string]interface{}{
+			{"name": "Pool", "value": "stratum+tcp://public-pool.io:21496", "inline": false},
+			{"name": "Miners", "value": "BitAxe-Ultra, BitAxe-Supra", "inline": false},
+		}
+	case AlertPoolMismatch:
+		base.Message = "ckpool credits worker bitaxe-ultra with 612.40 GH/s, 38.5% below the miner's 995.80 GH/s, for 3 fetches in a row"
+		base.Value = -38.5
+		base.Fields = []map[string]interface{}{
+			{"name": "Pool", "value": "ckpool", "inline": true},
+			{"name": "Worker", "value": "bitaxe-ultra", "inline": true},
+			{"name": "Pool Hashrate", "value": "612.40 GH/s", "inline": true},
+			{"name": "Miner Hashrate", "value": "995.80 GH/s", "inline": true},
+		}
+	case AlertMinerOnline:
+		base.Message = "Miner is back online after 12m40s"
+		base.Value = 760
+	case AlertPoolReconnected:
+		base.Message = "Pool reconnected after 3m10s"
+		base.Value = 190
+	case AlertTempNormal:
+		base.Message = "Temperature is back to 61.0°C (threshold: 65.0°C) after 8m0s"
+		base.Value = 480
+	case AlertPoolReachable:
+		base.MinerIP, base.MinerName = "", "stratum+tcp://public-pool.io:21496"
+		base.Message = "Answers MinerHQ's checks again after 6m0s (38 ms)"
+		base.Value = 360
+		base.Fields = []map[string]interface{}{
+			{"name": "Pool", "value": "stratum+tcp://public-pool.io:21496", "inline": false},
+			{"name": "Miners", "value": "BitAxe-Ultra, BitAxe-Supra", "inline": false},
+		}
+	case AlertPoolMatch:
+		base.Message = "ckpool credits worker bitaxe-ultra with 981.20 GH/s again, 1.5% below the miner's 995.80 GH/s"
+		base.Value = -1.5
+		base.Fields = []map[string]interface{}{
+			{"name": "Pool", "value": "ckpool", "inline": true},
+			{"name": "Worker", "value": "bitaxe-ultra", "inline": true},
+			{"name": "Pool Hashrate", "value": "981.20 GH/s", "inline": true},
+			{"name": "Miner Hashrate", "value": "995.80 GH/s", "inline": true},
+		}
 	}
 
 	return base
 }
 
+// alertFields returns the alert's custom fields, or the default Miner + IP fields
+func alertFields(alert Alert) []map[string]interface{} {
+	if alert.Fields != nil {
+		return alert.Fields
+	}
+	return []map[string]interface{}{
+		{"name": "Miner", "value": alert.MinerName, "inline": true},
+		{"name": "IP", "value": alert.MinerIP, "inline": true},
+	}
+}
+
 // buildDiscordPayload builds the JSON body for a Discord webhook embed.
 func buildDiscordPayload(alert Alert) ([]byte, error) {
 	d := getAlertDisplay(alert.Type)
 
-	// Use custom fields if provided, otherwise default Miner + IP fields
-	fields := alert.Fields
-	if fields == nil {
-		fields = []map[string]interface{}{
-			{"name": "Miner", "value": alert.MinerName, "inline": true},
-			{"name": "IP", "value": alert.MinerIP, "inline": true},
-		}
-	}
-
 	payload := map[string]interface{}{
 		"embeds": []map[string]interface{}{
 			{
 				"title":       fmt.Sprintf("%s %s", d.Emoji, d.Title),
 				"description": alert.Message,
 				"color":       d.Color,
-				"fields":      fields,
+				"fields":      alertFields(alert),
 				"timestamp":   alert.Timestamp.Format(time.RFC3339),
 				"footer": map[string]string{
 					"text": "MinerHQ Alert System",
@@ -558,41 +1303,119 @@ func buildDiscordPayload(alert Alert) ([]byte, error) {
 	return json.Marshal(payload)
 }
 
-// sendAlert sends an alert via Discord webhook (with cooldown)
-func (e *AlertEngine) sendAlert(alert Alert) {
-	// Check cooldown (5 minute cooldown per alert type per miner)
+// sendAlert sends an alert to the miner's channels (with cooldown)
+func (e *AlertEngine) sendAlert(alert Alert) bool {
+	// Check cooldown (5 minutes per alert type per miner unless overridden)
 	cooldownKey := fmt.Sprintf("%s:%s", alert.MinerIP, alert.Type)
 	if lastAlert, ok := e.alertCooldown[cooldownKey]; ok {
-		if time.Since(lastAlert) < 5*time.Minute {
-			return
+		if time.Since(lastAlert) < e.cooldown(alert.MinerIP) {
+			return false
 		}
 	}
 	e.alertCooldown[cooldownKey] = time.Now()
 
-	if e.config.WebhookURL == "" {
-		log.Printf("Alert [%s] %s: %s", alert.Type, alert.MinerName, alert.Message)
+	e.emit(alert)
+	return true
+}
+
+// emit records, broadcasts and delivers an alert without a cooldown check
+func (e *AlertEngine) emit(alert Alert) {
+	recordAlert(e.store, alert)
+	if e.onAlert != nil {
+		e.onAlert(alert)
+	}
+	e.deliver(e.minerConfig(alert.MinerIP), alert)
+}
+
+// recordAlert adds an alert to the alert history, if there is a store
+func recordAlert(store *storage.SQLiteStorage, alert Alert) {
+	if store == nil {
 		return
 	}
+	entry := &storage.AlertEntry{
+		Timestamp: alert.Timestamp,
+		Type:      string(alert.Type),
+		MinerIP:   alert.MinerIP,
+		MinerName: alert.MinerName,
+		Message:   alert.Message,
+		Value:     alert.Value,
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if err := store.InsertAlert(entry); err != nil {
+		log.Printf("Failed to record alert [%s] %s: %v", alert.Type, alert.MinerName, err)
+	}
+}
 
-	body, err := buildDiscordPayload(alert)
-	if err != nil {
-		log.Printf("Failed to marshal Discord payload: %v", err)
+// deliver sends an alert to its channels, unless quiet hours or a
+// maintenance window hold it back
+func (e *AlertEngine) deliver(config *AlertConfig, alert Alert) {
+	if e.hold(config, alert, time.Now()) {
 		return
 	}
+	e.send(config, alert)
+}
 
-	go e.postWebhook(e.config.WebhookURL, body)
+// send sends an alert to every configured channel in the background,
+// retrying failures, or logs it when no channel is configured
+func (e *AlertEngine) send(config *AlertConfig, alert Alert) {
+	if config.WebhookURL == "" && !config.matrixConfigured() && len(config.Notifiers) == 0 {
+		log.Printf("Alert [%s] %s: %s", alert.Type, alert.MinerName, alert.Message)
+		return
+	}
+
+	for _, nc := range config.Notifiers {
+		if notifies(nc, alert.Type) {
+			e.notify(nc, alert)
+		}
+	}
+
+	if config.WebhookURL != "" {
+		body, err := buildDiscordPayload(alert)
+		if err != nil {
+			log.Printf("Failed to marshal Discord payload: %v", err)
+		} else {
+			e.enqueueDiscord(discordItem{url: config.WebhookURL, alert: alert, body: body})
+		}
+	}
+
+	if config.matrixConfigured() {
+		body, err := buildMatrixMessage(alert, config.Display)
+		if err != nil {
+			log.Printf("Failed to marshal Matrix message: %v", err)
+		} else {
+			// Reuse the transaction ID across retries so the homeserver
+			// drops duplicates of a send that succeeded but timed out
+			txnID := newMatrixTxnID()
+			go func() {
+				attempts, err := e.sendWithRetry(ChannelMatrix, fmt.Sprintf("[%s] %s", alert.Type, alert.MinerName), func() error {
+					return e.postMatrix(config, txnID, body)
+				})
+				if err != nil {
+					e.deadLetter(ChannelMatrix, alert, body, attempts, err)
+				}
+			}()
+		}
+	}
 }
 
-// postWebhook posts a payload to the given webhook URL
-func (e *AlertEngine) postWebhook(url string, body []byte) {
+// postWebhook posts a payload to the given webhook URL, first waiting out
+// any Discord rate limit
+func (e *AlertEngine) postWebhook(url string, body []byte) error {
+	e.discordLimit.wait()
+
 	resp, err := e.client.Post(url, "application/json", bytes.NewReader(body))
 	if err != nil {
-		log.Printf("Failed to send Discord webhook: %v", err)
-		return
+		return fmt.Errorf("failed to send webhook: %w", err)
 	}
 	defer resp.Body.Close()
 
+	e.discordLimit.update(resp)
+
 	if resp.StatusCode >= 400 {
-		log.Printf("Discord webhook returned status %d", resp.StatusCode)
+		return &statusError{channel: ChannelDiscord, code: resp.StatusCode}
 	}
+
+	return nil
 }
@@ -0,0 +1,80 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/competition"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestCheckLeaderChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		scoring  string
+		expected map[string]float64
+		shares   []storage.Share // The last one alone makes a new leader
+	}{
+		{"raw", competition.Raw, nil, []storage.Share{
+			{MinerIP: "10.0.0.1", Difficulty: 100},
+			{MinerIP: "10.0.0.2", Difficulty: 50},
+			{MinerIP: "10.0.0.1", Difficulty: 150},
+			{MinerIP: "10.0.0.2", Difficulty: 200},
+		}},
+		// 10.0.0.1 scores 100 / 0.1 TH/s, 10.0.0.2 200 then 2000 / 1 TH/s
+		{"expected", competition.Expected, map[string]float64{"10.0.0.1": 100, "10.0.0.2": 1000}, []storage.Share{
+			{MinerIP: "10.0.0.1", Difficulty: 100},
+			{MinerIP: "10.0.0.2", Difficulty: 200},
+			{MinerIP: "10.0.0.2", Difficulty: 2000},
+		}},
+		// 10.0.0.1 averages 1 TH/s and 10.0.0.2 0.1 TH/s over the period
+		{"average", competition.Average, nil, []storage.Share{
+			{MinerIP: "10.0.0.1", Difficulty: 100},
+			{MinerIP: "10.0.0.2", Difficulty: 5},
+			{MinerIP: "10.0.0.2", Difficulty: 20},
+		}},
+	}
+
+	for _, tt := range tests {
+		e, sent := recordingEngine(&AlertConfig{OnNewLeader: true, CompetitionScoring: tt.scoring, ExpectedHashrates: tt.expected})
+		store := testStore(t)
+		e.SetStore(store)
+		for _, snap := range []*storage.MinerSnapshot{
+			{MinerIP: "10.0.0.1", Timestamp: e.weekStart, HashRate: 1000},
+			{MinerIP: "10.0.0.2", Timestamp: e.weekStart, HashRate: 100},
+		} {
+			if err := store.InsertSnapshot(snap); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i := range tt.shares {
+			share := tt.shares[i]
+			share.Timestamp = time.Now()
+			e.CheckLeaderChange(&share)
+			if i < len(tt.shares)-1 && len(*sent) != 0 {
+				t.Fatalf("%s: share %d sent %v, want no alert", tt.name, i, *sent)
+			}
+		}
+		if len(*sent) != 1 || (*sent)[0] != AlertNewLeader || e.weeklyLeaderIP != "10.0.0.2" {
+			t.Errorf("%s: sent %v with leader %s, want 10.0.0.2 to take the lead", tt.name, *sent, e.weeklyLeaderIP)
+		}
+	}
+}
+
+func TestInitWeeklyLeader(t *testing.T) {
+	e, sent := recordingEngine(&AlertConfig{OnNewLeader: true})
+	e.InitWeeklyLeader([]*storage.LeaderboardEntry{
+		{MinerIP: "10.0.0.1", Hostname: "alpha", BestDiff: 100},
+		{MinerIP: "10.0.0.2", Hostname: "beta", BestDiff: 80},
+	})
+	if e.weeklyLeaderIP != "10.0.0.1" {
+		t.Fatalf("expected alpha to lead, got %q", e.weeklyLeaderIP)
+	}
+
+	// A restart doesn't make the next share a new leader
+	e.CheckLeaderChange(&storage.Share{MinerIP: "10.0.0.2", Hostname: "beta", Difficulty: 90})
+	if len(*sent) != 0 {
+		t.Errorf("sent %v, want no alert below the seeded best", *sent)
+	}
+}
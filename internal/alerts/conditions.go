@@ -0,0 +1,143 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// tempHysteresis is how far below the threshold a miner must cool before its
+// high temperature clears, so a miner hovering at the limit doesn't flap
+const tempHysteresis = 2.0
+
+// condition is an ongoing problem with a miner. It is alerted once when it
+// opens rather than every cooldown window, and cleared when the miner is
+// healthy again.
+type condition struct {
+	since    time.Time
+	notified bool // The opening alert was sent rather than held back by cooldown
+}
+
+// recoveries maps conditions to the alert sent when they clear. Other
+// conditions clear silently.
+var recoveries = map[AlertType]AlertType{
+	AlertMinerOffline:     AlertMinerOnline,
+	AlertPoolDisconnected: AlertPoolReconnected,
+	AlertTempHigh:         AlertTempNormal,
+}
+
+// raise opens a condition and alerts, unless it's already open
+func (e *AlertEngine) raise(alert Alert) {
+	conditions, ok := e.open[alert.MinerIP]
+	if !ok {
+		conditions = make(map[AlertType]*condition)
+		e.open[alert.MinerIP] = conditions
+	}
+	if _, ok := conditions[alert.Type]; ok {
+		return
+	}
+
+	c := &condition{since: time.Now()}
+	conditions[alert.Type] = c
+	c.notified = e.sendAlert(alert)
+}
+
+// clear closes a condition if it's open. If its opening alert was sent and
+// the condition has a recovery, the recovery is sent with the message built
+// from how long the condition lasted.
+func (e *AlertEngine) clear(minerIP, minerName string, t AlertType, message func(lasted time.Duration) string) {
+	c, ok := e.open[minerIP][t]
+	if !ok {
+		return
+	}
+	delete(e.open[minerIP], t)
+	if len(e.open[minerIP]) == 0 {
+		delete(e.open, minerIP)
+	}
+
+	recovery, ok := recoveries[t]
+	if !ok || !c.notified || !e.config.OnRecovery {
+		return
+	}
+	lasted := time.Since(c.since).Round(time.Second)
+	e.emit(Alert{
+		Type:      recovery,
+		MinerIP:   minerIP,
+		MinerName: minerName,
+		Message:   message(lasted),
+		Value:     lasted.Seconds(),
+		Timestamp: time.Now(),
+	})
+}
+
+// check opens or clears a condition without a recovery alert
+func (e *AlertEngine) check(failing bool, alert Alert) {
+	if failing {
+		e.raise(alert)
+		return
+	}
+	e.clear(alert.MinerIP, alert.MinerName, alert.Type, nil)
+}
+
+// OpenCondition is an ongoing problem with a miner
+type OpenCondition struct {
+	MinerIP string    `json:"minerIp"`
+	Type    AlertType `json:"type"`
+	Since   time.Time `json:"since"`
+}
+
+// OpenConditions returns every open condition, oldest first
+func (e *AlertEngine) OpenConditions() []OpenCondition {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	open := make([]OpenCondition, 0)
+	for ip, conditions := range e.open {
+		for t, c := range conditions {
+			open = append(open, OpenCondition{MinerIP: ip, Type: t, Since: c.since})
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		if !open[i].Since.Equal(open[j].Since) {
+			return open[i].Since.Before(open[j].Since)
+		}
+		return open[i].MinerIP+string(open[i].Type) < open[j].MinerIP+string(open[j].Type)
+	})
+	return open
+}
+
+// onlineMessage is the message of a miner back online
+func onlineMessage(lasted time.Duration) string {
+	return fmt.Sprintf("Miner is back online after %v", lasted)
+}
+
+// poolReconnectedMessage is the message of a pool reconnection
+func poolReconnectedMessage(lasted time.Duration) string {
+	return fmt.Sprintf("Pool reconnected after %v", lasted)
+}
+
+// tempNormalMessage reports the temperature a miner cooled down to
+func tempNormalMessage(config *AlertConfig, label string, temp float64) func(time.Duration) string {
+	return func(lasted time.Duration) string {
+		return fmt.Sprintf("%s is back to %s (threshold: %s) after %v", label, config.Display.FormatTemperature(temp), config.Display.FormatTemperature(config.TempAbove), lasted)
+	}
+}
+
+// forgetConditions drops a miner's open conditions without recovery alerts,
+// or every miner's for an empty IP
+func (e *AlertEngine) forgetConditions(minerIP string) {
+	if minerIP == "" {
+		e.open = make(map[string]map[AlertType]*condition)
+		return
+	}
+	delete(e.open, minerIP)
+}
+
+// conditionsSince returns a miner's open conditions and when they opened
+func (e *AlertEngine) conditionsSince(minerIP string) map[string]time.Time {
+	open := make(map[string]time.Time, len(e.open[minerIP]))
+	for t, c := range e.open[minerIP] {
+		open[string(t)] = c.since
+	}
+	return open
+}
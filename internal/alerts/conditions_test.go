@@ -0,0 +1,84 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// recordingEngine returns an engine that collects the alerts it sends
+func recordingEngine(config *AlertConfig) (*AlertEngine, *[]AlertType) {
+	e := NewAlertEngine(config)
+	sent := &[]AlertType{}
+	e.SetOnAlert(func(a Alert) { *sent = append(*sent, a.Type) })
+	return e, sent
+}
+
+func TestPoolConditionAlertsOnceAndRecovers(t *testing.T) {
+	e, sent := recordingEngine(&AlertConfig{OnPoolDisconnected: true, OnRecovery: true})
+	snap := &storage.MinerSnapshot{MinerIP: "10.0.0.2", Hostname: "axe"}
+
+	e.CheckSnapshot(snap)
+	e.CheckSnapshot(snap)
+	if len(*sent) != 1 || (*sent)[0] != AlertPoolDisconnected {
+		t.Fatalf("sent %v, want one pool_disconnected", *sent)
+	}
+	if open := e.OpenConditions(); len(open) != 1 || open[0].Type != AlertPoolDisconnected {
+		t.Fatalf("open conditions %v", open)
+	}
+
+	snap.PoolConnected = true
+	e.CheckSnapshot(snap)
+	e.CheckSnapshot(snap)
+	if len(*sent) != 2 || (*sent)[1] != AlertPoolReconnected {
+		t.Fatalf("sent %v, want pool_reconnected after pool_disconnected", *sent)
+	}
+	if open := e.OpenConditions(); len(open) != 0 {
+		t.Errorf("open conditions %v after recovery", open)
+	}
+}
+
+func TestTempConditionHysteresis(t *testing.T) {
+	e, sent := recordingEngine(&AlertConfig{TempAbove: 65, OnRecovery: true})
+	snap := &storage.MinerSnapshot{MinerIP: "10.0.0.3", Hostname: "qaxe", PoolConnected: true}
+
+	for _, temp := range []float64{70, 64, 68, 63} {
+		snap.Temperature = temp
+		e.CheckSnapshot(snap)
+	}
+	// 64 and 68 are within the hysteresis band, 63 clears
+	if len(*sent) != 2 || (*sent)[0] != AlertTempHigh || (*sent)[1] != AlertTempNormal {
+		t.Fatalf("sent %v, want temp_high then temp_normal", *sent)
+	}
+}
+
+func TestRecoveryDisabled(t *testing.T) {
+	e, sent := recordingEngine(&AlertConfig{OnPoolDisconnected: true})
+	snap := &storage.MinerSnapshot{MinerIP: "10.0.0.4", Hostname: "gamma"}
+
+	e.CheckSnapshot(snap)
+	snap.PoolConnected = true
+	e.CheckSnapshot(snap)
+	if len(*sent) != 1 {
+		t.Fatalf("sent %v, want only pool_disconnected", *sent)
+	}
+	if open := e.OpenConditions(); len(open) != 0 {
+		t.Errorf("condition still open: %v", open)
+	}
+}
+
+func TestDisplayNames(t *testing.T) {
+	e := NewAlertEngine(&AlertConfig{OnPoolDisconnected: true, OnRecovery: true})
+	var names []string
+	e.SetOnAlert(func(a Alert) { names = append(names, a.MinerName) })
+	e.SetDisplayNames(map[string]string{"10.0.0.5": "Garage Shelf"})
+
+	e.CheckSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.5", Hostname: "bitaxe-3f"})
+	e.CheckSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.6", Hostname: "nerdqaxe"})
+	e.SetDisplayNames(nil)
+	e.CheckSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.5", Hostname: "bitaxe-3f", PoolConnected: true})
+
+	if len(names) != 3 || names[0] != "Garage Shelf" || names[1] != "nerdqaxe" || names[2] != "bitaxe-3f" {
+		t.Errorf("expected the display name until it was cleared, got %v", names)
+	}
+}
@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	discordQueueSize   = 100             // Alerts waiting to be posted before new ones are dead-lettered
+	discordBatchWindow = 2 * time.Second // How long to wait for more alerts to bundle into one message
+	discordMaxEmbeds   = 10              // Discord's limit of embeds per webhook message
+	maxRateLimitWait   = time.Minute     // Longest Retry-After honored before sending anyway
+)
+
+// discordItem is an alert waiting to be posted to a Discord webhook
+type discordItem struct {
+	url   string
+	alert Alert
+	body  []byte
+}
+
+// discordRateLimit tracks when Discord next accepts a webhook post, from
+// 429 responses and the X-RateLimit headers
+type discordRateLimit struct {
+	mu        sync.Mutex
+	notBefore time.Time
+}
+
+// wait blocks until the rate limit window has passed
+func (l *discordRateLimit) wait() {
+	l.mu.Lock()
+	d := time.Until(l.notBefore)
+	l.mu.Unlock()
+
+	if d > maxRateLimitWait {
+		d = maxRateLimitWait
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// update reads the rate limit state from a webhook response
+func (l *discordRateLimit) update(resp *http.Response) {
+	var d time.Duration
+	if resp.StatusCode == http.StatusTooManyRequests {
+		d = headerSeconds(resp, "Retry-After")
+		if d <= 0 {
+			var body struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			if json.NewDecoder(resp.Body).Decode(&body) == nil {
+				d = time.Duration(body.RetryAfter * float64(time.Second))
+			}
+		}
+		log.Printf("Discord rate limited webhook posts, waiting %v", d)
+	} else if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		d = headerSeconds(resp, "X-RateLimit-Reset-After")
+	}
+	if d <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.notBefore) {
+		l.notBefore = until
+	}
+}
+
+// headerSeconds parses a header holding a (possibly fractional) number of seconds
+func headerSeconds(resp *http.Response, name string) time.Duration {
+	secs, err := strconv.ParseFloat(resp.Header.Get(name), 64)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// enqueueDiscord queues an alert for the Discord worker. When the queue is
+// full (Discord has been unreachable for a while) the alert goes straight to
+// the dead-letter queue instead of waiting for Discord. It is stored from its
+// own goroutine, since the caller may hold the engine's lock, which
+// deadLetter takes to read the store.
+func (e *AlertEngine) enqueueDiscord(item discordItem) {
+	select {
+	case e.discordQueue <- item:
+	default:
+		err := fmt.Errorf("discord queue full")
+		log.Printf("Discord alert [%s] %s dropped: %v", item.alert.Type, item.alert.MinerName, err)
+		go e.deadLetter(ChannelDiscord, item.alert, item.body, 0, err)
+	}
+}
+
+// runDiscordQueue posts queued alerts one message at a time. Alerts arriving
+// together (e.g. every miner going offline after a power blip) are bundled
+// into a single message of up to discordMaxEmbeds embeds.
+func (e *AlertEngine) runDiscordQueue() {
+	var carry *discordItem
+	for {
+		first := carry
+		carry = nil
+		if first == nil {
+			item := <-e.discordQueue
+			first = &item
+		}
+
+		batch := []discordItem{*first}
+		timer := time.NewTimer(discordBatchWindow)
+	collect:
+		for len(batch) < discordMaxEmbeds {
+			select {
+			case item := <-e.discordQueue:
+				if item.url != first.url {
+					// The webhook changed in Settings; post it separately
+					carry = &item
+					break collect
+				}
+				batch = append(batch, item)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		e.postDiscordBatch(batch)
+	}
+}
+
+// postDiscordBatch posts a batch of alerts as one message, with retries. If
+// it can't be delivered each alert is dead-lettered on its own, so they can
+// be replayed individually.
+func (e *AlertEngine) postDiscordBatch(batch []discordItem) {
+	body := batch[0].body
+	what := fmt.Sprintf("[%s] %s", batch[0].alert.Type, batch[0].alert.MinerName)
+	if len(batch) > 1 {
+		bodies := make([][]byte, len(batch))
+		for i, item := range batch {
+			bodies[i] = item.body
+		}
+		merged, err := mergeDiscordPayloads(bodies)
+		if err != nil {
+			log.Printf("Failed to merge Discord payloads: %v", err)
+			for _, item := range batch {
+				e.postDiscordBatch([]discordItem{item})
+			}
+			return
+		}
+		body = merged
+		what = fmt.Sprintf("batch of %d", len(batch))
+	}
+
+	attempts, err := e.sendWithRetry(ChannelDiscord, what, func() error {
+		return e.postWebhook(batch[0].url, body)
+	})
+	if err != nil {
+		for _, item := range batch {
+			e.deadLetter(ChannelDiscord, item.alert, item.body, attempts, err)
+		}
+	}
+}
+
+// mergeDiscordPayloads combines the embeds of several webhook payloads into one
+func mergeDiscordPayloads(bodies [][]byte) ([]byte, error) {
+	var embeds []json.RawMessage
+	for _, body := range bodies {
+		var payload struct {
+			Embeds []json.RawMessage `json:"embeds"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		embeds = append(embeds, payload.Embeds...)
+	}
+	return json.Marshal(map[string]interface{}{"embeds": embeds})
+}
@@ -0,0 +1,126 @@
+package alerts
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// testStore returns a store in a temporary directory
+func testStore(t *testing.T) *storage.SQLiteStorage {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// waitForFailedDeliveries waits for n dead-lettered notifications
+func waitForFailedDeliveries(t *testing.T, store *storage.SQLiteStorage, n int) []*storage.FailedDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		failed, err := store.GetFailedDeliveries(100)
+		if err != nil {
+			t.Fatalf("failed to get failed deliveries: %v", err)
+		}
+		if len(failed) >= n || time.Now().After(deadline) {
+			if len(failed) != n {
+				t.Fatalf("expected %d failed deliveries, got %d", n, len(failed))
+			}
+			return failed
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDiscordQueueFull(t *testing.T) {
+	store := testStore(t)
+	e := newAlertEngine(&AlertConfig{WebhookURL: "http://127.0.0.1:1/webhook", OnPoolDisconnected: true})
+	e.store = store
+	e.discordQueue = make(chan discordItem, 1)
+	e.discordQueue <- discordItem{}
+
+	// CheckSnapshot holds the engine's lock while it sends
+	done := make(chan struct{})
+	go func() {
+		e.CheckSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.2", Hostname: "axe"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert engine deadlocked on a full Discord queue")
+	}
+
+	failed := waitForFailedDeliveries(t, store, 1)
+	if d := failed[0]; d.Channel != ChannelDiscord || d.AlertType != string(AlertPoolDisconnected) || d.Attempts != 0 || d.LastError != "discord queue full" {
+		t.Errorf("unexpected failed delivery %+v", d)
+	}
+}
+
+func TestPostDiscordBatch(t *testing.T) {
+	var posted [][]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Embeds []json.RawMessage `json:"embeds"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		posted = append(posted, payload.Embeds)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	e := newAlertEngine(&AlertConfig{})
+	var batch []discordItem
+	for _, alert := range []Alert{
+		{Type: AlertMinerOffline, MinerName: "alpha"},
+		{Type: AlertMinerOffline, MinerName: "beta"},
+		{Type: AlertMinerOffline, MinerName: "gamma"},
+	} {
+		body, err := buildDiscordPayload(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch = append(batch, discordItem{url: srv.URL, alert: alert, body: body})
+	}
+	e.postDiscordBatch(batch)
+	if len(posted) != 1 || len(posted[0]) != 3 {
+		t.Errorf("expected one message of 3 embeds, got %v", posted)
+	}
+}
+
+func TestPostDiscordBatchDeadLetters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	store := testStore(t)
+	e := newAlertEngine(&AlertConfig{})
+	e.store = store
+	var batch []discordItem
+	for _, name := range []string{"alpha", "beta"} {
+		alert := Alert{Type: AlertMinerOffline, MinerName: name, Timestamp: time.Now()}
+		body, _ := buildDiscordPayload(alert)
+		batch = append(batch, discordItem{url: srv.URL, alert: alert, body: body})
+	}
+	e.postDiscordBatch(batch)
+
+	// A 404 is permanent, so each alert is dead-lettered after one attempt
+	failed := waitForFailedDeliveries(t, store, 2)
+	for _, d := range failed {
+		if d.Channel != ChannelDiscord || d.Attempts != 1 || d.LastError != "discord returned status 404" {
+			t.Errorf("unexpected failed delivery %+v", d)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package alerts
+
+import "time"
+
+// hashrateWindow is how long hashrate is averaged over before comparing. A
+// single poll of an oscillating Bitaxe can read 15% low without anything
+// being wrong.
+const hashrateWindow = 10 * time.Minute
+
+// defaultHashrateDropChecks is how many consecutive checks the average must
+// stay down before alerting, when not configured
+const defaultHashrateDropChecks = 3
+
+type hashrateSample struct {
+	at  time.Time
+	ghs float64
+}
+
+// hashrateHistory holds a miner's hashrate samples from the last two windows
+// and how many consecutive checks its average has been down
+type hashrateHistory struct {
+	samples []hashrateSample
+	drops   int
+}
+
+// add records a sample and forgets those older than two windows
+func (h *hashrateHistory) add(at time.Time, ghs float64) {
+	h.samples = append(h.samples, hashrateSample{at: at, ghs: ghs})
+
+	cutoff := at.Add(-2 * hashrateWindow)
+	i := 0
+	for i < len(h.samples) && h.samples[i].at.Before(cutoff) {
+		i++
+	}
+	h.samples = h.samples[i:]
+}
+
+// averages returns the average hashrate of the last window and of the window
+// before it. ok is false until there's enough history to compare: samples in
+// both windows, going back at least one and a half windows.
+func (h *hashrateHistory) averages(now time.Time) (current, previous float64, ok bool) {
+	if len(h.samples) == 0 || h.samples[0].at.After(now.Add(-hashrateWindow*3/2)) {
+		return 0, 0, false
+	}
+
+	start := now.Add(-hashrateWindow)
+	var curSum, prevSum float64
+	var curN, prevN int
+	for _, s := range h.samples {
+		if s.at.After(start) {
+			curSum += s.ghs
+			curN++
+		} else {
+			prevSum += s.ghs
+			prevN++
+		}
+	}
+	if curN == 0 || prevN == 0 {
+		return 0, 0, false
+	}
+	return curSum / float64(curN), prevSum / float64(prevN), true
+}
+
+// average returns the average hashrate of the last window, 0 without samples
+func (h *hashrateHistory) average(now time.Time) float64 {
+	if h == nil {
+		return 0
+	}
+	start := now.Add(-hashrateWindow)
+	var sum float64
+	var n int
+	for _, s := range h.samples {
+		if s.at.After(start) {
+			sum += s.ghs
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// dropCount returns how many consecutive checks the average has been down
+func (h *hashrateHistory) dropCount() int {
+	if h == nil {
+		return 0
+	}
+	return h.drops
+}
@@ -0,0 +1,55 @@
+package alerts
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHashrateHistory(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := &hashrateHistory{}
+
+	// An oscillating miner averages out
+	for i := 0; i < 40; i++ {
+		ghs := 1000.0
+		if i%2 == 1 {
+			ghs = 800
+		}
+		h.add(start.Add(time.Duration(i)*30*time.Second), ghs)
+	}
+	now := start.Add(39 * 30 * time.Second)
+	current, previous, ok := h.averages(now)
+	if !ok || current != 900 || previous != 900 {
+		t.Fatalf("averages = %v, %v, %v; want 900, 900, true", current, previous, ok)
+	}
+
+	// A sustained drop shows up once the last window is down
+	for i := 40; i < 60; i++ {
+		h.add(start.Add(time.Duration(i)*30*time.Second), 450)
+	}
+	now = start.Add(59 * 30 * time.Second)
+	current, previous, _ = h.averages(now)
+	if current != 450 || math.Abs(previous-900) > 10 {
+		t.Errorf("averages = %v, %v; want 450, about 900", current, previous)
+	}
+	if len(h.samples) > 41 {
+		t.Errorf("kept %d samples, want at most two windows", len(h.samples))
+	}
+}
+
+func TestHashrateHistoryNeedsHistory(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := &hashrateHistory{}
+	for i := 0; i < 20; i++ {
+		h.add(start.Add(time.Duration(i)*30*time.Second), 1000)
+	}
+	if _, _, ok := h.averages(start.Add(10 * time.Minute)); ok {
+		t.Error("expected no comparison with only one window of samples")
+	}
+
+	var none *hashrateHistory
+	if none.average(start) != 0 || none.dropCount() != 0 {
+		t.Error("expected a miner without history to have no average")
+	}
+}
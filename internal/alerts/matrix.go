@@ -0,0 +1,86 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+// matrixTxnCounter makes Matrix transaction IDs unique within the process
+var matrixTxnCounter atomic.Uint64
+
+// matrixConfigured reports whether alerts should be sent to a Matrix room
+func (c *AlertConfig) matrixConfigured() bool {
+	return c.MatrixHomeserver != "" && c.MatrixAccessToken != "" && c.MatrixRoomID != ""
+}
+
+// buildMatrixMessage builds an m.room.message event mirroring the Discord
+// embed: a plain-text body plus an HTML version with the title in the alert's
+// color, the message, the fields and a footer.
+func buildMatrixMessage(alert Alert, display units.Display) ([]byte, error) {
+	d := getAlertDisplay(alert.Type)
+	title := fmt.Sprintf("%s %s", d.Emoji, d.Title)
+	footer := fmt.Sprintf("MinerHQ Alert System · %s", display.FormatTime(alert.Timestamp))
+
+	var plain, formatted strings.Builder
+	fmt.Fprintf(&plain, "%s\n%s\n", title, alert.Message)
+	fmt.Fprintf(&formatted, `<h4><font color="#%06X">%s</font></h4><p>%s</p>`, d.Color, html.EscapeString(title), html.EscapeString(alert.Message))
+
+	if fields := alertFields(alert); len(fields) > 0 {
+		formatted.WriteString("<ul>")
+		for _, field := range fields {
+			name, value := fmt.Sprint(field["name"]), fmt.Sprint(field["value"])
+			fmt.Fprintf(&plain, "%s: %s\n", name, value)
+			fmt.Fprintf(&formatted, "<li><strong>%s:</strong> %s</li>", html.EscapeString(name), html.EscapeString(value))
+		}
+		formatted.WriteString("</ul>")
+	}
+
+	plain.WriteString(footer)
+	fmt.Fprintf(&formatted, "<p><sub>%s</sub></p>", html.EscapeString(footer))
+
+	return json.Marshal(map[string]string{
+		"msgtype":        "m.notice",
+		"body":           plain.String(),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted.String(),
+	})
+}
+
+// newMatrixTxnID returns a transaction ID for a new message event
+func newMatrixTxnID() string {
+	return fmt.Sprintf("minerhq-%d-%d", time.Now().UnixNano(), matrixTxnCounter.Add(1))
+}
+
+// postMatrix sends a message event to the configured Matrix room. The
+// homeserver ignores repeated sends with the same transaction ID.
+func (e *AlertEngine) postMatrix(config *AlertConfig, txnID string, body []byte) error {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(config.MatrixHomeserver, "/"), url.PathEscape(config.MatrixRoomID), txnID)
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Matrix request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+config.MatrixAccessToken)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Matrix message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return &statusError{channel: ChannelMatrix, code: resp.StatusCode}
+	}
+	return nil
+}
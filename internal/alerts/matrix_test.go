@@ -0,0 +1,77 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+func TestBuildMatrixMessage(t *testing.T) {
+	ts := time.Date(2026, 3, 18, 15, 30, 0, 0, time.UTC)
+	body, err := buildMatrixMessage(Alert{
+		Type:      AlertTempHigh,
+		MinerIP:   "10.0.0.2",
+		MinerName: "axe <garage>",
+		Message:   "Temperature is 72.5°C & rising",
+		Timestamp: ts,
+	}, units.Display{TimeFormat: "12h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg map[string]string
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	footer := "MinerHQ Alert System · " + ts.Local().Format("2006-01-02 03:04:05 PM")
+	d := getAlertDisplay(AlertTempHigh)
+	wantPlain := d.Emoji + " " + d.Title + "\nTemperature is 72.5°C & rising\nMiner: axe <garage>\nIP: 10.0.0.2\n" + footer
+	if msg["msgtype"] != "m.notice" || msg["format"] != "org.matrix.custom.html" || msg["body"] != wantPlain {
+		t.Errorf("unexpected message %v", msg)
+	}
+	for _, want := range []string{
+		fmt.Sprintf(`<h4><font color="#%06X">%s %s</font></h4>`, d.Color, d.Emoji, d.Title),
+		"<p>Temperature is 72.5°C &amp; rising</p>",
+		"<li><strong>Miner:</strong> axe &lt;garage&gt;</li>",
+		"<p><sub>" + footer + "</sub></p>",
+	} {
+		if !strings.Contains(msg["formatted_body"], want) {
+			t.Errorf("expected %q in %s", want, msg["formatted_body"])
+		}
+	}
+}
+
+func TestSendTestAlertMatrixFailure(t *testing.T) {
+	var matrixRequests atomic.Int32
+	matrix := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matrixRequests.Add(1)
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer syt_token" || !strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+			t.Errorf("unexpected Matrix request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer matrix.Close()
+	discord, received := fakeService(t, http.StatusNoContent)
+
+	e := NewAlertEngine(&AlertConfig{
+		WebhookURL:        discord.URL,
+		MatrixHomeserver:  matrix.URL + "/",
+		MatrixAccessToken: "syt_token",
+		MatrixRoomID:      "!room:example.org",
+	})
+	for _, send := range []func() error{e.SendTestAlert, func() error { return e.SendTestAlertByType(string(AlertBlockFound)) }} {
+		if err := send(); err == nil || err.Error() != "matrix returned status 403" {
+			t.Errorf("expected the Matrix error, got %v", err)
+		}
+	}
+	if matrixRequests.Load() != 2 || len(*received) != 2 {
+		t.Errorf("expected both test alerts sent to Matrix and Discord, got %d and %d", matrixRequests.Load(), len(*received))
+	}
+}
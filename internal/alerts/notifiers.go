@@ -0,0 +1,331 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// pushoverAPI is the Pushover message endpoint, replaced in tests
+var pushoverAPI = "https://api.pushover.net/1/messages.json"
+
+// notifier delivers alerts to one configured channel. Building the payload
+// is separate from sending it so undeliverable payloads can be replayed.
+type notifier interface {
+	payload(alert Alert) ([]byte, error)
+	send(client *http.Client, body []byte) error
+}
+
+// newNotifier creates the driver for a configured channel
+func newNotifier(nc config.NotifierConfig) (notifier, error) {
+	name := nc.ChannelName()
+	switch nc.Type {
+	case config.NotifierWebhook:
+		return &webhookNotifier{name: name, url: nc.URL, headers: nc.Headers}, nil
+	case config.NotifierSlack:
+		return &slackNotifier{name: name, url: nc.URL}, nil
+	case config.NotifierNtfy:
+		return newNtfyNotifier(name, nc.URL, nc.Token)
+	case config.NotifierPushover:
+		return &pushoverNotifier{name: name, token: nc.Token, user: nc.User}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", nc.Type)
+}
+
+// notifies reports whether a channel wants alerts of the given type
+func notifies(nc config.NotifierConfig, t AlertType) bool {
+	if len(nc.AlertTypes) == 0 {
+		return true
+	}
+	for _, want := range nc.AlertTypes {
+		if AlertType(want) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotifiers logs alert types a channel selects that don't exist, which
+// would otherwise silently never be sent
+func checkNotifiers(notifiers []config.NotifierConfig) {
+	for _, nc := range notifiers {
+		for _, t := range nc.AlertTypes {
+			if !validAlertTypes[AlertType(t)] {
+				log.Printf("Notifier %s selects unknown alert type %q", nc.ChannelName(), t)
+			}
+		}
+	}
+}
+
+// postJSON posts a JSON body and turns error responses into statusErrors
+func postJSON(client *http.Client, channel, endpoint string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", channel, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s alert: %w", channel, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return &statusError{channel: channel, code: resp.StatusCode}
+	}
+	return nil
+}
+
+// alertTitle returns the alert's emoji and title, e.g. "🔴 Miner Offline"
+func alertTitle(alert Alert) string {
+	d := getAlertDisplay(alert.Type)
+	return fmt.Sprintf("%s %s", d.Emoji, d.Title)
+}
+
+// alertText returns the message followed by one "Name: value" line per field
+func alertText(alert Alert) string {
+	var text strings.Builder
+	text.WriteString(alert.Message)
+	for _, field := range alertFields(alert) {
+		fmt.Fprintf(&text, "\n%v: %v", field["name"], field["value"])
+	}
+	return text.String()
+}
+
+// webhookNotifier posts the alert as plain JSON for home automation and
+// custom integrations
+type webhookNotifier struct {
+	name    string
+	url     string
+	headers map[string]string
+}
+
+// webhookPayload is the body of a generic JSON webhook
+type webhookPayload struct {
+	Type      AlertType                `json:"type"`
+	Title     string                   `json:"title"`
+	MinerIP   string                   `json:"minerIp"`
+	MinerName string                   `json:"minerName"`
+	Message   string                   `json:"message"`
+	Value     float64                  `json:"value"`
+	Timestamp string                   `json:"timestamp"`
+	Fields    []map[string]interface{} `json:"fields"`
+}
+
+func (n *webhookNotifier) payload(alert Alert) ([]byte, error) {
+	return json.Marshal(webhookPayload{
+		Type:      alert.Type,
+		Title:     getAlertDisplay(alert.Type).Title,
+		MinerIP:   alert.MinerIP,
+		MinerName: alert.MinerName,
+		Message:   alert.Message,
+		Value:     alert.Value,
+		Timestamp: alert.Timestamp.Format(time.RFC3339),
+		Fields:    alertFields(alert),
+	})
+}
+
+func (n *webhookNotifier) send(client *http.Client, body []byte) error {
+	return postJSON(client, n.name, n.url, body, n.headers)
+}
+
+// slackNotifier posts to a Slack incoming webhook as a colored attachment
+type slackNotifier struct {
+	name string
+	url  string
+}
+
+func (n *slackNotifier) payload(alert Alert) ([]byte, error) {
+	d := getAlertDisplay(alert.Type)
+
+	fields := make([]map[string]interface{}, 0, len(alertFields(alert)))
+	for _, field := range alertFields(alert) {
+		inline, _ := field["inline"].(bool)
+		fields = append(fields, map[string]interface{}{
+			"title": field["name"],
+			"value": fmt.Sprint(field["value"]),
+			"short": inline,
+		})
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"text": fmt.Sprintf("%s: %s", alertTitle(alert), alert.Message),
+		"attachments": []map[string]interface{}{
+			{
+				"color":  fmt.Sprintf("#%06X", d.Color),
+				"title":  alertTitle(alert),
+				"text":   alert.Message,
+				"fields": fields,
+				"footer": "MinerHQ Alert System",
+				"ts":     alert.Timestamp.Unix(),
+			},
+		},
+	})
+}
+
+func (n *slackNotifier) send(client *http.Client, body []byte) error {
+	return postJSON(client, n.name, n.url, body, nil)
+}
+
+// ntfyNotifier publishes to an ntfy topic, on ntfy.sh or self-hosted
+type ntfyNotifier struct {
+	name   string
+	server string
+	topic  string
+	token  string
+}
+
+// newNtfyNotifier splits a topic URL such as https://ntfy.sh/miners into the
+// server to publish JSON to and the topic
+func newNtfyNotifier(name, topicURL, token string) (*ntfyNotifier, error) {
+	u, err := url.Parse(topicURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ntfy topic URL: %w", err)
+	}
+	dir, topic := path.Split(strings.TrimRight(u.Path, "/"))
+	if topic == "" {
+		return nil, fmt.Errorf("ntfy URL %q has no topic", topicURL)
+	}
+	u.Path = dir
+	return &ntfyNotifier{name: name, server: u.String(), topic: topic, token: token}, nil
+}
+
+// ntfyPriority maps alert types to ntfy priorities (3 is the default)
+func ntfyPriority(t AlertType) int {
+	switch t {
+	case AlertBlockFound:
+		return 5
+	case AlertMinerOffline, AlertMinerFrozen, AlertTempHigh, AlertPoolDisconnected:
+		return 4
+	}
+	return 3
+}
+
+func (n *ntfyNotifier) payload(alert Alert) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"topic":    n.topic,
+		"title":    alertTitle(alert),
+		"message":  alertText(alert),
+		"tags":     []string{string(alert.Type)},
+		"priority": ntfyPriority(alert.Type),
+	})
+}
+
+func (n *ntfyNotifier) send(client *http.Client, body []byte) error {
+	var headers map[string]string
+	if n.token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + n.token}
+	}
+	return postJSON(client, n.name, n.server, body, headers)
+}
+
+// pushoverNotifier sends push notifications through Pushover. The keys are
+// added when sending so they aren't stored with failed deliveries.
+type pushoverNotifier struct {
+	name  string
+	token string
+	user  string
+}
+
+func (n *pushoverNotifier) payload(alert Alert) ([]byte, error) {
+	priority := 0
+	if ntfyPriority(alert.Type) > 3 {
+		priority = 1 // High priority, bypasses quiet hours
+	}
+	return json.Marshal(map[string]interface{}{
+		"title":     alertTitle(alert),
+		"message":   alertText(alert),
+		"priority":  priority,
+		"timestamp": alert.Timestamp.Unix(),
+	})
+}
+
+func (n *pushoverNotifier) send(client *http.Client, body []byte) error {
+	var message map[string]interface{}
+	if err := json.Unmarshal(body, &message); err != nil {
+		return fmt.Errorf("invalid Pushover payload: %w", err)
+	}
+	message["token"] = n.token
+	message["user"] = n.user
+
+	withKeys, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return postJSON(client, n.name, pushoverAPI, withKeys, nil)
+}
+
+// notify sends an alert to a configured channel in the background, retrying
+// failures and dead-lettering it if it can't be delivered
+func (e *AlertEngine) notify(nc config.NotifierConfig, alert Alert) {
+	n, err := newNotifier(nc)
+	if err != nil {
+		log.Printf("Notifier %s: %v", nc.ChannelName(), err)
+		return
+	}
+	body, err := n.payload(alert)
+	if err != nil {
+		log.Printf("Failed to marshal %s payload: %v", nc.ChannelName(), err)
+		return
+	}
+
+	channel := nc.ChannelName()
+	go func() {
+		attempts, err := e.sendWithRetry(channel, fmt.Sprintf("[%s] %s", alert.Type, alert.MinerName), func() error {
+			return n.send(e.client, body)
+		})
+		if err != nil {
+			e.deadLetter(channel, alert, body, attempts, err)
+		}
+	}()
+}
+
+// notifyNow sends a test alert synchronously and without retries to every
+// configured channel that wants alertType, or to all of them if it's empty.
+// A failing channel doesn't keep the next ones from being tested; the errors
+// of all that failed are returned.
+func (e *AlertEngine) notifyNow(config *AlertConfig, alert Alert, alertType AlertType) error {
+	var errs []error
+	for _, nc := range config.Notifiers {
+		if alertType != "" && !notifies(nc, alertType) {
+			continue
+		}
+		n, err := newNotifier(nc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", nc.ChannelName(), err))
+			continue
+		}
+		body, err := n.payload(alert)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to marshal %s payload: %w", nc.ChannelName(), err))
+			continue
+		}
+		if err := n.send(e.client, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifierByChannel returns the configured channel with the given name
+func (c *AlertConfig) notifierByChannel(channel string) (config.NotifierConfig, bool) {
+	for _, nc := range c.Notifiers {
+		if nc.ChannelName() == channel {
+			return nc, true
+		}
+	}
+	return config.NotifierConfig{}, false
+}
@@ -0,0 +1,169 @@
+package alerts
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// capturedRequest is a request received by a fake notification service
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// fakeService records the requests it receives and answers them with status
+func fakeService(t *testing.T, status int) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var received []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("invalid JSON body %s: %v", raw, err)
+		}
+		received = append(received, capturedRequest{path: r.URL.Path, header: r.Header, body: body})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &received
+}
+
+// sendTo builds a channel's payload for alert and sends it
+func sendTo(t *testing.T, nc config.NotifierConfig, alert Alert) error {
+	t.Helper()
+	n, err := newNotifier(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := n.payload(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.send(&http.Client{Timeout: 5 * time.Second}, body)
+}
+
+var testNotifierAlert = Alert{
+	Type:      AlertBlockFound,
+	MinerIP:   "10.0.0.2",
+	MinerName: "axe",
+	Message:   "Block found!",
+	Timestamp: time.Date(2026, 3, 18, 15, 30, 0, 0, time.UTC),
+	Fields:    []map[string]interface{}{{"name": "Height", "value": 840000, "inline": true}},
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv, received := fakeService(t, http.StatusOK)
+	err := sendTo(t, config.NotifierConfig{Type: config.NotifierWebhook, URL: srv.URL + "/hook", Headers: map[string]string{"X-Token": "secret"}}, testNotifierAlert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*received) != 1 {
+		t.Fatalf("expected one request, got %d", len(*received))
+	}
+	r := (*received)[0]
+	if r.path != "/hook" || r.header.Get("X-Token") != "secret" || r.header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request to %s with %v", r.path, r.header)
+	}
+	if r.body["type"] != string(AlertBlockFound) || r.body["minerIp"] != "10.0.0.2" || r.body["timestamp"] != "2026-03-18T15:30:00Z" {
+		t.Errorf("unexpected payload %v", r.body)
+	}
+	if fields, _ := r.body["fields"].([]interface{}); len(fields) != 1 {
+		t.Errorf("expected the alert's field, got %v", r.body["fields"])
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	srv, received := fakeService(t, http.StatusOK)
+	if err := sendTo(t, config.NotifierConfig{Type: config.NotifierSlack, URL: srv.URL}, testNotifierAlert); err != nil {
+		t.Fatal(err)
+	}
+	attachments, _ := (*received)[0].body["attachments"].([]interface{})
+	if len(attachments) != 1 {
+		t.Fatalf("expected one attachment, got %v", (*received)[0].body)
+	}
+	a := attachments[0].(map[string]interface{})
+	fields, _ := a["fields"].([]interface{})
+	if a["title"] != alertTitle(testNotifierAlert) || a["text"] != "Block found!" || len(fields) != 1 || a["ts"] != float64(testNotifierAlert.Timestamp.Unix()) {
+		t.Errorf("unexpected attachment %v", a)
+	}
+	if f := fields[0].(map[string]interface{}); f["title"] != "Height" || f["value"] != "840000" || f["short"] != true {
+		t.Errorf("unexpected field %v", f)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	srv, received := fakeService(t, http.StatusOK)
+	if err := sendTo(t, config.NotifierConfig{Type: config.NotifierNtfy, URL: srv.URL + "/miners", Token: "tk_abc"}, testNotifierAlert); err != nil {
+		t.Fatal(err)
+	}
+	r := (*received)[0]
+	if r.path != "/" || r.header.Get("Authorization") != "Bearer tk_abc" {
+		t.Errorf("expected a JSON publish to the server root with the token, got %s with %v", r.path, r.header)
+	}
+	if r.body["topic"] != "miners" || r.body["priority"] != float64(5) || r.body["message"] != "Block found!\nHeight: 840000" {
+		t.Errorf("unexpected payload %v", r.body)
+	}
+
+	if _, err := newNotifier(config.NotifierConfig{Type: config.NotifierNtfy, URL: "https://ntfy.sh/"}); err == nil {
+		t.Error("expected a URL without a topic to be rejected")
+	}
+}
+
+func TestPushoverNotifier(t *testing.T) {
+	srv, received := fakeService(t, http.StatusOK)
+	defer func(api string) { pushoverAPI = api }(pushoverAPI)
+	pushoverAPI = srv.URL
+
+	nc := config.NotifierConfig{Type: config.NotifierPushover, Token: "app-token", User: "user-key"}
+	n, _ := newNotifier(nc)
+	stored, _ := n.payload(testNotifierAlert)
+	if strings.Contains(string(stored), "app-token") || strings.Contains(string(stored), "user-key") {
+		t.Errorf("expected the keys to be left out of the stored payload, got %s", stored)
+	}
+
+	if err := sendTo(t, nc, testNotifierAlert); err != nil {
+		t.Fatal(err)
+	}
+	r := (*received)[0]
+	if r.body["token"] != "app-token" || r.body["user"] != "user-key" || r.body["priority"] != float64(1) || r.body["title"] != alertTitle(testNotifierAlert) {
+		t.Errorf("unexpected payload %v", r.body)
+	}
+}
+
+func TestNotifierStatusError(t *testing.T) {
+	srv, _ := fakeService(t, http.StatusNotFound)
+	err := sendTo(t, config.NotifierConfig{Type: config.NotifierSlack, Name: "ops", URL: srv.URL}, testNotifierAlert)
+	if err == nil || err.Error() != "ops returned status 404" || retryable(err) {
+		t.Errorf("expected a permanent 404 from ops, got %v", err)
+	}
+}
+
+func TestSendTestAlertTriesEveryChannel(t *testing.T) {
+	broken, _ := fakeService(t, http.StatusInternalServerError)
+	working, received := fakeService(t, http.StatusOK)
+	discord, discordReceived := fakeService(t, http.StatusNoContent)
+
+	e := NewAlertEngine(&AlertConfig{
+		WebhookURL: discord.URL,
+		Notifiers: []config.NotifierConfig{
+			{Type: config.NotifierWebhook, Name: "broken", URL: broken.URL},
+			{Type: "carrier-pigeon"},
+			{Type: config.NotifierSlack, Name: "working", URL: working.URL},
+		},
+	})
+	err := e.SendTestAlert()
+	if err == nil || !strings.Contains(err.Error(), "broken returned status 500") || !strings.Contains(err.Error(), "carrier-pigeon") {
+		t.Errorf("expected the broken and unknown channels' errors, got %v", err)
+	}
+	if len(*received) != 1 || len(*discordReceived) != 1 {
+		t.Errorf("expected the channels after the failing ones to be tested, got %d Slack and %d Discord requests", len(*received), len(*discordReceived))
+	}
+}
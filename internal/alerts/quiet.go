@@ -0,0 +1,106 @@
+package alerts
+
+import (
+	"log"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// maxHeld is how many alerts quiet hours hold back before dropping the oldest
+const maxHeld = 100
+
+// heldAlert is an alert waiting for quiet hours to end, with the channels it
+// was meant for
+type heldAlert struct {
+	config *AlertConfig
+	alert  Alert
+}
+
+// SetMaintenance replaces the miners' maintenance windows
+func (e *AlertEngine) SetMaintenance(windows []*storage.MaintenanceWindow) {
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	e.maintenance = windows
+}
+
+// maintenanceWindow returns the maintenance window a miner is in at t, or nil
+func (e *AlertEngine) maintenanceWindow(minerIP string, t time.Time) *storage.MaintenanceWindow {
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	for _, w := range e.maintenance {
+		if w.MinerIP == minerIP && w.Active(t) {
+			return w
+		}
+	}
+	return nil
+}
+
+// hold keeps an alert off the channels: a miner in maintenance gets none, and
+// quiet hours queue or drop the alert types they don't allow. Block found
+// alerts always go through. It reports whether the alert was held back.
+func (e *AlertEngine) hold(config *AlertConfig, alert Alert, now time.Time) bool {
+	if alert.Type == AlertBlockFound {
+		return false
+	}
+	if alert.MinerIP != "" && e.maintenanceWindow(alert.MinerIP, now) != nil {
+		log.Printf("Alert [%s] %s not sent, miner in maintenance: %s", alert.Type, alert.MinerName, alert.Message)
+		return true
+	}
+
+	quiet := config.QuietHours
+	if !quiet.Active(now) {
+		return false
+	}
+	for _, t := range quiet.Allow {
+		if AlertType(t) == alert.Type {
+			return false
+		}
+	}
+	if !quiet.Queues() {
+		log.Printf("Alert [%s] %s not sent, quiet hours: %s", alert.Type, alert.MinerName, alert.Message)
+		return true
+	}
+
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	if len(e.held) >= maxHeld {
+		dropped := e.held[0]
+		log.Printf("Alert [%s] %s dropped, too many held during quiet hours", dropped.alert.Type, dropped.alert.MinerName)
+		e.held = e.held[1:]
+	}
+	e.held = append(e.held, heldAlert{config: config, alert: alert})
+	return true
+}
+
+// releaseHeld sends the alerts held back once quiet hours are over, oldest
+// first
+func (e *AlertEngine) releaseHeld(now time.Time) {
+	e.mu.RLock()
+	config := e.config
+	e.mu.RUnlock()
+	if config.QuietHours.Active(now) {
+		return
+	}
+
+	e.holdMu.Lock()
+	held := e.held
+	e.held = nil
+	e.holdMu.Unlock()
+
+	if len(held) > 0 {
+		log.Printf("Quiet hours over, sending %d held alerts", len(held))
+	}
+	for _, h := range held {
+		e.send(h.config, h.alert)
+	}
+}
+
+// runQuietHours releases held alerts when quiet hours end
+func (e *AlertEngine) runQuietHours() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		e.releaseHeld(now)
+	}
+}
@@ -0,0 +1,112 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestQuietHours(t *testing.T) {
+	night := time.Date(2026, 3, 2, 23, 30, 0, 0, time.Local)
+	morning := time.Date(2026, 3, 3, 7, 0, 0, 0, time.Local)
+	offline := Alert{Type: AlertMinerOffline, MinerIP: "10.0.0.2", MinerName: "axe"}
+
+	t.Run("queued until the window ends", func(t *testing.T) {
+		cfg := &AlertConfig{QuietHours: config.QuietHoursConfig{Start: "23:00", End: "07:00"}}
+		e := NewAlertEngine(cfg)
+
+		if !e.hold(cfg, offline, night) {
+			t.Fatal("expected an offline alert to be held at night")
+		}
+		if e.hold(cfg, Alert{Type: AlertBlockFound, MinerIP: "10.0.0.2"}, night) {
+			t.Error("expected block found alerts to go through")
+		}
+		if e.hold(cfg, offline, morning) {
+			t.Error("expected alerts to go through after quiet hours")
+		}
+
+		e.releaseHeld(night)
+		if len(e.held) != 1 {
+			t.Fatalf("expected the alert held until quiet hours end, %d held", len(e.held))
+		}
+		e.releaseHeld(morning)
+		if len(e.held) != 0 {
+			t.Errorf("expected held alerts sent after quiet hours, %d held", len(e.held))
+		}
+	})
+
+	t.Run("suppressed and allowed types", func(t *testing.T) {
+		cfg := &AlertConfig{QuietHours: config.QuietHoursConfig{Start: "23:00", End: "07:00", Mode: config.QuietSuppress, Allow: []string{"miner_offline"}}}
+		e := NewAlertEngine(cfg)
+
+		if e.hold(cfg, offline, night) {
+			t.Error("expected an allowed type to go through")
+		}
+		if !e.hold(cfg, Alert{Type: AlertTempHigh, MinerIP: "10.0.0.2"}, night) || len(e.held) != 0 {
+			t.Errorf("expected the alert dropped, %d held", len(e.held))
+		}
+	})
+
+	t.Run("too many held", func(t *testing.T) {
+		cfg := &AlertConfig{QuietHours: config.QuietHoursConfig{Start: "23:00", End: "07:00"}}
+		e := NewAlertEngine(cfg)
+		for i := 0; i < maxHeld+5; i++ {
+			e.hold(cfg, Alert{Type: AlertShareRejected, MinerIP: "10.0.0.2", Value: float64(i)}, night)
+		}
+		if len(e.held) != maxHeld || e.held[0].alert.Value != 5 {
+			t.Errorf("expected the oldest alerts dropped, %d held from %v", len(e.held), e.held[0].alert.Value)
+		}
+	})
+}
+
+func TestMaintenanceWindow(t *testing.T) {
+	now := time.Now()
+	cfg := &AlertConfig{}
+	e := NewAlertEngine(cfg)
+	e.SetMaintenance([]*storage.MaintenanceWindow{{MinerIP: "10.0.0.2", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}})
+
+	if !e.hold(cfg, Alert{Type: AlertTempHigh, MinerIP: "10.0.0.2"}, now) {
+		t.Error("expected alerts of a miner in maintenance to be held back")
+	}
+	if e.hold(cfg, Alert{Type: AlertBlockFound, MinerIP: "10.0.0.2"}, now) {
+		t.Error("expected block found alerts to go through maintenance")
+	}
+	if e.hold(cfg, Alert{Type: AlertTempHigh, MinerIP: "10.0.0.3"}, now) {
+		t.Error("expected other miners' alerts to go through")
+	}
+	if e.hold(cfg, Alert{Type: AlertTempHigh, MinerIP: "10.0.0.2"}, now.Add(2*time.Hour)) {
+		t.Error("expected alerts to go through after the window")
+	}
+	if e.MinerState("10.0.0.2").Maintenance == nil {
+		t.Error("expected the miner's state to show its maintenance window")
+	}
+}
+
+func TestMaintenanceFollowsMovedMiner(t *testing.T) {
+	now := time.Now()
+	store := testStore(t)
+	if err := store.UpsertMiner(&storage.Miner{IP: "10.0.0.2", Hostname: "axe", Enabled: true, LastSeen: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertMaintenanceWindow(&storage.MaintenanceWindow{MinerIP: "10.0.0.2", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	windows, _ := store.GetMaintenanceWindows()
+	cfg := &AlertConfig{}
+	e := NewAlertEngine(cfg)
+	e.SetStore(store)
+	e.SetMaintenance(windows)
+
+	if err := store.MoveMiner("10.0.0.2", "10.0.0.9"); err != nil {
+		t.Fatal(err)
+	}
+	e.MinerMoved("10.0.0.2")
+	if !e.hold(cfg, Alert{Type: AlertTempHigh, MinerIP: "10.0.0.9"}, now) {
+		t.Error("expected the moved miner to stay in maintenance")
+	}
+	if e.hold(cfg, Alert{Type: AlertTempHigh, MinerIP: "10.0.0.2"}, now) {
+		t.Error("expected the old IP to leave maintenance")
+	}
+}
@@ -0,0 +1,56 @@
+package alerts
+
+import "time"
+
+// rejectWindow is how far back the rejection rate is computed over
+const rejectWindow = 30 * time.Minute
+
+// minRejectSamples is how many shares must be submitted within the window
+// before the rejection rate is judged, so two rejects out of three shares
+// after a reboot don't alert
+const minRejectSamples = 20
+
+type rejectSample struct {
+	at       time.Time
+	accepted int64
+	rejected int64
+}
+
+// rejectHistory holds a miner's accepted and rejected share counters over
+// the last window
+type rejectHistory struct {
+	samples []rejectSample
+}
+
+// add records the counters and forgets samples older than the window, keeping
+// the last one before it as the baseline. The counters restart when the miner
+// reboots, which starts a new history.
+func (h *rejectHistory) add(at time.Time, accepted, rejected int64) {
+	if n := len(h.samples); n > 0 && (accepted < h.samples[n-1].accepted || rejected < h.samples[n-1].rejected) {
+		h.samples = nil
+	}
+	h.samples = append(h.samples, rejectSample{at: at, accepted: accepted, rejected: rejected})
+
+	cutoff := at.Add(-rejectWindow)
+	i := 0
+	for i < len(h.samples)-1 && !h.samples[i+1].at.After(cutoff) {
+		i++
+	}
+	h.samples = h.samples[i:]
+}
+
+// rate returns the percentage of shares rejected within the window. ok is
+// false until enough shares were submitted.
+func (h *rejectHistory) rate() (pct float64, submitted int64, ok bool) {
+	if len(h.samples) < 2 {
+		return 0, 0, false
+	}
+	first, last := h.samples[0], h.samples[len(h.samples)-1]
+	accepted := last.accepted - first.accepted
+	rejected := last.rejected - first.rejected
+	submitted = accepted + rejected
+	if submitted < minRejectSamples {
+		return 0, submitted, false
+	}
+	return float64(rejected) / float64(submitted) * 100, submitted, true
+}
@@ -0,0 +1,54 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestRejectHistory(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := &rejectHistory{}
+
+	h.add(start, 100, 2)
+	h.add(start.Add(time.Minute), 105, 3)
+	if _, _, ok := h.rate(); ok {
+		t.Error("expected no rate with only 6 shares submitted")
+	}
+
+	h.add(start.Add(10*time.Minute), 118, 10)
+	if pct, submitted, ok := h.rate(); !ok || submitted != 26 || pct < 30 || pct > 31 {
+		t.Errorf("rate = %.1f%% of %d, %v; want about 30.8%% of 26", pct, submitted, ok)
+	}
+
+	// Samples older than the window are forgotten
+	h.add(start.Add(45*time.Minute), 150, 10)
+	if pct, submitted, ok := h.rate(); !ok || submitted != 32 || pct != 0 {
+		t.Errorf("rate = %.1f%% of %d, %v; want 0%% of 32", pct, submitted, ok)
+	}
+
+	// A reboot resets the counters and the history
+	h.add(start.Add(46*time.Minute), 3, 0)
+	if len(h.samples) != 1 {
+		t.Errorf("kept %d samples after the counters restarted, want 1", len(h.samples))
+	}
+}
+
+func TestRejectRateCondition(t *testing.T) {
+	e, sent := recordingEngine(&AlertConfig{ShareRejectPct: 5})
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	snap := &storage.MinerSnapshot{MinerIP: "10.0.0.6", Hostname: "axe", PoolConnected: true}
+
+	for i, counts := range [][2]int64{{0, 0}, {40, 5}, {80, 10}, {200, 10}} {
+		snap.Timestamp = start.Add(time.Duration(i) * 10 * time.Minute)
+		snap.SharesAccept, snap.SharesReject = counts[0], counts[1]
+		e.CheckSnapshot(snap)
+	}
+	if len(*sent) != 1 || (*sent)[0] != AlertRejectRateHigh {
+		t.Fatalf("sent %v, want one reject_rate_high", *sent)
+	}
+	if open := e.OpenConditions(); len(open) != 0 {
+		t.Errorf("open conditions %v once the rate dropped", open)
+	}
+}
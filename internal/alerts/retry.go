@@ -0,0 +1,154 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// Alert channels, as recorded on failed deliveries. Other channels are
+// recorded by their configured name, see config.NotifierConfig.
+const (
+	ChannelDiscord = "discord"
+	ChannelMatrix  = "matrix"
+)
+
+// deliveryAttempts is how many times a notification is sent before it is
+// moved to the dead-letter queue. The wait doubles after each failure.
+const deliveryAttempts = 4
+
+// defaultRetryDelay is the wait before the first retry (then 4s, 8s)
+const defaultRetryDelay = 2 * time.Second
+
+// statusError is an HTTP error response from an alert channel
+type statusError struct {
+	channel string
+	code    int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.channel, e.code)
+}
+
+// retryable reports whether a failed send may succeed later. Network errors,
+// rate limits and server errors are retried; other client errors (bad
+// webhook URL, revoked token) won't fix themselves.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	return true
+}
+
+// SetStore sets where the alert history and undeliverable notifications
+// (for replay) are kept. Without a store alerts are only sent and logged.
+func (e *AlertEngine) SetStore(store *storage.SQLiteStorage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.store = store
+}
+
+// sendWithRetry calls send until it succeeds, fails with a permanent error or
+// runs out of attempts, doubling the wait after each failure. It returns the
+// number of attempts made and the last error.
+func (e *AlertEngine) sendWithRetry(channel, what string, send func() error) (int, error) {
+	delay := e.retryDelay
+	var err error
+	attempts := 0
+	for attempts < deliveryAttempts {
+		attempts++
+		if err = send(); err == nil {
+			return attempts, nil
+		}
+		if !retryable(err) || attempts == deliveryAttempts {
+			break
+		}
+		log.Printf("%s alert %s failed (attempt %d/%d), retrying in %v: %v", channel, what, attempts, deliveryAttempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	log.Printf("%s alert %s undeliverable after %d attempt(s): %v", channel, what, attempts, err)
+	return attempts, err
+}
+
+// deadLetter stores a notification that could not be delivered so it can be
+// replayed later
+func (e *AlertEngine) deadLetter(channel string, alert Alert, body []byte, attempts int, sendErr error) {
+	e.mu.RLock()
+	store := e.store
+	e.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	failed := &storage.FailedDelivery{
+		Timestamp:   alert.Timestamp,
+		Channel:     channel,
+		AlertType:   string(alert.Type),
+		MinerIP:     alert.MinerIP,
+		Payload:     string(body),
+		Attempts:    attempts,
+		LastError:   sendErr.Error(),
+		LastAttempt: time.Now(),
+	}
+	if failed.Timestamp.IsZero() {
+		failed.Timestamp = failed.LastAttempt
+	}
+	if err := store.InsertFailedDelivery(failed); err != nil {
+		log.Printf("Failed to store undelivered %s alert: %v", channel, err)
+	}
+}
+
+// ReplayFailedDelivery sends a dead-lettered notification once more to its
+// channel's current target. It is removed from the queue on success and its
+// attempt count is raised on failure.
+func (e *AlertEngine) ReplayFailedDelivery(d *storage.FailedDelivery) error {
+	e.mu.RLock()
+	config := e.config
+	store := e.store
+	e.mu.RUnlock()
+
+	if store == nil {
+		return fmt.Errorf("failed deliveries are not stored")
+	}
+
+	var err error
+	switch d.Channel {
+	case ChannelDiscord:
+		if config.WebhookURL == "" {
+			return fmt.Errorf("webhook URL is not configured")
+		}
+		err = e.postWebhook(config.WebhookURL, []byte(d.Payload))
+	case ChannelMatrix:
+		if !config.matrixConfigured() {
+			return fmt.Errorf("matrix is not configured")
+		}
+		err = e.postMatrix(config, newMatrixTxnID(), []byte(d.Payload))
+	default:
+		nc, ok := config.notifierByChannel(d.Channel)
+		if !ok {
+			return fmt.Errorf("unknown channel: %s", d.Channel)
+		}
+		n, nerr := newNotifier(nc)
+		if nerr != nil {
+			return nerr
+		}
+		err = n.send(e.client, []byte(d.Payload))
+	}
+
+	if err != nil {
+		if recErr := store.RecordFailedDeliveryAttempt(d.ID, err.Error(), time.Now()); recErr != nil {
+			log.Printf("Failed to update failed delivery %d: %v", d.ID, recErr)
+		}
+		return err
+	}
+
+	_, err = store.DeleteFailedDelivery(d.ID)
+	return err
+}
@@ -0,0 +1,130 @@
+package alerts
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// flakyService fails with status until it has been called failures times
+func flakyService(t *testing.T, status int, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// retryingEngine returns an engine with a store that retries without waiting
+func retryingEngine(t *testing.T, config *AlertConfig) (*AlertEngine, *storage.SQLiteStorage) {
+	t.Helper()
+	e := newAlertEngine(config)
+	e.retryDelay = time.Millisecond
+	store := testStore(t)
+	e.SetStore(store)
+	return e, store
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection refused"), true},
+		{&statusError{channel: "slack", code: http.StatusTooManyRequests}, true},
+		{&statusError{channel: "slack", code: http.StatusBadGateway}, true},
+		{&statusError{channel: "slack", code: http.StatusNotFound}, false},
+		{&statusError{channel: "slack", code: http.StatusUnauthorized}, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestNotifyRetriesThenSucceeds(t *testing.T) {
+	srv, calls := flakyService(t, http.StatusServiceUnavailable, 2)
+	e, store := retryingEngine(t, &AlertConfig{})
+
+	e.notify(config.NotifierConfig{Type: config.NotifierSlack, URL: srv.URL}, Alert{Type: AlertMinerOffline, MinerName: "axe"})
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if n := calls.Load(); n != 3 {
+		t.Errorf("expected 2 retries before the alert went through, got %d calls", n)
+	}
+	waitForFailedDeliveries(t, store, 0)
+}
+
+func TestNotifyDeadLettersAfterRetries(t *testing.T) {
+	srv, calls := flakyService(t, http.StatusInternalServerError, 100)
+	e, store := retryingEngine(t, &AlertConfig{})
+
+	alert := Alert{Type: AlertMinerOffline, MinerIP: "10.0.0.2", MinerName: "axe", Timestamp: time.Now()}
+	e.notify(config.NotifierConfig{Type: config.NotifierWebhook, Name: "home", URL: srv.URL}, alert)
+
+	failed := waitForFailedDeliveries(t, store, 1)
+	if d := failed[0]; d.Channel != "home" || d.MinerIP != "10.0.0.2" || d.Attempts != deliveryAttempts || d.LastError != "home returned status 500" || d.Payload == "" {
+		t.Errorf("unexpected failed delivery %+v", d)
+	}
+	if n := calls.Load(); n != deliveryAttempts {
+		t.Errorf("expected %d attempts, got %d", deliveryAttempts, n)
+	}
+}
+
+func TestNotifyPermanentErrorIsNotRetried(t *testing.T) {
+	srv, calls := flakyService(t, http.StatusNotFound, 100)
+	e, store := retryingEngine(t, &AlertConfig{})
+
+	e.notify(config.NotifierConfig{Type: config.NotifierSlack, URL: srv.URL}, Alert{Type: AlertMinerOffline, Timestamp: time.Now()})
+	if failed := waitForFailedDeliveries(t, store, 1); failed[0].Attempts != 1 || calls.Load() != 1 {
+		t.Errorf("expected one attempt at a permanent error, got %+v after %d calls", failed[0], calls.Load())
+	}
+}
+
+func TestReplayFailedDelivery(t *testing.T) {
+	srv, calls := flakyService(t, http.StatusBadGateway, 1)
+	e, store := retryingEngine(t, &AlertConfig{
+		Notifiers: []config.NotifierConfig{{Type: config.NotifierSlack, Name: "ops", URL: srv.URL}},
+	})
+
+	d := &storage.FailedDelivery{Timestamp: time.Now(), Channel: "ops", AlertType: string(AlertMinerOffline), Payload: `{"text":"offline"}`, Attempts: 4, LastError: "ops returned status 500", LastAttempt: time.Now()}
+	if err := store.InsertFailedDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	failed := waitForFailedDeliveries(t, store, 1)
+
+	// A failed replay is kept with one more attempt
+	if err := e.ReplayFailedDelivery(failed[0]); err == nil {
+		t.Fatal("expected the first replay to fail")
+	}
+	if failed = waitForFailedDeliveries(t, store, 1); failed[0].Attempts != 5 || failed[0].LastError != "ops returned status 502" {
+		t.Errorf("expected the attempt to be recorded, got %+v", failed[0])
+	}
+
+	if err := e.ReplayFailedDelivery(failed[0]); err != nil {
+		t.Fatalf("expected the replay to succeed, got %v", err)
+	}
+	waitForFailedDeliveries(t, store, 0)
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 sends, got %d", calls.Load())
+	}
+
+	if err := e.ReplayFailedDelivery(&storage.FailedDelivery{Channel: "removed"}); err == nil {
+		t.Error("expected a channel that is no longer configured to be rejected")
+	}
+}
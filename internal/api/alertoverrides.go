@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// validateAlertOverrides rejects overrides no alert could sensibly use. 0
+// turns a threshold off for the miner.
+func validateAlertOverrides(o *storage.AlertOverrides) error {
+	if o.TempAbove != nil && (*o.TempAbove < 0 || *o.TempAbove > 150) {
+		return fmt.Errorf("tempAbove %.1f must be between 0 and 150 °C", *o.TempAbove)
+	}
+	if o.HashrateDropPercent != nil && (*o.HashrateDropPercent < 0 || *o.HashrateDropPercent > 100) {
+		return fmt.Errorf("hashrateDropPercent %.1f must be between 0 and 100", *o.HashrateDropPercent)
+	}
+	if o.PoolDiffChangePct != nil && *o.PoolDiffChangePct < 0 {
+		return fmt.Errorf("poolDiffChangePct can't be negative")
+	}
+	if o.FanRPMBelow != nil && (*o.FanRPMBelow < 0 || *o.FanRPMBelow > 20000) {
+		return fmt.Errorf("fanRpmBelow %d must be between 0 and 20000", *o.FanRPMBelow)
+	}
+	if o.WifiSignalBelow != nil && (*o.WifiSignalBelow < -100 || *o.WifiSignalBelow > 0) {
+		return fmt.Errorf("wifiSignalBelow %d must be between -100 and 0 dBm", *o.WifiSignalBelow)
+	}
+	if o.MinerOfflineSeconds != nil && *o.MinerOfflineSeconds < 0 {
+		return fmt.Errorf("minerOfflineSeconds can't be negative")
+	}
+	if o.MinerFrozenSeconds != nil && *o.MinerFrozenSeconds < 0 {
+		return fmt.Errorf("minerFrozenSeconds can't be negative")
+	}
+	if o.CooldownMinutes != nil && (*o.CooldownMinutes < 0 || *o.CooldownMinutes > 24*60) {
+		return fmt.Errorf("cooldownMinutes %d must be between 0 and 1440", *o.CooldownMinutes)
+	}
+	return nil
+}
+
+// handleGetMinerAlerts returns a miner's alert overrides
+// GET /api/miners/{ip}/alerts
+func (s *Server) handleGetMinerAlerts(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	overrides, err := s.storage.GetAlertOverrides()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	o := overrides[ip]
+	if o == nil {
+		o = &storage.AlertOverrides{}
+	}
+	s.jsonResponse(w, map[string]interface{}{"ip": ip, "overrides": o})
+}
+
+// handleSetMinerAlerts replaces a miner's alert overrides. Omitted fields use
+// the global alert settings; an empty body clears every override.
+// PUT /api/miners/{ip}/alerts
+func (s *Server) handleSetMinerAlerts(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	var req storage.AlertOverrides
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateAlertOverrides(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.storage.SetAlertOverrides(ip, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s.alerts != nil {
+		s.alerts.SetMinerOverrides(ip, &req)
+	}
+
+	s.jsonResponse(w, map[string]interface{}{"success": true, "ip": ip, "overrides": req})
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// sessionCookie holds the session token of a web UI login
+const sessionCookie = "minerhq_session"
+
+// HashConfigPasswords replaces plaintext user passwords, API tokens and site
+// tokens in the config with hashes. Returns true if the config was modified and should be
+// saved.
+func HashConfigPasswords(cfg *config.Config) (bool, error) {
+	changed := false
+	for i := range cfg.Auth.Users {
+		u := &cfg.Auth.Users[i]
+		if u.Password == "" {
+			continue
+		}
+		hash, err := auth.HashPassword(u.Password)
+		if err != nil {
+			return changed, err
+		}
+		u.PasswordHash = hash
+		u.Password = ""
+		changed = true
+	}
+	for i := range cfg.Auth.Tokens {
+		t := &cfg.Auth.Tokens[i]
+		if t.Token == "" {
+			continue
+		}
+		t.TokenHash = auth.HashToken(t.Token)
+		t.Token = ""
+		changed = true
+	}
+	for i := range cfg.Sites {
+		site := &cfg.Sites[i]
+		if site.Token == "" {
+			continue
+		}
+		site.TokenHash = auth.HashToken(site.Token)
+		site.Token = ""
+		changed = true
+	}
+	return changed, nil
+}
+
+// withoutHashes returns a copy of cfg without the password, API token and site
+// token hashes, which never leave the server
+func withoutHashes(cfg *config.Config) *config.Config {
+	redacted := *cfg
+	redacted.Auth.Users = make([]config.UserConfig, len(cfg.Auth.Users))
+	for i, u := range cfg.Auth.Users {
+		u.PasswordHash = ""
+		redacted.Auth.Users[i] = u
+	}
+	redacted.Auth.Tokens = make([]config.TokenConfig, len(cfg.Auth.Tokens))
+	for i, t := range cfg.Auth.Tokens {
+		t.TokenHash = ""
+		redacted.Auth.Tokens[i] = t
+	}
+	redacted.Sites = make([]config.SiteConfig, len(cfg.Sites))
+	for i, site := range cfg.Sites {
+		site.TokenHash = ""
+		redacted.Sites[i] = site
+	}
+	return &redacted
+}
+
+// keepHashes gives users, API tokens and sites submitted without a new
+// password or token the hash stored for them in prev, since settings are
+// read without hashes
+func keepHashes(cfg, prev *config.Config) {
+	passwords := make(map[string]string)
+	for _, u := range prev.Auth.Users {
+		passwords[u.Username] = u.PasswordHash
+	}
+	for i := range cfg.Auth.Users {
+		if u := &cfg.Auth.Users[i]; u.Password == "" && u.PasswordHash == "" {
+			u.PasswordHash = passwords[u.Username]
+		}
+	}
+
+	tokens := make(map[string]string)
+	for _, t := range prev.Auth.Tokens {
+		tokens[t.Name] = t.TokenHash
+	}
+	for i := range cfg.Auth.Tokens {
+		if t := &cfg.Auth.Tokens[i]; t.Token == "" && t.TokenHash == "" {
+			t.TokenHash = tokens[t.Name]
+		}
+	}
+
+	sites := make(map[string]string)
+	for _, site := range prev.Sites {
+		sites[site.Name] = site.TokenHash
+	}
+	for i := range cfg.Sites {
+		if site := &cfg.Sites[i]; site.Token == "" && site.TokenHash == "" {
+			site.TokenHash = sites[site.Name]
+		}
+	}
+}
+
+// usersFromConfig converts configured accounts into authenticator users
+func usersFromConfig(cfg *config.Config) []*auth.User {
+	users := make([]*auth.User, 0, len(cfg.Auth.Users))
+	for _, u := range cfg.Auth.Users {
+		role := auth.Role(u.Role)
+		if !role.Valid() {
+			continue
+		}
+		users = append(users, &auth.User{
+			Username:     u.Username,
+			PasswordHash: u.PasswordHash,
+			Role:         role,
+		})
+	}
+	return users
+}
+
+// tokensFromConfig converts configured API tokens into authenticator tokens
+func tokensFromConfig(cfg *config.Config) []*auth.Token {
+	tokens := make([]*auth.Token, 0, len(cfg.Auth.Tokens))
+	for _, t := range cfg.Auth.Tokens {
+		role := auth.Role(t.Role)
+		if !role.Valid() || t.TokenHash == "" {
+			continue
+		}
+		tokens = append(tokens, &auth.Token{Name: t.Name, Hash: t.TokenHash, Role: role})
+	}
+	return tokens
+}
+
+// requestUser identifies the caller from, in order, a session cookie, a
+// bearer API token or HTTP Basic credentials
+func (s *Server) requestUser(r *http.Request) (*auth.User, bool) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if user, ok := s.auth.SessionUser(c.Value); ok {
+			return user, true
+		}
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.auth.AuthenticateToken(strings.TrimSpace(token))
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		return s.auth.Authenticate(username, password)
+	}
+	return nil, false
+}
+
+// isPublicPath reports whether a path is reachable without logging in: the
+// login page, its stylesheet, logging in and out, the health check, the API
+// documentation, the site agents' endpoint, which checks their tokens, and
+// share links and miner UI tickets, which check theirs
+func isPublicPath(path string) bool {
+	switch path {
+	case "/login", "/api/login", "/api/logout", "/api/health", "/static/css/style.css", "/api/openapi.json", "/api/docs", "/api/sites/ingest":
+		return true
+	}
+	return strings.HasPrefix(path, sharePrefix) || strings.HasPrefix(path, minerUIPrefix)
+}
+
+// authenticate requires valid credentials when auth is enabled and stores the
+// user in the request context
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.cfg().Auth.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if user, ok := s.requestUser(r); ok {
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+			return
+		}
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Send browsers to the login page; API clients get a 401
+		if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="MinerHQ"`)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+	})
+}
+
+// requireRoleForMutations rejects state-changing requests from users whose
+// role doesn't allow them (viewers)
+func (s *Server) requireRoleForMutations(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := auth.UserFromContext(r.Context()); user != nil && isMutating(r) && !user.Role.CanMutate() {
+			http.Error(w, "insufficient permissions", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether the request may see and change administrative data.
+// Always true when auth is disabled.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if !s.cfg().Auth.Enabled {
+		return true
+	}
+	user := auth.UserFromContext(r.Context())
+	return user != nil && user.Role == auth.RoleAdmin
+}
+
+// handleGetMe returns the authenticated user
+// GET /api/me
+func (s *Server) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		// Auth disabled: everyone is effectively an admin
+		s.jsonResponse(w, map[string]interface{}{
+			"authEnabled": false,
+			"role":        auth.RoleAdmin,
+			"readOnly":    s.cfg().Server.ReadOnly,
+		})
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"authEnabled": true,
+		"username":    user.Username,
+		"role":        user.Role,
+		"readOnly":    s.cfg().Server.ReadOnly,
+	})
+}
+
+// LoginRequest is the body of POST /api/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// handleLogin checks a username and password and starts a session, stored
+// in an HttpOnly cookie
+// POST /api/login
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg().Auth.Enabled {
+		http.Error(w, "authentication is disabled", http.StatusNotFound)
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	user, ok := s.auth.Authenticate(req.Username, req.Password)
+	if !ok {
+		log.Printf("Failed login for %q from %s", req.Username, r.RemoteAddr)
+		http.Error(w, "invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	token, expires, err := s.auth.NewSession(user, time.Duration(s.cfg().Auth.SessionHours)*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	s.jsonResponse(w, map[string]interface{}{
+		"username": user.Username,
+		"role":     user.Role,
+		"expires":  expires,
+	})
+}
+
+// handleLogout ends the session and clears its cookie
+// POST /api/logout
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		s.auth.EndSession(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleLoginPage serves the web UI login form
+// GET /login
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg().Auth.Enabled {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	s.serveWebFile(w, r, "templates/login.html")
+}
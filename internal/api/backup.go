@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// maxRestoreSize bounds the size of an uploaded backup
+const maxRestoreSize = 4 << 30
+
+// handleBackup returns a consistent copy of the database as a download. It's
+// safe while collecting, so the container doesn't have to be stopped.
+// POST /api/backup
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		http.Error(w, "insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "minerhq-backup-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "minerhq.db")
+	if err := s.storage.Backup(path); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	now := time.Now()
+	name := fmt.Sprintf("minerhq-%s.db", now.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(w, r, name, now, f)
+}
+
+// handleRestore replaces all data with an uploaded backup while running. The
+// backup is sent as the request body or as the "file" field of a multipart
+// form. Settings in config.json are not part of the database and stay as
+// they are.
+// POST /api/restore
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		http.Error(w, "insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreSize)
+	var upload io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "missing backup file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		upload = file
+	}
+
+	dir, err := os.MkdirTemp("", "minerhq-restore-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "minerhq.db")
+	f, err := os.Create(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = io.Copy(f, upload)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		http.Error(w, "failed to receive backup: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.CheckBackup(path); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.storage.Restore(path); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Collect from the restored miner list
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.collector.Resync(miners)
+	if s.alerts != nil {
+		if overrides, err := s.storage.GetAlertOverrides(); err == nil {
+			s.alerts.SetOverrides(overrides)
+		}
+	}
+	s.reloadDisplayNames()
+	s.reloadMaintenance()
+	log.Printf("Database restored from backup, %d miners", len(miners))
+
+	s.jsonResponse(w, map[string]interface{}{"success": true, "miners": len(miners)})
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleGetCollectorStatus returns the collector's loops and, per miner, the
+// last poll and its latency, the latest error and the WebSocket connection
+// with its reconnect count
+// GET /api/collector/status
+func (s *Server) handleGetCollectorStatus(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.collector.Health())
+}
+
+// handleReconnectMiner drops a miner's WebSocket and connects it again right
+// away, e.g. after the miner's log stream stalled
+// POST /api/collector/{ip}/reconnect
+func (s *Server) handleReconnectMiner(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if !s.collector.ReconnectMiner(ip) {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "reconnecting"})
+}
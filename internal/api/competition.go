@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// CompetitionPeriodResult is the final standings of one past competition period
+type CompetitionPeriodResult struct {
+	Period    string                       `json:"period"` // "daily", "weekly" or "monthly"
+	Start     time.Time                    `json:"start"`
+	End       time.Time                    `json:"end"`
+	Standings []*storage.CompetitionResult `json:"standings"`
+}
+
+// CompetitionHistory is the past competition periods and the hall of fame
+// of podium finishes across all of them
+type CompetitionHistory struct {
+	Periods  []CompetitionPeriodResult `json:"periods"`
+	Trophies []*storage.Trophies       `json:"trophies"`
+}
+
+// handleGetCompetitionHistory returns the final best share standings of past
+// competition periods, newest first, with each miner's trophy counts
+// GET /api/competition/history
+// Query params: limit (periods, default 10)
+func (s *Server) handleGetCompetitionHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	results, err := s.storage.GetCompetitionHistory(limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	trophies, err := s.storage.GetTrophies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	history := CompetitionHistory{Periods: []CompetitionPeriodResult{}, Trophies: trophies}
+	if history.Trophies == nil {
+		history.Trophies = []*storage.Trophies{}
+	}
+	names := s.displayNames()
+	for _, t := range history.Trophies {
+		t.Hostname = nameOr(names, t.MinerIP, t.Hostname)
+	}
+	for _, result := range results {
+		result.Hostname = nameOr(names, result.MinerIP, result.Hostname)
+		n := len(history.Periods)
+		if n == 0 || !history.Periods[n-1].Start.Equal(result.PeriodStart) {
+			history.Periods = append(history.Periods, CompetitionPeriodResult{
+				Period: result.Period,
+				Start:  result.PeriodStart,
+				End:    result.PeriodEnd,
+			})
+			n++
+		}
+		history.Periods[n-1].Standings = append(history.Periods[n-1].Standings, result)
+	}
+
+	s.jsonResponse(w, history)
+}
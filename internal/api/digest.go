@@ -0,0 +1,181 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/competition"
+)
+
+// Digest summarizes a finished competition period
+type Digest struct {
+	Period     string         `json:"period"` // "daily", "weekly" or "monthly"
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Shares     int            `json:"shares"`   // Shares the fleet submitted
+	BestDiff   float64        `json:"bestDiff"` // Best accepted share, the winner's
+	Blocks     int            `json:"blocks"`
+	WinnerIP   string         `json:"winnerIp,omitempty"` // Unset when no miner submitted a share
+	Winner     string         `json:"winner,omitempty"`
+	EnergyKWh  float64        `json:"energyKwh"` // Measured on the local days within the period
+	EnergyCost float64        `json:"energyCost"`
+	Currency   string         `json:"currency"`
+	Uptime     []DigestUptime `json:"uptime"` // Tracked miners, least available first
+}
+
+// DigestUptime is a miner's availability over a digest's period
+type DigestUptime struct {
+	MinerIP      string  `json:"minerIp"`
+	Hostname     string  `json:"hostname"`
+	Availability float64 `json:"availability"` // Percent of the tracked time online
+	Failures     int     `json:"failures"`     // Times the miner went offline
+}
+
+// buildDigest summarizes the competition period ending at end
+func (s *Server) buildDigest(period competition.Period, end time.Time) (*Digest, error) {
+	start := period.Previous(end)
+	d := &Digest{Period: period.Adjective(), Start: start, End: end, Uptime: []DigestUptime{}}
+
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(miners))
+	for _, m := range miners {
+		names[m.IP] = m.Name()
+	}
+
+	// Won on the configured scoring, like the stored standings
+	entries, err := s.storage.GetWeeklyLeaderboard(start, end)
+	if err != nil {
+		return nil, err
+	}
+	scorer, err := s.storage.CompetitionScorer(s.cfg().Competition.Scoring, s.cfg().ExpectedHashrates(), start, end)
+	if err != nil {
+		return nil, err
+	}
+	var winnerScore float64
+	for _, e := range entries {
+		d.Shares += e.ShareCount
+		d.Blocks += e.Blocks
+		if score := scorer.Score(e.MinerIP, e.BestDiff); d.WinnerIP == "" || score > winnerScore || score == winnerScore && e.MinerIP < d.WinnerIP {
+			winnerScore = score
+			d.BestDiff = e.BestDiff
+			d.WinnerIP = e.MinerIP
+			d.Winner = names[e.MinerIP]
+			if d.Winner == "" {
+				d.Winner = e.Hostname
+			}
+		}
+	}
+
+	// Energy is kept per local calendar day
+	usage, err := s.storage.GetEnergyDaily(start.In(time.Local))
+	if err != nil {
+		return nil, err
+	}
+	lastDay := end.In(time.Local).Format("2006-01-02")
+	for _, u := range usage {
+		if u.Day < lastDay {
+			d.EnergyKWh += u.KWh
+			d.EnergyCost += u.Cost
+		}
+	}
+	d.Currency = s.convertAmounts(s.cfg().Energy.Currency, &d.EnergyCost)
+
+	for _, m := range miners {
+		events, err := s.storage.GetUptimeEvents(m.IP, start)
+		if err != nil {
+			return nil, err
+		}
+		for len(events) > 0 && !events[len(events)-1].Timestamp.Before(end) {
+			events = events[:len(events)-1]
+		}
+		if len(events) == 0 {
+			continue
+		}
+		report := uptimeReport(events, start, end)
+		d.Uptime = append(d.Uptime, DigestUptime{MinerIP: m.IP, Hostname: m.Name(), Availability: report.Availability, Failures: report.Failures})
+	}
+	sort.SliceStable(d.Uptime, func(i, j int) bool {
+		return d.Uptime[i].Availability < d.Uptime[j].Availability
+	})
+
+	return d, nil
+}
+
+// maxUptimeLines keeps the uptime field within Discord's 1024 characters
+const maxUptimeLines = 20
+
+// digestMessage renders a digest as an alert message and fields
+func digestMessage(d *Digest) (string, []map[string]interface{}) {
+	first, last := d.Start.Format("Jan 2"), d.End.Add(-time.Second).Format("Jan 2")
+	message := fmt.Sprintf("%s%s summary from %s to %s", strings.ToUpper(d.Period[:1]), d.Period[1:], first, last)
+	if first == last {
+		message = fmt.Sprintf("%s%s summary for %s", strings.ToUpper(d.Period[:1]), d.Period[1:], first)
+	}
+
+	winner := "No shares"
+	if d.Winner != "" {
+		winner = d.Winner
+	}
+	fields := []map[string]interface{}{
+		{"name": "Shares", "value": fmt.Sprint(d.Shares), "inline": true},
+		{"name": "Best Share", "value": collector.FormatDifficulty(d.BestDiff), "inline": true},
+		{"name": "Blocks", "value": fmt.Sprint(d.Blocks), "inline": true},
+		{"name": "Winner", "value": winner, "inline": true},
+		{"name": "Energy", "value": fmt.Sprintf("%.1f kWh (%.2f %s)", d.EnergyKWh, d.EnergyCost, d.Currency), "inline": true},
+	}
+
+	if len(d.Uptime) > 0 {
+		var lines []string
+		for i, u := range d.Uptime {
+			if i == maxUptimeLines {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(d.Uptime)-i))
+				break
+			}
+			line := fmt.Sprintf("%s: %.1f%%", u.Hostname, u.Availability)
+			if u.Failures == 1 {
+				line += " (1 outage)"
+			} else if u.Failures > 1 {
+				line += fmt.Sprintf(" (%d outages)", u.Failures)
+			}
+			lines = append(lines, line)
+		}
+		fields = append(fields, map[string]interface{}{"name": "Uptime", "value": strings.Join(lines, "\n"), "inline": false})
+	}
+
+	return message, fields
+}
+
+// SendDigest sends the summary of the competition period ending at end to
+// the alert channels
+func (s *Server) SendDigest(period competition.Period, end time.Time) {
+	if s.alerts == nil || !s.cfg().Alerts.OnDigest {
+		return
+	}
+	d, err := s.buildDigest(period, end)
+	if err != nil {
+		log.Printf("Digest error: %v", err)
+		return
+	}
+	s.alerts.SendDigest(digestMessage(d))
+}
+
+// handleGetDigest returns the summary of the last finished competition
+// period, as sent when it ended
+// GET /api/competition/digest
+func (s *Server) handleGetDigest(w http.ResponseWriter, r *http.Request) {
+	period := s.cfg().Competition.CompetitionPeriod()
+	d, err := s.buildDigest(period, period.Start(time.Now()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, d)
+}
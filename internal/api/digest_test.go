@@ -0,0 +1,76 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestMessage(t *testing.T) {
+	start := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	d := &Digest{
+		Period:     "weekly",
+		Start:      start,
+		End:        start.AddDate(0, 0, 7),
+		Shares:     48210,
+		BestDiff:   4.29e9,
+		Blocks:     1,
+		Winner:     "BitAxe-Ultra",
+		EnergyKWh:  18.42,
+		EnergyCost: 2.21,
+		Currency:   "USD",
+		Uptime: []DigestUptime{
+			{Hostname: "BitAxe-Supra", Availability: 97.2, Failures: 2},
+			{Hostname: "BitAxe-Ultra", Availability: 100},
+		},
+	}
+
+	message, fields := digestMessage(d)
+	if message != "Weekly summary from Oct 5 to Oct 11" {
+		t.Errorf("unexpected message %q", message)
+	}
+	values := make(map[string]interface{})
+	for _, f := range fields {
+		values[f["name"].(string)] = f["value"]
+	}
+	want := map[string]string{
+		"Shares":     "48210",
+		"Best Share": "4.29G",
+		"Blocks":     "1",
+		"Winner":     "BitAxe-Ultra",
+		"Energy":     "18.4 kWh (2.21 USD)",
+		"Uptime":     "BitAxe-Supra: 97.2% (2 outages)\nBitAxe-Ultra: 100.0%",
+	}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s: expected %q, got %q", name, value, values[name])
+		}
+	}
+
+	t.Run("daily period without shares", func(t *testing.T) {
+		message, fields := digestMessage(&Digest{Period: "daily", Start: start, End: start.AddDate(0, 0, 1), Currency: "USD", Uptime: []DigestUptime{}})
+		if message != "Daily summary for Oct 5" {
+			t.Errorf("unexpected message %q", message)
+		}
+		for _, f := range fields {
+			if f["name"] == "Winner" && f["value"] != "No shares" {
+				t.Errorf("expected no winner, got %q", f["value"])
+			}
+			if f["name"] == "Uptime" {
+				t.Error("expected no uptime field without tracked miners")
+			}
+		}
+	})
+
+	t.Run("long uptime list is cut", func(t *testing.T) {
+		d := &Digest{Period: "weekly", Start: start, End: start.AddDate(0, 0, 7)}
+		for i := 0; i < maxUptimeLines+5; i++ {
+			d.Uptime = append(d.Uptime, DigestUptime{Hostname: "miner", Availability: 99})
+		}
+		_, fields := digestMessage(d)
+		uptime := fields[len(fields)-1]["value"].(string)
+		if lines := strings.Split(uptime, "\n"); len(lines) != maxUptimeLines+1 || lines[maxUptimeLines] != "…and 5 more" {
+			t.Errorf("expected %d lines and a remainder, got %q", maxUptimeLines+1, uptime)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// sseHeartbeat is how often an event stream sends a heartbeat, so clients
+	// and proxies can tell an idle stream from a dead one
+	sseHeartbeat = 15 * time.Second
+
+	// sseBuffer is how many events wait for a slow stream client before
+	// further events are dropped for it
+	sseBuffer = 64
+)
+
+// sseClient is a client of the event stream and its subscription
+type sseClient struct {
+	sub    *subscription
+	events chan Message
+}
+
+// send queues msg if it matches the client's subscription. A client that
+// falls behind misses events rather than stalling the hub.
+func (c *sseClient) send(msg Message) {
+	if !c.sub.matches(msg) {
+		return
+	}
+	select {
+	case c.events <- msg:
+	default:
+	}
+}
+
+// handleEvents streams the WebSocket events as Server-Sent Events, for
+// clients that can't use WebSockets. Each event is named after its type
+// ("share", "snapshot", "block", "alert" or "log") and carries the message's
+// data as JSON; a "heartbeat" event is sent every 15 seconds. The filters are
+// the WebSocket's: ?types=block,alert&miners=192.168.1.50.
+// GET /api/events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	sub, err := newSubscription(splitList(r.URL.Query().Get("types")), splitList(r.URL.Query().Get("miners")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	client := &sseClient{sub: sub, events: make(chan Message, sseBuffer)}
+	s.hub.addStream(client)
+	defer s.hub.removeStream(client)
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.hub.done:
+			return
+		case msg := <-client.events:
+			data, err := json.Marshal(msg.Data)
+			if err != nil {
+				log.Printf("Event stream marshal error: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data); err != nil {
+				return
+			}
+		case now := <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, "event: heartbeat\ndata: {\"time\":%q}\n\n", now.UTC().Format(time.RFC3339)); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
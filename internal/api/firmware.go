@@ -0,0 +1,288 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxFirmwareSize bounds an uploaded image; ESP32 OTA partitions are 4 MB at most
+const maxFirmwareSize = 8 << 20
+
+// maxRollouts is how many finished rollouts are kept for GET /api/firmware/updates
+const maxRollouts = 20
+
+// FamilyRelease is the latest release of a firmware family, or why it
+// couldn't be looked up
+type FamilyRelease struct {
+	Family  string                     `json:"family"`
+	Release *collector.FirmwareRelease `json:"release,omitempty"`
+	Error   string                     `json:"error,omitempty"`
+}
+
+// MinerFirmware is a miner's installed firmware against the latest release
+type MinerFirmware struct {
+	IP              string `json:"ip"`
+	Hostname        string `json:"hostname"`
+	DeviceModel     string `json:"deviceModel"`
+	Family          string `json:"family"`
+	Version         string `json:"version"`
+	Latest          string `json:"latest,omitempty"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+}
+
+// handleGetFirmwareReleases returns the latest upstream release of each
+// firmware family and which miners run an older version
+// GET /api/firmware/releases
+func (s *Server) handleGetFirmwareReleases(w http.ResponseWriter, r *http.Request) {
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	releases := make([]FamilyRelease, 0, 2)
+	latest := make(map[string]string)
+	for _, family := range []string{collector.FamilyNerdQAxe, collector.FamilyAxeOS} {
+		release, err := s.releases.Latest(family)
+		if err != nil {
+			releases = append(releases, FamilyRelease{Family: family, Error: err.Error()})
+			continue
+		}
+		releases = append(releases, FamilyRelease{Family: family, Release: release})
+		latest[family] = release.Version
+	}
+
+	result := make([]MinerFirmware, 0, len(miners))
+	for _, m := range miners {
+		if !m.Enabled {
+			continue
+		}
+		mf := MinerFirmware{
+			IP:          m.IP,
+			Hostname:    m.Hostname,
+			DeviceModel: m.DeviceModel,
+			Family:      collector.FirmwareFamily(m),
+			Version:     m.FirmwareVersion,
+		}
+		mf.Latest = latest[mf.Family]
+		mf.UpdateAvailable = mf.Latest != "" && mf.Version != "" && collector.CompareVersions(mf.Version, mf.Latest) < 0
+		result = append(result, mf)
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"releases": releases,
+		"miners":   result,
+	})
+}
+
+// otaTargets resolves the miners to flash and checks they can share the
+// uploaded images: one firmware family and, for NerdQAxe, whose builds are
+// per board, one model
+func otaTargets(ips []string, miners []*storage.Miner) ([]*collector.OTAMinerStatus, error) {
+	byIP := make(map[string]*storage.Miner, len(miners))
+	for _, m := range miners {
+		if m.Enabled {
+			byIP[m.IP] = m
+		}
+	}
+
+	var family, model string
+	seen := make(map[string]bool, len(ips))
+	targets := make([]*collector.OTAMinerStatus, 0, len(ips))
+	for _, ip := range ips {
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+
+		m, ok := byIP[ip]
+		if !ok {
+			return nil, fmt.Errorf("miner %s not found", ip)
+		}
+		f := collector.FirmwareFamily(m)
+		if f == "" {
+			return nil, fmt.Errorf("miner %s runs unknown firmware %q", ip, m.DeviceModel)
+		}
+		if family == "" {
+			family, model = f, m.DeviceModel
+		}
+		if f != family {
+			return nil, fmt.Errorf("miner %s runs %s firmware, not %s", ip, f, family)
+		}
+		if f == collector.FamilyNerdQAxe && m.DeviceModel != model {
+			return nil, fmt.Errorf("miner %s is a %s, not a %s", ip, m.DeviceModel, model)
+		}
+
+		targets = append(targets, &collector.OTAMinerStatus{
+			IP:          m.IP,
+			Hostname:    m.Hostname,
+			FromVersion: m.FirmwareVersion,
+		})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no miners to update")
+	}
+	return targets, nil
+}
+
+// receiveImage saves the uploaded image of the given kind to dir. It returns
+// nil if the form has no such file.
+func receiveImage(r *http.Request, kind, dir string) (*collector.OTAImage, error) {
+	file, header, err := r.FormFile(kind)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	path := filepath.Join(dir, kind+".bin")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(f, file)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == collector.ImageFirmware {
+		magic := make([]byte, 1)
+		if f, err := os.Open(path); err == nil {
+			io.ReadFull(f, magic)
+			f.Close()
+		}
+		if err := collector.CheckFirmwareImage(magic); err != nil {
+			return nil, fmt.Errorf("%s: %w", header.Filename, err)
+		}
+	}
+	return collector.NewOTAImage(kind, header.Filename, path)
+}
+
+// handleFirmwareUpdate starts flashing uploaded images to miners. The
+// multipart form has "firmware" (esp-miner.bin) and/or "www" (www.bin) files
+// and the target IPs as "miners", repeated or comma-separated. Miners are
+// updated one at a time in the background; poll the returned rollout's ID
+// for progress.
+// POST /api/firmware/update
+func (s *Server) handleFirmwareUpdate(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		http.Error(w, "insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxFirmwareSize)
+	if err := r.ParseMultipartForm(maxFirmwareSize); err != nil {
+		http.Error(w, "invalid upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var ips []string
+	for _, field := range r.MultipartForm.Value["miners"] {
+		for _, ip := range strings.Split(field, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	targets, err := otaTargets(ips, miners)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "minerhq-ota-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var images []*collector.OTAImage
+	for _, kind := range []string{collector.ImageFirmware, collector.ImageWWW} {
+		img, err := receiveImage(r, kind, dir)
+		if err != nil {
+			os.RemoveAll(dir)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if img != nil {
+			images = append(images, img)
+		}
+	}
+	if len(images) == 0 {
+		os.RemoveAll(dir)
+		http.Error(w, "no firmware or www image uploaded", http.StatusBadRequest)
+		return
+	}
+
+	s.otaMu.Lock()
+	for _, existing := range s.rollouts {
+		if existing.Running() {
+			s.otaMu.Unlock()
+			os.RemoveAll(dir)
+			http.Error(w, "an update is already running", http.StatusConflict)
+			return
+		}
+	}
+	rollout := collector.NewOTARollout(images, targets)
+	s.rollouts = append(s.rollouts, rollout)
+	if len(s.rollouts) > maxRollouts {
+		s.rollouts = s.rollouts[len(s.rollouts)-maxRollouts:]
+	}
+	s.otaMu.Unlock()
+
+	log.Printf("OTA rollout %s started for %d miners", rollout.ID, len(targets))
+	go func() {
+		defer os.RemoveAll(dir)
+		s.collector.RunOTA(rollout, s.control)
+	}()
+
+	s.jsonResponse(w, rollout.Status())
+}
+
+// handleGetFirmwareUpdates returns recent rollouts, newest first
+// GET /api/firmware/updates
+func (s *Server) handleGetFirmwareUpdates(w http.ResponseWriter, r *http.Request) {
+	s.otaMu.Lock()
+	rollouts := make([]collector.OTAStatus, 0, len(s.rollouts))
+	for i := len(s.rollouts) - 1; i >= 0; i-- {
+		rollouts = append(rollouts, s.rollouts[i].Status())
+	}
+	s.otaMu.Unlock()
+
+	s.jsonResponse(w, rollouts)
+}
+
+// handleGetFirmwareUpdate returns a rollout's per-miner progress
+// GET /api/firmware/updates/{id}
+func (s *Server) handleGetFirmwareUpdate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	s.otaMu.Lock()
+	defer s.otaMu.Unlock()
+	for _, rollout := range s.rollouts {
+		if rollout.ID == id {
+			s.jsonResponse(w, rollout.Status())
+			return
+		}
+	}
+	http.Error(w, "update not found", http.StatusNotFound)
+}
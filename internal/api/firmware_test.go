@@ -0,0 +1,43 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestOTATargets(t *testing.T) {
+	miners := []*storage.Miner{
+		{IP: "192.168.1.50", DeviceModel: "NerdQAxe++", FirmwareVersion: "v1.0.30", Enabled: true},
+		{IP: "192.168.1.51", DeviceModel: "NerdQAxe++", FirmwareVersion: "v1.0.29", Enabled: true},
+		{IP: "192.168.1.52", DeviceModel: "NerdOCTAXE-γ", Enabled: true},
+		{IP: "192.168.1.60", DeviceModel: "AxeOS (BM1370)", Enabled: true},
+		{IP: "192.168.1.61", DeviceModel: "AxeOS (BM1368)", Enabled: true},
+		{IP: "192.168.1.70", DeviceModel: "NerdQAxe++", Enabled: false},
+	}
+
+	targets, err := otaTargets([]string{"192.168.1.50", "192.168.1.51", "192.168.1.50"}, miners)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[1].FromVersion != "v1.0.29" {
+		t.Errorf("unexpected targets: %+v", targets)
+	}
+
+	// AxeOS images are shared across boards
+	if _, err := otaTargets([]string{"192.168.1.60", "192.168.1.61"}, miners); err != nil {
+		t.Errorf("AxeOS miners of different boards rejected: %v", err)
+	}
+
+	for name, ips := range map[string][]string{
+		"mixed families": {"192.168.1.50", "192.168.1.60"},
+		"mixed NerdQAxe": {"192.168.1.50", "192.168.1.52"},
+		"disabled miner": {"192.168.1.70"},
+		"unknown miner":  {"192.168.1.99"},
+		"no miners":      nil,
+	} {
+		if _, err := otaTargets(ips, miners); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -3,45 +3,118 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
+	"math"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/camarigor/miner-hq/internal/alerts"
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/competition"
+	"github.com/camarigor/miner-hq/internal/config"
 	"github.com/camarigor/miner-hq/internal/pricing"
 	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
 )
 
 // MinerWithSnapshot combines miner info with latest snapshot
 type MinerWithSnapshot struct {
 	IP          string                 `json:"ip"`
 	Hostname    string                 `json:"hostname"`
+	DisplayName string                 `json:"displayName,omitempty"`
 	DeviceModel string                 `json:"deviceModel"`
 	ASICModel   string                 `json:"asicModel"`
 	Enabled     bool                   `json:"enabled"`
+	Paused      bool                   `json:"paused"`
 	Online      bool                   `json:"online"`
 	CoinID      string                 `json:"coinId"`
+	Site        string                 `json:"site,omitempty"` // Remote site, empty for this server's own miners
+	Tags        []string               `json:"tags"`
 	Snapshot    *storage.MinerSnapshot `json:"snapshot,omitempty"`
 }
 
 // handleGetMiners returns all miners with online status and latest snapshot
 // GET /api/miners
+// Query params: include_disabled (also list paused and removed miners)
 func (s *Server) handleGetMiners(w http.ResponseWriter, r *http.Request) {
-	miners, err := s.storage.GetMiners()
+	list := s.storage.GetMiners
+	if all, _ := strconv.ParseBool(r.URL.Query().Get("include_disabled")); all {
+		list = s.storage.GetAllMiners
+	}
+	miners, err := list()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	s.jsonResponse(w, s.minerCards(miners))
+}
+
+// latestSnapshot returns a miner's most recent snapshot from the last 5
+// minutes. The collector's copy is preferred, as not every poll is stored.
+func (s *Server) latestSnapshot(ip string) *storage.MinerSnapshot {
+	since := time.Now().Add(-5 * time.Minute)
+	if snap := s.collector.LatestSnapshot(ip); snap != nil && snap.Timestamp.After(since) {
+		return snap
+	}
+	snapshots, err := s.storage.GetSnapshots(ip, since, 1)
+	if err == nil && len(snapshots) > 0 {
+		return snapshots[0]
+	}
+	return nil
+}
+
+// latestSnapshots returns the most recent snapshot from the last 5 minutes
+// of each of the miners, keyed by IP, like latestSnapshot. The database is
+// queried once, and only if the collector lacks a recent copy for a miner.
+func (s *Server) latestSnapshots(miners []*storage.Miner) map[string]*storage.MinerSnapshot {
+	since := time.Now().Add(-5 * time.Minute)
+	latest := s.collector.LatestSnapshots()
+
+	missing := false
+	for _, m := range miners {
+		if snap := latest[m.IP]; snap == nil || !snap.Timestamp.After(since) {
+			delete(latest, m.IP)
+			missing = true
+		}
+	}
+	if !missing {
+		return latest
+	}
+
+	stored, err := s.storage.GetLatestSnapshots(since)
+	if err != nil {
+		log.Printf("Failed to load latest snapshots: %v", err)
+		return latest
+	}
+	for _, m := range miners {
+		if _, ok := latest[m.IP]; !ok && stored[m.IP] != nil {
+			latest[m.IP] = stored[m.IP]
+		}
+	}
+	return latest
+}
+
+// minerCards pairs each miner with its online status and latest snapshot
+func (s *Server) minerCards(miners []*storage.Miner) []MinerWithSnapshot {
 	// Get current online status from collector
 	status := s.collector.GetMinerStatus()
+	snapshots := s.latestSnapshots(miners)
+	tags, err := s.storage.GetMinerTags()
+	if err != nil {
+		log.Printf("Failed to load miner tags: %v", err)
+	}
 
 	// Build response with snapshots
 	result := make([]MinerWithSnapshot, 0, len(miners))
@@ -49,27 +122,30 @@ func (s *Server) handleGetMiners(w http.ResponseWriter, r *http.Request) {
 		mws := MinerWithSnapshot{
 			IP:          m.IP,
 			Hostname:    m.Hostname,
+			DisplayName: m.DisplayName,
 			DeviceModel: m.DeviceModel,
 			ASICModel:   m.ASICModel,
 			Enabled:     m.Enabled,
+			Paused:      m.Paused,
 			Online:      false,
 			CoinID:      m.CoinID,
+			Site:        m.Site,
+			Tags:        tags[m.IP],
+		}
+		if mws.Tags == nil {
+			mws.Tags = []string{}
 		}
 
 		if online, ok := status[m.IP]; ok {
 			mws.Online = online
 		}
 
-		// Get latest snapshot for this miner
-		snapshots, err := s.storage.GetSnapshots(m.IP, time.Now().Add(-5*time.Minute), 1)
-		if err == nil && len(snapshots) > 0 {
-			mws.Snapshot = snapshots[0]
-		}
+		mws.Snapshot = snapshots[m.IP]
 
 		result = append(result, mws)
 	}
 
-	s.jsonResponse(w, result)
+	return result
 }
 
 // handleGetMiner returns a single miner by IP
@@ -98,10 +174,24 @@ func (s *Server) handleGetMiner(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "miner not found", http.StatusNotFound)
 }
 
-// handleGetMinerHistory returns miner snapshots history
-// GET /api/miners/{ip}/history
-// Query params: hours (default 24), limit (default 1000)
-func (s *Server) handleGetMinerHistory(w http.ResponseWriter, r *http.Request) {
+// MinerDetail gathers everything the miner detail page shows
+type MinerDetail struct {
+	Miner        *storage.Miner          `json:"miner"`
+	Snapshot     *storage.MinerSnapshot  `json:"snapshot,omitempty"`
+	UptimeSecs   int64                   `json:"uptimeSeconds"`
+	BootedAt     *time.Time              `json:"bootedAt,omitempty"`
+	RecentShares []*storage.Share        `json:"recentShares"`
+	BestShares   []*storage.Share        `json:"bestShares"` // Highest difficulty among retained shares
+	Blocks       []*storage.Block        `json:"blocks"`
+	BlockCount   int                     `json:"blockCount"`
+	Alerts       *alerts.MinerAlertState `json:"alerts,omitempty"`
+}
+
+// handleGetMinerDetail returns a miner with its latest snapshot, shares,
+// blocks and alert state in one response
+// GET /api/miners/{ip}/detail
+// Query params: hours (default 24, for shares), limit (default 20 per list)
+func (s *Server) handleGetMinerDetail(w http.ResponseWriter, r *http.Request) {
 	ip := chi.URLParam(r, "ip")
 
 	hours := 24
@@ -111,23 +201,212 @@ func (s *Server) handleGetMinerHistory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	limit := 1000
+	limit := 20
 	if l := r.URL.Query().Get("limit"); l != "" {
 		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
 			limit = parsed
 		}
 	}
 
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	detail := &MinerDetail{}
+	for _, m := range miners {
+		if m.IP == ip {
+			detail.Miner = m
+			break
+		}
+	}
+	if detail.Miner == nil {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+	if online, ok := s.collector.GetMinerStatus()[ip]; ok {
+		detail.Miner.Online = online
+	}
+
+	if detail.Snapshot = s.latestSnapshot(ip); detail.Snapshot != nil {
+		detail.UptimeSecs = detail.Snapshot.UptimeSecs
+		booted := detail.Snapshot.Timestamp.Add(-time.Duration(detail.Snapshot.UptimeSecs) * time.Second)
+		detail.BootedAt = &booted
+	}
+
 	since := time.Now().Add(-time.Duration(hours) * time.Hour)
-	snapshots, err := s.storage.GetSnapshots(ip, since, limit)
+	if detail.RecentShares, err = s.storage.GetMinerShares(ip, since, limit, false); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if detail.BestShares, err = s.storage.GetMinerShares(ip, since, limit, true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if detail.Blocks, err = s.storage.GetMinerBlocks(ip, limit); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if detail.BlockCount, err = s.storage.GetBlockCountAllTime(ip); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if detail.RecentShares == nil {
+		detail.RecentShares = []*storage.Share{}
+	}
+	if detail.BestShares == nil {
+		detail.BestShares = []*storage.Share{}
+	}
+	if detail.Blocks == nil {
+		detail.Blocks = []*storage.Block{}
+	}
+
+	if s.alerts != nil {
+		detail.Alerts = s.alerts.MinerState(ip)
+	}
+
+	s.jsonResponse(w, detail)
+}
+
+// handleGetMinerHistory returns miner snapshots history, newest first, a page
+// at a time. Ranges longer than the retained snapshots return hourly or daily
+// rollups instead.
+// GET /api/miners/{ip}/history
+// Query params: hours (default 24), page_size (default 1000), cursor,
+// resolution (optional, see historyResolution),
+// points (optional, LTTB-downsample the whole range to N points by hashrate, unpaged)
+// The resolution used is returned in the X-History-Resolution header, the
+// total in X-Total-Count and the next page in Link.
+func (s *Server) handleGetMinerHistory(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+
+	page, err := parsePage(r, 1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// When decimating, fetch the whole range so the shape is preserved
+	points := parsePoints(r)
+	if points > 0 {
+		page = pageRequest{Size: 100000}
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	resolution := historyResolution(r, hours)
+	w.Header().Set("X-History-Resolution", resolution)
+
+	if resolution != "raw" {
+		rollups, total, err := s.storage.GetHistoryRollupsPage(resolution, ip, since, page.After, page.Size+1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rollups == nil {
+			rollups = []*storage.HistoryRollup{}
+		}
+		if points == 0 {
+			var next *storage.PageCursor
+			if len(rollups) > page.Size {
+				rollups = rollups[:page.Size]
+				last := rollups[len(rollups)-1]
+				next = &storage.PageCursor{Timestamp: last.Timestamp, MinerIP: last.MinerIP}
+			}
+			setPageHeaders(w, r, page, total, next)
+		} else {
+			idx := lttbIndices(len(rollups), points,
+				func(i int) float64 { return float64(rollups[i].Timestamp.Unix()) },
+				func(i int) float64 { return rollups[i].HashRateAvg })
+			decimated := make([]*storage.HistoryRollup, len(idx))
+			for i, j := range idx {
+				decimated[i] = rollups[j]
+			}
+			rollups = decimated
+		}
+		s.jsonResponse(w, rollups)
+		return
+	}
+
+	snapshots, total, err := s.storage.GetSnapshotsPage(ip, since, page.After, page.Size+1)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if points == 0 {
+		var next *storage.PageCursor
+		if len(snapshots) > page.Size {
+			snapshots = snapshots[:page.Size]
+			last := snapshots[len(snapshots)-1]
+			next = &storage.PageCursor{Timestamp: last.Timestamp, ID: last.ID}
+		}
+		setPageHeaders(w, r, page, total, next)
+	} else {
+		idx := lttbIndices(len(snapshots), points,
+			func(i int) float64 { return float64(snapshots[i].Timestamp.Unix()) },
+			func(i int) float64 { return snapshots[i].HashRate })
+		decimated := make([]*storage.MinerSnapshot, len(idx))
+		for i, j := range idx {
+			decimated[i] = snapshots[j]
+		}
+		snapshots = decimated
+	}
+
 	s.jsonResponse(w, snapshots)
 }
 
+// handleGetHostnameHistory returns every hostname a miner has reported
+// GET /api/miners/{ip}/hostnames
+func (s *Server) handleGetHostnameHistory(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	history, err := s.storage.GetHostnameHistory(ip)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []*storage.HostnameChange{}
+	}
+
+	s.jsonResponse(w, history)
+}
+
+// handleGetPoolDifficultyChanges returns pool difficulty adjustments, newest first
+// GET /api/miners/{ip}/pool-difficulty
+// GET /api/pool-difficulty
+// Query params: hours (default 24)
+func (s *Server) handleGetPoolDifficultyChanges(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	changes, err := s.storage.GetPoolDifficultyChanges(ip, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if changes == nil {
+		changes = []*storage.PoolDifficultyChange{}
+	}
+
+	s.jsonResponse(w, changes)
+}
+
 // handleRemoveMiner removes a miner by IP
 // DELETE /api/miners/{ip}
 func (s *Server) handleRemoveMiner(w http.ResponseWriter, r *http.Request) {
@@ -142,105 +421,566 @@ func (s *Server) handleRemoveMiner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.jsonResponse(w, map[string]bool{"success": true})
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleSetMinerCoin sets the coin for a specific miner
+// PUT /api/miners/{ip}/coin
+func (s *Server) handleSetMinerCoin(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	var req struct {
+		Coin string `json:"coin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// Allow empty string to reset to global default
+	if req.Coin != "" {
+		valid := false
+		for _, c := range pricing.GetSupportedCoins() {
+			if c.ID == req.Coin {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			http.Error(w, "invalid coin", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.storage.SetMinerCoin(ip, req.Coin); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"status": "ok",
+		"ip":     ip,
+		"coin":   req.Coin,
+	})
+}
+
+// handleSetMinerLocation assigns a miner to an energy location
+// PUT /api/miners/{ip}/location
+func (s *Server) handleSetMinerLocation(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	var req struct {
+		Location string `json:"location"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// Allow empty string to reset to the default rate
+	if req.Location != "" && !s.cfg().Energy.HasLocation(req.Location) {
+		http.Error(w, "unknown location", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.SetMinerLocation(ip, req.Location); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.collector.SetEnergyLocation(ip, req.Location)
+
+	s.jsonResponse(w, map[string]interface{}{
+		"status":     "ok",
+		"ip":         ip,
+		"location":   req.Location,
+		"costPerKwh": s.cfg().Energy.CostFor(req.Location),
+	})
+}
+
+// handleRestartMiner reboots a miner
+// POST /api/miners/{ip}/restart
+func (s *Server) handleRestartMiner(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.control.Restart(ip); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("Restarted miner %s", ip)
+
+	s.jsonResponse(w, map[string]string{"status": "ok", "ip": ip})
+}
+
+// handleUpdateMinerSettings changes a miner's frequency, core voltage or fan
+// settings. Frequency and voltage usually apply after a restart.
+// PATCH /api/miners/{ip}/settings
+func (s *Server) handleUpdateMinerSettings(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+
+	var req collector.MinerSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.control.UpdateSettings(ip, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("Updated settings of miner %s", ip)
+
+	s.jsonResponse(w, map[string]interface{}{
+		"status":   "ok",
+		"ip":       ip,
+		"settings": req,
+	})
+}
+
+// handleSetMinerPowerCalibration sets the power multiplier/offset for a miner,
+// used to correct firmware wattage against a wall meter. Applies to new snapshots.
+// PUT /api/miners/{ip}/power-calibration
+func (s *Server) handleSetMinerPowerCalibration(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	var req storage.PowerCalibration
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// Allow 0 as shorthand for "no scaling"
+	if req.Multiplier == 0 {
+		req.Multiplier = 1
+	}
+	if req.Multiplier < 0.1 || req.Multiplier > 10 {
+		http.Error(w, "multiplier must be between 0.1 and 10", http.StatusBadRequest)
+		return
+	}
+	if req.Offset < -1000 || req.Offset > 1000 {
+		http.Error(w, "offset must be between -1000 and 1000 watts", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.SetMinerPowerCalibration(ip, req); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.collector.SetPowerCalibration(ip, req)
+
+	s.jsonResponse(w, map[string]interface{}{
+		"status":     "ok",
+		"ip":         ip,
+		"multiplier": req.Multiplier,
+		"offset":     req.Offset,
+	})
+}
+
+// handleResetSession resets MinerHQ-side session tracking (alert baselines
+// for hashrate and best difficulty, alert cooldowns) without rebooting the miner.
+// POST /api/miners/{ip}/session/reset
+// POST /api/miners/session/reset (all miners)
+func (s *Server) handleResetSession(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	if ip != "" {
+		miners, err := s.storage.GetMiners()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		found := false
+		for _, m := range miners {
+			if m.IP == ip {
+				found = true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, "miner not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	if s.alerts != nil {
+		s.alerts.ResetSession(ip)
+	}
+
+	scope := ip
+	if scope == "" {
+		scope = "all"
+	}
+	log.Printf("Session tracking reset for %s", scope)
+
+	s.jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"miner":   scope,
+		"resetAt": time.Now(),
+	})
+}
+
+// handleGetFirmwareConsistency reports miners whose firmware differs from
+// the most common version among miners of the same model
+// GET /api/firmware/consistency
+func (s *Server) handleGetFirmwareConsistency(w http.ResponseWriter, r *http.Request) {
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	groups := collector.CheckFirmwareConsistency(miners)
+	consistent := true
+	for _, g := range groups {
+		if !g.Consistent {
+			consistent = false
+			break
+		}
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"consistent": consistent,
+		"groups":     groups,
+	})
+}
+
+// NonceDistributionResponse contains per-ASIC nonce distribution stats for a miner
+type NonceDistributionResponse struct {
+	MinerIP string                     `json:"minerIp"`
+	Hours   int                        `json:"hours"`
+	Buckets int                        `json:"buckets"`
+	Shares  int                        `json:"shares"`
+	Asics   []collector.AsicNonceStats `json:"asics"`
+}
+
+// handleGetNonceDistribution returns nonce and version-rolling distribution per ASIC
+// GET /api/miners/{ip}/nonces
+// Query params: hours (default 24), buckets (power of two, default 16)
+func (s *Server) handleGetNonceDistribution(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+
+	buckets := 16
+	if b := r.URL.Query().Get("buckets"); b != "" {
+		if parsed, err := strconv.Atoi(b); err == nil && parsed > 0 {
+			buckets = parsed
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	shares, err := s.storage.GetMinerShareNonces(ip, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	asics := collector.AnalyzeNonces(shares, buckets)
+	if len(asics) > 0 {
+		buckets = len(asics[0].NonceBuckets)
+	}
+
+	s.jsonResponse(w, NonceDistributionResponse{
+		MinerIP: ip,
+		Hours:   hours,
+		Buckets: buckets,
+		Shares:  len(shares),
+		Asics:   asics,
+	})
+}
+
+// AsicShareStats is one ASIC chip's share output against its siblings
+type AsicShareStats struct {
+	storage.AsicShares
+	SharePct float64 `json:"sharePct"` // Of the miner's shares
+	Low      bool    `json:"low"`      // Less than half the shares of an even split
+}
+
+// AsicBreakdownResponse contains per-ASIC share counts for a miner
+type AsicBreakdownResponse struct {
+	MinerIP string           `json:"minerIp"`
+	Hours   int              `json:"hours"`
+	Shares  int64            `json:"shares"`
+	Asics   []AsicShareStats `json:"asics"`
+}
+
+// minAsicShares is how many shares per chip an even split must give before
+// chips are flagged low, so a few unlucky minutes don't count
+const minAsicShares = 20
+
+// handleGetMinerAsics returns share counts and best difficulty per ASIC chip,
+// to spot a dying chip on multi-ASIC boards. Chips up to the highest one seen
+// (or asics, if given) are listed even without shares.
+// GET /api/miners/{ip}/asics
+// Query params: hours (default 24), asics (chip count)
+func (s *Server) handleGetMinerAsics(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	shares, err := s.storage.GetAsicShares(ip, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	count := 0
+	if a := r.URL.Query().Get("asics"); a != "" {
+		if parsed, err := strconv.Atoi(a); err == nil && parsed > 0 && parsed <= 256 {
+			count = parsed
+		}
+	}
+	var total int64
+	byAsic := make(map[int]*storage.AsicShares, len(shares))
+	for _, a := range shares {
+		byAsic[a.AsicNum] = a
+		total += a.Shares
+		if a.AsicNum >= count {
+			count = a.AsicNum + 1
+		}
+	}
+
+	var even float64 // Shares per chip if they were split evenly
+	if count > 0 {
+		even = float64(total) / float64(count)
+	}
+	asics := make([]AsicShareStats, 0, count)
+	for i := 0; i < count; i++ {
+		stats := AsicShareStats{AsicShares: storage.AsicShares{AsicNum: i}}
+		if a, ok := byAsic[i]; ok {
+			stats.AsicShares = *a
+		}
+		if total > 0 {
+			stats.SharePct = float64(stats.Shares) / float64(total) * 100
+		}
+		stats.Low = even >= minAsicShares && float64(stats.Shares) < even/2
+		asics = append(asics, stats)
+	}
+
+	s.jsonResponse(w, AsicBreakdownResponse{
+		MinerIP: ip,
+		Hours:   hours,
+		Shares:  total,
+		Asics:   asics,
+	})
 }
 
-// handleSetMinerCoin sets the coin for a specific miner
-// PUT /api/miners/{ip}/coin
-func (s *Server) handleSetMinerCoin(w http.ResponseWriter, r *http.Request) {
-	ip := chi.URLParam(r, "ip")
-
-	var req struct {
-		Coin string `json:"coin"`
+// minerLocation returns a miner's energy location: the one assigned via the
+// API, or the location from the config's miners list
+func (s *Server) minerLocation(m *storage.Miner) string {
+	if m.Location != "" {
+		return m.Location
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
-		return
+	for _, mc := range s.cfg().Miners {
+		if mc.IP == m.IP {
+			return mc.Location
+		}
 	}
+	return ""
+}
 
-	// Allow empty string to reset to global default
-	if req.Coin != "" {
-		valid := false
-		for _, c := range pricing.GetSupportedCoins() {
-			if c.ID == req.Coin {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			http.Error(w, "invalid coin", http.StatusBadRequest)
-			return
-		}
+// projectedDailyCost returns the cost of running at a constant draw for the
+// next 24 hours, priced hour by hour so time-of-use tariffs are reflected
+func (s *Server) projectedDailyCost(m *storage.Miner, watts float64, from time.Time) float64 {
+	location := s.minerLocation(m)
+	var cost float64
+	for h := 0; h < 24; h++ {
+		cost += units.KWh(watts, 1) * s.cfg().Energy.RateAt(location, from.Add(time.Duration(h)*time.Hour))
 	}
+	return cost
+}
 
-	if err := s.storage.SetMinerCoin(ip, req.Coin); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// displayCurrency returns the currency stats and earnings are reported in
+func (s *Server) displayCurrency() string {
+	if s.cfg().Pricing.FiatCurrency != "" {
+		return strings.ToUpper(s.cfg().Pricing.FiatCurrency)
 	}
+	return "USD"
+}
 
-	s.jsonResponse(w, map[string]interface{}{
-		"status": "ok",
-		"ip":     ip,
-		"coin":   req.Coin,
-	})
+// convertAmounts converts amounts in place from one fiat currency to the
+// display currency and returns the currency they end up in. Without a known
+// exchange rate the amounts are left in their original currency.
+func (s *Server) convertAmounts(from string, amounts ...*float64) string {
+	to := s.displayCurrency()
+	rate, ok := s.pricing.GetExchangeRate(from, to)
+	if !ok {
+		return strings.ToUpper(from)
+	}
+	for _, a := range amounts {
+		*a *= rate
+	}
+	return to
 }
 
 // FleetStats represents aggregate fleet statistics
 type FleetStats struct {
 	TotalHashrate   float64 `json:"totalHashrate"`   // GH/s
+	HashrateDisplay string  `json:"hashrateDisplay"` // In display.hashrate_unit, e.g. "9.20 TH/s"
 	TotalPower      float64 `json:"totalPower"`      // Watts
 	Efficiency      float64 `json:"efficiency"`      // J/TH
 	OnlineMiners    int     `json:"onlineMiners"`
 	TotalMiners     int     `json:"totalMiners"`
-	EnergyCostPerDay float64 `json:"energyCostPerDay"` // Currency per day
+	EnergyCostPerDay float64 `json:"energyCostPerDay"` // Currency per day, projected from current draw
+	Currency         string  `json:"currency"`         // Currency of all energy costs
+
+	// Measured energy from integrated power samples, priced at the tariff in effect
+	EnergyTodayKWh  float64 `json:"energyTodayKwh"`
+	EnergyMonthKWh  float64 `json:"energyMonthKwh"`
+	EnergyTotalKWh  float64 `json:"energyTotalKwh"`
+	EnergyCostToday float64 `json:"energyCostToday"`
+	EnergyCostMonth float64 `json:"energyCostMonth"`
 }
 
 // handleGetStats returns fleet aggregate stats
 // GET /api/stats
+// Query params: tag (optional, only miners with this tag)
 func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	miners, err := s.storage.GetMiners()
+	if err == nil {
+		miners, err = s.filterByTag(r, miners)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	status := s.collector.GetMinerStatus()
+	s.jsonResponse(w, s.fleetStats(miners, s.minerCards(miners)))
+}
 
+// fleetStats totals the fleet from the miner cards built by minerCards
+func (s *Server) fleetStats(miners []*storage.Miner, cards []MinerWithSnapshot) FleetStats {
 	var stats FleetStats
 	stats.TotalMiners = len(miners)
 
-	// Get latest snapshot for each miner to calculate totals
-	for _, m := range miners {
-		if online, ok := status[m.IP]; ok && online {
+	// Use the latest snapshot of each online miner to calculate totals;
+	// projected energy cost uses each miner's location rate and tariffs
+	for i, card := range cards {
+		if card.Online {
 			stats.OnlineMiners++
 
-			// Get latest snapshot for this miner
-			snapshots, err := s.storage.GetSnapshots(m.IP, time.Now().Add(-5*time.Minute), 1)
-			if err == nil && len(snapshots) > 0 {
-				snap := snapshots[0]
+			if snap := card.Snapshot; snap != nil {
 				stats.TotalHashrate += snap.HashRate
 				stats.TotalPower += snap.Power
+				stats.EnergyCostPerDay += s.projectedDailyCost(miners[i], snap.Power, time.Now())
 			}
 		}
 	}
 
-	// Calculate efficiency (J/TH)
-	// Power is in Watts, HashRate is in GH/s
-	// J/TH = Watts / (GH/s / 1000) = Watts * 1000 / GH/s
-	if stats.TotalHashrate > 0 {
-		stats.Efficiency = (stats.TotalPower * 1000) / stats.TotalHashrate
+	// Calculate efficiency (J/TH) from base units (Watts, GH/s)
+	stats.Efficiency = units.EfficiencyJTH(stats.TotalPower, stats.TotalHashrate)
+	stats.HashrateDisplay = s.cfg().Display.Units().FormatHashrate(stats.TotalHashrate)
+
+	// Measured energy usage for active miners
+	active := make(map[string]bool, len(miners))
+	for _, m := range miners {
+		active[m.IP] = true
+	}
+	if counters, err := s.storage.GetEnergyCounters(time.Now()); err == nil {
+		for _, c := range counters {
+			if !active[c.MinerIP] {
+				continue
+			}
+			stats.EnergyTodayKWh += c.DayKWh
+			stats.EnergyMonthKWh += c.MonthKWh
+			stats.EnergyTotalKWh += c.TotalKWh
+			stats.EnergyCostToday += c.DayCost
+			stats.EnergyCostMonth += c.MonthCost
+		}
 	}
 
-	// Calculate energy cost per day
-	// (totalPower / 1000) * 24 * costPerKwh
-	stats.EnergyCostPerDay = (stats.TotalPower / 1000) * 24 * s.cfg.Energy.CostPerKWh
+	// Energy rates are entered in the energy currency
+	stats.Currency = s.convertAmounts(s.cfg().Energy.Currency, &stats.EnergyCostPerDay, &stats.EnergyCostToday, &stats.EnergyCostMonth)
+
+	return stats
+}
+
+// summaryTTL is how long a built dashboard summary is served from cache
+const summaryTTL = 5 * time.Second
+
+// SummaryResponse holds everything the dashboard needs on first load
+type SummaryResponse struct {
+	Stats       FleetStats          `json:"stats"`
+	Miners      []MinerWithSnapshot `json:"miners"`
+	BestShares  BestSharesResponse  `json:"bestShares"`
+	BlockCount  int64               `json:"blockCount"`
+	Earnings    *EarningsResponse   `json:"earnings"`
+	GeneratedAt time.Time           `json:"generatedAt"`
+}
+
+// handleGetSummary returns fleet stats, miner cards, best shares, block count
+// and earnings in one response. It is cached for a few seconds so dashboards
+// opened together share one round of queries.
+// GET /api/summary
+func (s *Server) handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+
+	if s.summary == nil || time.Since(s.summary.GeneratedAt) >= summaryTTL {
+		miners, err := s.storage.GetMiners()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		earnings, err := s.earnings(miners)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		blockCount, err := s.storage.GetBlockCount()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		top, err := s.storage.GetBestShares("", 1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		cards := s.minerCards(miners)
+		s.summary = &SummaryResponse{
+			Stats:       s.fleetStats(miners, cards),
+			Miners:      cards,
+			BestShares:  bestShares(cards, top),
+			BlockCount:  blockCount,
+			Earnings:    earnings,
+			GeneratedAt: time.Now(),
+		}
+	}
 
-	s.jsonResponse(w, stats)
+	s.jsonResponse(w, s.summary)
 }
 
-// handleGetShares returns recent shares
+// handleGetShares returns recent shares, newest first, a page at a time
 // GET /api/shares
-// Query params: hours (default 24), limit (default 100)
+// Query params: hours (default 24), page_size (default 100), cursor
+// The total is returned in the X-Total-Count header and the next page in Link.
 func (s *Server) handleGetShares(w http.ResponseWriter, r *http.Request) {
 	hours := 24
 	if h := r.URL.Query().Get("hours"); h != "" {
@@ -249,26 +989,34 @@ func (s *Server) handleGetShares(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	limit := 100
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
-			limit = parsed
-		}
+	page, err := parsePage(r, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	since := time.Now().Add(-time.Duration(hours) * time.Hour)
-	shares, err := s.storage.GetShares(since, limit)
+	shares, total, err := s.storage.GetSharesPage(since, page.After, page.Size+1)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	var next *storage.PageCursor
+	if len(shares) > page.Size {
+		shares = shares[:page.Size]
+		last := shares[len(shares)-1]
+		next = &storage.PageCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+	setPageHeaders(w, r, page, total, next)
+
 	s.jsonResponse(w, shares)
 }
 
-// handleGetBlocks returns found blocks
+// handleGetBlocks returns found blocks, newest first, a page at a time
 // GET /api/blocks
-// Query params: days (default 365), limit (default 100)
+// Query params: days (default 365), page_size (default 100), cursor
+// The total is returned in the X-Total-Count header and the next page in Link.
 func (s *Server) handleGetBlocks(w http.ResponseWriter, r *http.Request) {
 	days := 365
 	if d := r.URL.Query().Get("days"); d != "" {
@@ -277,20 +1025,27 @@ func (s *Server) handleGetBlocks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	limit := 100
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
-			limit = parsed
-		}
+	page, err := parsePage(r, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	since := time.Now().AddDate(0, 0, -days)
-	blocks, err := s.storage.GetBlocks(since, limit)
+	blocks, total, err := s.storage.GetBlocksPage(since, page.After, page.Size+1)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	var next *storage.PageCursor
+	if len(blocks) > page.Size {
+		blocks = blocks[:page.Size]
+		last := blocks[len(blocks)-1]
+		next = &storage.PageCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+	setPageHeaders(w, r, page, total, next)
+
 	s.jsonResponse(w, blocks)
 }
 
@@ -320,10 +1075,14 @@ type WeeklyCompetitor struct {
 	RankChange         int     `json:"rankChange"`         // +1 moved up, -1 moved down, 0 same
 	FoundBlockThisWeek bool    `json:"foundBlockThisWeek"` // Miner Legend status
 	BlocksThisWeek     int     `json:"blocksThisWeek"`     // Number of blocks found this week
+	Hashrate           float64 `json:"hashrate,omitempty"` // GH/s used to handicap the score
+	Score              float64 `json:"score"`              // Ranking value: BestDiff, or BestDiff per TH/s when handicapped
 }
 
 // WeeklyCompetition represents the weekly competition state
 type WeeklyCompetition struct {
+	Scoring          string                  `json:"scoring"` // "raw", "expected" or "average"
+	Period           string                  `json:"period"`  // "daily", "weekly" or "monthly"; week fields hold the current period
 	Competitors      []WeeklyCompetitor      `json:"competitors"`
 	BlockCompetitors []WeeklyBlockCompetitor `json:"blockCompetitors"`
 	WeekStart        time.Time               `json:"weekStart"`
@@ -364,78 +1123,73 @@ func getBlockTitle(blocksThisWeek int) (string, string) {
 	}
 }
 
-// handleGetWeeklyCompetition returns the weekly best share competition
+// handleGetWeeklyCompetition returns the best share competition for the
+// current period (weekly unless competition.period says otherwise)
 // GET /api/competition/weekly
+// Query params: scoring (raw, expected or average; defaults to competition.scoring)
 func (s *Server) handleGetWeeklyCompetition(w http.ResponseWriter, r *http.Request) {
-	// Calculate week boundaries (Sunday to Saturday, resets Sunday at midnight)
+	scoring := s.cfg().Competition.Scoring
+	if q := r.URL.Query().Get("scoring"); q != "" {
+		scoring = q
+	}
+	if !config.ValidCompetitionScoring(scoring) {
+		http.Error(w, "scoring must be raw, expected or average", http.StatusBadRequest)
+		return
+	}
+	if scoring == "" {
+		scoring = competition.Raw
+	}
+
+	// Calculate period boundaries (weekly resets Sunday at midnight by default)
+	period := s.cfg().Competition.CompetitionPeriod()
 	now := time.Now()
-	weekday := int(now.Weekday()) // Sunday = 0, Monday = 1, ..., Saturday = 6
-	weekStart := time.Date(now.Year(), now.Month(), now.Day()-weekday, 0, 0, 0, 0, now.Location())
-	weekEnd := weekStart.AddDate(0, 0, 7)
+	weekStart, weekEnd := period.Start(now), period.End(now)
 
-	// Get all miners
-	miners, err := s.storage.GetMiners()
+	scorer, err := s.storage.CompetitionScorer(scoring, s.cfg().ExpectedHashrates(), weekStart, now)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// For each miner, get their best share this week and all-time
-	var competitors []WeeklyCompetitor
-	for _, m := range miners {
-		// Get best share this week
-		weeklyBest, _ := s.storage.GetBestShareInRange(m.IP, weekStart, now)
-
-		// Get all-time best
-		allTimeBest, _ := s.storage.GetBestShare(m.IP, false)
-
-		// Get share count this week
-		shareCount, _ := s.storage.GetShareCountInRange(m.IP, weekStart, now)
-
-		var bestDiff, personalBest float64
-		if weeklyBest != nil {
-			bestDiff = weeklyBest.Difficulty
-		}
-		if allTimeBest != nil {
-			personalBest = allTimeBest.Difficulty
-		}
-
-		// Get blocks found this week
-		blocksThisWeek, _ := s.storage.GetBlockCountInRange(m.IP, weekStart, now)
+	// Best shares, share and block counts for every miner in one query
+	leaderboard, err := s.storage.GetWeeklyLeaderboard(weekStart, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		// Only include miners with shares this week
-		if bestDiff > 0 {
-			competitors = append(competitors, WeeklyCompetitor{
-				MinerIP:            m.IP,
-				Hostname:           m.Hostname,
-				BestDiff:           bestDiff,
-				ShareCount:         shareCount,
-				PersonalBest:       personalBest,
-				IsNewRecord:        bestDiff > personalBest && personalBest > 0, // Strictly greater = new record
-				FoundBlockThisWeek: blocksThisWeek > 0,
-				BlocksThisWeek:     blocksThisWeek,
-			})
+	names := s.displayNames()
+	var competitors []WeeklyCompetitor
+	for _, e := range leaderboard {
+		c := WeeklyCompetitor{
+			MinerIP:            e.MinerIP,
+			Hostname:           nameOr(names, e.MinerIP, e.Hostname),
+			BestDiff:           e.BestDiff,
+			ShareCount:         e.ShareCount,
+			PersonalBest:       e.PersonalBest,
+			IsNewRecord:        e.BestDiff > e.PersonalBest && e.PersonalBest > 0, // Strictly greater = new record
+			FoundBlockThisWeek: e.Blocks > 0,
+			BlocksThisWeek:     e.Blocks,
+			Hashrate:           scorer.Hashrate(e.MinerIP),
+			Score:              scorer.Score(e.MinerIP, e.BestDiff),
 		}
+		competitors = append(competitors, c)
 	}
 
-	// Sort by best difficulty (descending)
-	for i := 0; i < len(competitors)-1; i++ {
-		for j := i + 1; j < len(competitors); j++ {
-			if competitors[j].BestDiff > competitors[i].BestDiff {
-				competitors[i], competitors[j] = competitors[j], competitors[i]
-			}
-		}
+	// Raw scores come ranked from storage, handicapped ones need sorting
+	if scorer.Handicapped() {
+		sort.SliceStable(competitors, func(i, j int) bool { return competitors[i].Score > competitors[j].Score })
 	}
 
 	// Calculate ranks and percentages
-	var topDiff float64
+	var topScore float64
 	if len(competitors) > 0 {
-		topDiff = competitors[0].BestDiff
+		topScore = competitors[0].Score
 	}
 	for i := range competitors {
 		competitors[i].Rank = i + 1
-		if topDiff > 0 {
-			competitors[i].PercentOfTop = (competitors[i].BestDiff / topDiff) * 100
+		if topScore > 0 {
+			competitors[i].PercentOfTop = (competitors[i].Score / topScore) * 100
 		}
 	}
 
@@ -454,37 +1208,25 @@ func (s *Server) handleGetWeeklyCompetition(w http.ResponseWriter, r *http.Reque
 		timeRemaining = fmt.Sprintf("%dm", minutes)
 	}
 
-	// Build block competition data
+	// Build block competition data, ranked by blocks this week, then all-time
+	blockLeaderboard, err := s.storage.GetBlockLeaderboard(weekStart, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	var blockCompetitors []WeeklyBlockCompetitor
-	for _, m := range miners {
-		blocksThisWeek, _ := s.storage.GetBlockCountInRange(m.IP, weekStart, now)
-		blocksAllTime, _ := s.storage.GetBlockCountAllTime(m.IP)
-		streak, _ := s.storage.GetBlockStreak(m.IP)
-
-		// Only include miners with at least 1 block ever
-		if blocksAllTime > 0 {
-			title, titleIcon := getBlockTitle(blocksAllTime) // Use all-time for permanent titles
-			blockCompetitors = append(blockCompetitors, WeeklyBlockCompetitor{
-				MinerIP:        m.IP,
-				Hostname:       m.Hostname,
-				BlocksThisWeek: blocksThisWeek,
-				BlocksAllTime:  blocksAllTime,
-				Title:          title,
-				TitleIcon:      titleIcon,
-				Streak:         streak,
-			})
-		}
-	}
-
-	// Sort block competitors by blocks this week (descending), then all-time (descending)
-	for i := 0; i < len(blockCompetitors)-1; i++ {
-		for j := i + 1; j < len(blockCompetitors); j++ {
-			if blockCompetitors[j].BlocksThisWeek > blockCompetitors[i].BlocksThisWeek ||
-				(blockCompetitors[j].BlocksThisWeek == blockCompetitors[i].BlocksThisWeek &&
-					blockCompetitors[j].BlocksAllTime > blockCompetitors[i].BlocksAllTime) {
-				blockCompetitors[i], blockCompetitors[j] = blockCompetitors[j], blockCompetitors[i]
-			}
-		}
+	for _, e := range blockLeaderboard {
+		title, titleIcon := getBlockTitle(e.BlocksAllTime) // Use all-time for permanent titles
+		blockCompetitors = append(blockCompetitors, WeeklyBlockCompetitor{
+			MinerIP:        e.MinerIP,
+			Hostname:       nameOr(names, e.MinerIP, e.Hostname),
+			BlocksThisWeek: e.BlocksInRange,
+			BlocksAllTime:  e.BlocksAllTime,
+			Title:          title,
+			TitleIcon:      titleIcon,
+			Streak:         e.Streak,
+		})
 	}
 
 	// Assign ranks to block competitors
@@ -493,6 +1235,8 @@ func (s *Server) handleGetWeeklyCompetition(w http.ResponseWriter, r *http.Reque
 	}
 
 	s.jsonResponse(w, WeeklyCompetition{
+		Scoring:          scoring,
+		Period:           period.Adjective(),
 		Competitors:      competitors,
 		BlockCompetitors: blockCompetitors,
 		WeekStart:        weekStart,
@@ -502,6 +1246,26 @@ func (s *Server) handleGetWeeklyCompetition(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// handleGetRecords returns the hall of fame: permanent all-time records that
+// survive retention purges
+// GET /api/records
+func (s *Server) handleGetRecords(w http.ResponseWriter, r *http.Request) {
+	records, err := s.storage.GetRecords()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []*storage.Record{}
+	}
+	names := s.displayNames()
+	for _, record := range records {
+		record.Hostname = nameOr(names, record.MinerIP, record.Hostname)
+	}
+
+	s.jsonResponse(w, records)
+}
+
 // MoneyMakerCompetitor represents a miner in the money makers competition
 type MoneyMakerCompetitor struct {
 	MinerIP          string  `json:"minerIp"`
@@ -549,11 +1313,10 @@ func getMoneyTitle(totalUSD float64) (string, string) {
 // handleGetMoneyMakers returns the money makers leaderboard
 // GET /api/competition/moneymakers
 func (s *Server) handleGetMoneyMakers(w http.ResponseWriter, r *http.Request) {
-	// Calculate week boundaries
+	// Calculate competition period boundaries
+	period := s.cfg().Competition.CompetitionPeriod()
 	now := time.Now()
-	weekday := int(now.Weekday())
-	weekStart := time.Date(now.Year(), now.Month(), now.Day()-weekday, 0, 0, 0, 0, now.Location())
-	weekEnd := weekStart.AddDate(0, 0, 7)
+	weekStart, weekEnd := period.Start(now), period.End(now)
 
 	// Get all money makers (historical values)
 	makers, err := s.storage.GetMoneyMakers()
@@ -586,6 +1349,7 @@ func (s *Server) handleGetMoneyMakers(w http.ResponseWriter, r *http.Request) {
 		currentValueByMiner[minerIP] = currentTotal
 	}
 
+	names := s.displayNames()
 	var competitors []MoneyMakerCompetitor
 	for i, m := range makers {
 		// Get weekly earnings (historical)
@@ -602,7 +1366,7 @@ func (s *Server) handleGetMoneyMakers(w http.ResponseWriter, r *http.Request) {
 		title, titleIcon := getMoneyTitle(m.TotalUSD)
 		competitors = append(competitors, MoneyMakerCompetitor{
 			MinerIP:          m.MinerIP,
-			Hostname:         m.Hostname,
+			Hostname:         nameOr(names, m.MinerIP, m.Hostname),
 			TotalUSD:         m.TotalUSD,
 			CurrentUSD:       currentValueByMiner[m.MinerIP],
 			BlockCount:       m.BlockCount,
@@ -622,13 +1386,41 @@ func (s *Server) handleGetMoneyMakers(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleGetSettings returns the current configuration
+// handleGetSettings returns the current configuration. Password and token
+// hashes are left out for every role.
 // GET /api/settings
 func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
-	s.jsonResponse(w, s.cfg)
+	if s.cfg().Server.ReadOnly || !s.isAdmin(r) {
+		// Don't leak credentials on a public status page or to viewers
+		redacted := *s.cfg()
+		redacted.Alerts.WebhookURL = ""
+		redacted.Alerts.MatrixAccessToken = ""
+		redacted.Alerts.EmailPassword = ""
+		redacted.Alerts.Notifiers = nil // URLs and tokens are credentials
+		redacted.Scanner.DHCP.Username = ""
+		redacted.Scanner.DHCP.Password = ""
+		redacted.MQTT.Username = ""
+		redacted.MQTT.Password = ""
+		redacted.TSDB.Token = ""
+		redacted.TSDB.Username = ""
+		redacted.TSDB.Password = ""
+		redacted.Agent.Token = ""
+		redacted.Sites = nil            // Token hashes
+		redacted.SmartPlugs.Plugs = nil // Plug logins
+		redacted.Pricing.CoinGeckoAPIKey = ""
+		redacted.StratumProxy.Pools = nil // Pool logins
+		redacted.Auth.Users = nil
+		redacted.Auth.Tokens = nil
+		s.jsonResponse(w, &redacted)
+		return
+	}
+	s.jsonResponse(w, withoutHashes(s.cfg()))
 }
 
-// handleSaveSettings saves the configuration
+// handleSaveSettings applies and saves the configuration. Fields missing from
+// the body keep their current values, as do the hashes of users, API tokens
+// and sites sent without a new password or token. Components pick up the change as they
+// are notified by the config manager, without a restart.
 // POST /api/settings
 func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
@@ -638,84 +1430,96 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	if err := json.Unmarshal(body, s.cfg); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	var invalid error
+	err = s.settings.Update(func(cfg *config.Config) error {
+		prev := s.cfg()
+		if err := json.Unmarshal(body, cfg); err != nil {
+			invalid = errors.New("invalid JSON")
+			return invalid
+		}
+		keepHashes(cfg, prev)
+		if err := cfg.Validate(); err != nil {
+			invalid = err
+			return invalid
+		}
+		// Never persist plaintext passwords submitted through the UI
+		_, err := HashConfigPasswords(cfg)
+		return err
+	})
+	if invalid != nil {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
 		return
 	}
-
-	// Save to file
-	if err := s.cfg.Save("/data/config.json"); err != nil {
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Propagate alert config to the running engine
-	if s.alerts != nil {
-		s.alerts.UpdateConfig(&alerts.AlertConfig{
-			WebhookURL:          s.cfg.Alerts.WebhookURL,
-			MinerOfflineSeconds: s.cfg.Alerts.OfflineMinutes * 60,
-			TempAbove:           s.cfg.Alerts.TempThresholdC,
-			HashrateDropPercent: s.cfg.Alerts.HashrateDropPct,
-			FanRPMBelow:         s.cfg.Alerts.FanRPMBelow,
-			WifiSignalBelow:     s.cfg.Alerts.WifiSignalBelow,
-			OnShareRejected:     s.cfg.Alerts.OnShareRejected,
-			OnPoolDisconnected:  s.cfg.Alerts.OnPoolDisconnected,
-			OnNewBestDiff:       s.cfg.Alerts.OnNewBestDiff,
-			OnBlockFound:        s.cfg.Alerts.OnBlockFound,
-			OnNewLeader:         s.cfg.Alerts.OnNewLeader,
-		})
-	}
-
 	s.jsonResponse(w, map[string]bool{"success": true})
 }
 
 // ScanResponse represents the scan results
 type ScanResponse struct {
+	Source  string           `json:"source"` // "dhcp" or "subnet"
 	Subnets []string         `json:"subnets"`
+	Leases  int              `json:"leases,omitempty"` // DHCP leases read, when Source is "dhcp"
 	Results []*storage.Miner `json:"results"`
 }
 
-// handleScan starts a network scan
+// ScanRequest optionally names the networks to sweep: CIDRs, address ranges
+// or addresses (see scanner.ExpandNetwork)
+type ScanRequest struct {
+	Networks []string `json:"networks"`
+}
+
+// handleScan starts a network scan. Networks in the request body are swept
+// as given. Otherwise, with a DHCP lease source configured only leases that
+// look like miners are probed; without one, or if the leases can't be read,
+// the configured networks (or every local subnet) are swept.
 // POST /api/scan
 func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
-	// Detect all available subnets
-	subnets := s.scanner.DetectAllSubnets()
-	if len(subnets) == 0 {
-		http.Error(w, "no network interfaces found", http.StatusInternalServerError)
+	var req ScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Scanning subnets: %v", subnets)
+	cfg := s.cfg().Scanner
+	if len(req.Networks) > 0 {
+		for _, n := range req.Networks {
+			if _, err := s.scanner.ExpandNetwork(n); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		cfg.Networks = req.Networks
+		cfg.DHCP.Source = ""
+	}
 
 	// Run scan with timeout
 	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 	defer cancel()
 
-	// Scan all subnets
-	var allMiners []*storage.Miner
-	seen := make(map[string]bool)
-
-	for _, subnet := range subnets {
-		results, err := s.scanner.Scan(ctx, subnet)
-		if err != nil {
-			log.Printf("Error scanning subnet %s: %v", subnet, err)
-			continue
-		}
-
-		for _, result := range results {
-			// Avoid duplicates (in case same miner appears on multiple interfaces)
-			if !seen[result.Miner.IP] {
-				seen[result.Miner.IP] = true
-				allMiners = append(allMiners, result.Miner)
-			}
-		}
+	d, err := s.scanner.Discover(ctx, cfg)
+	if d == nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		log.Printf("Error probing DHCP leases: %v", err)
 	}
 
-	log.Printf("Scan complete: found %d miners", len(allMiners))
+	miners := make([]*storage.Miner, 0, len(d.Results))
+	for _, result := range d.Results {
+		miners = append(miners, result.Miner)
+	}
+	log.Printf("Scan complete: found %d miners", len(miners))
 
 	s.jsonResponse(w, ScanResponse{
-		Subnets: subnets,
-		Results: allMiners,
+		Source:  d.Source,
+		Subnets: d.Subnets,
+		Leases:  d.Leases,
+		Results: miners,
 	})
 }
 
@@ -746,11 +1550,20 @@ func (s *Server) handleAddMiner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Save miner to storage
+	// A registered miner that changed IP keeps its record and history
+	if _, err := s.collector.FollowMAC(req.IP, result.Miner.MacAddr); err != nil {
+		log.Printf("Could not move miner %s to %s: %v", result.Miner.MacAddr, req.IP, err)
+	}
+
+	// Save miner to storage, resuming it if it was paused
 	if err := s.storage.UpsertMiner(result.Miner); err != nil {
 		http.Error(w, "failed to save miner: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := s.storage.SetMinerPaused(req.IP, false); err != nil {
+		http.Error(w, "failed to save miner: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	// Start collecting from this miner
 	s.collector.AddMiner(req.IP)
@@ -758,144 +1571,432 @@ func (s *Server) handleAddMiner(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, result.Miner)
 }
 
+// MinerRefreshResult is the outcome of refreshing one miner's system info
+type MinerRefreshResult struct {
+	IP              string   `json:"ip"`
+	Hostname        string   `json:"hostname"`
+	DeviceModel     string   `json:"deviceModel"`
+	ASICModel       string   `json:"asicModel"`
+	FirmwareVersion string   `json:"firmwareVersion"`
+	MacAddr         string   `json:"macAddr"`
+	Changed         []string `json:"changed"` // Fields that differ from the stored record
+	Error           string   `json:"error,omitempty"`
+}
+
+// RefreshMinersResponse is the response for POST /api/miners/refresh
+type RefreshMinersResponse struct {
+	Refreshed int                  `json:"refreshed"`
+	Failed    int                  `json:"failed"`
+	Results   []MinerRefreshResult `json:"results"`
+}
+
+// handleRefreshMiners re-queries every known miner's system info and updates
+// hostname, model, firmware and MAC address in one pass
+// POST /api/miners/refresh
+func (s *Server) handleRefreshMiners(w http.ResponseWriter, r *http.Request) {
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Query miners concurrently; offline ones would otherwise stall the pass
+	results := make([]MinerRefreshResult, len(miners))
+	var wg sync.WaitGroup
+	for i, m := range miners {
+		wg.Add(1)
+		go func(i int, old *storage.Miner) {
+			defer wg.Done()
+			results[i] = s.refreshMiner(old)
+		}(i, m)
+	}
+	wg.Wait()
+
+	resp := RefreshMinersResponse{Results: results}
+	for _, res := range results {
+		if res.Error != "" {
+			resp.Failed++
+		} else {
+			resp.Refreshed++
+		}
+	}
+
+	log.Printf("Miner refresh: %d refreshed, %d failed", resp.Refreshed, resp.Failed)
+	s.jsonResponse(w, resp)
+}
+
+// refreshMiner refreshes one miner and reports which stored fields changed
+func (s *Server) refreshMiner(old *storage.Miner) MinerRefreshResult {
+	result := MinerRefreshResult{
+		IP:              old.IP,
+		Hostname:        old.Hostname,
+		DeviceModel:     old.DeviceModel,
+		ASICModel:       old.ASICModel,
+		FirmwareVersion: old.FirmwareVersion,
+		MacAddr:         old.MacAddr,
+		Changed:         []string{},
+	}
+
+	m, err := s.collector.RefreshMiner(old.IP)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Empty firmware and MAC are not stored over known values
+	if m.FirmwareVersion == "" {
+		m.FirmwareVersion = old.FirmwareVersion
+	}
+	if m.MacAddr == "" {
+		m.MacAddr = old.MacAddr
+	}
+
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"hostname", old.Hostname, m.Hostname},
+		{"deviceModel", old.DeviceModel, m.DeviceModel},
+		{"asicModel", old.ASICModel, m.ASICModel},
+		{"firmwareVersion", old.FirmwareVersion, m.FirmwareVersion},
+		{"macAddr", old.MacAddr, m.MacAddr},
+	}
+	for _, f := range fields {
+		if f.old != f.new {
+			result.Changed = append(result.Changed, f.name)
+		}
+	}
+
+	result.Hostname = m.Hostname
+	result.DeviceModel = m.DeviceModel
+	result.ASICModel = m.ASICModel
+	result.FirmwareVersion = m.FirmwareVersion
+	result.MacAddr = m.MacAddr
+	return result
+}
+
+// serveWebFile serves a file of the web UI, by its path under web/
+func (s *Server) serveWebFile(w http.ResponseWriter, r *http.Request, name string) {
+	if info, err := fs.Stat(s.files, name); err != nil || info.IsDir() {
+		http.Error(w, path.Base(name)+" not found", http.StatusNotFound)
+		return
+	}
+	http.ServeFileFS(w, r, s.files, name)
+}
+
 // handleStatic serves static files
 // GET /*
 func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+
+	// Serve index.html for root, and for paths that aren't files for SPA routing
+	if info, err := fs.Stat(s.files, name); name == "" || !fs.ValidPath(name) || err != nil || info.IsDir() {
+		s.serveWebFile(w, r, "templates/index.html")
+		return
+	}
+
+	// Disable cache for JS files during development
+	if strings.HasSuffix(name, ".js") {
+		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", "0")
+	}
+
+	http.ServeFileFS(w, r, s.files, name)
+}
+
+// HistoryPoint represents a point in time series data
+type HistoryPoint struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Hashrate    float64   `json:"hashrate"`    // GH/s - current/1min
+	Hashrate10m float64   `json:"hashrate10m"` // GH/s - 10min average
+	Hashrate1h  float64   `json:"hashrate1h"`  // GH/s - 1h average
+	TempASIC    float64   `json:"tempAsic"`    // °C
+	TempASIC2   float64   `json:"tempAsic2"`   // °C - secondary sensor, averaged over miners that report it
+	TempVReg    float64   `json:"tempVreg"`    // °C
+	Power       float64   `json:"power"`       // Watts
+}
+
+// PeriodWindow is the time range one side of a period comparison covers
+type PeriodWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// PeriodValue compares a metric between the current and previous period
+type PeriodValue struct {
+	MinerIP         string   `json:"minerIp,omitempty"`
+	Hostname        string   `json:"hostname,omitempty"`
+	Current         float64  `json:"current"`
+	Previous        float64  `json:"previous"`
+	ChangePct       *float64 `json:"changePct"` // Null when the previous period has nothing to compare against
+	CurrentSamples  int      `json:"currentSamples"`
+	PreviousSamples int      `json:"previousSamples"`
+}
+
+// PeriodComparison is the response of /api/compare-periods
+type PeriodComparison struct {
+	Metric   string        `json:"metric"`
+	Period   string        `json:"period"`
+	Unit     string        `json:"unit"`
+	Current  PeriodWindow  `json:"current"`
+	Previous PeriodWindow  `json:"previous"`
+	Fleet    PeriodValue   `json:"fleet"`
+	Miners   []PeriodValue `json:"miners"`
+}
+
+// periodStart returns the start of the local hour, day or week (from Sunday)
+// containing t, and the start of the period before it
+func periodStart(period string, t time.Time) (time.Time, time.Time, bool) {
+	switch period {
+	case "hour":
+		cur := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		return cur, cur.Add(-time.Hour), true
+	case "day":
+		cur := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return cur, cur.AddDate(0, 0, -1), true
+	case "week":
+		cur := time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+		return cur, cur.AddDate(0, 0, -7), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// setChange fills ChangePct when the previous period has data to compare against
+func (v *PeriodValue) setChange() {
+	if v.PreviousSamples > 0 && v.Previous != 0 {
+		pct := (v.Current - v.Previous) / v.Previous * 100
+		v.ChangePct = &pct
+	}
+}
+
+// handleComparePeriods compares a metric between the current period so far
+// and the same stretch of the previous period (today until now vs yesterday
+// until the same time), per miner and for the fleet
+// GET /api/compare-periods
+// Query params: metric (hashrate, power, temperature, shares, best_share,
+// blocks, earnings; default hashrate), period (hour, day, week; default day)
+func (s *Server) handleComparePeriods(w http.ResponseWriter, r *http.Request) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = "hashrate"
+	}
+	m, ok := storage.PeriodMetrics[metric]
+	if !ok {
+		http.Error(w, "unknown metric: "+metric, http.StatusBadRequest)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "day"
+	}
+	now := time.Now()
+	curStart, prevStart, ok := periodStart(period, now)
+	if !ok {
+		http.Error(w, "period must be hour, day or week", http.StatusBadRequest)
+		return
+	}
+	prevEnd := prevStart.Add(now.Sub(curStart))
+
+	current, err := s.storage.GetPeriodAggregates(metric, curStart, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	previous, err := s.storage.GetPeriodAggregates(metric, prevStart, prevEnd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := PeriodComparison{
+		Metric:   metric,
+		Period:   period,
+		Current:  PeriodWindow{Start: curStart, End: now},
+		Previous: PeriodWindow{Start: prevStart, End: prevEnd},
+		Miners:   make([]PeriodValue, 0, len(miners)),
+	}
 
-	// Serve index.html for root
-	if path == "/" || path == "" {
-		filePath := "web/templates/index.html"
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			http.Error(w, "index.html not found", http.StatusNotFound)
-			return
+	var curMiners, prevMiners int
+	for _, miner := range miners {
+		v := PeriodValue{MinerIP: miner.IP, Hostname: miner.Name()}
+		if a, ok := current[miner.IP]; ok {
+			v.Current, v.CurrentSamples = a.Value, a.Samples
+			curMiners++
+		}
+		if a, ok := previous[miner.IP]; ok {
+			v.Previous, v.PreviousSamples = a.Value, a.Samples
+			prevMiners++
+		}
+		resp.Miners = append(resp.Miners, v)
+
+		fleet := &resp.Fleet
+		fleet.CurrentSamples += v.CurrentSamples
+		fleet.PreviousSamples += v.PreviousSamples
+		if m.Max {
+			fleet.Current = math.Max(fleet.Current, v.Current)
+			fleet.Previous = math.Max(fleet.Previous, v.Previous)
+		} else {
+			fleet.Current += v.Current
+			fleet.Previous += v.Previous
+		}
+	}
+	if m.Average {
+		if curMiners > 0 {
+			resp.Fleet.Current /= float64(curMiners)
+		}
+		if prevMiners > 0 {
+			resp.Fleet.Previous /= float64(prevMiners)
 		}
-		http.ServeFile(w, r, filePath)
-		return
 	}
 
-	// Serve other static files
-	filePath := filepath.Join("web", path)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		// If file doesn't exist, serve index.html for SPA routing
-		indexPath := "web/templates/index.html"
-		if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-			http.Error(w, "not found", http.StatusNotFound)
-			return
+	// Present values in the configured units
+	switch metric {
+	case "hashrate":
+		resp.Unit = "GH/s"
+	case "power":
+		resp.Unit = "W"
+	case "temperature":
+		display := s.cfg().Display.Units()
+		resp.Unit = display.TemperatureSymbol()
+		resp.Fleet.Current, resp.Fleet.Previous = display.Temperature(resp.Fleet.Current), display.Temperature(resp.Fleet.Previous)
+		for i := range resp.Miners {
+			resp.Miners[i].Current = display.Temperature(resp.Miners[i].Current)
+			resp.Miners[i].Previous = display.Temperature(resp.Miners[i].Previous)
 		}
-		http.ServeFile(w, r, indexPath)
-		return
+	case "earnings":
+		amounts := []*float64{&resp.Fleet.Current, &resp.Fleet.Previous}
+		for i := range resp.Miners {
+			amounts = append(amounts, &resp.Miners[i].Current, &resp.Miners[i].Previous)
+		}
+		resp.Unit = s.convertAmounts("USD", amounts...)
+	case "best_share":
+		resp.Unit = "difficulty"
+	default:
+		resp.Unit = metric
 	}
 
-	// Disable cache for JS files during development
-	if strings.HasSuffix(path, ".js") {
-		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
+	resp.Fleet.setChange()
+	for i := range resp.Miners {
+		resp.Miners[i].setChange()
 	}
 
-	http.ServeFile(w, r, filePath)
+	s.jsonResponse(w, resp)
 }
 
-// HistoryPoint represents a point in time series data
-type HistoryPoint struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Hashrate    float64   `json:"hashrate"`    // GH/s - current/1min
-	Hashrate10m float64   `json:"hashrate10m"` // GH/s - 10min average
-	Hashrate1h  float64   `json:"hashrate1h"`  // GH/s - 1h average
-	TempASIC    float64   `json:"tempAsic"`    // °C
-	TempVReg    float64   `json:"tempVreg"`    // °C
-	Power       float64   `json:"power"`       // Watts
+// historyResolution picks what to chart a range of hours from: raw snapshots
+// while they are retained, hourly rollups up to a week and daily rollups
+// beyond. The resolution query param ("raw", "hour" or "day") overrides it.
+func historyResolution(r *http.Request, hours int) string {
+	switch res := r.URL.Query().Get("resolution"); res {
+	case "raw", storage.ResolutionHour, storage.ResolutionDay:
+		return res
+	}
+	switch {
+	case hours <= 1:
+		return "raw"
+	case hours <= 7*24:
+		return storage.ResolutionHour
+	}
+	return storage.ResolutionDay
 }
 
-// handleGetHistory returns aggregated hashrate history for the last hour
+// handleGetHistory returns aggregated fleet history
 // GET /api/history
+// Query params: hours (default 1) or from and to (RFC 3339 or Unix seconds,
+// to defaults to now), resolution (optional, see historyResolution, or a
+// bucket duration such as "1m"), points (optional, LTTB-downsample to N
+// points), tag (optional, only miners with this tag)
+// The resolution used is returned in the X-History-Resolution header. At most
+// maxHistoryPoints points are returned.
 func (s *Server) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	from, to, err := historyRange(r, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	span := to.Sub(from)
+
 	miners, err := s.storage.GetMiners()
+	if err == nil {
+		miners, err = s.filterByTag(r, miners)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Fixed 1 hour timeframe with 5 second sampling for detailed oscillations
-	since := time.Now().Add(-1 * time.Hour)
-	sampleInterval := 5 * time.Second
-
-	// For each time bucket, store snapshot data per miner
-	type minerData struct {
-		hashrate1m  float64 // 1min hashrate from miner
-		hashrate10m float64 // 10min average from miner
-		hashrate1h  float64 // 1h average from miner
-		tempASIC    float64
-		tempVReg    float64
-		power       float64
+	resolution := historyResolution(r, int((span+time.Hour-1)/time.Hour))
+	if res := r.URL.Query().Get("resolution"); res != "" {
+		if _, ok := historyBucket(res, span); ok {
+			resolution = res
+		}
 	}
-	buckets := make(map[time.Time]map[string]minerData)
 
-	for _, m := range miners {
-		snapshots, err := s.storage.GetSnapshots(m.IP, since, 20000)
-		if err != nil {
-			continue
+	var samples []*storage.FleetSample
+	if bucket, ok := historyBucket(resolution, span); ok {
+		ips := make([]string, len(miners))
+		for i, m := range miners {
+			ips[i] = m.IP
 		}
-
-		for _, snap := range snapshots {
-			rounded := snap.Timestamp.Truncate(sampleInterval)
-
-			if buckets[rounded] == nil {
-				buckets[rounded] = make(map[string]minerData)
-			}
-
-			// Always update with latest snapshot for this bucket
-			buckets[rounded][m.IP] = minerData{
-				hashrate1m:  snap.HashRate1m,  // Use miner's 1m average
-				hashrate10m: snap.HashRate10m, // Use miner's 10m average
-				hashrate1h:  snap.HashRate1h,  // Use miner's 1h average
-				tempASIC:    snap.Temperature,
-				tempVReg:    snap.VRTemp,
-				power:       snap.Power,
+		samples, err = s.storage.GetFleetSamples(ips, from, to, bucket)
+		if bucket != rawBucket {
+			resolution = bucket.String()
+		}
+	} else {
+		// Without a tag, rollups of removed miners still count
+		var ips []string
+		if r.URL.Query().Get("tag") != "" {
+			ips = make([]string, 0, len(miners))
+			for _, m := range miners {
+				ips = append(ips, m.IP)
 			}
 		}
+		samples, err = s.storage.GetFleetRollups(resolution, ips, from, to)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// Aggregate across miners for each time bucket
-	var history []HistoryPoint
-	for ts, minerMap := range buckets {
-		var totalHash1m, totalHash10m, totalHash1h, totalPower float64
-		var avgTempASIC, avgTempVReg float64
-		count := 0
-		for _, data := range minerMap {
-			totalHash1m += data.hashrate1m
-			totalHash10m += data.hashrate10m
-			totalHash1h += data.hashrate1h
-			totalPower += data.power
-			avgTempASIC += data.tempASIC
-			avgTempVReg += data.tempVReg
-			count++
-		}
-		if count > 0 {
-			avgTempASIC /= float64(count)
-			avgTempVReg /= float64(count)
-		}
+	history := make([]HistoryPoint, 0, len(samples))
+	for _, f := range samples {
 		history = append(history, HistoryPoint{
-			Timestamp:   ts,
-			Hashrate:    totalHash1m,  // 1min average shows oscillations
-			Hashrate10m: totalHash10m, // 10min average from miner
-			Hashrate1h:  totalHash1h,  // 1h average from miner
-			TempASIC:    avgTempASIC,
-			TempVReg:    avgTempVReg,
-			Power:       totalPower,
+			Timestamp:   f.Timestamp,
+			Hashrate:    f.HashRate1m,  // 1min average shows oscillations
+			Hashrate10m: f.HashRate10m, // 10min average from miner
+			Hashrate1h:  f.HashRate1h,  // 1h average from miner
+			TempASIC:    f.Temperature,
+			TempASIC2:   f.Temp2,
+			TempVReg:    f.VRTemp,
+			Power:       f.Power,
 		})
 	}
 
-	// Sort by timestamp
-	for i := 0; i < len(history)-1; i++ {
-		for j := i + 1; j < len(history); j++ {
-			if history[i].Timestamp.After(history[j].Timestamp) {
-				history[i], history[j] = history[j], history[i]
-			}
+	// Server-side decimation for charts, also capping long rollup series
+	points := parsePoints(r)
+	if points <= 0 || points > maxHistoryPoints {
+		points = maxHistoryPoints
+	}
+	if len(history) > points {
+		idx := lttbIndices(len(history), points,
+			func(i int) float64 { return float64(history[i].Timestamp.Unix()) },
+			func(i int) float64 { return history[i].Hashrate })
+		decimated := make([]HistoryPoint, len(idx))
+		for i, j := range idx {
+			decimated[i] = history[j]
 		}
+		history = decimated
 	}
 
+	w.Header().Set("X-History-Resolution", resolution)
 	s.jsonResponse(w, history)
 }
 
@@ -908,30 +2009,66 @@ type BestShareInfo struct {
 
 // BestSharesResponse contains best shares info
 type BestSharesResponse struct {
-	AllTime *BestShareInfo `json:"allTime,omitempty"`
-	Session *BestShareInfo `json:"session,omitempty"`
+	AllTime *BestShareInfo   `json:"allTime,omitempty"`
+	Session *BestShareInfo   `json:"session,omitempty"`
+	Top     []*storage.Share `json:"top,omitempty"` // Highest kept shares, best first
 }
 
-// handleGetBestShares returns the best shares across all miners
+// handleGetBestShares returns the best shares across all miners. The
+// all-time best and top list come from the kept best shares, which survive
+// share purges.
 // GET /api/shares/best
+// Query params: limit (default 10, at most storage.BestSharesKept)
 func (s *Server) handleGetBestShares(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > storage.BestSharesKept {
+		limit = storage.BestSharesKept
+	}
+
 	miners, err := s.storage.GetMiners()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	top, err := s.storage.GetBestShares("", limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	best := bestShares(s.minerCards(miners), top)
+	best.Top = top
+	names := s.displayNames()
+	for _, info := range []*BestShareInfo{best.AllTime, best.Session} {
+		if info != nil {
+			info.Hostname = nameOr(names, info.MinerIP, info.Hostname)
+		}
+	}
+	for _, share := range top {
+		share.Hostname = nameOr(names, share.MinerIP, share.Hostname)
+	}
+	s.jsonResponse(w, best)
+}
+
+// bestShares finds the best session shares in the miners' latest snapshots.
+// The all-time best is the first of the kept top shares, or the miners'
+// reported best if none were kept yet.
+func bestShares(cards []MinerWithSnapshot, top []*storage.Share) BestSharesResponse {
 	var bestAllTime, bestSession *BestShareInfo
 
-	for _, m := range miners {
-		// Get latest snapshot for this miner to get bestDiff values
-		snapshots, err := s.storage.GetSnapshots(m.IP, time.Now().Add(-5*time.Minute), 1)
-		if err != nil || len(snapshots) == 0 {
+	for _, m := range cards {
+		// The latest snapshot carries the miner's bestDiff values
+		snap := m.Snapshot
+		if snap == nil {
 			continue
 		}
-		snap := snapshots[0]
 
-		// All time best (from miner's bestDiff)
+		// All time best (from miner's bestDiff), until shares are kept
 		if snap.BestDiff > 0 {
 			if bestAllTime == nil || snap.BestDiff > bestAllTime.Difficulty {
 				bestAllTime = &BestShareInfo{
@@ -954,14 +2091,32 @@ func (s *Server) handleGetBestShares(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	s.jsonResponse(w, BestSharesResponse{
+	if len(top) > 0 {
+		bestAllTime = &BestShareInfo{
+			Difficulty: top[0].Difficulty,
+			Hostname:   top[0].Hostname,
+			MinerIP:    top[0].MinerIP,
+		}
+	}
+
+	return BestSharesResponse{
 		AllTime: bestAllTime,
 		Session: bestSession,
-	})
+	}
+}
+
+// PurgePreviewResponse reports what a purge would delete
+type PurgePreviewResponse struct {
+	DryRun     bool                    `json:"dryRun"`
+	Days       int                     `json:"days"`
+	Tables     []storage.PurgeEstimate `json:"tables"`
+	TotalRows  int64                   `json:"totalRows"`
+	TotalBytes int64                   `json:"totalBytes"` // Approximate space reclaimed
 }
 
 // handlePurge purges old data
 // POST /api/purge
+// Query params: days (default 30), dry_run (report what would be deleted without deleting)
 func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
 	days := 30
 	if d := r.URL.Query().Get("days"); d != "" {
@@ -970,7 +2125,26 @@ func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := s.storage.PurgeOldData(days); err != nil {
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		estimates, err := s.storage.PreviewPurgeOldData(days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := PurgePreviewResponse{DryRun: true, Days: days, Tables: estimates}
+		for _, e := range estimates {
+			resp.TotalRows += e.Rows
+			resp.TotalBytes += e.Bytes
+		}
+		s.jsonResponse(w, resp)
+		return
+	}
+
+	if _, err := s.storage.PurgeOldData(days); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.storage.Compact(s.cfg().Retention.Vacuum); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -978,10 +2152,10 @@ func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, map[string]bool{"success": true})
 }
 
-// handleGetDBSize returns the database file size
+// handleGetDBSize returns the database file size with a per-table breakdown
 // GET /api/dbsize
 func (s *Server) handleGetDBSize(w http.ResponseWriter, r *http.Request) {
-	info, err := os.Stat(s.cfg.DBPath)
+	info, err := os.Stat(s.cfg().DBPath)
 	if err != nil {
 		s.jsonResponse(w, map[string]interface{}{
 			"size":      0,
@@ -991,21 +2165,23 @@ func (s *Server) handleGetDBSize(w http.ResponseWriter, r *http.Request) {
 	}
 
 	size := info.Size()
-	var sizeHuman string
-	switch {
-	case size >= 1<<30:
-		sizeHuman = fmt.Sprintf("%.2f GB", float64(size)/(1<<30))
-	case size >= 1<<20:
-		sizeHuman = fmt.Sprintf("%.2f MB", float64(size)/(1<<20))
-	case size >= 1<<10:
-		sizeHuman = fmt.Sprintf("%.2f KB", float64(size)/(1<<10))
-	default:
-		sizeHuman = fmt.Sprintf("%d B", size)
+
+	tables, err := s.storage.GetTableUsage()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var growth int64
+	for _, t := range tables {
+		growth += t.BytesPerDay
 	}
 
 	s.jsonResponse(w, map[string]interface{}{
-		"size":      size,
-		"sizeHuman": sizeHuman,
+		"size":                   size,
+		"sizeHuman":              units.FormatBytes(size),
+		"tables":                 tables,
+		"growthBytesPerDay":      growth,
+		"growthBytesPerDayHuman": units.FormatBytes(growth) + "/day",
 	})
 }
 
@@ -1026,6 +2202,10 @@ type CoinEarningsDetail struct {
 	HistoricalUSD float64 `json:"historicalUsd"` // Value when mined
 	CurrentPrice  float64 `json:"currentPrice"`
 	CurrentUSD    float64 `json:"currentUsd"` // Value at current price
+
+	// Same values in the display currency
+	HistoricalValue float64 `json:"historicalValue"`
+	CurrentValue    float64 `json:"currentValue"`
 }
 
 // EarningsResponse contains earnings calculation
@@ -1034,19 +2214,130 @@ type EarningsResponse struct {
 	TotalBlocks   int                  `json:"totalBlocks"`
 	TotalEarnedUSD float64             `json:"totalEarnedUsd"`   // Historical total
 	TotalCurrentUSD float64            `json:"totalCurrentUsd"`  // Current total
+
+	// Totals converted from USD to the display currency
+	Currency     string  `json:"currency"`
+	TotalEarned  float64 `json:"totalEarned"`
+	TotalCurrent float64 `json:"totalCurrent"`
 }
 
 // handleGetEarnings returns earnings for all coins being mined
 // GET /api/earnings
 // Includes coins configured on miners even if no blocks found yet
 func (s *Server) handleGetEarnings(w http.ResponseWriter, r *http.Request) {
-	// 1. Collect all unique coins being mined (from miner configs)
 	miners, err := s.storage.GetMiners()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	response, err := s.earnings(miners)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, response)
+}
+
+// EnergyDayUsage is energy usage and cost on one local calendar day
+type EnergyDayUsage struct {
+	Day  string  `json:"day"` // "2006-01-02"
+	KWh  float64 `json:"kwh"`
+	Cost float64 `json:"cost"`
+}
+
+// MinerEnergyUsage is a miner's energy usage over the report period
+type MinerEnergyUsage struct {
+	IP       string           `json:"ip"`
+	Hostname string           `json:"hostname"`
+	KWh      float64          `json:"kwh"`
+	Cost     float64          `json:"cost"`
+	Daily    []EnergyDayUsage `json:"daily"` // Days with usage, oldest first
+}
+
+// EnergyReport is the response for GET /api/energy
+type EnergyReport struct {
+	Days      int                `json:"days"`
+	Currency  string             `json:"currency"`
+	TotalKWh  float64            `json:"totalKwh"`
+	TotalCost float64            `json:"totalCost"`
+	Daily     []EnergyDayUsage   `json:"daily"` // Fleet totals for every day, oldest first
+	Miners    []MinerEnergyUsage `json:"miners"`
+}
+
+// handleGetEnergy returns measured energy usage and cost per miner and day
+// GET /api/energy
+// Query params: days (default 30, including today)
+func (s *Server) handleGetEnergy(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-(days-1), 0, 0, 0, 0, now.Location())
+	usage, err := s.storage.GetEnergyDaily(start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hostnames := make(map[string]string)
+	if miners, err := s.storage.GetMiners(); err == nil {
+		for _, m := range miners {
+			hostnames[m.IP] = m.Name()
+		}
+	}
+
+	// Costs are recorded in the energy currency
+	rate := 1.0
+	report := EnergyReport{Days: days, Currency: s.convertAmounts(s.cfg().Energy.Currency, &rate)}
+
+	// Every day of the period, so charts don't skip days without usage
+	dayIndex := make(map[string]int, days)
+	report.Daily = make([]EnergyDayUsage, 0, days)
+	for d := start; !d.After(now); d = d.AddDate(0, 0, 1) {
+		dayIndex[d.Format("2006-01-02")] = len(report.Daily)
+		report.Daily = append(report.Daily, EnergyDayUsage{Day: d.Format("2006-01-02")})
+	}
+
+	byMiner := make(map[string]*MinerEnergyUsage)
+	for _, u := range usage {
+		cost := u.Cost * rate
+		m, ok := byMiner[u.MinerIP]
+		if !ok {
+			m = &MinerEnergyUsage{IP: u.MinerIP, Hostname: hostnames[u.MinerIP], Daily: []EnergyDayUsage{}}
+			byMiner[u.MinerIP] = m
+		}
+		m.KWh += u.KWh
+		m.Cost += cost
+		m.Daily = append(m.Daily, EnergyDayUsage{Day: u.Day, KWh: u.KWh, Cost: cost})
+
+		if i, ok := dayIndex[u.Day]; ok {
+			report.Daily[i].KWh += u.KWh
+			report.Daily[i].Cost += cost
+		}
+		report.TotalKWh += u.KWh
+		report.TotalCost += cost
+	}
+
+	report.Miners = make([]MinerEnergyUsage, 0, len(byMiner))
+	for _, m := range byMiner {
+		report.Miners = append(report.Miners, *m)
+	}
+	sort.Slice(report.Miners, func(i, j int) bool {
+		return report.Miners[i].KWh > report.Miners[j].KWh
+	})
+
+	s.jsonResponse(w, report)
+}
+
+// earnings values the blocks found for every coin being mined
+func (s *Server) earnings(miners []*storage.Miner) (*EarningsResponse, error) {
+	// 1. Collect all unique coins being mined (from miner configs)
 	activeCoinIDs := make(map[string]bool)
 	for _, m := range miners {
 		coinID := m.CoinID
@@ -1059,8 +2350,7 @@ func (s *Server) handleGetEarnings(w http.ResponseWriter, r *http.Request) {
 	// 2. Get actual earnings (coins with blocks)
 	allEarnings, err := s.storage.GetTotalEarnings()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	earningsByCoin := make(map[string]*storage.CoinEarnings)
@@ -1104,14 +2394,25 @@ func (s *Server) handleGetEarnings(w http.ResponseWriter, r *http.Request) {
 		response.Coins = append(response.Coins, detail)
 	}
 
+	// Coin prices are fetched in USD
+	response.TotalEarned = response.TotalEarnedUSD
+	response.TotalCurrent = response.TotalCurrentUSD
+	amounts := []*float64{&response.TotalEarned, &response.TotalCurrent}
+	for i := range response.Coins {
+		response.Coins[i].HistoricalValue = response.Coins[i].HistoricalUSD
+		response.Coins[i].CurrentValue = response.Coins[i].CurrentUSD
+		amounts = append(amounts, &response.Coins[i].HistoricalValue, &response.Coins[i].CurrentValue)
+	}
+	response.Currency = s.convertAmounts("USD", amounts...)
+
 	if len(response.Coins) == 0 {
 		response.Coins = []CoinEarningsDetail{}
 	}
 
-	s.jsonResponse(w, response)
+	return &response, nil
 }
 
-// handleTestAlert sends a test alert to the configured Discord webhook.
+// handleTestAlert sends a test alert to the configured Discord webhook and Matrix room.
 // POST /api/alerts/test
 // Body (optional): {"type": "block_found"} — sends a sample alert for that type.
 // Empty body or no type — sends the generic connectivity test.
@@ -1137,6 +2438,192 @@ func (s *Server) handleTestAlert(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, map[string]bool{"success": true})
 }
 
+// handleGetFailedDeliveries returns alert notifications that exhausted their retries
+// GET /api/alerts/failed
+// Query params: limit (default 200)
+func (s *Server) handleGetFailedDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		http.Error(w, "insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	limit := 200
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	deliveries, err := s.storage.GetFailedDeliveries(limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []*storage.FailedDelivery{}
+	}
+	s.jsonResponse(w, deliveries)
+}
+
+// ReplayResult reports the outcome of replaying one failed delivery
+type ReplayResult struct {
+	ID      int64  `json:"id"`
+	Channel string `json:"channel"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleReplayFailedDelivery sends one failed delivery again
+// POST /api/alerts/failed/{id}/replay
+func (s *Server) handleReplayFailedDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.storage.GetFailedDelivery(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if d == nil {
+		http.Error(w, "failed delivery not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.alerts.ReplayFailedDelivery(d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleReplayFailedDeliveries sends every failed delivery again, oldest first
+// POST /api/alerts/failed/replay
+func (s *Server) handleReplayFailedDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := s.storage.GetFailedDeliveries(1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]ReplayResult, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		d := deliveries[i]
+		result := ReplayResult{ID: d.ID, Channel: d.Channel, Success: true}
+		if err := s.alerts.ReplayFailedDelivery(d); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	s.jsonResponse(w, results)
+}
+
+// handleDeleteFailedDelivery discards a failed delivery without sending it
+// DELETE /api/alerts/failed/{id}
+func (s *Server) handleDeleteFailedDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.storage.DeleteFailedDelivery(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "failed delivery not found", http.StatusNotFound)
+		return
+	}
+
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleGetAlerts returns the alert history
+// GET /api/alerts
+// Query params: hours (default 24), type, miner, limit (default 200)
+func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+
+	limit := 200
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	alerts, err := s.storage.GetAlerts(since, r.URL.Query().Get("type"), r.URL.Query().Get("miner"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if alerts == nil {
+		alerts = []*storage.AlertEntry{}
+	}
+	s.jsonResponse(w, alerts)
+}
+
+// handleGetOpenAlerts returns the ongoing problems that have alerted and not
+// yet cleared, oldest first
+// GET /api/alerts/open
+func (s *Server) handleGetOpenAlerts(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		s.jsonResponse(w, []alerts.OpenCondition{})
+		return
+	}
+	s.jsonResponse(w, s.alerts.OpenConditions())
+}
+
+// handleGetAuditLog returns recorded API mutations
+// GET /api/audit
+// Query params: hours (default 168), user, miner, limit (default 200)
+func (s *Server) handleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		http.Error(w, "insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	hours := 168
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+
+	limit := 200
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	entries, err := s.storage.GetAuditEntries(since, r.URL.Query().Get("user"), r.URL.Query().Get("miner"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if entries == nil {
+		entries = []*storage.AuditEntry{}
+	}
+	s.jsonResponse(w, entries)
+}
+
 // jsonResponse sends a JSON response
 func (s *Server) jsonResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
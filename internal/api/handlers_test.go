@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/alerts"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/web"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleStatic(t *testing.T) {
+	s := &Server{files: fstest.MapFS{
+		"templates/index.html": {Data: []byte("index")},
+		"templates/login.html": {Data: []byte("login")},
+		"static/css/style.css": {Data: []byte("body {}")},
+		"static/js/app.js":     {Data: []byte("app()")},
+	}}
+
+	tests := []struct {
+		path     string
+		wantBody string
+	}{
+		{"/", "index"},
+		{"/static/css/style.css", "body {}"},
+		{"/static/js/app.js", "app()"},
+		{"/dashboard/miners", "index"}, // SPA routes
+		{"/static/css", "index"},       // No directory listings
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.handleStatic(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: expected %q, got %d %q", tt.path, tt.wantBody, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.serveWebFile(rec, httptest.NewRequest("GET", "/api/docs", nil), "templates/apidocs.html")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "apidocs.html not found") {
+		t.Errorf("expected 404 for a missing page, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEmbeddedWebUI(t *testing.T) {
+	s := &Server{files: web.Files}
+	for _, page := range []string{"templates/index.html", "templates/login.html", "templates/share.html", "templates/apidocs.html", "static/css/style.css", "static/js/app.js"} {
+		rec := httptest.NewRecorder()
+		s.serveWebFile(rec, httptest.NewRequest("GET", "/", nil), page)
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("expected %s to be built in, got %d", page, rec.Code)
+		}
+	}
+}
+
+func TestReplayFailedDelivery(t *testing.T) {
+	fail := true
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer discord.Close()
+
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine := alerts.NewAlertEngine(&alerts.AlertConfig{WebhookURL: discord.URL})
+	engine.SetStore(store)
+	d := &storage.FailedDelivery{Timestamp: time.Now(), Channel: alerts.ChannelDiscord, AlertType: "miner_offline", Payload: `{"embeds":[]}`, Attempts: 4, LastError: "discord returned status 500", LastAttempt: time.Now()}
+	if err := store.InsertFailedDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	queued, _ := store.GetFailedDeliveries(10)
+	if len(queued) != 1 {
+		t.Fatalf("expected one failed delivery, got %d", len(queued))
+	}
+	id := strconv.FormatInt(queued[0].ID, 10)
+
+	s := &Server{storage: store, alerts: engine}
+	r := chi.NewRouter()
+	r.Post("/api/alerts/failed/{id}/replay", s.handleReplayFailedDelivery)
+
+	tests := []struct {
+		name       string
+		id         string
+		working    bool
+		wantStatus int
+		wantQueued int
+	}{
+		{"invalid id", "abc", false, http.StatusBadRequest, 1},
+		{"unknown id", "999", false, http.StatusNotFound, 1},
+		{"channel still failing", id, false, http.StatusBadGateway, 1},
+		{"delivered", id, true, http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		fail = !tt.working
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/alerts/failed/"+tt.id+"/replay", nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d %s", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+		if queued, _ := store.GetFailedDeliveries(10); len(queued) != tt.wantQueued {
+			t.Errorf("%s: expected %d queued, got %d", tt.name, tt.wantQueued, len(queued))
+		}
+	}
+}
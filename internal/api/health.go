@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/pricing"
+)
+
+// Overall health, from worst to best
+const (
+	healthError    = "error"    // The database doesn't answer; answered with a 503
+	healthDegraded = "degraded" // Running, but miners or pricing need attention
+	healthOK       = "ok"
+)
+
+// DatabaseHealth reports whether the database answers queries
+type DatabaseHealth struct {
+	OK        bool    `json:"ok"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// HealthResponse is the self-diagnostic report of GET /api/health
+type HealthResponse struct {
+	Status        string                     `json:"status"`
+	UptimeSecs    int64                      `json:"uptimeSecs,omitempty"`
+	Database      *DatabaseHealth            `json:"database,omitempty"`
+	Collector     *collector.CollectorHealth `json:"collector,omitempty"`
+	Pricing       *pricing.Health            `json:"pricing,omitempty"`
+	ClientSockets int                        `json:"clientWebSockets,omitempty"`   // Dashboards connected to /ws
+	ClientStreams int                        `json:"clientEventStreams,omitempty"` // Clients of /api/events
+}
+
+// handleHealth reports the state of the database, the collector and its
+// connection to each miner, and the price APIs. It answers 503 when the
+// database is down so it can back a Docker HEALTHCHECK; offline miners or
+// unreachable price APIs only degrade the status. Without credentials while
+// auth is enabled only the status is returned.
+// GET /api/health
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := &HealthResponse{Status: healthOK}
+
+	start := time.Now()
+	db := &DatabaseHealth{OK: true}
+	if err := s.storage.Ping(); err != nil {
+		db.OK, db.Error = false, err.Error()
+		resp.Status = healthError
+	}
+	db.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	coll := s.collector.Health()
+	if resp.Status == healthOK && (coll.Online < coll.Miners || coll.Pollers < coll.Miners) {
+		resp.Status = healthDegraded
+	}
+
+	var prices pricing.Health
+	if s.pricing != nil {
+		prices = s.pricing.Health()
+		if resp.Status == healthOK && prices.Status == "unreachable" {
+			resp.Status = healthDegraded
+		}
+	}
+
+	if !s.cfg().Auth.Enabled || auth.UserFromContext(r.Context()) != nil {
+		resp.UptimeSecs = int64(time.Since(s.started).Seconds())
+		resp.Database = db
+		resp.Collector = coll
+		if s.pricing != nil {
+			resp.Pricing = &prices
+		}
+		resp.ClientSockets = s.hub.ClientCount()
+		resp.ClientStreams = s.hub.streamCount()
+	}
+
+	if resp.Status == healthError {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	s.jsonResponse(w, resp)
+}
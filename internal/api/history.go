@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rawBucket is the finest fleet history bucket, about one poll per miner
+const rawBucket = 5 * time.Second
+
+// maxHistoryPoints caps the points fleet history returns. Snapshot buckets
+// are widened to stay below it and longer rollup series are downsampled.
+const maxHistoryPoints = 2000
+
+// historyRange returns the time range a history request covers: from and to,
+// as RFC 3339 or Unix seconds, or else the last hours (default 1). to
+// defaults to now.
+func historyRange(r *http.Request, now time.Time) (from, to time.Time, err error) {
+	q := r.URL.Query()
+
+	to = now
+	if v := q.Get("to"); v != "" {
+		if to, err = parseHistoryTime(v); err != nil {
+			return from, to, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	if v := q.Get("from"); v != "" {
+		if from, err = parseHistoryTime(v); err != nil {
+			return from, to, fmt.Errorf("invalid from: %w", err)
+		}
+	} else {
+		hours := 1
+		if h := q.Get("hours"); h != "" {
+			if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+				hours = parsed
+			}
+		}
+		from = to.Add(-time.Duration(hours) * time.Hour)
+	}
+
+	if !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseHistoryTime(v string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// historyBucket returns the snapshot bucket size for a resolution: 5 seconds
+// for "raw" or a duration such as "1m", widened to whole seconds so that span
+// fits in maxHistoryPoints buckets. ok is false for the rollup resolutions
+// and anything else that isn't a duration of at least 5 seconds.
+func historyBucket(resolution string, span time.Duration) (bucket time.Duration, ok bool) {
+	bucket = rawBucket
+	if resolution != "raw" {
+		d, err := time.ParseDuration(resolution)
+		if err != nil || d < rawBucket {
+			return 0, false
+		}
+		bucket = d
+	}
+	if widest := span / maxHistoryPoints; bucket < widest {
+		bucket = (widest + time.Second - 1).Truncate(time.Second)
+	}
+	return bucket, true
+}
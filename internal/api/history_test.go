@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHistoryRange(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{name: "default last hour", query: "", wantFrom: now.Add(-time.Hour), wantTo: now},
+		{name: "hours", query: "hours=24", wantFrom: now.Add(-24 * time.Hour), wantTo: now},
+		{name: "from and to", query: "from=2026-02-01T00:00:00Z&to=2026-02-08T00:00:00Z",
+			wantFrom: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2026, 2, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "unix seconds", query: "from=1772323200", wantFrom: time.Unix(1772323200, 0), wantTo: now},
+		{name: "hours before to", query: "hours=2&to=2026-02-01T00:00:00Z",
+			wantFrom: time.Date(2026, 1, 31, 22, 0, 0, 0, time.UTC), wantTo: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "invalid from", query: "from=yesterday", wantErr: true},
+		{name: "reversed", query: "from=2026-02-08T00:00:00Z&to=2026-02-01T00:00:00Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := historyRange(httptest.NewRequest("GET", "/api/history?"+tt.query, nil), now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v to %v", from, to)
+				}
+				return
+			}
+			if err != nil || !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("got %v to %v (%v), want %v to %v", from, to, err, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestHistoryBucket(t *testing.T) {
+	tests := []struct {
+		resolution string
+		span       time.Duration
+		want       time.Duration
+		wantOK     bool
+	}{
+		{resolution: "raw", span: time.Hour, want: 5 * time.Second, wantOK: true},
+		{resolution: "raw", span: 24 * time.Hour, want: 44 * time.Second, wantOK: true},
+		{resolution: "1m", span: 24 * time.Hour, want: time.Minute, wantOK: true},
+		{resolution: "1m", span: 7 * 24 * time.Hour, want: 303 * time.Second, wantOK: true},
+		{resolution: "1s", span: time.Hour},
+		{resolution: "hour", span: time.Hour},
+	}
+
+	for _, tt := range tests {
+		bucket, ok := historyBucket(tt.resolution, tt.span)
+		if bucket != tt.want || ok != tt.wantOK {
+			t.Errorf("historyBucket(%q, %v) = %v, %v; want %v, %v", tt.resolution, tt.span, bucket, ok, tt.want, tt.wantOK)
+		}
+	}
+}
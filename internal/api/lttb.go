@@ -0,0 +1,82 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// maxChartPoints caps the points=N parameter on history endpoints
+const maxChartPoints = 10000
+
+// parsePoints reads the points=N query parameter (0 = no downsampling)
+func parsePoints(r *http.Request) int {
+	if p := r.URL.Query().Get("points"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			if parsed > maxChartPoints {
+				parsed = maxChartPoints
+			}
+			return parsed
+		}
+	}
+	return 0
+}
+
+// lttbIndices returns the indices of the points to keep when downsampling a
+// series of n points to threshold points with the largest-triangle-three-buckets
+// algorithm. x and y return the coordinates of point i; x must be monotonic
+// (either direction). The first and last points are always kept. If threshold
+// is out of range (< 3 or >= n), all indices are returned.
+func lttbIndices(n, threshold int, x, y func(i int) float64) []int {
+	if threshold >= n || threshold < 3 {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	keep := make([]int, 0, threshold)
+	keep = append(keep, 0)
+
+	// Bucket size for the points between the first and last
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+
+	for i := 0; i < threshold-2; i++ {
+		// Average of the next bucket, used as the third triangle vertex
+		nextStart := int(math.Floor(float64(i+1)*every)) + 1
+		nextEnd := int(math.Floor(float64(i+2)*every)) + 1
+		if nextEnd > n {
+			nextEnd = n
+		}
+		var avgX, avgY float64
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += x(j)
+			avgY += y(j)
+		}
+		if count := nextEnd - nextStart; count > 0 {
+			avgX /= float64(count)
+			avgY /= float64(count)
+		}
+
+		// Pick the point in the current bucket forming the largest triangle
+		start := int(math.Floor(float64(i)*every)) + 1
+		end := int(math.Floor(float64(i+1)*every)) + 1
+		ax, ay := x(a), y(a)
+		maxArea := -1.0
+		next := start
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(y(j)-ay) - (ax-x(j))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+
+		keep = append(keep, next)
+		a = next
+	}
+
+	return append(keep, n-1)
+}
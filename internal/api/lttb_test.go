@@ -0,0 +1,53 @@
+package api
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLTTBIndices(t *testing.T) {
+	// Flat series with a single spike: the spike must survive decimation
+	n := 1000
+	ys := make([]float64, n)
+	for i := range ys {
+		ys[i] = 100 + math.Sin(float64(i)/10)
+	}
+	ys[537] = 500
+
+	x := func(i int) float64 { return float64(i) }
+	y := func(i int) float64 { return ys[i] }
+
+	tests := []struct {
+		name      string
+		threshold int
+		wantLen   int
+	}{
+		{name: "downsample", threshold: 50, wantLen: 50},
+		{name: "threshold above length keeps all", threshold: 5000, wantLen: n},
+		{name: "threshold too small keeps all", threshold: 2, wantLen: n},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := lttbIndices(n, tt.threshold, x, y)
+			if len(idx) != tt.wantLen {
+				t.Fatalf("got %d points, want %d", len(idx), tt.wantLen)
+			}
+			if idx[0] != 0 || idx[len(idx)-1] != n-1 {
+				t.Errorf("first/last points not kept: %d..%d", idx[0], idx[len(idx)-1])
+			}
+			spike := false
+			for i, j := range idx {
+				if i > 0 && j <= idx[i-1] {
+					t.Fatalf("indices not strictly increasing at %d", i)
+				}
+				if j == 537 {
+					spike = true
+				}
+			}
+			if !spike {
+				t.Error("spike was dropped by decimation")
+			}
+		})
+	}
+}
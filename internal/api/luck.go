@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// LuckSummary compares the shares found with what the hashrate should have
+// found
+type LuckSummary struct {
+	Shares         int64   `json:"shares"`         // Shares at or above the pool difficulty
+	ExpectedShares float64 `json:"expectedShares"` // Few expected shares make luck noisy
+	Luck           float64 `json:"luck"`           // Shares per 100 expected, 0 without expected shares
+	BestDiff       float64 `json:"bestDiff"`
+	ExpectedBest   float64 `json:"expectedBest"` // Difficulty the work should reach once
+}
+
+// add counts a miner's day into the summary
+func (l *LuckSummary) add(d *storage.LuckDay) {
+	l.Shares += d.Shares
+	l.ExpectedShares += d.ExpectedShares
+	l.ExpectedBest += d.ExpectedBest
+	if d.BestDiff > l.BestDiff {
+		l.BestDiff = d.BestDiff
+	}
+	if l.ExpectedShares > 0 {
+		l.Luck = float64(l.Shares) / l.ExpectedShares * 100
+	}
+}
+
+// LuckDayReport is the luck of one local calendar day
+type LuckDayReport struct {
+	Day string `json:"day"` // "2006-01-02"
+	LuckSummary
+}
+
+// MinerLuck is a miner's luck over the report period
+type MinerLuck struct {
+	MinerIP  string `json:"minerIp"`
+	Hostname string `json:"hostname"`
+	LuckSummary
+	Daily []LuckDayReport `json:"daily"` // Recorded days, oldest first
+}
+
+// LuckReport is the response for GET /api/luck
+type LuckReport struct {
+	Days int `json:"days"`
+	LuckSummary
+	Daily  []LuckDayReport `json:"daily"`  // Fleet luck for every day, oldest first
+	Miners []MinerLuck     `json:"miners"` // Unluckiest first
+}
+
+// luckDays parses the days query param and returns the first day of the
+// range. Luck is only recorded for complete days, so the range ends
+// yesterday.
+func luckDays(r *http.Request, now time.Time) (int, time.Time) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+	return days, time.Date(now.Year(), now.Month(), now.Day()-days, 0, 0, 0, 0, now.Location())
+}
+
+// luckReport adds up the recorded luck of the days from start to yesterday
+func luckReport(rows []*storage.LuckDay, days int, start time.Time, names map[string]string) LuckReport {
+	report := LuckReport{Days: days, Daily: make([]LuckDayReport, 0, days)}
+
+	// Every day of the period, so charts don't skip days without luck
+	dayIndex := make(map[string]int, days)
+	for i, d := 0, start; i < days; i, d = i+1, d.AddDate(0, 0, 1) {
+		dayIndex[d.Format("2006-01-02")] = i
+		report.Daily = append(report.Daily, LuckDayReport{Day: d.Format("2006-01-02")})
+	}
+
+	byMiner := make(map[string]*MinerLuck)
+	for _, row := range rows {
+		m, ok := byMiner[row.MinerIP]
+		if !ok {
+			m = &MinerLuck{MinerIP: row.MinerIP, Hostname: names[row.MinerIP], Daily: []LuckDayReport{}}
+			byMiner[row.MinerIP] = m
+		}
+		m.add(row)
+		day := LuckDayReport{Day: row.Day}
+		day.add(row)
+		m.Daily = append(m.Daily, day)
+
+		if i, ok := dayIndex[row.Day]; ok {
+			report.Daily[i].add(row)
+		}
+		report.add(row)
+	}
+
+	report.Miners = make([]MinerLuck, 0, len(byMiner))
+	for _, m := range byMiner {
+		report.Miners = append(report.Miners, *m)
+	}
+	sort.Slice(report.Miners, func(i, j int) bool {
+		if report.Miners[i].Luck != report.Miners[j].Luck {
+			return report.Miners[i].Luck < report.Miners[j].Luck
+		}
+		return report.Miners[i].MinerIP < report.Miners[j].MinerIP
+	})
+	return report
+}
+
+// handleGetLuck returns the fleet's daily luck and each miner's luck
+// GET /api/luck
+// Query params: days (default 30, complete days before today)
+func (s *Server) handleGetLuck(w http.ResponseWriter, r *http.Request) {
+	days, start := luckDays(r, time.Now())
+	rows, err := s.storage.GetLuckDays("", start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	names := make(map[string]string)
+	if miners, err := s.storage.GetMiners(); err == nil {
+		for _, m := range miners {
+			names[m.IP] = m.Name()
+		}
+	}
+	s.jsonResponse(w, luckReport(rows, days, start, names))
+}
+
+// handleGetMinerLuck returns a miner's daily luck
+// GET /api/miners/{ip}/luck
+// Query params: days (default 30, complete days before today)
+func (s *Server) handleGetMinerLuck(w http.ResponseWriter, r *http.Request) {
+	miner, ok := s.findMiner(w, chi.URLParam(r, "ip"))
+	if !ok {
+		return
+	}
+
+	days, start := luckDays(r, time.Now())
+	rows, err := s.storage.GetLuckDays(miner.IP, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	luck := MinerLuck{MinerIP: miner.IP, Hostname: miner.Name(), Daily: []LuckDayReport{}}
+	if report := luckReport(rows, days, start, nil); len(report.Miners) > 0 {
+		luck.LuckSummary = report.Miners[0].LuckSummary
+		luck.Daily = report.Miners[0].Daily
+	}
+	s.jsonResponse(w, luck)
+}
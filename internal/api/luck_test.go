@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestLuckReport(t *testing.T) {
+	start := time.Date(2026, 10, 5, 0, 0, 0, 0, time.Local)
+	rows := []*storage.LuckDay{
+		{MinerIP: "192.168.1.10", Day: "2026-10-05", ExpectedShares: 10, Shares: 12, BestDiff: 3e6, ExpectedBest: 1e6},
+		{MinerIP: "192.168.1.11", Day: "2026-10-05", ExpectedShares: 10, Shares: 4, BestDiff: 5e6, ExpectedBest: 1e6},
+		{MinerIP: "192.168.1.10", Day: "2026-10-07", ExpectedShares: 10, Shares: 8, BestDiff: 2e6, ExpectedBest: 1e6},
+	}
+
+	report := luckReport(rows, 3, start, map[string]string{"192.168.1.11": "BitAxe-Supra"})
+	if report.Shares != 24 || report.ExpectedShares != 30 || report.Luck != 80 || report.BestDiff != 5e6 || report.ExpectedBest != 3e6 {
+		t.Errorf("unexpected fleet luck: %+v", report.LuckSummary)
+	}
+
+	if len(report.Daily) != 3 {
+		t.Fatalf("expected every day of the range, got %d", len(report.Daily))
+	}
+	if d := report.Daily[0]; d.Day != "2026-10-05" || d.Shares != 16 || d.Luck != 80 {
+		t.Errorf("unexpected first day: %+v", d)
+	}
+	if d := report.Daily[1]; d.Day != "2026-10-06" || d.ExpectedShares != 0 || d.Luck != 0 {
+		t.Errorf("expected an empty day without luck, got %+v", d)
+	}
+
+	if len(report.Miners) != 2 {
+		t.Fatalf("expected 2 miners, got %d", len(report.Miners))
+	}
+	if m := report.Miners[0]; m.MinerIP != "192.168.1.11" || m.Hostname != "BitAxe-Supra" || m.Luck != 40 {
+		t.Errorf("expected the unluckiest miner first, got %+v", m)
+	}
+	if m := report.Miners[1]; m.Luck != 100 || len(m.Daily) != 2 || m.Daily[1].Luck != 80 {
+		t.Errorf("unexpected miner luck: %+v", m)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// MaintenanceRequest is the body of POST /api/maintenance
+type MaintenanceRequest struct {
+	MinerIP string     `json:"minerIp"`
+	Start   *time.Time `json:"start,omitempty"`   // Defaults to now
+	End     *time.Time `json:"end,omitempty"`     // Either end or minutes is required
+	Minutes int        `json:"minutes,omitempty"` // Length of the window from its start
+	Note    string     `json:"note,omitempty"`
+}
+
+// reloadMaintenance hands the stored maintenance windows to the alert engine
+func (s *Server) reloadMaintenance() {
+	if s.alerts == nil {
+		return
+	}
+	windows, err := s.storage.GetMaintenanceWindows()
+	if err != nil {
+		log.Printf("Failed to load maintenance windows: %v", err)
+		return
+	}
+	s.alerts.SetMaintenance(windows)
+}
+
+// handleGetMaintenance lists the maintenance windows
+// GET /api/maintenance
+func (s *Server) handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	windows, err := s.storage.GetMaintenanceWindows()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if windows == nil {
+		windows = []*storage.MaintenanceWindow{}
+	}
+	s.jsonResponse(w, windows)
+}
+
+// handleCreateMaintenance schedules a maintenance window for a miner, during
+// which its alerts aren't sent
+// POST /api/maintenance
+func (s *Server) handleCreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Minutes < 0 {
+		http.Error(w, "minutes must not be negative", http.StatusBadRequest)
+		return
+	}
+	if (req.End == nil) == (req.Minutes == 0) {
+		http.Error(w, "either end or minutes is required", http.StatusBadRequest)
+		return
+	}
+
+	window := storage.MaintenanceWindow{MinerIP: req.MinerIP, Start: time.Now(), Note: strings.TrimSpace(req.Note)}
+	if req.Start != nil {
+		window.Start = *req.Start
+	}
+	if req.End != nil {
+		window.End = *req.End
+	} else {
+		window.End = window.Start.Add(time.Duration(req.Minutes) * time.Minute)
+	}
+	if !window.End.After(window.Start) {
+		http.Error(w, "end must be after start", http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.findMiner(w, req.MinerIP); !ok {
+		return
+	}
+
+	if err := s.storage.InsertMaintenanceWindow(&window); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.reloadMaintenance()
+	s.jsonResponse(w, window)
+}
+
+// handleDeleteMaintenance removes a maintenance window, ending it early if it
+// is in progress
+// DELETE /api/maintenance/{id}
+func (s *Server) handleDeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.storage.DeleteMaintenanceWindow(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "maintenance window not found", http.StatusNotFound)
+		return
+	}
+	s.reloadMaintenance()
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
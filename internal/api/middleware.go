@@ -0,0 +1,147 @@
+package api
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// isMutating reports whether a request method can change server state
+func isMutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// requestTimeout cancels requests that run longer than d, except the event
+// stream, which stays open until the client leaves
+func requestTimeout(d time.Duration) func(http.Handler) http.Handler {
+	timeout := middleware.Timeout(d)
+	return func(next http.Handler) http.Handler {
+		limited := timeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/events" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// realIP sets the request's RemoteAddr to the client's address. Forwarded
+// headers are only believed from the configured trusted proxies, since any
+// other client could forge them; X-Forwarded-For is read from the right,
+// skipping trusted proxies, so entries the client made up are ignored.
+func (s *Server) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxies := s.cfg().Server.TrustedProxies
+		peer, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			peer = r.RemoteAddr
+		}
+		if len(proxies) == 0 || !trustedProxy(peer, proxies) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := ""
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			hops := strings.Split(xff, ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop := strings.TrimSpace(hops[i])
+				if net.ParseIP(hop) == nil {
+					break
+				}
+				client = hop
+				if !trustedProxy(hop, proxies) {
+					break
+				}
+			}
+		} else if xrip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xrip) != nil {
+			client = xrip
+		}
+		if client != "" {
+			r.RemoteAddr = client
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trustedProxy reports whether ip is one of the proxies, given as IPs or CIDRs
+func trustedProxy(ip string, proxies []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, p := range proxies {
+		if _, network, err := net.ParseCIDR(p); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if proxy := net.ParseIP(p); proxy != nil && proxy.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyGuard rejects every mutating request when the server runs in read-only mode
+func (s *Server) readOnlyGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg().Server.ReadOnly && isMutating(r) {
+			http.Error(w, "server is in read-only mode", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditLog records every mutating API call (including rejected ones) in the audit log
+func (s *Server) auditLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutating(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		entry := &storage.AuditEntry{
+			Timestamp:  time.Now(),
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Action:     r.Method + " " + r.URL.Path,
+			Status:     ww.Status(),
+		}
+		if user := auth.UserFromContext(r.Context()); user != nil {
+			entry.Username = user.Username
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			// Requests rejected before routing only match the "/api/*" mount pattern
+			if pattern := rctx.RoutePattern(); pattern != "" && !strings.HasSuffix(pattern, "*") {
+				entry.Action = r.Method + " " + pattern
+			}
+			entry.Target = rctx.URLParam("ip")
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+
+		if err := s.storage.InsertAuditEntry(entry); err != nil {
+			log.Printf("Failed to write audit log: %v", err)
+		}
+	})
+}
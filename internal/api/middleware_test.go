@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestRealIP(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12"}
+	s := &Server{settings: config.NewManager("", cfg)}
+	var got string
+	handler := s.realIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.RemoteAddr }))
+
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		want    string
+	}{
+		{"direct client", "192.168.1.20:5000", nil, "192.168.1.20:5000"},
+		{"forged by a client", "192.168.1.20:5000", map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4"}, "192.168.1.20:5000"},
+		{"trusted proxy", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "192.168.1.20"}, "192.168.1.20"},
+		{"trusted proxy in a CIDR", "172.17.0.2:443", map[string]string{"X-Real-IP": "192.168.1.20"}, "192.168.1.20"},
+		{"client entry before the proxy's", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "1.2.3.4, 192.168.1.20"}, "192.168.1.20"},
+		{"chained proxies", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "192.168.1.20, 172.17.0.2"}, "192.168.1.20"},
+		{"trusted proxy without headers", "10.0.0.1:443", nil, "10.0.0.1:443"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/scan", nil)
+		req.RemoteAddr = tt.peer
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%s: RemoteAddr = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadOnlyGuard(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := config.DefaultConfig()
+	cfg.Server.ReadOnly = true
+	router := NewServer(config.NewManager("", cfg), store, nil, nil, nil).routes()
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"POST", "/api/miners", http.StatusForbidden},
+		{"PUT", "/api/miners/192.168.1.100/coin", http.StatusForbidden},
+		{"PATCH", "/api/miners/192.168.1.100", http.StatusForbidden},
+		{"DELETE", "/api/schedules/1", http.StatusForbidden},
+		{"POST", "/api/settings", http.StatusForbidden},
+		{"GET", "/api/me", http.StatusOK},
+		{"GET", "/api/settings", http.StatusOK},
+		// Exempt routes reach their handlers
+		{"POST", "/api/login", http.StatusNotFound}, // auth is disabled
+		{"POST", "/api/logout", http.StatusOK},
+		{"POST", "/api/sites/ingest", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}")))
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s %s: status = %d, want %d (%s)", tt.method, tt.path, w.Code, tt.wantStatus, strings.TrimSpace(w.Body.String()))
+		}
+	}
+
+	entries, err := store.GetAuditEntries(time.Time{}, "", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("audit entries = %d, want the 5 rejected mutations", len(entries))
+	}
+}
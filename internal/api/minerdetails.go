@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxDisplayNameLen = 64
+	maxNotesLen       = 4000
+)
+
+// MinerUpdate is a change to a miner's details; unset fields are kept
+type MinerUpdate struct {
+	DisplayName  *string `json:"displayName"`
+	Location     *string `json:"location"` // Energy location, see handleSetMinerLocation
+	PurchaseDate *string `json:"purchaseDate"`
+	Notes        *string `json:"notes"`
+}
+
+// apply validates the update and applies it to d
+func (u *MinerUpdate) apply(d *storage.MinerDetails) error {
+	if u.DisplayName != nil {
+		name := strings.TrimSpace(*u.DisplayName)
+		if len(name) > maxDisplayNameLen {
+			return errors.New("displayName is too long")
+		}
+		d.DisplayName = name
+	}
+	if u.PurchaseDate != nil {
+		if *u.PurchaseDate != "" {
+			if _, err := time.Parse("2006-01-02", *u.PurchaseDate); err != nil {
+				return errors.New("purchaseDate must be YYYY-MM-DD")
+			}
+		}
+		d.PurchaseDate = *u.PurchaseDate
+	}
+	if u.Notes != nil {
+		if len(*u.Notes) > maxNotesLen {
+			return errors.New("notes are too long")
+		}
+		d.Notes = *u.Notes
+	}
+	return nil
+}
+
+// handleUpdateMiner changes a miner's display name, energy location, purchase
+// date or notes. The display name replaces the hostname in leaderboards and
+// alerts; an empty one shows the hostname again.
+// PATCH /api/miners/{ip}
+func (s *Server) handleUpdateMiner(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	var req MinerUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Location != nil && *req.Location != "" && !s.cfg().Energy.HasLocation(*req.Location) {
+		http.Error(w, "unknown location", http.StatusBadRequest)
+		return
+	}
+
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var miner *storage.Miner
+	for _, m := range miners {
+		if m.IP == ip {
+			miner = m
+			break
+		}
+	}
+	if miner == nil {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+
+	if err := req.apply(&miner.MinerDetails); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.storage.SetMinerDetails(ip, miner.MinerDetails); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Location != nil {
+		if err := s.storage.SetMinerLocation(ip, *req.Location); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		miner.Location = *req.Location
+		s.collector.SetEnergyLocation(ip, miner.Location)
+	}
+	if req.DisplayName != nil {
+		s.reloadDisplayNames()
+	}
+
+	if online, ok := s.collector.GetMinerStatus()[ip]; ok {
+		miner.Online = online
+	}
+	s.jsonResponse(w, miner)
+}
+
+// displayNames returns the display names of miners that have one, keyed by
+// IP. Leaderboards show them instead of hostnames.
+func (s *Server) displayNames() map[string]string {
+	names, err := s.storage.GetDisplayNames()
+	if err != nil {
+		log.Printf("GetDisplayNames failed: %v", err)
+	}
+	return names
+}
+
+// reloadDisplayNames passes the display names on to the alert engine
+func (s *Server) reloadDisplayNames() {
+	if s.alerts != nil {
+		s.alerts.SetDisplayNames(s.displayNames())
+	}
+}
+
+// nameOr returns the display name of a miner, or hostname if it has none
+func nameOr(names map[string]string, ip, hostname string) string {
+	if name := names[ip]; name != "" {
+		return name
+	}
+	return hostname
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestMinerUpdate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	current := storage.MinerDetails{DisplayName: "Shelf", PurchaseDate: "2025-11-28", Notes: "Fan replaced"}
+
+	tests := []struct {
+		name    string
+		update  MinerUpdate
+		want    storage.MinerDetails
+		wantErr bool
+	}{
+		{name: "unset fields are kept", update: MinerUpdate{Notes: str("New PSU")},
+			want: storage.MinerDetails{DisplayName: "Shelf", PurchaseDate: "2025-11-28", Notes: "New PSU"}},
+		{name: "display name trimmed", update: MinerUpdate{DisplayName: str("  Garage  ")},
+			want: storage.MinerDetails{DisplayName: "Garage", PurchaseDate: "2025-11-28", Notes: "Fan replaced"}},
+		{name: "cleared", update: MinerUpdate{DisplayName: str(""), PurchaseDate: str("")},
+			want: storage.MinerDetails{Notes: "Fan replaced"}},
+		{name: "invalid date", update: MinerUpdate{PurchaseDate: str("28/11/2025")}, wantErr: true},
+		{name: "name too long", update: MinerUpdate{DisplayName: str(strings.Repeat("x", maxDisplayNameLen+1))}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := current
+			err := tt.update.apply(&d)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", d)
+				}
+				return
+			}
+			if err != nil || d != tt.want {
+				t.Errorf("got %+v (%v), want %+v", d, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxLogLines bounds the lines query param
+const maxLogLines = 10000
+
+// handleGetMinerLogs returns the last raw log lines a miner streamed over its
+// WebSocket, oldest first
+// GET /api/miners/{ip}/logs
+// Query params: lines (default 500)
+func (s *Server) handleGetMinerLogs(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	if s.cfg().MinerLogs.BufferLines <= 0 && !s.cfg().MinerLogs.Persist {
+		http.Error(w, "miner log capture is disabled", http.StatusNotFound)
+		return
+	}
+
+	lines := 500
+	if l := r.URL.Query().Get("lines"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			lines = parsed
+		}
+	}
+	if lines > maxLogLines {
+		lines = maxLogLines
+	}
+
+	logs, err := s.collector.MinerLogs(ip, lines)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if logs == nil {
+		logs = []*storage.MinerLogLine{}
+	}
+
+	s.jsonResponse(w, logs)
+}
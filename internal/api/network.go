@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/camarigor/miner-hq/internal/pricing"
+	"github.com/go-chi/chi/v5"
+)
+
+// NetworkStatus is the response for GET /api/network/{coin}
+type NetworkStatus struct {
+	CoinID           string  `json:"coinId"`
+	CoinSymbol       string  `json:"coinSymbol"`
+	Difficulty       float64 `json:"difficulty"`
+	DifficultySource string  `json:"difficultySource"` // "chain", "miners", or "" if unknown
+	BlockHeight      int64   `json:"blockHeight"`      // 0 if unknown
+	NetworkHashrate  float64 `json:"networkHashrate"`  // GH/s estimated from the difficulty, 0 if unknown
+
+	// Odds of the online miners on this coin finding a block
+	pricing.BlockOdds
+	Hashrate float64 `json:"hashrate"` // GH/s
+	Miners   int     `json:"miners"`
+
+	Halving *pricing.Halving `json:"halving"` // nil when the coin doesn't halve or the height is unknown
+}
+
+// handleGetNetwork returns a coin's network difficulty, block height and next
+// halving, and the fleet's odds of finding one of its blocks
+// GET /api/network/{coin}
+func (s *Server) handleGetNetwork(w http.ResponseWriter, r *http.Request) {
+	coinID := strings.ToLower(chi.URLParam(r, "coin"))
+	info := s.pricing.GetCoinInfoByID(coinID)
+	if info == nil {
+		http.Error(w, "unknown coin", http.StatusNotFound)
+		return
+	}
+
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := NetworkStatus{CoinID: coinID, CoinSymbol: info.Symbol}
+	if network, ok := s.pricing.GetNetworkInfo(coinID); ok {
+		status.Difficulty = network.Difficulty
+		status.DifficultySource = "chain"
+		status.BlockHeight = network.BlockHeight
+		status.Halving = pricing.NextHalving(coinID, network.BlockHeight, network.Fetched)
+	}
+
+	for i, card := range s.minerCards(miners) {
+		m := miners[i]
+		minerCoin := m.CoinID
+		if minerCoin == "" {
+			minerCoin = "dgb" // default fallback for miners without a coin set
+		}
+		if minerCoin != coinID || !card.Online || card.Snapshot == nil {
+			continue
+		}
+
+		if status.Difficulty == 0 {
+			// No chain API, so use what the miners' pools report
+			if difficulty := s.collector.NetworkDifficulty(m.IP); difficulty > 0 {
+				status.Difficulty = difficulty
+				status.DifficultySource = "miners"
+			}
+		}
+		hashrate := card.Snapshot.HashRate1h
+		if hashrate == 0 {
+			hashrate = card.Snapshot.HashRate
+		}
+		status.Hashrate += hashrate
+		status.Miners++
+	}
+
+	status.BlockOdds = pricing.Odds(status.Hashrate, status.Difficulty)
+	status.NetworkHashrate = pricing.NetworkHashrate(coinID, status.Difficulty)
+
+	s.jsonResponse(w, status)
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeDoc describes an API route in the OpenAPI document
+type routeDoc struct {
+	Tag         string
+	Description string // Markdown; its first clause is the summary
+}
+
+// apiDocs documents every API route, keyed by method and path. They are the
+// rows of the API Reference in the README, grouped by its sections.
+var apiDocs = map[string]routeDoc{
+	"GET /api/miners":                        {"Miners", "List all miners with latest snapshot (`?include_disabled=true` adds paused and removed ones)"},
+	"GET /api/miners/{ip}":                   {"Miners", "Single miner details"},
+	"GET /api/miners/{ip}/detail":            {"Miners", "Miner, latest snapshot, uptime, recent and best shares, blocks and alert state in one call (`?hours=24&limit=20`)"},
+	"GET /api/miners/{ip}/hostnames":         {"Miners", "Hostnames the miner has reported over time"},
+	"GET /api/miners/{ip}/pool-difficulty":   {"Miners", "Pool difficulty changes for a miner (`?hours=24`)"},
+	"GET /api/miners/{ip}/logs":              {"Miners", "Last raw log lines the miner streamed, oldest first (`?lines=500`, at most 10000)"},
+	"GET /api/miners/{ip}/uptime":            {"Miners", "Availability, offline incidents, MTBF and MTTR (`?days=30`)"},
+	"GET /api/miners/{ip}/luck":              {"Miners", "Shares found vs expected from hashrate and pool difficulty, and best share vs expected, per day (`?days=30`, complete days)"},
+	"GET /api/miners/{ip}/history":           {"Miners", "Historical snapshots, or hourly/daily rollups with avg/min/max for longer ranges (`?hours=24&page_size=1000&cursor=`, `points=500` to downsample the whole range, `resolution`)"},
+	"POST /api/miners":                       {"Miners", "Add miner by IP"},
+	"POST /api/miners/refresh":               {"Miners", "Re-query every miner and update hostname, model, firmware and MAC"},
+	"DELETE /api/miners/{ip}":                {"Miners", "Remove miner"},
+	"POST /api/miners/{ip}/enable":           {"Miners", "Resume a paused or removed miner"},
+	"POST /api/miners/{ip}/disable":          {"Miners", "Pause a miner, keeping its history"},
+	"PUT /api/miners/{ip}/coin":              {"Miners", "Set coin for miner"},
+	"PATCH /api/miners/{ip}":                 {"Miners", "Set the miner's display name, energy location, purchase date or notes (`{\"displayName\": \"Garage Shelf\"}`)"},
+	"PUT /api/miners/{ip}/location":          {"Miners", "Assign miner to an energy location (`{\"location\": \"garage\"}`, empty for default rate)"},
+	"GET /api/miners/{ip}/tags":              {"Miners", "Miner's tags"},
+	"PUT /api/miners/{ip}/tags":              {"Miners", "Replace miner's tags (`{\"tags\": [\"Garage\", \"rack-1\"]}`)"},
+	"GET /api/miners/{ip}/alerts":            {"Miners", "Miner's alert overrides"},
+	"PUT /api/miners/{ip}/alerts":            {"Miners", "Replace miner's alert thresholds, cooldown and channels (`{\"tempAbove\": 80}`; `{}` clears)"},
+	"GET /api/tags":                          {"Miners", "Every tag with its miners"},
+	"PUT /api/tags/{tag}":                    {"Miners", "Rename a tag on all miners (`{\"name\": \"Shed\"}`)"},
+	"DELETE /api/tags/{tag}":                 {"Miners", "Remove a tag from all miners"},
+	"POST /api/miners/{ip}/restart":          {"Miners", "Reboot the miner (admin)"},
+	"POST /api/miners/{ip}/powercycle":       {"Miners", "Switch the miner's smart plug off and back on, for a miner too hung to restart (admin)"},
+	"PATCH /api/miners/{ip}/settings":        {"Miners", "Change `frequency` (MHz), `coreVoltage` (mV), `fanSpeed` (%) or `autoFanSpeed` on the miner (admin). Frequency and voltage usually apply after a restart"},
+	"PUT /api/miners/{ip}/power-calibration": {"Miners", "Set power multiplier/offset (`{\"multiplier\": 1.08, \"offset\": 2.5}`)"},
+	"GET /api/miners/{ip}/nonces":            {"Miners", "Nonce and version-rolling distribution per ASIC (`hours`, `buckets`)"},
+	"GET /api/miners/{ip}/asics":             {"Miners", "Share count, share of the total and best difficulty per ASIC chip; chips with under half an even split are flagged `low` (`hours`; `asics` to list chips that never sent a share)"},
+	"POST /api/miners/{ip}/session/reset":    {"Miners", "Reset MinerHQ session tracking (alert baselines, cooldowns) for a miner"},
+	"POST /api/miners/session/reset":         {"Miners", "Reset session tracking for the whole fleet"},
+	"GET /api/stats":                         {"Stats & History", "Fleet aggregate stats (`?tag=` for tagged miners only)"},
+	"GET /api/summary":                       {"Stats & History", "Dashboard first load in one call: stats, miners with latest snapshots, best shares, block count and earnings (cached 5s)"},
+	"GET /api/history":                       {"Stats & History", "Aggregated fleet history (`?hours=1`, default, or `?from=&to=`; longer ranges use rollups; `?points=500` to downsample, at most 2,000; `resolution`, `tag`)"},
+	"GET /api/energy":                        {"Stats & History", "Measured energy usage and cost per miner and day, with fleet daily totals (`?days=30`, including today)"},
+	"GET /api/compare-periods":               {"Stats & History", "Current vs previous period per miner and fleet, e.g. today so far vs yesterday up to the same time (`?metric=` hashrate, power, temperature, shares, best_share, blocks or earnings; `?period=` hour, day or week). Hashrate, power and temperature only reach back as far as snapshots are kept"},
+	"GET /api/shares":                        {"Shares & Blocks", "Recent shares, with `networkDifficulty` at submission and `networkPct` (share difficulty as % of a block) (`?hours=24&page_size=100&cursor=`)"},
+	"GET /api/shares/best":                   {"Shares & Blocks", "Best shares: all-time and session, plus the `top` kept shares across miners (`limit`, default 10, at most 100)"},
+	"GET /api/shares/stats":                  {"Shares & Blocks", "Per-miner shares/hour, acceptance rate and hourly (daily beyond 3 days) rejection rate trend from the miners' share counters, and a difficulty histogram by order of magnitude; worst acceptance first (`hours`, default 24)"},
+	"GET /api/luck":                          {"Shares & Blocks", "Fleet luck per day and each miner's luck over the range, unluckiest first (`?days=30`, complete days)"},
+	"GET /api/pool-difficulty":               {"Shares & Blocks", "Pool difficulty changes across all miners (`?hours=24`)"},
+	"GET /api/blocks":                        {"Shares & Blocks", "Found blocks (`?days=365&page_size=100&cursor=`)"},
+	"GET /api/blocks/count":                  {"Shares & Blocks", "Total block count"},
+	"GET /api/competition/weekly":            {"Competition", "Weekly best share + block hunters (`?scoring=` raw, expected or average)"},
+	"GET /api/competition/moneymakers":       {"Competition", "Money makers leaderboard"},
+	"GET /api/competition/history":           {"Competition", "Final standings of past periods, newest first, and trophy counts per miner (`limit` periods, default 10)"},
+	"GET /api/competition/digest":            {"Competition", "Summary of the last finished period: shares, best share, blocks, winner, energy and uptime per miner"},
+	"GET /api/records":                       {"Competition", "Hall of fame: all-time records"},
+	"POST /api/login":                        {"Configuration & Tools", "Log in (`{\"username\", \"password\"}`) and set the session cookie"},
+	"POST /api/logout":                       {"Configuration & Tools", "End the session"},
+	"GET /api/me":                            {"Configuration & Tools", "Current user and role"},
+	"GET /api/openapi.json":                  {"Configuration & Tools", "This OpenAPI 3 document"},
+	"GET /api/docs":                          {"Configuration & Tools", "Swagger UI for this document"},
+	"GET /api/health":                        {"Configuration & Tools", "Database, collector, per-miner connection and price API status; `503` when the database is down"},
+	"GET /api/sites":                         {"Configuration & Tools", "This server's miners and each remote site with its agent's last batch, miners online, hashrate and power"},
+	"POST /api/sites/ingest":                 {"Configuration & Tools", "Batch of miners, snapshots, shares and blocks from a site agent (site token as bearer token)"},
+	"GET /api/collector/status":              {"Configuration & Tools", "Collector loops and each miner's last poll, poll latency, latest error and WebSocket reconnects"},
+	"POST /api/collector/{ip}/reconnect":     {"Configuration & Tools", "Drop a miner's WebSocket and connect it again right away"},
+	"GET /api/settings":                      {"Configuration & Tools", "Current configuration"},
+	"POST /api/settings":                     {"Configuration & Tools", "Save and apply configuration (admin). Invalid settings return `400`"},
+	"GET /api/alerts":                        {"Configuration & Tools", "Alert history, newest first (`hours`, default 24; `type`; `miner`; `limit`)"},
+	"GET /api/alerts/open":                   {"Configuration & Tools", "Ongoing alert conditions and when they started, oldest first"},
+	"POST /api/alerts/test":                  {"Configuration & Tools", "Send test alert (optional `{\"type\": \"...\"}`)"},
+	"GET /api/alerts/failed":                 {"Configuration & Tools", "Alerts that could not be delivered after retries (admin)"},
+	"POST /api/alerts/failed/{id}/replay":    {"Configuration & Tools", "Send a failed alert again"},
+	"POST /api/alerts/failed/replay":         {"Configuration & Tools", "Send every failed alert again, oldest first"},
+	"DELETE /api/alerts/failed/{id}":         {"Configuration & Tools", "Discard a failed alert"},
+	"GET /api/maintenance":                   {"Configuration & Tools", "Maintenance windows silencing a miner's alerts, latest start first"},
+	"POST /api/maintenance":                  {"Configuration & Tools", "Silence a miner's alerts for a while (`{\"minerIp\": \"192.168.1.100\", \"minutes\": 60, \"note\": \"new fan\"}`, or `start`/`end`)"},
+	"DELETE /api/maintenance/{id}":           {"Configuration & Tools", "Remove a maintenance window, ending it early"},
+	"GET /api/plugs":                         {"Configuration & Tools", "Smart plugs with their miner, last wall power reading, errors and last power cycle"},
+	"GET /api/watchdog":                      {"Configuration & Tools", "Watchdog settings and each miner's state: stuck reason, restarts in 24h, backoff end"},
+	"GET /api/pools":                         {"Configuration & Tools", "Pool check settings and each pool the local miners use: miners, reachability, handshake latency, failed checks in a row"},
+	"GET /api/pools/workers":                 {"Configuration & Tools", "Pool stats settings and each pool account's workers: hashrate and best share the pool credits them with, the matched miner's, divergence"},
+	"GET /api/stratum-proxy":                 {"Configuration & Tools", "Each pool the stratum proxy relays to: connection, pool and miner difficulty, and the miners connected through it with their submitted, accepted and rejected shares and blocks"},
+	"GET /api/schedules":                     {"Configuration & Tools", "Mining schedules that pause or underclock miners on a time window, tariff or signal"},
+	"POST /api/schedules":                    {"Configuration & Tools", "Add a mining schedule (admin). Invalid schedules return `400`"},
+	"PUT /api/schedules/{id}":                {"Configuration & Tools", "Replace a mining schedule (admin)"},
+	"DELETE /api/schedules/{id}":             {"Configuration & Tools", "Delete a mining schedule and restore its miners (admin); its savings are kept"},
+	"GET /api/scheduler":                     {"Configuration & Tools", "What the scheduler does with each miner (mining, paused, underclocked), overrides, errors and signal values"},
+	"GET /api/scheduler/savings":             {"Configuration & Tools", "Energy and cost saved per schedule and miner against the power drawn before (`?days=30`, including today)"},
+	"PUT /api/scheduler/overrides/{ip}":      {"Configuration & Tools", "Keep a miner mining or paused whatever its schedules say (`{\"mode\": \"run\"|\"pause\", \"minutes\": 60}`, admin)"},
+	"DELETE /api/scheduler/overrides/{ip}":   {"Configuration & Tools", "Hand a miner back to its schedules (admin)"},
+	"POST /api/scheduler/signals/{name}":     {"Configuration & Tools", "Set an external signal such as solar surplus watts (`{\"value\": 1200}`, admin)"},
+	"GET /api/share-links":                   {"Configuration & Tools", "Read-only share links, without their tokens"},
+	"POST /api/share-links":                  {"Configuration & Tools", "Generate a share link to the stats, competitions and blocks (`{\"name\": \"Discord\", \"hideIps\": true, \"days\": 30}`; the token is only returned once, admin)"},
+	"DELETE /api/share-links/{id}":           {"Configuration & Tools", "Revoke a share link (admin)"},
+	"POST /api/scan":                         {"Configuration & Tools", "Scan network for miners (router DHCP leases when `scanner.dhcp` is set, otherwise a subnet sweep; `{\"networks\": [...]}` sweeps the given CIDRs, ranges or addresses)"},
+	"GET /api/dbsize":                        {"Configuration & Tools", "Database size with per-table rows, bytes and growth per day"},
+	"POST /api/purge":                        {"Configuration & Tools", "Delete snapshots, shares, pool difficulty changes, hourly rollups and failed deliveries older than `days` (`dry_run=true` to preview)"},
+	"GET /api/retention/status":              {"Configuration & Tools", "Retention policies with their cutoffs, schedules and last runs"},
+	"POST /api/backup":                       {"Configuration & Tools", "Download a consistent copy of the database (admin only)"},
+	"POST /api/restore":                      {"Configuration & Tools", "Replace all data with an uploaded backup, as the request body or multipart `file` (admin only)"},
+	"GET /api/audit":                         {"Configuration & Tools", "Audit log of mutating API calls (`hours`, `user`, `miner`, `limit`; admin only)"},
+	"GET /api/firmware/consistency":          {"Configuration & Tools", "Firmware versions per model group and miners that differ"},
+	"GET /api/firmware/releases":             {"Configuration & Tools", "Latest upstream release per firmware family and miners with an update available"},
+	"POST /api/firmware/update":              {"Configuration & Tools", "Flash multipart `firmware` and/or `www` images to the `miners` one at a time (admin only)"},
+	"GET /api/firmware/updates":              {"Configuration & Tools", "Recent firmware rollouts, newest first"},
+	"GET /api/firmware/updates/{id}":         {"Configuration & Tools", "Per-miner progress of a firmware rollout"},
+	"GET /api/coins":                         {"Configuration & Tools", "Supported coins with prices"},
+	"GET /api/network/{coin}":                {"Configuration & Tools", "Network difficulty, block height, next halving and the fleet's odds for a coin"},
+	"GET /api/prices/history":                {"Configuration & Tools", "Recorded coin prices and mined-coin portfolio value (`?coin=&days=`)"},
+	"GET /api/earnings":                      {"Configuration & Tools", "Earnings breakdown per coin"},
+	"GET /api/profitability":                 {"Configuration & Tools", "Expected blocks per year, odds per day, revenue, energy cost and break-even electricity price per miner, per coin and for the fleet"},
+	"GET /api/ws":                            {"Real-time", "WebSocket (share, snapshot, block, alert and log events; `?types=` and `?miners=` to filter)"},
+	"GET /api/events":                        {"Real-time", "Server-Sent Events stream of the same events, named after their type, with a `heartbeat` every 15 seconds (`?types=` and `?miners=` to filter)"},
+}
+
+// apiTags orders the route groups in the OpenAPI document
+var apiTags = []string{"Miners", "Stats & History", "Shares & Blocks", "Competition", "Configuration & Tools", "Real-time"}
+
+// nonJSONResponses are the routes that don't answer with JSON, and the
+// content type they answer with instead
+var nonJSONResponses = map[string]string{
+	"GET /api/docs":    "text/html",
+	"GET /api/events":  "text/event-stream",
+	"POST /api/backup": "application/octet-stream",
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// openAPISpec returns the OpenAPI 3 document of the API routes of r
+func openAPISpec(r chi.Routes) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	_ = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, "/api/") || strings.Contains(route, "*") {
+			return nil
+		}
+		path := pathParam.ReplaceAllString(route, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(method)] = openAPIOperation(method, path)
+		return nil
+	})
+
+	tags := make([]map[string]string, 0, len(apiTags))
+	for _, tag := range apiTags {
+		tags = append(tags, map[string]string{"name": tag})
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "MinerHQ API",
+			"version":     "1.0",
+			"description": "Monitoring API for Bitaxe, NerdQAxe and other AxeOS miners. When auth is enabled, send a session cookie from `POST /api/login`, a bearer API token or HTTP Basic credentials.",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"session":  map[string]string{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+				"apiToken": map[string]string{"type": "http", "scheme": "bearer"},
+				"basic":    map[string]string{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []map[string][]string{{"session": {}}, {"apiToken": {}}, {"basic": {}}},
+	}
+}
+
+// openAPIOperation describes one route
+func openAPIOperation(method, path string) map[string]interface{} {
+	key := method + " " + path
+	doc, ok := apiDocs[key]
+	if !ok {
+		doc = routeDoc{Tag: "Other", Description: key}
+	}
+
+	op := map[string]interface{}{
+		"summary":     summarize(doc.Description),
+		"description": doc.Description,
+		"tags":        []string{doc.Tag},
+		"responses":   openAPIResponses(key),
+	}
+	if isPublicPath(path) {
+		op["security"] = []map[string][]string{}
+	}
+
+	var params []map[string]interface{}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]string{"type": "string"},
+		})
+	}
+	if params != nil {
+		op["parameters"] = params
+	}
+
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		op["requestBody"] = map[string]interface{}{
+			"required": method != http.MethodPost,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]string{"type": "object"}},
+			},
+		}
+	}
+	return op
+}
+
+// openAPIResponses describes the responses of a route
+func openAPIResponses(key string) map[string]interface{} {
+	if key == "GET /api/ws" {
+		return map[string]interface{}{
+			"101": map[string]string{"description": "Switched to the WebSocket protocol"},
+		}
+	}
+	contentType := "application/json"
+	if t, ok := nonJSONResponses[key]; ok {
+		contentType = t
+	}
+	return map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "OK",
+			"content":     map[string]interface{}{contentType: map[string]interface{}{}},
+		},
+		"400": map[string]string{"description": "Invalid request"},
+		"401": map[string]string{"description": "Authentication required"},
+		"403": map[string]string{"description": "Not allowed for the user's role or in read-only mode"},
+	}
+}
+
+// summarize returns the first clause of a route's description
+func summarize(description string) string {
+	if i := strings.IndexAny(description, "(.;"); i > 0 {
+		description = description[:i]
+	}
+	return strings.TrimSpace(description)
+}
+
+// handleGetOpenAPI returns the OpenAPI 3 document of the API
+// GET /api/openapi.json
+func (s *Server) handleGetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.openAPI)
+}
+
+// handleAPIDocs serves Swagger UI for the OpenAPI document
+// GET /api/docs
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	s.serveWebFile(w, r, "templates/apidocs.html")
+}
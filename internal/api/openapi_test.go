@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	r := (&Server{}).routes()
+
+	// Every API route is documented
+	routes := 0
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") && !strings.Contains(route, "*") {
+			routes++
+			if _, ok := apiDocs[method+" "+route]; !ok {
+				t.Errorf("%s %s is missing from apiDocs", method, route)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes != len(apiDocs) {
+		t.Errorf("%d API routes but %d documented, remove the docs of routes that are gone", routes, len(apiDocs))
+	}
+
+	spec := openAPISpec(r)
+	paths := spec["paths"].(map[string]map[string]interface{})
+	op, ok := paths["/api/miners/{ip}"]["patch"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected PATCH /api/miners/{ip} in the spec")
+	}
+	params := op["parameters"].([]map[string]interface{})
+	if len(params) != 1 || params[0]["name"] != "ip" || params[0]["in"] != "path" {
+		t.Errorf("expected the ip path parameter, got %v", params)
+	}
+	if op["summary"] != "Set the miner's display name, energy location, purchase date or notes" {
+		t.Errorf("unexpected summary %q", op["summary"])
+	}
+	if _, ok := op["requestBody"]; !ok {
+		t.Error("expected a request body for PATCH")
+	}
+	if _, ok := paths["/api/health"]["get"].(map[string]interface{})["security"]; !ok {
+		t.Error("expected the health check to need no credentials")
+	}
+}
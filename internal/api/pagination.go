@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// maxPageSize bounds page_size; the shares chart asks for up to this many
+const maxPageSize = 20000
+
+// pageRequest is a page of a newest-first listing
+type pageRequest struct {
+	After storage.PageCursor
+	Size  int
+}
+
+// parsePage reads the cursor and page_size query params. limit is accepted
+// as an older name for page_size. Sizes above maxPageSize are capped.
+func parsePage(r *http.Request, defaultSize int) (pageRequest, error) {
+	page := pageRequest{Size: defaultSize}
+	for _, name := range []string{"limit", "page_size"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+				page.Size = parsed
+			}
+		}
+	}
+	if page.Size > maxPageSize {
+		page.Size = maxPageSize
+	}
+
+	after, err := storage.ParsePageCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		return page, err
+	}
+	page.After = after
+	return page, nil
+}
+
+// setPageHeaders sets X-Total-Count to the rows across all pages and, when
+// there are more, a Link header with the next page's URL. next is the cursor
+// of the page's last row, or nil when the page is the last one.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, page pageRequest, total int64, next *storage.PageCursor) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+
+	links := ""
+	if next != nil {
+		links = pageLink(r, page.Size, next.String(), "next") + ", "
+	}
+	w.Header().Set("Link", links+pageLink(r, page.Size, "", "first"))
+}
+
+// pageLink returns a Link header entry for the request's URL at a cursor
+func pageLink(r *http.Request, size int, cursor, rel string) string {
+	q := r.URL.Query()
+	q.Del("limit")
+	q.Del("cursor")
+	q.Set("page_size", strconv.Itoa(size))
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	return "<" + r.URL.Path + "?" + q.Encode() + `>; rel="` + rel + `"`
+}
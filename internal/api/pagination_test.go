@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestParsePage(t *testing.T) {
+	page, err := parsePage(httptest.NewRequest("GET", "/api/shares?limit=50", nil), 100)
+	if err != nil || page.Size != 50 || !page.After.IsZero() {
+		t.Errorf("expected limit to set the page size, got %+v (%v)", page, err)
+	}
+
+	page, _ = parsePage(httptest.NewRequest("GET", "/api/shares?page_size=999999", nil), 100)
+	if page.Size != maxPageSize {
+		t.Errorf("expected page size capped at %d, got %d", maxPageSize, page.Size)
+	}
+
+	if _, err := parsePage(httptest.NewRequest("GET", "/api/shares?cursor=bogus", nil), 100); err == nil {
+		t.Error("expected an invalid cursor to be rejected")
+	}
+}
+
+func TestSetPageHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/shares?hours=6&limit=2", nil)
+	page, _ := parsePage(r, 100)
+	next := &storage.PageCursor{Timestamp: time.Unix(1760600000, 0), ID: 421}
+
+	w := httptest.NewRecorder()
+	setPageHeaders(w, r, page, 5, next)
+	if got := w.Header().Get("X-Total-Count"); got != "5" {
+		t.Errorf("X-Total-Count = %q, want 5", got)
+	}
+	link := w.Header().Get("Link")
+	want := `</api/shares?cursor=` + next.String() + `&hours=6&page_size=2>; rel="next", </api/shares?hours=6&page_size=2>; rel="first"`
+	if link != want {
+		t.Errorf("Link = %q, want %q", link, want)
+	}
+
+	w = httptest.NewRecorder()
+	setPageHeaders(w, r, page, 5, nil)
+	if strings.Contains(w.Header().Get("Link"), `rel="next"`) {
+		t.Errorf("expected no next link on the last page, got %q", w.Header().Get("Link"))
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleEnableMiner resumes collecting from a paused or removed miner
+// POST /api/miners/{ip}/enable
+func (s *Server) handleEnableMiner(w http.ResponseWriter, r *http.Request) {
+	miner, ok := s.findMiner(w, chi.URLParam(r, "ip"))
+	if !ok {
+		return
+	}
+
+	if err := s.storage.SetMinerPaused(miner.IP, false); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	miner.Enabled, miner.Paused = true, false
+
+	s.collector.SetPowerCalibration(miner.IP, miner.PowerCalibration())
+	s.collector.SetEnergyLocation(miner.IP, miner.Location)
+	s.collector.AddMiner(miner.IP)
+
+	s.jsonResponse(w, s.minerCards([]*storage.Miner{miner})[0])
+}
+
+// handleDisableMiner pauses a miner: it stops being collected from and is
+// hidden like a removed miner, but keeps its history and settings, and isn't
+// added back by network scans
+// POST /api/miners/{ip}/disable
+func (s *Server) handleDisableMiner(w http.ResponseWriter, r *http.Request) {
+	miner, ok := s.findMiner(w, chi.URLParam(r, "ip"))
+	if !ok {
+		return
+	}
+
+	s.collector.RemoveMiner(miner.IP)
+	if err := s.storage.SetMinerPaused(miner.IP, true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	miner.Enabled, miner.Paused = false, true
+
+	s.jsonResponse(w, s.minerCards([]*storage.Miner{miner})[0])
+}
+
+// findMiner looks up a miner, enabled or not, writing an error response if
+// there is none
+func (s *Server) findMiner(w http.ResponseWriter, ip string) (*storage.Miner, bool) {
+	miners, err := s.storage.GetAllMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	for _, m := range miners {
+		if m.IP == ip {
+			return m, true
+		}
+	}
+	http.Error(w, "miner not found", http.StatusNotFound)
+	return nil, false
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/camarigor/miner-hq/internal/plugs"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleGetPlugs returns the configured smart plugs with their last power
+// readings
+// GET /api/plugs
+func (s *Server) handleGetPlugs(w http.ResponseWriter, r *http.Request) {
+	if s.plugs == nil {
+		http.Error(w, "smart plugs not running", http.StatusServiceUnavailable)
+		return
+	}
+	s.jsonResponse(w, s.plugs.Status())
+}
+
+// handlePowerCycle switches a miner's smart plug off and back on, for miners
+// hung so badly their API doesn't answer a restart
+// POST /api/miners/{ip}/powercycle
+func (s *Server) handlePowerCycle(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+	if s.plugs == nil {
+		http.Error(w, "smart plugs not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := s.plugs.PowerCycle(ip); err != nil {
+		if errors.Is(err, plugs.ErrNoPlug) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	s.jsonResponse(w, map[string]string{"status": "ok", "ip": ip})
+}
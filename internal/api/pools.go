@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/camarigor/miner-hq/internal/poolcheck"
+)
+
+// PoolsResponse reports the pool check settings and what the checks found
+// of each pool
+type PoolsResponse struct {
+	Enabled      bool               `json:"enabled"`
+	IntervalSecs int                `json:"intervalSecs"`
+	TimeoutSecs  int                `json:"timeoutSecs"`
+	Failures     int                `json:"failures"`
+	Pools        []poolcheck.Status `json:"pools"`
+}
+
+// handleGetPools returns every pool the local miners are configured for,
+// with the outcome of MinerHQ's own checks of it
+// GET /api/pools
+func (s *Server) handleGetPools(w http.ResponseWriter, r *http.Request) {
+	if s.pools == nil {
+		http.Error(w, "pool checks not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg := s.cfg().PoolCheck
+	s.jsonResponse(w, PoolsResponse{
+		Enabled:      cfg.Enabled,
+		IntervalSecs: cfg.IntervalSecs,
+		TimeoutSecs:  cfg.TimeoutSecs,
+		Failures:     cfg.Failures,
+		Pools:        s.pools.Status(),
+	})
+}
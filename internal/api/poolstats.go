@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/camarigor/miner-hq/internal/poolstats"
+)
+
+// PoolWorkersResponse reports the pool stats settings and what the pools
+// credit each account's workers with
+type PoolWorkersResponse struct {
+	Enabled         bool                `json:"enabled"`
+	IntervalSecs    int                 `json:"intervalSecs"`
+	DivergencePct   float64             `json:"divergencePct"`
+	DivergentChecks int                 `json:"divergentChecks"`
+	Accounts        []poolstats.Account `json:"accounts"`
+}
+
+// handleGetPoolWorkers returns the configured pool accounts with their
+// workers, reconciled with the miners they are matched to
+// GET /api/pools/workers
+func (s *Server) handleGetPoolWorkers(w http.ResponseWriter, r *http.Request) {
+	if s.poolStats == nil {
+		http.Error(w, "pool stats not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg := s.cfg().PoolStats
+	s.jsonResponse(w, PoolWorkersResponse{
+		Enabled:         cfg.Enabled,
+		IntervalSecs:    cfg.IntervalSecs,
+		DivergencePct:   cfg.DivergencePct,
+		DivergentChecks: cfg.DivergentChecks,
+		Accounts:        s.poolStats.Accounts(),
+	})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// PortfolioPoint is the value of the coins mined up to a point in time, at
+// the prices of that time
+type PortfolioPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	ValueUSD  float64   `json:"valueUsd"`
+	Value     float64   `json:"value"` // In the display currency, at today's exchange rate
+}
+
+// PriceHistoryResponse is the response for GET /api/prices/history
+type PriceHistoryResponse struct {
+	Coin      string                `json:"coin,omitempty"`
+	Days      int                   `json:"days"`
+	Currency  string                `json:"currency"`
+	Prices    []*storage.PricePoint `json:"prices"`    // Oldest first
+	Portfolio []PortfolioPoint      `json:"portfolio"` // Oldest first
+}
+
+// handleGetPriceHistory returns the recorded coin prices and how the value
+// of the mined coins evolved with them
+// GET /api/prices/history
+// Query params: coin (default all coins), days (default 30)
+func (s *Server) handleGetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	coinID := r.URL.Query().Get("coin")
+	if coinID != "" && s.pricing.GetCoinInfoByID(coinID) == nil {
+		http.Error(w, "unknown coin", http.StatusBadRequest)
+		return
+	}
+
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+
+	prices, err := s.storage.GetPriceHistory(coinID, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	startPrices, err := s.storage.GetLastPricesBefore(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	blocks, err := s.storage.GetBlocksInRange(time.Time{}, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if prices == nil {
+		prices = []*storage.PricePoint{}
+	}
+
+	resp := PriceHistoryResponse{
+		Coin:      coinID,
+		Days:      days,
+		Prices:    prices,
+		Portfolio: portfolioValue(blocks, prices, startPrices, coinID),
+	}
+
+	// Coin prices are fetched in USD
+	amounts := make([]*float64, len(resp.Portfolio))
+	for i := range resp.Portfolio {
+		resp.Portfolio[i].Value = resp.Portfolio[i].ValueUSD
+		amounts[i] = &resp.Portfolio[i].Value
+	}
+	resp.Currency = s.convertAmounts("USD", amounts...)
+
+	s.jsonResponse(w, resp)
+}
+
+// portfolioValue values the coins mined up to each recorded price, oldest
+// first, leaving out orphaned blocks. blocks and prices must be sorted oldest first, and startPrices holds
+// each coin's last price before the first one. Prices recorded within the same
+// minute, as when all coins are refreshed together, make up one point. A
+// non-empty coinID only values that coin.
+func portfolioValue(blocks []*storage.Block, prices []*storage.PricePoint, startPrices map[string]float64, coinID string) []PortfolioPoint {
+	current := make(map[string]float64, len(startPrices))
+	for coin, price := range startPrices {
+		current[coin] = price
+	}
+	holdings := make(map[string]float64)
+
+	points := []PortfolioPoint{}
+	next := 0
+	for _, p := range prices {
+		for next < len(blocks) && !blocks[next].Timestamp.After(p.Timestamp) {
+			if b := blocks[next]; b.CoinID != "" && b.Status != storage.BlockOrphaned && (coinID == "" || b.CoinID == coinID) {
+				holdings[b.CoinID] += b.BlockReward
+			}
+			next++
+		}
+		current[p.CoinID] = p.PriceUSD
+
+		var value float64
+		for coin, coins := range holdings {
+			value += coins * current[coin]
+		}
+
+		point := PortfolioPoint{Timestamp: p.Timestamp, ValueUSD: value}
+		if n := len(points); n > 0 && points[n-1].Timestamp.Truncate(time.Minute).Equal(p.Timestamp.Truncate(time.Minute)) {
+			points[n-1] = point
+		} else {
+			points = append(points, point)
+		}
+	}
+	return points
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestPortfolioValue(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	blocks := []*storage.Block{
+		{Timestamp: start.Add(-24 * time.Hour), CoinID: "dgb", BlockReward: 200},
+		{Timestamp: start.Add(30 * time.Minute), CoinID: "dgb", BlockReward: 100},
+		{Timestamp: start.Add(45 * time.Minute), CoinID: "bch", BlockReward: 3},
+		{Timestamp: start.Add(50 * time.Minute), CoinID: "bch", BlockReward: 3, Status: storage.BlockOrphaned},
+	}
+	prices := []*storage.PricePoint{
+		{CoinID: "dgb", Timestamp: start, PriceUSD: 0.01},
+		{CoinID: "dgb", Timestamp: start.Add(time.Hour), PriceUSD: 0.02},
+		{CoinID: "bch", Timestamp: start.Add(time.Hour + 5*time.Second), PriceUSD: 400},
+	}
+
+	points := portfolioValue(blocks, prices, map[string]float64{"bch": 300}, "")
+	if len(points) != 2 {
+		t.Fatalf("expected prices within a minute to make one point, got %+v", points)
+	}
+	if points[0].ValueUSD != 2 {
+		t.Errorf("expected 200 DGB at $0.01, got %v", points[0].ValueUSD)
+	}
+	if points[1].ValueUSD != 300*0.02+3*400 {
+		t.Errorf("expected 300 DGB and 3 BCH at the new prices, got %v", points[1].ValueUSD)
+	}
+
+	dgb := portfolioValue(blocks, prices, nil, "dgb")
+	if len(dgb) != 2 || dgb[1].ValueUSD != 300*0.02 {
+		t.Errorf("expected only DGB to be valued, got %+v", dgb)
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/pricing"
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+// ProfitEstimate is what a miner, a coin's miners or the fleet can expect to
+// find, earn and spend per day at the current hashrate, difficulty and prices
+type ProfitEstimate struct {
+	pricing.BlockOdds
+	Hashrate         float64 `json:"hashrate"` // GH/s
+	Power            float64 `json:"power"`    // W
+	RevenuePerDay    float64 `json:"revenuePerDay"`
+	EnergyCostPerDay float64 `json:"energyCostPerDay"`
+	ProfitPerDay     float64 `json:"profitPerDay"`
+
+	// Electricity price per kWh at which revenue just covers energy, nil
+	// without power readings
+	BreakEvenPrice *float64 `json:"breakEvenPrice"`
+}
+
+// add adds another estimate's totals. Odds combine as the chance that at
+// least one of them finds a block.
+func (e *ProfitEstimate) add(o ProfitEstimate) {
+	e.BlocksPerDay += o.BlocksPerDay
+	e.BlocksPerYear += o.BlocksPerYear
+	e.OddsPerDay = 1 - (1-e.OddsPerDay)*(1-o.OddsPerDay)
+	e.Hashrate += o.Hashrate
+	e.Power += o.Power
+	e.RevenuePerDay += o.RevenuePerDay
+	e.EnergyCostPerDay += o.EnergyCostPerDay
+}
+
+// finish derives the totals that don't add up across estimates
+func (e *ProfitEstimate) finish() {
+	if e.BlocksPerDay > 0 {
+		e.DaysToBlock = 1 / e.BlocksPerDay
+	}
+	e.ProfitPerDay = e.RevenuePerDay - e.EnergyCostPerDay
+	e.BreakEvenPrice = nil
+	if kwh := units.KWh(e.Power, 24); kwh > 0 {
+		price := e.RevenuePerDay / kwh
+		e.BreakEvenPrice = &price
+	}
+}
+
+// amounts returns the estimate's money amounts, for currency conversion
+func (e *ProfitEstimate) amounts() []*float64 {
+	amounts := []*float64{&e.RevenuePerDay, &e.EnergyCostPerDay, &e.ProfitPerDay}
+	if e.BreakEvenPrice != nil {
+		amounts = append(amounts, e.BreakEvenPrice)
+	}
+	return amounts
+}
+
+// MinerProfitability is the estimate for one online miner
+type MinerProfitability struct {
+	ProfitEstimate
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	CoinID   string `json:"coinId"`
+}
+
+// CoinProfitability is the estimate for all online miners on one coin
+type CoinProfitability struct {
+	ProfitEstimate
+	CoinID            string  `json:"coinId"`
+	CoinSymbol        string  `json:"coinSymbol"`
+	CoinIcon          string  `json:"coinIcon"`
+	NetworkDifficulty float64 `json:"networkDifficulty"`
+	DifficultySource  string  `json:"difficultySource"` // "chain", "miners", or "" if unknown
+	BlockReward       float64 `json:"blockReward"`
+	Price             float64 `json:"price"` // USD per coin
+	Miners            int     `json:"miners"`
+}
+
+// ProfitabilityReport is the response for GET /api/profitability
+type ProfitabilityReport struct {
+	Currency string               `json:"currency"`
+	Fleet    ProfitEstimate       `json:"fleet"`
+	Coins    []CoinProfitability  `json:"coins"`
+	Miners   []MinerProfitability `json:"miners"`
+}
+
+// handleGetProfitability estimates block odds, revenue, energy cost and the
+// break-even electricity price per miner, per coin and for the fleet
+// GET /api/profitability
+func (s *Server) handleGetProfitability(w http.ResponseWriter, r *http.Request) {
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	energyCurrency := s.cfg().Energy.Currency
+	report := ProfitabilityReport{Coins: []CoinProfitability{}, Miners: []MinerProfitability{}}
+	coins := make(map[string]*CoinProfitability)
+
+	for i, card := range s.minerCards(miners) {
+		snap := card.Snapshot
+		if !card.Online || snap == nil {
+			continue
+		}
+		m := miners[i]
+
+		coinID := m.CoinID
+		if coinID == "" {
+			coinID = "dgb" // default fallback for miners without a coin set
+		}
+		coin, ok := coins[coinID]
+		if !ok {
+			coin = s.coinProfitability(coinID)
+			coins[coinID] = coin
+		}
+		difficulty := coin.NetworkDifficulty
+		if difficulty == 0 {
+			// No chain API, so use what this miner's pool reports
+			difficulty = s.collector.NetworkDifficulty(m.IP)
+			if difficulty > 0 && coin.DifficultySource == "" {
+				coin.NetworkDifficulty = difficulty
+				coin.DifficultySource = "miners"
+			}
+		}
+
+		hashrate := snap.HashRate1h
+		if hashrate == 0 {
+			hashrate = snap.HashRate
+		}
+
+		// Prices are in USD and energy rates in the energy currency
+		odds := pricing.Odds(hashrate, difficulty)
+		revenue, _ := s.pricing.Convert(odds.BlocksPerDay*coin.BlockReward*coin.Price, "USD", energyCurrency)
+
+		miner := MinerProfitability{
+			ProfitEstimate: ProfitEstimate{
+				BlockOdds:        odds,
+				Hashrate:         hashrate,
+				Power:            snap.Power,
+				RevenuePerDay:    revenue,
+				EnergyCostPerDay: s.projectedDailyCost(m, snap.Power, now),
+			},
+			IP:       m.IP,
+			Hostname: card.Hostname,
+			CoinID:   coinID,
+		}
+		miner.finish()
+		report.Miners = append(report.Miners, miner)
+
+		coin.Miners++
+		coin.add(miner.ProfitEstimate)
+	}
+
+	amounts := []*float64{}
+	for i := range report.Miners {
+		amounts = append(amounts, report.Miners[i].amounts()...)
+	}
+	for _, coin := range coins {
+		coin.finish()
+		report.Fleet.add(coin.ProfitEstimate)
+		report.Coins = append(report.Coins, *coin)
+	}
+	for i := range report.Coins {
+		amounts = append(amounts, report.Coins[i].amounts()...)
+	}
+	report.Fleet.finish()
+	amounts = append(amounts, report.Fleet.amounts()...)
+	report.Currency = s.convertAmounts(energyCurrency, amounts...)
+
+	sort.Slice(report.Coins, func(i, j int) bool {
+		return report.Coins[i].RevenuePerDay > report.Coins[j].RevenuePerDay
+	})
+	sort.Slice(report.Miners, func(i, j int) bool {
+		return report.Miners[i].Hashrate > report.Miners[j].Hashrate
+	})
+
+	s.jsonResponse(w, report)
+}
+
+// coinProfitability starts a coin's estimate with its price, block reward and
+// network difficulty from its chain API
+func (s *Server) coinProfitability(coinID string) *CoinProfitability {
+	coin := &CoinProfitability{
+		CoinID:     coinID,
+		CoinSymbol: strings.ToUpper(coinID),
+		Price:      s.pricing.GetPriceForCoin(coinID),
+	}
+	if info := s.pricing.GetCoinInfoByID(coinID); info != nil {
+		coin.CoinSymbol = info.Symbol
+		coin.CoinIcon = info.Icon
+		coin.BlockReward = info.BlockReward
+	}
+	if difficulty, ok := s.pricing.GetNetworkDifficulty(coinID); ok {
+		coin.NetworkDifficulty = difficulty
+		coin.DifficultySource = "chain"
+	}
+	return coin
+}
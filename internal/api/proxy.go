@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/go-chi/chi/v5"
+)
+
+// minerUIPrefix is where browsers logged in with a session cookie reach miner
+// UIs, with a ticket: /miner-ui/{ip}/{ticket}/
+const minerUIPrefix = "/miner-ui/"
+
+// minerUISandbox is the Content-Security-Policy of proxied pages. Without
+// allow-same-origin they run in an opaque origin, so the miner's scripts can't
+// use MinerHQ's session or read its pages and API.
+const minerUISandbox = "sandbox allow-scripts allow-forms allow-popups allow-modals allow-downloads"
+
+// minerUIScope is the session scope of a ticket to a miner's UI
+func minerUIScope(ip string) string {
+	return "miner-ui:" + ip
+}
+
+// handleMinerUIRedirect adds the trailing slash so relative links in the
+// miner's UI resolve under the proxy prefix
+// GET /miners/{ip}/ui
+func (s *Server) handleMinerUIRedirect(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+}
+
+// handleMinerUI proxies the miner's own web interface, so remote users can
+// reach it through MinerHQ (and its auth) without direct access to the device.
+// Only miners MinerHQ is collecting from can be proxied, not those at remote
+// sites. The pages are sandboxed, so they don't send the session cookie:
+// browsers logged in with one are sent on to the UI with a ticket instead.
+// ANY /miners/{ip}/ui/*
+func (s *Server) handleMinerUI(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if !s.minerUIAvailable(w, ip) {
+		return
+	}
+
+	if c, err := r.Cookie(sessionCookie); err == nil && r.Method == http.MethodGet && s.cfg().Auth.Enabled {
+		if user, ok := s.auth.SessionUser(c.Value); ok {
+			s.redirectToMinerUITicket(w, r, ip, user)
+			return
+		}
+	}
+	newMinerUIProxy(ip, chi.URLParam(r, "*"), "/miners/"+ip+"/ui").ServeHTTP(w, r)
+}
+
+// handleMinerUITicket proxies a miner's web interface for a browser holding
+// a ticket to it, see requireMinerUITicket
+// ANY /miner-ui/{ip}/{ticket}/*
+func (s *Server) handleMinerUITicket(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if !s.minerUIAvailable(w, ip) {
+		return
+	}
+	newMinerUIProxy(ip, chi.URLParam(r, "*"), minerUIPrefix+ip+"/"+chi.URLParam(r, "ticket")).ServeHTTP(w, r)
+}
+
+// minerUIAvailable reports whether a miner's UI can be proxied, answering the
+// request if it can't
+func (s *Server) minerUIAvailable(w http.ResponseWriter, ip string) bool {
+	if s.cfg().Server.ReadOnly {
+		http.Error(w, "miner UIs are not available in read-only mode", http.StatusForbidden)
+		return false
+	}
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok || collector.IsRemote(ip) {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// redirectToMinerUITicket sends a browser to the same page of a miner's UI
+// under a new ticket. A ticket only grants the user's access to that miner's
+// UI, so the miner's scripts, which can read it, can't use it for anything
+// else. It lasts as long as a login session.
+func (s *Server) redirectToMinerUITicket(w http.ResponseWriter, r *http.Request, ip string, user *auth.User) {
+	ticket, _, err := s.auth.NewScopedSession(user, minerUIScope(ip), time.Duration(s.cfg().Auth.SessionHours)*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	target := minerUIPrefix + ip + "/" + ticket + "/" + chi.URLParam(r, "*")
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// requireMinerUITicket serves a miner UI route if its ticket is valid for the
+// miner, as the user it was issued to. A browser with an expired ticket is
+// sent back through /miners/{ip}/ui/ for a new one.
+func (s *Server) requireMinerUITicket(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := chi.URLParam(r, "ip")
+		// The page, as the route's wildcard isn't matched yet
+		page := "/miners/" + ip + "/ui" + chi.RouteContext(r.Context()).RoutePath
+		user, ok := s.auth.ScopedSessionUser(chi.URLParam(r, "ticket"), minerUIScope(ip))
+		if !ok {
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, page, http.StatusSeeOther)
+				return
+			}
+			http.Error(w, "miner UI ticket expired", http.StatusUnauthorized)
+			return
+		}
+		// Keep the ticket out of the audit log
+		u := *r.URL
+		u.Path = page
+		u.RawPath = ""
+		r = r.WithContext(auth.WithUser(r.Context(), user))
+		r.URL = &u
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newMinerUIProxy proxies a request to path on a miner's web server, with
+// the miner's pages kept under prefix
+func newMinerUIProxy(host, path, prefix string) *httputil.ReverseProxy {
+	target := &url.URL{Scheme: "http", Host: host}
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = "/" + path
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = host
+			// MinerHQ credentials are not the miner's business
+			pr.Out.Header.Del("Authorization")
+			pr.Out.Header.Del("Cookie")
+			// Let the transport negotiate compression so bodies can be rewritten
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// The miner's cookies would be set for MinerHQ's origin
+			resp.Header.Del("Set-Cookie")
+			resp.Header.Add("Content-Security-Policy", minerUISandbox)
+			return rewriteMinerUIResponse(resp, prefix)
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Miner UI proxy to %s failed: %v", host, err)
+			http.Error(w, "miner unreachable", http.StatusBadGateway)
+		},
+	}
+}
+
+// rewriteMinerUIResponse keeps the browser under the proxy prefix: redirects
+// to absolute paths are prefixed, and so are root-relative URLs in HTML and
+// JavaScript (miner UIs are single-page apps that call "/api/..." directly)
+func rewriteMinerUIResponse(resp *http.Response, prefix string) error {
+	if loc := resp.Header.Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+		resp.Header.Set("Location", prefix+loc)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/html", "application/javascript", "text/javascript":
+	default:
+		return nil
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	body = rewriteRootPaths(body, prefix)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// rewriteRootPaths prefixes the <base href="/"> tag and root-relative API and
+// asset paths that start a string literal or follow a template interpolation
+// (`${host}/api/ws`)
+func rewriteRootPaths(body []byte, prefix string) []byte {
+	body = bytes.ReplaceAll(body, []byte(`<base href="/">`), []byte(`<base href="`+prefix+`/">`))
+	for _, delim := range []string{`"`, `'`, "`", "}"} {
+		for _, root := range []string{"/api/", "/assets/"} {
+			body = bytes.ReplaceAll(body, []byte(delim+root), []byte(delim+prefix+root))
+		}
+	}
+	return body
+}
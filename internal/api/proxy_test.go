@@ -0,0 +1,147 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRewriteMinerUIResponse(t *testing.T) {
+	const prefix = "/miners/192.168.1.42/ui"
+
+	html := `<html><head><base href="/"><script src="/assets/main.js"></script></head></html>`
+	js := "fetch('/api/system/info');new WebSocket(`ws://${location.host}/api/ws`);x=\"/apiary\""
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"html", "text/html; charset=utf-8", html, `<html><head><base href="/miners/192.168.1.42/ui/"><script src="/miners/192.168.1.42/ui/assets/main.js"></script></head></html>`},
+		{"javascript", "application/javascript", js, "fetch('/miners/192.168.1.42/ui/api/system/info');new WebSocket(`ws://${location.host}/miners/192.168.1.42/ui/api/ws`);x=\"/apiary\""},
+		{"json untouched", "application/json", `{"path":"/api/x"}`, `{"path":"/api/x"}`},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{
+			Header: http.Header{"Content-Type": {tt.contentType}},
+			Body:   io.NopCloser(strings.NewReader(tt.body)),
+		}
+		if err := rewriteMinerUIResponse(resp, prefix); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Location": {"/login"}}, Body: io.NopCloser(strings.NewReader(""))}
+	if err := rewriteMinerUIResponse(resp, prefix); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.Header.Get("Location"); got != prefix+"/login" {
+		t.Errorf("expected redirect under prefix, got %s", got)
+	}
+}
+
+func TestMinerUIProxy(t *testing.T) {
+	var got *http.Request
+	miner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Set-Cookie", "miner=1")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Write([]byte(`<script src="/assets/main.js"></script>`))
+	}))
+	defer miner.Close()
+
+	host := strings.TrimPrefix(miner.URL, "http://")
+	req := httptest.NewRequest("GET", "/miners/10.0.0.2/ui/settings?tab=pool", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: "secret"})
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	newMinerUIProxy(host, "settings", "/miners/10.0.0.2/ui").ServeHTTP(rec, req)
+
+	if got == nil || got.URL.Path != "/settings" || got.URL.RawQuery != "tab=pool" {
+		t.Fatalf("unexpected proxied request %+v", got)
+	}
+	if got.Header.Get("Cookie") != "" || got.Header.Get("Authorization") != "" {
+		t.Errorf("expected MinerHQ credentials not to reach the miner, got %v", got.Header)
+	}
+	if c := rec.Header().Get("Set-Cookie"); c != "" {
+		t.Errorf("expected the miner's cookie to be dropped, got %s", c)
+	}
+	if csp := rec.Header().Values("Content-Security-Policy"); len(csp) != 2 || csp[1] != minerUISandbox {
+		t.Errorf("expected the page to be sandboxed, got %v", csp)
+	}
+	if body := rec.Body.String(); body != `<script src="/miners/10.0.0.2/ui/assets/main.js"></script>` {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestMinerUITickets(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Auth.Enabled = true
+	user := &auth.User{Username: "admin", Role: auth.RoleAdmin}
+	s := &Server{settings: config.NewManager("", cfg), auth: auth.NewAuthenticator([]*auth.User{user})}
+
+	// A logged-in browser is sent on with a ticket to the same page
+	r := chi.NewRouter()
+	r.Get("/miners/{ip}/ui/*", func(w http.ResponseWriter, r *http.Request) {
+		s.redirectToMinerUITicket(w, r, chi.URLParam(r, "ip"), user)
+	})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/miners/10.0.0.2/ui/settings?tab=pool", nil))
+	location := rec.Header().Get("Location")
+	if rec.Code != http.StatusSeeOther || !strings.HasPrefix(location, "/miner-ui/10.0.0.2/") || !strings.HasSuffix(location, "/settings?tab=pool") {
+		t.Fatalf("expected a redirect to a ticket, got %d %s", rec.Code, location)
+	}
+	ticket := strings.Split(location, "/")[3]
+
+	r.Route("/miner-ui/{ip}/{ticket}", func(r chi.Router) {
+		r.Use(s.requireMinerUITicket)
+		r.Handle("/*", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(auth.UserFromContext(r.Context()).Username + " " + r.URL.Path))
+		}))
+	})
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{"ticket", "GET", "/miner-ui/10.0.0.2/" + ticket + "/settings", 200, "", "admin /miners/10.0.0.2/ui/settings"},
+		{"ticket posting", "POST", "/miner-ui/10.0.0.2/" + ticket + "/api/system", 200, "", "admin /miners/10.0.0.2/ui/api/system"},
+		{"another miner", "GET", "/miner-ui/10.0.0.3/" + ticket + "/settings", 303, "/miners/10.0.0.3/ui/settings", ""},
+		{"expired page", "GET", "/miner-ui/10.0.0.2/nope/settings", 303, "/miners/10.0.0.2/ui/settings", ""},
+		{"expired post", "POST", "/miner-ui/10.0.0.2/nope/api/system", 401, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.wantStatus, rec.Code)
+		}
+		if tt.wantLocation != "" && rec.Header().Get("Location") != tt.wantLocation {
+			t.Errorf("%s: expected a redirect to %s, got %s", tt.name, tt.wantLocation, rec.Header().Get("Location"))
+		}
+		if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.wantBody, rec.Body.String())
+		}
+	}
+
+	// A ticket isn't a login session
+	if _, ok := s.auth.SessionUser(ticket); ok {
+		t.Error("expected a ticket not to work as a session")
+	}
+}
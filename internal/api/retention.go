@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/retention"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// RetentionStatusResponse reports how long each kind of data is kept and
+// when it was last purged
+type RetentionStatusResponse struct {
+	DryRun   bool               `json:"dryRun"` // Purges only log what they would delete
+	Vacuum   string             `json:"vacuum"`
+	Policies []retention.Status `json:"policies"`
+}
+
+// handleGetRetentionStatus returns the retention policies with their
+// cutoffs, schedules and last runs
+// GET /api/retention/status
+func (s *Server) handleGetRetentionStatus(w http.ResponseWriter, r *http.Request) {
+	if s.retention == nil {
+		http.Error(w, "retention manager not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg := s.cfg()
+	resp := RetentionStatusResponse{
+		DryRun:   cfg.Retention.DryRun,
+		Vacuum:   cfg.Retention.Vacuum,
+		Policies: s.retention.Status(time.Now()),
+	}
+	if resp.Vacuum == "" {
+		resp.Vacuum = storage.VacuumIncremental
+	}
+	s.jsonResponse(w, resp)
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/scheduler"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// refreshScheduler applies schedule and override changes right away
+func (s *Server) refreshScheduler() {
+	if s.scheduler != nil {
+		s.scheduler.Refresh()
+	}
+}
+
+// handleGetSchedules lists the mining schedules
+// GET /api/schedules
+func (s *Server) handleGetSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.storage.GetSchedules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if schedules == nil {
+		schedules = []*storage.Schedule{}
+	}
+	s.jsonResponse(w, schedules)
+}
+
+// decodeSchedule reads and validates a schedule from a request body
+func decodeSchedule(w http.ResponseWriter, r *http.Request) (*storage.Schedule, bool) {
+	var sc storage.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return nil, false
+	}
+	if err := scheduler.Validate(&sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	for i, d := range sc.Days {
+		sc.Days[i] = strings.ToLower(d)
+	}
+	return &sc, true
+}
+
+// handleCreateSchedule adds a mining schedule
+// POST /api/schedules
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	sc, ok := decodeSchedule(w, r)
+	if !ok {
+		return
+	}
+	sc.ID = 0
+	if _, err := s.storage.SaveSchedule(sc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.refreshScheduler()
+	s.jsonResponse(w, sc)
+}
+
+// handleUpdateSchedule replaces a mining schedule
+// PUT /api/schedules/{id}
+func (s *Server) handleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	sc, ok := decodeSchedule(w, r)
+	if !ok {
+		return
+	}
+	sc.ID = id
+
+	found, err := s.storage.SaveSchedule(sc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	s.refreshScheduler()
+	s.jsonResponse(w, sc)
+}
+
+// handleDeleteSchedule deletes a mining schedule. Its miners are restored
+// at once if it paused or underclocked them; its savings are kept.
+// DELETE /api/schedules/{id}
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.storage.DeleteSchedule(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	s.refreshScheduler()
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleGetScheduler returns what the scheduler does with each miner and the
+// last value of every signal
+// GET /api/scheduler
+func (s *Server) handleGetScheduler(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		http.Error(w, "scheduler not running", http.StatusServiceUnavailable)
+		return
+	}
+	s.jsonResponse(w, s.scheduler.Status(time.Now()))
+}
+
+// ScheduleOverrideRequest is the body of PUT /api/scheduler/overrides/{ip}
+type ScheduleOverrideRequest struct {
+	Mode    string `json:"mode"`              // "run" or "pause"
+	Minutes int    `json:"minutes,omitempty"` // How long the override lasts, 0 = until removed
+}
+
+// handleSetScheduleOverride keeps a miner mining or paused whatever its
+// schedules say
+// PUT /api/scheduler/overrides/{ip}
+func (s *Server) handleSetScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	miner, ok := s.findMiner(w, chi.URLParam(r, "ip"))
+	if !ok {
+		return
+	}
+
+	var req ScheduleOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Mode != scheduler.OverrideRun && req.Mode != scheduler.OverridePause {
+		http.Error(w, "mode must be run or pause", http.StatusBadRequest)
+		return
+	}
+	if req.Minutes < 0 {
+		http.Error(w, "minutes must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	override := &storage.ScheduleOverride{MinerIP: miner.IP, Mode: req.Mode}
+	if req.Minutes > 0 {
+		until := time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+		override.Until = &until
+	}
+	if err := s.storage.SetScheduleOverride(override); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.refreshScheduler()
+	s.jsonResponse(w, override)
+}
+
+// handleDeleteScheduleOverride hands a miner back to its schedules
+// DELETE /api/scheduler/overrides/{ip}
+func (s *Server) handleDeleteScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	if err := s.storage.DeleteScheduleOverride(chi.URLParam(r, "ip")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.refreshScheduler()
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleSetSignal records the value of an external signal, e.g. pushed by a
+// solar inverter's automation
+// POST /api/scheduler/signals/{name}
+func (s *Server) handleSetSignal(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		http.Error(w, "scheduler not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Value *float64 `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Value == nil {
+		http.Error(w, "invalid request, expected {\"value\": number}", http.StatusBadRequest)
+		return
+	}
+	s.scheduler.SetSignal(chi.URLParam(r, "name"), *req.Value, time.Now())
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// ScheduleSavings is what a schedule saved on one miner
+type ScheduleSavings struct {
+	storage.ScheduleSavings
+	Schedule string `json:"schedule"` // Name of the schedule, "manual override" for ID 0, empty once deleted
+	Hostname string `json:"hostname"`
+}
+
+// SchedulerSavingsReport is the response for GET /api/scheduler/savings
+type SchedulerSavingsReport struct {
+	Days      int               `json:"days"`
+	Currency  string            `json:"currency"`
+	Hours     float64           `json:"hours"` // Miner hours paused or underclocked
+	SavedKWh  float64           `json:"savedKwh"`
+	SavedCost float64           `json:"savedCost"`
+	Savings   []ScheduleSavings `json:"savings"`
+}
+
+// handleGetSchedulerSavings returns the energy and cost the schedules saved,
+// against what each miner drew before its pause or underclock
+// GET /api/scheduler/savings
+// Query params: days (default 30, including today)
+func (s *Server) handleGetSchedulerSavings(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-(days-1), 0, 0, 0, 0, now.Location())
+	savings, err := s.storage.GetScheduleSavings(start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	names := map[int64]string{0: "manual override"}
+	if schedules, err := s.storage.GetSchedules(); err == nil {
+		for _, sc := range schedules {
+			names[sc.ID] = sc.Name
+		}
+	}
+	hostnames := make(map[string]string)
+	if miners, err := s.storage.GetAllMiners(); err == nil {
+		for _, m := range miners {
+			hostnames[m.IP] = m.Name()
+		}
+	}
+
+	// Savings are recorded in the energy currency
+	rate := 1.0
+	report := SchedulerSavingsReport{Days: days, Currency: s.convertAmounts(s.cfg().Energy.Currency, &rate), Savings: []ScheduleSavings{}}
+	for _, sv := range savings {
+		sv.SavedCost *= rate
+		report.Savings = append(report.Savings, ScheduleSavings{ScheduleSavings: *sv, Schedule: names[sv.ScheduleID], Hostname: hostnames[sv.MinerIP]})
+		report.Hours += sv.Hours
+		report.SavedKWh += sv.SavedKWh
+		report.SavedCost += sv.SavedCost
+	}
+	s.jsonResponse(w, report)
+}
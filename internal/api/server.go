@@ -3,44 +3,167 @@ package api
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/camarigor/miner-hq/internal/agent"
 	"github.com/camarigor/miner-hq/internal/alerts"
+	"github.com/camarigor/miner-hq/internal/auth"
 	"github.com/camarigor/miner-hq/internal/collector"
 	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/mqtt"
+	"github.com/camarigor/miner-hq/internal/plugs"
+	"github.com/camarigor/miner-hq/internal/poolcheck"
+	"github.com/camarigor/miner-hq/internal/poolstats"
 	"github.com/camarigor/miner-hq/internal/pricing"
+	"github.com/camarigor/miner-hq/internal/retention"
 	"github.com/camarigor/miner-hq/internal/scanner"
+	"github.com/camarigor/miner-hq/internal/scheduler"
 	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/stratumproxy"
+	"github.com/camarigor/miner-hq/internal/tsdb"
+	"github.com/camarigor/miner-hq/internal/watchdog"
+	"github.com/camarigor/miner-hq/web"
 )
 
 // Server represents the HTTP API server
 type Server struct {
-	cfg       *config.Config
+	settings  *config.Manager
 	storage   *storage.SQLiteStorage
 	collector *collector.Collector
 	scanner   *scanner.Scanner
+	control   *collector.MinerControl
+	releases  *collector.ReleaseChecker
 	pricing   *pricing.PriceService
 	alerts    *alerts.AlertEngine
+	auth      *auth.Authenticator
 	hub       *WebSocketHub
+	files     fs.FS                // Web UI, embedded unless SetWebDir is called
+	mqtt      *mqtt.Publisher      // Optional, see SetMQTT
+	tsdb      *tsdb.Exporter       // Optional, see SetTSDB
+	agent     *agent.Forwarder     // Optional, see SetAgent
+	retention *retention.Manager   // Optional, see SetRetention
+	watchdog  *watchdog.Watchdog   // Optional, see SetWatchdog
+	pools     *poolcheck.Checker   // Optional, see SetPoolChecker
+	poolStats *poolstats.Monitor   // Optional, see SetPoolStats
+	proxy     *stratumproxy.Proxy  // Optional, see SetStratumProxy
+	plugs     *plugs.Manager       // Optional, see SetPlugs
+	scheduler *scheduler.Scheduler // Optional, see SetScheduler
 	server    *http.Server
+	started   time.Time
+	openAPI   map[string]interface{} // OpenAPI document of the routes, see handleGetOpenAPI
+
+	summaryMu sync.Mutex
+	summary   *SummaryResponse // Cached dashboard summary, see handleGetSummary
+
+	otaMu    sync.Mutex
+	rollouts []*collector.OTARollout // Recent firmware updates, oldest first
 }
 
 // NewServer creates a new API server
-func NewServer(cfg *config.Config, store *storage.SQLiteStorage, coll *collector.Collector, price *pricing.PriceService, alertEngine *alerts.AlertEngine) *Server {
-	return &Server{
-		cfg:       cfg,
+func NewServer(settings *config.Manager, store *storage.SQLiteStorage, coll *collector.Collector, price *pricing.PriceService, alertEngine *alerts.AlertEngine) *Server {
+	cfg := settings.Get()
+	s := &Server{
+		settings:  settings,
 		storage:   store,
 		collector: coll,
 		scanner:   scanner.NewScanner(),
+		control:   collector.NewMinerControl(),
+		releases:  collector.NewReleaseChecker(),
 		pricing:   price,
 		alerts:    alertEngine,
+		auth:      auth.NewAuthenticator(usersFromConfig(cfg)),
 		hub:       NewWebSocketHub(),
+		files:     web.Files,
+		started:   time.Now(),
+	}
+	s.auth.SetTokens(tokensFromConfig(cfg))
+	settings.Subscribe(func(old, cur *config.Config) {
+		s.auth.SetUsers(usersFromConfig(cur))
+		s.auth.SetTokens(tokensFromConfig(cur))
+	})
+	if alertEngine != nil {
+		alertEngine.SetOnAlert(s.forwardAlert)
 	}
+	return s
+}
+
+// cfg returns the current settings. They change while the server runs, so
+// handlers read them once per request rather than keeping them.
+func (s *Server) cfg() *config.Config {
+	return s.settings.Get()
+}
+
+// SetMQTT makes the server publish collector events and alerts to MQTT as it
+// forwards them. Call before Start.
+func (s *Server) SetMQTT(p *mqtt.Publisher) {
+	s.mqtt = p
+}
+
+// SetTSDB makes the server forward snapshots to a time series database as it
+// forwards them to clients. Call before Start.
+func (s *Server) SetTSDB(e *tsdb.Exporter) {
+	s.tsdb = e
+}
+
+// SetAgent makes the server forward the local miners' snapshots, shares and
+// blocks to a central MinerHQ as it forwards them to clients. Call before
+// Start.
+func (s *Server) SetAgent(f *agent.Forwarder) {
+	s.agent = f
+}
+
+// SetWatchdog makes the server feed snapshots and shares to the watchdog as
+// it forwards them to clients. Call before Start.
+func (s *Server) SetWatchdog(w *watchdog.Watchdog) {
+	s.watchdog = w
+}
+
+// SetPoolChecker makes the server report the pool checks
+func (s *Server) SetPoolChecker(c *poolcheck.Checker) {
+	s.pools = c
+}
+
+// SetPoolStats makes the server report the pool accounts' workers
+func (s *Server) SetPoolStats(m *poolstats.Monitor) {
+	s.poolStats = m
+}
+
+// SetStratumProxy makes the server report the stratum proxy's pools and
+// miners
+func (s *Server) SetStratumProxy(p *stratumproxy.Proxy) {
+	s.proxy = p
+}
+
+// SetPlugs enables power cycling miners through their smart plugs and
+// reporting the plugs' readings
+func (s *Server) SetPlugs(m *plugs.Manager) {
+	s.plugs = m
+}
+
+// SetScheduler makes the server feed snapshots to the mining scheduler and
+// report its state. Call before Start.
+func (s *Server) SetScheduler(sc *scheduler.Scheduler) {
+	s.scheduler = sc
+}
+
+// SetWebDir serves the web UI from a directory holding templates/ and
+// static/ instead of the copy embedded in the binary, so changes show without
+// rebuilding. Call before Start.
+func (s *Server) SetWebDir(dir string) {
+	s.files = os.DirFS(dir)
+}
+
+// SetRetention reports the status of the retention manager's purges
+func (s *Server) SetRetention(m *retention.Manager) {
+	s.retention = m
 }
 
 // Start starts the HTTP server
@@ -51,37 +174,130 @@ func (s *Server) Start() error {
 	// Start event forwarding from collector
 	go s.forwardEvents()
 
-	// Setup chi router
+	r := s.routes()
+	s.openAPI = openAPISpec(r)
+
+	// Create HTTP server
+	addr := fmt.Sprintf("%s:%d", s.cfg().Server.Host, s.cfg().Server.Port)
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      r,
+		ReadTimeout:  s.cfg().Server.ReadTimeout,
+		WriteTimeout: s.cfg().Server.WriteTimeout,
+	}
+
+	log.Printf("Starting HTTP server on %s", addr)
+	return s.server.ListenAndServe()
+}
+
+// routes sets up the router with the middleware, the API and the web UI
+func (s *Server) routes() *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(s.realIP)
+	r.Use(requestTimeout(60 * time.Second))
 
 	// CORS
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Authentication applies to the UI and the API alike
+	r.Use(s.authenticate)
+
+	// Logging in and out isn't subject to read-only mode or roles
+	r.Get("/login", s.handleLoginPage)
+	r.Post("/api/login", s.handleLogin)
+	r.Post("/api/logout", s.handleLogout)
+
+	// Site agents authenticate with their site's token, and their batches
+	// aren't subject to read-only mode, roles or the audit log
+	r.Post("/api/sites/ingest", s.handleSiteIngest)
+
+	// Share links expose a read-only subset of the API to anyone with their
+	// token: stats, competitions and blocks, but no settings or miner control
+	r.Route("/share/{token}", func(r chi.Router) {
+		r.Use(s.requireShareLink)
+
+		r.Get("/", s.handleSharePage)
+		r.Get("/api/stats", s.handleGetStats)
+		r.Get("/api/competition/weekly", s.handleGetWeeklyCompetition)
+		r.Get("/api/competition/moneymakers", s.handleGetMoneyMakers)
+		r.Get("/api/competition/history", s.handleGetCompetitionHistory)
+		r.Get("/api/records", s.handleGetRecords)
+		r.Get("/api/blocks", s.handleGetBlocks)
+		r.Get("/api/blocks/count", s.handleGetBlockCount)
+	})
+
 	// API routes
 	r.Route("/api", func(r chi.Router) {
+		r.Use(s.auditLog)
+		r.Use(s.readOnlyGuard)
+		r.Use(s.requireRoleForMutations)
+
+		// Current user
+		r.Get("/me", s.handleGetMe)
+
+		// API documentation
+		r.Get("/openapi.json", s.handleGetOpenAPI)
+		r.Get("/docs", s.handleAPIDocs)
+
+		// Self-diagnostics
+		r.Get("/health", s.handleHealth)
+		r.Get("/collector/status", s.handleGetCollectorStatus)
+		r.Post("/collector/{ip}/reconnect", s.handleReconnectMiner)
+
+		// Remote sites
+		r.Get("/sites", s.handleGetSites)
+
 		// Miners
 		r.Get("/miners", s.handleGetMiners)
 		r.Post("/miners", s.handleAddMiner)
+		r.Post("/miners/refresh", s.handleRefreshMiners)
 		r.Get("/miners/{ip}", s.handleGetMiner)
+		r.Patch("/miners/{ip}", s.handleUpdateMiner)
+		r.Get("/miners/{ip}/detail", s.handleGetMinerDetail)
 		r.Delete("/miners/{ip}", s.handleRemoveMiner)
+		r.Post("/miners/{ip}/enable", s.handleEnableMiner)
+		r.Post("/miners/{ip}/disable", s.handleDisableMiner)
 		r.Get("/miners/{ip}/history", s.handleGetMinerHistory)
+		r.Get("/miners/{ip}/hostnames", s.handleGetHostnameHistory)
+		r.Get("/miners/{ip}/pool-difficulty", s.handleGetPoolDifficultyChanges)
+		r.Get("/miners/{ip}/logs", s.handleGetMinerLogs)
+		r.Get("/miners/{ip}/uptime", s.handleGetMinerUptime)
+		r.Get("/miners/{ip}/luck", s.handleGetMinerLuck)
 		r.Put("/miners/{ip}/coin", s.handleSetMinerCoin)
+		r.Put("/miners/{ip}/power-calibration", s.handleSetMinerPowerCalibration)
+		r.Put("/miners/{ip}/location", s.handleSetMinerLocation)
+		r.Get("/miners/{ip}/tags", s.handleGetMinerTags)
+		r.Put("/miners/{ip}/tags", s.handleSetMinerTags)
+		r.Get("/miners/{ip}/alerts", s.handleGetMinerAlerts)
+		r.Put("/miners/{ip}/alerts", s.handleSetMinerAlerts)
+		r.Post("/miners/{ip}/restart", s.handleRestartMiner)
+		r.Post("/miners/{ip}/powercycle", s.handlePowerCycle)
+		r.Patch("/miners/{ip}/settings", s.handleUpdateMinerSettings)
+		r.Get("/miners/{ip}/nonces", s.handleGetNonceDistribution)
+		r.Get("/miners/{ip}/asics", s.handleGetMinerAsics)
+		r.Post("/miners/{ip}/session/reset", s.handleResetSession)
+		r.Post("/miners/session/reset", s.handleResetSession)
+
+		// Tags
+		r.Get("/tags", s.handleGetTags)
+		r.Put("/tags/{tag}", s.handleRenameTag)
+		r.Delete("/tags/{tag}", s.handleDeleteTag)
 
 		// Stats
 		r.Get("/stats", s.handleGetStats)
+		r.Get("/summary", s.handleGetSummary)
+		r.Get("/compare-periods", s.handleComparePeriods)
 
 		// History (aggregated)
 		r.Get("/history", s.handleGetHistory)
@@ -89,6 +305,11 @@ func (s *Server) Start() error {
 		// Shares
 		r.Get("/shares", s.handleGetShares)
 		r.Get("/shares/best", s.handleGetBestShares)
+		r.Get("/shares/stats", s.handleGetShareStats)
+		r.Get("/luck", s.handleGetLuck)
+
+		// Pool difficulty
+		r.Get("/pool-difficulty", s.handleGetPoolDifficultyChanges)
 
 		// Blocks
 		r.Get("/blocks", s.handleGetBlocks)
@@ -97,45 +318,115 @@ func (s *Server) Start() error {
 		// Competition
 		r.Get("/competition/weekly", s.handleGetWeeklyCompetition)
 		r.Get("/competition/moneymakers", s.handleGetMoneyMakers)
+		r.Get("/competition/history", s.handleGetCompetitionHistory)
+		r.Get("/competition/digest", s.handleGetDigest)
+		r.Get("/records", s.handleGetRecords)
 
 		// Settings
 		r.Get("/settings", s.handleGetSettings)
 		r.Post("/settings", s.handleSaveSettings)
 
 		// Alerts
+		r.Get("/alerts", s.handleGetAlerts)
+		r.Get("/alerts/open", s.handleGetOpenAlerts)
 		r.Post("/alerts/test", s.handleTestAlert)
+		r.Get("/alerts/failed", s.handleGetFailedDeliveries)
+		r.Post("/alerts/failed/replay", s.handleReplayFailedDeliveries)
+		r.Post("/alerts/failed/{id}/replay", s.handleReplayFailedDelivery)
+		r.Delete("/alerts/failed/{id}", s.handleDeleteFailedDelivery)
+		r.Get("/maintenance", s.handleGetMaintenance)
+		r.Post("/maintenance", s.handleCreateMaintenance)
+		r.Delete("/maintenance/{id}", s.handleDeleteMaintenance)
+
+		// Watchdog
+		r.Get("/watchdog", s.handleGetWatchdog)
+
+		// Pool checks
+		r.Get("/pools", s.handleGetPools)
+		r.Get("/pools/workers", s.handleGetPoolWorkers)
+
+		// Stratum proxy
+		r.Get("/stratum-proxy", s.handleGetStratumProxy)
+
+		// Smart plugs
+		r.Get("/plugs", s.handleGetPlugs)
+
+		// Mining schedules
+		r.Get("/schedules", s.handleGetSchedules)
+		r.Post("/schedules", s.handleCreateSchedule)
+		r.Put("/schedules/{id}", s.handleUpdateSchedule)
+		r.Delete("/schedules/{id}", s.handleDeleteSchedule)
+		r.Get("/scheduler", s.handleGetScheduler)
+		r.Get("/scheduler/savings", s.handleGetSchedulerSavings)
+		r.Put("/scheduler/overrides/{ip}", s.handleSetScheduleOverride)
+		r.Delete("/scheduler/overrides/{ip}", s.handleDeleteScheduleOverride)
+		r.Post("/scheduler/signals/{name}", s.handleSetSignal)
+
+		// Share links
+		r.Get("/share-links", s.handleGetShareLinks)
+		r.Post("/share-links", s.handleCreateShareLink)
+		r.Delete("/share-links/{id}", s.handleDeleteShareLink)
 
 		// Network scan
 		r.Post("/scan", s.handleScan)
 
+		// Firmware
+		r.Get("/firmware/consistency", s.handleGetFirmwareConsistency)
+		r.Get("/firmware/releases", s.handleGetFirmwareReleases)
+		r.Post("/firmware/update", s.handleFirmwareUpdate)
+		r.Get("/firmware/updates", s.handleGetFirmwareUpdates)
+		r.Get("/firmware/updates/{id}", s.handleGetFirmwareUpdate)
+
 		// Pricing
 		r.Get("/coins", s.handleGetCoins)
+		r.Get("/prices/history", s.handleGetPriceHistory)
+		r.Get("/network/{coin}", s.handleGetNetwork)
 
 		// Earnings
 		r.Get("/earnings", s.handleGetEarnings)
+		r.Get("/profitability", s.handleGetProfitability)
+
+		// Energy
+		r.Get("/energy", s.handleGetEnergy)
 
 		// Database management
 		r.Get("/dbsize", s.handleGetDBSize)
 		r.Post("/purge", s.handlePurge)
+		r.Get("/retention/status", s.handleGetRetentionStatus)
+		r.Post("/backup", s.handleBackup)
+		r.Post("/restore", s.handleRestore)
+
+		// Audit log
+		r.Get("/audit", s.handleGetAuditLog)
 
-		// WebSocket
+		// WebSocket, and the same events as Server-Sent Events
 		r.Get("/ws", s.handleWebSocket)
+		r.Get("/events", s.handleEvents)
+	})
+
+	// Miner web UIs, proxied; changing settings through them requires an admin
+	r.Group(func(r chi.Router) {
+		r.Use(s.auditLog)
+		r.Use(s.requireRoleForMutations)
+
+		r.Get("/miners/{ip}/ui", s.handleMinerUIRedirect)
+		r.Handle("/miners/{ip}/ui/*", http.HandlerFunc(s.handleMinerUI))
+	})
+
+	// Miner web UIs reached with a ticket, which browsers logged in with a
+	// session cookie are given, since the sandboxed pages don't send it
+	r.Route("/miner-ui/{ip}/{ticket}", func(r chi.Router) {
+		r.Use(s.requireMinerUITicket)
+		r.Use(s.auditLog)
+		r.Use(s.requireRoleForMutations)
+
+		r.Handle("/*", http.HandlerFunc(s.handleMinerUITicket))
 	})
 
 	// Static files
 	r.Get("/*", s.handleStatic)
 
-	// Create HTTP server
-	addr := fmt.Sprintf("%s:%d", s.cfg.Server.Host, s.cfg.Server.Port)
-	s.server = &http.Server{
-		Addr:         addr,
-		Handler:      r,
-		ReadTimeout:  s.cfg.Server.ReadTimeout,
-		WriteTimeout: s.cfg.Server.WriteTimeout,
-	}
-
-	log.Printf("Starting HTTP server on %s", addr)
-	return s.server.ListenAndServe()
+	return r
 }
 
 // Stop stops the HTTP server
@@ -158,29 +449,15 @@ func (s *Server) initWeeklyLeader() {
 	}
 
 	now := time.Now()
-	weekday := int(now.Weekday())
-	weekStart := time.Date(now.Year(), now.Month(), now.Day()-weekday, 0, 0, 0, 0, now.Location())
+	weekStart := s.cfg().Competition.CompetitionPeriod().Start(now)
 
-	miners, err := s.storage.GetMiners()
+	leaderboard, err := s.storage.GetWeeklyLeaderboard(weekStart, now)
 	if err != nil {
-		log.Printf("Failed to load miners for weekly leader init: %v", err)
+		log.Printf("Failed to load leaderboard for weekly leader init: %v", err)
 		return
 	}
 
-	var bestDiff float64
-	var leader string
-	for _, m := range miners {
-		share, err := s.storage.GetBestShareInRange(m.IP, weekStart, now)
-		if err != nil || share == nil {
-			continue
-		}
-		if share.Difficulty > bestDiff {
-			bestDiff = share.Difficulty
-			leader = share.Hostname
-		}
-	}
-
-	s.alerts.InitWeeklyLeader(leader, bestDiff)
+	s.alerts.InitWeeklyLeader(leaderboard)
 }
 
 // forwardEvents forwards collector events to WebSocket hub
@@ -194,13 +471,31 @@ func (s *Server) forwardEvents() {
 				return
 			}
 			s.hub.Broadcast(Message{
-				Type: "share",
-				Data: share,
+				Type:    "share",
+				Data:    share,
+				minerIP: share.MinerIP,
 			})
+			if s.mqtt != nil {
+				s.mqtt.PublishShare(share)
+			}
+			if s.agent != nil {
+				s.agent.AddShare(share)
+			}
+			if s.watchdog != nil {
+				s.watchdog.ObserveShare(share)
+			}
 			if s.alerts != nil {
 				s.alerts.CheckLeaderChange(share)
 			}
 
+		case share, ok := <-s.collector.RejectChan:
+			if !ok {
+				return
+			}
+			if s.alerts != nil {
+				s.alerts.CheckShare(share, true)
+			}
+
 		case snapshot, ok := <-s.collector.SnapshotChan:
 			if !ok {
 				return
@@ -211,9 +506,25 @@ func (s *Server) forwardEvents() {
 			}
 
 			s.hub.Broadcast(Message{
-				Type: "snapshot",
-				Data: snapshot,
+				Type:    "snapshot",
+				Data:    snapshot,
+				minerIP: snapshot.MinerIP,
 			})
+			if s.mqtt != nil {
+				s.mqtt.PublishSnapshot(snapshot)
+			}
+			if s.tsdb != nil {
+				s.tsdb.Add(snapshot)
+			}
+			if s.agent != nil {
+				s.agent.AddSnapshot(snapshot)
+			}
+			if s.watchdog != nil {
+				s.watchdog.Observe(snapshot)
+			}
+			if s.scheduler != nil {
+				s.scheduler.Observe(snapshot)
+			}
 
 		case block, ok := <-s.collector.BlockChan:
 			if !ok {
@@ -221,16 +532,46 @@ func (s *Server) forwardEvents() {
 			}
 			log.Printf("Broadcasting block found event from %s", block.Hostname)
 			s.hub.Broadcast(Message{
-				Type: "block",
-				Data: block,
+				Type:    "block",
+				Data:    block,
+				minerIP: block.MinerIP,
 			})
+			if s.mqtt != nil {
+				s.mqtt.PublishBlock(block)
+			}
+			if s.agent != nil {
+				s.agent.AddBlock(block)
+			}
 			if s.alerts != nil {
 				s.alerts.CheckBlock(block)
 			}
+
+		case line, ok := <-s.collector.LogChan:
+			if !ok {
+				return
+			}
+			s.hub.Broadcast(Message{
+				Type:    "log",
+				Data:    line,
+				minerIP: line.MinerIP,
+			})
 		}
 	}
 }
 
+// forwardAlert forwards a sent alert to WebSocket clients and MQTT. It's
+// called with the alert engine locked, so it must not block.
+func (s *Server) forwardAlert(alert alerts.Alert) {
+	s.hub.Broadcast(Message{
+		Type:    "alert",
+		Data:    alert,
+		minerIP: alert.MinerIP,
+	})
+	if s.mqtt != nil {
+		s.mqtt.PublishAlert(alert)
+	}
+}
+
 // GetHub returns the WebSocket hub for external access
 func (s *Server) GetHub() *WebSocketHub {
 	return s.hub
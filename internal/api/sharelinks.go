@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// sharePrefix is where share links serve their dashboard and API
+const sharePrefix = "/share/"
+
+// ShareLinkRequest is the body of POST /api/share-links
+type ShareLinkRequest struct {
+	Name    string `json:"name"`
+	HideIPs bool   `json:"hideIps"`        // Replace miner IPs with pseudonyms
+	Days    int    `json:"days,omitempty"` // How long the link works, 0 = until revoked
+}
+
+// ShareLinkCreated is a new share link with its token, which is only
+// returned once
+type ShareLinkCreated struct {
+	storage.ShareLink
+	Token string `json:"token"`
+	URL   string `json:"url"` // Path of the shared dashboard
+}
+
+// handleGetShareLinks lists the share links, without their tokens
+// GET /api/share-links
+func (s *Server) handleGetShareLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := s.storage.GetShareLinks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if links == nil {
+		links = []*storage.ShareLink{}
+	}
+	s.jsonResponse(w, links)
+}
+
+// handleCreateShareLink generates a share link. The token is part of the URL
+// and can't be shown again.
+// POST /api/share-links
+func (s *Server) handleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	var req ShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if req.Days < 0 {
+		http.Error(w, "days must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	link := storage.ShareLink{Name: req.Name, TokenHash: auth.HashToken(token), HideIPs: req.HideIPs, Created: time.Now()}
+	if req.Days > 0 {
+		expires := link.Created.AddDate(0, 0, req.Days)
+		link.Expires = &expires
+	}
+	if err := s.storage.InsertShareLink(&link); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, ShareLinkCreated{ShareLink: link, Token: token, URL: sharePrefix + token + "/"})
+}
+
+// handleDeleteShareLink revokes a share link
+// DELETE /api/share-links/{id}
+func (s *Server) handleDeleteShareLink(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.storage.DeleteShareLink(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "share link not found", http.StatusNotFound)
+		return
+	}
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// requireShareLink serves a share link's routes if its token is valid and
+// hasn't expired, hiding miner IPs from the responses if the link says so
+func (s *Server) requireShareLink(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		link, err := s.storage.GetShareLinkByTokenHash(auth.HashToken(chi.URLParam(r, "token")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if link == nil || link.Expired(time.Now()) {
+			http.Error(w, "share link not found", http.StatusNotFound)
+			return
+		}
+		if !link.HideIPs || !strings.Contains(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+		body := buf.body.Bytes()
+		if buf.status == http.StatusOK {
+			if redacted, err := hideIPs(body, link.TokenHash); err == nil {
+				body = redacted
+			} else {
+				log.Printf("Failed to hide IPs from %s: %v", r.URL.Path, err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+		w.WriteHeader(buf.status)
+		w.Write(body)
+	})
+}
+
+// bufferedResponse holds a response back so it can be rewritten
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// ipFields are the JSON fields that hold a miner's IP
+var ipFields = map[string]bool{"ip": true, "minerIp": true}
+
+// hideIPs replaces the miner IPs in a JSON response with pseudonyms, which
+// are stable for a share link but can't be traced back to the IPs. Other
+// fields equal to an IP, such as hostnames that default to it, are replaced
+// too.
+func hideIPs(body []byte, salt string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	ips := make(map[string]bool)
+	collectIPs(v, ips)
+	v = replaceIPs(v, ips, salt)
+
+	var out bytes.Buffer
+	if err := json.NewEncoder(&out).Encode(v); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// collectIPs gathers the values of the IP fields anywhere in v
+func collectIPs(v interface{}, ips map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ip, ok := value.(string); ok && ipFields[key] && ip != "" {
+				ips[ip] = true
+			}
+			collectIPs(value, ips)
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectIPs(value, ips)
+		}
+	}
+}
+
+// replaceIPs replaces every string in ips with its pseudonym
+func replaceIPs(v interface{}, ips map[string]bool, salt string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = replaceIPs(value, ips, salt)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = replaceIPs(value, ips, salt)
+		}
+	case string:
+		if ips[v] {
+			sum := sha256.Sum256([]byte(salt + v))
+			return "miner-" + hex.EncodeToString(sum[:3])
+		}
+	}
+	return v
+}
+
+// handleSharePage serves the shared dashboard
+// GET /share/{token}/
+func (s *Server) handleSharePage(w http.ResponseWriter, r *http.Request) {
+	// The page loads the API relative to its own URL
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	w.Header().Set("Referrer-Policy", "no-referrer") // The token is in the URL
+	s.serveWebFile(w, r, "templates/share.html")
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHideIPs(t *testing.T) {
+	body := `{"competitors":[{"minerIp":"10.0.0.5","hostname":"bitaxe-1","bestDiff":12345678901234,"rank":1},` +
+		`{"minerIp":"10.0.0.6","hostname":"10.0.0.6","rank":2}],"weekStart":"2026-10-11T00:00:00Z","note":"10.0.0.7"}`
+
+	out, err := hideIPs([]byte(body), "salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "10.0.0.5") || strings.Contains(string(out), "10.0.0.6") {
+		t.Fatalf("expected IPs to be hidden, got %s", out)
+	}
+
+	var got struct {
+		Competitors []struct {
+			MinerIP  string      `json:"minerIp"`
+			Hostname string      `json:"hostname"`
+			BestDiff json.Number `json:"bestDiff"`
+		} `json:"competitors"`
+		Note string `json:"note"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	first, second := got.Competitors[0], got.Competitors[1]
+	if !strings.HasPrefix(first.MinerIP, "miner-") || first.MinerIP == second.MinerIP {
+		t.Errorf("expected distinct pseudonyms, got %q and %q", first.MinerIP, second.MinerIP)
+	}
+	if first.Hostname != "bitaxe-1" || first.BestDiff != "12345678901234" {
+		t.Errorf("expected other fields unchanged, got %+v", first)
+	}
+	if second.Hostname != second.MinerIP {
+		t.Errorf("expected a hostname defaulting to the IP to get the same pseudonym, got %q", second.Hostname)
+	}
+	if got.Note != "10.0.0.7" {
+		t.Errorf("expected strings that aren't miner IPs unchanged, got %q", got.Note)
+	}
+
+	// Pseudonyms are stable for a link but differ between links
+	again, _ := hideIPs([]byte(body), "salt")
+	other, _ := hideIPs([]byte(body), "pepper")
+	if string(again) != string(out) || string(other) == string(out) {
+		t.Error("expected pseudonyms to depend only on the link")
+	}
+
+	if _, err := hideIPs([]byte("not json"), "salt"); err == nil {
+		t.Error("expected an error for a non-JSON body")
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RejectionPoint is a miner's share acceptance in one bucket of the trend
+type RejectionPoint struct {
+	Start         time.Time `json:"start"`
+	Accepted      int64     `json:"accepted"`
+	Rejected      int64     `json:"rejected"`
+	RejectionRate float64   `json:"rejectionRate"` // Percent of submitted shares
+}
+
+// HistogramBin counts shares with a difficulty in [Min, Max)
+type HistogramBin struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int64   `json:"count"`
+}
+
+// MinerShareStats is one miner's share production and quality over a period
+type MinerShareStats struct {
+	IP             string           `json:"ip"`
+	Hostname       string           `json:"hostname"`
+	Shares         int64            `json:"shares"` // Logged shares
+	SharesPerHour  float64          `json:"sharesPerHour"`
+	BestDifficulty float64          `json:"bestDifficulty"`
+	AvgDifficulty  float64          `json:"avgDifficulty"`
+	Accepted       int64            `json:"accepted"` // From the miner's counters
+	Rejected       int64            `json:"rejected"`
+	AcceptanceRate *float64         `json:"acceptanceRate"` // Percent, null without submitted shares
+	RejectionTrend []RejectionPoint `json:"rejectionTrend"`
+	Histogram      []HistogramBin   `json:"histogram"`
+}
+
+// histogramBin returns the bin of a difficulty decade
+func histogramBin(decade int) HistogramBin {
+	if decade == 0 {
+		return HistogramBin{Min: 0, Max: 10}
+	}
+	return HistogramBin{Min: math.Pow10(decade), Max: math.Pow10(decade + 1)}
+}
+
+// rejectionRate returns rejected as a percentage of submitted shares
+func rejectionRate(accepted, rejected int64) float64 {
+	if accepted+rejected == 0 {
+		return 0
+	}
+	return float64(rejected) / float64(accepted+rejected) * 100
+}
+
+// handleGetShareStats returns per-miner shares per hour, acceptance rate,
+// rejection rate trend and difficulty histogram, worst acceptance first.
+// The trend is hourly up to 3 days and daily beyond.
+// GET /api/shares/stats
+// Query params: hours (default 24)
+func (s *Server) handleGetShareStats(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	bucket := time.Hour
+	if hours > 72 {
+		bucket = 24 * time.Hour
+	}
+
+	rates, err := s.storage.GetShareRates(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	acceptance, err := s.storage.GetShareAcceptance(since, bucket)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	histogram, err := s.storage.GetDifficultyHistogram(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stats := make(map[string]*MinerShareStats)
+	get := func(ip string) *MinerShareStats {
+		st, ok := stats[ip]
+		if !ok {
+			st = &MinerShareStats{IP: ip, RejectionTrend: []RejectionPoint{}, Histogram: []HistogramBin{}}
+			stats[ip] = st
+		}
+		return st
+	}
+
+	for _, rate := range rates {
+		st := get(rate.MinerIP)
+		st.Hostname = rate.Hostname
+		st.Shares = rate.Shares
+		st.SharesPerHour = float64(rate.Shares) / float64(hours)
+		st.BestDifficulty = rate.BestDifficulty
+		st.AvgDifficulty = rate.AvgDifficulty
+	}
+	for _, b := range acceptance {
+		st := get(b.MinerIP)
+		st.Accepted += b.Accepted
+		st.Rejected += b.Rejected
+		st.RejectionTrend = append(st.RejectionTrend, RejectionPoint{
+			Start:         b.Start,
+			Accepted:      b.Accepted,
+			Rejected:      b.Rejected,
+			RejectionRate: rejectionRate(b.Accepted, b.Rejected),
+		})
+	}
+
+	fleet := make(map[int]int64)
+	for _, b := range histogram {
+		bin := histogramBin(b.Decade)
+		bin.Count = b.Count
+		st := get(b.MinerIP)
+		st.Histogram = append(st.Histogram, bin)
+		fleet[b.Decade] += b.Count
+	}
+
+	for _, m := range miners {
+		if st, ok := stats[m.IP]; ok && m.Hostname != "" {
+			st.Hostname = m.Hostname
+		}
+	}
+
+	result := make([]*MinerShareStats, 0, len(stats))
+	for _, st := range stats {
+		if st.Accepted+st.Rejected > 0 {
+			rate := 100 - rejectionRate(st.Accepted, st.Rejected)
+			st.AcceptanceRate = &rate
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := rejectionRate(result[i].Accepted, result[i].Rejected), rejectionRate(result[j].Accepted, result[j].Rejected)
+		if ri != rj {
+			return ri > rj
+		}
+		return result[i].IP < result[j].IP
+	})
+
+	decades := make([]int, 0, len(fleet))
+	for d := range fleet {
+		decades = append(decades, d)
+	}
+	sort.Ints(decades)
+	fleetHistogram := make([]HistogramBin, 0, len(decades))
+	for _, d := range decades {
+		bin := histogramBin(d)
+		bin.Count = fleet[d]
+		fleetHistogram = append(fleetHistogram, bin)
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"hours":     hours,
+		"bucket":    int64(bucket / time.Second),
+		"miners":    result,
+		"histogram": fleetHistogram,
+	})
+}
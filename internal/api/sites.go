@@ -0,0 +1,130 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/auth"
+	"github.com/camarigor/miner-hq/internal/collector"
+)
+
+const (
+	// maxSiteBatchBytes bounds the body of a site agent's batch
+	maxSiteBatchBytes = 8 << 20
+
+	// siteTimeout is how long a site counts as connected after its agent's
+	// last batch
+	siteTimeout = time.Minute
+)
+
+// SiteSummary is a site's part of the fleet: its miners and their combined
+// hashrate and power
+type SiteSummary struct {
+	Name      string     `json:"name"`               // Empty for this server's own miners
+	Local     bool       `json:"local"`              // This server's own miners
+	Connected bool       `json:"connected"`          // The agent sent a batch in the last minute; always true for local
+	LastSeen  *time.Time `json:"lastSeen,omitempty"` // Last batch from the agent, unset if none since the start
+	Miners    int        `json:"miners"`
+	Online    int        `json:"online"`
+	HashRate  float64    `json:"hashRate"` // GH/s of the online miners
+	Power     float64    `json:"power"`    // W of the online miners
+}
+
+// siteForToken returns the configured site whose token the request bears
+func (s *Server) siteForToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+	hash := []byte(auth.HashToken(strings.TrimSpace(token)))
+	for _, site := range s.cfg().Sites {
+		if subtle.ConstantTimeCompare(hash, []byte(site.TokenHash)) == 1 {
+			return site.Name, true
+		}
+	}
+	return "", false
+}
+
+// handleSiteIngest stores a batch of miners, snapshots, shares and blocks
+// forwarded by a remote site's agent. The agent authenticates with its site's
+// token rather than as a user.
+// POST /api/sites/ingest
+func (s *Server) handleSiteIngest(w http.ResponseWriter, r *http.Request) {
+	site, ok := s.siteForToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="MinerHQ"`)
+		http.Error(w, "unknown site token", http.StatusUnauthorized)
+		return
+	}
+
+	var batch collector.RemoteBatch
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSiteBatchBytes)).Decode(&batch); err != nil {
+		http.Error(w, "invalid batch", http.StatusBadRequest)
+		return
+	}
+	s.collector.Ingest(site, &batch)
+
+	s.jsonResponse(w, map[string]bool{"success": true})
+}
+
+// handleGetSites returns the fleet broken down by site: this server's own
+// miners first, then every configured or reporting remote site by name
+// GET /api/sites
+func (s *Server) handleGetSites(w http.ResponseWriter, r *http.Request) {
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	local := &SiteSummary{Local: true, Connected: true}
+	bySite := map[string]*SiteSummary{}
+	for _, site := range s.cfg().Sites {
+		bySite[site.Name] = &SiteSummary{Name: site.Name}
+	}
+	for _, st := range s.collector.Sites() {
+		summary := bySite[st.Name]
+		if summary == nil {
+			summary = &SiteSummary{Name: st.Name} // Removed from the config since
+			bySite[st.Name] = summary
+		}
+		if !st.LastSeen.IsZero() {
+			lastSeen := st.LastSeen
+			summary.LastSeen = &lastSeen
+			summary.Connected = now.Sub(lastSeen) < siteTimeout
+		}
+	}
+
+	status := s.collector.GetMinerStatus()
+	snapshots := s.latestSnapshots(miners)
+	for _, m := range miners {
+		summary := local
+		if m.Site != "" {
+			if summary = bySite[m.Site]; summary == nil {
+				summary = &SiteSummary{Name: m.Site}
+				bySite[m.Site] = summary
+			}
+		}
+		summary.Miners++
+		if !status[m.IP] {
+			continue
+		}
+		summary.Online++
+		if snap := snapshots[m.IP]; snap != nil {
+			summary.HashRate += snap.HashRate
+			summary.Power += snap.Power
+		}
+	}
+
+	sites := make([]*SiteSummary, 0, len(bySite)+1)
+	for _, summary := range bySite {
+		sites = append(sites, summary)
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	s.jsonResponse(w, append([]*SiteSummary{local}, sites...))
+}
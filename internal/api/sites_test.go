@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+func TestSiteForToken(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sites = []config.SiteConfig{
+		{Name: "cabin", Token: "cabin-0123456789abcdef0123456789ab"},
+		{Name: "barn", Token: "barn-0123456789abcdef0123456789abc"},
+	}
+	if _, err := HashConfigPasswords(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sites[0].Token != "" || cfg.Sites[0].TokenHash == "" {
+		t.Fatal("expected plaintext site token to be replaced with its hash")
+	}
+	s := &Server{settings: config.NewManager("", cfg)}
+
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer barn-0123456789abcdef0123456789abc", "barn", true},
+		{"Bearer cabin-0123456789abcdef0123456789ab", "cabin", true},
+		{"Bearer nope", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/sites/ingest", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		if got, ok := s.siteForToken(req); got != tt.want || ok != tt.ok {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import "net/http"
+
+// handleGetStratumProxy returns every pool the stratum proxy relays to, with
+// the miners connected through it and their shares since MinerHQ started
+// GET /api/stratum-proxy
+func (s *Server) handleGetStratumProxy(w http.ResponseWriter, r *http.Request) {
+	if s.proxy == nil {
+		http.Error(w, "stratum proxy not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	miners, err := s.storage.GetMiners()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	names := make(map[string]string, len(miners))
+	for _, m := range miners {
+		names[m.IP] = m.Name()
+	}
+
+	pools := s.proxy.Status()
+	for _, p := range pools {
+		for i := range p.Miners {
+			p.Miners[i].Hostname = names[p.Miners[i].IP]
+		}
+	}
+	s.jsonResponse(w, pools)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxTagLength bounds tag names, which are shown on miner cards
+const maxTagLength = 64
+
+// normalizeTags trims and validates tag names and drops duplicates. Names
+// matching an existing tag take its spelling, so "garage" joins "Garage".
+func normalizeTags(tags []string, existing []*storage.Tag) ([]string, error) {
+	spelling := make(map[string]string, len(existing))
+	for _, t := range existing {
+		spelling[strings.ToLower(t.Name)] = t.Name
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("tag names can't be empty")
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if name, ok := spelling[key]; ok {
+			tag = name
+		}
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
+
+// tagParam returns the unescaped {tag} URL parameter
+func tagParam(r *http.Request) string {
+	tag := chi.URLParam(r, "tag")
+	if unescaped, err := url.PathUnescape(tag); err == nil {
+		tag = unescaped
+	}
+	return strings.TrimSpace(tag)
+}
+
+// filterByTag returns the miners with the ?tag= query parameter's tag, or all
+// miners without one
+func (s *Server) filterByTag(r *http.Request, miners []*storage.Miner) ([]*storage.Miner, error) {
+	tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+	if tag == "" {
+		return miners, nil
+	}
+
+	tags, err := s.storage.GetMinerTags()
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]*storage.Miner, 0, len(miners))
+	for _, m := range miners {
+		for _, t := range tags[m.IP] {
+			if strings.EqualFold(t, tag) {
+				filtered = append(filtered, m)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// handleGetTags returns every tag with the miners that have it
+// GET /api/tags
+func (s *Server) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.storage.GetTags()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []*storage.Tag{}
+	}
+	s.jsonResponse(w, tags)
+}
+
+// handleRenameTag renames a tag on every miner, merging it into an existing
+// tag of the new name
+// PUT /api/tags/{tag}
+func (s *Server) handleRenameTag(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.storage.GetTags()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	oldName := tagParam(r)
+	names, err := normalizeTags([]string{req.Name}, existing)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newName := names[0]
+	if strings.EqualFold(newName, oldName) {
+		// Changing the case of a tag's name, not merging it into itself
+		newName = strings.TrimSpace(req.Name)
+	}
+
+	renamed, err := s.storage.RenameTag(oldName, newName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if renamed == 0 {
+		http.Error(w, "tag not found", http.StatusNotFound)
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{"success": true, "name": newName, "miners": renamed})
+}
+
+// handleDeleteTag removes a tag from every miner
+// DELETE /api/tags/{tag}
+func (s *Server) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	deleted, err := s.storage.DeleteTag(tagParam(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "tag not found", http.StatusNotFound)
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{"success": true, "miners": deleted})
+}
+
+// handleGetMinerTags returns a miner's tags
+// GET /api/miners/{ip}/tags
+func (s *Server) handleGetMinerTags(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	tags, err := s.storage.GetMinerTags()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	minerTags := tags[ip]
+	if minerTags == nil {
+		minerTags = []string{}
+	}
+	s.jsonResponse(w, map[string]interface{}{"ip": ip, "tags": minerTags})
+}
+
+// handleSetMinerTags replaces a miner's tags
+// PUT /api/miners/{ip}/tags
+func (s *Server) handleSetMinerTags(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+
+	existing, err := s.storage.GetTags()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tags, err := normalizeTags(req.Tags, existing)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.SetMinerTags(ip, tags); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{"success": true, "ip": ip, "tags": tags})
+}
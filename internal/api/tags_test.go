@@ -0,0 +1,28 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	existing := []*storage.Tag{{Name: "Garage"}}
+
+	got, err := normalizeTags([]string{" garage ", "Rack 1", "rack 1"}, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Garage", "Rack 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeTags = %v, want %v", got, want)
+	}
+
+	if _, err := normalizeTags([]string{"  "}, nil); err == nil {
+		t.Error("expected an empty tag to be rejected")
+	}
+	if _, err := normalizeTags([]string{strings.Repeat("x", maxTagLength+1)}, nil); err == nil {
+		t.Error("expected a long tag to be rejected")
+	}
+}
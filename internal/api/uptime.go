@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// UptimeIncident is a period a miner was offline
+type UptimeIncident struct {
+	Start        time.Time  `json:"start"`
+	End          *time.Time `json:"end,omitempty"` // Unset while the miner is still offline
+	DurationSecs int64      `json:"durationSecs"`
+}
+
+// UptimeReport summarizes a miner's availability over a range of days
+type UptimeReport struct {
+	MinerIP      string           `json:"minerIp"`
+	Days         int              `json:"days"`
+	TrackedSince time.Time        `json:"trackedSince"` // Start of the range, or of tracking if later
+	Online       bool             `json:"online"`
+	Availability float64          `json:"availability"` // Percent of the tracked time online
+	UptimeSecs   int64            `json:"uptimeSecs"`
+	DowntimeSecs int64            `json:"downtimeSecs"`
+	Failures     int              `json:"failures"`           // Times the miner went offline within the range
+	MTBFSecs     int64            `json:"mtbfSecs,omitempty"` // Mean time between failures, unset without failures
+	MTTRSecs     int64            `json:"mttrSecs,omitempty"` // Mean time to recover, unset until an incident ended
+	Incidents    []UptimeIncident `json:"incidents"`          // Oldest first
+}
+
+// handleGetMinerUptime returns how reliably a miner stayed online
+// GET /api/miners/{ip}/uptime
+// Query params: days (default 30)
+func (s *Server) handleGetMinerUptime(w http.ResponseWriter, r *http.Request) {
+	ip := chi.URLParam(r, "ip")
+	if _, ok := s.collector.GetMinerStatus()[ip]; !ok {
+		http.Error(w, "miner not found", http.StatusNotFound)
+		return
+	}
+
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -days)
+	events, err := s.storage.GetUptimeEvents(ip, from)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report := uptimeReport(events, from, now)
+	report.MinerIP = ip
+	report.Days = days
+	s.jsonResponse(w, report)
+}
+
+// uptimeReport adds up a miner's uptime events from the range start to end.
+// The first event may precede the range and gives the status at its start;
+// otherwise the range is only tracked from the first event.
+func uptimeReport(events []*storage.UptimeEvent, start, end time.Time) UptimeReport {
+	report := UptimeReport{TrackedSince: end, Incidents: []UptimeIncident{}}
+	if len(events) == 0 {
+		return report
+	}
+
+	at := events[0].Timestamp
+	if at.Before(start) {
+		at = start
+	}
+	report.TrackedSince = at
+	online := events[0].Online
+	var offlineSince time.Time
+	if !online {
+		offlineSince = at
+		if !events[0].Timestamp.Before(start) {
+			report.Failures++
+		}
+	}
+
+	var uptime, downtime, recovery time.Duration
+	var recovered int
+	advance := func(to time.Time) {
+		if online {
+			uptime += to.Sub(at)
+		} else {
+			downtime += to.Sub(at)
+		}
+		at = to
+	}
+	for _, e := range events[1:] {
+		if e.Online == online || e.Timestamp.Before(at) {
+			continue
+		}
+		advance(e.Timestamp)
+		online = e.Online
+		if !online {
+			offlineSince = at
+			report.Failures++
+			continue
+		}
+		ended := at
+		report.Incidents = append(report.Incidents, UptimeIncident{
+			Start:        offlineSince,
+			End:          &ended,
+			DurationSecs: int64(ended.Sub(offlineSince).Seconds()),
+		})
+		recovery += ended.Sub(offlineSince)
+		recovered++
+	}
+	advance(end)
+	if !online {
+		report.Incidents = append(report.Incidents, UptimeIncident{
+			Start:        offlineSince,
+			DurationSecs: int64(end.Sub(offlineSince).Seconds()),
+		})
+	}
+
+	report.Online = online
+	report.UptimeSecs = int64(uptime.Seconds())
+	report.DowntimeSecs = int64(downtime.Seconds())
+	if tracked := uptime + downtime; tracked > 0 {
+		report.Availability = float64(uptime) / float64(tracked) * 100
+	}
+	if report.Failures > 0 {
+		report.MTBFSecs = int64(uptime.Seconds()) / int64(report.Failures)
+	}
+	if recovered > 0 {
+		report.MTTRSecs = int64(recovery.Seconds()) / int64(recovered)
+	}
+	return report
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestUptimeReport(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(100 * time.Hour)
+	event := func(offset time.Duration, online bool) *storage.UptimeEvent {
+		return &storage.UptimeEvent{MinerIP: "10.0.0.1", Timestamp: start.Add(offset), Online: online}
+	}
+
+	t.Run("no events", func(t *testing.T) {
+		report := uptimeReport(nil, start, end)
+		if report.Availability != 0 || report.UptimeSecs != 0 || len(report.Incidents) != 0 {
+			t.Errorf("expected nothing tracked, got %+v", report)
+		}
+	})
+
+	t.Run("online before the range", func(t *testing.T) {
+		report := uptimeReport([]*storage.UptimeEvent{
+			event(-time.Hour, true),
+			event(10*time.Hour, false),
+			event(12*time.Hour, true),
+			event(50*time.Hour, false),
+			event(58*time.Hour, true),
+			event(70*time.Hour, true), // Repeated status changes nothing
+		}, start, end)
+
+		if !report.TrackedSince.Equal(start) || !report.Online {
+			t.Errorf("expected the whole range tracked and the miner online, got %+v", report)
+		}
+		if report.DowntimeSecs != 10*3600 || report.Availability != 90 {
+			t.Errorf("expected 10h down and 90%% availability, got %ds and %.2f%%", report.DowntimeSecs, report.Availability)
+		}
+		if report.Failures != 2 || report.MTBFSecs != 45*3600 || report.MTTRSecs != 5*3600 {
+			t.Errorf("expected 2 failures, 45h MTBF and 5h MTTR, got %d, %ds, %ds", report.Failures, report.MTBFSecs, report.MTTRSecs)
+		}
+		if len(report.Incidents) != 2 || report.Incidents[1].DurationSecs != 8*3600 || report.Incidents[1].End == nil {
+			t.Errorf("expected 2 ended incidents, got %+v", report.Incidents)
+		}
+	})
+
+	t.Run("tracked from the first event and still offline", func(t *testing.T) {
+		report := uptimeReport([]*storage.UptimeEvent{
+			event(20*time.Hour, true),
+			event(80*time.Hour, false),
+		}, start, end)
+
+		if !report.TrackedSince.Equal(start.Add(20*time.Hour)) || report.Online {
+			t.Errorf("expected tracking from the first event and the miner offline, got %+v", report)
+		}
+		if report.Availability != 75 || report.MTTRSecs != 0 {
+			t.Errorf("expected 75%% availability and no MTTR yet, got %.2f%% and %ds", report.Availability, report.MTTRSecs)
+		}
+		if len(report.Incidents) != 1 || report.Incidents[0].End != nil || report.Incidents[0].DurationSecs != 20*3600 {
+			t.Errorf("expected one ongoing incident, got %+v", report.Incidents)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/watchdog"
+)
+
+// WatchdogResponse reports the watchdog's settings and what it knows of each
+// miner
+type WatchdogResponse struct {
+	Enabled        bool              `json:"enabled"`
+	StuckMinutes   int               `json:"stuckMinutes"`
+	BackoffMinutes int               `json:"backoffMinutes"`
+	MaxRestarts    int               `json:"maxRestarts"`
+	Miners         []watchdog.Status `json:"miners"`
+}
+
+// handleGetWatchdog returns every miner's watchdog state: whether it counts
+// as stuck, its restarts and the end of its backoff
+// GET /api/watchdog
+func (s *Server) handleGetWatchdog(w http.ResponseWriter, r *http.Request) {
+	if s.watchdog == nil {
+		http.Error(w, "watchdog not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg := s.cfg().Watchdog
+	s.jsonResponse(w, WatchdogResponse{
+		Enabled:        cfg.Enabled,
+		StuckMinutes:   cfg.StuckMinutes,
+		BackoffMinutes: cfg.BackoffMinutes,
+		MaxRestarts:    cfg.MaxRestarts,
+		Miners:         s.watchdog.Status(time.Now()),
+	})
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/websocket"
@@ -12,18 +16,126 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
+// Event types clients can subscribe to
+var eventTypes = map[string]bool{"share": true, "snapshot": true, "block": true, "alert": true, "log": true}
+
+// optInEventTypes are only sent to clients that subscribe to them by name, as
+// every log line of every miner would swamp clients that want everything
+var optInEventTypes = map[string]bool{"log": true}
+
 // Message represents a WebSocket message
 type Message struct {
-	Type string      `json:"type"` // "share" or "snapshot"
+	Type string      `json:"type"` // "share", "snapshot", "block", "alert" or "log"
 	Data interface{} `json:"data"`
+
+	minerIP string // Miner the event is about, for subscription filters
+}
+
+// subscription selects the messages a client receives. Empty sets match
+// everything.
+type subscription struct {
+	Types  []string `json:"types"`
+	Miners []string `json:"miners"`
+
+	types  map[string]bool
+	miners map[string]bool
+}
+
+// newSubscription validates event types and builds the lookup sets
+func newSubscription(types, miners []string) (*subscription, error) {
+	sub := &subscription{
+		Types:  []string{},
+		Miners: []string{},
+		types:  make(map[string]bool),
+		miners: make(map[string]bool),
+	}
+	for _, t := range types {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if !eventTypes[t] {
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+		sub.types[t] = true
+	}
+	for _, ip := range miners {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			sub.miners[ip] = true
+		}
+	}
+	for t := range sub.types {
+		sub.Types = append(sub.Types, t)
+	}
+	for ip := range sub.miners {
+		sub.Miners = append(sub.Miners, ip)
+	}
+	sort.Strings(sub.Types)
+	sort.Strings(sub.Miners)
+	return sub, nil
+}
+
+// matches reports whether a message passes the filter. Messages that aren't
+// about a single miner, such as fleet-wide alerts, pass any miner filter.
+func (s *subscription) matches(msg Message) bool {
+	if optInEventTypes[msg.Type] && !s.types[msg.Type] {
+		return false
+	}
+	if len(s.types) > 0 && !s.types[msg.Type] {
+		return false
+	}
+	if len(s.miners) > 0 && msg.minerIP != "" && !s.miners[msg.minerIP] {
+		return false
+	}
+	return true
+}
+
+// subscribeRequest is sent by clients to change their subscription
+type subscribeRequest struct {
+	Action string   `json:"action"` // "subscribe"
+	Types  []string `json:"types"`
+	Miners []string `json:"miners"`
+}
+
+// wsClient is a connected client and its subscription
+type wsClient struct {
+	conn *websocket.Conn
+
+	mu  sync.Mutex // Serializes writes and guards sub
+	sub *subscription
+}
+
+// send writes msg if it matches the client's subscription
+func (c *wsClient) send(msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.sub.matches(msg) {
+		return nil
+	}
+	return c.conn.WriteJSON(msg)
+}
+
+// reply writes a message regardless of the subscription
+func (c *wsClient) reply(msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(msg)
 }
 
-// WebSocketHub manages WebSocket connections and broadcasts
+// subscribe replaces the client's subscription
+func (c *wsClient) subscribe(sub *subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sub = sub
+}
+
+// WebSocketHub manages WebSocket connections and event streams, and
+// broadcasts to both
 type WebSocketHub struct {
-	clients    map[*websocket.Conn]bool
+	clients    map[*websocket.Conn]*wsClient
+	streams    map[*sseClient]bool // Clients of /api/events, guarded by clientsMu
 	clientsMu  sync.RWMutex
 	broadcast  chan Message
-	register   chan *websocket.Conn
+	register   chan *wsClient
 	unregister chan *websocket.Conn
 	done       chan struct{}
 }
@@ -31,9 +143,10 @@ type WebSocketHub struct {
 // NewWebSocketHub creates a new WebSocketHub
 func NewWebSocketHub() *WebSocketHub {
 	return &WebSocketHub{
-		clients:    make(map[*websocket.Conn]bool),
+		clients:    make(map[*websocket.Conn]*wsClient),
+		streams:    make(map[*sseClient]bool),
 		broadcast:  make(chan Message, 256),
-		register:   make(chan *websocket.Conn),
+		register:   make(chan *wsClient),
 		unregister: make(chan *websocket.Conn),
 		done:       make(chan struct{}),
 	}
@@ -53,9 +166,9 @@ func (h *WebSocketHub) Run() {
 			h.clientsMu.Unlock()
 			return
 
-		case conn := <-h.register:
+		case client := <-h.register:
 			h.clientsMu.Lock()
-			h.clients[conn] = true
+			h.clients[client.conn] = client
 			h.clientsMu.Unlock()
 			log.Printf("WebSocket client connected, total clients: %d", len(h.clients))
 
@@ -70,8 +183,8 @@ func (h *WebSocketHub) Run() {
 
 		case msg := <-h.broadcast:
 			h.clientsMu.RLock()
-			for conn := range h.clients {
-				err := conn.WriteJSON(msg)
+			for conn, client := range h.clients {
+				err := client.send(msg)
 				if err != nil {
 					log.Printf("WebSocket write error: %v", err)
 					// Queue for unregister
@@ -80,16 +193,51 @@ func (h *WebSocketHub) Run() {
 					}(conn)
 				}
 			}
+			for stream := range h.streams {
+				stream.send(msg)
+			}
 			h.clientsMu.RUnlock()
 		}
 	}
 }
 
+// addStream starts broadcasting to an event stream client
+func (h *WebSocketHub) addStream(c *sseClient) {
+	h.clientsMu.Lock()
+	h.streams[c] = true
+	n := len(h.streams)
+	h.clientsMu.Unlock()
+	log.Printf("Event stream client connected, total streams: %d", n)
+}
+
+// removeStream stops broadcasting to an event stream client
+func (h *WebSocketHub) removeStream(c *sseClient) {
+	h.clientsMu.Lock()
+	delete(h.streams, c)
+	n := len(h.streams)
+	h.clientsMu.Unlock()
+	log.Printf("Event stream client disconnected, total streams: %d", n)
+}
+
+// streamCount returns how many event stream clients are connected
+func (h *WebSocketHub) streamCount() int {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+	return len(h.streams)
+}
+
 // Stop stops the hub
 func (h *WebSocketHub) Stop() {
 	close(h.done)
 }
 
+// ClientCount returns how many clients are connected
+func (h *WebSocketHub) ClientCount() int {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+	return len(h.clients)
+}
+
 // Broadcast sends a message to all connected clients
 func (h *WebSocketHub) Broadcast(msg Message) {
 	select {
@@ -100,27 +248,58 @@ func (h *WebSocketHub) Broadcast(msg Message) {
 	}
 }
 
-// handleWebSocket handles WebSocket upgrade and connection
+// splitList splits a comma-separated query parameter
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// handleWebSocket handles WebSocket upgrade and connection. Clients receive
+// every event unless they subscribe to some, either when connecting
+// (?types=block,alert&miners=192.168.1.50) or at any time by sending
+// {"action": "subscribe", "types": [...], "miners": [...]}.
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub, err := newSubscription(splitList(r.URL.Query().Get("types")), splitList(r.URL.Query().Get("miners")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
-	s.hub.register <- conn
+	client := &wsClient{conn: conn, sub: sub}
+	s.hub.register <- client
 
-	// Read loop to detect client disconnect
+	// Read loop for subscriptions and to detect client disconnect
 	go func() {
 		defer func() {
 			s.hub.unregister <- conn
 		}()
 
 		for {
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				return
 			}
+
+			var req subscribeRequest
+			if err := json.Unmarshal(data, &req); err != nil || req.Action != "subscribe" {
+				client.reply(Message{Type: "error", Data: "expected {\"action\": \"subscribe\", \"types\": [...], \"miners\": [...]}"})
+				continue
+			}
+			sub, err := newSubscription(req.Types, req.Miners)
+			if err != nil {
+				client.reply(Message{Type: "error", Data: err.Error()})
+				continue
+			}
+			client.subscribe(sub)
+			client.reply(Message{Type: "subscribed", Data: sub})
 		}
 	}()
 }
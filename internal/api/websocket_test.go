@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscriptionMatches(t *testing.T) {
+	sub, err := newSubscription([]string{"block", " alert"}, []string{"192.168.1.50"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		msg  Message
+		want bool
+	}{
+		{Message{Type: "block", minerIP: "192.168.1.50"}, true},
+		{Message{Type: "block", minerIP: "192.168.1.51"}, false},
+		{Message{Type: "share", minerIP: "192.168.1.50"}, false},
+		{Message{Type: "alert"}, true}, // Fleet-wide alerts pass the miner filter
+		{Message{Type: "log", minerIP: "192.168.1.50"}, false},
+	}
+	for _, tt := range tests {
+		if got := sub.matches(tt.msg); got != tt.want {
+			t.Errorf("matches(%s from %q) = %v, want %v", tt.msg.Type, tt.msg.minerIP, got, tt.want)
+		}
+	}
+
+	everything, _ := newSubscription(nil, nil)
+	if everything.matches(Message{Type: "log", minerIP: "192.168.1.50"}) {
+		t.Error("expected log lines to be sent only to clients subscribed to them")
+	}
+	logs, _ := newSubscription([]string{"log"}, []string{"192.168.1.50"})
+	if !logs.matches(Message{Type: "log", minerIP: "192.168.1.50"}) {
+		t.Error("expected log lines to be sent to clients subscribed to them")
+	}
+
+	if _, err := newSubscription([]string{"stats"}, nil); err == nil {
+		t.Error("expected an unknown event type to be rejected")
+	}
+}
+
+func TestWebSocketSubscribe(t *testing.T) {
+	s := &Server{hub: NewWebSocketHub()}
+	go s.hub.Run()
+	defer s.hub.Stop()
+
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?types=block", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if err := conn.WriteJSON(subscribeRequest{Action: "subscribe", Types: []string{"share"}, Miners: []string{"192.168.1.50"}}); err != nil {
+		t.Fatal(err)
+	}
+	var reply struct {
+		Type string       `json:"type"`
+		Data subscription `json:"data"`
+	}
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Type != "subscribed" || len(reply.Data.Types) != 1 || reply.Data.Types[0] != "share" {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+
+	s.hub.Broadcast(Message{Type: "block", Data: "block", minerIP: "192.168.1.50"})
+	s.hub.Broadcast(Message{Type: "share", Data: "other miner", minerIP: "192.168.1.51"})
+	s.hub.Broadcast(Message{Type: "share", Data: "subscribed", minerIP: "192.168.1.50"})
+
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "share" || msg.Data != "subscribed" {
+		t.Errorf("expected only the subscribed miner's share, got %+v", msg)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	s := &Server{hub: NewWebSocketHub()}
+	go s.hub.Run()
+	defer s.hub.Stop()
+
+	srv := httptest.NewServer(http.HandlerFunc(s.handleEvents))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?types=block&miners=192.168.1.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	// Wait for the stream to be registered
+	for i := 0; s.hub.streamCount() == 0; i++ {
+		if i > 100 {
+			t.Fatal("stream not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.hub.Broadcast(Message{Type: "share", Data: "not subscribed", minerIP: "192.168.1.50"})
+	s.hub.Broadcast(Message{Type: "block", Data: "other miner", minerIP: "192.168.1.51"})
+	s.hub.Broadcast(Message{Type: "block", Data: map[string]int{"height": 1}, minerIP: "192.168.1.50"})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 4 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	want := []string{"retry: 5000", "", "event: block", `data: {"height":1}`}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("stream = %q, want %q", lines, want)
+		}
+	}
+}
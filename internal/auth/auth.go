@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role determines what an authenticated user is allowed to do
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"  // Full access: settings, purge, miner management and control
+	RoleViewer Role = "viewer" // Read-only access to dashboards, competitions and history
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	return r == RoleAdmin || r == RoleViewer
+}
+
+// CanMutate reports whether the role may perform state-changing requests
+func (r Role) CanMutate() bool {
+	return r == RoleAdmin
+}
+
+// User is an authenticated MinerHQ account
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Role         Role   `json:"role"`
+}
+
+// HashPassword returns a bcrypt hash suitable for storing in the config file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Token is an API token for scripts and integrations. Only the hash of the
+// token is kept.
+type Token struct {
+	Name string
+	Hash string // See HashToken
+	Role Role
+}
+
+// HashToken returns the SHA-256 hash of an API token, hex encoded. Tokens are
+// random, so unlike passwords they don't need a slow hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticator verifies credentials against the configured users and API
+// tokens, and keeps track of login sessions
+type Authenticator struct {
+	users    map[string]*User
+	tokens   []*Token
+	sessions map[string]*session
+	mu       sync.RWMutex
+}
+
+// NewAuthenticator creates an authenticator for the given users
+func NewAuthenticator(users []*User) *Authenticator {
+	a := &Authenticator{sessions: make(map[string]*session)}
+	a.SetUsers(users)
+	return a
+}
+
+// SetTokens replaces the set of API tokens
+func (a *Authenticator) SetTokens(tokens []*Token) {
+	a.mu.Lock()
+	a.tokens = tokens
+	a.mu.Unlock()
+}
+
+// AuthenticateToken returns a user acting for the API token if it matches one
+// of the configured tokens. The user is named "token:<name>".
+func (a *Authenticator) AuthenticateToken(token string) (*User, bool) {
+	hash := []byte(HashToken(token))
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			return &User{Username: "token:" + t.Name, Role: t.Role}, true
+		}
+	}
+	return nil, false
+}
+
+// SetUsers replaces the set of known users. Sessions of users that were
+// removed or whose password changed are ended, scoped ones included, so a
+// new password logs out every browser that knew the old one.
+func (a *Authenticator) SetUsers(users []*User) {
+	byName := make(map[string]*User, len(users))
+	for _, u := range users {
+		byName[u.Username] = u
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for token, s := range a.sessions {
+		old, cur := a.users[s.username], byName[s.username]
+		if old == nil || cur == nil || cur.PasswordHash != old.PasswordHash {
+			delete(a.sessions, token)
+		}
+	}
+	a.users = byName
+}
+
+// Authenticate returns the user if the username and password match
+func (a *Authenticator) Authenticate(username, password string) (*User, bool) {
+	a.mu.RLock()
+	u, ok := a.users[username]
+	a.mu.RUnlock()
+
+	if !ok {
+		// Compare against a dummy hash so unknown users take as long as known ones
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+// dummyHash is compared against when the username doesn't exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("minerhq"), bcrypt.DefaultCost)
+
+type contextKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// UserFromContext returns the authenticated user, or nil when auth is disabled
+func UserFromContext(ctx context.Context) *User {
+	u, _ := ctx.Value(contextKey{}).(*User)
+	return u
+}
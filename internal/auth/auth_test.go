@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAuthenticator(t *testing.T) {
+	adminHash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	viewerHash, _ := HashPassword("view")
+
+	a := NewAuthenticator([]*User{
+		{Username: "admin", PasswordHash: adminHash, Role: RoleAdmin},
+		{Username: "kid", PasswordHash: viewerHash, Role: RoleViewer},
+	})
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantOK   bool
+		wantRole Role
+	}{
+		{name: "admin valid", username: "admin", password: "secret", wantOK: true, wantRole: RoleAdmin},
+		{name: "viewer valid", username: "kid", password: "view", wantOK: true, wantRole: RoleViewer},
+		{name: "wrong password", username: "admin", password: "nope", wantOK: false},
+		{name: "unknown user", username: "ghost", password: "secret", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, ok := a.Authenticate(tt.username, tt.password)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if ok && user.Role != tt.wantRole {
+				t.Errorf("expected role %s, got %s", tt.wantRole, user.Role)
+			}
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	if !RoleAdmin.CanMutate() {
+		t.Error("admin should be able to mutate")
+	}
+	if RoleViewer.CanMutate() {
+		t.Error("viewer should not be able to mutate")
+	}
+	if Role("root").Valid() {
+		t.Error("unknown role should not be valid")
+	}
+}
+
+func TestUserContext(t *testing.T) {
+	if UserFromContext(context.Background()) != nil {
+		t.Error("expected no user in empty context")
+	}
+
+	u := &User{Username: "admin", Role: RoleAdmin}
+	ctx := WithUser(context.Background(), u)
+	if got := UserFromContext(ctx); got != u {
+		t.Errorf("expected user from context, got %v", got)
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a := NewAuthenticator(nil)
+	a.SetTokens([]*Token{{Name: "grafana", Hash: HashToken("0123456789abcdef0123456789abcdef"), Role: RoleViewer}})
+
+	user, ok := a.AuthenticateToken("0123456789abcdef0123456789abcdef")
+	if !ok {
+		t.Fatal("expected token to authenticate")
+	}
+	if user.Username != "token:grafana" || user.Role != RoleViewer {
+		t.Errorf("expected token:grafana as viewer, got %s as %s", user.Username, user.Role)
+	}
+	if _, ok := a.AuthenticateToken("wrong"); ok {
+		t.Error("expected unknown token to be rejected")
+	}
+}
+
+func TestSessions(t *testing.T) {
+	admin := &User{Username: "admin", Role: RoleAdmin}
+	a := NewAuthenticator([]*User{admin})
+
+	token, _, err := a.NewSession(admin, time.Hour)
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if got, ok := a.SessionUser(token); !ok || got != admin {
+		t.Fatalf("expected session to belong to admin, got %v", got)
+	}
+
+	expired, _, _ := a.NewSession(admin, -time.Minute)
+	if _, ok := a.SessionUser(expired); ok {
+		t.Error("expected expired session to be rejected")
+	}
+
+	a.EndSession(token)
+	if _, ok := a.SessionUser(token); ok {
+		t.Error("expected ended session to be rejected")
+	}
+
+	// Removing the user ends their sessions, even if they are added back
+	token, _, _ = a.NewSession(admin, time.Hour)
+	a.SetUsers(nil)
+	if _, ok := a.SessionUser(token); ok {
+		t.Error("expected session of removed user to be rejected")
+	}
+	a.SetUsers([]*User{admin})
+	if _, ok := a.SessionUser(token); ok {
+		t.Error("expected session of re-added user to stay ended")
+	}
+
+	// A scoped session only works for its scope
+	scoped, _, err := a.NewScopedSession(admin, "miner-ui:10.0.0.2", time.Hour)
+	if err != nil {
+		t.Fatalf("NewScopedSession failed: %v", err)
+	}
+	if got, ok := a.ScopedSessionUser(scoped, "miner-ui:10.0.0.2"); !ok || got != admin {
+		t.Errorf("expected scoped session to belong to admin, got %v", got)
+	}
+	if _, ok := a.ScopedSessionUser(scoped, "miner-ui:10.0.0.3"); ok {
+		t.Error("expected scoped session to be rejected for another scope")
+	}
+	if _, ok := a.SessionUser(scoped); ok {
+		t.Error("expected scoped session to be rejected as a login session")
+	}
+}
+
+func TestPasswordChangeEndsSessions(t *testing.T) {
+	admin := &User{Username: "admin", PasswordHash: "old", Role: RoleAdmin}
+	kid := &User{Username: "kid", PasswordHash: "kid", Role: RoleViewer}
+	a := NewAuthenticator([]*User{admin, kid})
+
+	login, _, _ := a.NewSession(admin, time.Hour)
+	ticket, _, _ := a.NewScopedSession(admin, "miner-ui:10.0.0.2", time.Hour)
+	other, _, _ := a.NewSession(kid, time.Hour)
+
+	// Reloading unchanged users, e.g. after another setting is saved, keeps
+	// everyone logged in; a role change applies to the live session
+	viewer := &User{Username: "admin", PasswordHash: "old", Role: RoleViewer}
+	a.SetUsers([]*User{viewer, kid})
+	if got, ok := a.SessionUser(login); !ok || got != viewer {
+		t.Fatalf("expected the session kept with the new role, got %v", got)
+	}
+
+	a.SetUsers([]*User{{Username: "admin", PasswordHash: "new", Role: RoleAdmin}, kid})
+	if _, ok := a.SessionUser(login); ok {
+		t.Error("expected the session to end with the password change")
+	}
+	if _, ok := a.ScopedSessionUser(ticket, "miner-ui:10.0.0.2"); ok {
+		t.Error("expected the scoped session to end with the password change")
+	}
+	if got, ok := a.SessionUser(other); !ok || got != kid {
+		t.Errorf("expected other users to stay logged in, got %v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// session is a logged-in browser, identified by the token in its cookie, or
+// a scoped session, which only grants what its scope names
+type session struct {
+	username string
+	scope    string // Empty for a login session
+	expires  time.Time
+}
+
+// newToken returns a random 256-bit token, hex encoded
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewSession starts a session for the user lasting ttl and returns its token.
+// Sessions live in memory, so a restart logs everyone out.
+func (a *Authenticator) NewSession(u *User, ttl time.Duration) (string, time.Time, error) {
+	return a.newSession(u, "", ttl)
+}
+
+// NewScopedSession starts a session for the user that only ScopedSessionUser
+// accepts, for the given scope, e.g. one miner's proxied web UI. Its token
+// can be handed to code MinerHQ doesn't trust with a login session.
+func (a *Authenticator) NewScopedSession(u *User, scope string, ttl time.Duration) (string, time.Time, error) {
+	return a.newSession(u, scope, ttl)
+}
+
+func (a *Authenticator) newSession(u *User, scope string, ttl time.Duration) (string, time.Time, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expires := now.Add(ttl)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for t, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, t)
+		}
+	}
+	a.sessions[token] = &session{username: u.Username, scope: scope, expires: expires}
+	return token, expires, nil
+}
+
+// SessionUser returns the user a login session token belongs to. Sessions of
+// users that have since been removed are rejected, and role changes apply at
+// once. Scoped sessions are rejected.
+func (a *Authenticator) SessionUser(token string) (*User, bool) {
+	return a.ScopedSessionUser(token, "")
+}
+
+// ScopedSessionUser returns the user a session token of the given scope
+// belongs to
+func (a *Authenticator) ScopedSessionUser(token, scope string) (*User, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	s, ok := a.sessions[token]
+	if !ok || s.scope != scope || time.Now().After(s.expires) {
+		return nil, false
+	}
+	u, ok := a.users[s.username]
+	return u, ok
+}
+
+// EndSession logs a session out
+func (a *Authenticator) EndSession(token string) {
+	a.mu.Lock()
+	delete(a.sessions, token)
+	a.mu.Unlock()
+}
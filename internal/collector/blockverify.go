@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/pricing"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const (
+	// blockSearchRange is how many heights on either side of the expected
+	// one are searched for a found block. The miner's reported height can
+	// lag its job by a block or two, and the chain tip used when the miner
+	// doesn't report one may be cached.
+	blockSearchRange = 3
+
+	// hashDifficultyTolerance is the relative difference allowed between
+	// the difficulty a miner logged and the one its block's hash meets,
+	// as the log rounds it
+	hashDifficultyTolerance = 0.001
+)
+
+// blockExplorer looks up blocks on chain
+type blockExplorer interface {
+	GetNetworkInfo(coinID string) (pricing.NetworkInfo, bool)
+	BlockHashAt(coinID string, height int64) (string, error)
+}
+
+// markForVerification sets the height a found block is expected at and
+// queues it for on-chain verification when its coin has a block explorer
+func (c *Collector) markForVerification(ip string, block *storage.Block) {
+	if c.pricing == nil || !pricing.HasExplorer(block.CoinID) {
+		return
+	}
+
+	c.minersMu.RLock()
+	if conn, exists := c.miners[ip]; exists {
+		block.Height = conn.blockHeight
+	}
+	c.minersMu.RUnlock()
+
+	if block.Height == 0 {
+		// The miner doesn't report its job's height, so the block
+		// extends the chain tip
+		if info, ok := c.pricing.GetNetworkInfo(block.CoinID); ok && info.BlockHeight > 0 {
+			block.Height = info.BlockHeight + 1
+		}
+	}
+	if block.Height > 0 {
+		block.Status = storage.BlockPending
+	}
+}
+
+// StartBlockVerifier starts a background goroutine that looks up pending
+// blocks on their coin's block explorer periodically
+func (c *Collector) StartBlockVerifier(interval time.Duration) {
+	if c.pricing == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			c.verifyPendingBlocks()
+			<-ticker.C
+		}
+	}()
+}
+
+// verifyPendingBlocks records which pending blocks made it into the chain
+func (c *Collector) verifyPendingBlocks() {
+	blocks, err := c.storage.GetPendingBlocks()
+	if err != nil {
+		log.Printf("GetPendingBlocks failed: %v", err)
+		return
+	}
+
+	for _, block := range blocks {
+		status, height, hash := verifyBlock(c.pricing, block)
+		if status == storage.BlockPending {
+			continue
+		}
+		if err := c.storage.SetBlockVerification(block.ID, status, height, hash); err != nil {
+			log.Printf("SetBlockVerification %d failed: %v", block.ID, err)
+			continue
+		}
+		if status == storage.BlockConfirmed {
+			log.Printf("Block found by %s (%s) confirmed on chain at height %d: %s", block.Hostname, block.MinerIP, height, hash)
+		} else {
+			log.Printf("Block found by %s (%s) at %s is not in the %s chain around height %d, marked orphaned",
+				block.Hostname, block.MinerIP, block.Timestamp.Format(time.RFC3339), block.CoinSymbol, block.Height)
+		}
+	}
+}
+
+// verifyBlock searches the heights around a block's expected one for a hash
+// meeting the difficulty its miner logged. The block is orphaned once all of
+// them are in the chain without it, and stays pending while the explorer
+// can't be reached or the chain hasn't reached them yet.
+func verifyBlock(explorer blockExplorer, block *storage.Block) (status string, height int64, hash string) {
+	info, ok := explorer.GetNetworkInfo(block.CoinID)
+	if !ok || info.BlockHeight == 0 {
+		return storage.BlockPending, block.Height, ""
+	}
+
+	for h := block.Height - blockSearchRange; h <= block.Height+blockSearchRange && h <= info.BlockHeight; h++ {
+		candidate, err := explorer.BlockHashAt(block.CoinID, h)
+		if err != nil {
+			return storage.BlockPending, block.Height, ""
+		}
+		diff := pricing.HashDifficulty(candidate)
+		if math.Abs(diff-block.Difficulty) <= hashDifficultyTolerance*block.Difficulty {
+			return storage.BlockConfirmed, h, candidate
+		}
+	}
+
+	if info.BlockHeight >= block.Height+blockSearchRange {
+		return storage.BlockOrphaned, block.Height, ""
+	}
+	return storage.BlockPending, block.Height, ""
+}
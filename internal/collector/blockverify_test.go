@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/pricing"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// The genesis block's hash meets difficulty 2536.43
+const genesisHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+
+type fakeExplorer struct {
+	tip    int64
+	hashes map[int64]string
+	down   bool
+}
+
+func (f *fakeExplorer) GetNetworkInfo(coinID string) (pricing.NetworkInfo, bool) {
+	return pricing.NetworkInfo{Difficulty: 1, BlockHeight: f.tip}, !f.down
+}
+
+func (f *fakeExplorer) BlockHashAt(coinID string, height int64) (string, error) {
+	if f.down {
+		return "", fmt.Errorf("unreachable")
+	}
+	if hash, ok := f.hashes[height]; ok {
+		return hash, nil
+	}
+	return "00000000000000000001a4ee6d4fb5c6b29dc6bf6ad0b1b8e1e1c58d3b2f7a91", nil
+}
+
+func TestVerifyBlock(t *testing.T) {
+	block := &storage.Block{CoinID: "btc", Height: 100, Difficulty: 2536.4, Status: storage.BlockPending}
+
+	explorer := &fakeExplorer{tip: 101, hashes: map[int64]string{101: genesisHash}}
+	if status, height, hash := verifyBlock(explorer, block); status != storage.BlockConfirmed || height != 101 || hash != genesisHash {
+		t.Errorf("expected the block confirmed at the next height, got %s %d %s", status, height, hash)
+	}
+
+	explorer = &fakeExplorer{tip: 101}
+	if status, _, _ := verifyBlock(explorer, block); status != storage.BlockPending {
+		t.Errorf("expected the block pending until the chain passes it, got %s", status)
+	}
+
+	explorer.tip = 103
+	if status, _, _ := verifyBlock(explorer, block); status != storage.BlockOrphaned {
+		t.Errorf("expected the block orphaned, got %s", status)
+	}
+
+	explorer.down = true
+	if status, _, _ := verifyBlock(explorer, block); status != storage.BlockPending {
+		t.Errorf("expected the block pending while the explorer is down, got %s", status)
+	}
+}
@@ -0,0 +1,351 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// CGMinerPort is the port of the cgminer API
+const CGMinerPort = 4028
+
+// mmField matches the Key[value] pairs of an Avalon's "MM ID" stats
+var mmField = regexp.MustCompile(`(\w+)\[([^\]]*)\]`)
+
+// CGMinerClient talks to the cgminer API of miners such as the Avalon Nano
+// and the Lucky Miner: one JSON command per TCP connection, answered with
+// one JSON object
+type CGMinerClient struct {
+	port    int
+	timeout time.Duration
+}
+
+// NewCGMinerClient creates a CGMinerClient with default timeout
+func NewCGMinerClient() *CGMinerClient {
+	return &CGMinerClient{port: CGMinerPort, timeout: 3 * time.Second}
+}
+
+// CGMinerInfo is what a miner answers to the summary, pools, stats and
+// version commands. The objects keep cgminer's keys, e.g. "MHS 1m".
+type CGMinerInfo struct {
+	Summary map[string]interface{}
+	Pools   []map[string]interface{}
+	Stats   []map[string]interface{}
+	Version map[string]interface{}
+}
+
+// Command sends one command and returns the objects of its reply section,
+// e.g. SUMMARY for summary. A reply with an error status fails.
+func (c *CGMinerClient) Command(ip, command string) ([]map[string]interface{}, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(c.port)), c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to cgminer API: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err := conn.Write([]byte(`{"command":"` + command + `"}`)); err != nil {
+		return nil, fmt.Errorf("cgminer %s: %w", command, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(conn, 1<<20))
+	if err != nil && len(data) == 0 {
+		return nil, fmt.Errorf("cgminer %s: %w", command, err)
+	}
+	// The reply ends with a NUL, and some firmwares put control characters
+	// in the strings
+	data = bytes.TrimRight(data, "\x00\r\n ")
+	data = bytes.Map(func(r rune) rune {
+		if r < 0x20 {
+			return ' '
+		}
+		return r
+	}, data)
+
+	var reply map[string]json.RawMessage
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("cgminer %s: invalid reply: %w", command, err)
+	}
+	var status []struct {
+		Status string `json:"STATUS"`
+		Msg    string `json:"Msg"`
+	}
+	json.Unmarshal(reply["STATUS"], &status)
+	if len(status) == 0 {
+		return nil, fmt.Errorf("cgminer %s: reply without status", command)
+	}
+	if status[0].Status == "E" || status[0].Status == "F" {
+		return nil, fmt.Errorf("cgminer %s: %s", command, status[0].Msg)
+	}
+
+	var objects []map[string]interface{}
+	if section, ok := reply[strings.ToUpper(command)]; ok {
+		if err := json.Unmarshal(section, &objects); err != nil {
+			return nil, fmt.Errorf("cgminer %s: %w", command, err)
+		}
+	}
+	return objects, nil
+}
+
+// FetchInfo runs the commands a poll needs. Only summary is required; the
+// others fill in what the miner supports.
+func (c *CGMinerClient) FetchInfo(ip string) (*CGMinerInfo, error) {
+	summary, err := c.Command(ip, "summary")
+	if err != nil {
+		return nil, err
+	}
+	if len(summary) == 0 {
+		return nil, fmt.Errorf("cgminer summary: empty reply")
+	}
+	info := &CGMinerInfo{Summary: summary[0]}
+	info.Pools, _ = c.Command(ip, "pools")
+	info.Stats, _ = c.Command(ip, "stats")
+	if version, err := c.Command(ip, "version"); err == nil && len(version) > 0 {
+		info.Version = version[0]
+	}
+	return info, nil
+}
+
+// activePool returns the pool the miner mines on: the first alive one with
+// an active stratum connection, else the first one
+func (info *CGMinerInfo) activePool() map[string]interface{} {
+	for _, p := range info.Pools {
+		if cgBool(p["Stratum Active"]) && cgString(p["Status"]) == "Alive" {
+			return p
+		}
+	}
+	if len(info.Pools) > 0 {
+		return info.Pools[0]
+	}
+	return nil
+}
+
+// avalonStats returns the Key[value] pairs of an Avalon's "MM ID0" stats,
+// which carry its temperatures, fan and power supply readings
+func (info *CGMinerInfo) avalonStats() map[string]string {
+	fields := make(map[string]string)
+	for _, s := range info.Stats {
+		mm := cgString(s["MM ID0"])
+		if mm == "" {
+			continue
+		}
+		for _, m := range mmField.FindAllStringSubmatch(mm, -1) {
+			fields[m[1]] = m[2]
+		}
+		break
+	}
+	return fields
+}
+
+// hashRate returns a summary hashrate in GH/s, e.g. interval "1m" reads
+// "MHS 1m" (cgminer), or "GHS 1m" (bmminer)
+func (info *CGMinerInfo) hashRate(interval string) float64 {
+	if v, ok := info.Summary["MHS "+interval]; ok {
+		return cgFloat(v) / 1000
+	}
+	if v, ok := info.Summary["GHS "+interval]; ok {
+		return cgFloat(v)
+	}
+	return 0
+}
+
+// model returns the device model the miner reports
+func (info *CGMinerInfo) model() string {
+	for _, key := range []string{"PROD", "MODEL", "Type"} {
+		if s := cgString(info.Version[key]); s != "" {
+			return s
+		}
+	}
+	return "cgminer"
+}
+
+// macAddr returns the MAC address the miner reports, formatted as AxeOS
+// does, e.g. "E0:E1:E2:E3:E4:E5"
+func (info *CGMinerInfo) macAddr() string {
+	mac := strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(cgString(info.Version["MAC"])))
+	if len(mac) != 12 {
+		return ""
+	}
+	var parts []string
+	for i := 0; i < 12; i += 2 {
+		parts = append(parts, mac[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// hostname returns a name for the miner, which the cgminer API doesn't
+// report: the model and the end of its MAC address, or else of its IP
+func (info *CGMinerInfo) hostname(ip string) string {
+	suffix := ip
+	if mac := strings.ReplaceAll(info.macAddr(), ":", ""); mac != "" {
+		suffix = strings.ToLower(mac[6:])
+	} else if i := strings.LastIndex(ip, "."); i >= 0 {
+		suffix = ip[i+1:]
+	}
+	return strings.ReplaceAll(info.model(), " ", "-") + "-" + suffix
+}
+
+// ToMiner converts a cgminer reply to storage.Miner
+func (c *CGMinerClient) ToMiner(ip string, info *CGMinerInfo) *storage.Miner {
+	firmware := cgString(info.Version["LVERSION"])
+	if firmware == "" {
+		firmware = cgString(info.Version["CGMiner"])
+	}
+
+	var poolURL string
+	var poolPort int
+	if p := info.activePool(); p != nil {
+		poolURL = cgString(p["URL"])
+		hostPort := poolURL
+		if _, rest, ok := strings.Cut(hostPort, "://"); ok {
+			hostPort = rest
+		}
+		if _, port, err := net.SplitHostPort(hostPort); err == nil {
+			poolPort, _ = strconv.Atoi(port)
+		}
+	}
+
+	return &storage.Miner{
+		IP:              ip,
+		Hostname:        info.hostname(ip),
+		DeviceModel:     info.model(),
+		FirmwareVersion: firmware,
+		MacAddr:         info.macAddr(),
+		PoolURL:         poolURL,
+		PoolPort:        poolPort,
+		Driver:          DriverCGMiner,
+		Enabled:         true,
+		LastSeen:        time.Now(),
+		Online:          true,
+	}
+}
+
+// ToSnapshot converts a cgminer reply to storage.MinerSnapshot. cgminer
+// averages hashrate over 5s, 1m, 5m and 15m and since it started, which
+// stand in for AxeOS's 1m, 10m, 1h and 1d averages.
+func (c *CGMinerClient) ToSnapshot(ip string, info *CGMinerInfo) *storage.MinerSnapshot {
+	s := info.Summary
+	avg := info.hashRate("av")
+	hashRate := info.hashRate("5s")
+	if hashRate == 0 {
+		hashRate = avg
+	}
+	hashRate1m := info.hashRate("1m")
+	if hashRate1m == 0 {
+		hashRate1m = hashRate
+	}
+	hashRate10m := info.hashRate("15m")
+	if hashRate10m == 0 {
+		hashRate10m = avg
+	}
+
+	snap := &storage.MinerSnapshot{
+		MinerIP:          ip,
+		Timestamp:        time.Now(),
+		Hostname:         info.hostname(ip),
+		DeviceModel:      info.model(),
+		HashRate:         hashRate,
+		HashRate1m:       hashRate1m,
+		HashRate10m:      hashRate10m,
+		HashRate1h:       avg,
+		HashRate1d:       avg,
+		SharesAccept:     int64(cgFloat(s["Accepted"])),
+		SharesReject:     int64(cgFloat(s["Rejected"])),
+		BestDiff:         cgFloat(s["Best Share"]),
+		BestDiffSess:     cgFloat(s["Best Share"]),
+		UptimeSecs:       int64(cgFloat(s["Elapsed"])),
+		FoundBlocks:      int(cgFloat(s["Found Blocks"])),
+		TotalFoundBlocks: int(cgFloat(s["Found Blocks"])),
+	}
+	if p := info.activePool(); p != nil {
+		snap.PoolConnected = cgBool(p["Stratum Active"]) || cgString(p["Status"]) == "Alive"
+		snap.PoolDiff = cgFloat(p["Stratum Difficulty"])
+		if snap.PoolDiff == 0 {
+			snap.PoolDiff = cgFloat(p["Last Share Difficulty"])
+		}
+	}
+
+	// Avalons: average chip temperature, else the hottest, fan speed and
+	// duty, and the power supply's readings, the last of which is watts
+	mm := info.avalonStats()
+	for _, key := range []string{"TAvg", "TMax", "Temp"} {
+		if t := cgFloat(mm[key]); t > 0 {
+			snap.Temperature = t
+			break
+		}
+	}
+	snap.FanRPM = int(cgFloat(mm["Fan1"]))
+	snap.FanPercent = int(cgFloat(strings.TrimSuffix(mm["FanR"], "%")))
+	if ps := strings.Fields(mm["PS"]); len(ps) > 0 {
+		snap.PowerRaw = cgFloat(ps[len(ps)-1])
+		snap.Power = snap.PowerRaw
+	}
+	return snap
+}
+
+// cgMinerDriver polls the cgminer API. cgminer has no WebSocket: shares
+// and blocks are only counted in the summary.
+type cgMinerDriver struct {
+	client *CGMinerClient
+}
+
+func (d *cgMinerDriver) Name() string  { return DriverCGMiner }
+func (d *cgMinerDriver) Streams() bool { return false }
+
+func (d *cgMinerDriver) Poll(ip string) (*Reading, error) {
+	info, err := d.client.FetchInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return &Reading{
+		Miner:    d.client.ToMiner(ip, info),
+		Snapshot: d.client.ToSnapshot(ip, info),
+	}, nil
+}
+
+// cgFloat reads a number cgminer sent as a JSON number or a string
+func cgFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f
+	case bool:
+		if n {
+			return 1
+		}
+	}
+	return 0
+}
+
+// cgString reads a string, or a number as text
+func cgString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
+
+// cgBool reads a flag cgminer sent as a JSON boolean or "true"/"Y"
+func cgBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return strings.EqualFold(b, "true") || b == "Y"
+	case float64:
+		return b != 0
+	}
+	return false
+}
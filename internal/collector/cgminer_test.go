@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// avalonReplies are an Avalon Nano 3's answers to the poll's commands
+var avalonReplies = map[string]string{
+	"summary": `{"STATUS":[{"STATUS":"S","When":1718000000,"Code":11,"Msg":"Summary","Description":"cgminer 4.11.1"}],"SUMMARY":[{"Elapsed":5400,"MHS av":4012345.67,"MHS 5s":3998765.43,"MHS 1m":4001234.5,"MHS 5m":4005678.9,"MHS 15m":4010000.1,"Found Blocks":0,"Accepted":812,"Rejected":3,"Best Share":1234567890}],"id":1}`,
+	"pools":   `{"STATUS":[{"STATUS":"S","Code":7,"Msg":"1 Pool(s)"}],"POOLS":[{"POOL":0,"URL":"stratum+tcp://public-pool.io:21496","Status":"Alive","Stratum Active":true,"Stratum Difficulty":8192.0,"Last Share Difficulty":8192.0}],"id":1}`,
+	"stats":   `{"STATUS":[{"STATUS":"S","Code":70,"Msg":"CGMiner stats"}],"STATS":[{"STATS":0,"ID":"AVALON0","Elapsed":5400,"MM ID0":"Ver[Nano3-25021401_56abae7] DNA[020100008c0d3a1c] Elapsed[5400] Temp[31] TMax[72] TAvg[65] Fan1[2010] FanR[45%] PS[0 0 27 4 0 3558 140]"}],"id":1}`,
+	"version": `{"STATUS":[{"STATUS":"S","Code":22,"Msg":"CGMiner versions"}],"VERSION":[{"CGMiner":"4.11.1","API":"3.7","PROD":"Avalon Nano3","MODEL":"Nano3","LVERSION":"25021401_56abae7","MAC":"e0e1e2a1b2c3"}],"id":1}` + "\x00",
+}
+
+// fakeCGMiner answers the cgminer API with replies, one command per
+// connection, and an error status for the others
+func fakeCGMiner(t *testing.T, replies map[string]string) *CGMinerClient {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var req struct {
+					Command string `json:"command"`
+				}
+				if err := json.NewDecoder(conn).Decode(&req); err != nil {
+					return
+				}
+				reply, ok := replies[req.Command]
+				if !ok {
+					reply = `{"STATUS":[{"STATUS":"E","Code":14,"Msg":"Invalid command"}],"id":1}`
+				}
+				io.WriteString(conn, reply)
+			}(conn)
+		}
+	}()
+	return &CGMinerClient{port: ln.Addr().(*net.TCPAddr).Port, timeout: 2 * time.Second}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Abs(b)
+}
+
+func TestCGMinerPoll(t *testing.T) {
+	d := &cgMinerDriver{client: fakeCGMiner(t, avalonReplies)}
+	reading, err := d.Poll("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := reading.Miner
+	if m.Hostname != "Avalon-Nano3-a1b2c3" || m.DeviceModel != "Avalon Nano3" || m.FirmwareVersion != "25021401_56abae7" || m.MacAddr != "E0:E1:E2:A1:B2:C3" {
+		t.Errorf("unexpected miner %+v", m)
+	}
+	if m.PoolURL != "stratum+tcp://public-pool.io:21496" || m.PoolPort != 21496 || m.Driver != DriverCGMiner {
+		t.Errorf("unexpected pool or driver %+v", m)
+	}
+
+	s := reading.Snapshot
+	if !approxEqual(s.HashRate, 3998.76543) || !approxEqual(s.HashRate1m, 4001.2345) || !approxEqual(s.HashRate10m, 4010.0001) || !approxEqual(s.HashRate1d, 4012.34567) {
+		t.Errorf("unexpected hashrates %v %v %v %v", s.HashRate, s.HashRate1m, s.HashRate10m, s.HashRate1d)
+	}
+	if s.SharesAccept != 812 || s.SharesReject != 3 || s.BestDiff != 1234567890 || s.UptimeSecs != 5400 {
+		t.Errorf("unexpected shares %+v", s)
+	}
+	if !s.PoolConnected || s.PoolDiff != 8192 {
+		t.Errorf("unexpected pool state %+v", s)
+	}
+	if s.Temperature != 65 || s.FanRPM != 2010 || s.FanPercent != 45 || s.Power != 140 || s.PowerRaw != 140 {
+		t.Errorf("unexpected Avalon readings %+v", s)
+	}
+}
+
+func TestCGMinerPollSummaryOnly(t *testing.T) {
+	d := &cgMinerDriver{client: fakeCGMiner(t, map[string]string{
+		"summary": `{"STATUS":[{"STATUS":"S","Msg":"Summary"}],"SUMMARY":[{"Elapsed":60,"GHS 5s":"1.25","GHS av":"1.2","Accepted":2,"Rejected":0}]}`,
+	})}
+	reading, err := d.Poll("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Miner.Hostname != "cgminer-1" || reading.Miner.PoolURL != "" {
+		t.Errorf("unexpected miner %+v", reading.Miner)
+	}
+	if s := reading.Snapshot; s.HashRate != 1.25 || s.HashRate1m != 1.25 || s.HashRate1h != 1.2 || s.PoolConnected {
+		t.Errorf("unexpected snapshot %+v", s)
+	}
+
+	if _, err := fakeCGMiner(t, map[string]string{}).FetchInfo("127.0.0.1"); err == nil || err.Error() != "cgminer summary: Invalid command" {
+		t.Errorf("expected the error status, got %v", err)
+	}
+}
+
+// stubDriver answers like a miner of another API would: not at all
+type stubDriver struct{}
+
+func (stubDriver) Name() string                     { return DriverAxeOS }
+func (stubDriver) Streams() bool                    { return true }
+func (stubDriver) Poll(ip string) (*Reading, error) { return nil, errors.New("connection refused") }
+
+func TestPollDetectsDriver(t *testing.T) {
+	c := &Collector{
+		drivers: []Driver{stubDriver{}, &cgMinerDriver{client: fakeCGMiner(t, avalonReplies)}},
+		miners:  map[string]*minerConn{"127.0.0.1": {ip: "127.0.0.1"}},
+	}
+	if !c.streams("127.0.0.1") {
+		t.Error("expected a miner to stream until its driver is known")
+	}
+	reading, err := c.poll("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Miner.Driver != DriverCGMiner || c.miners["127.0.0.1"].driver.Name() != DriverCGMiner {
+		t.Errorf("expected the cgminer driver to be kept, got %+v", reading.Miner)
+	}
+	if c.streams("127.0.0.1") {
+		t.Error("expected a cgminer miner not to stream")
+	}
+
+	if _, err := c.poll("127.0.0.2"); err == nil || err.Error() != "connection refused" {
+		t.Errorf("expected the first driver's error, got %v", err)
+	}
+}
@@ -151,10 +151,13 @@ func (c *MinerClient) ToSnapshot(ip string, info *MinerAPIResponse) *storage.Min
 		HashRate1h:    hashRate1h,
 		HashRate1d:    hashRate1d,
 		Temperature:   info.Temp,
+		Temperature2:  info.Temp2,
 		VRTemp:        info.VRTemp,
 		Power:         info.Power,
+		PowerRaw:      info.Power,
 		Voltage:       info.Voltage,
 		FanRPM:        info.FanRPM,
+		Fan2RPM:       info.Fan2RPM,
 		FanPercent:    int(info.FanSpeed),
 		SharesAccept:  info.SharesAccepted,
 		SharesReject:  info.SharesRejected,
@@ -176,13 +179,24 @@ func (c *MinerClient) ToMiner(ip string, info *MinerAPIResponse) *storage.Miner
 		deviceModel = fmt.Sprintf("AxeOS (%s)", info.ASICModel)
 	}
 
+	// Firmware version: AxeOS reports it as axeOSVersion, NerdQAxe as version
+	firmware := info.Version
+	if info.AxeOSVersion != "" {
+		firmware = info.AxeOSVersion
+	}
+
 	return &storage.Miner{
-		IP:          ip,
-		Hostname:    info.Hostname,
-		DeviceModel: deviceModel,
-		ASICModel:   info.ASICModel,
-		Enabled:     true,
-		LastSeen:    time.Now(),
-		Online:      true,
+		IP:              ip,
+		Hostname:        info.Hostname,
+		DeviceModel:     deviceModel,
+		ASICModel:       info.ASICModel,
+		FirmwareVersion: firmware,
+		MacAddr:         info.MacAddr,
+		PoolURL:         info.StratumURL,
+		PoolPort:        info.StratumPort,
+		Driver:          DriverAxeOS,
+		Enabled:         true,
+		LastSeen:        time.Now(),
+		Online:          true,
 	}
 }
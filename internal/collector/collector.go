@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -12,65 +13,149 @@ import (
 	"github.com/camarigor/miner-hq/internal/storage"
 )
 
+// wsReconnectDelay is how long to wait before reconnecting a miner's WebSocket
+const wsReconnectDelay = 5 * time.Second
+
 type Collector struct {
 	storage      *storage.SQLiteStorage
 	pricing      *pricing.PriceService
 	client       *MinerClient
+	drivers      []Driver // Tried in turn on miners whose driver isn't known yet
 	parser       *ShareParser
 	blockParser  *BlockParser
+	energy       *energyMeter
+	records      *recordKeeper
+	snapshots    *snapshotQueue // Batches snapshot writes
+	logs         *minerLogs
+	uptime       *uptimeTracker
 	miners       map[string]*minerConn
+	remote       map[string]*remoteMiner             // Miners at remote sites by key, guarded by minersMu; see Ingest
+	sitesSeen    map[string]time.Time                // Last batch from each remote site, guarded by minersMu
+	calibration  map[string]storage.PowerCalibration // Per-miner power calibration, guarded by minersMu
 	minersMu     sync.RWMutex
 	pollInterval time.Duration
 
-	// Channels for broadcasting to API WebSocket clients
+	// Adaptive polling, guarded by minersMu
+	pollIntervals   map[string]time.Duration // Per-miner poll intervals overriding pollInterval
+	backoffAfter    int                      // Failed polls in a row before backing off
+	maxPollInterval time.Duration            // Longest interval while backing off
+
+	// Snapshot sampling, guarded by minersMu
+	storeEvery     int           // Store every Nth poll
+	storeInterval  time.Duration // Minimum time between stored snapshots
+	storeUnchanged time.Duration // Minimum time between stored snapshots that repeat the last stored one
+
+	onMoved func(oldIP, newIP string) // Called after a miner is moved to a new IP
+
+	plugPower func(ip string) (float64, bool) // Wall power from the miner's smart plug, see SetPlugPower
+	proxied   func(ip string) bool            // Miners connected through the stratum proxy, see SetProxied
+
+	pollers   atomic.Int32 // Running poll loops, see Health
+	wsReaders atomic.Int32 // Running WebSocket loops, see Health
+
+	running bool           // Collecting, guarded by minersMu; see Stop and Start
+	workers sync.WaitGroup // Poll and WebSocket loops, waited for by Stop
+
+	// Channels for broadcasting to API WebSocket clients. They are never
+	// closed, so a stopped collector can be started again.
 	ShareChan    chan *storage.Share
 	SnapshotChan chan *storage.MinerSnapshot
 	BlockChan    chan *storage.Block
+	LogChan      chan *storage.MinerLogLine
+	RejectChan   chan *storage.Share // Shares the pool rejected
 }
 
 type minerConn struct {
 	ip       string
+	hostname string  // Latest hostname reported by the miner
+	mac      string  // Latest MAC address reported by the miner
+	netDiff  float64 // Latest network difficulty seen, for annotating shares
+	poolDiff float64 // Last pool difficulty recorded
+	stale    staleTracker
+	driver   Driver // Driver the miner answered, nil until then
 	wsConn   *websocket.Conn
+	wsSince  time.Time // When wsConn connected
 	cancel   context.CancelFunc
 	lastSeen time.Time
+	failures int // Failed polls in a row
+
+	pollLatency time.Duration // How long the last successful poll took
+	lastError   string        // Latest failed poll or WebSocket error
+	lastErrorAt time.Time
+	wsConnects  int           // WebSocket connections made, the first included
+	reconnect   chan struct{} // Signals the WebSocket loop to reconnect, see ReconnectMiner
+
+	polls      int                    // Polls since the miner was added
+	lastStored time.Time              // Timestamp of the last snapshot written to the database
+	stored     *storage.MinerSnapshot // Last snapshot written to the database
+	latest     *storage.MinerSnapshot // Latest polled snapshot, stored or not
+
+	submitted []*storage.Share // Submitted shares awaiting the pool's response, oldest first
+
+	blockHeight int64 // Height of the block the miner last reported working on
 }
 
 func NewCollector(store *storage.SQLiteStorage, priceSvc *pricing.PriceService) *Collector {
-	return &Collector{
-		storage:      store,
-		pricing:      priceSvc,
-		client:       NewMinerClient(),
-		parser:       NewShareParser(),
-		blockParser:  NewBlockParser(),
-		miners:       make(map[string]*minerConn),
-		pollInterval: 2 * time.Second,
-		ShareChan:    make(chan *storage.Share, 100),
-		SnapshotChan: make(chan *storage.MinerSnapshot, 100),
-		BlockChan:    make(chan *storage.Block, 10),
+	client := NewMinerClient()
+	c := &Collector{
+		storage:       store,
+		pricing:       priceSvc,
+		client:        client,
+		drivers:       []Driver{&axeOSDriver{client: client}, &cgMinerDriver{client: NewCGMinerClient()}},
+		parser:        NewShareParser(),
+		blockParser:   NewBlockParser(),
+		energy:        newEnergyMeter(store),
+		records:       newRecordKeeper(store),
+		miners:        make(map[string]*minerConn),
+		remote:        make(map[string]*remoteMiner),
+		sitesSeen:     make(map[string]time.Time),
+		calibration:   make(map[string]storage.PowerCalibration),
+		pollInterval:  2 * time.Second,
+		pollIntervals: make(map[string]time.Duration),
+		ShareChan:     make(chan *storage.Share, 100),
+		SnapshotChan:  make(chan *storage.MinerSnapshot, 100),
+		BlockChan:     make(chan *storage.Block, 10),
+		LogChan:       make(chan *storage.MinerLogLine, 256),
+		RejectChan:    make(chan *storage.Share, 100),
+		snapshots:     newSnapshotQueue(store),
+		logs:          newMinerLogs(store),
+		uptime:        newUptimeTracker(store),
+		running:       true,
 	}
+	go c.snapshots.run()
+	return c
 }
 
 // AddMiner starts collecting data from a miner
 func (c *Collector) AddMiner(ip string) {
+	if IsRemote(ip) {
+		return // Forwarded by its site's agent, see Ingest
+	}
+
 	c.minersMu.Lock()
 	defer c.minersMu.Unlock()
 
+	if !c.running {
+		return // Stopped, Start resumes collection
+	}
 	if _, exists := c.miners[ip]; exists {
 		return // Already monitoring
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	conn := &minerConn{
-		ip:     ip,
-		cancel: cancel,
+		ip:        ip,
+		cancel:    cancel,
+		reconnect: make(chan struct{}, 1),
 	}
 	c.miners[ip] = conn
+	c.workers.Add(2)
 
 	// Start polling goroutine
 	go c.pollMiner(ctx, ip)
 
-	// Start WebSocket goroutine
-	go c.connectWebSocket(ctx, ip)
+	// Start WebSocket goroutine, which ends for miners without one
+	go c.connectWebSocket(ctx, ip, conn.reconnect)
 }
 
 // RemoveMiner stops collecting from a miner
@@ -79,51 +164,85 @@ func (c *Collector) RemoveMiner(ip string) {
 	defer c.minersMu.Unlock()
 
 	if conn, exists := c.miners[ip]; exists {
-		conn.cancel()
-		if conn.wsConn != nil {
-			conn.wsConn.Close()
-		}
+		conn.cancel() // Also closes the WebSocket
 		delete(c.miners, ip)
 	}
+	delete(c.remote, ip)
+	c.logs.forget(ip)
 }
 
-// pollMiner polls the REST API every pollInterval
+// pollMiner polls the miner's API at its poll interval, backing off
+// while it doesn't answer
 func (c *Collector) pollMiner(ctx context.Context, ip string) {
-	ticker := time.NewTicker(c.pollInterval)
-	defer ticker.Stop()
+	defer c.workers.Done()
+	c.pollers.Add(1)
+	defer c.pollers.Add(-1)
 
 	// Initial poll
 	c.fetchAndStore(ip)
 
+	timer := time.NewTimer(c.nextPollDelay(ip))
+	defer timer.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			c.fetchAndStore(ip)
+			timer.Reset(c.nextPollDelay(ip))
 		}
 	}
 }
 
 // fetchAndStore fetches miner info and stores snapshot
 func (c *Collector) fetchAndStore(ip string) {
-	info, err := c.client.FetchInfo(ip)
+	start := time.Now()
+	reading, err := c.poll(ip)
+	c.recordPoll(ip, time.Since(start), err)
 	if err != nil {
 		log.Printf("Poll %s failed: %v", ip, err)
 		return
 	}
 
-	// Update miner record
-	miner := c.client.ToMiner(ip, info)
+	// Update miner record, after taking over the miner's record from its
+	// previous IP if its address changed
+	miner := reading.Miner
+	c.trackMAC(ip, miner.MacAddr)
 	if err := c.storage.UpsertMiner(miner); err != nil {
 		log.Printf("UpsertMiner %s failed: %v", ip, err)
 	}
+	c.trackHostname(ip, miner.Hostname)
+	c.trackNetworkDifficulty(ip, reading.NetworkDiff)
 
-	// Store snapshot
-	snapshot := c.client.ToSnapshot(ip, info)
-	if err := c.storage.InsertSnapshot(snapshot); err != nil {
-		log.Printf("InsertSnapshot %s failed: %v", ip, err)
+	// Store snapshot with the smart plug's reading, or else calibrated power
+	snapshot := reading.Snapshot
+	c.minersMu.Lock()
+	if cal, ok := c.calibration[ip]; ok {
+		snapshot.Power = cal.Apply(snapshot.PowerRaw)
+	}
+	if c.plugPower != nil {
+		if watts, ok := c.plugPower(ip); ok {
+			snapshot.Power = watts
+			snapshot.PlugPower = true
+		}
 	}
+	store := true
+	if conn, exists := c.miners[ip]; exists {
+		snapshot.FrozenSecs = int64(conn.stale.observe(snapshot).Seconds())
+		store = c.sampleSnapshot(conn, snapshot)
+		conn.latest = snapshot
+		if reading.BlockHeight > 0 {
+			conn.blockHeight = reading.BlockHeight
+		}
+	}
+	c.minersMu.Unlock()
+	c.trackPoolDifficulty(ip, snapshot.Hostname, snapshot.PoolDiff)
+	if store {
+		c.snapshots.add(snapshot)
+	}
+	c.energy.record(ip, snapshot.Power, snapshot.Timestamp)
+	c.records.observeUptime(snapshot)
 
 	// Update last seen
 	c.minersMu.Lock()
@@ -139,59 +258,160 @@ func (c *Collector) fetchAndStore(ip string) {
 	}
 }
 
-// connectWebSocket maintains a persistent WebSocket connection
-func (c *Collector) connectWebSocket(ctx context.Context, ip string) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
+// sampleSnapshot reports whether a polled snapshot should be written to the
+// database: only every storeEvery-th poll, no sooner than storeInterval after
+// the last stored one, and no sooner than storeUnchanged if it repeats the
+// last stored one. The first poll is always stored.
+// Caller must hold minersMu.
+func (c *Collector) sampleSnapshot(conn *minerConn, snap *storage.MinerSnapshot) bool {
+	conn.polls++
+	if c.storeEvery > 1 && (conn.polls-1)%c.storeEvery != 0 {
+		return false
+	}
+	ts := snap.Timestamp
+	if c.storeInterval > 0 && !conn.lastStored.IsZero() && ts.Sub(conn.lastStored) < c.storeInterval {
+		return false
+	}
+	if c.storeUnchanged > 0 && conn.stored != nil && ts.Sub(conn.lastStored) < c.storeUnchanged && sameReadings(conn.stored, snap) {
+		return false
+	}
+	conn.lastStored = ts
+	conn.stored = snap
+	return true
+}
 
+// RefreshMiner re-queries a miner's system info and updates its stored
+// hostname, model, firmware and MAC address without recording a snapshot
+func (c *Collector) RefreshMiner(ip string) (*storage.Miner, error) {
+	reading, err := c.poll(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	miner := reading.Miner
+	if err := c.storage.UpsertMiner(miner); err != nil {
+		return nil, err
+	}
+	c.trackHostname(ip, miner.Hostname)
+	c.trackNetworkDifficulty(ip, reading.NetworkDiff)
+
+	return miner, nil
+}
+
+// connectWebSocket maintains a persistent WebSocket connection until ctx is
+// canceled, which closes the connection, or the miner turns out to have a
+// driver without one. A signal on reconnect skips the wait before
+// reconnecting.
+func (c *Collector) connectWebSocket(ctx context.Context, ip string, reconnect chan struct{}) {
+	defer c.workers.Done()
+	c.wsReaders.Add(1)
+	defer c.wsReaders.Add(-1)
+
+	for ctx.Err() == nil && c.streams(ip) {
 		u := url.URL{Scheme: "ws", Host: ip, Path: "/api/ws"}
 
-		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !c.streams(ip) {
+				return // Polled by a driver without a WebSocket
+			}
 			log.Printf("WebSocket connect %s failed: %v", ip, err)
-			time.Sleep(5 * time.Second)
+			c.recordError(ip, err)
+			if !waitReconnect(ctx, reconnect, wsReconnectDelay) {
+				return
+			}
 			continue
 		}
 
+		// The miner may have been removed while connecting
 		c.minersMu.Lock()
+		if ctx.Err() != nil {
+			c.minersMu.Unlock()
+			conn.Close()
+			return
+		}
 		if mc, exists := c.miners[ip]; exists {
 			mc.wsConn = conn
+			mc.wsSince = time.Now()
+			mc.wsConnects++
 		}
 		c.minersMu.Unlock()
+		stopClose := context.AfterFunc(ctx, func() { conn.Close() })
 
 		log.Printf("WebSocket connected to %s", ip)
 
-		// Get miner hostname for share attribution
-		hostname := ip
+		// Get miner hostname for share attribution (refreshed per message
+		// from poll data so a rename applies without reconnecting)
+		fallbackHostname := ip
 		miners, _ := c.storage.GetMiners()
 		for _, m := range miners {
 			if m.IP == ip {
-				hostname = m.Hostname
+				fallbackHostname = m.Hostname
 				break
 			}
 		}
 
 		// Read messages until error
+		requested := false
 		for {
 			_, message, err := conn.ReadMessage()
 			if err != nil {
-				log.Printf("WebSocket read %s error: %v", ip, err)
+				select {
+				case <-reconnect:
+					requested = true
+					log.Printf("WebSocket %s reconnecting on request", ip)
+				default:
+					if ctx.Err() == nil {
+						log.Printf("WebSocket read %s error: %v", ip, err)
+						c.recordError(ip, err)
+					}
+				}
+				stopClose()
 				conn.Close()
+				c.minersMu.Lock()
+				if mc, exists := c.miners[ip]; exists && mc.wsConn == conn {
+					mc.wsConn = nil
+				}
+				c.minersMu.Unlock()
 				break
 			}
 
+			// Capture the raw log lines for the log viewer and live tailing
+			for _, line := range c.logs.record(ip, string(message), time.Now()) {
+				select {
+				case c.LogChan <- line:
+				default:
+				}
+			}
+
+			hostname := c.currentHostname(ip, fallbackHostname)
+
+			// Shares, results and blocks of miners connected through the
+			// stratum proxy are recorded by the proxy
+			if c.isProxied(ip) {
+				continue
+			}
+
 			// Parse share from message
 			share := c.parser.Parse(ip, string(message))
 			if share != nil {
 				share.Hostname = hostname
 
+				// NerdQAxe reports network difficulty per share; AxeOS only via polling
+				if share.NetworkDifficulty > 0 {
+					c.trackNetworkDifficulty(ip, share.NetworkDifficulty)
+				} else {
+					share.SetNetworkDifficulty(c.NetworkDifficulty(ip))
+				}
+
 				if err := c.storage.InsertShare(share); err != nil {
 					log.Printf("InsertShare failed: %v", err)
 				}
+				c.records.observeShare(share)
+				c.awaitResult(share)
 
 				// Broadcast (non-blocking)
 				select {
@@ -200,90 +420,483 @@ func (c *Collector) connectWebSocket(ctx context.Context, ip string) {
 				}
 			}
 
+			// Match the pool's response to the oldest submitted share
+			if rejected, reason, ok := c.parser.ParseResult(string(message)); ok {
+				c.shareResult(ip, hostname, rejected, reason, time.Now())
+			}
+
 			// Parse block from message
 			block := c.blockParser.Parse(ip, string(message))
 			if block != nil {
 				block.Hostname = hostname
+				c.foundBlock(ip, block)
+			}
+		}
 
-				// Populate value tracking fields from pricing service
-				// Use per-miner coin if configured, otherwise fall back to global
-				if c.pricing != nil {
-					var coin *pricing.Coin
-					miners, _ := c.storage.GetMiners()
-					for _, m := range miners {
-						if m.IP == ip && m.CoinID != "" {
-							coin = c.pricing.GetCoinInfoByID(m.CoinID)
-							break
-						}
-					}
-					if coin == nil {
-						coin = c.pricing.GetCoinInfoByID("dgb") // default fallback
-					}
-					if coin != nil {
-						block.CoinID = coin.ID
-						block.CoinSymbol = coin.Symbol
-						block.BlockReward = coin.BlockReward
-						block.CoinPrice = c.pricing.GetPriceForCoin(coin.ID)
-						block.ValueUSD = block.BlockReward * block.CoinPrice
-					}
-				}
-
-				log.Printf("BLOCK FOUND by %s (%s)! Diff: %.0f > Network: %.0f | Value: %.2f %s ($%.2f)",
-					hostname, ip, block.Difficulty, block.NetworkDifficulty,
-					block.BlockReward, block.CoinSymbol, block.ValueUSD)
+		// Wait before reconnecting, unless asked to reconnect
+		if !requested && !waitReconnect(ctx, reconnect, wsReconnectDelay) {
+			return
+		}
+	}
+}
 
-				if err := c.storage.InsertBlock(block); err != nil {
-					log.Printf("InsertBlock failed: %v", err)
-				}
+// foundBlock values, stores and broadcasts a block found by a miner
+func (c *Collector) foundBlock(ip string, block *storage.Block) {
+	c.trackNetworkDifficulty(ip, block.NetworkDifficulty)
 
-				// Broadcast (non-blocking)
-				select {
-				case c.BlockChan <- block:
-				default:
-				}
+	// Populate value tracking fields from pricing service
+	// Use per-miner coin if configured, otherwise fall back to global
+	if c.pricing != nil {
+		var coin *pricing.Coin
+		miners, _ := c.storage.GetMiners()
+		for _, m := range miners {
+			if m.IP == ip && m.CoinID != "" {
+				coin = c.pricing.GetCoinInfoByID(m.CoinID)
+				break
 			}
 		}
+		if coin == nil {
+			coin = c.pricing.GetCoinInfoByID("dgb") // default fallback
+		}
+		if coin != nil {
+			block.CoinID = coin.ID
+			block.CoinSymbol = coin.Symbol
+			block.BlockReward = coin.BlockReward
+			block.CoinPrice = c.pricing.GetPriceForCoin(coin.ID)
+			block.ValueUSD = block.BlockReward * block.CoinPrice
+		}
+	}
+	c.markForVerification(ip, block)
+
+	log.Printf("BLOCK FOUND by %s (%s)! Diff: %.0f > Network: %.0f | Value: %.2f %s ($%.2f)",
+		block.Hostname, ip, block.Difficulty, block.NetworkDifficulty,
+		block.BlockReward, block.CoinSymbol, block.ValueUSD)
 
-		// Wait before reconnecting
-		time.Sleep(5 * time.Second)
+	if err := c.storage.InsertBlock(block); err != nil {
+		log.Printf("InsertBlock failed: %v", err)
+	} else {
+		c.records.observeBlock(block)
+	}
+
+	// Broadcast (non-blocking)
+	select {
+	case c.BlockChan <- block:
+	default:
 	}
 }
 
-// GetMinerStatus returns online status for all miners
+// waitReconnect waits for d or a signal on reconnect. It returns false if ctx
+// was canceled first.
+func waitReconnect(ctx context.Context, reconnect chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-reconnect:
+		return true
+	case <-timer.C:
+		return true
+	}
+}
+
+// ReconnectMiner drops a miner's WebSocket and connects it again right away.
+// It returns false if the miner isn't being collected from.
+func (c *Collector) ReconnectMiner(ip string) bool {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+
+	conn, exists := c.miners[ip]
+	if !exists {
+		return false
+	}
+	select {
+	case conn.reconnect <- struct{}{}:
+	default:
+	}
+	if conn.wsConn != nil {
+		conn.wsConn.Close()
+	}
+	return true
+}
+
+// recordError keeps a miner's latest poll or WebSocket error for Health
+func (c *Collector) recordError(ip string, err error) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	if conn, exists := c.miners[ip]; exists {
+		conn.lastError = err.Error()
+		conn.lastErrorAt = time.Now()
+	}
+}
+
+// trackHostname records hostname changes reported by a miner's poll data
+func (c *Collector) trackHostname(ip, hostname string) {
+	if hostname == "" {
+		return
+	}
+
+	c.minersMu.Lock()
+	conn, exists := c.miners[ip]
+	if !exists || conn.hostname == hostname {
+		c.minersMu.Unlock()
+		return
+	}
+	conn.hostname = hostname
+	c.minersMu.Unlock()
+
+	previous, changed, err := c.storage.RecordHostname(ip, hostname, time.Now())
+	if err != nil {
+		log.Printf("RecordHostname %s failed: %v", ip, err)
+		return
+	}
+	if changed && previous != "" {
+		log.Printf("Miner %s renamed: %s -> %s", ip, previous, hostname)
+	}
+}
+
+// trackMAC checks a MAC address newly reported by a miner against the other
+// registered miners. If the miner was registered under another IP before,
+// that record and its history are moved to this IP.
+func (c *Collector) trackMAC(ip, mac string) {
+	if mac == "" {
+		return
+	}
+
+	c.minersMu.Lock()
+	conn, exists := c.miners[ip]
+	if !exists || conn.mac == mac {
+		c.minersMu.Unlock()
+		return
+	}
+	conn.mac = mac
+	c.minersMu.Unlock()
+
+	if _, err := c.FollowMAC(ip, mac); err != nil {
+		log.Printf("FollowMAC %s failed: %v", ip, err)
+	}
+}
+
+// FollowMAC moves the miner registered with a MAC address at another IP, if
+// any, to ip: a DHCP lease change gave it a new address. It returns the IP
+// the miner was moved from, or "" if no other miner has the MAC.
+func (c *Collector) FollowMAC(ip, mac string) (string, error) {
+	oldIP, err := c.storage.GetMinerIPByMAC(mac, ip)
+	if err != nil || oldIP == "" {
+		return "", err
+	}
+	return oldIP, c.MoveMiner(oldIP, ip)
+}
+
+// MoveMiner moves a miner and its history from oldIP to newIP, stops
+// collecting from the old address and starts collecting from the new one
+func (c *Collector) MoveMiner(oldIP, newIP string) error {
+	if err := c.storage.MoveMiner(oldIP, newIP); err != nil {
+		return err
+	}
+	c.RemoveMiner(oldIP)
+
+	c.minersMu.Lock()
+	delete(c.calibration, oldIP)
+	if interval, ok := c.pollIntervals[oldIP]; ok {
+		c.pollIntervals[newIP] = interval
+		delete(c.pollIntervals, oldIP)
+	}
+	onMoved := c.onMoved
+	c.minersMu.Unlock()
+
+	miners, err := c.storage.GetMiners()
+	if err != nil {
+		return err
+	}
+	// A paused miner stays paused at its new address
+	for _, m := range miners {
+		if m.IP == newIP {
+			c.SetPowerCalibration(m.IP, m.PowerCalibration())
+			c.SetEnergyLocation(m.IP, m.Location)
+			c.AddMiner(newIP)
+			break
+		}
+	}
+
+	log.Printf("Miner moved: %s -> %s (same MAC address)", oldIP, newIP)
+	if onMoved != nil {
+		onMoved(oldIP, newIP)
+	}
+	return nil
+}
+
+// SetOnMinerMoved registers a function called after a miner is moved to a
+// new IP, e.g. to reload state kept by IP elsewhere
+func (c *Collector) SetOnMinerMoved(fn func(oldIP, newIP string)) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	c.onMoved = fn
+}
+
+// trackPoolDifficulty records pool difficulty adjustments reported by a miner's poll data
+func (c *Collector) trackPoolDifficulty(ip, hostname string, poolDiff float64) {
+	if poolDiff <= 0 {
+		return
+	}
+
+	c.minersMu.Lock()
+	conn, exists := c.miners[ip]
+	if !exists || conn.poolDiff == poolDiff {
+		c.minersMu.Unlock()
+		return
+	}
+	conn.poolDiff = poolDiff
+	c.minersMu.Unlock()
+
+	change, err := c.storage.RecordPoolDifficulty(ip, hostname, poolDiff, time.Now())
+	if err != nil {
+		log.Printf("RecordPoolDifficulty %s failed: %v", ip, err)
+		return
+	}
+	if change != nil && change.OldDifficulty > 0 {
+		log.Printf("Miner %s pool difficulty changed: %s -> %s", hostname, FormatDifficulty(change.OldDifficulty), FormatDifficulty(change.NewDifficulty))
+	}
+}
+
+// trackNetworkDifficulty remembers the latest network difficulty seen for a miner
+func (c *Collector) trackNetworkDifficulty(ip string, netDiff float64) {
+	if netDiff <= 0 {
+		return
+	}
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	if conn, exists := c.miners[ip]; exists {
+		conn.netDiff = netDiff
+	}
+}
+
+// NetworkDifficulty returns the latest network difficulty seen for a miner, 0 if unknown
+func (c *Collector) NetworkDifficulty(ip string) float64 {
+	c.minersMu.RLock()
+	defer c.minersMu.RUnlock()
+	if conn, exists := c.miners[ip]; exists {
+		return conn.netDiff
+	}
+	return 0
+}
+
+// currentHostname returns the latest polled hostname for a miner, or fallback if unknown
+func (c *Collector) currentHostname(ip, fallback string) string {
+	c.minersMu.RLock()
+	defer c.minersMu.RUnlock()
+	if conn, exists := c.miners[ip]; exists && conn.hostname != "" {
+		return conn.hostname
+	}
+	return fallback
+}
+
+// SetEnergyRate sets the function used to price measured energy
+func (c *Collector) SetEnergyRate(rate EnergyRateFunc) {
+	c.energy.setRate(rate)
+}
+
+// SetEnergyLocation sets the energy location used to price a miner's usage
+func (c *Collector) SetEnergyLocation(ip, location string) {
+	c.energy.setLocation(ip, location)
+}
+
+// SetPowerCalibration sets the power calibration applied to a miner's future snapshots
+func (c *Collector) SetPowerCalibration(ip string, cal storage.PowerCalibration) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	c.calibration[ip] = cal
+}
+
+// SetPlugPower sets where to read miners' wall power from. A reading replaces
+// the firmware's calibrated power in their snapshots. Call before Start.
+func (c *Collector) SetPlugPower(power func(ip string) (float64, bool)) {
+	c.plugPower = power
+}
+
+// SetSnapshotSampling limits how many polled snapshots are written to the
+// database. Snapshots that only repeat the last stored one are written once
+// per unchanged at most (0 = like any other). Polling, live updates and
+// alerts are not affected.
+func (c *Collector) SetSnapshotSampling(every int, interval, unchanged time.Duration) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	c.storeEvery = every
+	c.storeInterval = interval
+	c.storeUnchanged = unchanged
+}
+
+// SetSnapshotFlushInterval sets how often stored snapshots are written to
+// the database, in one transaction for all miners (0 = every 5 seconds)
+func (c *Collector) SetSnapshotFlushInterval(interval time.Duration) {
+	c.snapshots.setInterval(interval)
+}
+
+// SetLogCapture sets how many raw log lines are kept in memory per miner and
+// whether every line is also stored in the database. With neither, lines are
+// not captured or broadcast.
+func (c *Collector) SetLogCapture(lines int, persist bool) {
+	c.logs.configure(lines, persist)
+}
+
+// MinerLogs returns up to limit of the last log lines a miner streamed,
+// oldest first
+func (c *Collector) MinerLogs(ip string, limit int) ([]*storage.MinerLogLine, error) {
+	return c.logs.last(ip, limit)
+}
+
+// LatestSnapshot returns the most recent snapshot polled from a miner, which
+// may be newer than the latest stored one, or nil if none was polled yet
+func (c *Collector) LatestSnapshot(ip string) *storage.MinerSnapshot {
+	c.minersMu.RLock()
+	defer c.minersMu.RUnlock()
+	if conn, exists := c.miners[ip]; exists {
+		return conn.latest
+	}
+	if rm, exists := c.remote[ip]; exists {
+		return rm.latest
+	}
+	return nil
+}
+
+// LatestSnapshots returns the latest polled snapshot of every miner that has
+// one, keyed by IP
+func (c *Collector) LatestSnapshots() map[string]*storage.MinerSnapshot {
+	c.minersMu.RLock()
+	defer c.minersMu.RUnlock()
+
+	latest := make(map[string]*storage.MinerSnapshot, len(c.miners)+len(c.remote))
+	for ip, conn := range c.miners {
+		if conn.latest != nil {
+			latest[ip] = conn.latest
+		}
+	}
+	for key, rm := range c.remote {
+		if rm.latest != nil {
+			latest[key] = rm.latest
+		}
+	}
+	return latest
+}
+
+// GetMinerStatus returns online status for all miners, those at remote sites
+// included
 func (c *Collector) GetMinerStatus() map[string]bool {
 	c.minersMu.RLock()
 	defer c.minersMu.RUnlock()
 
 	status := make(map[string]bool)
 	for ip, conn := range c.miners {
-		status[ip] = time.Since(conn.lastSeen) < 30*time.Second
+		// Slowly polled miners get three intervals to answer
+		timeout := 3 * c.minerPollInterval(ip)
+		if timeout < 30*time.Second {
+			timeout = 30 * time.Second
+		}
+		status[ip] = time.Since(conn.lastSeen) < timeout
+	}
+	for key, rm := range c.remote {
+		status[key] = time.Since(rm.lastSeen) < remoteTimeout
 	}
 	return status
 }
 
-// Start begins collecting from a list of miners
+// Start begins collecting from a list of miners, also after Stop
 func (c *Collector) Start(miners []storage.Miner) {
+	c.resume()
+	for _, m := range miners {
+		if m.Enabled && m.Site != "" {
+			c.trackRemote(m.IP, m.Site)
+		} else if m.Enabled {
+			c.SetPowerCalibration(m.IP, m.PowerCalibration())
+			c.SetEnergyLocation(m.IP, m.Location)
+			c.AddMiner(m.IP)
+		}
+	}
+}
+
+// Resync matches collection to a replaced miner list, e.g. after a database
+// restore: miners no longer in the list are dropped, new ones are added and
+// cached records are reloaded
+func (c *Collector) Resync(miners []*storage.Miner) {
+	enabled := make(map[string]bool, len(miners))
 	for _, m := range miners {
 		if m.Enabled {
+			enabled[m.IP] = true
+		}
+	}
+
+	c.minersMu.RLock()
+	var removed []string
+	for ip := range c.miners {
+		if !enabled[ip] {
+			removed = append(removed, ip)
+		}
+	}
+	c.minersMu.RUnlock()
+	for _, ip := range removed {
+		c.RemoveMiner(ip)
+	}
+
+	for _, m := range miners {
+		if m.Enabled && m.Site != "" {
+			c.trackRemote(m.IP, m.Site)
+		} else if m.Enabled {
+			c.SetPowerCalibration(m.IP, m.PowerCalibration())
+			c.SetEnergyLocation(m.IP, m.Location)
 			c.AddMiner(m.IP)
 		}
 	}
+
+	c.records.reset()
 }
 
-// Stop stops all collection
+// Stop stops collecting from every miner. It waits for the polls and shares
+// in flight to be stored, then writes the queued snapshots, energy counters
+// and log lines. Start or Restart resume collection.
 func (c *Collector) Stop() {
 	c.minersMu.Lock()
-	defer c.minersMu.Unlock()
-
+	if !c.running {
+		c.minersMu.Unlock()
+		return
+	}
+	c.running = false
 	for ip, conn := range c.miners {
 		conn.cancel()
-		if conn.wsConn != nil {
-			conn.wsConn.Close()
-		}
 		delete(c.miners, ip)
 	}
+	c.minersMu.Unlock()
+
+	c.workers.Wait()
+	c.energy.flush()
+	c.snapshots.close()
+	c.logs.flush()
+}
+
+// Restart stops collection and starts it again from the same miners, e.g.
+// so a changed poll interval applies right away. Power calibrations, energy
+// locations and poll intervals are kept.
+func (c *Collector) Restart() {
+	c.minersMu.RLock()
+	ips := make([]string, 0, len(c.miners))
+	for ip := range c.miners {
+		ips = append(ips, ip)
+	}
+	c.minersMu.RUnlock()
+
+	c.Stop()
+	c.resume()
+	for _, ip := range ips {
+		c.AddMiner(ip)
+	}
+}
 
-	close(c.ShareChan)
-	close(c.SnapshotChan)
-	close(c.BlockChan)
+// resume lets miners be added again after Stop
+func (c *Collector) resume() {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	if c.running {
+		return
+	}
+	c.running = true
+	c.snapshots.reopen()
 }
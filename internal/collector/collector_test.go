@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestSampleSnapshot(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		every    int
+		interval time.Duration
+		want     []bool // One entry per poll, 2 seconds apart
+	}{
+		{"every poll", 0, 0, []bool{true, true, true, true}},
+		{"every 3rd poll", 3, 0, []bool{true, false, false, true, false, false, true}},
+		{"once per 5s", 0, 5 * time.Second, []bool{true, false, false, true, false, false, true}},
+		{"both", 2, 5 * time.Second, []bool{true, false, false, false, true, false, false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Collector{storeEvery: tt.every, storeInterval: tt.interval}
+			conn := &minerConn{}
+			for i, want := range tt.want {
+				ts := start.Add(time.Duration(i*2) * time.Second)
+				if got := c.sampleSnapshot(conn, &storage.MinerSnapshot{Timestamp: ts}); got != want {
+					t.Errorf("poll %d: stored = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleUnchangedSnapshots(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &Collector{storeUnchanged: 10 * time.Second}
+	conn := &minerConn{}
+
+	// One entry per poll, 2 seconds apart, with the hashrate polled
+	tests := []struct {
+		hashRate float64
+		want     bool
+	}{
+		{500, true},  // First poll
+		{500, false}, // Only the timestamp and uptime changed
+		{500, false},
+		{510, true}, // Readings changed
+		{510, false},
+		{510, false},
+		{510, false},
+		{510, false},
+		{510, true}, // Unchanged for 10 seconds, stored as a heartbeat
+	}
+	for i, tt := range tests {
+		snap := &storage.MinerSnapshot{
+			Timestamp:  start.Add(time.Duration(i*2) * time.Second),
+			UptimeSecs: int64(1000 + i*2),
+			Hostname:   "bitaxe",
+			HashRate:   tt.hashRate,
+		}
+		if got := c.sampleSnapshot(conn, snap); got != tt.want {
+			t.Errorf("poll %d: stored = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestCollectorRestart(t *testing.T) {
+	c := NewCollector(nil, nil)
+	c.SetPollInterval(time.Hour, 0, time.Hour)
+	c.AddMiner("127.0.0.1:1") // Nothing listens, polls and WebSocket dials fail
+
+	c.Restart()
+	if h := c.Health(); h.Miners != 1 || h.Pollers+h.WebSocketLoops > 2 {
+		t.Fatalf("expected the miner collected again after Restart, got %+v", h)
+	}
+
+	if !c.ReconnectMiner("127.0.0.1:1") || c.ReconnectMiner("127.0.0.1:2") {
+		t.Error("expected only collected miners reconnected")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stop did not return while the WebSocket waited to reconnect")
+	}
+	if h := c.Health(); h.Miners != 0 || h.Pollers != 0 || h.WebSocketLoops != 0 {
+		t.Errorf("expected no loops left after Stop, got %+v", h)
+	}
+
+	c.AddMiner("127.0.0.1:1")
+	if n := c.Health().Miners; n != 0 {
+		t.Errorf("expected AddMiner ignored while stopped, got %d miners", n)
+	}
+	c.Start(nil)
+	c.AddMiner("127.0.0.1:1")
+	if n := c.Health().Miners; n != 1 {
+		t.Errorf("expected collection resumed after Start, got %d miners", n)
+	}
+	c.Stop()
+
+	// Sends after Stop must not panic
+	select {
+	case c.SnapshotChan <- nil:
+	default:
+	}
+}
+
+func TestRemoteMiners(t *testing.T) {
+	c := NewCollector(nil, nil)
+	c.Start([]storage.Miner{
+		{IP: "10.0.0.2@cabin", Site: "cabin", Enabled: true},
+		{IP: "10.0.0.2@barn", Site: "barn", Enabled: true},
+	})
+	defer c.Stop()
+
+	c.AddMiner(RemoteKey("10.0.0.3", "cabin"))
+	if n := c.Health().Miners; n != 0 {
+		t.Fatalf("expected remote miners never polled, got %d", n)
+	}
+
+	status := c.GetMinerStatus()
+	if online, ok := status["10.0.0.2@cabin"]; !ok || online {
+		t.Errorf("expected the stored remote miner listed offline, got %v", status)
+	}
+
+	sites := c.Sites()
+	if len(sites) != 2 || sites[0].Name != "barn" || sites[1].Miners != 1 || sites[1].Online != 0 {
+		t.Errorf("unexpected sites %+v", sites)
+	}
+	if !IsRemote("10.0.0.2@cabin") || IsRemote("10.0.0.2") {
+		t.Error("IsRemote doesn't tell remote keys from IPs")
+	}
+}
@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MinerSettings are the tunables MinerHQ can change on a miner. Nil fields
+// are left as they are.
+type MinerSettings struct {
+	Frequency    *int  `json:"frequency,omitempty"`    // ASIC frequency in MHz
+	CoreVoltage  *int  `json:"coreVoltage,omitempty"`  // ASIC core voltage in mV
+	FanSpeed     *int  `json:"fanSpeed,omitempty"`     // Manual fan speed in percent, turns auto fan off
+	AutoFanSpeed *bool `json:"autoFanSpeed,omitempty"` // Let the firmware control the fan
+}
+
+// Validate rejects values outside what any supported ASIC runs at, so a typo
+// can't push a miner into a state it won't boot from
+func (s *MinerSettings) Validate() error {
+	if s.Frequency == nil && s.CoreVoltage == nil && s.FanSpeed == nil && s.AutoFanSpeed == nil {
+		return fmt.Errorf("no settings to change")
+	}
+	if s.Frequency != nil && (*s.Frequency < 100 || *s.Frequency > 1200) {
+		return fmt.Errorf("frequency %d MHz must be between 100 and 1200", *s.Frequency)
+	}
+	if s.CoreVoltage != nil && (*s.CoreVoltage < 900 || *s.CoreVoltage > 1400) {
+		return fmt.Errorf("core voltage %d mV must be between 900 and 1400", *s.CoreVoltage)
+	}
+	if s.FanSpeed != nil && (*s.FanSpeed < 0 || *s.FanSpeed > 100) {
+		return fmt.Errorf("fan speed %d%% must be between 0 and 100", *s.FanSpeed)
+	}
+	if s.FanSpeed != nil && s.AutoFanSpeed != nil && *s.AutoFanSpeed {
+		return fmt.Errorf("fan speed can't be set with auto fan speed on")
+	}
+	return nil
+}
+
+// payload converts the settings to the firmware's PATCH /api/system body
+func (s *MinerSettings) payload() map[string]int {
+	body := make(map[string]int)
+	if s.Frequency != nil {
+		body["frequency"] = *s.Frequency
+	}
+	if s.CoreVoltage != nil {
+		body["coreVoltage"] = *s.CoreVoltage
+	}
+	if s.AutoFanSpeed != nil {
+		body["autofanspeed"] = 0
+		if *s.AutoFanSpeed {
+			body["autofanspeed"] = 1
+		}
+	}
+	if s.FanSpeed != nil {
+		body["fanspeed"] = *s.FanSpeed
+		body["autofanspeed"] = 0
+	}
+	return body
+}
+
+// MinerControl changes settings on and restarts NerdQAxe and AxeOS/Zyber
+// miners through their REST API
+type MinerControl struct {
+	httpClient *http.Client
+	otaClient  *http.Client // Firmware uploads, see Flash
+}
+
+// NewMinerControl creates a new MinerControl with default timeout
+func NewMinerControl() *MinerControl {
+	return &MinerControl{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		otaClient: &http.Client{
+			Timeout: otaTimeout,
+		},
+	}
+}
+
+// do sends a request to the miner's API and checks the status code
+func (c *MinerControl) do(method, ip, path string, body interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", ip, path), reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach miner: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// UpdateSettings applies settings to the miner. Frequency and voltage changes
+// take effect after a restart on most firmware.
+func (c *MinerControl) UpdateSettings(ip string, settings MinerSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	return c.do(http.MethodPatch, ip, "/api/system", settings.payload())
+}
+
+// Restart reboots the miner
+func (c *MinerControl) Restart(ip string) error {
+	return c.do(http.MethodPost, ip, "/api/system/restart", nil)
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMinerSettingsValidate(t *testing.T) {
+	intp := func(v int) *int { return &v }
+	boolp := func(v bool) *bool { return &v }
+
+	tests := []struct {
+		name     string
+		settings MinerSettings
+		wantErr  bool
+	}{
+		{"empty", MinerSettings{}, true},
+		{"frequency", MinerSettings{Frequency: intp(600)}, false},
+		{"frequency too high", MinerSettings{Frequency: intp(5000)}, true},
+		{"voltage too low", MinerSettings{CoreVoltage: intp(500)}, true},
+		{"fan speed", MinerSettings{FanSpeed: intp(80)}, false},
+		{"fan speed over 100", MinerSettings{FanSpeed: intp(120)}, true},
+		{"fan speed with auto fan", MinerSettings{FanSpeed: intp(80), AutoFanSpeed: boolp(true)}, true},
+		{"auto fan", MinerSettings{AutoFanSpeed: boolp(true)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.settings.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMinerControl(t *testing.T) {
+	var gotMethod, gotPath string
+	var gotBody map[string]int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		gotBody = nil
+		json.NewDecoder(r.Body).Decode(&gotBody)
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	c := NewMinerControl()
+	freq, fan := 650, 70
+	if err := c.UpdateSettings(addr, MinerSettings{Frequency: &freq, FanSpeed: &fan}); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	if gotMethod != http.MethodPatch || gotPath != "/api/system" {
+		t.Errorf("got %s %s, want PATCH /api/system", gotMethod, gotPath)
+	}
+	want := map[string]int{"frequency": 650, "fanspeed": 70, "autofanspeed": 0}
+	if !reflect.DeepEqual(gotBody, want) {
+		t.Errorf("got body %v, want %v", gotBody, want)
+	}
+
+	if err := c.Restart(addr); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if gotMethod != http.MethodPost || gotPath != "/api/system/restart" {
+		t.Errorf("got %s %s, want POST /api/system/restart", gotMethod, gotPath)
+	}
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// Drivers, as stored in storage.Miner.Driver
+const (
+	DriverAxeOS   = "axeos"   // AxeOS and NerdQAxe HTTP API
+	DriverCGMiner = "cgminer" // cgminer TCP API, e.g. Avalon Nano and Lucky Miner
+)
+
+// Driver polls the miners that speak one API
+type Driver interface {
+	// Name is the driver's DriverX constant
+	Name() string
+	// Poll reads a miner's record and readings
+	Poll(ip string) (*Reading, error)
+	// Streams reports whether the miners stream their log, shares and blocks
+	// over AxeOS's /api/ws WebSocket
+	Streams() bool
+}
+
+// Reading is one poll of a miner
+type Reading struct {
+	Miner       *storage.Miner
+	Snapshot    *storage.MinerSnapshot
+	NetworkDiff float64 // 0 if the miner doesn't report it
+	BlockHeight int64   // 0 if the miner doesn't report it
+}
+
+// axeOSDriver polls /api/system/info
+type axeOSDriver struct {
+	client *MinerClient
+}
+
+func (d *axeOSDriver) Name() string  { return DriverAxeOS }
+func (d *axeOSDriver) Streams() bool { return true }
+
+func (d *axeOSDriver) Poll(ip string) (*Reading, error) {
+	info, err := d.client.FetchInfo(ip)
+	if err != nil {
+		return nil, err
+	}
+	return &Reading{
+		Miner:       d.client.ToMiner(ip, info),
+		Snapshot:    d.client.ToSnapshot(ip, info),
+		NetworkDiff: info.NetworkDiff,
+		BlockHeight: info.BlockHeight,
+	}, nil
+}
+
+// poll reads a miner with its driver. Until a miner has answered, every
+// driver is tried in turn and the first to answer is kept for the miner.
+func (c *Collector) poll(ip string) (*Reading, error) {
+	c.minersMu.RLock()
+	var driver Driver
+	if conn, exists := c.miners[ip]; exists {
+		driver = conn.driver
+	}
+	c.minersMu.RUnlock()
+	if driver != nil {
+		return driver.Poll(ip)
+	}
+
+	var firstErr error
+	for _, d := range c.drivers {
+		reading, err := d.Poll(ip)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		c.minersMu.Lock()
+		if conn, exists := c.miners[ip]; exists {
+			conn.driver = d
+		}
+		c.minersMu.Unlock()
+		return reading, nil
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no driver for %s", ip)
+	}
+	return nil, firstErr
+}
+
+// streams reports whether a miner may stream over the WebSocket: false once
+// its driver is known not to
+func (c *Collector) streams(ip string) bool {
+	c.minersMu.RLock()
+	defer c.minersMu.RUnlock()
+	conn, exists := c.miners[ip]
+	return !exists || conn.driver == nil || conn.driver.Streams()
+}
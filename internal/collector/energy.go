@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// energyMaxGap is the longest interval integrated between two power
+	// samples; longer gaps (miner offline, collector stopped) count as no usage.
+	energyMaxGap = 5 * time.Minute
+
+	// energyFlushInterval is how often accumulated kWh are written to storage
+	energyFlushInterval = time.Minute
+)
+
+// energyStore persists measured energy
+type energyStore interface {
+	AddEnergy(minerIP string, kwh, cost float64, at time.Time) error
+}
+
+// EnergyRateFunc returns the electricity rate (currency per kWh) for a
+// miner's energy location at a given time
+type EnergyRateFunc func(location string, at time.Time) float64
+
+// meterState tracks integration state for a single miner
+type meterState struct {
+	lastWatts   float64
+	lastAt      time.Time
+	pending     float64 // kWh not yet written to storage
+	pendingCost float64 // Cost of the pending kWh
+	flushedAt   time.Time
+}
+
+// energyMeter integrates power samples into kWh per miner using the
+// trapezoidal rule, prices each interval at the tariff in effect and
+// periodically flushes the totals to storage.
+type energyMeter struct {
+	store     energyStore
+	mu        sync.Mutex
+	miners    map[string]*meterState
+	rate      EnergyRateFunc
+	locations map[string]string // Miner IP -> energy location
+}
+
+func newEnergyMeter(store energyStore) *energyMeter {
+	return &energyMeter{
+		store:     store,
+		miners:    make(map[string]*meterState),
+		locations: make(map[string]string),
+	}
+}
+
+// setRate sets the function used to price energy (nil = no cost tracking)
+func (m *energyMeter) setRate(rate EnergyRateFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rate = rate
+}
+
+// setLocation sets the energy location used to price a miner's usage
+func (m *energyMeter) setLocation(ip, location string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locations[ip] = location
+}
+
+// record adds a power sample (Watts) taken at the given time
+func (m *energyMeter) record(ip string, watts float64, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.miners[ip]
+	if !ok {
+		m.miners[ip] = &meterState{lastWatts: watts, lastAt: at, flushedAt: at}
+		return
+	}
+
+	if dt := at.Sub(st.lastAt); dt > 0 && dt <= energyMaxGap {
+		kwh := (st.lastWatts + watts) / 2 * dt.Hours() / 1000
+		st.pending += kwh
+		if m.rate != nil {
+			st.pendingCost += kwh * m.rate(m.locations[ip], st.lastAt.Add(dt/2))
+		}
+	}
+	st.lastWatts = watts
+	st.lastAt = at
+
+	if at.Sub(st.flushedAt) >= energyFlushInterval {
+		m.flushLocked(ip, st, at)
+	}
+}
+
+// flush writes all pending energy to storage
+func (m *energyMeter) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for ip, st := range m.miners {
+		m.flushLocked(ip, st, now)
+	}
+}
+
+func (m *energyMeter) flushLocked(ip string, st *meterState, at time.Time) {
+	st.flushedAt = at
+	if st.pending <= 0 {
+		return
+	}
+	if err := m.store.AddEnergy(ip, st.pending, st.pendingCost, at); err != nil {
+		log.Printf("AddEnergy %s failed: %v", ip, err)
+		return // Keep pending and retry on the next flush
+	}
+	st.pending = 0
+	st.pendingCost = 0
+}
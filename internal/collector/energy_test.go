@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type fakeEnergyStore struct {
+	kwh  map[string]float64
+	cost map[string]float64
+}
+
+func (f *fakeEnergyStore) AddEnergy(minerIP string, kwh, cost float64, at time.Time) error {
+	f.kwh[minerIP] += kwh
+	if f.cost != nil {
+		f.cost[minerIP] += cost
+	}
+	return nil
+}
+
+func TestEnergyMeter(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		samples []float64     // Watts
+		step    time.Duration // Interval between samples
+		gapAt   int           // Insert an offline gap before this sample index (0 = none)
+		want    float64       // kWh
+	}{
+		{
+			name:    "constant 100W for one hour",
+			samples: repeatWatts(100, 61),
+			step:    time.Minute,
+			want:    0.1,
+		},
+		{
+			name:    "linear ramp uses trapezoid",
+			samples: []float64{0, 120},
+			step:    time.Hour / 60,
+			want:    0.001, // avg 60W for 1 minute
+		},
+		{
+			name:    "offline gap is not integrated",
+			samples: repeatWatts(60, 4),
+			step:    time.Minute,
+			gapAt:   2,
+			want:    0.002, // two 1-minute intervals at 60W
+		},
+		{
+			name:    "single sample yields nothing",
+			samples: []float64{150},
+			step:    time.Minute,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeEnergyStore{kwh: make(map[string]float64)}
+			meter := newEnergyMeter(store)
+
+			at := start
+			for i, w := range tt.samples {
+				if i > 0 {
+					at = at.Add(tt.step)
+					if tt.gapAt > 0 && i == tt.gapAt {
+						at = at.Add(energyMaxGap)
+					}
+				}
+				meter.record("10.0.0.1", w, at)
+			}
+			meter.flush()
+
+			if got := store.kwh["10.0.0.1"]; math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("measured %.6f kWh, want %.6f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnergyMeterTariffs(t *testing.T) {
+	store := &fakeEnergyStore{kwh: make(map[string]float64), cost: make(map[string]float64)}
+	meter := newEnergyMeter(store)
+	meter.setLocation("10.0.0.1", "garage")
+
+	// 0.10/kWh before 12:00, 0.30/kWh after; garage pays double
+	meter.setRate(func(location string, at time.Time) float64 {
+		rate := 0.10
+		if at.Hour() >= 12 {
+			rate = 0.30
+		}
+		if location == "garage" {
+			rate *= 2
+		}
+		return rate
+	})
+
+	// 1000W from 11:00 to 13:00 in 1-minute steps = 1 kWh at each rate
+	at := time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)
+	for i := 0; i <= 120; i++ {
+		meter.record("10.0.0.1", 1000, at.Add(time.Duration(i)*time.Minute))
+		meter.record("10.0.0.2", 1000, at.Add(time.Duration(i)*time.Minute))
+	}
+	meter.flush()
+
+	if got := store.cost["10.0.0.1"]; math.Abs(got-0.8) > 1e-9 {
+		t.Errorf("garage cost = %.4f, want 0.8000", got)
+	}
+	if got := store.cost["10.0.0.2"]; math.Abs(got-0.4) > 1e-9 {
+		t.Errorf("default location cost = %.4f, want 0.4000", got)
+	}
+}
+
+func repeatWatts(w float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = w
+	}
+	return out
+}
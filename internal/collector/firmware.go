@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"sort"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// FirmwareOutlier is a miner running a different firmware than its model group
+type FirmwareOutlier struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Version  string `json:"version"`
+}
+
+// FirmwareGroup summarizes firmware versions across miners of one device model
+type FirmwareGroup struct {
+	DeviceModel string            `json:"deviceModel"`
+	Expected    string            `json:"expected"` // Most common version in the group
+	Versions    map[string]int    `json:"versions"` // Version -> miner count
+	Miners      int               `json:"miners"`
+	Consistent  bool              `json:"consistent"`
+	Outliers    []FirmwareOutlier `json:"outliers"`
+}
+
+// CheckFirmwareConsistency groups miners by device model and reports the
+// ones whose firmware differs from the most common version in their group.
+// Miners with an unknown version are ignored. Ties pick the highest version
+// so a half-finished OTA round flags the miners still on the old one.
+func CheckFirmwareConsistency(miners []*storage.Miner) []FirmwareGroup {
+	byModel := make(map[string][]*storage.Miner)
+	for _, m := range miners {
+		if m.FirmwareVersion == "" {
+			continue
+		}
+		byModel[m.DeviceModel] = append(byModel[m.DeviceModel], m)
+	}
+
+	groups := make([]FirmwareGroup, 0, len(byModel))
+	for model, members := range byModel {
+		g := FirmwareGroup{
+			DeviceModel: model,
+			Versions:    make(map[string]int),
+			Miners:      len(members),
+			Outliers:    []FirmwareOutlier{},
+		}
+		for _, m := range members {
+			g.Versions[m.FirmwareVersion]++
+		}
+		for v, n := range g.Versions {
+			if n > g.Versions[g.Expected] || (n == g.Versions[g.Expected] && newerVersion(v, g.Expected)) {
+				g.Expected = v
+			}
+		}
+		for _, m := range members {
+			if m.FirmwareVersion != g.Expected {
+				g.Outliers = append(g.Outliers, FirmwareOutlier{IP: m.IP, Hostname: m.Hostname, Version: m.FirmwareVersion})
+			}
+		}
+		g.Consistent = len(g.Outliers) == 0
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].DeviceModel < groups[j].DeviceModel })
+	return groups
+}
+
+// newerVersion reports whether version a is newer than b, comparing numbers
+// (v2.10.0 is newer than v2.9.0). Spellings of the same version are ordered
+// as strings so the pick doesn't depend on map order.
+func newerVersion(a, b string) bool {
+	if c := CompareVersions(a, b); c != 0 {
+		return c > 0
+	}
+	return a > b
+}
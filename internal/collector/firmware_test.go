@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestCheckFirmwareConsistency(t *testing.T) {
+	miners := []*storage.Miner{
+		{IP: "10.0.0.1", Hostname: "gamma-1", DeviceModel: "AxeOS (BM1370)", FirmwareVersion: "v2.5.0"},
+		{IP: "10.0.0.2", Hostname: "gamma-2", DeviceModel: "AxeOS (BM1370)", FirmwareVersion: "v2.5.0"},
+		{IP: "10.0.0.3", Hostname: "gamma-3", DeviceModel: "AxeOS (BM1370)", FirmwareVersion: "v2.4.1"},
+		{IP: "10.0.0.4", Hostname: "qaxe-1", DeviceModel: "NerdQAxe++", FirmwareVersion: "v1.0.30"},
+		{IP: "10.0.0.5", Hostname: "qaxe-2", DeviceModel: "NerdQAxe++", FirmwareVersion: "v1.0.31"},
+		{IP: "10.0.0.6", Hostname: "octaxe", DeviceModel: "NerdOctaxe", FirmwareVersion: ""},
+		{IP: "10.0.0.7", Hostname: "supra-1", DeviceModel: "AxeOS (BM1368)", FirmwareVersion: "v2.9.0"},
+		{IP: "10.0.0.8", Hostname: "supra-2", DeviceModel: "AxeOS (BM1368)", FirmwareVersion: "v2.10.0"},
+	}
+
+	groups := CheckFirmwareConsistency(miners)
+
+	tests := []struct {
+		model        string
+		wantExpected string
+		wantOutliers []string
+	}{
+		{model: "AxeOS (BM1368)", wantExpected: "v2.10.0", wantOutliers: []string{"10.0.0.7"}}, // Compared as numbers
+		{model: "AxeOS (BM1370)", wantExpected: "v2.5.0", wantOutliers: []string{"10.0.0.3"}},
+		{model: "NerdQAxe++", wantExpected: "v1.0.31", wantOutliers: []string{"10.0.0.4"}}, // Tie -> highest
+	}
+
+	if len(groups) != len(tests) {
+		t.Fatalf("expected %d groups (unknown versions ignored), got %d", len(tests), len(groups))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			g := groups[i]
+			if g.DeviceModel != tt.model {
+				t.Fatalf("group %d: got model %q, want %q", i, g.DeviceModel, tt.model)
+			}
+			if g.Expected != tt.wantExpected {
+				t.Errorf("expected version %q, got %q", tt.wantExpected, g.Expected)
+			}
+			if len(g.Outliers) != len(tt.wantOutliers) {
+				t.Fatalf("got %d outliers, want %d", len(g.Outliers), len(tt.wantOutliers))
+			}
+			for j, ip := range tt.wantOutliers {
+				if g.Outliers[j].IP != ip {
+					t.Errorf("outlier %d: got %s, want %s", j, g.Outliers[j].IP, ip)
+				}
+			}
+			if g.Consistent {
+				t.Error("group with outliers reported as consistent")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"sort"
+	"time"
+)
+
+// MinerHealth is the collector's view of one miner's connections
+type MinerHealth struct {
+	IP             string    `json:"ip"`
+	Online         bool      `json:"online"`
+	LastPoll       time.Time `json:"lastPoll,omitempty"` // Last successful poll
+	FailedPolls    int       `json:"failedPolls"`        // Failed polls in a row
+	PollInterval   string    `json:"pollInterval"`       // Current interval, backed off while failing
+	Driver         string    `json:"driver,omitempty"`   // API the miner answered, unset until it has
+	WebSocket      bool      `json:"webSocket"`          // Log stream connected, never for cgminer miners
+	WebSocketSince time.Time `json:"webSocketSince,omitempty"`
+
+	PollLatencyMs       float64   `json:"pollLatencyMs"`         // How long the last successful poll took
+	LastError           string    `json:"lastError,omitempty"`   // Latest failed poll or WebSocket error
+	LastErrorAt         time.Time `json:"lastErrorAt,omitempty"` // Unset without errors
+	WebSocketReconnects int       `json:"webSocketReconnects"`   // Connections after the first
+}
+
+// CollectorHealth reports the collector's goroutines and per-miner connections
+type CollectorHealth struct {
+	Miners          int            `json:"miners"`
+	Online          int            `json:"online"`
+	Pollers         int            `json:"pollers"`         // Running poll loops, one per miner
+	WebSocketLoops  int            `json:"webSocketLoops"`  // Running WebSocket loops, one per miner
+	WebSockets      int            `json:"webSockets"`      // Connected WebSockets
+	QueuedSnapshots int            `json:"queuedSnapshots"` // Snapshots waiting to be written
+	MinerStatus     []*MinerHealth `json:"minerStatus"`     // By IP
+}
+
+// Health returns the state of the collector's goroutines and of every
+// miner's poll loop and WebSocket
+func (c *Collector) Health() *CollectorHealth {
+	status := c.GetMinerStatus()
+
+	c.minersMu.RLock()
+	health := &CollectorHealth{
+		Miners:         len(c.miners),
+		Pollers:        int(c.pollers.Load()),
+		WebSocketLoops: int(c.wsReaders.Load()),
+		MinerStatus:    make([]*MinerHealth, 0, len(c.miners)),
+	}
+	for ip, conn := range c.miners {
+		m := &MinerHealth{
+			IP:           ip,
+			Online:       status[ip],
+			LastPoll:     conn.lastSeen,
+			FailedPolls:  conn.failures,
+			PollInterval: backoffInterval(c.minerPollInterval(ip), c.maxPollInterval, c.backoffAfter, conn.failures).String(),
+			WebSocket:    conn.wsConn != nil,
+
+			PollLatencyMs: float64(conn.pollLatency.Microseconds()) / 1000,
+			LastError:     conn.lastError,
+			LastErrorAt:   conn.lastErrorAt,
+		}
+		if conn.driver != nil {
+			m.Driver = conn.driver.Name()
+		}
+		if conn.wsConnects > 1 {
+			m.WebSocketReconnects = conn.wsConnects - 1
+		}
+		if m.WebSocket {
+			m.WebSocketSince = conn.wsSince
+			health.WebSockets++
+		}
+		if m.Online {
+			health.Online++
+		}
+		health.MinerStatus = append(health.MinerStatus, m)
+	}
+	c.minersMu.RUnlock()
+
+	health.QueuedSnapshots = c.snapshots.queued()
+	sort.Slice(health.MinerStatus, func(i, j int) bool {
+		return health.MinerStatus[i].IP < health.MinerStatus[j].IP
+	})
+	return health
+}
@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHealth(t *testing.T) {
+	now := time.Now()
+	c := &Collector{
+		miners: map[string]*minerConn{
+			"10.0.0.2": {lastSeen: now, wsConn: &websocket.Conn{}, wsSince: now, wsConnects: 3, pollLatency: 1500 * time.Microsecond},
+			"10.0.0.1": {lastSeen: now.Add(-time.Hour), failures: 4, lastError: "timeout", lastErrorAt: now},
+		},
+		pollIntervals: make(map[string]time.Duration),
+		snapshots:     newSnapshotQueue(&fakeSnapshotStore{}),
+	}
+	c.SetPollInterval(2*time.Second, 3, time.Minute)
+	c.pollers.Add(2)
+
+	h := c.Health()
+	if h.Miners != 2 || h.Online != 1 || h.Pollers != 2 || h.WebSockets != 1 {
+		t.Fatalf("health = %+v, want 2 miners, 1 online, 2 pollers and 1 WebSocket", h)
+	}
+	offline, online := h.MinerStatus[0], h.MinerStatus[1]
+	if offline.IP != "10.0.0.1" || offline.Online || offline.FailedPolls != 4 || offline.PollInterval != "8s" || offline.WebSocket {
+		t.Errorf("offline miner = %+v, want 4 failed polls backed off to 8s", offline)
+	}
+	if offline.LastError != "timeout" || !offline.LastErrorAt.Equal(now) || offline.WebSocketReconnects != 0 {
+		t.Errorf("offline miner = %+v, want its last error and no reconnects", offline)
+	}
+	if online.IP != "10.0.0.2" || !online.Online || !online.WebSocket || !online.WebSocketSince.Equal(now) {
+		t.Errorf("online miner = %+v, want online with its WebSocket connected", online)
+	}
+	if online.PollLatencyMs != 1.5 || online.WebSocketReconnects != 2 {
+		t.Errorf("online miner = %+v, want 1.5ms polls and 2 reconnects", online)
+	}
+}
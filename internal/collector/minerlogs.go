@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const (
+	// minerLogFlushInterval is how often captured lines are written to
+	// storage when persistence is on
+	minerLogFlushInterval = 5 * time.Second
+
+	// maxPendingLogLines bounds the lines awaiting a write while storage is
+	// failing; beyond it the oldest are dropped
+	maxPendingLogLines = 10000
+)
+
+// ansiEscape matches the terminal color codes in ESP-IDF log output
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// minerLogStore persists captured log lines
+type minerLogStore interface {
+	InsertMinerLogs(lines []*storage.MinerLogLine) error
+	GetMinerLogs(minerIP string, limit int) ([]*storage.MinerLogLine, error)
+}
+
+// minerLogs keeps the last lines each miner logged over its WebSocket in
+// memory and, when persistence is on, writes all of them to storage in
+// batches
+type minerLogs struct {
+	store     minerLogStore
+	mu        sync.Mutex
+	size      int // Lines kept per miner (0 = none)
+	persist   bool
+	recent    map[string][]*storage.MinerLogLine
+	pending   []*storage.MinerLogLine // Lines not yet written to storage
+	flushedAt time.Time
+}
+
+func newMinerLogs(store minerLogStore) *minerLogs {
+	return &minerLogs{
+		store:  store,
+		recent: make(map[string][]*storage.MinerLogLine),
+	}
+}
+
+// configure sets how many lines are kept per miner and whether they are
+// stored
+func (m *minerLogs) configure(size int, persist bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.size = size
+	m.persist = persist
+	for ip, lines := range m.recent {
+		if len(lines) > size {
+			m.recent[ip] = lines[len(lines)-size:]
+		}
+	}
+}
+
+// record captures the lines of a WebSocket message, without color codes,
+// and returns them. Nothing is captured when capture is off.
+func (m *minerLogs) record(ip, message string, at time.Time) []*storage.MinerLogLine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.size <= 0 && !m.persist {
+		return nil
+	}
+
+	var lines []*storage.MinerLogLine
+	for _, text := range strings.Split(ansiEscape.ReplaceAllString(message, ""), "\n") {
+		if text = strings.TrimRight(text, "\r\t "); text != "" {
+			lines = append(lines, &storage.MinerLogLine{MinerIP: ip, Timestamp: at, Line: text})
+		}
+	}
+	if m.size > 0 {
+		recent := append(m.recent[ip], lines...)
+		if len(recent) > m.size {
+			recent = recent[len(recent)-m.size:]
+		}
+		m.recent[ip] = recent
+	}
+	if m.persist {
+		m.pending = append(m.pending, lines...)
+		if at.Sub(m.flushedAt) >= minerLogFlushInterval {
+			m.flushLocked(at)
+		}
+	}
+	return lines
+}
+
+// last returns a miner's last lines, oldest first: from storage when lines
+// are persisted, so they survive restarts, and otherwise from memory
+func (m *minerLogs) last(ip string, limit int) ([]*storage.MinerLogLine, error) {
+	m.mu.Lock()
+	if m.persist {
+		m.flushLocked(time.Now())
+		m.mu.Unlock()
+		return m.store.GetMinerLogs(ip, limit)
+	}
+	defer m.mu.Unlock()
+
+	recent := m.recent[ip]
+	if len(recent) > limit {
+		recent = recent[len(recent)-limit:]
+	}
+	return append([]*storage.MinerLogLine(nil), recent...), nil
+}
+
+// forget drops the lines kept in memory for a miner
+func (m *minerLogs) forget(ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.recent, ip)
+}
+
+// flush writes all pending lines to storage
+func (m *minerLogs) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushLocked(time.Now())
+}
+
+func (m *minerLogs) flushLocked(at time.Time) {
+	m.flushedAt = at
+	if len(m.pending) == 0 {
+		return
+	}
+	if err := m.store.InsertMinerLogs(m.pending); err != nil {
+		log.Printf("InsertMinerLogs failed for %d lines: %v", len(m.pending), err)
+		if len(m.pending) > maxPendingLogLines {
+			m.pending = m.pending[len(m.pending)-maxPendingLogLines:]
+		}
+		return // Keep pending and retry on the next flush
+	}
+	m.pending = nil
+}
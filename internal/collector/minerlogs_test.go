@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+type fakeMinerLogStore struct {
+	lines []*storage.MinerLogLine
+}
+
+func (f *fakeMinerLogStore) InsertMinerLogs(lines []*storage.MinerLogLine) error {
+	f.lines = append(f.lines, lines...)
+	return nil
+}
+
+func (f *fakeMinerLogStore) GetMinerLogs(minerIP string, limit int) ([]*storage.MinerLogLine, error) {
+	var lines []*storage.MinerLogLine
+	for _, l := range f.lines {
+		if l.MinerIP == minerIP {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines, nil
+}
+
+func TestMinerLogs(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("off by default", func(t *testing.T) {
+		logs := newMinerLogs(&fakeMinerLogStore{})
+		if lines := logs.record("10.0.0.1", "I (100) asic: hello", start); lines != nil {
+			t.Errorf("expected nothing captured, got %d lines", len(lines))
+		}
+	})
+
+	t.Run("ring buffer", func(t *testing.T) {
+		logs := newMinerLogs(&fakeMinerLogStore{})
+		logs.configure(3, false)
+
+		lines := logs.record("10.0.0.1", "\x1b[0;32mI (100) asic: one\x1b[0m\r\nI (101) asic: two\n", start)
+		if len(lines) != 2 || lines[0].Line != "I (100) asic: one" || lines[1].Line != "I (101) asic: two" {
+			t.Fatalf("unexpected lines: %+v", lines)
+		}
+		logs.record("10.0.0.1", "I (102) asic: three", start)
+		logs.record("10.0.0.1", "I (103) asic: four", start)
+		logs.record("10.0.0.2", "I (104) other miner", start)
+
+		last, err := logs.last("10.0.0.1", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(last) != 3 || last[0].Line != "I (101) asic: two" || last[2].Line != "I (103) asic: four" {
+			t.Errorf("expected the last 3 lines, oldest first, got %+v", last)
+		}
+		if last, _ := logs.last("10.0.0.1", 1); len(last) != 1 || last[0].Line != "I (103) asic: four" {
+			t.Errorf("expected only the newest line, got %+v", last)
+		}
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		store := &fakeMinerLogStore{}
+		logs := newMinerLogs(store)
+		logs.configure(0, true)
+
+		logs.record("10.0.0.1", "I (100) asic: one", start)
+		logs.record("10.0.0.1", "I (101) asic: two", start.Add(time.Second))
+		if len(store.lines) != 1 {
+			t.Errorf("expected lines within the flush interval to be batched, %d were written", len(store.lines))
+		}
+
+		last, err := logs.last("10.0.0.1", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(last) != 2 {
+			t.Errorf("expected pending lines to be written before reading, got %d lines", len(last))
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"math"
+	"sort"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// versionRollingMask covers the BIP320 version bits ASICs are allowed to roll
+const versionRollingMask = 0x1FFFE000
+
+// VersionBitUsage is the fraction of shares with a given rolled version bit set.
+// Healthy version rolling keeps every ratio near 0.5.
+type VersionBitUsage struct {
+	Bit   int     `json:"bit"`
+	Ratio float64 `json:"ratio"`
+}
+
+// AsicNonceStats summarizes the nonce and version distribution of one ASIC chip
+type AsicNonceStats struct {
+	AsicNum          int               `json:"asicNum"`
+	Shares           int               `json:"shares"`
+	NonceBuckets     []int             `json:"nonceBuckets"`     // Share count per equal slice of the 32-bit nonce range
+	ChiSquare        float64           `json:"chiSquare"`        // Goodness of fit against a uniform distribution
+	MaxBucketRatio   float64           `json:"maxBucketRatio"`   // Fullest bucket relative to the expected count
+	EmptyBuckets     int               `json:"emptyBuckets"`     // Buckets with no shares at all
+	Skewed           bool              `json:"skewed"`           // Distribution is very unlikely to be uniform
+	DistinctVersions int               `json:"distinctVersions"` // Number of different block versions seen
+	VersionBits      []VersionBitUsage `json:"versionBits"`
+}
+
+// AnalyzeNonces groups shares by ASIC and computes nonce histograms and
+// version-rolling statistics. Failing chips tend to produce nonces from only
+// part of the range, which shows up as a high chi-square and empty buckets.
+// buckets must be a power of two between 2 and 256.
+func AnalyzeNonces(shares []*storage.Share, buckets int) []AsicNonceStats {
+	if buckets < 2 || buckets > 256 || buckets&(buckets-1) != 0 {
+		buckets = 16
+	}
+	shift := 32 - uint(math.Log2(float64(buckets)))
+
+	type asicData struct {
+		nonceBuckets []int
+		versions     map[uint32]bool
+		bitCounts    map[int]int
+		shares       int
+	}
+	byAsic := make(map[int]*asicData)
+
+	for _, sh := range shares {
+		d, ok := byAsic[sh.AsicNum]
+		if !ok {
+			d = &asicData{
+				nonceBuckets: make([]int, buckets),
+				versions:     make(map[uint32]bool),
+				bitCounts:    make(map[int]int),
+			}
+			byAsic[sh.AsicNum] = d
+		}
+		d.shares++
+		d.nonceBuckets[sh.Nonce>>shift]++
+		d.versions[sh.Version] = true
+		for bit := 13; bit <= 28; bit++ {
+			if sh.Version&(1<<bit) != 0 {
+				d.bitCounts[bit]++
+			}
+		}
+	}
+
+	result := make([]AsicNonceStats, 0, len(byAsic))
+	for asic, d := range byAsic {
+		expected := float64(d.shares) / float64(buckets)
+		stats := AsicNonceStats{
+			AsicNum:          asic,
+			Shares:           d.shares,
+			NonceBuckets:     d.nonceBuckets,
+			DistinctVersions: len(d.versions),
+		}
+
+		maxCount := 0
+		for _, count := range d.nonceBuckets {
+			diff := float64(count) - expected
+			stats.ChiSquare += diff * diff / expected
+			if count > maxCount {
+				maxCount = count
+			}
+			if count == 0 {
+				stats.EmptyBuckets++
+			}
+		}
+		stats.MaxBucketRatio = float64(maxCount) / expected
+
+		// Only judge skew with enough samples (at least 5 expected per bucket)
+		if expected >= 5 {
+			stats.Skewed = stats.ChiSquare > chiSquareCritical(buckets-1)
+		}
+
+		for bit := 13; bit <= 28; bit++ {
+			if versionRollingMask&(1<<bit) == 0 {
+				continue
+			}
+			stats.VersionBits = append(stats.VersionBits, VersionBitUsage{
+				Bit:   bit,
+				Ratio: float64(d.bitCounts[bit]) / float64(d.shares),
+			})
+		}
+
+		result = append(result, stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].AsicNum < result[j].AsicNum })
+	return result
+}
+
+// chiSquareCritical approximates the chi-square critical value at p = 0.001
+// using the Wilson-Hilferty transformation
+func chiSquareCritical(df int) float64 {
+	const z = 3.09 // 99.9th percentile of the standard normal
+	k := float64(df)
+	t := 1 - 2/(9*k) + z*math.Sqrt(2/(9*k))
+	return k * t * t * t
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestAnalyzeNonces(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	var shares []*storage.Share
+	for i := 0; i < 2000; i++ {
+		// ASIC 0 is healthy: nonces across the full range
+		shares = append(shares, &storage.Share{AsicNum: 0, Nonce: rng.Uint32(), Version: rng.Uint32() & versionRollingMask})
+		// ASIC 1 is failing: nonces only in the lower quarter of the range
+		shares = append(shares, &storage.Share{AsicNum: 1, Nonce: rng.Uint32() >> 2, Version: 0x20000000})
+	}
+
+	stats := AnalyzeNonces(shares, 16)
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 ASICs, got %d", len(stats))
+	}
+
+	healthy, failing := stats[0], stats[1]
+	if healthy.AsicNum != 0 || failing.AsicNum != 1 {
+		t.Fatalf("expected results sorted by ASIC, got %d and %d", healthy.AsicNum, failing.AsicNum)
+	}
+
+	if healthy.Shares != 2000 {
+		t.Errorf("expected 2000 shares for ASIC 0, got %d", healthy.Shares)
+	}
+	if healthy.Skewed {
+		t.Errorf("expected uniform nonces for ASIC 0, chi-square %.1f", healthy.ChiSquare)
+	}
+	if healthy.EmptyBuckets != 0 {
+		t.Errorf("expected no empty buckets for ASIC 0, got %d", healthy.EmptyBuckets)
+	}
+
+	if !failing.Skewed {
+		t.Errorf("expected skewed nonces for ASIC 1, chi-square %.1f", failing.ChiSquare)
+	}
+	if failing.EmptyBuckets != 12 {
+		t.Errorf("expected 12 empty buckets for ASIC 1, got %d", failing.EmptyBuckets)
+	}
+	if failing.DistinctVersions != 1 {
+		t.Errorf("expected 1 distinct version for ASIC 1, got %d", failing.DistinctVersions)
+	}
+
+	for _, vb := range healthy.VersionBits {
+		if vb.Ratio < 0.4 || vb.Ratio > 0.6 {
+			t.Errorf("expected version bit %d ratio near 0.5, got %.2f", vb.Bit, vb.Ratio)
+		}
+	}
+}
+
+func TestAnalyzeNoncesInvalidBuckets(t *testing.T) {
+	shares := []*storage.Share{{AsicNum: 0, Nonce: 0xFFFFFFFF}}
+
+	stats := AnalyzeNonces(shares, 10) // not a power of two, falls back to 16
+	if len(stats[0].NonceBuckets) != 16 {
+		t.Errorf("expected 16 buckets, got %d", len(stats[0].NonceBuckets))
+	}
+	if stats[0].NonceBuckets[15] != 1 {
+		t.Errorf("expected max nonce in the last bucket")
+	}
+}
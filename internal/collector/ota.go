@@ -0,0 +1,310 @@
+package collector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// Firmware image kinds and the endpoints that flash them
+const (
+	ImageFirmware = "firmware" // esp-miner.bin, the miner restarts after flashing
+	ImageWWW      = "www"      // www.bin, the web interface
+)
+
+var imageEndpoints = map[string]string{
+	ImageFirmware: "/api/system/OTA",
+	ImageWWW:      "/api/system/OTAWWW",
+}
+
+// espImageMagic is the first byte of every ESP32 application image
+const espImageMagic = 0xE9
+
+// otaTimeout bounds a single upload; flashing over WiFi takes a minute or two
+const otaTimeout = 5 * time.Minute
+
+// How long a flashed miner gets to go down, and then to come back, and how
+// often it's polled meanwhile. Variables so tests don't wait minutes.
+var (
+	rebootDelay   = 10 * time.Second
+	rebootTimeout = 3 * time.Minute
+	rebootPoll    = 5 * time.Second
+)
+
+// CheckFirmwareImage rejects files that aren't ESP32 application images, so
+// a www.bin or a download error page isn't flashed as firmware
+func CheckFirmwareImage(header []byte) error {
+	if len(header) == 0 || header[0] != espImageMagic {
+		return fmt.Errorf("not an ESP32 firmware image (esp-miner.bin)")
+	}
+	return nil
+}
+
+// countingReader reports the bytes read so far
+type countingReader struct {
+	r        io.Reader
+	n        int64
+	progress func(sent int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if n > 0 && c.progress != nil {
+		c.progress(c.n)
+	}
+	return n, err
+}
+
+// Flash uploads a firmware or www image to the miner's OTA endpoint.
+// progress, if set, is called with the bytes sent so far.
+func (c *MinerControl) Flash(ip, kind string, image io.Reader, size int64, progress func(sent int64)) error {
+	path, ok := imageEndpoints[kind]
+	if !ok {
+		return fmt.Errorf("unknown image kind %q", kind)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s%s", ip, path), &countingReader{r: image, progress: progress})
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.otaClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", kind, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("miner rejected %s: status %d %s", kind, resp.StatusCode, body)
+	}
+	return nil
+}
+
+// OTA miner states
+const (
+	OTAPending   = "pending"
+	OTAUploading = "uploading"
+	OTARebooting = "rebooting"
+	OTADone      = "done"
+	OTAFailed    = "failed"
+	OTASkipped   = "skipped" // Not attempted because an earlier miner failed
+)
+
+// OTAImage is a firmware or www image on disk to flash
+type OTAImage struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	path string
+}
+
+// NewOTAImage describes an image file of the given kind
+func NewOTAImage(kind, name, path string) (*OTAImage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &OTAImage{Kind: kind, Name: name, Size: info.Size(), path: path}, nil
+}
+
+// OTAMinerStatus is the progress of one miner in a rollout
+type OTAMinerStatus struct {
+	IP          string     `json:"ip"`
+	Hostname    string     `json:"hostname"`
+	State       string     `json:"state"`
+	Image       string     `json:"image,omitempty"` // Kind being uploaded
+	Sent        int64      `json:"sent"`            // Bytes of all images sent
+	Total       int64      `json:"total"`
+	FromVersion string     `json:"fromVersion"`
+	ToVersion   string     `json:"toVersion,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// OTAStatus is a rollout's progress
+type OTAStatus struct {
+	ID        string            `json:"id"`
+	State     string            `json:"state"` // "running", "done" or "failed"
+	Images    []*OTAImage       `json:"images"`
+	Miners    []*OTAMinerStatus `json:"miners"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// OTARollout flashes images to miners one at a time, waiting for each to
+// come back before moving on and stopping at the first failure, so a bad
+// image never takes down the whole fleet
+type OTARollout struct {
+	OTAStatus
+
+	mu sync.Mutex
+}
+
+// NewOTARollout creates a rollout of images to miners, in order. Firmware is
+// flashed last, since it restarts the miner.
+func NewOTARollout(images []*OTAImage, miners []*OTAMinerStatus) *OTARollout {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	ordered := make([]*OTAImage, 0, len(images))
+	for _, kind := range []string{ImageWWW, ImageFirmware} {
+		for _, img := range images {
+			if img.Kind == kind {
+				ordered = append(ordered, img)
+			}
+		}
+	}
+
+	var total int64
+	for _, img := range ordered {
+		total += img.Size
+	}
+	for _, m := range miners {
+		m.State = OTAPending
+		m.Total = total
+	}
+
+	return &OTARollout{OTAStatus: OTAStatus{
+		ID:        hex.EncodeToString(id),
+		State:     "running",
+		Images:    ordered,
+		Miners:    miners,
+		CreatedAt: time.Now(),
+	}}
+}
+
+// Status returns a copy of the rollout that's safe to encode while it runs
+func (r *OTARollout) Status() OTAStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.OTAStatus
+	status.Miners = make([]*OTAMinerStatus, len(r.Miners))
+	for i, m := range r.Miners {
+		copied := *m
+		status.Miners[i] = &copied
+	}
+	return status
+}
+
+// Running reports whether the rollout hasn't finished
+func (r *OTARollout) Running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.State == "running"
+}
+
+// update changes a miner's status under the rollout's lock
+func (r *OTARollout) update(m *OTAMinerStatus, fn func(m *OTAMinerStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(m)
+}
+
+// RunOTA flashes the rollout's images to its miners and records the version
+// each one comes back with. It blocks until the rollout ends.
+func (c *Collector) RunOTA(rollout *OTARollout, control *MinerControl) {
+	failed := false
+	for _, m := range rollout.Miners {
+		if failed {
+			rollout.update(m, func(m *OTAMinerStatus) { m.State = OTASkipped })
+			continue
+		}
+
+		if err := c.flashMiner(rollout, m, control); err != nil {
+			log.Printf("OTA update of %s failed: %v", m.IP, err)
+			now := time.Now()
+			rollout.update(m, func(m *OTAMinerStatus) {
+				m.State = OTAFailed
+				m.Error = err.Error()
+				m.FinishedAt = &now
+			})
+			failed = true
+			continue
+		}
+	}
+
+	rollout.mu.Lock()
+	rollout.State = "done"
+	if failed {
+		rollout.State = "failed"
+	}
+	rollout.mu.Unlock()
+	log.Printf("OTA rollout %s %s", rollout.ID, rollout.Status().State)
+}
+
+// flashMiner flashes every image to one miner and waits for it to come back
+func (c *Collector) flashMiner(rollout *OTARollout, m *OTAMinerStatus, control *MinerControl) error {
+	now := time.Now()
+	rollout.update(m, func(m *OTAMinerStatus) {
+		m.State = OTAUploading
+		m.StartedAt = &now
+	})
+	log.Printf("OTA updating %s (%s) from %s", m.IP, m.Hostname, m.FromVersion)
+
+	var done int64
+	for _, img := range rollout.Images {
+		f, err := os.Open(img.path)
+		if err != nil {
+			return err
+		}
+		rollout.update(m, func(m *OTAMinerStatus) { m.Image = img.Kind })
+		err = control.Flash(m.IP, img.Kind, f, img.Size, func(sent int64) {
+			rollout.update(m, func(m *OTAMinerStatus) { m.Sent = done + sent })
+		})
+		f.Close()
+		if err != nil {
+			return err
+		}
+		done += img.Size
+	}
+
+	rollout.update(m, func(m *OTAMinerStatus) {
+		m.State = OTARebooting
+		m.Image = ""
+	})
+	miner, err := c.waitForMiner(m.IP)
+	if err != nil {
+		return err
+	}
+
+	finished := time.Now()
+	rollout.update(m, func(m *OTAMinerStatus) {
+		m.State = OTADone
+		m.ToVersion = miner.FirmwareVersion
+		m.FinishedAt = &finished
+	})
+	log.Printf("OTA updated %s (%s) to %s", m.IP, m.Hostname, miner.FirmwareVersion)
+	return nil
+}
+
+// waitForMiner waits for a flashed miner to restart and answer again, then
+// records its new firmware version
+func (c *Collector) waitForMiner(ip string) (*storage.Miner, error) {
+	// Give the firmware time to go down before polling
+	time.Sleep(rebootDelay)
+
+	deadline := time.Now().Add(rebootTimeout)
+	for {
+		miner, err := c.RefreshMiner(ip)
+		if err == nil {
+			return miner, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("miner didn't come back within %s: %w", rebootTimeout, err)
+		}
+		time.Sleep(rebootPoll)
+	}
+}
@@ -0,0 +1,221 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestFlash(t *testing.T) {
+	var gotPath, gotType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotType = r.URL.Path, r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	image := []byte{espImageMagic, 1, 2, 3}
+	var sent int64
+	c := NewMinerControl()
+	if err := c.Flash(addr, ImageFirmware, bytes.NewReader(image), int64(len(image)), func(n int64) { sent = n }); err != nil {
+		t.Fatalf("Flash failed: %v", err)
+	}
+	if gotPath != "/api/system/OTA" || gotType != "application/octet-stream" {
+		t.Errorf("got %s as %s, want /api/system/OTA as application/octet-stream", gotPath, gotType)
+	}
+	if !bytes.Equal(gotBody, image) || sent != int64(len(image)) {
+		t.Errorf("got body %v with %d bytes reported, want %v", gotBody, sent, image)
+	}
+
+	if err := c.Flash(addr, ImageWWW, strings.NewReader("www"), 3, nil); err != nil || gotPath != "/api/system/OTAWWW" {
+		t.Errorf("www image went to %s: %v", gotPath, err)
+	}
+	if err := c.Flash(addr, "bootloader", strings.NewReader(""), 0, nil); err == nil {
+		t.Error("expected an unknown image kind to be rejected")
+	}
+}
+
+func TestCheckFirmwareImage(t *testing.T) {
+	if err := CheckFirmwareImage([]byte{espImageMagic}); err != nil {
+		t.Errorf("ESP32 image rejected: %v", err)
+	}
+	if err := CheckFirmwareImage([]byte("<html>")); err == nil {
+		t.Error("expected an HTML page to be rejected")
+	}
+}
+
+func TestNewOTARollout(t *testing.T) {
+	images := []*OTAImage{{Kind: ImageFirmware, Size: 100}, {Kind: ImageWWW, Size: 50}}
+	rollout := NewOTARollout(images, []*OTAMinerStatus{{IP: "192.168.1.50"}})
+
+	if rollout.Images[0].Kind != ImageWWW {
+		t.Error("expected the www image before the firmware, which restarts the miner")
+	}
+	status := rollout.Status()
+	if status.State != "running" || status.Miners[0].State != OTAPending || status.Miners[0].Total != 150 {
+		t.Errorf("unexpected status: %+v %+v", status, status.Miners[0])
+	}
+}
+
+// fakeOTAMiner is an AxeOS miner that takes OTA uploads. Flashing firmware
+// takes it down for reboots polls, after which it reports newVersion; with
+// reject set, firmware uploads fail.
+type fakeOTAMiner struct {
+	mu         sync.Mutex
+	version    string
+	newVersion string
+	reboots    int
+	reject     bool
+	flashed    []string
+}
+
+func (m *fakeOTAMiner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/system/OTAWWW", "/api/system/OTA":
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/api/system/OTA" && m.reject {
+			http.Error(w, "bad image", http.StatusInternalServerError)
+			return
+		}
+		m.flashed = append(m.flashed, string(body))
+		if r.URL.Path == "/api/system/OTA" {
+			m.version = m.newVersion
+			m.reboots = 2
+		}
+	case "/api/system/info":
+		if m.reboots > 0 {
+			m.reboots--
+			http.Error(w, "rebooting", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"hostname": "bitaxe", "version": m.version})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// startFakeOTAMiner serves miner and returns its address
+func startFakeOTAMiner(t *testing.T, miner *fakeOTAMiner) string {
+	t.Helper()
+	srv := httptest.NewServer(miner)
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// otaCollector returns a collector polling AxeOS miners into a temporary
+// store, with reboot waits shortened to milliseconds
+func otaCollector(t *testing.T) *Collector {
+	t.Helper()
+	delay, timeout, poll := rebootDelay, rebootTimeout, rebootPoll
+	rebootDelay, rebootTimeout, rebootPoll = 0, 200*time.Millisecond, time.Millisecond
+	t.Cleanup(func() { rebootDelay, rebootTimeout, rebootPoll = delay, timeout, poll })
+
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	c := NewCollector(store, nil)
+	c.drivers = c.drivers[:1]
+	t.Cleanup(func() {
+		c.Stop()
+		store.Close()
+	})
+	return c
+}
+
+// otaImage writes an image of the given kind to a temporary file
+func otaImage(t *testing.T, kind, content string) *OTAImage {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), kind+".bin")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	img, err := NewOTAImage(kind, kind+".bin", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestRunOTA(t *testing.T) {
+	c := otaCollector(t)
+	first := &fakeOTAMiner{version: "v2.9.0", newVersion: "v2.10.0"}
+	second := &fakeOTAMiner{version: "v2.9.0", newVersion: "v2.10.0"}
+	images := []*OTAImage{otaImage(t, ImageFirmware, "firmware"), otaImage(t, ImageWWW, "www")}
+	rollout := NewOTARollout(images, []*OTAMinerStatus{
+		{IP: startFakeOTAMiner(t, first), FromVersion: "v2.9.0"},
+		{IP: startFakeOTAMiner(t, second), FromVersion: "v2.9.0"},
+	})
+
+	c.RunOTA(rollout, NewMinerControl())
+	status := rollout.Status()
+	if status.State != "done" {
+		t.Fatalf("expected the rollout done, got %q", status.State)
+	}
+	for i, miner := range []*fakeOTAMiner{first, second} {
+		m := status.Miners[i]
+		if m.State != OTADone || m.ToVersion != "v2.10.0" || m.Sent != m.Total || m.Total != 11 || m.Error != "" || m.FinishedAt == nil {
+			t.Errorf("unexpected miner status %+v", m)
+		}
+		if strings.Join(miner.flashed, ",") != "www,firmware" {
+			t.Errorf("expected www then firmware flashed, got %v", miner.flashed)
+		}
+	}
+
+	stored, err := c.storage.GetMiners()
+	if err != nil || len(stored) != 2 || stored[0].FirmwareVersion != "v2.10.0" {
+		t.Errorf("expected the new versions stored, got %v: %v", stored, err)
+	}
+}
+
+func TestRunOTAStopsAtFailure(t *testing.T) {
+	c := otaCollector(t)
+	rejecting := &fakeOTAMiner{version: "v2.9.0", reject: true}
+	untouched := &fakeOTAMiner{version: "v2.9.0", newVersion: "v2.10.0"}
+	rollout := NewOTARollout([]*OTAImage{otaImage(t, ImageFirmware, "firmware")}, []*OTAMinerStatus{
+		{IP: startFakeOTAMiner(t, rejecting)},
+		{IP: startFakeOTAMiner(t, untouched)},
+	})
+
+	c.RunOTA(rollout, NewMinerControl())
+	status := rollout.Status()
+	if status.State != "failed" {
+		t.Fatalf("expected the rollout failed, got %q", status.State)
+	}
+	if m := status.Miners[0]; m.State != OTAFailed || !strings.Contains(m.Error, "status 500 bad image") {
+		t.Errorf("expected the rejected upload to fail the miner, got %+v", m)
+	}
+	if m := status.Miners[1]; m.State != OTASkipped || len(untouched.flashed) != 0 {
+		t.Errorf("expected the next miner skipped, got %+v and %v flashed", m, untouched.flashed)
+	}
+}
+
+func TestWaitForMiner(t *testing.T) {
+	c := otaCollector(t)
+	miner := &fakeOTAMiner{version: "v2.10.0", reboots: 3}
+	if m, err := c.waitForMiner(startFakeOTAMiner(t, miner)); err != nil || m.FirmwareVersion != "v2.10.0" {
+		t.Errorf("expected the miner back on v2.10.0, got %+v: %v", m, err)
+	}
+
+	miner.mu.Lock()
+	miner.reboots = 1 << 30
+	miner.mu.Unlock()
+	if _, err := c.waitForMiner(startFakeOTAMiner(t, miner)); err == nil || !strings.Contains(err.Error(), "didn't come back within 200ms") {
+		t.Errorf("expected a miner that stays down to time out, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"log"
+	"time"
+)
+
+// SetPollInterval sets how often miners are polled. Miners whose polls fail
+// backoffAfter times in a row are polled half as often after each further
+// failure, up to maxInterval, and at full speed again once they answer.
+// A backoffAfter of 0 never backs off.
+func (c *Collector) SetPollInterval(interval time.Duration, backoffAfter int, maxInterval time.Duration) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	c.pollInterval = interval
+	c.backoffAfter = backoffAfter
+	c.maxPollInterval = maxInterval
+}
+
+// SetMinerPollInterval overrides the poll interval of one miner. A zero
+// interval uses the global one.
+func (c *Collector) SetMinerPollInterval(ip string, interval time.Duration) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	if interval <= 0 {
+		delete(c.pollIntervals, ip)
+		return
+	}
+	c.pollIntervals[ip] = interval
+}
+
+// minerPollInterval returns a miner's poll interval when it is answering.
+// The caller must hold minersMu.
+func (c *Collector) minerPollInterval(ip string) time.Duration {
+	if interval, ok := c.pollIntervals[ip]; ok {
+		return interval
+	}
+	return c.pollInterval
+}
+
+// nextPollDelay returns how long to wait before polling a miner again
+func (c *Collector) nextPollDelay(ip string) time.Duration {
+	c.minersMu.RLock()
+	defer c.minersMu.RUnlock()
+
+	failures := 0
+	if conn, exists := c.miners[ip]; exists {
+		failures = conn.failures
+	}
+	return backoffInterval(c.minerPollInterval(ip), c.maxPollInterval, c.backoffAfter, failures)
+}
+
+// recordPoll counts a miner's failed polls in a row, logging when it starts
+// being backed off and when it recovers, and keeps the latency of successful
+// polls and the error of failed ones
+func (c *Collector) recordPoll(ip string, latency time.Duration, err error) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+
+	conn, exists := c.miners[ip]
+	if !exists {
+		return
+	}
+	if err == nil {
+		conn.pollLatency = latency
+		if c.backoffAfter > 0 && conn.failures >= c.backoffAfter {
+			log.Printf("Miner %s is answering again, polling every %s", ip, c.minerPollInterval(ip))
+		}
+		conn.failures = 0
+		return
+	}
+	conn.failures++
+	conn.lastError, conn.lastErrorAt = err.Error(), time.Now()
+	if conn.failures == c.backoffAfter {
+		log.Printf("Miner %s failed %d polls in a row, backing off", ip, conn.failures)
+	}
+}
+
+// backoffInterval returns the interval between polls after failures failed
+// polls in a row: interval until backoffAfter failures, then doubling with
+// each further failure, capped at maxInterval
+func backoffInterval(interval, maxInterval time.Duration, backoffAfter, failures int) time.Duration {
+	if backoffAfter <= 0 || failures < backoffAfter || maxInterval <= interval {
+		return interval
+	}
+	delay := interval
+	for i := backoffAfter; i <= failures && delay < maxInterval; i++ {
+		delay *= 2
+	}
+	if delay > maxInterval {
+		delay = maxInterval
+	}
+	return delay
+}
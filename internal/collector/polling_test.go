@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffInterval(t *testing.T) {
+	const interval, maxInterval = 2 * time.Second, 60 * time.Second
+
+	tests := []struct {
+		backoffAfter int
+		failures     int
+		want         time.Duration
+	}{
+		{3, 0, 2 * time.Second},
+		{3, 2, 2 * time.Second},
+		{3, 3, 4 * time.Second},
+		{3, 4, 8 * time.Second},
+		{3, 7, 60 * time.Second},
+		{3, 1000, 60 * time.Second},
+		{0, 10, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := backoffInterval(interval, maxInterval, tt.backoffAfter, tt.failures); got != tt.want {
+			t.Errorf("backoffInterval(after %d, %d failures) = %s, want %s", tt.backoffAfter, tt.failures, got, tt.want)
+		}
+	}
+
+	if got := backoffInterval(90*time.Second, maxInterval, 3, 5); got != 90*time.Second {
+		t.Errorf("interval above the cap = %s, want it unchanged", got)
+	}
+}
+
+func TestNextPollDelay(t *testing.T) {
+	c := &Collector{
+		miners:        map[string]*minerConn{"10.0.0.1": {}, "10.0.0.2": {}},
+		pollIntervals: make(map[string]time.Duration),
+	}
+	c.SetPollInterval(5*time.Second, 2, 30*time.Second)
+	c.SetMinerPollInterval("10.0.0.2", 20*time.Second)
+
+	if got := c.nextPollDelay("10.0.0.1"); got != 5*time.Second {
+		t.Errorf("global interval = %s, want 5s", got)
+	}
+	if got := c.nextPollDelay("10.0.0.2"); got != 20*time.Second {
+		t.Errorf("per-miner interval = %s, want 20s", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		c.recordPoll("10.0.0.1", 0, errors.New("timeout"))
+	}
+	if got := c.nextPollDelay("10.0.0.1"); got != 20*time.Second {
+		t.Errorf("after 3 failures = %s, want 20s", got)
+	}
+	if conn := c.miners["10.0.0.1"]; conn.lastError != "timeout" || conn.lastErrorAt.IsZero() {
+		t.Errorf("last error = %q at %v, want timeout", conn.lastError, conn.lastErrorAt)
+	}
+	c.recordPoll("10.0.0.1", 50*time.Millisecond, nil)
+	if got := c.nextPollDelay("10.0.0.1"); got != 5*time.Second {
+		t.Errorf("after recovering = %s, want 5s", got)
+	}
+	if got := c.miners["10.0.0.1"].pollLatency; got != 50*time.Millisecond {
+		t.Errorf("poll latency = %s, want 50ms", got)
+	}
+
+	c.SetMinerPollInterval("10.0.0.2", 0)
+	if got := c.nextPollDelay("10.0.0.2"); got != 5*time.Second {
+		t.Errorf("cleared per-miner interval = %s, want 5s", got)
+	}
+}
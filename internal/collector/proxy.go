@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"log"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// SetProxied sets how to tell miners connected through the stratum proxy.
+// Their shares, the pool's answers and their blocks are taken from the proxy,
+// see ProxyShare, instead of their logs. Call before Start.
+func (c *Collector) SetProxied(proxied func(ip string) bool) {
+	c.proxied = proxied
+}
+
+// isProxied reports whether a miner is connected through the stratum proxy
+func (c *Collector) isProxied(ip string) bool {
+	return c.proxied != nil && c.proxied(ip)
+}
+
+// ProxyShare records a share a miner submitted through the stratum proxy,
+// with the pool's answer already known: it is stored and broadcast like a
+// logged share, flagged when rejected, and stored as a block too when it
+// solved one
+func (c *Collector) ProxyShare(share *storage.Share, block bool) {
+	ip := share.MinerIP
+	share.Hostname = c.currentHostname(ip, ip)
+	if share.NetworkDifficulty > 0 {
+		c.trackNetworkDifficulty(ip, share.NetworkDifficulty)
+	}
+
+	if err := c.storage.InsertShare(share); err != nil {
+		log.Printf("InsertShare failed: %v", err)
+	} else if share.Rejected {
+		if err := c.storage.MarkShareRejected(share.ID, share.RejectReason); err != nil {
+			log.Printf("MarkShareRejected %s failed: %v", ip, err)
+		}
+	}
+	if !share.Rejected {
+		c.records.observeShare(share)
+	}
+
+	// Broadcast (non-blocking)
+	select {
+	case c.ShareChan <- share:
+	default:
+	}
+	if share.Rejected {
+		select {
+		case c.RejectChan <- share:
+		default:
+		}
+	}
+
+	if block {
+		c.foundBlock(ip, &storage.Block{
+			MinerIP:           ip,
+			Hostname:          share.Hostname,
+			Timestamp:         share.Timestamp,
+			Difficulty:        share.Difficulty,
+			NetworkDifficulty: share.NetworkDifficulty,
+		})
+	}
+}
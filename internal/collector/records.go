@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// recordKeeper raises hall of fame records as data arrives. It caches the
+// current values so the database is only written when a record falls.
+type recordKeeper struct {
+	store *storage.SQLiteStorage
+	mu    sync.Mutex
+	best  map[string]float64 // Current value per record kind, nil until loaded
+}
+
+func newRecordKeeper(store *storage.SQLiteStorage) *recordKeeper {
+	return &recordKeeper{store: store}
+}
+
+// reset drops the cached values, so they are reloaded from the database
+func (k *recordKeeper) reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.best = nil
+}
+
+// observe stores r if it beats the current record of its kind
+func (k *recordKeeper) observe(r *storage.Record) {
+	if r.Value <= 0 {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.best == nil {
+		records, err := k.store.GetRecords()
+		if err != nil {
+			log.Printf("GetRecords failed: %v", err)
+			return
+		}
+		k.best = make(map[string]float64, len(records))
+		for _, rec := range records {
+			k.best[rec.Kind] = rec.Value
+		}
+	}
+
+	if r.Value <= k.best[r.Kind] {
+		return
+	}
+
+	updated, err := k.store.UpdateRecord(r)
+	if err != nil {
+		log.Printf("UpdateRecord %s failed: %v", r.Kind, err)
+		return
+	}
+	previous := k.best[r.Kind]
+	k.best[r.Kind] = r.Value
+	if updated && previous > 0 && r.Kind != storage.RecordLongestUptime {
+		log.Printf("New record %s: %g by %s (was %g)", r.Kind, r.Value, r.Hostname, previous)
+	}
+}
+
+// observeShare checks a share against the best share record
+func (k *recordKeeper) observeShare(share *storage.Share) {
+	k.observe(&storage.Record{
+		Kind:       storage.RecordBestShare,
+		Value:      share.Difficulty,
+		MinerIP:    share.MinerIP,
+		Hostname:   share.Hostname,
+		AchievedAt: share.Timestamp,
+	})
+}
+
+// observeUptime checks a snapshot against the longest uptime record. Uptime
+// is rounded down to whole minutes so the record holder isn't rewritten on
+// every poll.
+func (k *recordKeeper) observeUptime(snap *storage.MinerSnapshot) {
+	k.observe(&storage.Record{
+		Kind:       storage.RecordLongestUptime,
+		Value:      float64(snap.UptimeSecs / 60 * 60),
+		MinerIP:    snap.MinerIP,
+		Hostname:   snap.Hostname,
+		AchievedAt: snap.Timestamp,
+	})
+}
+
+// observeBlock checks a stored block against the share, block streak and
+// best day records
+func (k *recordKeeper) observeBlock(block *storage.Block) {
+	k.observe(&storage.Record{
+		Kind:       storage.RecordBestShare,
+		Value:      block.Difficulty,
+		MinerIP:    block.MinerIP,
+		Hostname:   block.Hostname,
+		AchievedAt: block.Timestamp,
+	})
+
+	if streak, ended, err := k.store.GetLongestBlockStreak(block.MinerIP); err != nil {
+		log.Printf("GetLongestBlockStreak %s failed: %v", block.MinerIP, err)
+	} else {
+		k.observe(&storage.Record{
+			Kind:       storage.RecordBlockStreak,
+			Value:      float64(streak),
+			MinerIP:    block.MinerIP,
+			Hostname:   block.Hostname,
+			AchievedAt: ended,
+		})
+	}
+
+	day := block.Timestamp.Local()
+	if total, err := k.store.GetDayEarnings(day); err != nil {
+		log.Printf("GetDayEarnings failed: %v", err)
+	} else {
+		k.observe(&storage.Record{
+			Kind:       storage.RecordBestDayEarnings,
+			Value:      total,
+			AchievedAt: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()),
+			Detail:     day.Format("2006-01-02"),
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"log"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const (
+	// maxAwaitingResult bounds the shares kept per miner while waiting for
+	// the pool's response
+	maxAwaitingResult = 32
+
+	// resultTimeout is how long a submitted share waits for the pool's
+	// response before it's assumed the response was never logged
+	resultTimeout = time.Minute
+)
+
+// awaitResult queues a share that the miner submitted to the pool, so the
+// pool's response logged after it can be matched back to it. Shares below
+// the pool difficulty are only logged, never submitted.
+func (c *Collector) awaitResult(share *storage.Share) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+
+	conn, exists := c.miners[share.MinerIP]
+	if !exists || (conn.poolDiff > 0 && share.Difficulty < conn.poolDiff) {
+		return
+	}
+	conn.submitted = append(conn.submitted, share)
+	if len(conn.submitted) > maxAwaitingResult {
+		conn.submitted = conn.submitted[len(conn.submitted)-maxAwaitingResult:]
+	}
+}
+
+// takeSubmitted returns the oldest share of a miner still waiting for the
+// pool's response, or nil
+func (c *Collector) takeSubmitted(ip string, at time.Time) *storage.Share {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+
+	conn, exists := c.miners[ip]
+	if !exists {
+		return nil
+	}
+	for len(conn.submitted) > 0 {
+		share := conn.submitted[0]
+		conn.submitted = conn.submitted[1:]
+		if at.Sub(share.Timestamp) <= resultTimeout {
+			return share
+		}
+	}
+	return nil
+}
+
+// shareResult handles the pool's response to a share submitted by a miner.
+// A rejected share is flagged in storage and broadcast on RejectChan; when it
+// can't be matched to a logged share a bare share carrying the reason is
+// broadcast instead.
+func (c *Collector) shareResult(ip, hostname string, rejected bool, reason string, at time.Time) {
+	share := c.takeSubmitted(ip, at)
+	if !rejected {
+		return
+	}
+
+	if share != nil {
+		if err := c.storage.MarkShareRejected(share.ID, reason); err != nil {
+			log.Printf("MarkShareRejected %s failed: %v", ip, err)
+		}
+		// Copy, the share was already broadcast on ShareChan
+		rejectedShare := *share
+		share = &rejectedShare
+	} else {
+		share = &storage.Share{MinerIP: ip, Hostname: hostname, Timestamp: at}
+	}
+	share.Rejected = true
+	share.RejectReason = reason
+
+	select {
+	case c.RejectChan <- share:
+	default:
+	}
+}
@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// Firmware families, each released from its own upstream repository
+const (
+	FamilyNerdQAxe = "nerdqaxe"
+	FamilyAxeOS    = "axeos"
+)
+
+// firmwareRepos maps firmware families to their GitHub repositories
+var firmwareRepos = map[string]string{
+	FamilyNerdQAxe: "shufps/ESP-Miner-NerdQAxePlus",
+	FamilyAxeOS:    "bitaxeorg/ESP-Miner",
+}
+
+// releaseTTL is how long a fetched release is reused. Unauthenticated GitHub
+// API calls are limited to 60 an hour.
+const releaseTTL = time.Hour
+
+// FirmwareFamily returns the firmware family a miner runs, "" if unknown
+func FirmwareFamily(m *storage.Miner) string {
+	model := strings.ToLower(m.DeviceModel)
+	switch {
+	case strings.HasPrefix(model, "axeos"):
+		return FamilyAxeOS
+	case strings.Contains(model, "nerd"):
+		return FamilyNerdQAxe
+	}
+	return ""
+}
+
+// ReleaseAsset is a file attached to a release, e.g. esp-miner.bin
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// FirmwareRelease is the latest upstream release of a firmware family
+type FirmwareRelease struct {
+	Family      string         `json:"family"`
+	Repo        string         `json:"repo"`
+	Version     string         `json:"version"`
+	Name        string         `json:"name"`
+	URL         string         `json:"url"`
+	PublishedAt time.Time      `json:"publishedAt"`
+	Assets      []ReleaseAsset `json:"assets"`
+}
+
+// githubRelease matches the GitHub "latest release" response
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	HTMLURL     string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+		Size               int64  `json:"size"`
+	} `json:"assets"`
+}
+
+type cachedRelease struct {
+	release *FirmwareRelease
+	fetched time.Time
+}
+
+// ReleaseChecker looks up the latest upstream firmware releases on GitHub
+type ReleaseChecker struct {
+	httpClient *http.Client
+	apiURL     string // GitHub API base URL, replaced in tests
+
+	mu    sync.Mutex
+	cache map[string]cachedRelease
+}
+
+// NewReleaseChecker creates a new ReleaseChecker
+func NewReleaseChecker() *ReleaseChecker {
+	return &ReleaseChecker{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		apiURL:     "https://api.github.com",
+		cache:      make(map[string]cachedRelease),
+	}
+}
+
+// Latest returns the latest release of a firmware family. A stale release is
+// returned if GitHub can't be reached.
+func (c *ReleaseChecker) Latest(family string) (*FirmwareRelease, error) {
+	repo, ok := firmwareRepos[family]
+	if !ok {
+		return nil, fmt.Errorf("unknown firmware family %q", family)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, found := c.cache[family]
+	if found && time.Since(cached.fetched) < releaseTTL {
+		return cached.release, nil
+	}
+
+	release, err := c.fetch(family, repo)
+	if err != nil {
+		if found {
+			return cached.release, nil
+		}
+		return nil, err
+	}
+	c.cache[family] = cachedRelease{release: release, fetched: time.Now()}
+	return release, nil
+}
+
+// fetch fetches the latest release of repo from GitHub
+func (c *ReleaseChecker) fetch(family, repo string) (*FirmwareRelease, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/releases/latest", c.apiURL, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s releases: %w", repo, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned status %d for %s", resp.StatusCode, repo)
+	}
+
+	var data githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s release: %w", repo, err)
+	}
+
+	release := &FirmwareRelease{
+		Family:      family,
+		Repo:        repo,
+		Version:     data.TagName,
+		Name:        data.Name,
+		URL:         data.HTMLURL,
+		PublishedAt: data.PublishedAt,
+		Assets:      make([]ReleaseAsset, 0, len(data.Assets)),
+	}
+	for _, a := range data.Assets {
+		release.Assets = append(release.Assets, ReleaseAsset{Name: a.Name, URL: a.BrowserDownloadURL, Size: a.Size})
+	}
+	return release, nil
+}
+
+// CompareVersions compares dotted version strings such as "v2.4.1" and
+// "2.5.0-rc1" number by number, ignoring a leading "v" and any suffix. It
+// returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	pa, pb := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers parses the leading dotted numbers of a version
+func versionNumbers(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(v)), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+
+	var numbers []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v2.4.1", "2.4.1", 0},
+		{"v2.4.1", "v2.5.0", -1},
+		{"2.10.0", "2.9.3", 1},
+		{"v1.0", "v1.0.0", 0},
+		{"v2.5.0-rc1", "v2.5.0", 0},
+		{"v1.2.3", "", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFirmwareFamily(t *testing.T) {
+	tests := map[string]string{
+		"NerdQAxe++":     FamilyNerdQAxe,
+		"NerdOCTAXE-γ":   FamilyNerdQAxe,
+		"AxeOS (BM1370)": FamilyAxeOS,
+		"Antminer S19":   "",
+	}
+	for model, want := range tests {
+		if got := FirmwareFamily(&storage.Miner{DeviceModel: model}); got != want {
+			t.Errorf("FirmwareFamily(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestReleaseChecker(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/repos/bitaxeorg/ESP-Miner/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"tag_name":"v2.5.0","name":"v2.5.0","html_url":"https://example.com",
+			"assets":[{"name":"esp-miner.bin","browser_download_url":"https://example.com/esp-miner.bin","size":1234}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewReleaseChecker()
+	c.apiURL = srv.URL
+
+	release, err := c.Latest(FamilyAxeOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "v2.5.0" || len(release.Assets) != 1 || release.Assets[0].Size != 1234 {
+		t.Errorf("unexpected release: %+v", release)
+	}
+	if _, err := c.Latest(FamilyAxeOS); err != nil || calls != 1 {
+		t.Errorf("expected the cached release, got %v after %d calls", err, calls)
+	}
+
+	if _, err := c.Latest(FamilyNerdQAxe); err == nil {
+		t.Error("expected an error for a missing release")
+	}
+	if _, err := c.Latest("cgminer"); err == nil {
+		t.Error("expected an error for an unknown family")
+	}
+}
@@ -0,0 +1,191 @@
+package collector
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// remoteTimeout is how long a remote site's miner stays online without a
+// snapshot forwarded by the site's agent
+const remoteTimeout = time.Minute
+
+// RemoteBatch is what a remote site's agent forwards: the miners it collects
+// from, and their snapshots, shares and blocks since the last batch. IPs are
+// the miners' addresses at the site.
+type RemoteBatch struct {
+	Miners    []*storage.Miner         `json:"miners"`
+	Snapshots []*storage.MinerSnapshot `json:"snapshots"`
+	Shares    []*storage.Share         `json:"shares"`
+	Blocks    []*storage.Block         `json:"blocks"`
+}
+
+// remoteMiner is a miner at a remote site, known from its agent's batches
+type remoteMiner struct {
+	site     string
+	lastSeen time.Time              // Last snapshot received
+	latest   *storage.MinerSnapshot // Latest snapshot received
+}
+
+// SiteStatus reports the miners a remote site's agent forwarded
+type SiteStatus struct {
+	Name     string    `json:"name"`
+	LastSeen time.Time `json:"lastSeen"` // Last batch received from the agent, unset if none since the start
+	Miners   int       `json:"miners"`
+	Online   int       `json:"online"`
+}
+
+// RemoteKey returns the key a remote site's miner is stored and listed under:
+// its IP at the site followed by "@<site>", as sites may share addresses
+func RemoteKey(ip, site string) string {
+	return ip + "@" + site
+}
+
+// IsRemote reports whether key names a miner at a remote site
+func IsRemote(key string) bool {
+	return strings.Contains(key, "@")
+}
+
+// Ingest takes a batch forwarded by a remote site's agent in place of polls
+// and WebSocket messages: the miners are stored under their remote keys, and
+// their snapshots, shares and blocks are stored and broadcast like local ones
+func (c *Collector) Ingest(site string, batch *RemoteBatch) {
+	now := time.Now()
+
+	c.minersMu.Lock()
+	c.sitesSeen[site] = now
+	c.minersMu.Unlock()
+
+	for _, m := range batch.Miners {
+		if m.IP == "" || IsRemote(m.IP) {
+			continue
+		}
+		m.IP = RemoteKey(m.IP, site)
+		m.Site = site
+		m.Enabled = true
+		m.LastSeen = now
+		if err := c.storage.UpsertMiner(m); err != nil {
+			log.Printf("UpsertMiner %s failed: %v", m.IP, err)
+			continue
+		}
+
+		c.minersMu.Lock()
+		_, known := c.remote[m.IP]
+		if !known {
+			c.remote[m.IP] = &remoteMiner{site: site}
+		}
+		c.minersMu.Unlock()
+		if !known {
+			if err := c.storage.SetMinerSite(m.IP, site); err != nil {
+				log.Printf("SetMinerSite %s failed: %v", m.IP, err)
+			}
+		}
+	}
+
+	for _, snapshot := range batch.Snapshots {
+		if snapshot.MinerIP == "" || IsRemote(snapshot.MinerIP) {
+			continue
+		}
+		snapshot.ID = 0
+		snapshot.MinerIP = RemoteKey(snapshot.MinerIP, site)
+
+		c.minersMu.Lock()
+		rm, exists := c.remote[snapshot.MinerIP]
+		if exists {
+			rm.lastSeen = now
+			rm.latest = snapshot
+		}
+		c.minersMu.Unlock()
+		if !exists {
+			continue // Not in the batch's miners
+		}
+
+		c.snapshots.add(snapshot)
+		c.energy.record(snapshot.MinerIP, snapshot.Power, snapshot.Timestamp)
+		c.records.observeUptime(snapshot)
+		select {
+		case c.SnapshotChan <- snapshot:
+		default:
+		}
+	}
+
+	for _, share := range batch.Shares {
+		if share.MinerIP == "" || IsRemote(share.MinerIP) {
+			continue
+		}
+		share.ID = 0
+		share.MinerIP = RemoteKey(share.MinerIP, site)
+		if err := c.storage.InsertShare(share); err != nil {
+			log.Printf("InsertShare failed: %v", err)
+			continue
+		}
+		c.records.observeShare(share)
+		select {
+		case c.ShareChan <- share:
+		default:
+		}
+	}
+
+	for _, block := range batch.Blocks {
+		if block.MinerIP == "" || IsRemote(block.MinerIP) {
+			continue
+		}
+		block.ID = 0
+		block.MinerIP = RemoteKey(block.MinerIP, site)
+		log.Printf("BLOCK FOUND by %s (%s) at site %s! Diff: %.0f > Network: %.0f",
+			block.Hostname, block.MinerIP, site, block.Difficulty, block.NetworkDifficulty)
+		if err := c.storage.InsertBlock(block); err != nil {
+			log.Printf("InsertBlock failed: %v", err)
+			continue
+		}
+		c.records.observeBlock(block)
+		select {
+		case c.BlockChan <- block:
+		default:
+		}
+	}
+}
+
+// trackRemote lists a stored miner of a remote site, offline until the site's
+// agent forwards it
+func (c *Collector) trackRemote(key, site string) {
+	c.minersMu.Lock()
+	defer c.minersMu.Unlock()
+	if _, exists := c.remote[key]; !exists {
+		c.remote[key] = &remoteMiner{site: site}
+	}
+}
+
+// Sites reports every remote site with stored miners or that forwarded a
+// batch, by name
+func (c *Collector) Sites() []SiteStatus {
+	now := time.Now()
+
+	c.minersMu.RLock()
+	bySite := make(map[string]*SiteStatus, len(c.sitesSeen))
+	for name, seen := range c.sitesSeen {
+		bySite[name] = &SiteStatus{Name: name, LastSeen: seen}
+	}
+	for _, rm := range c.remote {
+		st := bySite[rm.site]
+		if st == nil {
+			st = &SiteStatus{Name: rm.site}
+			bySite[rm.site] = st
+		}
+		st.Miners++
+		if now.Sub(rm.lastSeen) < remoteTimeout {
+			st.Online++
+		}
+	}
+	c.minersMu.RUnlock()
+
+	sites := make([]SiteStatus, 0, len(bySite))
+	for _, st := range bySite {
+		sites = append(sites, *st)
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	return sites
+}
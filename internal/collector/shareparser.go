@@ -3,6 +3,7 @@ package collector
 import (
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/camarigor/miner-hq/internal/storage"
@@ -22,6 +23,32 @@ var shareRegexAxeOS = regexp.MustCompile(
 	`asic_result:.*ID:\s*([0-9a-fA-F]+),\s*ASIC nr:\s*(\d+).*diff\s+([\d.]+)`,
 )
 
+// NerdQAxe appends the network difficulty after share/pool difficulty: "diff 5894.3/18304/3.70G"
+var shareNetworkDiffRegex = regexp.MustCompile(
+	`diff\s+[\d.]+/[\d.]+/([\d.]+)([kKMGTP]?)`,
+)
+
+// difficultySuffixes maps the suffixes firmware uses for large difficulties
+var difficultySuffixes = map[string]float64{
+	"": 1, "k": 1e3, "K": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15,
+}
+
+// Both formats carry the rolled block version and the winning nonce as hex
+var shareVersionNonceRegex = regexp.MustCompile(
+	`(?i)ver:\s*([0-9a-f]{1,8})\s+Nonce\s+([0-9a-f]{1,8})`,
+)
+
+// The pool's response to a submitted share, logged by the stratum task:
+//
+//	I (52344) stratum_task: message result accepted
+//	W (52344) stratum_task: message result rejected: Stale
+var shareResultRegex = regexp.MustCompile(
+	`(?i)\b(?:result|share)\s+(accepted|rejected)\b[:\s]*(.*)`,
+)
+
+// Some firmware logs stale shares on their own, e.g. "Stale share, job not found"
+var staleShareRegex = regexp.MustCompile(`(?i)\bstale share\b`)
+
 type ShareParser struct{}
 
 func NewShareParser() *ShareParser {
@@ -38,13 +65,20 @@ func (p *ShareParser) Parse(minerIP string, line string) *storage.Share {
 		asicNum, _ := strconv.Atoi(matches[2])
 		difficulty, _ := strconv.ParseFloat(matches[3], 64)
 
-		return &storage.Share{
+		share := &storage.Share{
 			MinerIP:    minerIP,
 			Timestamp:  time.Now(),
 			AsicNum:    asicNum,
 			Difficulty: difficulty,
 			JobID:      jobID,
 		}
+		parseVersionNonce(line, share)
+		if m := shareNetworkDiffRegex.FindStringSubmatch(line); m != nil {
+			if value, err := strconv.ParseFloat(m[1], 64); err == nil {
+				share.SetNetworkDifficulty(value * difficultySuffixes[m[2]])
+			}
+		}
+		return share
 	}
 
 	// Try AxeOS/Bitaxe format
@@ -53,18 +87,50 @@ func (p *ShareParser) Parse(minerIP string, line string) *storage.Share {
 		asicNum, _ := strconv.Atoi(matches[2])
 		difficulty, _ := strconv.ParseFloat(matches[3], 64)
 
-		return &storage.Share{
+		share := &storage.Share{
 			MinerIP:    minerIP,
 			Timestamp:  time.Now(),
 			AsicNum:    asicNum,
 			Difficulty: difficulty,
 			JobID:      jobID,
 		}
+		parseVersionNonce(line, share)
+		return share
 	}
 
 	return nil
 }
 
+// ParseResult reports whether a log line is the pool's response to a
+// submitted share, and if so whether it was rejected and why. ok is false
+// for any other line.
+func (p *ShareParser) ParseResult(line string) (rejected bool, reason string, ok bool) {
+	if m := shareResultRegex.FindStringSubmatch(line); m != nil {
+		if !strings.EqualFold(m[1], "rejected") {
+			return false, "", true
+		}
+		return true, strings.TrimSpace(m[2]), true
+	}
+	if staleShareRegex.MatchString(line) {
+		return true, "Stale", true
+	}
+	return false, "", false
+}
+
+// parseVersionNonce fills in the share's version and nonce if present in the line
+func parseVersionNonce(line string, share *storage.Share) {
+	matches := shareVersionNonceRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return
+	}
+	if version, err := strconv.ParseUint(matches[1], 16, 32); err == nil {
+		share.Version = uint32(version)
+	}
+	if nonce, err := strconv.ParseUint(matches[2], 16, 32); err == nil {
+		share.Nonce = uint32(nonce)
+	}
+}
+
 // FormatDifficulty formats difficulty as human-readable (K, M, G)
 func FormatDifficulty(diff float64) string {
 	switch {
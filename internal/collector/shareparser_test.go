@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"math"
 	"testing"
 )
 
@@ -31,6 +32,22 @@ func TestShareParser_ParseValidShareLine(t *testing.T) {
 		t.Errorf("expected Difficulty %f, got %f", 5894.3, share.Difficulty)
 	}
 
+	if share.Version != 0x23B82202 {
+		t.Errorf("expected Version %08X, got %08X", 0x23B82202, share.Version)
+	}
+
+	if share.Nonce != 0xF854197E {
+		t.Errorf("expected Nonce %08X, got %08X", 0xF854197E, share.Nonce)
+	}
+
+	if math.Abs(share.NetworkDifficulty-3.70e9) > 1 {
+		t.Errorf("expected NetworkDifficulty %f, got %f", 3.70e9, share.NetworkDifficulty)
+	}
+
+	if want := 5894.3 / 3.70e9 * 100; math.Abs(share.NetworkPct-want) > 1e-9 {
+		t.Errorf("expected NetworkPct %g, got %g", want, share.NetworkPct)
+	}
+
 	if share.Timestamp.IsZero() {
 		t.Error("expected Timestamp to be set, got zero time")
 	}
@@ -86,6 +103,19 @@ func TestShareParser_ParseAxeOSShareLine(t *testing.T) {
 	if share.Difficulty != 432.8 {
 		t.Errorf("expected Difficulty %f, got %f", 432.8, share.Difficulty)
 	}
+
+	if share.Version != 0x24564000 {
+		t.Errorf("expected Version %08X, got %08X", 0x24564000, share.Version)
+	}
+
+	if share.Nonce != 0xC27001F0 {
+		t.Errorf("expected Nonce %08X, got %08X", 0xC27001F0, share.Nonce)
+	}
+
+	// AxeOS lines don't carry network difficulty; the collector fills it from polling
+	if share.NetworkDifficulty != 0 {
+		t.Errorf("expected no NetworkDifficulty, got %f", share.NetworkDifficulty)
+	}
 }
 
 func TestShareParser_ParseAxeOSHighDiffShare(t *testing.T) {
@@ -229,3 +259,30 @@ func TestNewShareParser(t *testing.T) {
 		t.Error("expected NewShareParser to return non-nil parser")
 	}
 }
+
+func TestShareParser_ParseResult(t *testing.T) {
+	parser := NewShareParser()
+
+	testCases := []struct {
+		line     string
+		rejected bool
+		reason   string
+		ok       bool
+	}{
+		{"I (52344) stratum_task: message result accepted", false, "", true},
+		{"W (52344) stratum_task: message result rejected: Stale", true, "Stale", true},
+		{"E (1200) stratum_api: Share rejected: Above target", true, "Above target", true},
+		{"W (1200) stratum_task: Stale share, job not found", true, "Stale", true},
+		{"I (12345) stratum: Connected to pool", false, "", false},
+		{"I (12345) asic_result: ID: 69868e2b00000b0b, ASIC nr: 0, ver: 21BF0000 Nonce 383C02D4 diff 260.2 of 2048.", false, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			rejected, reason, ok := parser.ParseResult(tc.line)
+			if rejected != tc.rejected || reason != tc.reason || ok != tc.ok {
+				t.Errorf("ParseResult = %v, %q, %v; want %v, %q, %v", rejected, reason, ok, tc.rejected, tc.reason, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// staleTracker detects miners whose HTTP API keeps answering with frozen data.
+// Some firmware hangs leave the web server up while mining has stopped: uptime
+// stops advancing, or every metric is repeated verbatim poll after poll.
+type staleTracker struct {
+	last  *storage.MinerSnapshot
+	since time.Time // When the data first stopped changing, zero while it changes
+}
+
+// observe records a poll and returns how long the miner's data has been frozen,
+// 0 if it is still changing
+func (t *staleTracker) observe(snap *storage.MinerSnapshot) time.Duration {
+	prev := t.last
+	t.last = snap
+
+	if prev == nil || !isFrozen(prev, snap) {
+		t.since = time.Time{}
+		return 0
+	}
+
+	if t.since.IsZero() {
+		t.since = prev.Timestamp
+	}
+	return snap.Timestamp.Sub(t.since)
+}
+
+// isFrozen reports whether cur repeats prev: either the uptime counter did not
+// advance, or a hashing miner reported exactly the same readings again
+func isFrozen(prev, cur *storage.MinerSnapshot) bool {
+	if cur.UptimeSecs > 0 && cur.UptimeSecs == prev.UptimeSecs {
+		return true
+	}
+
+	return cur.HashRate > 0 &&
+		cur.HashRate == prev.HashRate &&
+		cur.Temperature == prev.Temperature &&
+		cur.PowerRaw == prev.PowerRaw &&
+		cur.SharesAccept == prev.SharesAccept &&
+		cur.SharesReject == prev.SharesReject
+}
+
+// sameReadings reports whether cur stores the same values as prev, apart from
+// its timestamp and the uptime, which always advance
+func sameReadings(prev, cur *storage.MinerSnapshot) bool {
+	a, b := *prev, *cur
+	a.ID, b.ID = 0, 0
+	a.Timestamp, b.Timestamp = time.Time{}, time.Time{}
+	a.UptimeSecs, b.UptimeSecs = 0, 0
+	a.FrozenSecs, b.FrozenSecs = 0, 0 // Not stored
+	a.PlugPower, b.PlugPower = false, false
+	return a == b
+}
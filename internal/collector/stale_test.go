@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestStaleTracker(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	snap := func(sec int, uptime int64, hashRate float64, accepted int64) *storage.MinerSnapshot {
+		return &storage.MinerSnapshot{
+			Timestamp:    start.Add(time.Duration(sec) * time.Second),
+			UptimeSecs:   uptime,
+			HashRate:     hashRate,
+			Temperature:  55.5,
+			PowerRaw:     14.2,
+			SharesAccept: accepted,
+		}
+	}
+
+	tests := []struct {
+		name string
+		snap *storage.MinerSnapshot
+		want time.Duration
+	}{
+		{"first poll", snap(0, 100, 500.1, 10), 0},
+		{"changing", snap(2, 102, 498.7, 11), 0},
+		{"uptime stuck", snap(4, 102, 501.3, 11), 2 * time.Second},
+		{"still stuck", snap(6, 102, 499.9, 11), 4 * time.Second},
+		{"recovered", snap(8, 108, 502.0, 12), 0},
+		{"identical readings", snap(10, 110, 502.0, 12), 2 * time.Second},
+		{"idle miner is not frozen", snap(12, 112, 0, 12), 0},
+		{"idle again", snap(14, 114, 0, 12), 0},
+		{"reboot", snap(16, 2, 480.0, 0), 0},
+	}
+
+	var tracker staleTracker
+	for _, tt := range tests {
+		if got := tracker.observe(tt.snap); got != tt.want {
+			t.Errorf("%s: observe() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
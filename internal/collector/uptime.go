@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// uptimeStore persists miners going online and offline
+type uptimeStore interface {
+	InsertUptimeEvent(e *storage.UptimeEvent) error
+	GetLastUptimeEvent(minerIP string) (*storage.UptimeEvent, error)
+}
+
+// uptimeTracker records each change of a miner's online status as an
+// uptime event
+type uptimeTracker struct {
+	store  uptimeStore
+	mu     sync.Mutex
+	online map[string]bool // Last recorded status per miner
+}
+
+func newUptimeTracker(store uptimeStore) *uptimeTracker {
+	return &uptimeTracker{store: store, online: make(map[string]bool)}
+}
+
+// observe compares a miner's status with the last one recorded and stores an
+// event when it changed. A miner going offline is dated when it was last
+// seen. The first status observed for a miner is compared with the last
+// stored event, so restarting MinerHQ doesn't add events.
+func (t *uptimeTracker) observe(ip string, online bool, lastSeen, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recorded, known := t.online[ip]
+	if !known {
+		last, err := t.store.GetLastUptimeEvent(ip)
+		if err != nil {
+			log.Printf("GetLastUptimeEvent %s failed: %v", ip, err)
+			return
+		}
+		if last != nil {
+			recorded, known = last.Online, true
+		}
+	}
+	if known && recorded == online {
+		t.online[ip] = online
+		return
+	}
+
+	at := now
+	if !online && !lastSeen.IsZero() {
+		at = lastSeen
+	}
+	if err := t.store.InsertUptimeEvent(&storage.UptimeEvent{MinerIP: ip, Timestamp: at, Online: online}); err != nil {
+		log.Printf("InsertUptimeEvent %s failed: %v", ip, err)
+		return
+	}
+	t.online[ip] = online
+}
+
+// forget drops the status of miners no longer collected from, which is read
+// back from storage if they return
+func (t *uptimeTracker) forget(collected map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ip := range t.online {
+		if !collected[ip] {
+			delete(t.online, ip)
+		}
+	}
+}
+
+// StartUptimeTracker starts a background goroutine that records the miners
+// going online and offline, checking every interval
+func (c *Collector) StartUptimeTracker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			c.trackUptime(time.Now())
+		}
+	}()
+}
+
+// trackUptime records the changes of every miner's online status
+func (c *Collector) trackUptime(now time.Time) {
+	status := c.GetMinerStatus()
+
+	c.minersMu.RLock()
+	lastSeen := make(map[string]time.Time, len(c.miners)+len(c.remote))
+	for ip, conn := range c.miners {
+		lastSeen[ip] = conn.lastSeen
+	}
+	for key, rm := range c.remote {
+		lastSeen[key] = rm.lastSeen
+	}
+	c.minersMu.RUnlock()
+
+	collected := make(map[string]bool, len(status))
+	for ip, online := range status {
+		c.uptime.observe(ip, online, lastSeen[ip], now)
+		collected[ip] = true
+	}
+	c.uptime.forget(collected)
+}
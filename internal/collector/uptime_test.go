@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+type fakeUptimeStore struct {
+	events []*storage.UptimeEvent
+}
+
+func (f *fakeUptimeStore) InsertUptimeEvent(e *storage.UptimeEvent) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeUptimeStore) GetLastUptimeEvent(minerIP string) (*storage.UptimeEvent, error) {
+	for i := len(f.events) - 1; i >= 0; i-- {
+		if f.events[i].MinerIP == minerIP {
+			return f.events[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestUptimeTracker(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeUptimeStore{}
+	tracker := newUptimeTracker(store)
+
+	tracker.observe("10.0.0.1", true, now, now)
+	tracker.observe("10.0.0.1", true, now.Add(time.Minute), now.Add(time.Minute))
+	if len(store.events) != 1 || !store.events[0].Online {
+		t.Fatalf("expected only the first online status recorded, got %+v", store.events)
+	}
+
+	lastSeen := now.Add(90 * time.Second)
+	tracker.observe("10.0.0.1", false, lastSeen, now.Add(3*time.Minute))
+	if len(store.events) != 2 || store.events[1].Online || !store.events[1].Timestamp.Equal(lastSeen) {
+		t.Fatalf("expected going offline dated when the miner was last seen, got %+v", store.events)
+	}
+
+	// After a restart the status is compared with the stored one
+	restarted := newUptimeTracker(store)
+	restarted.observe("10.0.0.1", false, time.Time{}, now.Add(time.Hour))
+	if len(store.events) != 2 {
+		t.Errorf("expected no event for an unchanged status after a restart, got %+v", store.events)
+	}
+	restarted.observe("10.0.0.1", true, now.Add(2*time.Hour), now.Add(2*time.Hour))
+	if len(store.events) != 3 || !store.events[2].Online {
+		t.Errorf("expected the miner coming back recorded, got %+v", store.events)
+	}
+
+	restarted.forget(map[string]bool{})
+	if len(restarted.online) != 0 {
+		t.Errorf("expected removed miners forgotten, got %v", restarted.online)
+	}
+}
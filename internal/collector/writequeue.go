@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const (
+	// snapshotFlushInterval is how often queued snapshots are written to
+	// storage unless configured otherwise
+	snapshotFlushInterval = 5 * time.Second
+
+	// maxQueuedSnapshots bounds the queue while storage is failing or busy;
+	// beyond it the oldest snapshots are dropped
+	maxQueuedSnapshots = 10000
+)
+
+// snapshotStore persists batches of snapshots
+type snapshotStore interface {
+	InsertSnapshots(snaps []*storage.MinerSnapshot) error
+}
+
+// snapshotQueue buffers the snapshots polled from all miners and writes them
+// in one transaction per flush interval, instead of one INSERT per poll, so
+// polling never waits on the database.
+type snapshotQueue struct {
+	store    snapshotStore
+	mu       sync.Mutex
+	pending  []*storage.MinerSnapshot
+	interval time.Duration
+	dropped  int  // Snapshots dropped since the last flush
+	closed   bool // Snapshots added after close are written right away
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newSnapshotQueue(store snapshotStore) *snapshotQueue {
+	return &snapshotQueue{
+		store:    store,
+		interval: snapshotFlushInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// setInterval sets how often queued snapshots are written (0 = the default).
+// It takes effect after the next flush.
+func (q *snapshotQueue) setInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = snapshotFlushInterval
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.interval = interval
+}
+
+// add queues a snapshot for the next flush
+func (q *snapshotQueue) add(snap *storage.MinerSnapshot) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		if err := q.store.InsertSnapshots([]*storage.MinerSnapshot{snap}); err != nil {
+			log.Printf("InsertSnapshot %s failed: %v", snap.MinerIP, err)
+		}
+		return
+	}
+	q.pending = append(q.pending, snap)
+	q.trimLocked()
+	q.mu.Unlock()
+}
+
+// queued returns how many snapshots are waiting to be written
+func (q *snapshotQueue) queued() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// trimLocked drops the oldest queued snapshots beyond maxQueuedSnapshots.
+// The caller must hold mu.
+func (q *snapshotQueue) trimLocked() {
+	if len(q.pending) > maxQueuedSnapshots {
+		q.dropped += len(q.pending) - maxQueuedSnapshots
+		q.pending = q.pending[len(q.pending)-maxQueuedSnapshots:]
+	}
+}
+
+// run flushes the queue every interval until close is called
+func (q *snapshotQueue) run() {
+	defer close(q.done)
+
+	timer := time.NewTimer(q.flushInterval())
+	defer timer.Stop()
+	for {
+		select {
+		case <-q.stop:
+			q.flush()
+			return
+		case <-timer.C:
+			q.flush()
+			timer.Reset(q.flushInterval())
+		}
+	}
+}
+
+func (q *snapshotQueue) flushInterval() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.interval
+}
+
+// flush writes all queued snapshots in one batch. A failed batch is kept and
+// retried on the next flush.
+func (q *snapshotQueue) flush() {
+	q.mu.Lock()
+	batch, dropped := q.pending, q.dropped
+	q.pending, q.dropped = nil, 0
+	q.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Snapshot queue full, dropped %d snapshots", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if err := q.store.InsertSnapshots(batch); err != nil {
+		log.Printf("InsertSnapshots failed for %d snapshots: %v", len(batch), err)
+		q.mu.Lock()
+		q.pending = append(batch, q.pending...)
+		q.trimLocked()
+		q.mu.Unlock()
+	}
+}
+
+// close writes the remaining snapshots and stops the flush loop
+func (q *snapshotQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	close(q.stop)
+	<-q.done
+}
+
+// reopen starts the flush loop again after close
+func (q *snapshotQueue) reopen() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		return
+	}
+	q.closed = false
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+	go q.run()
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+type fakeSnapshotStore struct {
+	mu      sync.Mutex
+	batches [][]*storage.MinerSnapshot
+	fail    bool
+}
+
+func (f *fakeSnapshotStore) InsertSnapshots(snaps []*storage.MinerSnapshot) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("database is locked")
+	}
+	f.batches = append(f.batches, snaps)
+	return nil
+}
+
+func (f *fakeSnapshotStore) stored() (batches, snapshots int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, b := range f.batches {
+		snapshots += len(b)
+	}
+	return len(f.batches), snapshots
+}
+
+func TestSnapshotQueue(t *testing.T) {
+	t.Run("one batch per flush", func(t *testing.T) {
+		store := &fakeSnapshotStore{}
+		q := newSnapshotQueue(store)
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			q.add(&storage.MinerSnapshot{MinerIP: ip})
+		}
+		q.flush()
+		q.flush()
+
+		if batches, snapshots := store.stored(); batches != 1 || snapshots != 3 {
+			t.Errorf("stored %d batches of %d snapshots, want 1 batch of 3", batches, snapshots)
+		}
+	})
+
+	t.Run("failed batches are retried", func(t *testing.T) {
+		store := &fakeSnapshotStore{fail: true}
+		q := newSnapshotQueue(store)
+		q.add(&storage.MinerSnapshot{MinerIP: "10.0.0.1"})
+		q.flush()
+		q.add(&storage.MinerSnapshot{MinerIP: "10.0.0.2"})
+
+		store.fail = false
+		q.flush()
+		if batches, snapshots := store.stored(); batches != 1 || snapshots != 2 {
+			t.Errorf("stored %d batches of %d snapshots, want 1 batch of 2", batches, snapshots)
+		}
+		if ip := store.batches[0][0].MinerIP; ip != "10.0.0.1" {
+			t.Errorf("first retried snapshot is from %s, want the oldest (10.0.0.1)", ip)
+		}
+	})
+
+	t.Run("oldest dropped when full", func(t *testing.T) {
+		store := &fakeSnapshotStore{}
+		q := newSnapshotQueue(store)
+		for i := 0; i < maxQueuedSnapshots+5; i++ {
+			q.add(&storage.MinerSnapshot{ID: int64(i)})
+		}
+		if q.dropped != 5 || len(q.pending) != maxQueuedSnapshots || q.pending[0].ID != 5 {
+			t.Errorf("dropped %d, queued %d starting at %d; want 5 dropped, %d queued starting at 5", q.dropped, len(q.pending), q.pending[0].ID, maxQueuedSnapshots)
+		}
+	})
+
+	t.Run("close writes what is left", func(t *testing.T) {
+		store := &fakeSnapshotStore{}
+		q := newSnapshotQueue(store)
+		q.setInterval(time.Hour)
+		go q.run()
+
+		q.add(&storage.MinerSnapshot{MinerIP: "10.0.0.1"})
+		q.close()
+		q.add(&storage.MinerSnapshot{MinerIP: "10.0.0.2"})
+
+		if batches, snapshots := store.stored(); batches != 2 || snapshots != 2 {
+			t.Errorf("stored %d batches of %d snapshots, want 2 batches of 1", batches, snapshots)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+// Package competition computes the boundaries of competition periods, shared
+// by the leaderboards, the leader alert and the share purge schedule.
+package competition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period lengths
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// weekdays maps the accepted weekday names (and their first three letters)
+// to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Period is a repeating competition period that resets at midnight. The zero
+// Period is weekly, resetting Sunday at midnight local time.
+type Period struct {
+	Kind     string         // Daily, Weekly or Monthly
+	Weekday  time.Weekday   // Day weekly periods reset on
+	MonthDay int            // Day of the month monthly periods reset on, 1-28
+	Location *time.Location // Time zone of the midnight reset, nil for local time
+}
+
+// New parses a period. resetDay is a weekday ("sun", "monday") for weekly
+// periods and a day of the month from 1 to 28 for monthly ones; empty means
+// Sunday or the 1st, and daily periods ignore it. timezone is an IANA name
+// such as "Europe/Lisbon"; empty means the server's local time.
+func New(kind, resetDay, timezone string) (Period, error) {
+	p := Period{Kind: strings.ToLower(kind), MonthDay: 1}
+	if p.Kind == "" {
+		p.Kind = Weekly
+	}
+
+	resetDay = strings.ToLower(strings.TrimSpace(resetDay))
+	switch p.Kind {
+	case Daily:
+		// Every day resets, so the reset day doesn't matter
+	case Weekly:
+		if resetDay != "" {
+			name := resetDay
+			if len(name) > 3 {
+				name = name[:3]
+			}
+			wd, ok := weekdays[name]
+			if !ok {
+				return Period{}, fmt.Errorf("reset day %q is not a weekday", resetDay)
+			}
+			p.Weekday = wd
+		}
+	case Monthly:
+		if resetDay != "" {
+			day, err := strconv.Atoi(resetDay)
+			if err != nil || day < 1 || day > 28 {
+				return Period{}, fmt.Errorf("reset day %q must be a day of the month from 1 to 28", resetDay)
+			}
+			p.MonthDay = day
+		}
+	default:
+		return Period{}, fmt.Errorf("period %q must be daily, weekly or monthly", kind)
+	}
+
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return Period{}, fmt.Errorf("unknown timezone %q", timezone)
+		}
+		p.Location = loc
+	}
+	return p, nil
+}
+
+// location returns the period's time zone
+func (p Period) location() *time.Location {
+	if p.Location == nil {
+		return time.Local
+	}
+	return p.Location
+}
+
+// Start returns the start of the period containing t
+func (p Period) Start(t time.Time) time.Time {
+	t = t.In(p.location())
+	switch p.Kind {
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case Monthly:
+		day := p.MonthDay
+		if day < 1 {
+			day = 1
+		}
+		start := time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location())
+		if start.After(t) {
+			start = start.AddDate(0, -1, 0)
+		}
+		return start
+	}
+	back := (int(t.Weekday()) - int(p.Weekday) + 7) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-back, 0, 0, 0, 0, t.Location())
+}
+
+// End returns the end of the period containing t, which is when the next
+// one starts
+func (p Period) End(t time.Time) time.Time {
+	return p.next(p.Start(t))
+}
+
+// Previous returns the start of the period before the one containing t
+func (p Period) Previous(t time.Time) time.Time {
+	start := p.Start(t)
+	switch p.Kind {
+	case Daily:
+		return start.AddDate(0, 0, -1)
+	case Monthly:
+		return start.AddDate(0, -1, 0)
+	}
+	return start.AddDate(0, 0, -7)
+}
+
+// next returns the start of the period after the one starting at start
+func (p Period) next(start time.Time) time.Time {
+	switch p.Kind {
+	case Daily:
+		return start.AddDate(0, 0, 1)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// Adjective names the period for messages, e.g. "weekly"
+func (p Period) Adjective() string {
+	if p.Kind == "" {
+		return Weekly
+	}
+	return p.Kind
+}
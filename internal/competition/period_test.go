@@ -0,0 +1,62 @@
+package competition
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodStartEnd(t *testing.T) {
+	utc := time.UTC
+	// Wednesday 2026-03-18 15:30 UTC
+	now := time.Date(2026, 3, 18, 15, 30, 0, 0, utc)
+
+	tests := []struct {
+		kind, resetDay string
+		start, end     time.Time
+	}{
+		{"", "", time.Date(2026, 3, 15, 0, 0, 0, 0, utc), time.Date(2026, 3, 22, 0, 0, 0, 0, utc)},
+		{"weekly", "monday", time.Date(2026, 3, 16, 0, 0, 0, 0, utc), time.Date(2026, 3, 23, 0, 0, 0, 0, utc)},
+		{"weekly", "wed", time.Date(2026, 3, 18, 0, 0, 0, 0, utc), time.Date(2026, 3, 25, 0, 0, 0, 0, utc)},
+		{"daily", "", time.Date(2026, 3, 18, 0, 0, 0, 0, utc), time.Date(2026, 3, 19, 0, 0, 0, 0, utc)},
+		{"monthly", "", time.Date(2026, 3, 1, 0, 0, 0, 0, utc), time.Date(2026, 4, 1, 0, 0, 0, 0, utc)},
+		{"monthly", "20", time.Date(2026, 2, 20, 0, 0, 0, 0, utc), time.Date(2026, 3, 20, 0, 0, 0, 0, utc)},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.kind, tt.resetDay, "UTC")
+		if err != nil {
+			t.Fatalf("New(%q, %q): %v", tt.kind, tt.resetDay, err)
+		}
+		if got := p.Start(now); !got.Equal(tt.start) {
+			t.Errorf("%s/%s: start %v, want %v", tt.kind, tt.resetDay, got, tt.start)
+		}
+		if got := p.End(now); !got.Equal(tt.end) {
+			t.Errorf("%s/%s: end %v, want %v", tt.kind, tt.resetDay, got, tt.end)
+		}
+	}
+}
+
+func TestPeriodTimezone(t *testing.T) {
+	p, err := New("daily", "", "Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// 20:00 UTC is already the next day in Tokyo (UTC+9)
+	now := time.Date(2026, 3, 18, 20, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 18, 15, 0, 0, 0, time.UTC)
+	if got := p.Start(now); !got.Equal(want) {
+		t.Errorf("start %v, want %v", got.UTC(), want)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, args := range [][3]string{
+		{"fortnightly", "", ""},
+		{"weekly", "someday", ""},
+		{"monthly", "31", ""},
+		{"weekly", "", "Mars/Olympus_Mons"},
+	} {
+		if _, err := New(args[0], args[1], args[2]); err == nil {
+			t.Errorf("New(%q, %q, %q) should fail", args[0], args[1], args[2])
+		}
+	}
+}
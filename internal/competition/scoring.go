@@ -0,0 +1,56 @@
+package competition
+
+import "github.com/camarigor/miner-hq/internal/units"
+
+// Scoring modes
+const (
+	Raw      = "raw"      // Best share difficulty
+	Expected = "expected" // Best share difficulty per TH/s of the miner's expected hashrate
+	Average  = "average"  // Best share difficulty per TH/s of the miner's average hashrate over the period
+)
+
+// Scorer ranks miners' best shares of a period under one scoring mode. The
+// leaderboard, the new leader alert, the digest and the final standings all
+// score with it.
+type Scorer struct {
+	Mode     string             // Raw, Expected or Average; empty means Raw
+	Expected map[string]float64 // Configured expected hashrate in GH/s, by miner IP
+	Average  map[string]float64 // Hashrate in GH/s averaged over the period, by miner IP
+}
+
+// Handicapped reports whether best shares are divided by hashrate, so small
+// miners compete on luck rather than size
+func (s Scorer) Handicapped() bool {
+	return s.Mode == Expected || s.Mode == Average
+}
+
+// Hashrate returns the GH/s a miner's best share is divided by: its expected
+// hashrate for Expected (falling back to the period's average), or its
+// average over the period for Average. It returns 0 when unknown or when the
+// scoring isn't handicapped.
+func (s Scorer) Hashrate(ip string) float64 {
+	switch s.Mode {
+	case Expected:
+		if ghs := s.Expected[ip]; ghs > 0 {
+			return ghs
+		}
+		return s.Average[ip]
+	case Average:
+		return s.Average[ip]
+	}
+	return 0
+}
+
+// Score returns the ranking value of a miner's best share: its difficulty,
+// or its difficulty per TH/s when handicapped. Handicapped miners with an
+// unknown hashrate score 0.
+func (s Scorer) Score(ip string, bestDiff float64) float64 {
+	if !s.Handicapped() {
+		return bestDiff
+	}
+	ghs := s.Hashrate(ip)
+	if ghs <= 0 {
+		return 0
+	}
+	return bestDiff / (ghs / units.TeraHash)
+}
@@ -0,0 +1,29 @@
+package competition
+
+import "testing"
+
+func TestScorer(t *testing.T) {
+	expected := map[string]float64{"10.0.0.1": 500}
+	average := map[string]float64{"10.0.0.1": 400, "10.0.0.2": 2000}
+
+	tests := []struct {
+		mode string
+		ip   string
+		want float64
+	}{
+		{"", "10.0.0.1", 1000},
+		{Raw, "10.0.0.3", 1000},
+		{Expected, "10.0.0.1", 2000},
+		{Expected, "10.0.0.2", 500}, // Falls back to the average
+		{Expected, "10.0.0.3", 0},
+		{Average, "10.0.0.1", 2500},
+		{Average, "10.0.0.2", 500},
+		{Average, "10.0.0.3", 0},
+	}
+	for _, tt := range tests {
+		s := Scorer{Mode: tt.mode, Expected: expected, Average: average}
+		if got := s.Score(tt.ip, 1000); got != tt.want {
+			t.Errorf("%q scoring of %s: got %v, want %v", tt.mode, tt.ip, got, tt.want)
+		}
+	}
+}
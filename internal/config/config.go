@@ -2,8 +2,17 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/camarigor/miner-hq/internal/competition"
+	"github.com/camarigor/miner-hq/internal/units"
 )
 
 // MinerConfig defines a single miner device to monitor
@@ -13,14 +22,20 @@ type MinerConfig struct {
 	Port     int    `json:"port"`
 	Enabled  bool   `json:"enabled"`
 	Location string `json:"location,omitempty"`
+
+	ExpectedHashrate float64 `json:"expected_hashrate_ghs,omitempty"` // Nominal hashrate for handicapped competitions
+	PollIntervalSecs int     `json:"poll_interval_secs,omitempty"`    // Overrides polling.interval_secs for this miner
 }
 
 // AlertConfig defines alerting thresholds and settings
 type AlertConfig struct {
 	Enabled            bool    `json:"enabled"`
-	HashrateDropPct    float64 `json:"hashrate_drop_pct"`    // Alert if hashrate drops by this percentage
+	HashrateDropPct    float64 `json:"hashrate_drop_pct"`    // Alert if the 10-minute average hashrate drops by this percentage
+	HashrateDropChecks int     `json:"hashrate_drop_checks"` // ...for this many consecutive checks
+	PoolDiffChangePct  float64 `json:"pool_diff_change_pct"` // Alert if pool difficulty moves by this percentage (100 = doubled or halved), 0 = off
 	TempThresholdC     float64 `json:"temp_threshold_c"`     // Alert if temp exceeds this value
 	OfflineMinutes     int     `json:"offline_minutes"`      // Alert if miner offline for this duration
+	FrozenMinutes      int     `json:"frozen_minutes"`       // Alert if miner repeats identical data for this duration
 	ShareRejectPct     float64 `json:"share_reject_pct"`     // Alert if rejection rate exceeds this
 	FanRPMBelow        int     `json:"fan_rpm_below"`        // Alert if fan RPM drops below this
 	WifiSignalBelow    int     `json:"wifi_signal_below"`    // Alert if WiFi signal drops below this (dBm)
@@ -29,26 +44,241 @@ type AlertConfig struct {
 	OnNewBestDiff      bool    `json:"on_new_best_diff"`     // Alert on new best difficulty
 	OnBlockFound       bool    `json:"on_block_found"`       // Alert when a block is found
 	OnNewLeader        bool    `json:"on_new_leader"`        // Alert when weekly leader changes
+	OnFirmwareMismatch bool    `json:"on_firmware_mismatch"` // Alert when a miner's firmware differs from its model group
+	OnRecovery         bool    `json:"on_recovery"`          // Alert when an offline miner, lost pool or high temperature recovers
+	OnDigest           bool    `json:"on_digest"`            // Send a summary when a competition period ends
+	OnPoolUnreachable  bool    `json:"on_pool_unreachable"`  // Alert when a pool fails MinerHQ's own pool checks
+	OnPoolMismatch     bool    `json:"on_pool_mismatch"`     // Alert when a pool credits a worker with a hashrate far from the miner's
 	WebhookURL         string  `json:"webhook_url,omitempty"`
+	MatrixHomeserver   string  `json:"matrix_homeserver,omitempty"`   // e.g. https://matrix.example.org
+	MatrixAccessToken  string  `json:"matrix_access_token,omitempty"` // Token of the bot account posting alerts
+	MatrixRoomID       string  `json:"matrix_room_id,omitempty"`      // Internal room ID, e.g. !abc123:example.org
 	EmailEnabled       bool    `json:"email_enabled"`
 	EmailSMTPServer    string  `json:"email_smtp_server,omitempty"`
 	EmailSMTPPort      int     `json:"email_smtp_port,omitempty"`
 	EmailFrom          string  `json:"email_from,omitempty"`
 	EmailTo            string  `json:"email_to,omitempty"`
 	EmailPassword      string  `json:"email_password,omitempty"`
+
+	// Extra channels: JSON webhooks, Slack, ntfy and Pushover
+	Notifiers []NotifierConfig `json:"notifiers,omitempty"`
+
+	QuietHours QuietHoursConfig `json:"quiet_hours"` // Hold back alerts at night
+}
+
+// Quiet hours modes
+const (
+	QuietQueue    = "queue"
+	QuietSuppress = "suppress"
+)
+
+// QuietHoursConfig holds back alerts in a daily time window, e.g. at night.
+// Start/End are local "HH:MM"; End <= Start wraps past midnight. Empty Days
+// match every day. Block found alerts are always sent.
+type QuietHoursConfig struct {
+	Start string   `json:"start,omitempty"` // Empty = no quiet hours
+	End   string   `json:"end,omitempty"`
+	Days  []string `json:"days,omitempty"`  // "mon".."sun", the day the window starts
+	Mode  string   `json:"mode,omitempty"`  // "queue" (default) sends held alerts when the window ends, "suppress" drops them
+	Allow []string `json:"allow,omitempty"` // Alert types sent anyway, e.g. "miner_offline"
+}
+
+// Active reports whether quiet hours apply at t (local time)
+func (q QuietHoursConfig) Active(t time.Time) bool {
+	if q.Start == "" {
+		return false
+	}
+	window := TariffPeriod{Start: q.Start, End: q.End, Days: q.Days}
+	return window.Matches(t)
+}
+
+// Queues reports whether alerts held during quiet hours are sent when they
+// end, rather than dropped
+func (q QuietHoursConfig) Queues() bool {
+	return q.Mode != QuietSuppress
+}
+
+// Notifier types
+const (
+	NotifierWebhook  = "webhook"
+	NotifierSlack    = "slack"
+	NotifierNtfy     = "ntfy"
+	NotifierPushover = "pushover"
+)
+
+// NotifierConfig defines an alert channel besides Discord and Matrix
+type NotifierConfig struct {
+	Type       string            `json:"type"`                  // "webhook", "slack", "ntfy" or "pushover"
+	Name       string            `json:"name,omitempty"`        // Shown in logs and failed deliveries, defaults to the type
+	URL        string            `json:"url,omitempty"`         // JSON webhook, Slack incoming webhook or ntfy topic URL
+	Token      string            `json:"token,omitempty"`       // ntfy access token or Pushover application token
+	User       string            `json:"user,omitempty"`        // Pushover user or group key
+	Headers    map[string]string `json:"headers,omitempty"`     // Extra headers for JSON webhooks, e.g. Authorization
+	AlertTypes []string          `json:"alert_types,omitempty"` // Alert types sent to this channel, empty = all
+}
+
+// ChannelName returns the name the notifier is logged and dead-lettered as
+func (n NotifierConfig) ChannelName() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.Type
 }
 
 // EnergyConfig defines energy cost settings for profitability calculations
 type EnergyConfig struct {
-	CostPerKWh float64 `json:"cost_per_kwh"` // Cost in local currency per kWh
-	Currency   string  `json:"currency"`     // Currency code (USD, EUR, etc.)
+	CostPerKWh float64          `json:"cost_per_kwh"`        // Cost in local currency per kWh
+	Currency   string           `json:"currency"`            // Currency code (USD, EUR, etc.)
+	Tariffs    []TariffPeriod   `json:"tariffs,omitempty"`   // Time-of-use rates overriding CostPerKWh
+	Locations  []EnergyLocation `json:"locations,omitempty"` // Per-location rates overriding CostPerKWh
+}
+
+// EnergyLocation defines the electricity rate for a group of miners on the
+// same meter (garage, remote site). Rates are in EnergyConfig.Currency.
+type EnergyLocation struct {
+	Name       string         `json:"name"`
+	CostPerKWh float64        `json:"cost_per_kwh"`
+	Tariffs    []TariffPeriod `json:"tariffs,omitempty"` // Time-of-use rates for this location
+}
+
+// TariffPeriod is a time-of-use rate window, e.g. peak 17:00-21:00 on
+// weekdays in summer. Start/End are local "HH:MM"; End <= Start wraps past
+// midnight. Empty Days/Months match every day/month.
+type TariffPeriod struct {
+	Name       string   `json:"name"`
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Days       []string `json:"days,omitempty"`   // "mon".."sun"
+	Months     []int    `json:"months,omitempty"` // 1-12, for seasonal rates
+	CostPerKWh float64  `json:"cost_per_kwh"`
 }
 
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Matches reports whether the period applies at t (local time). For windows
+// that wrap past midnight, Days/Months refer to the day the window starts.
+func (p *TariffPeriod) Matches(t time.Time) bool {
+	start, err1 := parseClock(p.Start)
+	end, err2 := parseClock(p.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t
+	switch {
+	case start < end:
+		if minute < start || minute >= end {
+			return false
+		}
+	default: // Wraps past midnight (or spans the whole day when equal)
+		if minute < start && minute >= end {
+			return false
+		}
+		if minute < start {
+			day = t.AddDate(0, 0, -1)
+		}
+	}
+
+	if len(p.Months) > 0 {
+		found := false
+		for _, m := range p.Months {
+			if time.Month(m) == day.Month() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(p.Days) > 0 {
+		found := false
+		for _, d := range p.Days {
+			if wd, ok := weekdayNames[strings.ToLower(d)]; ok && wd == day.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CostFor returns the flat electricity rate for a location, falling back to
+// the global CostPerKWh for an empty or unknown location.
+func (e *EnergyConfig) CostFor(location string) float64 {
+	if location != "" {
+		for _, l := range e.Locations {
+			if l.Name == location {
+				return l.CostPerKWh
+			}
+		}
+	}
+	return e.CostPerKWh
+}
+
+// RateAt returns the electricity rate for a location at time t. The first
+// matching tariff period wins; the location's tariffs replace the global
+// ones. Without a matching period the flat rate from CostFor applies.
+func (e *EnergyConfig) RateAt(location string, t time.Time) float64 {
+	tariffs := e.Tariffs
+	if location != "" {
+		for _, l := range e.Locations {
+			if l.Name == location && len(l.Tariffs) > 0 {
+				tariffs = l.Tariffs
+				break
+			}
+		}
+	}
+	for i := range tariffs {
+		if tariffs[i].Matches(t) {
+			return tariffs[i].CostPerKWh
+		}
+	}
+	return e.CostFor(location)
+}
+
+// HasLocation reports whether a location is defined
+func (e *EnergyConfig) HasLocation(location string) bool {
+	for _, l := range e.Locations {
+		if l.Name == location {
+			return true
+		}
+	}
+	return false
+}
+
+// Price providers
+const (
+	PriceBinance     = "binance"
+	PriceCoinGecko   = "coingecko"
+	PriceKraken      = "kraken"
+	PriceCoinPaprika = "coinpaprika"
+)
+
 // PricingConfig defines cryptocurrency price fetching settings
 type PricingConfig struct {
-	Enabled        bool          `json:"enabled"`
-	UpdateInterval time.Duration `json:"update_interval"`
-	FiatCurrency   string        `json:"fiat_currency"`
+	Enabled         bool          `json:"enabled"`
+	UpdateInterval  time.Duration `json:"update_interval"`
+	FiatCurrency    string        `json:"fiat_currency"`     // Display currency for costs and earnings (converted via ECB rates)
+	Providers       []string      `json:"providers"`         // Price APIs to try, in order of priority
+	CoinGeckoAPIKey string        `json:"coingecko_api_key"` // Optional, raises CoinGecko's rate limit
+	CoinGeckoPro    bool          `json:"coingecko_pro"`     // The key is for the paid Pro API rather than a free demo key
 }
 
 // RetentionConfig defines data retention policies
@@ -56,15 +286,246 @@ type RetentionConfig struct {
 	MetricsRetentionDays  int `json:"metrics_retention_days"`  // How long to keep detailed metrics
 	SharesRetentionDays   int `json:"shares_retention_days"`   // How long to keep share data
 	AlertsRetentionDays   int `json:"alerts_retention_days"`   // How long to keep alert history
+	AuditRetentionDays    int `json:"audit_retention_days,omitempty"` // How long to keep the audit log (0 = a year)
 	AggregationIntervalH  int `json:"aggregation_interval_h"`  // Hours between aggregation runs
+	DryRun                bool `json:"dry_run"`                // Only log what the retention purges would delete
+
+	// Miners are polled every few seconds for live updates and alerts; these
+	// thin out what is written to the database
+	SnapshotEvery         int `json:"snapshot_every,omitempty"`          // Store every Nth poll per miner (0 or 1 = every poll)
+	SnapshotIntervalSecs  int `json:"snapshot_interval_secs,omitempty"`  // Store at most one snapshot per miner this often (0 = no limit)
+	SnapshotFlushSecs     int `json:"snapshot_flush_secs,omitempty"`     // Write stored snapshots in one transaction this often (0 = 5 seconds)
+	SnapshotUnchangedSecs int `json:"snapshot_unchanged_secs,omitempty"` // Store snapshots that repeat the last stored one at most this often (0 = like any other)
+
+	// How the disk space freed by purges is reclaimed: "incremental"
+	// (default), "full" or "off", see storage.Compact
+	Vacuum string `json:"vacuum,omitempty"`
+}
+
+// ExportConfig defines scheduled daily exports of snapshot rollups, shares and blocks
+type ExportConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Directory string   `json:"directory"`           // Local directory the files are written to
+	Formats   []string `json:"formats"`             // "csv" and/or "json"
+	Time      string   `json:"time"`                // Local "HH:MM" at which the previous day is exported
+	SFTP      string   `json:"sftp,omitempty"`      // Optional upload target, "user@host:/path"
+	SFTPPort  int      `json:"sftp_port,omitempty"` // Defaults to 22
+	SFTPKey   string   `json:"sftp_key,omitempty"`  // Private key file for SFTP authentication
+}
+
+// MQTTConfig defines publishing of miner events to an MQTT broker.
+// Topic templates may use {prefix}, {ip}, {hostname} and, for alerts, {type}.
+type MQTTConfig struct {
+	Enabled              bool       `json:"enabled"`
+	Broker               string     `json:"broker"`                 // "tcp://host:1883", or "tls://host:8883"
+	ClientID             string     `json:"client_id"`              // Must be unique per broker
+	Username             string     `json:"username,omitempty"`     // Optional broker login
+	Password             string     `json:"password,omitempty"`     // Optional broker login
+	Insecure             bool       `json:"insecure,omitempty"`     // Accept a self-signed broker certificate
+	TopicPrefix          string     `json:"topic_prefix"`           // Substituted for {prefix}; "{prefix}/status" reports online/offline
+	Topics               MQTTTopics `json:"topics"`                 // Topic template per event
+	SnapshotIntervalSecs int        `json:"snapshot_interval_secs"` // Publish at most one snapshot per miner this often (0 = every poll)
+	RetainSnapshots      bool       `json:"retain_snapshots"`       // Let new subscribers see the last snapshot
+}
+
+// TSDBConfig defines forwarding of every polled snapshot to a time series
+// database, so long-term metrics don't have to live in SQLite
+type TSDBConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Format    string `json:"format"`             // "influx" (line protocol) or "remote_write" (Prometheus remote-write)
+	URL       string `json:"url"`                // Write endpoint, e.g. InfluxDB's /api/v2/write or VictoriaMetrics' /api/v1/write
+	Token     string `json:"token,omitempty"`    // InfluxDB 2 API token, or bearer token for remote-write
+	Username  string `json:"username,omitempty"` // Optional HTTP Basic login
+	Password  string `json:"password,omitempty"` // Optional HTTP Basic login
+	FlushSecs int    `json:"flush_secs"`         // Seconds between batched writes
+}
+
+// AgentConfig runs MinerHQ as the agent of a remote site, forwarding the
+// snapshots, shares and blocks of its miners to a central MinerHQ
+type AgentConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Central   string `json:"central"`         // URL of the central MinerHQ, e.g. "https://minerhq.example.com"
+	Token     string `json:"token,omitempty"` // The site's token, as configured in the central server's sites
+	FlushSecs int    `json:"flush_secs"`      // Seconds between batches sent to the central server
+}
+
+// SiteConfig is a remote site whose agent may forward its miners' data to
+// this server. Its miners are listed as "<ip>@<name>".
+type SiteConfig struct {
+	Name      string `json:"name"`
+	Token     string `json:"token,omitempty"` // Replaced with token_hash on load
+	TokenHash string `json:"token_hash,omitempty"`
+}
+
+// WatchdogConfig defines automatic restarts of miners that stay reachable
+// but stop hashing or submitting shares
+type WatchdogConfig struct {
+	Enabled        bool     `json:"enabled"`
+	StuckMinutes   int      `json:"stuck_minutes"`    // Minutes at 0 GH/s, frozen or without shares before a restart
+	BackoffMinutes int      `json:"backoff_minutes"`  // Wait after a restart, doubled for every further one in a row
+	MaxRestarts    int      `json:"max_restarts"`     // Restarts per miner per 24 hours before the watchdog gives up
+	Miners         []string `json:"miners,omitempty"` // IPs of the watched miners, all local miners if empty
+}
+
+// PoolCheckConfig defines MinerHQ's own checks of the pools the miners are
+// configured for
+type PoolCheckConfig struct {
+	Enabled      bool `json:"enabled"`
+	IntervalSecs int  `json:"interval_secs"` // Seconds between checks of a pool
+	TimeoutSecs  int  `json:"timeout_secs"`  // Seconds a pool has to answer the stratum handshake
+	Failures     int  `json:"failures"`      // Failed checks in a row before the pool counts as unreachable
+}
+
+// PoolStatsConfig defines the pool accounts whose worker stats are fetched
+// and reconciled with what the miners report
+type PoolStatsConfig struct {
+	Enabled         bool                `json:"enabled"`
+	IntervalSecs    int                 `json:"interval_secs"`    // Seconds between fetches of an account
+	DivergencePct   float64             `json:"divergence_pct"`   // Pool-side hashrate this far from the miner's, in % of the miner's, counts as diverging
+	DivergentChecks int                 `json:"divergent_checks"` // Diverging fetches in a row before alerting
+	Accounts        []PoolAccountConfig `json:"accounts"`
+}
+
+// PoolAccountConfig is an account at a pool with a public stats API, i.e.
+// the payout address the miners mine to
+type PoolAccountConfig struct {
+	Pool    string            `json:"pool"`              // "ckpool" (solo.ckpool.org) or "public-pool" (public-pool.io)
+	Address string            `json:"address"`           // Payout address, the stratum user without the worker name
+	URL     string            `json:"url,omitempty"`     // API of a self-hosted instance, e.g. "http://umbrel.local:2019"; empty = the public pool's
+	Workers map[string]string `json:"workers,omitempty"` // Worker name to miner IP, for workers not named after their miner's hostname
+}
+
+// StratumProxyConfig defines the built-in stratum proxy, which miners can
+// point at instead of their pool
+type StratumProxyConfig struct {
+	Enabled bool              `json:"enabled"`
+	Pools   []ProxyPoolConfig `json:"pools"`
+}
+
+// ProxyPoolConfig is a pool the stratum proxy relays the miners connecting to
+// its port to, over one connection
+type ProxyPoolConfig struct {
+	Port       int     `json:"port"`                 // Port the miners connect to, e.g. 3333
+	URL        string  `json:"url"`                  // The pool with its port, e.g. "stratum+tcp://public-pool.io:21496"
+	User       string  `json:"user"`                 // Worker the shares are submitted as, e.g. "<address>.minerhq"
+	Password   string  `json:"password,omitempty"`   // Usually "x"
+	Difficulty float64 `json:"difficulty,omitempty"` // Difficulty set on the miners when below the pool's, so shares the pool doesn't need are recorded too; 0 = the pool's
+}
+
+// SmartPlugsConfig defines the smart plugs miners are powered through
+type SmartPlugsConfig struct {
+	PollSecs int          `json:"poll_secs"` // Seconds between power readings
+	OffSecs  int          `json:"off_secs"`  // How long a power cycle keeps the miner off
+	Plugs    []PlugConfig `json:"plugs"`
+}
+
+// SchedulerConfig defines the mining scheduler. Schedules and overrides are
+// managed through the API; this sets where external signals come from.
+type SchedulerConfig struct {
+	Enabled       bool           `json:"enabled"`
+	MinRunMinutes int            `json:"min_run_minutes"`   // Keep a pause or underclock at least this long, so a signal hovering around its threshold doesn't flap miners
+	Signals       []SignalConfig `json:"signals,omitempty"` // Signals read from the MQTT broker
+}
+
+// SignalConfig reads an external signal, such as a solar inverter's surplus
+// power, from an MQTT topic. Signals can also be pushed over HTTP without
+// being listed here.
+type SignalConfig struct {
+	Name  string `json:"name"`
+	Topic string `json:"topic"`           // Topic on the broker of the mqtt section
+	Field string `json:"field,omitempty"` // JSON field holding the value, dotted for nested fields; empty for plain number payloads
+}
+
+// PlugConfig maps a smart plug to the miner it powers
+type PlugConfig struct {
+	Miner    string `json:"miner"`              // IP of the miner on the plug
+	Type     string `json:"type"`               // "tasmota", "shelly", "shelly_rpc" (Plus/Pro and later) or "tplink" (Kasa)
+	Address  string `json:"address"`            // The plug's host, or host:port
+	Relay    int    `json:"relay,omitempty"`    // Outlet of a multi-outlet plug, from 0
+	Username string `json:"username,omitempty"` // Optional plug login (Tasmota, Shelly)
+	Password string `json:"password,omitempty"` // Optional plug login (Tasmota, Shelly)
+}
+
+// MQTTTopics are the topic templates of the published events
+type MQTTTopics struct {
+	Snapshot string `json:"snapshot"`
+	Share    string `json:"share"`
+	Block    string `json:"block"`
+	Alert    string `json:"alert"`
+}
+
+// CompetitionConfig defines how the best share competition is scored and
+// how long each round lasts
+type CompetitionConfig struct {
+	// Scoring is "raw" (best difficulty), "expected" (best difficulty per TH/s
+	// of each miner's expected_hashrate_ghs) or "average" (per TH/s of the
+	// miner's reported 24h average hashrate)
+	Scoring string `json:"scoring"`
+
+	Period   string `json:"period"`    // "daily", "weekly" or "monthly"
+	ResetDay string `json:"reset_day"` // Weekday for weekly periods ("sun"), day of the month (1-28) for monthly ones
+	Timezone string `json:"timezone"`  // IANA time zone of the midnight reset, empty for the server's
+}
+
+// CompetitionPeriod returns the configured competition period, or weekly
+// periods from Sunday local time if it is invalid (see Validate)
+func (c CompetitionConfig) CompetitionPeriod() competition.Period {
+	p, err := competition.New(c.Period, c.ResetDay, c.Timezone)
+	if err != nil {
+		return competition.Period{}
+	}
+	return p
+}
+
+// ExpectedHashrates returns the configured expected hashrate in GH/s of each
+// miner that has one, by IP, for "expected" competition scoring
+func (c *Config) ExpectedHashrates() map[string]float64 {
+	expected := make(map[string]float64)
+	for _, m := range c.Miners {
+		if m.ExpectedHashrate > 0 {
+			expected[m.IP] = m.ExpectedHashrate
+		}
+	}
+	return expected
+}
+
+// PollingConfig defines how often miners are polled. Miners that fail
+// backoff_after polls in a row are polled half as often after each further
+// failure, up to max_interval_secs, until they answer again.
+type PollingConfig struct {
+	IntervalSecs    int `json:"interval_secs"`     // Seconds between polls of each miner
+	BackoffAfter    int `json:"backoff_after"`     // Failed polls in a row before backing off (0 = never back off)
+	MaxIntervalSecs int `json:"max_interval_secs"` // Longest interval while backing off
+}
+
+// MinerLogsConfig defines capture of the raw log lines miners stream over
+// their WebSocket, for the log viewer and live tailing
+type MinerLogsConfig struct {
+	BufferLines   int  `json:"buffer_lines"`             // Recent lines kept in memory per miner (0 = keep none)
+	Persist       bool `json:"persist"`                  // Also store every line in the database
+	RetentionDays int  `json:"retention_days,omitempty"` // How long stored lines are kept (default 3)
 }
 
 // ScannerConfig defines network scanner settings
 type ScannerConfig struct {
 	Enabled      bool          `json:"enabled"`
-	Networks     []string      `json:"networks"`      // CIDR ranges (empty = auto-detect)
+	Networks     []string      `json:"networks"`      // CIDRs, address ranges or addresses (empty = auto-detect)
 	ScanInterval time.Duration `json:"scan_interval"`
 	AutoAdd      bool          `json:"auto_add"`      // Automatically add discovered miners
+	DHCP         DHCPConfig    `json:"dhcp"`
+}
+
+// DHCPConfig points the scanner at the router's DHCP leases, so only leased
+// addresses that look like miners are probed instead of sweeping subnets
+type DHCPConfig struct {
+	Source           string   `json:"source,omitempty"`            // "opnsense", "pfsense", "openwrt" or "dnsmasq"; empty = subnet sweep
+	URL              string   `json:"url,omitempty"`               // Router base URL, e.g. https://192.168.1.1
+	Username         string   `json:"username,omitempty"`          // API key (opnsense) or login user (openwrt)
+	Password         string   `json:"password,omitempty"`          // API secret (opnsense), REST API key (pfsense) or login password (openwrt)
+	Insecure         bool     `json:"insecure,omitempty"`          // Accept the router's self-signed certificate
+	LeaseFile        string   `json:"lease_file,omitempty"`        // dnsmasq lease file, e.g. /var/lib/misc/dnsmasq.leases
+	HostnamePrefixes []string `json:"hostname_prefixes,omitempty"` // Leases to probe by hostname (default: stock miner hostnames)
+	MACPrefixes      []string `json:"mac_prefixes,omitempty"`      // Leases to probe by MAC (default: Espressif OUIs)
 }
 
 // ServerConfig defines HTTP server settings
@@ -73,25 +534,109 @@ type ServerConfig struct {
 	Port         int    `json:"port"`
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
+	ReadOnly     bool          `json:"read_only"` // Reject all mutating API requests (public status page)
+
+	// Reverse proxies, as IPs or CIDRs, whose X-Forwarded-For and X-Real-IP
+	// headers name the client. Other peers' headers are ignored.
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+}
+
+// UserConfig defines a login account. Set Password to a plaintext value and it
+// is replaced with PasswordHash on the next start.
+type UserConfig struct {
+	Username     string `json:"username"`
+	Password     string `json:"password,omitempty"`
+	PasswordHash string `json:"password_hash,omitempty"`
+	Role         string `json:"role"` // "admin" or "viewer"
+}
+
+// TokenConfig defines an API token for scripts and integrations, sent as
+// "Authorization: Bearer <token>". Set Token to a plaintext value and it is
+// replaced with TokenHash on the next start.
+type TokenConfig struct {
+	Name      string `json:"name"`
+	Token     string `json:"token,omitempty"`
+	TokenHash string `json:"token_hash,omitempty"`
+	Role      string `json:"role"` // "admin" or "viewer"
+}
+
+// AuthConfig defines authentication and user roles
+type AuthConfig struct {
+	Enabled      bool          `json:"enabled"`
+	Users        []UserConfig  `json:"users"`
+	Tokens       []TokenConfig `json:"tokens"`
+	SessionHours int           `json:"session_hours"` // How long a web UI login lasts
 }
 
-// DisplayConfig defines chart display preferences
+// DisplayConfig defines display preferences. The unit and time settings also
+// apply to alert messages, API summaries and CSV exports.
 type DisplayConfig struct {
 	SharesMinDifficulty float64 `json:"shares_min_difficulty"` // Hide shares below this difficulty (0 = show all)
+	TemperatureUnit     string  `json:"temperature_unit"`      // "C" or "F"
+	TimeFormat          string  `json:"time_format"`           // "24h" or "12h"
+	HashrateUnit        string  `json:"hashrate_unit"`         // "auto" to scale per value, or a fixed unit like "TH/s"
+}
+
+// Units returns the preferences as a formatter for server-side output
+func (d DisplayConfig) Units() units.Display {
+	return units.Display{
+		TemperatureUnit: d.TemperatureUnit,
+		TimeFormat:      d.TimeFormat,
+		HashrateUnit:    d.HashrateUnit,
+	}
 }
 
 // Config is the main configuration structure
 type Config struct {
-	Server    ServerConfig    `json:"server"`
-	Miners    []MinerConfig   `json:"miners"`
-	Alerts    AlertConfig     `json:"alerts"`
-	Energy    EnergyConfig    `json:"energy"`
-	Pricing   PricingConfig   `json:"pricing"`
-	Retention RetentionConfig `json:"retention"`
-	Scanner   ScannerConfig   `json:"scanner"`
-	Display   DisplayConfig   `json:"display"`
-	DBPath    string          `json:"db_path"`
-	LogLevel  string          `json:"log_level"`
+	Server       ServerConfig       `json:"server"`
+	Miners       []MinerConfig      `json:"miners"`
+	Alerts       AlertConfig        `json:"alerts"`
+	Energy       EnergyConfig       `json:"energy"`
+	Pricing      PricingConfig      `json:"pricing"`
+	Retention    RetentionConfig    `json:"retention"`
+	Polling      PollingConfig      `json:"polling"`
+	MinerLogs    MinerLogsConfig    `json:"miner_logs"`
+	Export       ExportConfig       `json:"export"`
+	MQTT         MQTTConfig         `json:"mqtt"`
+	TSDB         TSDBConfig         `json:"tsdb"`
+	Agent        AgentConfig        `json:"agent"`
+	Sites        []SiteConfig       `json:"sites"`
+	Watchdog     WatchdogConfig     `json:"watchdog"`
+	PoolCheck    PoolCheckConfig    `json:"pool_check"`
+	PoolStats    PoolStatsConfig    `json:"pool_stats"`
+	StratumProxy StratumProxyConfig `json:"stratum_proxy"`
+	SmartPlugs   SmartPlugsConfig   `json:"smart_plugs"`
+	Scheduler    SchedulerConfig    `json:"scheduler"`
+	Competition  CompetitionConfig  `json:"competition"`
+	Scanner      ScannerConfig      `json:"scanner"`
+	Display      DisplayConfig      `json:"display"`
+	Auth         AuthConfig         `json:"auth"`
+	DBPath       string             `json:"db_path"`
+	LogLevel     string             `json:"log_level"`
+}
+
+// validSiteName reports whether name can name a site: it is appended to the
+// IPs of the site's miners, so only letters, digits, - and _ are allowed
+func validSiteName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validMQTTScheme reports whether scheme is a broker URL scheme the MQTT
+// publisher can connect with
+func validMQTTScheme(scheme string) bool {
+	switch scheme {
+	case "tcp", "mqtt", "tls", "ssl", "mqtts":
+		return true
+	}
+	return false
 }
 
 // DefaultConfig returns a Config with sensible default values
@@ -107,8 +652,10 @@ func DefaultConfig() *Config {
 		Alerts: AlertConfig{
 			Enabled:            true,
 			HashrateDropPct:    20.0,
+			HashrateDropChecks: 3,
 			TempThresholdC:     80.0,
 			OfflineMinutes:     5,
+			FrozenMinutes:      5,
 			ShareRejectPct:     5.0,
 			FanRPMBelow:        1000,
 			WifiSignalBelow:    -70,
@@ -117,6 +664,10 @@ func DefaultConfig() *Config {
 			OnNewBestDiff:      false,
 			OnBlockFound:       true,
 			OnNewLeader:        true,
+			OnRecovery:         true,
+			OnDigest:           true,
+			OnPoolUnreachable:  true,
+			OnPoolMismatch:     true,
 			EmailSMTPPort:      587,
 		},
 		Energy: EnergyConfig{
@@ -127,6 +678,7 @@ func DefaultConfig() *Config {
 			Enabled:        true,
 			UpdateInterval: 5 * time.Minute,
 			FiatCurrency:   "USD",
+			Providers:      []string{PriceBinance, PriceCoinGecko, PriceKraken, PriceCoinPaprika},
 		},
 		Retention: RetentionConfig{
 			MetricsRetentionDays: 30,
@@ -134,12 +686,95 @@ func DefaultConfig() *Config {
 			AlertsRetentionDays:  90,
 			AggregationIntervalH: 1,
 		},
+		Polling: PollingConfig{
+			IntervalSecs:    2,
+			BackoffAfter:    3,
+			MaxIntervalSecs: 60,
+		},
+		MinerLogs: MinerLogsConfig{
+			BufferLines:   1000,
+			RetentionDays: 3,
+		},
+		Export: ExportConfig{
+			Enabled:   false,
+			Directory: "/data/exports",
+			Formats:   []string{"csv"},
+			Time:      "00:30",
+		},
+		MQTT: MQTTConfig{
+			Enabled:     false,
+			Broker:      "tcp://localhost:1883",
+			ClientID:    "minerhq",
+			TopicPrefix: "minerhq",
+			Topics: MQTTTopics{
+				Snapshot: "{prefix}/{hostname}/snapshot",
+				Share:    "{prefix}/{hostname}/share",
+				Block:    "{prefix}/{hostname}/block",
+				Alert:    "{prefix}/{hostname}/alert/{type}",
+			},
+			SnapshotIntervalSecs: 30,
+		},
+		TSDB: TSDBConfig{
+			Enabled:   false,
+			Format:    "influx",
+			URL:       "http://localhost:8086/api/v2/write?org=home&bucket=minerhq",
+			FlushSecs: 10,
+		},
+		Agent: AgentConfig{
+			Enabled:   false,
+			FlushSecs: 5,
+		},
+		Watchdog: WatchdogConfig{
+			Enabled:        false,
+			StuckMinutes:   15,
+			BackoffMinutes: 10,
+			MaxRestarts:    3,
+		},
+		PoolCheck: PoolCheckConfig{
+			Enabled:      true,
+			IntervalSecs: 60,
+			TimeoutSecs:  10,
+			Failures:     3,
+		},
+		PoolStats: PoolStatsConfig{
+			Enabled:         false,
+			IntervalSecs:    300,
+			DivergencePct:   30,
+			DivergentChecks: 3,
+			Accounts:        []PoolAccountConfig{},
+		},
+		StratumProxy: StratumProxyConfig{
+			Enabled: false,
+			Pools:   []ProxyPoolConfig{},
+		},
+		SmartPlugs: SmartPlugsConfig{
+			PollSecs: 10,
+			OffSecs:  10,
+			Plugs:    []PlugConfig{},
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:       false,
+			MinRunMinutes: 10,
+		},
+		Competition: CompetitionConfig{
+			Scoring:  "raw",
+			Period:   competition.Weekly,
+			ResetDay: "sun",
+		},
 		Scanner: ScannerConfig{
 			Enabled:      false,
 			Networks:     []string{}, // Auto-detect all networks
 			ScanInterval: 5 * time.Minute,
 			AutoAdd:      false,
 		},
+		Display: DisplayConfig{
+			TemperatureUnit: "C",
+			TimeFormat:      "24h",
+			HashrateUnit:    "auto",
+		},
+		Auth: AuthConfig{
+			SessionHours: 168, // 1 week
+		},
 		DBPath:   "/data/minerhq.db",
 		LogLevel: "info",
 	}
@@ -169,3 +804,634 @@ func (c *Config) Save(path string) error {
 
 	return os.WriteFile(path, data, 0644)
 }
+
+// envOverrides maps environment variables to the config fields they override.
+// Applied after the config file so container deployments can tweak settings
+// without editing /data/config.json.
+var envOverrides = map[string]func(c *Config, v string) error{
+	"MINERHQ_HOST": func(c *Config, v string) error { c.Server.Host = v; return nil },
+	"MINERHQ_PORT": func(c *Config, v string) error {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		c.Server.Port = port
+		return nil
+	},
+	"MINERHQ_DB_PATH":             func(c *Config, v string) error { c.DBPath = v; return nil },
+	"MINERHQ_LOG_LEVEL":           func(c *Config, v string) error { c.LogLevel = v; return nil },
+	"MINERHQ_WEBHOOK_URL":         func(c *Config, v string) error { c.Alerts.WebhookURL = v; return nil },
+	"MINERHQ_MATRIX_HOMESERVER":   func(c *Config, v string) error { c.Alerts.MatrixHomeserver = v; return nil },
+	"MINERHQ_MATRIX_ACCESS_TOKEN": func(c *Config, v string) error { c.Alerts.MatrixAccessToken = v; return nil },
+	"MINERHQ_MATRIX_ROOM_ID":      func(c *Config, v string) error { c.Alerts.MatrixRoomID = v; return nil },
+	"MINERHQ_COST_PER_KWH": func(c *Config, v string) error {
+		cost, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		c.Energy.CostPerKWh = cost
+		return nil
+	},
+	"MINERHQ_CURRENCY": func(c *Config, v string) error { c.Energy.Currency = strings.ToUpper(v); return nil },
+	"MINERHQ_FIAT_CURRENCY": func(c *Config, v string) error {
+		c.Pricing.FiatCurrency = strings.ToUpper(v)
+		return nil
+	},
+	"MINERHQ_COINGECKO_API_KEY": func(c *Config, v string) error { c.Pricing.CoinGeckoAPIKey = v; return nil },
+	"MINERHQ_READ_ONLY": func(c *Config, v string) error {
+		readOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		c.Server.ReadOnly = readOnly
+		return nil
+	},
+}
+
+// ValidCompetitionScoring reports whether s is a known competition scoring mode
+// ("" means raw)
+func ValidCompetitionScoring(s string) bool {
+	switch s {
+	case "", competition.Raw, competition.Expected, competition.Average:
+		return true
+	}
+	return false
+}
+
+// validCurrency reports whether s looks like an ISO 4217 currency code
+func validCurrency(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// validScanNetwork reports whether n is a CIDR, an IPv4 address range
+// ("192.168.20.10-192.168.20.50" or "192.168.20.10-50") or a single address
+func validScanNetwork(n string) bool {
+	if _, _, err := net.ParseCIDR(n); err == nil {
+		return true
+	}
+	from, to, found := strings.Cut(n, "-")
+	if net.ParseIP(strings.TrimSpace(from)).To4() == nil {
+		return false
+	}
+	if !found {
+		return true
+	}
+	to = strings.TrimSpace(to)
+	if net.ParseIP(to).To4() != nil {
+		return true
+	}
+	octet, err := strconv.Atoi(to)
+	return err == nil && octet >= 0 && octet <= 255
+}
+
+// validateTariffs checks time-of-use periods, reporting problems via add
+func validateTariffs(prefix string, tariffs []TariffPeriod, add func(format string, args ...interface{})) {
+	for i, p := range tariffs {
+		if _, err := parseClock(p.Start); err != nil {
+			add("%s[%d].start: %q is not a valid HH:MM time", prefix, i, p.Start)
+		}
+		if _, err := parseClock(p.End); err != nil {
+			add("%s[%d].end: %q is not a valid HH:MM time", prefix, i, p.End)
+		}
+		for _, d := range p.Days {
+			if _, ok := weekdayNames[strings.ToLower(d)]; !ok {
+				add("%s[%d].days: %q is not a valid day (mon..sun)", prefix, i, d)
+			}
+		}
+		for _, m := range p.Months {
+			if m < 1 || m > 12 {
+				add("%s[%d].months: %d must be between 1 and 12", prefix, i, m)
+			}
+		}
+		if p.CostPerKWh < 0 {
+			add("%s[%d].cost_per_kwh: must not be negative", prefix, i)
+		}
+	}
+}
+
+// ApplyEnv overrides config values from MINERHQ_* environment variables
+func (c *Config) ApplyEnv() error {
+	var errs []error
+	for name, apply := range envOverrides {
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			continue
+		}
+		if err := apply(c, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", name, v, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the configuration for invalid values and returns every problem found
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		add("server.port: %d is not a valid port", c.Server.Port)
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 {
+		add("server: timeouts must not be negative")
+	}
+	for i, p := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			add("server.trusted_proxies[%d]: %q is not an IP address or CIDR", i, p)
+		}
+	}
+
+	for i, m := range c.Miners {
+		if net.ParseIP(m.IP) == nil {
+			add("miners[%d].ip: %q is not a valid IP address", i, m.IP)
+		}
+		if m.Port < 0 || m.Port > 65535 {
+			add("miners[%d].port: %d is not a valid port", i, m.Port)
+		}
+		if m.ExpectedHashrate < 0 {
+			add("miners[%d].expected_hashrate_ghs: must not be negative", i)
+		}
+		if m.PollIntervalSecs < 0 {
+			add("miners[%d].poll_interval_secs: must not be negative", i)
+		}
+	}
+
+	if c.Alerts.HashrateDropPct < 0 || c.Alerts.HashrateDropPct > 100 {
+		add("alerts.hashrate_drop_pct: %.1f must be between 0 and 100", c.Alerts.HashrateDropPct)
+	}
+	if c.Alerts.HashrateDropChecks < 0 {
+		add("alerts.hashrate_drop_checks: must not be negative")
+	}
+	if c.Alerts.ShareRejectPct < 0 || c.Alerts.ShareRejectPct > 100 {
+		add("alerts.share_reject_pct: %.1f must be between 0 and 100", c.Alerts.ShareRejectPct)
+	}
+	if c.Alerts.OfflineMinutes < 0 {
+		add("alerts.offline_minutes: must not be negative")
+	}
+	if c.Alerts.PoolDiffChangePct < 0 {
+		add("alerts.pool_diff_change_pct: must not be negative")
+	}
+	if c.Alerts.FrozenMinutes < 0 {
+		add("alerts.frozen_minutes: must not be negative")
+	}
+	if c.Alerts.FanRPMBelow < 0 {
+		add("alerts.fan_rpm_below: must not be negative")
+	}
+	if c.Alerts.WifiSignalBelow > 0 {
+		add("alerts.wifi_signal_below: %d dBm must be zero or negative", c.Alerts.WifiSignalBelow)
+	}
+	if c.Alerts.WebhookURL != "" {
+		if u, err := url.Parse(c.Alerts.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("alerts.webhook_url: %q is not a valid http(s) URL", c.Alerts.WebhookURL)
+		}
+	}
+	if c.Alerts.MatrixHomeserver != "" || c.Alerts.MatrixAccessToken != "" || c.Alerts.MatrixRoomID != "" {
+		if u, err := url.Parse(c.Alerts.MatrixHomeserver); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("alerts.matrix_homeserver: %q is not a valid http(s) URL", c.Alerts.MatrixHomeserver)
+		}
+		if c.Alerts.MatrixAccessToken == "" {
+			add("alerts.matrix_access_token: required when Matrix is configured")
+		}
+		if !strings.HasPrefix(c.Alerts.MatrixRoomID, "!") || !strings.Contains(c.Alerts.MatrixRoomID, ":") {
+			add("alerts.matrix_room_id: %q is not a room ID like !abc123:example.org", c.Alerts.MatrixRoomID)
+		}
+	}
+	seenNotifiers := map[string]bool{"discord": true, "matrix": true}
+	for i, n := range c.Alerts.Notifiers {
+		switch n.Type {
+		case NotifierWebhook, NotifierSlack, NotifierNtfy:
+			if u, err := url.Parse(n.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("alerts.notifiers[%d].url: %q is not a valid http(s) URL", i, n.URL)
+			}
+		case NotifierPushover:
+			if n.Token == "" || n.User == "" {
+				add("alerts.notifiers[%d]: token and user are required for Pushover", i)
+			}
+		default:
+			add("alerts.notifiers[%d].type: %q must be webhook, slack, ntfy or pushover", i, n.Type)
+		}
+		if name := n.ChannelName(); seenNotifiers[name] {
+			add("alerts.notifiers[%d].name: duplicate channel %q", i, name)
+		}
+		seenNotifiers[n.ChannelName()] = true
+	}
+	if c.Alerts.EmailEnabled && (c.Alerts.EmailSMTPServer == "" || c.Alerts.EmailTo == "") {
+		add("alerts: email_smtp_server and email_to are required when email is enabled")
+	}
+	if q := c.Alerts.QuietHours; q.Start != "" || q.End != "" {
+		if _, err := parseClock(q.Start); err != nil {
+			add("alerts.quiet_hours.start: %q is not a valid HH:MM time", q.Start)
+		}
+		if _, err := parseClock(q.End); err != nil {
+			add("alerts.quiet_hours.end: %q is not a valid HH:MM time", q.End)
+		}
+		for _, d := range q.Days {
+			if _, ok := weekdayNames[strings.ToLower(d)]; !ok {
+				add("alerts.quiet_hours.days: %q is not a valid day (mon..sun)", d)
+			}
+		}
+		if q.Mode != "" && q.Mode != QuietQueue && q.Mode != QuietSuppress {
+			add("alerts.quiet_hours.mode: %q must be queue or suppress", q.Mode)
+		}
+	}
+
+	if c.Energy.CostPerKWh < 0 {
+		add("energy.cost_per_kwh: must not be negative")
+	}
+	if c.Energy.Currency != "" && !validCurrency(c.Energy.Currency) {
+		add("energy.currency: %q is not a valid currency code", c.Energy.Currency)
+	}
+	seenLocations := make(map[string]bool)
+	for i, l := range c.Energy.Locations {
+		if l.Name == "" {
+			add("energy.locations[%d].name: must not be empty", i)
+		}
+		if seenLocations[l.Name] {
+			add("energy.locations[%d].name: duplicate location %q", i, l.Name)
+		}
+		seenLocations[l.Name] = true
+		if l.CostPerKWh < 0 {
+			add("energy.locations[%d].cost_per_kwh: must not be negative", i)
+		}
+		validateTariffs(fmt.Sprintf("energy.locations[%d].tariffs", i), l.Tariffs, add)
+	}
+	validateTariffs("energy.tariffs", c.Energy.Tariffs, add)
+
+	if c.Pricing.Enabled && c.Pricing.UpdateInterval < 0 {
+		add("pricing.update_interval: must not be negative")
+	}
+	if c.Pricing.FiatCurrency != "" && !validCurrency(c.Pricing.FiatCurrency) {
+		add("pricing.fiat_currency: %q is not a valid currency code", c.Pricing.FiatCurrency)
+	}
+	seenProviders := make(map[string]bool)
+	for i, name := range c.Pricing.Providers {
+		switch name {
+		case PriceBinance, PriceCoinGecko, PriceKraken, PriceCoinPaprika:
+		default:
+			add("pricing.providers[%d]: %q must be binance, coingecko, kraken or coinpaprika", i, name)
+		}
+		if seenProviders[name] {
+			add("pricing.providers[%d]: duplicate provider %q", i, name)
+		}
+		seenProviders[name] = true
+	}
+
+	if c.Retention.MetricsRetentionDays < 0 || c.Retention.SharesRetentionDays < 0 || c.Retention.AlertsRetentionDays < 0 || c.Retention.AuditRetentionDays < 0 {
+		add("retention: retention days must not be negative")
+	}
+	if c.Retention.SnapshotEvery < 0 || c.Retention.SnapshotIntervalSecs < 0 || c.Retention.SnapshotFlushSecs < 0 || c.Retention.SnapshotUnchangedSecs < 0 {
+		add("retention: snapshot sampling must not be negative")
+	}
+	switch c.Retention.Vacuum {
+	case "", "incremental", "full", "off":
+	default:
+		add("retention.vacuum: %q must be incremental, full or off", c.Retention.Vacuum)
+	}
+
+	if c.Polling.IntervalSecs <= 0 {
+		add("polling.interval_secs: must be positive")
+	}
+	if c.Polling.BackoffAfter < 0 {
+		add("polling.backoff_after: must not be negative")
+	}
+	if c.Polling.BackoffAfter > 0 && c.Polling.MaxIntervalSecs < c.Polling.IntervalSecs {
+		add("polling.max_interval_secs: %d must not be below interval_secs", c.Polling.MaxIntervalSecs)
+	}
+	if c.MinerLogs.BufferLines < 0 || c.MinerLogs.RetentionDays < 0 {
+		add("miner_logs: buffer_lines and retention_days must not be negative")
+	}
+
+	if c.Export.Enabled && c.Export.Directory == "" {
+		add("export.directory: required when exports are enabled")
+	}
+	for i, f := range c.Export.Formats {
+		if f != "csv" && f != "json" {
+			add("export.formats[%d]: %q must be csv or json", i, f)
+		}
+	}
+	if _, err := parseClock(c.Export.Time); c.Export.Enabled && err != nil {
+		add("export.time: %q is not a valid HH:MM time", c.Export.Time)
+	}
+	if c.Export.SFTP != "" {
+		if host, _, ok := strings.Cut(c.Export.SFTP, ":"); !ok || host == "" {
+			add("export.sftp: %q must be user@host:/path", c.Export.SFTP)
+		}
+	}
+	if c.Export.SFTPPort < 0 || c.Export.SFTPPort > 65535 {
+		add("export.sftp_port: %d is not a valid port", c.Export.SFTPPort)
+	}
+
+	if c.MQTT.Enabled {
+		if u, err := url.Parse(c.MQTT.Broker); err != nil || !validMQTTScheme(u.Scheme) || u.Host == "" {
+			add("mqtt.broker: %q must be a tcp:// or tls:// URL", c.MQTT.Broker)
+		}
+		if c.MQTT.ClientID == "" {
+			add("mqtt.client_id: required when MQTT is enabled")
+		}
+		for name, topic := range map[string]string{"snapshot": c.MQTT.Topics.Snapshot, "share": c.MQTT.Topics.Share, "block": c.MQTT.Topics.Block, "alert": c.MQTT.Topics.Alert} {
+			if strings.ContainsAny(topic, "+#") {
+				add("mqtt.topics.%s: %q must not contain wildcards", name, topic)
+			}
+		}
+	}
+	if c.MQTT.SnapshotIntervalSecs < 0 {
+		add("mqtt.snapshot_interval_secs: must not be negative")
+	}
+
+	if c.TSDB.Enabled {
+		if c.TSDB.Format != "influx" && c.TSDB.Format != "remote_write" {
+			add("tsdb.format: %q must be influx or remote_write", c.TSDB.Format)
+		}
+		if u, err := url.Parse(c.TSDB.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tsdb.url: %q must be an http:// or https:// URL", c.TSDB.URL)
+		}
+	}
+	if c.TSDB.FlushSecs < 0 {
+		add("tsdb.flush_secs: must not be negative")
+	}
+
+	if c.Agent.Enabled {
+		if u, err := url.Parse(c.Agent.Central); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("agent.central: %q must be an http:// or https:// URL", c.Agent.Central)
+		}
+		if c.Agent.Token == "" {
+			add("agent.token: required when the agent is enabled")
+		}
+	}
+	if c.Agent.FlushSecs < 0 {
+		add("agent.flush_secs: must not be negative")
+	}
+	seenSites := make(map[string]bool)
+	for i, site := range c.Sites {
+		if !validSiteName(site.Name) {
+			add("sites[%d].name: %q must be letters, digits, - or _", i, site.Name)
+		}
+		if seenSites[site.Name] {
+			add("sites[%d].name: duplicate site %q", i, site.Name)
+		}
+		seenSites[site.Name] = true
+		if site.Token == "" && site.TokenHash == "" {
+			add("sites[%d]: token or token_hash is required", i)
+		}
+		if site.Token != "" && len(site.Token) < 32 {
+			add("sites[%d].token: must be at least 32 characters", i)
+		}
+	}
+
+	if c.Watchdog.Enabled {
+		if c.Watchdog.StuckMinutes <= 0 {
+			add("watchdog.stuck_minutes: must be positive when the watchdog is enabled")
+		}
+		if c.Watchdog.MaxRestarts <= 0 {
+			add("watchdog.max_restarts: must be positive when the watchdog is enabled")
+		}
+	}
+	if c.Watchdog.BackoffMinutes < 0 {
+		add("watchdog.backoff_minutes: must not be negative")
+	}
+	for i, ip := range c.Watchdog.Miners {
+		if net.ParseIP(ip) == nil {
+			add("watchdog.miners[%d]: %q is not an IP address", i, ip)
+		}
+	}
+
+	if c.PoolCheck.Enabled {
+		if c.PoolCheck.IntervalSecs < 10 {
+			add("pool_check.interval_secs: must be at least 10")
+		}
+		if c.PoolCheck.TimeoutSecs <= 0 || c.PoolCheck.TimeoutSecs >= c.PoolCheck.IntervalSecs {
+			add("pool_check.timeout_secs: must be positive and shorter than interval_secs")
+		}
+		if c.PoolCheck.Failures <= 0 {
+			add("pool_check.failures: must be positive")
+		}
+	}
+
+	if c.PoolStats.Enabled {
+		if c.PoolStats.IntervalSecs < 60 {
+			add("pool_stats.interval_secs: must be at least 60")
+		}
+		if c.PoolStats.DivergencePct <= 0 {
+			add("pool_stats.divergence_pct: must be positive")
+		}
+		if c.PoolStats.DivergentChecks <= 0 {
+			add("pool_stats.divergent_checks: must be positive")
+		}
+		if len(c.PoolStats.Accounts) == 0 {
+			add("pool_stats.accounts: at least one account is required when pool stats are enabled")
+		}
+	}
+	for i, a := range c.PoolStats.Accounts {
+		if a.Pool != "ckpool" && a.Pool != "public-pool" {
+			add("pool_stats.accounts[%d].pool: %q must be ckpool or public-pool", i, a.Pool)
+		}
+		if a.Address == "" || strings.ContainsAny(a.Address, "./ ") {
+			add("pool_stats.accounts[%d].address: %q must be a payout address, without a worker name", i, a.Address)
+		}
+		if a.URL != "" {
+			if u, err := url.Parse(a.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("pool_stats.accounts[%d].url: %q must be an http(s) URL", i, a.URL)
+			}
+		}
+		for worker, ip := range a.Workers {
+			if net.ParseIP(ip) == nil {
+				add("pool_stats.accounts[%d].workers[%s]: %q is not an IP address", i, worker, ip)
+			}
+		}
+	}
+
+	seenProxyPorts := make(map[int]bool)
+	for i, p := range c.StratumProxy.Pools {
+		if p.Port <= 0 || p.Port > 65535 {
+			add("stratum_proxy.pools[%d].port: %d is not a valid port", i, p.Port)
+		} else if p.Port == c.Server.Port {
+			add("stratum_proxy.pools[%d].port: %d is the web server's port", i, p.Port)
+		} else if seenProxyPorts[p.Port] {
+			add("stratum_proxy.pools[%d].port: %d is already used by another pool", i, p.Port)
+		}
+		seenProxyPorts[p.Port] = true
+		hostPort := p.URL
+		if _, rest, ok := strings.Cut(hostPort, "://"); ok {
+			hostPort = rest
+		}
+		if _, port, err := net.SplitHostPort(strings.TrimSuffix(hostPort, "/")); err != nil || port == "" {
+			add("stratum_proxy.pools[%d].url: %q must be a pool with its port, e.g. stratum+tcp://public-pool.io:21496", i, p.URL)
+		}
+		if p.User == "" {
+			add("stratum_proxy.pools[%d].user: required", i)
+		}
+		if p.Difficulty < 0 {
+			add("stratum_proxy.pools[%d].difficulty: must not be negative", i)
+		}
+	}
+	if c.StratumProxy.Enabled && len(c.StratumProxy.Pools) == 0 {
+		add("stratum_proxy.pools: at least one pool is required when the proxy is enabled")
+	}
+
+	if c.SmartPlugs.PollSecs < 0 {
+		add("smart_plugs.poll_secs: must not be negative")
+	}
+	if c.SmartPlugs.OffSecs < 0 || c.SmartPlugs.OffSecs > 300 {
+		add("smart_plugs.off_secs: must be between 0 and 300")
+	}
+	seenPlugs := make(map[string]bool)
+	for i, p := range c.SmartPlugs.Plugs {
+		if net.ParseIP(p.Miner) == nil {
+			add("smart_plugs.plugs[%d].miner: %q is not an IP address", i, p.Miner)
+		}
+		if seenPlugs[p.Miner] {
+			add("smart_plugs.plugs[%d].miner: %s already has a plug", i, p.Miner)
+		}
+		seenPlugs[p.Miner] = true
+		switch p.Type {
+		case "tasmota", "shelly":
+		case "shelly_rpc":
+			if p.Username != "" || p.Password != "" {
+				add("smart_plugs.plugs[%d]: logins are not supported for shelly_rpc plugs, turn authentication off on the plug", i)
+			}
+		case "tplink":
+			if p.Relay != 0 {
+				add("smart_plugs.plugs[%d].relay: multi-outlet TP-Link plugs are not supported", i)
+			}
+		default:
+			add("smart_plugs.plugs[%d].type: %q must be tasmota, shelly, shelly_rpc or tplink", i, p.Type)
+		}
+		if p.Address == "" {
+			add("smart_plugs.plugs[%d].address: required", i)
+		}
+		if p.Relay < 0 {
+			add("smart_plugs.plugs[%d].relay: must not be negative", i)
+		}
+	}
+
+	if c.Scheduler.MinRunMinutes < 0 {
+		add("scheduler.min_run_minutes: must not be negative")
+	}
+	if len(c.Scheduler.Signals) > 0 && !c.MQTT.Enabled {
+		add("scheduler.signals: reading signals needs the MQTT broker, enable mqtt")
+	}
+	seenSignals := make(map[string]bool)
+	for i, sig := range c.Scheduler.Signals {
+		if sig.Name == "" {
+			add("scheduler.signals[%d].name: required", i)
+		}
+		if seenSignals[sig.Name] {
+			add("scheduler.signals[%d].name: %q is already used", i, sig.Name)
+		}
+		seenSignals[sig.Name] = true
+		if sig.Topic == "" || strings.ContainsAny(sig.Topic, "+#") {
+			add("scheduler.signals[%d].topic: must be a topic without wildcards", i)
+		}
+	}
+
+	for i, n := range c.Scanner.Networks {
+		if !validScanNetwork(n) {
+			add("scanner.networks[%d]: %q is not a CIDR, address range or address", i, n)
+		}
+	}
+	if c.Scanner.Enabled && c.Scanner.ScanInterval <= 0 {
+		add("scanner.scan_interval: must be positive when the scanner is enabled")
+	}
+	switch c.Scanner.DHCP.Source {
+	case "":
+	case "opnsense", "pfsense", "openwrt":
+		if u, err := url.Parse(c.Scanner.DHCP.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("scanner.dhcp.url: %q must be the router's http(s) URL", c.Scanner.DHCP.URL)
+		}
+	case "dnsmasq":
+		if c.Scanner.DHCP.LeaseFile == "" {
+			add("scanner.dhcp.lease_file: required for the dnsmasq source")
+		}
+	default:
+		add("scanner.dhcp.source: %q must be opnsense, pfsense, openwrt or dnsmasq", c.Scanner.DHCP.Source)
+	}
+
+	seenUsers := make(map[string]bool)
+	hasAdmin := false
+	for i, u := range c.Auth.Users {
+		if u.Username == "" {
+			add("auth.users[%d].username: must not be empty", i)
+		}
+		if seenUsers[u.Username] {
+			add("auth.users[%d].username: duplicate user %q", i, u.Username)
+		}
+		seenUsers[u.Username] = true
+		if u.Password == "" && u.PasswordHash == "" {
+			add("auth.users[%d]: password or password_hash is required", i)
+		}
+		switch u.Role {
+		case "admin":
+			hasAdmin = true
+		case "viewer":
+		default:
+			add("auth.users[%d].role: %q must be admin or viewer", i, u.Role)
+		}
+	}
+	if c.Auth.Enabled && !hasAdmin {
+		add("auth: at least one admin user is required when auth is enabled")
+	}
+	seenTokens := make(map[string]bool)
+	for i, t := range c.Auth.Tokens {
+		if t.Name == "" {
+			add("auth.tokens[%d].name: must not be empty", i)
+		}
+		if seenTokens[t.Name] {
+			add("auth.tokens[%d].name: duplicate token %q", i, t.Name)
+		}
+		seenTokens[t.Name] = true
+		if t.Token == "" && t.TokenHash == "" {
+			add("auth.tokens[%d]: token or token_hash is required", i)
+		}
+		if t.Token != "" && len(t.Token) < 32 {
+			add("auth.tokens[%d].token: must be at least 32 characters", i)
+		}
+		if t.Role != "admin" && t.Role != "viewer" {
+			add("auth.tokens[%d].role: %q must be admin or viewer", i, t.Role)
+		}
+	}
+	if c.Auth.SessionHours <= 0 {
+		add("auth.session_hours: must be positive")
+	}
+
+	if !ValidCompetitionScoring(c.Competition.Scoring) {
+		add("competition.scoring: %q must be raw, expected or average", c.Competition.Scoring)
+	}
+	if _, err := competition.New(c.Competition.Period, c.Competition.ResetDay, c.Competition.Timezone); err != nil {
+		add("competition: %v", err)
+	}
+
+	if c.Display.SharesMinDifficulty < 0 {
+		add("display.shares_min_difficulty: must not be negative")
+	}
+	switch strings.ToUpper(c.Display.TemperatureUnit) {
+	case "", "C", "F":
+	default:
+		add("display.temperature_unit: %q must be C or F", c.Display.TemperatureUnit)
+	}
+	switch strings.ToLower(c.Display.TimeFormat) {
+	case "", "24h", "12h":
+	default:
+		add("display.time_format: %q must be 24h or 12h", c.Display.TimeFormat)
+	}
+	if !units.ValidHashrateUnit(c.Display.HashrateUnit) {
+		add("display.hashrate_unit: %q must be auto or one of H/s, KH/s, MH/s, GH/s, TH/s, PH/s", c.Display.HashrateUnit)
+	}
+
+	switch c.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		add("log_level: %q must be one of debug, info, warn, error", c.LogLevel)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("defaults are valid", func(t *testing.T) {
+		if err := DefaultConfig().Validate(); err != nil {
+			t.Errorf("expected default config to be valid, got: %v", err)
+		}
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Port = 70000
+		cfg.Alerts.HashrateDropPct = 150
+		cfg.Alerts.WebhookURL = "not a url"
+		cfg.Alerts.MatrixHomeserver = "https://matrix.example.org"
+		cfg.Alerts.MatrixRoomID = "#alerts:example.org"
+		cfg.Alerts.Notifiers = []NotifierConfig{{Type: "pushover", Token: "app"}, {Type: "ntfy", Name: "matrix", URL: "https://ntfy.sh/miners"}}
+		cfg.Alerts.QuietHours = QuietHoursConfig{Start: "23:00", End: "7am", Mode: "later"}
+		cfg.Scanner.Networks = []string{"10.0.0.0/24", "bogus", "10.0.1.10-50"}
+		cfg.Miners = []MinerConfig{{Name: "bad", IP: "999.1.1.1", PollIntervalSecs: -1}}
+		cfg.Polling.MaxIntervalSecs = 1
+		cfg.MinerLogs.BufferLines = -1
+		cfg.Retention.Vacuum = "weekly"
+		cfg.Energy.Locations = []EnergyLocation{{Name: "garage", CostPerKWh: 0.2}, {Name: "garage", CostPerKWh: 0.3}}
+		cfg.Pricing.FiatCurrency = "euro"
+		cfg.Pricing.Providers = []string{"kraken", "bitstamp", "kraken"}
+		cfg.Export.Formats = []string{"csv", "xml"}
+		cfg.Display.TemperatureUnit = "K"
+		cfg.Display.HashrateUnit = "EH/s"
+		cfg.Competition.Scoring = "luck"
+		cfg.Competition.Period = "fortnightly"
+		cfg.Auth.Tokens = []TokenConfig{{Name: "grafana", Token: "short", Role: "viewer"}}
+		cfg.MQTT.Enabled = true
+		cfg.MQTT.Broker = "http://broker"
+		cfg.TSDB.Enabled = true
+		cfg.TSDB.Format = "graphite"
+		cfg.Sites = []SiteConfig{{Name: "cabin", TokenHash: "abc"}, {Name: "barn@2", TokenHash: "def"}}
+		cfg.Watchdog.Miners = []string{"miner-1"}
+		cfg.PoolCheck.TimeoutSecs = 60
+		cfg.PoolStats.Accounts = []PoolAccountConfig{{Pool: "ckpool", Address: "bc1qtest.bitaxe"}}
+		cfg.StratumProxy.Pools = []ProxyPoolConfig{{Port: 3333, URL: "public-pool.io"}}
+		cfg.SmartPlugs.Plugs = []PlugConfig{{Miner: "192.168.1.10", Type: "zigbee", Address: "192.168.1.200"}}
+		cfg.Scheduler.Signals = []SignalConfig{{Name: "solar", Topic: "inverter/+/power"}}
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Fatal("expected validation errors, got nil")
+		}
+
+		for _, want := range []string{"server.port", "hashrate_drop_pct", "webhook_url", "matrix_access_token", "matrix_room_id", "notifiers[0]: token and user", "notifiers[1].name", "quiet_hours.end", "quiet_hours.mode", "scanner.networks[1]", "miners[0].ip", "miners[0].poll_interval_secs", "polling.max_interval_secs", "miner_logs", "retention.vacuum", "energy.locations[1].name", "pricing.fiat_currency", "pricing.providers[1]", "pricing.providers[2]: duplicate", "export.formats[1]", "display.temperature_unit", "display.hashrate_unit", "competition.scoring", "competition: period", "auth.tokens[0].token", "mqtt.broker", "tsdb.format", "sites[1].name", "watchdog.miners[0]", "pool_check.timeout_secs", "pool_stats.accounts[0].address", "stratum_proxy.pools[0].url", "stratum_proxy.pools[0].user", "smart_plugs.plugs[0].type", "scheduler.signals[0].topic"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to mention %q, got: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("MINERHQ_PORT", "9090")
+	t.Setenv("MINERHQ_DB_PATH", "/tmp/test.db")
+	t.Setenv("MINERHQ_CURRENCY", "eur")
+	t.Setenv("MINERHQ_FIAT_CURRENCY", "brl")
+
+	cfg := DefaultConfig()
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Server.Port != 9090 {
+		t.Errorf("expected port 9090, got %d", cfg.Server.Port)
+	}
+	if cfg.DBPath != "/tmp/test.db" {
+		t.Errorf("expected db path /tmp/test.db, got %s", cfg.DBPath)
+	}
+	if cfg.Energy.Currency != "EUR" {
+		t.Errorf("expected currency EUR, got %s", cfg.Energy.Currency)
+	}
+	if cfg.Pricing.FiatCurrency != "BRL" {
+		t.Errorf("expected fiat currency BRL, got %s", cfg.Pricing.FiatCurrency)
+	}
+
+	t.Setenv("MINERHQ_PORT", "abc")
+	if err := DefaultConfig().ApplyEnv(); err == nil {
+		t.Error("expected error for non-numeric port")
+	}
+}
+
+func TestEnergyCostFor(t *testing.T) {
+	e := EnergyConfig{
+		CostPerKWh: 0.12,
+		Locations:  []EnergyLocation{{Name: "garage", CostPerKWh: 0.25}, {Name: "remote", CostPerKWh: 0.05}},
+	}
+
+	tests := []struct {
+		location string
+		want     float64
+	}{
+		{"", 0.12},
+		{"garage", 0.25},
+		{"remote", 0.05},
+		{"unknown", 0.12},
+	}
+
+	for _, tt := range tests {
+		if got := e.CostFor(tt.location); got != tt.want {
+			t.Errorf("CostFor(%q) = %v, want %v", tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestEnergyRateAt(t *testing.T) {
+	e := EnergyConfig{
+		CostPerKWh: 0.15,
+		Tariffs: []TariffPeriod{
+			{Name: "summer peak", Start: "17:00", End: "21:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Months: []int{6, 7, 8}, CostPerKWh: 0.40},
+			{Name: "off-peak", Start: "23:00", End: "07:00", CostPerKWh: 0.08},
+		},
+		Locations: []EnergyLocation{
+			{Name: "remote", CostPerKWh: 0.05},
+			{Name: "cabin", CostPerKWh: 0.30, Tariffs: []TariffPeriod{{Name: "night", Start: "00:00", End: "06:00", CostPerKWh: 0.10}}},
+		},
+	}
+
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		t        time.Time
+		want     float64
+	}{
+		{"summer weekday peak", "", at(7, 15, 18, 0), 0.40}, // Wednesday
+		{"summer weekend is not peak", "", at(7, 18, 18, 0), 0.15},
+		{"winter weekday is not peak", "", at(1, 14, 18, 0), 0.15},
+		{"peak end is exclusive", "", at(7, 15, 21, 0), 0.15},
+		{"off-peak before midnight", "", at(1, 14, 23, 30), 0.08},
+		{"off-peak after midnight", "", at(1, 15, 6, 59), 0.08},
+		{"location without tariffs uses global tariffs", "remote", at(1, 15, 2, 0), 0.08},
+		{"location without tariffs falls back to its flat rate", "remote", at(1, 15, 12, 0), 0.05},
+		{"location tariffs replace global ones", "cabin", at(1, 15, 2, 0), 0.10},
+		{"location flat rate outside its tariffs", "cabin", at(1, 14, 23, 30), 0.30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.RateAt(tt.location, tt.t); got != tt.want {
+				t.Errorf("RateAt(%q, %v) = %v, want %v", tt.location, tt.t, got, tt.want)
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Energy.Tariffs = []TariffPeriod{{Start: "25:00", End: "07:00", Days: []string{"funday"}, Months: []int{13}}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected tariff validation errors, got nil")
+	}
+	for _, want := range []string{"tariffs[0].start", "tariffs[0].days", "tariffs[0].months"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
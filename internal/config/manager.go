@@ -0,0 +1,278 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Manager holds the running configuration and notifies subscribers when it
+// changes. The configuration it hands out is never modified: a change is made
+// on a copy that then replaces it, so readers don't need to lock.
+//
+// The running configuration is the file's with an optional overlay applied:
+// settings that hold for this run only, such as environment overrides, which
+// are never saved.
+type Manager struct {
+	path    string
+	overlay func(c *Config) error
+
+	mu          sync.RWMutex
+	file        *Config // As saved, without the overlay
+	current     *Config
+	subscribers []func(old, cur *Config)
+
+	changeMu sync.Mutex // Serializes Update and Replace so no change is lost
+}
+
+// NewManager returns a manager for cfg, as read from path, which Update saves
+// to
+func NewManager(path string, cfg *Config) *Manager {
+	return &Manager{path: path, file: cfg, current: cfg}
+}
+
+// Get returns the current configuration. It must not be modified; use Update.
+func (m *Manager) Get() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// Path returns the file the configuration is saved to
+func (m *Manager) Path() string {
+	return m.path
+}
+
+// SetOverlay applies overlay on top of the file's settings from now on, and
+// makes the result current. Nothing changes if overlay returns an error.
+func (m *Manager) SetOverlay(overlay func(c *Config) error) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+
+	m.mu.Lock()
+	prev, file := m.overlay, m.file
+	m.overlay = overlay
+	m.mu.Unlock()
+
+	cur, err := m.Overlaid(file)
+	if err != nil {
+		m.mu.Lock()
+		m.overlay = prev
+		m.mu.Unlock()
+		return err
+	}
+	m.replaceLocked(file, cur)
+	return nil
+}
+
+// Overlaid returns a copy of cfg, as read from the file, with the overlay
+// applied. Without an overlay cfg is returned as is.
+func (m *Manager) Overlaid(cfg *Config) (*Config, error) {
+	m.mu.RLock()
+	overlay := m.overlay
+	m.mu.RUnlock()
+	if overlay == nil {
+		return cfg, nil
+	}
+
+	cur, err := cfg.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy config: %w", err)
+	}
+	if err := overlay(cur); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// Subscribe registers a function called after every change with the previous
+// and the new configuration. Subscribers are called in order from the
+// goroutine making the change and should return quickly.
+func (m *Manager) Subscribe(fn func(old, cur *Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Update applies change to a copy of the current configuration, saves it and
+// makes it current. Fields the overlay sets are saved with their values from
+// the file, and keep the overlay's while running. Nothing changes if change
+// returns an error, which is returned as is.
+func (m *Manager) Update(change func(c *Config) error) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+
+	cfg, err := m.Get().Clone()
+	if err != nil {
+		return fmt.Errorf("failed to copy config: %w", err)
+	}
+	if err := change(cfg); err != nil {
+		return err
+	}
+	file, err := m.withoutOverlay(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to copy config: %w", err)
+	}
+	cur, err := m.Overlaid(file)
+	if err != nil {
+		return err
+	}
+	if m.path != "" {
+		if err := file.Save(m.path); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+	m.replaceLocked(file, cur)
+	return nil
+}
+
+// withoutOverlay returns cfg with every field the overlay sets put back to
+// its value in the file
+func (m *Manager) withoutOverlay(cfg *Config) (*Config, error) {
+	m.mu.RLock()
+	overlay, file, cur := m.overlay, m.file, m.current
+	m.mu.RUnlock()
+	if overlay == nil {
+		return cfg, nil
+	}
+
+	// The current config is the file's with the overlay applied, so the
+	// fields that differ between them are the overlay's
+	fileFields, err := jsonObject(file)
+	if err != nil {
+		return nil, err
+	}
+	overlaid, err := jsonObject(cur)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := jsonObject(cfg)
+	if err != nil {
+		return nil, err
+	}
+	restoreOverlaid(changed, fileFields, overlaid)
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return nil, err
+	}
+	restored := &Config{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// jsonObject returns the configuration as a generic JSON object
+func jsonObject(cfg *Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// restoreOverlaid sets every field of cfg that differs between the file and
+// its overlaid copy back to the file's value, or removes it if the file
+// doesn't have it. The maps are JSON objects.
+func restoreOverlaid(cfg, file, overlaid map[string]interface{}) {
+	keys := make(map[string]bool)
+	for k := range file {
+		keys[k] = true
+	}
+	for k := range overlaid {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		fv, inFile := file[k]
+		ov, inOverlay := overlaid[k]
+		if inFile == inOverlay && reflect.DeepEqual(fv, ov) {
+			continue
+		}
+		fm, fileObject := fv.(map[string]interface{})
+		om, overlayObject := ov.(map[string]interface{})
+		cm, cfgObject := cfg[k].(map[string]interface{})
+		if fileObject && overlayObject && cfgObject {
+			restoreOverlaid(cm, fm, om)
+			continue
+		}
+		if inFile {
+			cfg[k] = fv
+		} else {
+			delete(cfg, k)
+		}
+	}
+}
+
+// Replace makes cfg, e.g. after it was reloaded from the file, the current
+// configuration with the overlay applied, without saving it. Nothing changes
+// if the overlay fails.
+func (m *Manager) Replace(cfg *Config) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+
+	cur, err := m.Overlaid(cfg)
+	if err != nil {
+		return err
+	}
+	m.replaceLocked(cfg, cur)
+	return nil
+}
+
+func (m *Manager) replaceLocked(file, cfg *Config) {
+	m.mu.Lock()
+	old := m.current
+	m.file = file
+	m.current = cfg
+	subscribers := m.subscribers
+	m.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(old, cfg)
+	}
+}
+
+// Clone returns a deep copy of the configuration
+func (c *Config) Clone() (*Config, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// RestartRequired returns the sections that changed between old and cur but
+// are only read at startup
+func RestartRequired(old, cur *Config) []string {
+	var sections []string
+	if old.Server.Host != cur.Server.Host || old.Server.Port != cur.Server.Port ||
+		old.Server.ReadTimeout != cur.Server.ReadTimeout || old.Server.WriteTimeout != cur.Server.WriteTimeout {
+		sections = append(sections, "server")
+	}
+	if old.DBPath != cur.DBPath {
+		sections = append(sections, "db_path")
+	}
+	if !reflect.DeepEqual(old.Export, cur.Export) {
+		sections = append(sections, "export")
+	}
+	if !reflect.DeepEqual(old.MQTT, cur.MQTT) {
+		sections = append(sections, "mqtt")
+	}
+	if !reflect.DeepEqual(old.Scheduler.Signals, cur.Scheduler.Signals) {
+		sections = append(sections, "scheduler.signals")
+	}
+	if !reflect.DeepEqual(old.StratumProxy, cur.StratumProxy) {
+		sections = append(sections, "stratum_proxy")
+	}
+	return sections
+}
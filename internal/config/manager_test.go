@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	initial := DefaultConfig()
+	initial.Miners = []MinerConfig{{Name: "garage", IP: "10.0.0.1"}}
+	m := NewManager(path, initial)
+
+	var notified [][2]*Config
+	m.Subscribe(func(old, cur *Config) { notified = append(notified, [2]*Config{old, cur}) })
+
+	err := m.Update(func(c *Config) error {
+		c.Polling.IntervalSecs = 10
+		c.Miners[0].PollIntervalSecs = 30
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur := m.Get()
+	if cur == initial || cur.Polling.IntervalSecs != 10 {
+		t.Fatalf("expected the update to replace the config, got interval %d", cur.Polling.IntervalSecs)
+	}
+	if initial.Polling.IntervalSecs == 10 || initial.Miners[0].PollIntervalSecs != 0 {
+		t.Error("expected the previous config to be left untouched")
+	}
+	if len(notified) != 1 || notified[0][0] != initial || notified[0][1] != cur {
+		t.Errorf("expected one notification with the old and new config, got %d", len(notified))
+	}
+
+	saved, err := Load(path)
+	if err != nil || saved.Polling.IntervalSecs != 10 {
+		t.Errorf("expected the update to be saved, got %+v (%v)", saved, err)
+	}
+
+	failed := errors.New("invalid")
+	if err := m.Update(func(c *Config) error { c.Polling.IntervalSecs = 99; return failed }); err != failed {
+		t.Errorf("expected the change's error, got %v", err)
+	}
+	if m.Get() != cur || len(notified) != 1 {
+		t.Error("expected a failed change to leave the config alone")
+	}
+
+	reloaded := DefaultConfig()
+	m.Replace(reloaded)
+	if m.Get() != reloaded || len(notified) != 2 {
+		t.Error("expected Replace to make the config current and notify")
+	}
+}
+
+func TestManagerOverlay(t *testing.T) {
+	t.Setenv("MINERHQ_PORT", "9090")
+	t.Setenv("MINERHQ_WEBHOOK_URL", "https://example.com/hook")
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := DefaultConfig()
+	m := NewManager(path, file)
+	if err := m.SetOverlay(func(c *Config) error {
+		c.Server.ReadOnly = true // As with -read-only
+		return c.ApplyEnv()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if cur := m.Get(); cur.Server.Port != 9090 || cur.Alerts.WebhookURL != "https://example.com/hook" || !cur.Server.ReadOnly {
+		t.Fatalf("expected the overlay applied, got %+v", cur.Server)
+	}
+
+	// A save writes the file's values for the overlaid fields, even when the
+	// body echoes the overlay's or changes them
+	err := m.Update(func(c *Config) error {
+		c.Polling.IntervalSecs = 10
+		c.Server.Port = 9091
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Polling.IntervalSecs != 10 || saved.Server.Port != file.Server.Port || saved.Alerts.WebhookURL != "" || saved.Server.ReadOnly {
+		t.Errorf("expected only the change saved, got polling %d, server %+v, webhook %q", saved.Polling.IntervalSecs, saved.Server, saved.Alerts.WebhookURL)
+	}
+	if cur := m.Get(); cur.Polling.IntervalSecs != 10 || cur.Server.Port != 9090 || !cur.Server.ReadOnly {
+		t.Errorf("expected the overlay kept while running, got polling %d, server %+v", cur.Polling.IntervalSecs, cur.Server)
+	}
+
+	if err := m.SetOverlay(func(c *Config) error { return errors.New("invalid") }); err == nil || m.Get().Server.Port != 9090 {
+		t.Error("expected a failing overlay to leave the config alone")
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := DefaultConfig()
+	cur, err := old.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur.Polling.IntervalSecs = 30
+	cur.Server.ReadOnly = true
+	if sections := RestartRequired(old, cur); len(sections) != 0 {
+		t.Errorf("expected live settings not to need a restart, got %v", sections)
+	}
+
+	cur.Server.Port = 9090
+	cur.MQTT.Broker = "tcp://broker:1883"
+	if sections := RestartRequired(old, cur); !reflect.DeepEqual(sections, []string{"server", "mqtt"}) {
+		t.Errorf("expected server and mqtt to need a restart, got %v", sections)
+	}
+}
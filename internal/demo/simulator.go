@@ -0,0 +1,283 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// profile describes a simulated device model
+type profile struct {
+	DeviceModel  string
+	ASICModel    string
+	AxeOS        bool
+	HashRate     float64 // GH/s nominal
+	Power        float64 // Watts nominal
+	ASICCount    int
+	PoolDiff     float64
+	BaseTemp     float64
+	BaseFanRPM   int
+	BlockChance  float64 // Probability of a block per share
+	ShareEveryMs int     // Average milliseconds between shares
+}
+
+// profiles cycles through a realistic mixed fleet
+var profiles = []profile{
+	{DeviceModel: "NerdQAxe++", ASICModel: "BM1370", HashRate: 4800, Power: 78, ASICCount: 4, PoolDiff: 4096, BaseTemp: 58, BaseFanRPM: 4200, BlockChance: 0.0005, ShareEveryMs: 1500},
+	{DeviceModel: "NerdQAxe+", ASICModel: "BM1368", HashRate: 2500, Power: 48, ASICCount: 4, PoolDiff: 2048, BaseTemp: 55, BaseFanRPM: 3800, BlockChance: 0.0003, ShareEveryMs: 2000},
+	{AxeOS: true, ASICModel: "BM1370", HashRate: 1200, Power: 18, ASICCount: 1, PoolDiff: 1024, BaseTemp: 52, BaseFanRPM: 3500, BlockChance: 0.0002, ShareEveryMs: 3000},
+	{DeviceModel: "NerdOctaxe", ASICModel: "BM1368", HashRate: 9000, Power: 150, ASICCount: 8, PoolDiff: 8192, BaseTemp: 62, BaseFanRPM: 5000, BlockChance: 0.0008, ShareEveryMs: 1000},
+	{DeviceModel: "NerdAxe", ASICModel: "BM1366", HashRate: 480, Power: 12, ASICCount: 1, PoolDiff: 512, BaseTemp: 50, BaseFanRPM: 3000, BlockChance: 0.0001, ShareEveryMs: 4000},
+}
+
+// simMiner is a single simulated device serving the AxeOS/NerdQAxe HTTP and WebSocket API
+type simMiner struct {
+	profile  profile
+	index    int
+	hostname string
+	addr     string
+	started  time.Time
+	phase    float64
+	rng      *rand.Rand
+
+	mu              sync.Mutex
+	sharesAccepted  int64
+	sharesRejected  int64
+	bestDiff        float64
+	bestSessionDiff float64
+	foundBlocks     int
+}
+
+// Simulator runs a fleet of simulated miners on localhost
+type Simulator struct {
+	miners    []*simMiner
+	listeners []net.Listener
+	upgrader  websocket.Upgrader
+	done      chan struct{}
+}
+
+// NewSimulator creates a simulator with the given number of miners
+func NewSimulator(count int) *Simulator {
+	s := &Simulator{
+		upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < count; i++ {
+		p := profiles[i%len(profiles)]
+		name := p.DeviceModel
+		if p.AxeOS {
+			name = "Bitaxe"
+		}
+		s.miners = append(s.miners, &simMiner{
+			profile:  p,
+			index:    i,
+			hostname: fmt.Sprintf("demo-%s-%d", name, i+1),
+			started:  time.Now(),
+			phase:    float64(i) * 1.3,
+			rng:      rand.New(rand.NewSource(time.Now().UnixNano() + int64(i))),
+		})
+	}
+	return s
+}
+
+// Start launches an HTTP server per simulated miner and returns their
+// addresses (host:port), which the collector can poll like real miner IPs.
+func (s *Simulator) Start() ([]string, error) {
+	var addrs []string
+	for _, m := range s.miners {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			s.Stop()
+			return nil, fmt.Errorf("failed to listen for simulated miner: %w", err)
+		}
+		s.listeners = append(s.listeners, ln)
+		m.addr = ln.Addr().String()
+		addrs = append(addrs, m.addr)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/system/info", m.handleInfo)
+		mux.HandleFunc("/api/ws", func(w http.ResponseWriter, r *http.Request) {
+			s.handleWS(m, w, r)
+		})
+
+		go func(ln net.Listener) {
+			if err := http.Serve(ln, mux); err != nil {
+				select {
+				case <-s.done:
+				default:
+					log.Printf("Demo miner server error: %v", err)
+				}
+			}
+		}(ln)
+
+		log.Printf("Demo miner %s (%s) listening on %s", m.hostname, m.profile.ASICModel, m.addr)
+	}
+	return addrs, nil
+}
+
+// Stop shuts down all simulated miners
+func (s *Simulator) Stop() {
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+}
+
+// hashRate returns an oscillating hashrate around the nominal value
+func (m *simMiner) hashRate(at time.Time) float64 {
+	t := at.Sub(m.started).Seconds()
+	wave := 0.04*math.Sin(t/45+m.phase) + 0.02*math.Sin(t/7+m.phase*2)
+	m.mu.Lock()
+	noise := (m.rng.Float64() - 0.5) * 0.03
+	m.mu.Unlock()
+	return m.profile.HashRate * (1 + wave + noise)
+}
+
+// handleInfo serves /api/system/info in the NerdQAxe/AxeOS format
+func (m *simMiner) handleInfo(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	p := m.profile
+	hr := m.hashRate(now)
+	load := hr / p.HashRate
+
+	m.mu.Lock()
+	accepted, rejected := m.sharesAccepted, m.sharesRejected
+	bestDiff, bestSession := m.bestDiff, m.bestSessionDiff
+	blocks := m.foundBlocks
+	tempNoise := (m.rng.Float64() - 0.5) * 1.5
+	m.mu.Unlock()
+
+	temp := p.BaseTemp + (load-1)*40 + tempNoise
+	info := map[string]interface{}{
+		"ASICModel":       p.ASICModel,
+		"hostname":        m.hostname,
+		"hostip":          m.addr,
+		"macAddr":         fmt.Sprintf("02:DE:00:00:00:%02X", m.index+1),
+		"version":         "demo-1.0.0",
+		"hashRate":        hr,
+		"temp":            temp,
+		"vrTemp":          temp + 8,
+		"power":           p.Power * (0.97 + load*0.03),
+		"voltage":         5.1,
+		"coreVoltage":     1150,
+		"frequency":       525,
+		"fanrpm":          p.BaseFanRPM + int((temp-p.BaseTemp)*40),
+		"fanspeed":        60 + (temp-p.BaseTemp)*2,
+		"sharesAccepted":  accepted,
+		"sharesRejected":  rejected,
+		"bestDiff":        bestDiff,
+		"bestSessionDiff": bestSession,
+		"poolDifficulty":  p.PoolDiff,
+		"uptimeSeconds":   int64(now.Sub(m.started).Seconds()),
+		"wifiRSSI":        -50 - (m.index*7)%20,
+		"asicCount":       p.ASICCount,
+	}
+
+	if p.AxeOS {
+		info["axeOSVersion"] = "v2.5.0-demo"
+		info["stratumURL"] = "demo.pool.local"
+		info["stratumPort"] = 3333
+		info["blockFound"] = blocks
+	} else {
+		info["deviceModel"] = p.DeviceModel
+		info["hashRate_1m"] = hr
+		info["hashRate_10m"] = p.HashRate * (1 + 0.01*math.Sin(float64(now.Unix())/600+m.phase))
+		info["hashRate_1h"] = p.HashRate * 0.995
+		info["hashRate_1d"] = p.HashRate * 0.99
+		info["foundBlocks"] = blocks
+		info["totalFoundBlocks"] = blocks
+		info["stratum"] = map[string]interface{}{
+			"pools": []map[string]interface{}{
+				{"connected": true, "poolDifficulty": p.PoolDiff, "accepted": accepted, "rejected": rejected, "bestDiff": bestSession},
+			},
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleWS streams simulated share and block log lines
+func (s *Simulator) handleWS(m *simMiner, w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	jobID := 1
+	for {
+		m.mu.Lock()
+		wait := time.Duration(float64(m.profile.ShareEveryMs)*(0.2+m.rng.ExpFloat64())) * time.Millisecond
+		m.mu.Unlock()
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(wait):
+		}
+
+		for _, line := range m.nextShare(jobID) {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+				return
+			}
+		}
+		jobID++
+	}
+}
+
+// nextShare generates the log lines for one share (and occasionally a block)
+func (m *simMiner) nextShare(jobID int) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.profile
+	asic := m.rng.Intn(p.ASICCount)
+	nonce := m.rng.Uint32()
+	version := m.rng.Uint32() & 0x1FFFE000
+
+	// Share difficulty follows a heavy-tailed distribution above pool difficulty
+	diff := p.PoolDiff / (1 - m.rng.Float64()*0.999999)
+	if m.rng.Float64() < 0.01 {
+		m.sharesRejected++
+	} else {
+		m.sharesAccepted++
+	}
+	if diff > m.bestDiff {
+		m.bestDiff = diff
+	}
+	if diff > m.bestSessionDiff {
+		m.bestSessionDiff = diff
+	}
+
+	var lines []string
+	if p.AxeOS {
+		lines = append(lines, fmt.Sprintf("asic_result: ID: %08x%08x, ASIC nr: %d, ver: %08X Nonce %08X diff %.1f of %.0f.",
+			jobID, nonce, asic, version, nonce, diff, p.PoolDiff))
+	} else {
+		lines = append(lines, fmt.Sprintf("asic_result: (Pri) Job ID: %d AsicNr: %d Ver: %08X Nonce %08X; Extranonce2 %08x diff %.1f/%.0f/3.70G",
+			jobID, asic, version, nonce, m.rng.Uint32(), diff, p.PoolDiff))
+	}
+
+	if m.rng.Float64() < p.BlockChance {
+		m.foundBlocks++
+		networkDiff := diff * (0.5 + m.rng.Float64()*0.4)
+		blockDiff := diff * 10
+		lines = append(lines, fmt.Sprintf("I (%d) STRATUM_MANAGER: FOUND BLOCK!!! %.1f > %.1f", jobID, blockDiff, networkDiff))
+	}
+
+	return lines
+}
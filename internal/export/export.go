@@ -0,0 +1,384 @@
+// Package export writes daily dumps of snapshot rollups, shares and blocks to
+// a local directory, optionally uploading them to an SFTP server, for users
+// who archive data outside the SQLite file.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+// dataset is one exported table
+type dataset struct {
+	name   string
+	header []string
+	rows   [][]string
+	data   interface{} // Marshalled as-is for JSON exports
+}
+
+// Exporter writes daily exports on a schedule. Raw snapshots are purged
+// hourly, so their rollups are captured before each purge into a staging
+// file per day and exported from there.
+type Exporter struct {
+	store   *storage.SQLiteStorage
+	cfg     config.ExportConfig
+	display units.Display // Units and clock for CSV columns
+
+	mu       sync.Mutex
+	captured time.Time // Snapshots before this time are in the staging files
+	loaded   bool      // Whether captured was read from the database
+}
+
+// NewExporter creates an exporter for the given settings. CSV files use the
+// display preferences; JSON files keep base units and RFC 3339 timestamps.
+func NewExporter(store *storage.SQLiteStorage, cfg config.ExportConfig, display units.Display) *Exporter {
+	return &Exporter{store: store, cfg: cfg, display: display}
+}
+
+// Start runs the export of the previous day every day at the configured time
+func (e *Exporter) Start() {
+	go func() {
+		for {
+			next := nextRun(time.Now(), e.cfg.Time)
+			time.Sleep(time.Until(next))
+
+			day := next.AddDate(0, 0, -1)
+			files, err := e.ExportDay(day)
+			if err != nil {
+				log.Printf("Export for %s failed: %v", day.Format("2006-01-02"), err)
+				continue
+			}
+			log.Printf("Exported %d files for %s", len(files), day.Format("2006-01-02"))
+		}
+	}()
+}
+
+// nextRun returns the next time after now at the local "HH:MM" clock time
+func nextRun(now time.Time, clock string) time.Time {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		t = time.Date(0, 1, 1, 0, 30, 0, 0, time.UTC)
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// CaptureSnapshots stages hourly rollups of snapshots taken before cutoff so
+// they survive the snapshot purge. Call it with the purge cutoff just before
+// purging. The time captured up to is kept in the database and advanced as
+// each day's staging file is written, so a restart or a failed write never
+// stages the same snapshots twice.
+func (e *Exporter) CaptureSnapshots(cutoff time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.loaded {
+		captured, err := e.store.GetExportCursor()
+		if err != nil {
+			return err
+		}
+		e.captured, e.loaded = captured, true
+	}
+	if !cutoff.After(e.captured) {
+		return nil
+	}
+	rollups, err := e.store.GetSnapshotRollups(e.captured, cutoff)
+	if err != nil {
+		return err
+	}
+
+	// Group by local day so each staging file covers one export
+	var days []string
+	byDay := make(map[string][]*storage.SnapshotRollup)
+	for _, r := range rollups {
+		day := r.Hour.Local().Format("2006-01-02")
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], r)
+	}
+	for i, day := range days {
+		staged, err := e.loadStaged(day)
+		if err != nil {
+			return err
+		}
+		if err := e.saveStaged(day, mergeRollups(staged, byDay[day])); err != nil {
+			return err
+		}
+
+		// Rollups come hour by hour, so every snapshot of the days still to
+		// write was taken at or after the first hour of the next one
+		captured := cutoff
+		if i+1 < len(days) {
+			captured = byDay[days[i+1]][0].Hour
+		}
+		if err := e.advance(captured); err != nil {
+			return err
+		}
+	}
+	if len(days) == 0 {
+		return e.advance(cutoff)
+	}
+	return nil
+}
+
+// advance records that snapshots before t are staged
+func (e *Exporter) advance(t time.Time) error {
+	if err := e.store.SetExportCursor(t); err != nil {
+		return fmt.Errorf("export cursor: %w", err)
+	}
+	e.captured = t
+	return nil
+}
+
+// stagingPath returns the staging file holding captured rollups for a day
+func (e *Exporter) stagingPath(day string) string {
+	return filepath.Join(e.cfg.Directory, ".snapshots-"+day+".json")
+}
+
+func (e *Exporter) loadStaged(day string) ([]*storage.SnapshotRollup, error) {
+	data, err := os.ReadFile(e.stagingPath(day))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rollups []*storage.SnapshotRollup
+	if err := json.Unmarshal(data, &rollups); err != nil {
+		return nil, fmt.Errorf("staging file for %s: %w", day, err)
+	}
+	return rollups, nil
+}
+
+func (e *Exporter) saveStaged(day string, rollups []*storage.SnapshotRollup) error {
+	if err := os.MkdirAll(e.cfg.Directory, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rollups)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.stagingPath(day), data, 0644)
+}
+
+// mergeRollups combines rollups of the same miner and hour, which occur when
+// a capture boundary falls inside an hour. Averages are weighted by samples.
+func mergeRollups(a, b []*storage.SnapshotRollup) []*storage.SnapshotRollup {
+	type key struct {
+		ip   string
+		hour int64
+	}
+	merged := make(map[key]*storage.SnapshotRollup)
+	var out []*storage.SnapshotRollup
+	for _, r := range append(append([]*storage.SnapshotRollup{}, a...), b...) {
+		k := key{r.MinerIP, r.Hour.Unix()}
+		m, ok := merged[k]
+		if !ok {
+			c := *r
+			merged[k] = &c
+			out = append(out, &c)
+			continue
+		}
+		total := float64(m.Samples + r.Samples)
+		if total > 0 {
+			m.HashRate = (m.HashRate*float64(m.Samples) + r.HashRate*float64(r.Samples)) / total
+			m.Temperature = (m.Temperature*float64(m.Samples) + r.Temperature*float64(r.Samples)) / total
+			m.Power = (m.Power*float64(m.Samples) + r.Power*float64(r.Samples)) / total
+		}
+		m.Samples += r.Samples
+		if r.BestDiff > m.BestDiff {
+			m.BestDiff = r.BestDiff
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Hour.Equal(out[j].Hour) {
+			return out[i].Hour.Before(out[j].Hour)
+		}
+		return out[i].MinerIP < out[j].MinerIP
+	})
+	return out
+}
+
+// ExportDay writes the data for the local calendar day containing day and
+// returns the paths of the files written. Files are uploaded to the SFTP
+// target when one is configured.
+func (e *Exporter) ExportDay(day time.Time) ([]string, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	// Snapshots still in the database, plus rollups captured before purges
+	if err := e.CaptureSnapshots(end); err != nil {
+		return nil, fmt.Errorf("snapshot rollups: %w", err)
+	}
+	e.mu.Lock()
+	rollups, err := e.loadStaged(start.Format("2006-01-02"))
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	shares, err := e.store.GetSharesInRange(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("shares: %w", err)
+	}
+	blocks, err := e.store.GetBlocksInRange(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("blocks: %w", err)
+	}
+
+	if err := os.MkdirAll(e.cfg.Directory, 0755); err != nil {
+		return nil, err
+	}
+
+	formats := e.cfg.Formats
+	if len(formats) == 0 {
+		formats = []string{"csv"}
+	}
+
+	var files []string
+	for _, ds := range []dataset{snapshotDataset(rollups, e.display), shareDataset(shares, e.display), blockDataset(blocks, e.display)} {
+		for _, format := range formats {
+			path := filepath.Join(e.cfg.Directory, fmt.Sprintf("minerhq-%s-%s.%s", start.Format("2006-01-02"), ds.name, format))
+			if err := writeFile(path, format, ds); err != nil {
+				return files, err
+			}
+			files = append(files, path)
+		}
+	}
+
+	if e.cfg.SFTP != "" {
+		if err := uploadSFTP(e.cfg, files); err != nil {
+			return files, fmt.Errorf("sftp upload: %w", err)
+		}
+	}
+
+	os.Remove(e.stagingPath(start.Format("2006-01-02")))
+	return files, nil
+}
+
+// writeFile writes a dataset to path, replacing any previous export
+func writeFile(path, format string, ds dataset) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, format, ds); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// write encodes a dataset as CSV or JSON
+func write(w io.Writer, format string, ds dataset) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(ds.header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(ds.rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ds.data)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// formatTime writes a timestamp in local time with its UTC offset, so rows
+// stay unambiguous across DST changes and when read in another time zone
+func formatTime(t time.Time) string {
+	return t.Local().Format(time.RFC3339)
+}
+
+// columnSuffix turns a unit such as "TH/s" or "°F" into a header suffix ("ths", "f")
+func columnSuffix(unit string) string {
+	unit = strings.TrimPrefix(unit, "°")
+	return strings.ToLower(strings.ReplaceAll(unit, "/", ""))
+}
+
+func snapshotDataset(rollups []*storage.SnapshotRollup, d units.Display) dataset {
+	_, hashUnit := d.Hashrate(0)
+	ds := dataset{
+		name: "snapshots",
+		header: []string{"hour", "miner_ip", "samples", "hash_rate_" + columnSuffix(hashUnit),
+			"temperature_" + columnSuffix(d.TemperatureSymbol()), "power_w", "best_diff"},
+		data: rollups,
+	}
+	for _, r := range rollups {
+		hashRate, _ := d.Hashrate(r.HashRate)
+		ds.rows = append(ds.rows, []string{
+			formatTime(r.Hour), r.MinerIP, strconv.Itoa(r.Samples),
+			formatFloat(hashRate), formatFloat(d.Temperature(r.Temperature)), formatFloat(r.Power), formatFloat(r.BestDiff),
+		})
+	}
+	if rollups == nil {
+		ds.data = []*storage.SnapshotRollup{}
+	}
+	return ds
+}
+
+func shareDataset(shares []*storage.Share, d units.Display) dataset {
+	ds := dataset{
+		name:   "shares",
+		header: []string{"timestamp", "miner_ip", "hostname", "asic_num", "difficulty", "network_difficulty", "network_pct", "job_id", "nonce", "version", "rejected", "reject_reason"},
+		data:   shares,
+	}
+	for _, s := range shares {
+		ds.rows = append(ds.rows, []string{
+			formatTime(s.Timestamp), s.MinerIP, s.Hostname, strconv.Itoa(s.AsicNum),
+			formatFloat(s.Difficulty), formatFloat(s.NetworkDifficulty), formatFloat(s.NetworkPct), s.JobID,
+			fmt.Sprintf("%08x", s.Nonce), fmt.Sprintf("%08x", s.Version),
+			strconv.FormatBool(s.Rejected), s.RejectReason,
+		})
+	}
+	if shares == nil {
+		ds.data = []*storage.Share{}
+	}
+	return ds
+}
+
+func blockDataset(blocks []*storage.Block, d units.Display) dataset {
+	ds := dataset{
+		name:   "blocks",
+		header: []string{"timestamp", "miner_ip", "hostname", "difficulty", "network_difficulty", "coin", "block_reward", "coin_price_usd", "value_usd"},
+		data:   blocks,
+	}
+	for _, b := range blocks {
+		ds.rows = append(ds.rows, []string{
+			formatTime(b.Timestamp), b.MinerIP, b.Hostname,
+			formatFloat(b.Difficulty), formatFloat(b.NetworkDifficulty), b.CoinSymbol,
+			formatFloat(b.BlockReward), formatFloat(b.CoinPrice), formatFloat(b.ValueUSD),
+		})
+	}
+	if blocks == nil {
+		ds.data = []*storage.Block{}
+	}
+	return ds
+}
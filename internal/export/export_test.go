@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+func TestNextRun(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, loc)
+
+	if got, want := nextRun(now, "00:30"), time.Date(2026, 3, 11, 0, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("nextRun before midnight = %v, want %v", got, want)
+	}
+	if got, want := nextRun(now, "18:15"), time.Date(2026, 3, 10, 18, 15, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("nextRun later today = %v, want %v", got, want)
+	}
+}
+
+func TestMergeRollups(t *testing.T) {
+	hour := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	a := []*storage.SnapshotRollup{{MinerIP: "10.0.0.1", Hour: hour, Samples: 10, HashRate: 1000, Power: 20, BestDiff: 5000}}
+	b := []*storage.SnapshotRollup{
+		{MinerIP: "10.0.0.1", Hour: hour, Samples: 30, HashRate: 1400, Power: 24, BestDiff: 3000},
+		{MinerIP: "10.0.0.2", Hour: hour, Samples: 5, HashRate: 500},
+	}
+
+	merged := mergeRollups(a, b)
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 rollups, got %d", len(merged))
+	}
+	m := merged[0]
+	if m.Samples != 40 || m.HashRate != 1300 || m.Power != 23 || m.BestDiff != 5000 {
+		t.Errorf("unexpected merged rollup: %+v", m)
+	}
+	if a[0].Samples != 10 {
+		t.Error("merge modified its input")
+	}
+}
+
+// stagedSamples counts the snapshots staged for a day
+func stagedSamples(t *testing.T, e *Exporter, day string) int {
+	t.Helper()
+	rollups, err := e.loadStaged(day)
+	if err != nil {
+		t.Fatalf("failed to load staged rollups: %v", err)
+	}
+	samples := 0
+	for _, r := range rollups {
+		samples += r.Samples
+	}
+	return samples
+}
+
+func TestCaptureSnapshots(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	for _, ts := range []time.Time{
+		time.Date(2026, 3, 10, 10, 0, 0, 0, time.Local),
+		time.Date(2026, 3, 10, 10, 10, 0, 0, time.Local),
+		time.Date(2026, 3, 11, 10, 0, 0, 0, time.Local),
+	} {
+		if err := store.InsertSnapshot(&storage.MinerSnapshot{MinerIP: "10.0.0.1", Timestamp: ts, HashRate: 1000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.ExportConfig{Directory: t.TempDir()}
+	cutoff := time.Date(2026, 3, 12, 0, 0, 0, 0, time.Local)
+
+	// The second day's staging file can't be written
+	e := NewExporter(store, cfg, units.Display{})
+	blocked := e.stagingPath("2026-03-11")
+	if err := os.MkdirAll(blocked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CaptureSnapshots(cutoff); err == nil {
+		t.Fatal("expected the blocked staging file to fail the capture")
+	}
+	captured, err := store.GetExportCursor()
+	if want := time.Date(2026, 3, 11, 10, 0, 0, 0, time.Local); err != nil || !captured.Equal(want) {
+		t.Errorf("expected the cursor kept at the unwritten day, got %v: %v", captured, err)
+	}
+
+	// A restarted exporter resumes from the stored cursor
+	os.Remove(blocked)
+	e = NewExporter(store, cfg, units.Display{})
+	if err := e.CaptureSnapshots(cutoff); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if err := e.CaptureSnapshots(cutoff.Add(time.Hour)); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if first, second := stagedSamples(t, e, "2026-03-10"), stagedSamples(t, e, "2026-03-11"); first != 2 || second != 1 {
+		t.Errorf("expected each snapshot staged once, got %d and %d", first, second)
+	}
+	if captured, err := store.GetExportCursor(); err != nil || !captured.Equal(cutoff.Add(time.Hour)) {
+		t.Errorf("expected the cursor at the last cutoff, got %v: %v", captured, err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	share := &storage.Share{
+		MinerIP:    "10.0.0.1",
+		Hostname:   "nerd-1",
+		Timestamp:  time.Date(2026, 3, 10, 9, 15, 0, 0, time.Local),
+		Difficulty: 5000,
+		JobID:      "18",
+		Nonce:      0xF854197E,
+	}
+	share.SetNetworkDifficulty(1e6)
+
+	var buf bytes.Buffer
+	if err := write(&buf, "csv", shareDataset([]*storage.Share{share}, units.Display{})); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and 1 row, got %d lines", len(lines))
+	}
+	want := share.Timestamp.Format(time.RFC3339) + ",10.0.0.1,nerd-1,0,5000,1000000,0.5,18,f854197e,00000000,false,"
+	if lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+
+	buf.Reset()
+	if err := write(&buf, "json", shareDataset(nil, units.Display{})); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected empty JSON array, got %q", buf.String())
+	}
+}
+
+func TestSnapshotDatasetDisplay(t *testing.T) {
+	rollups := []*storage.SnapshotRollup{{
+		MinerIP:     "10.0.0.1",
+		Hour:        time.Date(2026, 3, 10, 14, 0, 0, 0, time.Local),
+		Samples:     1800,
+		HashRate:    1200,
+		Temperature: 50,
+		Power:       18,
+	}}
+
+	ds := snapshotDataset(rollups, units.Display{TemperatureUnit: "F", TimeFormat: "12h", HashrateUnit: "TH/s"})
+
+	if ds.header[3] != "hash_rate_ths" || ds.header[4] != "temperature_f" {
+		t.Errorf("header = %v, want TH/s and °F columns", ds.header)
+	}
+	want := []string{rollups[0].Hour.Format(time.RFC3339), "10.0.0.1", "1800", "1.2", "122", "18", "0"}
+	if strings.Join(ds.rows[0], ",") != strings.Join(want, ",") {
+		t.Errorf("row = %v, want %v", ds.rows[0], want)
+	}
+
+	if ds := snapshotDataset(rollups, units.Display{}); ds.header[3] != "hash_rate_ghs" || ds.header[4] != "temperature_c" {
+		t.Errorf("default header = %v, want GH/s and °C columns", ds.header)
+	}
+}
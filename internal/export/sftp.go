@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// uploadSFTP copies files to the configured "user@host:/path" target using
+// the system sftp client in batch mode. Authentication must not prompt, so a
+// private key (or an ssh-agent) is required.
+func uploadSFTP(cfg config.ExportConfig, files []string) error {
+	host, dir, ok := strings.Cut(cfg.SFTP, ":")
+	if !ok || host == "" {
+		return fmt.Errorf("invalid sftp target %q, expected user@host:/path", cfg.SFTP)
+	}
+
+	args := []string{"-b", "-", "-o", "BatchMode=yes"}
+	if cfg.SFTPPort > 0 {
+		args = append(args, "-P", strconv.Itoa(cfg.SFTPPort))
+	}
+	if cfg.SFTPKey != "" {
+		args = append(args, "-i", cfg.SFTPKey)
+	}
+	args = append(args, host)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sftp", args...)
+	cmd.Stdin = strings.NewReader(sftpBatch(dir, files))
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+// sftpBatch builds the sftp batch script uploading files into dir
+func sftpBatch(dir string, files []string) string {
+	var b strings.Builder
+	if dir != "" {
+		// "-" prefix: don't abort if the directory already exists
+		fmt.Fprintf(&b, "-mkdir %q\n", dir)
+		fmt.Fprintf(&b, "cd %q\n", dir)
+	}
+	for _, f := range files {
+		fmt.Fprintf(&b, "put %q\n", f)
+	}
+	return b.String()
+}
@@ -0,0 +1,307 @@
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types (upper nibble of the first header byte)
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetSubscribe  = 0x82 // Flags 0010 are mandatory for SUBSCRIBE
+	packetPingreq    = 0xC0
+	packetDisconnect = 0xE0
+)
+
+// keepAlive is how long the broker waits for a packet before dropping us
+const keepAlive = 60 * time.Second
+
+// connackErrors describes the CONNACK return codes that refuse a connection
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad username or password",
+	5: "not authorized",
+}
+
+// will is the message the broker publishes when the connection drops
+type will struct {
+	topic   string
+	payload []byte
+}
+
+// client is a minimal MQTT 3.1.1 client that publishes and subscribes at
+// QoS 0. Lost messages are replaced by the next ones, so acknowledged
+// delivery isn't needed.
+type client struct {
+	conn      net.Conn
+	mu        sync.Mutex // Serializes writes
+	done      chan struct{}
+	err       error                              // Why the connection ended, set before done is closed
+	onMessage func(topic string, payload []byte) // Receives messages on subscribed topics, if set
+}
+
+// dial connects and logs in to the broker at rawURL ("tcp://host:1883" or
+// "tls://host:8883"). Messages on subscribed topics go to onMessage.
+func dial(rawURL, clientID, username, password string, insecure bool, lastWill *will, onMessage func(topic string, payload []byte)) (*client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = dialer.Dial("tcp", hostPort(u, "1883"))
+	case "tls", "ssl", "mqtts":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, "8883"), &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: insecure,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write(connectPacket(clientID, username, password, lastWill)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	if err := readConnack(r); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &client{conn: conn, done: make(chan struct{}), onMessage: onMessage}
+	go c.readLoop(r)
+	go c.pingLoop()
+	return c, nil
+}
+
+// hostPort returns the URL's host with the default port added if it has none
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// publish sends a QoS 0 message
+func (c *client) publish(topic string, payload []byte, retain bool) error {
+	header := byte(packetPublish)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.write(packet(header, body))
+}
+
+// subscribe asks the broker for the messages on topics, at QoS 0. The
+// broker's SUBACK is not waited for.
+func (c *client) subscribe(topics []string) error {
+	body := []byte{0, 1} // Packet identifier
+	for _, topic := range topics {
+		body = appendString(body, topic)
+		body = append(body, 0) // QoS 0
+	}
+	return c.write(packet(packetSubscribe, body))
+}
+
+// close disconnects cleanly, so the broker doesn't publish the will
+func (c *client) close() {
+	c.write(packet(packetDisconnect, nil))
+	c.conn.Close()
+	<-c.done
+}
+
+// write sends a packet, failing if it can't be sent within the keep-alive
+func (c *client) write(p []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(keepAlive / 2))
+	_, err := c.conn.Write(p)
+	return err
+}
+
+// readLoop hands PUBLISH packets to onMessage and discards what else the
+// broker sends (PINGRESPs, SUBACKs) until the connection ends
+func (c *client) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		header, err := r.ReadByte()
+		if err != nil {
+			c.err = err
+			return
+		}
+		length, err := readLength(r)
+		if err != nil {
+			c.err = err
+			return
+		}
+		if header&0xF0 != packetPublish || c.onMessage == nil {
+			if _, err := r.Discard(length); err != nil {
+				c.err = err
+				return
+			}
+			continue
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			c.err = err
+			return
+		}
+		var n int
+		if len(body) >= 2 {
+			n = int(body[0])<<8 | int(body[1])
+		}
+		if len(body) < 2 || 2+n > len(body) {
+			c.err = errors.New("malformed PUBLISH")
+			return
+		}
+		topic, payload := string(body[2:2+n]), body[2+n:]
+		if header&0x06 != 0 && len(payload) >= 2 {
+			payload = payload[2:] // Packet identifier of QoS 1 and 2 messages
+		}
+		c.onMessage(topic, payload)
+	}
+}
+
+// pingLoop keeps the connection alive while nothing is published
+func (c *client) pingLoop() {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(packet(packetPingreq, nil)); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// connectPacket builds a CONNECT packet for a clean session
+func connectPacket(clientID, username, password string, lastWill *will) []byte {
+	flags := byte(0x02) // Clean session
+	if lastWill != nil {
+		flags |= 0x04 | 0x20 // Will, retained, QoS 0
+	}
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+
+	secs := int(keepAlive / time.Second)
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, byte(secs>>8), byte(secs)) // Protocol level 4 is 3.1.1
+	body = appendString(body, clientID)
+	if lastWill != nil {
+		body = appendString(body, lastWill.topic)
+		body = appendString(body, string(lastWill.payload))
+	}
+	if username != "" {
+		body = appendString(body, username)
+		if password != "" {
+			body = appendString(body, password)
+		}
+	}
+	return packet(packetConnect, body)
+}
+
+// readConnack reads the broker's reply to CONNECT
+func readConnack(r *bufio.Reader) error {
+	header, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if header != packetConnack {
+		return fmt.Errorf("expected CONNACK, got packet type 0x%02X", header)
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return err
+	}
+	if length != 2 {
+		return errors.New("malformed CONNACK")
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(r, reply); err != nil {
+		return err
+	}
+	if code := reply[1]; code != 0 {
+		if msg, ok := connackErrors[code]; ok {
+			return fmt.Errorf("broker refused connection: %s", msg)
+		}
+		return fmt.Errorf("broker refused connection: code %d", code)
+	}
+	return nil
+}
+
+// packet prefixes body with the fixed header
+func packet(header byte, body []byte) []byte {
+	p := []byte{header}
+	p = appendLength(p, len(body))
+	return append(p, body...)
+}
+
+// appendLength appends n in MQTT's variable length encoding
+func appendLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+// readLength reads a variable length encoded remaining length
+func readLength(r *bufio.Reader) (int, error) {
+	n, multiplier := 0, 1
+	for i := 0; i < 4; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			return n, nil
+		}
+		multiplier *= 128
+	}
+	return 0, errors.New("malformed remaining length")
+}
+
+// appendString appends a length-prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,195 @@
+// Package mqtt publishes miner events to an MQTT broker for home automation
+// (Home Assistant, Node-RED)
+package mqtt
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/alerts"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// queueSize bounds the messages waiting for the broker; more are dropped
+const queueSize = 256
+
+// message is a queued publish
+type message struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// Publisher publishes snapshots, shares, blocks and alerts. Messages are
+// queued and sent in the background, reconnecting to the broker as needed,
+// so publishing never blocks the caller.
+type Publisher struct {
+	cfg   config.MQTTConfig
+	queue chan message
+	stop  chan struct{}
+	done  chan struct{}
+
+	mu           sync.Mutex
+	lastSnapshot map[string]time.Time // Miner IP -> last published snapshot
+	dropped      int                  // Messages dropped since the last warning
+	lastDropWarn time.Time
+}
+
+// NewPublisher creates a publisher for the configured broker
+func NewPublisher(cfg config.MQTTConfig) *Publisher {
+	return &Publisher{
+		cfg:          cfg,
+		queue:        make(chan message, queueSize),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+		lastSnapshot: make(map[string]time.Time),
+	}
+}
+
+// Start connects to the broker in the background
+func (p *Publisher) Start() {
+	go p.run()
+}
+
+// Stop publishes the offline status and disconnects
+func (p *Publisher) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+// statusTopic is where "online" and "offline" are published (retained)
+func (p *Publisher) statusTopic() string {
+	return p.cfg.TopicPrefix + "/status"
+}
+
+// run keeps a connection to the broker and sends queued messages
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	backoff := time.Second
+	for {
+		c, err := dial(p.cfg.Broker, p.cfg.ClientID, p.cfg.Username, p.cfg.Password, p.cfg.Insecure,
+			&will{topic: p.statusTopic(), payload: []byte("offline")}, nil)
+		if err != nil {
+			log.Printf("MQTT: connecting to %s failed, retrying in %s: %v", p.cfg.Broker, backoff, err)
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+
+		log.Printf("MQTT: connected to %s", p.cfg.Broker)
+		backoff = time.Second
+		if p.send(c) {
+			return
+		}
+	}
+}
+
+// send publishes queued messages until the connection drops (returns false)
+// or the publisher is stopped (returns true)
+func (p *Publisher) send(c *client) bool {
+	if err := c.publish(p.statusTopic(), []byte("online"), true); err != nil {
+		log.Printf("MQTT: publish failed: %v", err)
+		c.close()
+		return false
+	}
+
+	for {
+		select {
+		case <-p.stop:
+			c.publish(p.statusTopic(), []byte("offline"), true)
+			c.close()
+			return true
+		case <-c.done:
+			log.Printf("MQTT: connection to %s lost: %v", p.cfg.Broker, c.err)
+			return false
+		case m := <-p.queue:
+			if err := c.publish(m.topic, m.payload, m.retain); err != nil {
+				log.Printf("MQTT: publish to %s failed: %v", m.topic, err)
+				c.close()
+				return false
+			}
+		}
+	}
+}
+
+// enqueue queues a message, dropping it if the broker can't keep up
+func (p *Publisher) enqueue(topic string, v interface{}, retain bool) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("MQTT: failed to encode message for %s: %v", topic, err)
+		return
+	}
+
+	select {
+	case p.queue <- message{topic: topic, payload: payload, retain: retain}:
+	default:
+		p.mu.Lock()
+		p.dropped++
+		if time.Since(p.lastDropWarn) > time.Minute {
+			log.Printf("MQTT: queue full, dropped %d messages", p.dropped)
+			p.dropped = 0
+			p.lastDropWarn = time.Now()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// topic expands a topic template
+func (p *Publisher) topic(template, ip, hostname, alertType string) string {
+	return strings.NewReplacer(
+		"{prefix}", p.cfg.TopicPrefix,
+		"{ip}", topicLevel(ip),
+		"{hostname}", topicLevel(hostname),
+		"{type}", topicLevel(alertType),
+	).Replace(template)
+}
+
+// topicLevel makes a value safe to use as a single topic level
+func topicLevel(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(s)
+}
+
+// PublishSnapshot publishes a miner snapshot, at most once per configured
+// interval per miner
+func (p *Publisher) PublishSnapshot(snap *storage.MinerSnapshot) {
+	if interval := time.Duration(p.cfg.SnapshotIntervalSecs) * time.Second; interval > 0 {
+		p.mu.Lock()
+		last := p.lastSnapshot[snap.MinerIP]
+		if snap.Timestamp.Sub(last) < interval {
+			p.mu.Unlock()
+			return
+		}
+		p.lastSnapshot[snap.MinerIP] = snap.Timestamp
+		p.mu.Unlock()
+	}
+	p.enqueue(p.topic(p.cfg.Topics.Snapshot, snap.MinerIP, snap.Hostname, ""), snap, p.cfg.RetainSnapshots)
+}
+
+// PublishShare publishes an accepted share
+func (p *Publisher) PublishShare(share *storage.Share) {
+	p.enqueue(p.topic(p.cfg.Topics.Share, share.MinerIP, share.Hostname, ""), share, false)
+}
+
+// PublishBlock publishes a found block
+func (p *Publisher) PublishBlock(block *storage.Block) {
+	p.enqueue(p.topic(p.cfg.Topics.Block, block.MinerIP, block.Hostname, ""), block, false)
+}
+
+// PublishAlert publishes a triggered alert
+func (p *Publisher) PublishAlert(alert alerts.Alert) {
+	p.enqueue(p.topic(p.cfg.Topics.Alert, alert.MinerIP, alert.MinerName, string(alert.Type)), alert, false)
+}
@@ -0,0 +1,113 @@
+package mqtt
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// readPacket reads one packet from a client and returns its header and body
+func readPacket(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	header, err := r.ReadByte()
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+	length, err := readLength(r)
+	if err != nil {
+		t.Fatalf("failed to read length: %v", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return header, body
+}
+
+// splitPublish splits a PUBLISH body into topic and payload
+func splitPublish(body []byte) (string, string) {
+	n := int(body[0])<<8 | int(body[1])
+	return string(body[2 : 2+n]), string(body[2+n:])
+}
+
+func TestPublisher(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := config.DefaultConfig().MQTT
+	cfg.Broker = "tcp://" + ln.Addr().String()
+	cfg.Username = "miner"
+	cfg.Password = "secret"
+	p := NewPublisher(cfg)
+	p.Start()
+	defer p.Stop()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	header, body := readPacket(t, r)
+	if header != packetConnect {
+		t.Fatalf("expected CONNECT, got 0x%02X", header)
+	}
+	if flags := body[7]; flags&0xC6 != 0xC6 {
+		t.Errorf("expected clean session, will, username and password flags, got 0x%02X", flags)
+	}
+	conn.Write([]byte{packetConnack, 2, 0, 0})
+
+	header, body = readPacket(t, r)
+	if topic, payload := splitPublish(body); header != packetPublish|0x01 || topic != "minerhq/status" || payload != "online" {
+		t.Fatalf("expected retained online status, got 0x%02X %s %s", header, topic, payload)
+	}
+
+	now := time.Now()
+	p.PublishSnapshot(&storage.MinerSnapshot{MinerIP: "192.168.1.50", Hostname: "nerdqaxe", Timestamp: now, HashRate: 500})
+	p.PublishSnapshot(&storage.MinerSnapshot{MinerIP: "192.168.1.50", Hostname: "nerdqaxe", Timestamp: now.Add(time.Second)}) // Within the interval
+	p.PublishBlock(&storage.Block{MinerIP: "192.168.1.50", Hostname: "nerd/qaxe+1"})
+
+	_, body = readPacket(t, r)
+	if topic, _ := splitPublish(body); topic != "minerhq/nerdqaxe/snapshot" {
+		t.Errorf("expected snapshot topic, got %s", topic)
+	}
+	_, body = readPacket(t, r)
+	if topic, _ := splitPublish(body); topic != "minerhq/nerd_qaxe_1/block" {
+		t.Errorf("expected the second snapshot to be skipped and a sanitized block topic, got %s", topic)
+	}
+}
+
+func TestConnackRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		r.ReadByte()
+		length, _ := readLength(r)
+		r.Discard(length)
+		conn.Write([]byte{packetConnack, 2, 0, 4})
+	}()
+
+	if _, err := dial("tcp://"+ln.Addr().String(), "minerhq", "miner", "wrong", false, nil, nil); err == nil || err.Error() != "broker refused connection: bad username or password" {
+		t.Errorf("expected bad credentials error, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package mqtt
+
+import (
+	"log"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// Subscriber receives the messages of a few topics, such as the scheduler's
+// signals, reconnecting to the broker as needed. It connects separately from
+// the Publisher, with "-sub" appended to the client ID.
+type Subscriber struct {
+	cfg    config.MQTTConfig
+	topics []string
+	handle func(topic string, payload []byte)
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewSubscriber creates a subscriber to topics on the configured broker.
+// handle is called from a single goroutine.
+func NewSubscriber(cfg config.MQTTConfig, topics []string, handle func(topic string, payload []byte)) *Subscriber {
+	return &Subscriber{
+		cfg:    cfg,
+		topics: topics,
+		handle: handle,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start connects to the broker in the background
+func (s *Subscriber) Start() {
+	go s.run()
+}
+
+// Stop disconnects
+func (s *Subscriber) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// run keeps a subscribed connection to the broker
+func (s *Subscriber) run() {
+	defer close(s.done)
+
+	backoff := time.Second
+	for {
+		c, err := dial(s.cfg.Broker, s.cfg.ClientID+"-sub", s.cfg.Username, s.cfg.Password, s.cfg.Insecure, nil, s.handle)
+		if err == nil {
+			if err = c.subscribe(s.topics); err != nil {
+				c.close()
+			}
+		}
+		if err != nil {
+			log.Printf("MQTT: subscribing on %s failed, retrying in %s: %v", s.cfg.Broker, backoff, err)
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+
+		log.Printf("MQTT: subscribed to %d topics on %s", len(s.topics), s.cfg.Broker)
+		backoff = time.Second
+		select {
+		case <-s.stop:
+			c.close()
+			return
+		case <-c.done:
+			log.Printf("MQTT: subscription to %s lost: %v", s.cfg.Broker, c.err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package mqtt
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+func TestSubscriber(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := config.DefaultConfig().MQTT
+	cfg.Broker = "tcp://" + ln.Addr().String()
+	received := make(chan string, 1)
+	s := NewSubscriber(cfg, []string{"inverter/surplus"}, func(topic string, payload []byte) {
+		received <- topic + " " + string(payload)
+	})
+	s.Start()
+	defer s.Stop()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	header, body := readPacket(t, r)
+	if header != packetConnect {
+		t.Fatalf("expected CONNECT, got 0x%02X", header)
+	}
+	if flags := body[7]; flags&0x04 != 0 {
+		t.Errorf("expected no will, got flags 0x%02X", flags)
+	}
+	conn.Write([]byte{packetConnack, 2, 0, 0})
+
+	header, body = readPacket(t, r)
+	if header != packetSubscribe {
+		t.Fatalf("expected SUBSCRIBE, got 0x%02X", header)
+	}
+	if topic, qos := splitPublish(body[2:]); topic != "inverter/surplus" || qos != "\x00" {
+		t.Errorf("expected a QoS 0 subscription to inverter/surplus, got %q %q", topic, qos)
+	}
+	conn.Write([]byte{0x90, 3, 0, 1, 0}) // SUBACK
+
+	publish := appendString(nil, "inverter/surplus")
+	conn.Write(packet(packetPublish, append(publish, "1234.5"...)))
+	select {
+	case got := <-received:
+		if got != "inverter/surplus 1234.5" {
+			t.Errorf("unexpected message %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
@@ -0,0 +1,315 @@
+// Package plugs reads the wall power of miners from the smart plugs they are
+// powered through, power cycles miners that no longer answer their API, and
+// switches miners off and on for the scheduler.
+package plugs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+const (
+	// defaultPollInterval is how often plugs are read unless configured
+	// otherwise
+	defaultPollInterval = 10 * time.Second
+
+	// defaultOffTime is how long a power cycle keeps a miner off unless
+	// configured otherwise
+	defaultOffTime = 10 * time.Second
+
+	// staleReadings is how many poll intervals a reading stays in use when
+	// the plug stops answering
+	staleReadings = 3
+)
+
+// ErrNoPlug is returned for miners without a configured plug
+var ErrNoPlug = errors.New("no smart plug is configured for this miner")
+
+// device is a plug's protocol
+type device interface {
+	power() (float64, error)       // Watts drawn through the miner's outlet
+	cycle(off time.Duration) error // Switches the outlet off, and the plug itself back on after off
+	switchTo(on bool) error        // Switches the outlet on or off for good
+}
+
+// newDevice returns the protocol of a configured plug
+func newDevice(cfg config.PlugConfig, client *http.Client) (device, error) {
+	switch cfg.Type {
+	case "tasmota":
+		return &tasmota{cfg: cfg, client: client}, nil
+	case "shelly":
+		return &shelly{cfg: cfg, client: client}, nil
+	case "shelly_rpc":
+		return &shellyRPC{cfg: cfg, client: client}, nil
+	case "tplink":
+		return &tplink{cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("unknown plug type %q", cfg.Type)
+}
+
+// Status reports a plug and its last reading
+type Status struct {
+	Miner     string     `json:"miner"`
+	Type      string     `json:"type"`
+	Address   string     `json:"address"`
+	Watts     *float64   `json:"watts,omitempty"` // Last reading, unset if none since the start
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+	Error     string     `json:"error,omitempty"`     // Error of the last reading, if it failed
+	LastCycle *time.Time `json:"lastCycle,omitempty"` // Last power cycle since the start
+}
+
+type plugState struct {
+	watts     float64
+	readAt    time.Time
+	err       string
+	lastCycle time.Time
+}
+
+// Manager reads every configured plug each poll interval and power cycles
+// miners on request. The plugs are read from the settings on every poll, so
+// changes apply without a restart.
+type Manager struct {
+	settings func() *config.Config
+	client   *http.Client
+
+	mu    sync.Mutex
+	plugs map[string]*plugState // By miner IP
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewManager creates a manager for the plugs in the settings
+func NewManager(settings func() *config.Config) *Manager {
+	return &Manager{
+		settings: settings,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		plugs:    make(map[string]*plugState),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start reads the plugs in the background
+func (m *Manager) Start() {
+	go func() {
+		defer close(m.done)
+		m.poll()
+		timer := time.NewTimer(m.interval())
+		defer timer.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-timer.C:
+				m.poll()
+				timer.Reset(m.interval())
+			}
+		}
+	}()
+}
+
+// Stop stops reading the plugs
+func (m *Manager) Stop() {
+	close(m.stop)
+	<-m.done
+}
+
+// interval returns the configured poll interval
+func (m *Manager) interval() time.Duration {
+	if secs := m.settings().SmartPlugs.PollSecs; secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return defaultPollInterval
+}
+
+// plug returns the configured plug of a miner
+func (m *Manager) plug(ip string) (config.PlugConfig, bool) {
+	for _, p := range m.settings().SmartPlugs.Plugs {
+		if p.Miner == ip {
+			return p, true
+		}
+	}
+	return config.PlugConfig{}, false
+}
+
+// poll reads every configured plug at once and forgets plugs that were
+// removed from the settings
+func (m *Manager) poll() {
+	plugs := m.settings().SmartPlugs.Plugs
+
+	var wg sync.WaitGroup
+	for _, p := range plugs {
+		wg.Add(1)
+		go func(p config.PlugConfig) {
+			defer wg.Done()
+			m.read(p)
+		}(p)
+	}
+	wg.Wait()
+
+	configured := make(map[string]bool, len(plugs))
+	for _, p := range plugs {
+		configured[p.Miner] = true
+	}
+	m.mu.Lock()
+	for ip := range m.plugs {
+		if !configured[ip] {
+			delete(m.plugs, ip)
+		}
+	}
+	m.mu.Unlock()
+}
+
+// read takes a power reading from a plug
+func (m *Manager) read(p config.PlugConfig) {
+	dev, err := newDevice(p, m.client)
+	var watts float64
+	if err == nil {
+		watts, err = dev.power()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.state(p.Miner)
+	if err != nil {
+		if st.err == "" {
+			log.Printf("Smart plug of %s (%s) failed: %v", p.Miner, p.Address, err)
+		}
+		st.err = err.Error()
+		return
+	}
+	if st.err != "" {
+		log.Printf("Smart plug of %s (%s) answers again", p.Miner, p.Address)
+	}
+	st.watts, st.readAt, st.err = watts, time.Now(), ""
+}
+
+// state returns a plug's state, creating it if needed. The caller must hold
+// mu.
+func (m *Manager) state(ip string) *plugState {
+	st := m.plugs[ip]
+	if st == nil {
+		st = &plugState{}
+		m.plugs[ip] = st
+	}
+	return st
+}
+
+// Power returns the wall power of a miner read from its plug, unless it has
+// no plug or the plug hasn't been read for a few poll intervals
+func (m *Manager) Power(ip string) (float64, bool) {
+	maxAge := staleReadings * m.interval()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.plugs[ip]
+	if st == nil || st.readAt.IsZero() || time.Since(st.readAt) > maxAge {
+		return 0, false
+	}
+	return st.watts, true
+}
+
+// PowerCycle switches a miner's plug off, and back on after off_secs. The
+// plug switches itself back on, so the miner comes back even if MinerHQ
+// can't reach the plug in between.
+func (m *Manager) PowerCycle(ip string) error {
+	p, ok := m.plug(ip)
+	if !ok {
+		return ErrNoPlug
+	}
+	dev, err := newDevice(p, m.client)
+	if err != nil {
+		return err
+	}
+	off := defaultOffTime
+	if secs := m.settings().SmartPlugs.OffSecs; secs > 0 {
+		off = time.Duration(secs) * time.Second
+	}
+	if err := dev.cycle(off); err != nil {
+		return err
+	}
+
+	log.Printf("Power cycled %s through its smart plug (%s), off for %v", ip, p.Address, off)
+	m.mu.Lock()
+	m.state(ip).lastCycle = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// SetPower switches a miner's plug on or off, e.g. to pause it for a
+// schedule. Unlike PowerCycle, an outlet switched off stays off.
+func (m *Manager) SetPower(ip string, on bool) error {
+	p, ok := m.plug(ip)
+	if !ok {
+		return ErrNoPlug
+	}
+	dev, err := newDevice(p, m.client)
+	if err != nil {
+		return err
+	}
+	if err := dev.switchTo(on); err != nil {
+		return err
+	}
+
+	state := "off"
+	if on {
+		state = "on"
+	}
+	log.Printf("Switched %s %s through its smart plug (%s)", ip, state, p.Address)
+	return nil
+}
+
+// Status reports every configured plug in the order of the settings
+func (m *Manager) Status() []Status {
+	plugs := m.settings().SmartPlugs.Plugs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(plugs))
+	for _, p := range plugs {
+		s := Status{Miner: p.Miner, Type: p.Type, Address: p.Address}
+		if st := m.plugs[p.Miner]; st != nil {
+			s.Error = st.err
+			if !st.readAt.IsZero() {
+				watts, readAt := st.watts, st.readAt
+				s.Watts, s.ReadAt = &watts, &readAt
+			}
+			if !st.lastCycle.IsZero() {
+				lastCycle := st.lastCycle
+				s.LastCycle = &lastCycle
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// getJSON fetches a plug's HTTP endpoint and decodes its JSON answer, with
+// basic auth if a username or password is set
+func getJSON(client *http.Client, url, username, password string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach plug: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
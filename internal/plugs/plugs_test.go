@@ -0,0 +1,225 @@
+package plugs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// plugServer serves canned answers by path and records the requested URLs.
+// Read them only once the requests are answered.
+func plugServer(t *testing.T, answers map[string]string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.String())
+		mu.Unlock()
+		answer, ok := answers[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); ok && (user != "admin" || pass != "secret") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(answer))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestTasmota(t *testing.T) {
+	srv, requests := plugServer(t, map[string]string{"/cm": `{"StatusSNS":{"ENERGY":{"Power":[12,31.5]}}}`})
+	cfg := config.PlugConfig{Type: "tasmota", Address: strings.TrimPrefix(srv.URL, "http://"), Relay: 1, Username: "admin", Password: "secret"}
+	dev, _ := newDevice(cfg, srv.Client())
+
+	watts, err := dev.power()
+	if err != nil || watts != 31.5 {
+		t.Fatalf("power() = %v, %v; want the second outlet's 31.5", watts, err)
+	}
+	if err := dev.cycle(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*requests)[1]; !strings.Contains(got, "Backlog+Power2+Off%3B+Delay+100%3B+Power2+On") || !strings.Contains(got, "password=secret") {
+		t.Errorf("unexpected cycle request %s", got)
+	}
+	if err := dev.switchTo(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*requests)[2]; !strings.Contains(got, "cmnd=Power2+Off") {
+		t.Errorf("unexpected switch request %s", got)
+	}
+
+	srv, _ = plugServer(t, map[string]string{"/cm": `{"WARNING":"Need user=<username>&password=<password>"}`})
+	cfg.Address = strings.TrimPrefix(srv.URL, "http://")
+	dev, _ = newDevice(cfg, srv.Client())
+	if _, err := dev.power(); err == nil {
+		t.Error("expected the login warning as an error")
+	}
+}
+
+func TestShelly(t *testing.T) {
+	srv, requests := plugServer(t, map[string]string{
+		"/status":               `{"meters":[{"power":14.2}]}`,
+		"/relay/0":              `{"ison":false}`,
+		"/rpc/Switch.GetStatus": `{"id":0,"apower":15.8}`,
+		"/rpc/Switch.Set":       `{"was_on":true}`,
+	})
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	gen1, _ := newDevice(config.PlugConfig{Type: "shelly", Address: addr}, srv.Client())
+	if watts, err := gen1.power(); err != nil || watts != 14.2 {
+		t.Errorf("shelly power() = %v, %v", watts, err)
+	}
+	if err := gen1.cycle(15 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen1.switchTo(true); err != nil {
+		t.Fatal(err)
+	}
+
+	rpc, _ := newDevice(config.PlugConfig{Type: "shelly_rpc", Address: addr}, srv.Client())
+	if watts, err := rpc.power(); err != nil || watts != 15.8 {
+		t.Errorf("shelly_rpc power() = %v, %v", watts, err)
+	}
+	if err := rpc.cycle(15 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := rpc.switchTo(false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/status", "/relay/0?turn=off&timer=15", "/relay/0?turn=on",
+		"/rpc/Switch.GetStatus?id=0", "/rpc/Switch.Set?id=0&on=false&toggle_after=15", "/rpc/Switch.Set?id=0&on=false"}
+	if strings.Join(*requests, " ") != strings.Join(want, " ") {
+		t.Errorf("requests = %v, want %v", *requests, want)
+	}
+}
+
+func TestTPLinkCipher(t *testing.T) {
+	plain := []byte(`{"system":{"get_sysinfo":{}}}`)
+	cipher := tplinkEncrypt(plain)
+	if cipher[0] != 171^'{' {
+		t.Errorf("first byte = %d, want %d", cipher[0], 171^'{')
+	}
+	if got := tplinkDecrypt(cipher); string(got) != string(plain) {
+		t.Errorf("round trip = %q", got)
+	}
+}
+
+func TestTPLink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var mu sync.Mutex
+	var requests []string
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var size [4]byte
+			io.ReadFull(conn, size[:])
+			body := make([]byte, binary.BigEndian.Uint32(size[:]))
+			io.ReadFull(conn, body)
+			req := string(tplinkDecrypt(body))
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+
+			answer := `{"count_down":{"delete_all_rules":{"err_code":0},"add_rule":{"err_code":0}},"system":{"set_relay_state":{"err_code":0}}}`
+			if strings.Contains(req, "emeter") {
+				answer = `{"emeter":{"get_realtime":{"power_mw":12345,"err_code":0}}}`
+			}
+			out := make([]byte, 4)
+			binary.BigEndian.PutUint32(out, uint32(len(answer)))
+			conn.Write(append(out, tplinkEncrypt([]byte(answer))...))
+			conn.Close()
+		}
+	}()
+
+	dev, _ := newDevice(config.PlugConfig{Type: "tplink", Address: ln.Addr().String()}, nil)
+	if watts, err := dev.power(); err != nil || watts != 12.345 {
+		t.Fatalf("power() = %v, %v; want 12.345", watts, err)
+	}
+	if err := dev.cycle(20 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 4 || !strings.Contains(requests[2], `"delay":20`) || !strings.Contains(requests[3], `"state":0`) {
+		t.Errorf("unexpected requests %v", requests)
+	}
+	var rule map[string]interface{}
+	if err := json.Unmarshal([]byte(requests[2]), &rule); err != nil {
+		t.Errorf("countdown rule isn't JSON: %v", err)
+	}
+}
+
+func TestManager(t *testing.T) {
+	srv, _ := plugServer(t, map[string]string{"/status": `{"meters":[{"power":14.2}]}`, "/relay/0": `{"ison":false}`})
+	cfg := &config.Config{}
+	cfg.SmartPlugs.Plugs = []config.PlugConfig{
+		{Miner: "10.0.0.2", Type: "shelly", Address: strings.TrimPrefix(srv.URL, "http://")},
+		{Miner: "10.0.0.3", Type: "shelly", Address: "127.0.0.1:1"},
+	}
+	m := NewManager(func() *config.Config { return cfg })
+
+	m.poll()
+	if watts, ok := m.Power("10.0.0.2"); !ok || watts != 14.2 {
+		t.Errorf("Power = %v, %v; want 14.2", watts, ok)
+	}
+	if _, ok := m.Power("10.0.0.3"); ok {
+		t.Error("expected no reading from an unreachable plug")
+	}
+	if _, ok := m.Power("10.0.0.9"); ok {
+		t.Error("expected no reading for a miner without a plug")
+	}
+
+	if err := m.PowerCycle("10.0.0.9"); !errors.Is(err, ErrNoPlug) {
+		t.Errorf("PowerCycle without a plug = %v, want ErrNoPlug", err)
+	}
+	if err := m.PowerCycle("10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetPower("10.0.0.9", false); !errors.Is(err, ErrNoPlug) {
+		t.Errorf("SetPower without a plug = %v, want ErrNoPlug", err)
+	}
+	if err := m.SetPower("10.0.0.2", false); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := m.Status()
+	if len(statuses) != 2 || statuses[0].LastCycle == nil || statuses[0].Watts == nil || statuses[1].Error == "" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+
+	// A stale reading is no longer used
+	m.plugs["10.0.0.2"].readAt = time.Now().Add(-time.Minute)
+	if _, ok := m.Power("10.0.0.2"); ok {
+		t.Error("expected a reading older than 3 poll intervals ignored")
+	}
+
+	cfg.SmartPlugs.Plugs = cfg.SmartPlugs.Plugs[:1]
+	m.poll()
+	if len(m.plugs) != 1 {
+		t.Errorf("expected the removed plug forgotten, got %d plugs", len(m.plugs))
+	}
+}
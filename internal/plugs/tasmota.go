@@ -0,0 +1,86 @@
+package plugs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// tasmota talks to plugs running Tasmota through its HTTP command API
+type tasmota struct {
+	cfg    config.PlugConfig
+	client *http.Client
+}
+
+// command runs a Tasmota command and decodes its answer. Tasmota answers a
+// missing or wrong login with 200 and a warning.
+func (t *tasmota) command(cmnd string, out interface{}) error {
+	q := url.Values{"cmnd": {cmnd}}
+	if t.cfg.Username != "" || t.cfg.Password != "" {
+		q.Set("user", t.cfg.Username)
+		q.Set("password", t.cfg.Password)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := getJSON(t.client, "http://"+t.cfg.Address+"/cm?"+q.Encode(), "", "", &resp); err != nil {
+		return err
+	}
+	if warning, ok := resp["WARNING"]; ok {
+		return fmt.Errorf("plug refused the command: %s", warning)
+	}
+	if out == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(resp)
+	return json.Unmarshal(raw, out)
+}
+
+func (t *tasmota) power() (float64, error) {
+	var resp struct {
+		StatusSNS struct {
+			Energy *struct {
+				Power json.RawMessage `json:"Power"`
+			} `json:"ENERGY"`
+		} `json:"StatusSNS"`
+	}
+	if err := t.command("Status 8", &resp); err != nil {
+		return 0, err
+	}
+	if resp.StatusSNS.Energy == nil {
+		return 0, fmt.Errorf("plug does not measure power")
+	}
+
+	// Multi-channel plugs report one reading per outlet
+	raw := resp.StatusSNS.Energy.Power
+	var watts float64
+	if err := json.Unmarshal(raw, &watts); err == nil {
+		return watts, nil
+	}
+	var channels []float64
+	if err := json.Unmarshal(raw, &channels); err != nil {
+		return 0, fmt.Errorf("unexpected power reading %s", raw)
+	}
+	if t.cfg.Relay >= len(channels) {
+		return 0, fmt.Errorf("plug has no outlet %d", t.cfg.Relay)
+	}
+	return channels[t.cfg.Relay], nil
+}
+
+// cycle has the plug run the whole cycle itself: Tasmota's Delay counts
+// tenths of a second
+func (t *tasmota) cycle(off time.Duration) error {
+	relay := t.cfg.Relay + 1 // Tasmota numbers outlets from 1
+	return t.command(fmt.Sprintf("Backlog Power%d Off; Delay %d; Power%d On", relay, off/(100*time.Millisecond), relay), nil)
+}
+
+func (t *tasmota) switchTo(on bool) error {
+	state := "Off"
+	if on {
+		state = "On"
+	}
+	return t.command(fmt.Sprintf("Power%d %s", t.cfg.Relay+1, state), nil)
+}
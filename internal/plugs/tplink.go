@@ -0,0 +1,174 @@
+package plugs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// tplinkPort is the port of the Kasa local protocol
+const tplinkPort = "9999"
+
+// tplink talks to TP-Link Kasa plugs over their local protocol: JSON
+// obfuscated with an autokey XOR cipher, prefixed with its length, one
+// request per connection
+type tplink struct {
+	cfg config.PlugConfig
+}
+
+// tplinkResult is the err_code every Kasa method answers with
+type tplinkResult struct {
+	ErrCode int    `json:"err_code"`
+	ErrMsg  string `json:"err_msg"`
+}
+
+func (r tplinkResult) err() error {
+	if r.ErrCode != 0 {
+		return fmt.Errorf("plug error %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
+
+// tplinkEncrypt obfuscates a request: each byte is XORed with the previous
+// ciphertext byte, starting from 171
+func tplinkEncrypt(plain []byte) []byte {
+	out := make([]byte, len(plain))
+	key := byte(171)
+	for i, b := range plain {
+		key ^= b
+		out[i] = key
+	}
+	return out
+}
+
+// tplinkDecrypt reverses tplinkEncrypt
+func tplinkDecrypt(cipher []byte) []byte {
+	out := make([]byte, len(cipher))
+	key := byte(171)
+	for i, b := range cipher {
+		out[i] = key ^ b
+		key = b
+	}
+	return out
+}
+
+// call sends one request to the plug and decodes its answer
+func (t *tplink) call(req interface{}, out interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	addr := t.cfg.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, tplinkPort)
+	}
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to reach plug: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	frame := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	if _, err := conn.Write(append(frame, tplinkEncrypt(body)...)); err != nil {
+		return err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > 1<<20 {
+		return fmt.Errorf("answer of %d bytes is too large", n)
+	}
+	answer := make([]byte, n)
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return err
+	}
+	return json.Unmarshal(tplinkDecrypt(answer), out)
+}
+
+func (t *tplink) power() (float64, error) {
+	var resp struct {
+		Emeter *struct {
+			Realtime struct {
+				tplinkResult
+				PowerMW *float64 `json:"power_mw"` // Hardware version 2 and later
+				Power   *float64 `json:"power"`    // Hardware version 1, in W
+			} `json:"get_realtime"`
+		} `json:"emeter"`
+	}
+	req := map[string]interface{}{"emeter": map[string]interface{}{"get_realtime": struct{}{}}}
+	if err := t.call(req, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Emeter == nil {
+		return 0, fmt.Errorf("plug does not measure power")
+	}
+	rt := resp.Emeter.Realtime
+	if err := rt.err(); err != nil {
+		return 0, err
+	}
+	switch {
+	case rt.PowerMW != nil:
+		return *rt.PowerMW / 1000, nil
+	case rt.Power != nil:
+		return *rt.Power, nil
+	}
+	return 0, fmt.Errorf("plug does not measure power")
+}
+
+// cycle sets a countdown rule that switches the plug back on, then switches
+// it off
+func (t *tplink) cycle(off time.Duration) error {
+	var cleared struct {
+		CountDown struct {
+			Delete tplinkResult `json:"delete_all_rules"`
+		} `json:"count_down"`
+	}
+	if err := t.call(map[string]interface{}{"count_down": map[string]interface{}{"delete_all_rules": struct{}{}}}, &cleared); err != nil {
+		return err
+	}
+	if err := cleared.CountDown.Delete.err(); err != nil {
+		return err
+	}
+
+	var added struct {
+		CountDown struct {
+			Add tplinkResult `json:"add_rule"`
+		} `json:"count_down"`
+	}
+	rule := map[string]interface{}{"enable": 1, "delay": int(off.Seconds()), "act": 1, "name": "MinerHQ power cycle"}
+	if err := t.call(map[string]interface{}{"count_down": map[string]interface{}{"add_rule": rule}}, &added); err != nil {
+		return err
+	}
+	if err := added.CountDown.Add.err(); err != nil {
+		return err
+	}
+
+	return t.switchTo(false)
+}
+
+func (t *tplink) switchTo(on bool) error {
+	state := 0
+	if on {
+		state = 1
+	}
+	var switched struct {
+		System struct {
+			Relay tplinkResult `json:"set_relay_state"`
+		} `json:"system"`
+	}
+	if err := t.call(map[string]interface{}{"system": map[string]interface{}{"set_relay_state": map[string]int{"state": state}}}, &switched); err != nil {
+		return err
+	}
+	return switched.System.Relay.err()
+}
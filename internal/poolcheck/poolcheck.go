@@ -0,0 +1,335 @@
+// Package poolcheck connects to the pools the miners are configured for and
+// subscribes like a miner would, so a pool that is down or unreachable is
+// noticed before the miners drop their connections to it.
+package poolcheck
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// tickInterval is how often the checker looks for pools due for a check
+const tickInterval = 10 * time.Second
+
+// Pool is a stratum endpoint
+type Pool struct {
+	Host string
+	Port int
+	TLS  bool // stratum+ssl or stratum+tls
+}
+
+// String returns the pool's URL, e.g. stratum+tcp://public-pool.io:21496
+func (p Pool) String() string {
+	scheme := "stratum+tcp"
+	if p.TLS {
+		scheme = "stratum+ssl"
+	}
+	return scheme + "://" + net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+// ParsePool returns the pool of a miner's stratum URL and port. The URL may
+// be a bare host, as AxeOS stores it, or carry a scheme and a port of its
+// own, which wins over port.
+func ParsePool(url string, port int) (Pool, bool) {
+	url = strings.TrimSpace(url)
+	var p Pool
+	if scheme, rest, ok := strings.Cut(url, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case "stratum+ssl", "stratum+tls", "ssl", "tls":
+			p.TLS = true
+		}
+		url = rest
+	}
+	url, _, _ = strings.Cut(url, "/")
+
+	p.Host, p.Port = url, port
+	if host, portStr, err := net.SplitHostPort(url); err == nil {
+		n, err := strconv.Atoi(portStr)
+		if err != nil {
+			return Pool{}, false
+		}
+		p.Host, p.Port = host, n
+	}
+	if p.Host == "" || p.Port <= 0 || p.Port > 65535 {
+		return Pool{}, false
+	}
+	return p, true
+}
+
+// Handshake connects to a pool and sends mining.subscribe, returning how long
+// the pool took to answer. A pool that accepts the connection but doesn't
+// answer the subscription, or refuses it, fails.
+func Handshake(p Pool, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	dialer := &net.Dialer{Timeout: timeout}
+	addr := net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+
+	var conn net.Conn
+	var err error
+	if p.TLS {
+		// Only reachability is checked; whether to trust the certificate is
+		// up to the miners
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: p.Host, InsecureSkipVerify: true})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(start.Add(timeout))
+
+	if _, err := conn.Write([]byte(`{"id":1,"method":"mining.subscribe","params":["MinerHQ"]}` + "\n")); err != nil {
+		return 0, err
+	}
+
+	// Pools may send notifications, e.g. mining.set_difficulty, before the
+	// answer
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return 0, fmt.Errorf("no answer to mining.subscribe: %w", err)
+		}
+		var msg struct {
+			ID    json.RawMessage `json:"id"`
+			Error json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return 0, fmt.Errorf("not a stratum server: %w", err)
+		}
+		if string(msg.ID) != "1" {
+			continue
+		}
+		if len(msg.Error) > 0 && string(msg.Error) != "null" {
+			return 0, fmt.Errorf("subscription refused: %s", msg.Error)
+		}
+		return time.Since(start), nil
+	}
+}
+
+// Miner is a miner configured for a pool
+type Miner struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+}
+
+// Status reports the checks of a pool
+type Status struct {
+	Pool         string     `json:"pool"`
+	Miners       []Miner    `json:"miners"` // Local miners configured for the pool
+	Reachable    bool       `json:"reachable"`
+	Unreachable  bool       `json:"unreachable"`            // Failed enough checks in a row to alert
+	LatencyMs    int64      `json:"latencyMs,omitempty"`    // Handshake time of the last check, if it succeeded
+	Failures     int        `json:"failures"`               // Failed checks in a row
+	FailingSince *time.Time `json:"failingSince,omitempty"` // First of the failed checks in a row
+	LastCheck    *time.Time `json:"lastCheck,omitempty"`
+	LastSuccess  *time.Time `json:"lastSuccess,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+}
+
+// poolState is what the checker tracks of one pool
+type poolState struct {
+	pool     Pool
+	miners   []Miner
+	checking bool
+	status   Status
+}
+
+// Checker checks the pools of the local miners every interval_secs and
+// reports a pool failing failures checks in a row, and its recovery. The
+// settings are read on every tick, so changes apply without a restart.
+type Checker struct {
+	settings  func() *config.Config
+	miners    func() ([]*storage.Miner, error)
+	handshake func(p Pool, timeout time.Duration) (time.Duration, error)
+	onChange  func(s Status, message string) // Called when a pool becomes unreachable or reachable again
+
+	mu    sync.Mutex
+	pools map[string]*poolState
+	wg    sync.WaitGroup // Checks in flight
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a checker of the pools of miners, e.g. storage.GetMiners.
+// onChange may be nil.
+func New(settings func() *config.Config, miners func() ([]*storage.Miner, error), onChange func(s Status, message string)) *Checker {
+	return &Checker{
+		settings:  settings,
+		miners:    miners,
+		handshake: Handshake,
+		onChange:  onChange,
+		pools:     make(map[string]*poolState),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start checks the pools in the background
+func (c *Checker) Start() {
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		c.tick(time.Now())
+		for {
+			select {
+			case <-c.stop:
+				c.wg.Wait()
+				return
+			case <-ticker.C:
+				c.tick(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks, waiting for those in flight
+func (c *Checker) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+// Status returns every pool's state, ordered by pool
+func (c *Checker) Status() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]Status, 0, len(c.pools))
+	for _, st := range c.pools {
+		s := st.status
+		s.Miners = append([]Miner(nil), st.miners...)
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Pool < statuses[j].Pool })
+	return statuses
+}
+
+// tick refreshes the pools from the miners and starts the checks that are
+// due
+func (c *Checker) tick(now time.Time) {
+	cfg := c.settings().PoolCheck
+	if !cfg.Enabled {
+		return
+	}
+	miners, err := c.miners()
+	if err != nil {
+		log.Printf("Pool check: failed to get miners: %v", err)
+		return
+	}
+	interval := time.Duration(cfg.IntervalSecs) * time.Second
+	timeout := time.Duration(cfg.TimeoutSecs) * time.Second
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh(miners)
+	for _, st := range c.pools {
+		if st.checking || (st.status.LastCheck != nil && now.Sub(*st.status.LastCheck) < interval) {
+			continue
+		}
+		st.checking = true
+		c.wg.Add(1)
+		go func(st *poolState) {
+			defer c.wg.Done()
+			c.check(st, timeout, cfg.Failures)
+		}(st)
+	}
+}
+
+// refresh groups the local miners by pool, dropping pools no miner is
+// configured for anymore. Miners of remote sites reach their pools over their
+// own network. The caller must hold mu.
+func (c *Checker) refresh(miners []*storage.Miner) {
+	byPool := make(map[string][]Miner)
+	pools := make(map[string]Pool)
+	for _, m := range miners {
+		if collector.IsRemote(m.IP) {
+			continue
+		}
+		p, ok := ParsePool(m.PoolURL, m.PoolPort)
+		if !ok {
+			continue
+		}
+		key := p.String()
+		pools[key] = p
+		byPool[key] = append(byPool[key], Miner{IP: m.IP, Hostname: m.Name()})
+	}
+
+	for key, st := range c.pools {
+		if _, ok := pools[key]; !ok && !st.checking {
+			delete(c.pools, key)
+		}
+	}
+	for key, p := range pools {
+		st := c.pools[key]
+		if st == nil {
+			st = &poolState{pool: p, status: Status{Pool: key}}
+			c.pools[key] = st
+		}
+		st.miners = byPool[key]
+	}
+}
+
+// check runs one handshake with a pool and records the outcome, reporting
+// the pool once it has failed failures checks in a row and again once it
+// answers
+func (c *Checker) check(st *poolState, timeout time.Duration, failures int) {
+	latency, err := c.handshake(st.pool, timeout)
+	now := time.Now()
+
+	c.mu.Lock()
+	st.checking = false
+	s := &st.status
+	s.LastCheck = &now
+	var message string
+	if err == nil {
+		if s.Unreachable {
+			message = fmt.Sprintf("Answers MinerHQ's checks again after %v (%d ms)", now.Sub(*s.FailingSince).Round(time.Second), latency.Milliseconds())
+		}
+		s.Reachable = true
+		s.Unreachable = false
+		s.LatencyMs = latency.Milliseconds()
+		s.Failures = 0
+		s.FailingSince = nil
+		s.LastSuccess = &now
+		s.LastError = ""
+	} else {
+		if s.Failures == 0 {
+			s.FailingSince = &now
+		}
+		s.Reachable = false
+		s.LatencyMs = 0
+		s.Failures++
+		s.LastError = err.Error()
+		if !s.Unreachable && s.Failures >= failures {
+			s.Unreachable = true
+			message = fmt.Sprintf("Failed %d checks in a row from MinerHQ: %s", s.Failures, s.LastError)
+		}
+	}
+	report := *s
+	report.Miners = append([]Miner(nil), st.miners...)
+	c.mu.Unlock()
+
+	if message == "" {
+		return
+	}
+	log.Printf("Pool check: %s: %s", report.Pool, message)
+	if c.onChange != nil {
+		c.onChange(report, message)
+	}
+}
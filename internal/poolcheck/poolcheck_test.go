@@ -0,0 +1,158 @@
+package poolcheck
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+func TestParsePool(t *testing.T) {
+	tests := []struct {
+		url  string
+		port int
+		want string
+		ok   bool
+	}{
+		{"public-pool.io", 21496, "stratum+tcp://public-pool.io:21496", true},
+		{"stratum+tcp://solo.ckpool.org", 3333, "stratum+tcp://solo.ckpool.org:3333", true},
+		{"stratum+ssl://pool.example.com:4443/", 3333, "stratum+ssl://pool.example.com:4443", true},
+		{" 192.168.1.5 ", 3333, "stratum+tcp://192.168.1.5:3333", true},
+		{"", 3333, "", false},
+		{"public-pool.io", 0, "", false},
+		{"pool.example.com:abc", 3333, "", false},
+	}
+	for _, tt := range tests {
+		p, ok := ParsePool(tt.url, tt.port)
+		if ok != tt.ok || (ok && p.String() != tt.want) {
+			t.Errorf("ParsePool(%q, %d) = %v, %v; want %s, %v", tt.url, tt.port, p, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// fakePool serves one stratum connection per answer, replying to the
+// subscription with it
+func fakePool(t *testing.T, answers ...string) Pool {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for _, answer := range answers {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil && answer != "" {
+				conn.Write([]byte(answer))
+			}
+			time.Sleep(50 * time.Millisecond)
+			conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return Pool{Host: "127.0.0.1", Port: addr.Port}
+}
+
+func TestHandshake(t *testing.T) {
+	p := fakePool(t,
+		`{"id":null,"method":"mining.set_difficulty","params":[1024]}`+"\n"+`{"id":1,"result":[[],"08000002",4],"error":null}`+"\n",
+		`{"id":1,"result":null,"error":[20,"Banned",null]}`+"\n",
+		"HTTP/1.1 400 Bad Request\r\n",
+		"",
+	)
+
+	if _, err := Handshake(p, time.Second); err != nil {
+		t.Errorf("expected the subscription to succeed, got %v", err)
+	}
+	if _, err := Handshake(p, time.Second); err == nil || !strings.Contains(err.Error(), "Banned") {
+		t.Errorf("expected a refused subscription, got %v", err)
+	}
+	if _, err := Handshake(p, time.Second); err == nil || !strings.Contains(err.Error(), "not a stratum server") {
+		t.Errorf("expected a non-stratum answer to fail, got %v", err)
+	}
+	if _, err := Handshake(p, time.Second); err == nil || !strings.Contains(err.Error(), "no answer") {
+		t.Errorf("expected a pool closing without answering to fail, got %v", err)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.PoolCheck.Failures = 2
+	miners := []*storage.Miner{
+		{IP: "192.168.1.10", Hostname: "bitaxe-1", PoolURL: "public-pool.io", PoolPort: 21496},
+		{IP: "192.168.1.11", Hostname: "bitaxe-2", PoolURL: "stratum+tcp://public-pool.io:21496"},
+		{IP: "192.168.1.12", Hostname: "bitaxe-3"},
+		{IP: "10.0.0.5@cabin", Hostname: "remote", PoolURL: "solo.ckpool.org", PoolPort: 3333},
+	}
+
+	var changes []Status
+	var messages []string
+	c := New(func() *config.Config { return cfg }, func() ([]*storage.Miner, error) { return miners, nil }, func(s Status, message string) {
+		changes = append(changes, s)
+		messages = append(messages, message)
+	})
+	down := true
+	checks := 0
+	c.handshake = func(p Pool, timeout time.Duration) (time.Duration, error) {
+		checks++
+		if down {
+			return 0, errors.New("connection refused")
+		}
+		return 42 * time.Millisecond, nil
+	}
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		c.tick(now.Add(time.Duration(i) * time.Hour))
+		c.wg.Wait()
+	}
+
+	statuses := c.Status()
+	if len(statuses) != 1 {
+		t.Fatalf("expected the local miners' one pool, got %+v", statuses)
+	}
+	s := statuses[0]
+	if s.Pool != "stratum+tcp://public-pool.io:21496" || len(s.Miners) != 2 || s.Failures != 3 || !s.Unreachable || s.LastError != "connection refused" {
+		t.Errorf("unexpected status: %+v", s)
+	}
+	if len(changes) != 1 || !changes[0].Unreachable || changes[0].Failures != 2 || changes[0].FailingSince == nil {
+		t.Fatalf("expected one report after 2 failures, got %+v", changes)
+	}
+	if messages[0] != "Failed 2 checks in a row from MinerHQ: connection refused" {
+		t.Errorf("unexpected message %q", messages[0])
+	}
+
+	down = false
+	c.tick(now.Add(4 * time.Hour))
+	c.wg.Wait()
+	if len(changes) != 2 || changes[1].Unreachable || !changes[1].Reachable || changes[1].LatencyMs != 42 {
+		t.Fatalf("expected a recovery report, got %+v", changes)
+	}
+	if !strings.HasPrefix(messages[1], "Answers MinerHQ's checks again after") || !strings.HasSuffix(messages[1], "(42 ms)") {
+		t.Errorf("unexpected message %q", messages[1])
+	}
+
+	// Checked less than interval_secs ago
+	c.tick(time.Now())
+	c.wg.Wait()
+	if checks != 4 {
+		t.Errorf("expected 4 checks, got %d", checks)
+	}
+
+	cfg.PoolCheck.Enabled = false
+	miners = nil
+	c.tick(now.Add(5 * time.Hour))
+	if len(c.Status()) != 1 {
+		t.Error("expected a disabled checker to leave its pools alone")
+	}
+}
@@ -0,0 +1,485 @@
+// Package poolstats fetches the worker stats of the pool accounts the miners
+// mine to from the pools' public APIs, solo.ckpool.org and public-pool.io,
+// and reconciles them with the hashrate and best shares the miners report,
+// so a pool crediting a miner with much less than it hashes is noticed.
+package poolstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+// tickInterval is how often the monitor looks for accounts due for a fetch
+const tickInterval = 10 * time.Second
+
+// defaultURLs are the APIs of the public pools
+var defaultURLs = map[string]string{
+	"ckpool":      "https://solo.ckpool.org",
+	"public-pool": "https://public-pool.io:40557",
+}
+
+// Worker is a pool's view of one worker of an account, beside the view of
+// the miner it is matched to
+type Worker struct {
+	Name           string     `json:"name"`
+	MinerIP        string     `json:"minerIp,omitempty"` // Miner the worker is matched to, unset if none
+	Hostname       string     `json:"hostname,omitempty"`
+	PoolHashRate   float64    `json:"poolHashRate"`   // GH/s the pool credits the worker with
+	LocalHashRate  float64    `json:"localHashRate"`  // GH/s the miner reports, its 1h average
+	DivergencePct  float64    `json:"divergencePct"`  // Pool's hashrate against the miner's, in % of the miner's; 0 without one
+	Diverging      bool       `json:"diverging"`      // Off by more than divergence_pct for divergent_checks fetches in a row
+	PoolBestShare  float64    `json:"poolBestShare"`  // Best share the pool credited the worker with
+	LocalBestShare float64    `json:"localBestShare"` // Best share the miner reports
+	LastShare      *time.Time `json:"lastShare,omitempty"`
+
+	checks int // Diverging fetches in a row
+}
+
+// Account reports the fetches of a pool account
+type Account struct {
+	Pool          string     `json:"pool"`
+	Address       string     `json:"address"`
+	PoolHashRate  float64    `json:"poolHashRate"`  // GH/s the pool credits the account with
+	LocalHashRate float64    `json:"localHashRate"` // GH/s of the miners matched to its workers
+	BestShare     float64    `json:"bestShare"`     // Best share the pool credited the account with
+	Workers       []Worker   `json:"workers"`       // By name
+	LastFetch     *time.Time `json:"lastFetch,omitempty"`
+	LastSuccess   *time.Time `json:"lastSuccess,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// poolStats is what a pool's API reports of an account
+type poolStats struct {
+	hashRate  float64 // GH/s
+	bestShare float64
+	workers   []workerStats
+}
+
+type workerStats struct {
+	name      string
+	hashRate  float64 // GH/s
+	bestShare float64
+	lastShare time.Time
+}
+
+// accountState is what the monitor tracks of one account
+type accountState struct {
+	cfg      config.PoolAccountConfig
+	fetching bool
+	status   Account
+}
+
+// Monitor fetches the accounts every interval_secs and reports a worker
+// whose pool-side hashrate diverges from its miner's, and its recovery. The
+// settings are read on every tick, so changes apply without a restart.
+type Monitor struct {
+	settings  func() *config.Config
+	miners    func() ([]*storage.Miner, error)
+	snapshots func() map[string]*storage.MinerSnapshot
+	client    *http.Client
+	onChange  func(pool string, w Worker, message string) // Called when a worker starts or stops diverging
+
+	mu       sync.Mutex
+	accounts map[string]*accountState // By pool and address
+	wg       sync.WaitGroup           // Fetches in flight
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a monitor of the configured pool accounts, matching their
+// workers to miners, e.g. storage.GetMiners, and their latest snapshots, e.g.
+// collector.LatestSnapshots. onChange may be nil.
+func New(settings func() *config.Config, miners func() ([]*storage.Miner, error), snapshots func() map[string]*storage.MinerSnapshot, onChange func(pool string, w Worker, message string)) *Monitor {
+	return &Monitor{
+		settings:  settings,
+		miners:    miners,
+		snapshots: snapshots,
+		client:    &http.Client{Timeout: 15 * time.Second},
+		onChange:  onChange,
+		accounts:  make(map[string]*accountState),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start fetches the accounts in the background
+func (m *Monitor) Start() {
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		m.tick(time.Now())
+		for {
+			select {
+			case <-m.stop:
+				m.wg.Wait()
+				return
+			case <-ticker.C:
+				m.tick(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop stops the background fetches, waiting for those in flight
+func (m *Monitor) Stop() {
+	close(m.stop)
+	<-m.done
+}
+
+// Accounts returns every account's state, in the configured order
+func (m *Monitor) Accounts() []Account {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accounts := make([]Account, 0, len(m.accounts))
+	for _, a := range m.settings().PoolStats.Accounts {
+		if st, ok := m.accounts[accountKey(a)]; ok {
+			s := st.status
+			s.Workers = append([]Worker(nil), st.status.Workers...)
+			accounts = append(accounts, s)
+		}
+	}
+	return accounts
+}
+
+func accountKey(a config.PoolAccountConfig) string {
+	return a.Pool + "/" + a.Address
+}
+
+// tick refreshes the accounts from the settings and starts the fetches that
+// are due
+func (m *Monitor) tick(now time.Time) {
+	cfg := m.settings().PoolStats
+	if !cfg.Enabled {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSecs) * time.Second
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	configured := make(map[string]bool)
+	for _, a := range cfg.Accounts {
+		key := accountKey(a)
+		configured[key] = true
+		st := m.accounts[key]
+		if st == nil {
+			st = &accountState{status: Account{Pool: a.Pool, Address: a.Address, Workers: []Worker{}}}
+			m.accounts[key] = st
+		}
+		st.cfg = a
+	}
+	for key, st := range m.accounts {
+		if !configured[key] && !st.fetching {
+			delete(m.accounts, key)
+		}
+	}
+
+	for key, st := range m.accounts {
+		if !configured[key] || st.fetching || (st.status.LastFetch != nil && now.Sub(*st.status.LastFetch) < interval) {
+			continue
+		}
+		st.fetching = true
+		m.wg.Add(1)
+		go func(st *accountState) {
+			defer m.wg.Done()
+			m.update(st, cfg)
+		}(st)
+	}
+}
+
+// update fetches an account and reconciles its workers with the miners,
+// reporting the workers that start or stop diverging
+func (m *Monitor) update(st *accountState, cfg config.PoolStatsConfig) {
+	m.mu.Lock()
+	account := st.cfg
+	m.mu.Unlock()
+
+	stats, err := m.fetch(account)
+	var miners []*storage.Miner
+	var snapshots map[string]*storage.MinerSnapshot
+	if err == nil {
+		if miners, err = m.miners(); err != nil {
+			err = fmt.Errorf("failed to get miners: %w", err)
+		}
+		snapshots = m.snapshots()
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	st.fetching = false
+	s := &st.status
+	s.LastFetch = &now
+	if err != nil {
+		s.LastError = err.Error()
+		m.mu.Unlock()
+		log.Printf("Pool stats: %s %s: %v", account.Pool, account.Address, err)
+		return
+	}
+	s.LastSuccess = &now
+	s.LastError = ""
+	changed := reconcile(s, account, stats, miners, snapshots, cfg)
+	m.mu.Unlock()
+
+	for _, c := range changed {
+		log.Printf("Pool stats: %s", c.message)
+		if m.onChange != nil {
+			m.onChange(account.Pool, c.worker, c.message)
+		}
+	}
+}
+
+// change is a worker that started or stopped diverging
+type change struct {
+	worker  Worker
+	message string
+}
+
+// reconcile replaces an account's workers with the ones fetched, matched to
+// miners by the configured worker names, else by hostname or display name,
+// and returns the workers that started or stopped diverging
+func reconcile(s *Account, account config.PoolAccountConfig, stats *poolStats, miners []*storage.Miner, snapshots map[string]*storage.MinerSnapshot, cfg config.PoolStatsConfig) []change {
+	byIP := make(map[string]*storage.Miner)
+	byName := make(map[string]*storage.Miner)
+	for _, m := range miners {
+		byIP[m.IP] = m
+		for _, name := range []string{m.Hostname, m.DisplayName} {
+			if name != "" {
+				byName[strings.ToLower(name)] = m
+			}
+		}
+	}
+	previous := make(map[string]Worker)
+	for _, w := range s.Workers {
+		previous[w.Name] = w
+	}
+
+	var changed []change
+	s.PoolHashRate = stats.hashRate
+	s.LocalHashRate = 0
+	s.BestShare = stats.bestShare
+	s.Workers = make([]Worker, 0, len(stats.workers))
+	for _, ws := range stats.workers {
+		w := Worker{Name: ws.name, PoolHashRate: ws.hashRate, PoolBestShare: ws.bestShare}
+		if !ws.lastShare.IsZero() {
+			lastShare := ws.lastShare
+			w.LastShare = &lastShare
+		}
+
+		m := byName[strings.ToLower(ws.name)]
+		if ip, ok := account.Workers[ws.name]; ok {
+			m = byIP[ip]
+		}
+		if m != nil {
+			w.MinerIP, w.Hostname = m.IP, m.Name()
+			if snap := snapshots[m.IP]; snap != nil {
+				w.LocalHashRate = snap.HashRate1h
+				w.LocalBestShare = snap.BestDiff
+			}
+		}
+		s.LocalHashRate += w.LocalHashRate
+
+		// A miner without a hashrate is offline, which its own alerts
+		// report; its worker keeps its state until it hashes again
+		prev := previous[ws.name]
+		w.checks, w.Diverging = prev.checks, prev.Diverging
+		if w.LocalHashRate > 0 {
+			w.DivergencePct = (w.PoolHashRate - w.LocalHashRate) / w.LocalHashRate * 100
+			if math.Abs(w.DivergencePct) > cfg.DivergencePct {
+				w.checks++
+			} else {
+				w.checks = 0
+			}
+			switch {
+			case !w.Diverging && w.checks >= cfg.DivergentChecks:
+				w.Diverging = true
+				changed = append(changed, change{w, fmt.Sprintf("%s credits worker %s with %s, %s the miner's %s, for %d fetches in a row",
+					account.Pool, w.Name, units.FormatHashrate(w.PoolHashRate), describeDivergence(w.DivergencePct), units.FormatHashrate(w.LocalHashRate), w.checks)})
+			case w.Diverging && w.checks == 0:
+				w.Diverging = false
+				changed = append(changed, change{w, fmt.Sprintf("%s credits worker %s with %s again, %s the miner's %s",
+					account.Pool, w.Name, units.FormatHashrate(w.PoolHashRate), describeDivergence(w.DivergencePct), units.FormatHashrate(w.LocalHashRate))})
+			}
+		}
+		s.Workers = append(s.Workers, w)
+	}
+	sort.Slice(s.Workers, func(i, j int) bool { return s.Workers[i].Name < s.Workers[j].Name })
+	return changed
+}
+
+// describeDivergence says how far above or below the miner's the pool's
+// hashrate is, e.g. "38.5% below"
+func describeDivergence(pct float64) string {
+	if pct < 0 {
+		return fmt.Sprintf("%.1f%% below", -pct)
+	}
+	return fmt.Sprintf("%.1f%% above", pct)
+}
+
+// fetch gets an account's stats from its pool
+func (m *Monitor) fetch(a config.PoolAccountConfig) (*poolStats, error) {
+	base := a.URL
+	if base == "" {
+		base = defaultURLs[a.Pool]
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	switch a.Pool {
+	case "ckpool":
+		var user ckpoolUser
+		if err := m.get(base+"/users/"+url.PathEscape(a.Address), &user); err != nil {
+			return nil, err
+		}
+		return user.stats(a.Address), nil
+	case "public-pool":
+		var client publicPoolClient
+		if err := m.get(base+"/api/client/"+url.PathEscape(a.Address), &client); err != nil {
+			return nil, err
+		}
+		return client.stats(), nil
+	}
+	return nil, fmt.Errorf("unknown pool %q", a.Pool)
+}
+
+// get decodes the JSON at endpoint into v
+func (m *Monitor) get(endpoint string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("unknown account: the pool has no shares from it")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// ckpoolUser is solo.ckpool.org's /users/<address>. Hashrates are strings
+// with an SI suffix in H/s, e.g. "1.21T".
+type ckpoolUser struct {
+	HashRate1h string  `json:"hashrate1hr"`
+	BestEver   float64 `json:"bestever"`
+	Workers    []struct {
+		Name       string  `json:"workername"` // <address>.<worker>
+		HashRate1h string  `json:"hashrate1hr"`
+		LastShare  int64   `json:"lastshare"` // Unix seconds
+		BestEver   float64 `json:"bestever"`
+	} `json:"worker"`
+}
+
+func (u *ckpoolUser) stats(address string) *poolStats {
+	stats := &poolStats{hashRate: parseSIHashrate(u.HashRate1h), bestShare: u.BestEver}
+	for _, w := range u.Workers {
+		ws := workerStats{
+			name:      strings.TrimPrefix(w.Name, address+"."),
+			hashRate:  parseSIHashrate(w.HashRate1h),
+			bestShare: w.BestEver,
+		}
+		if w.LastShare > 0 {
+			ws.lastShare = time.Unix(w.LastShare, 0)
+		}
+		stats.workers = append(stats.workers, ws)
+	}
+	return stats
+}
+
+// publicPoolClient is public-pool's /api/client/<address>, with a worker
+// per connection. Numbers may come as strings, hashrates in H/s.
+type publicPoolClient struct {
+	BestDifficulty flexFloat `json:"bestDifficulty"`
+	Workers        []struct {
+		Name           string    `json:"name"`
+		HashRate       flexFloat `json:"hashRate"`
+		BestDifficulty flexFloat `json:"bestDifficulty"`
+		LastSeen       time.Time `json:"lastSeen"`
+	} `json:"workers"`
+}
+
+// stats sums the connections of each worker name
+func (c *publicPoolClient) stats() *poolStats {
+	stats := &poolStats{bestShare: float64(c.BestDifficulty)}
+	index := make(map[string]int)
+	for _, w := range c.Workers {
+		i, ok := index[w.Name]
+		if !ok {
+			i = len(stats.workers)
+			index[w.Name] = i
+			stats.workers = append(stats.workers, workerStats{name: w.Name})
+		}
+		ws := &stats.workers[i]
+		ghs := float64(w.HashRate) * units.HashPerSecond
+		ws.hashRate += ghs
+		stats.hashRate += ghs
+		ws.bestShare = math.Max(ws.bestShare, float64(w.BestDifficulty))
+		if w.LastSeen.After(ws.lastShare) {
+			ws.lastShare = w.LastSeen
+		}
+	}
+	return stats
+}
+
+// flexFloat reads a JSON number, or a string holding one
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// siPrefixes are the hashrate suffixes ckpool uses, as multipliers of H/s
+var siPrefixes = map[byte]float64{
+	'K': 1e3, 'M': 1e6, 'G': 1e9, 'T': 1e12, 'P': 1e15, 'E': 1e18, 'Z': 1e21,
+}
+
+// parseSIHashrate converts a hashrate such as "1.21T" (H/s) to GH/s, 0 if
+// it can't be read
+func parseSIHashrate(s string) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	mult := 1.0
+	if m, ok := siPrefixes[s[len(s)-1]]; ok {
+		mult = m
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v * mult * units.HashPerSecond
+}
@@ -0,0 +1,218 @@
+package poolstats
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const testAddress = "bc1qtest"
+
+// fakePools serves an account at ckpool's and public-pool's endpoints; any
+// other address is unknown
+func fakePools(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/" + testAddress:
+			w.Write([]byte(`{"hashrate1m":"1.6T","hashrate1hr":"1.5T","lastshare":1718000000,"workers":2,"bestshare":52000.5,"bestever":4294967296,
+				"worker":[{"workername":"bc1qtest.bitaxe","hashrate1m":"1.1T","hashrate1hr":"1.05T","lastshare":1718000000,"bestshare":52000.5,"bestever":4294967296},
+				{"workername":"bc1qtest.nerdqaxe","hashrate1hr":"450G","lastshare":1717999000,"bestever":123456}]}`))
+		case "/api/client/" + testAddress:
+			w.Write([]byte(`{"bestDifficulty":"8589934592","workersCount":3,"workers":[
+				{"sessionId":"a1","name":"bitaxe","bestDifficulty":"1000.5","hashRate":"600000000000","startTime":"2024-06-10T00:00:00.000Z","lastSeen":"2024-06-10T06:00:00.000Z"},
+				{"sessionId":"a2","name":"bitaxe","bestDifficulty":2000,"hashRate":400000000000,"startTime":"2024-06-10T00:00:00.000Z","lastSeen":"2024-06-10T06:05:00.000Z"},
+				{"sessionId":"b1","name":"avalon","bestDifficulty":"50","hashRate":"4000000000000","startTime":"2024-06-10T00:00:00.000Z","lastSeen":"2024-06-10T06:01:00.000Z"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Abs(b)
+}
+
+func TestFetch(t *testing.T) {
+	base := fakePools(t)
+	m := New(nil, nil, nil, nil)
+
+	stats, err := m.fetch(config.PoolAccountConfig{Pool: "ckpool", Address: testAddress, URL: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.hashRate != 1500 || stats.bestShare != 4294967296 || len(stats.workers) != 2 {
+		t.Fatalf("unexpected ckpool stats %+v", stats)
+	}
+	if w := stats.workers[0]; w.name != "bitaxe" || w.hashRate != 1050 || w.bestShare != 4294967296 || !w.lastShare.Equal(time.Unix(1718000000, 0)) {
+		t.Errorf("unexpected ckpool worker %+v", w)
+	}
+	if w := stats.workers[1]; w.name != "nerdqaxe" || w.hashRate != 450 {
+		t.Errorf("unexpected ckpool worker %+v", w)
+	}
+
+	stats, err = m.fetch(config.PoolAccountConfig{Pool: "public-pool", Address: testAddress, URL: base + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(stats.hashRate, 5000) || stats.bestShare != 8589934592 || len(stats.workers) != 2 {
+		t.Fatalf("unexpected public-pool stats %+v", stats)
+	}
+	if w := stats.workers[0]; w.name != "bitaxe" || !approx(w.hashRate, 1000) || w.bestShare != 2000 || w.lastShare.Minute() != 5 {
+		t.Errorf("expected the bitaxe's connections to be summed, got %+v", w)
+	}
+
+	if _, err := m.fetch(config.PoolAccountConfig{Pool: "ckpool", Address: "bc1qother", URL: base}); err == nil || !strings.Contains(err.Error(), "unknown account") {
+		t.Errorf("expected an unknown account, got %v", err)
+	}
+}
+
+func TestParseSIHashrate(t *testing.T) {
+	tests := map[string]float64{
+		"1.21T": 1210,
+		"450G":  450,
+		"12.5M": 0.0125,
+		"2P":    2e6,
+		"0":     0,
+		"":      0,
+		"fast":  0,
+	}
+	for s, want := range tests {
+		if got := parseSIHashrate(s); !approx(got, want) {
+			t.Errorf("parseSIHashrate(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	cfg := config.PoolStatsConfig{DivergencePct: 30, DivergentChecks: 2}
+	account := config.PoolAccountConfig{Pool: "ckpool", Address: testAddress, Workers: map[string]string{"rig": "10.0.0.3"}}
+	miners := []*storage.Miner{
+		{IP: "10.0.0.1", Hostname: "BitAxe"},
+		{IP: "10.0.0.2", Hostname: "nerdqaxe-1", MinerDetails: storage.MinerDetails{DisplayName: "Office"}},
+		{IP: "10.0.0.3", Hostname: "avalon-nano3"},
+	}
+	snapshots := map[string]*storage.MinerSnapshot{
+		"10.0.0.1": {HashRate1h: 1000, BestDiff: 5e9},
+		"10.0.0.2": {HashRate1h: 4800},
+		"10.0.0.3": {HashRate1h: 4000},
+	}
+	fetched := func(bitaxe float64) *poolStats {
+		return &poolStats{hashRate: bitaxe + 9000, workers: []workerStats{
+			{name: "bitaxe", hashRate: bitaxe},
+			{name: "office", hashRate: 5000},
+			{name: "rig", hashRate: 4000},
+			{name: "stranger", hashRate: 100},
+		}}
+	}
+
+	s := &Account{}
+	if changed := reconcile(s, account, fetched(600), miners, snapshots, cfg); len(changed) != 0 {
+		t.Errorf("expected one diverging fetch not to be reported, got %v", changed)
+	}
+	if len(s.Workers) != 4 || s.LocalHashRate != 9800 || s.PoolHashRate != 9600 {
+		t.Fatalf("unexpected account %+v", s)
+	}
+	byName := make(map[string]Worker)
+	for _, w := range s.Workers {
+		byName[w.Name] = w
+	}
+	if w := byName["bitaxe"]; w.MinerIP != "10.0.0.1" || w.DivergencePct != -40 || w.Diverging || w.LocalBestShare != 5e9 {
+		t.Errorf("expected the worker matched by hostname, got %+v", w)
+	}
+	if w := byName["office"]; w.MinerIP != "10.0.0.2" || w.Hostname != "Office" {
+		t.Errorf("expected the worker matched by display name, got %+v", w)
+	}
+	if w := byName["rig"]; w.MinerIP != "10.0.0.3" || w.DivergencePct != 0 {
+		t.Errorf("expected the worker matched by the configured name, got %+v", w)
+	}
+	if w := byName["stranger"]; w.MinerIP != "" || w.DivergencePct != 0 {
+		t.Errorf("expected an unmatched worker, got %+v", w)
+	}
+
+	changed := reconcile(s, account, fetched(600), miners, snapshots, cfg)
+	if len(changed) != 1 || !changed[0].worker.Diverging || changed[0].worker.MinerIP != "10.0.0.1" {
+		t.Fatalf("expected the bitaxe to diverge, got %v", changed)
+	}
+	if want := "ckpool credits worker bitaxe with 600.00 GH/s, 40.0% below the miner's 1.00 TH/s, for 2 fetches in a row"; changed[0].message != want {
+		t.Errorf("expected %q, got %q", want, changed[0].message)
+	}
+	if changed := reconcile(s, account, fetched(500), miners, snapshots, cfg); len(changed) != 0 {
+		t.Errorf("expected a diverging worker to be reported once, got %v", changed)
+	}
+
+	// An offline miner's worker keeps its state
+	delete(snapshots, "10.0.0.1")
+	if changed := reconcile(s, account, fetched(0), miners, snapshots, cfg); len(changed) != 0 || !s.Workers[0].Diverging {
+		t.Errorf("expected no change while the miner is offline, got %v", changed)
+	}
+	snapshots["10.0.0.1"] = &storage.MinerSnapshot{HashRate1h: 1000}
+
+	changed = reconcile(s, account, fetched(1100), miners, snapshots, cfg)
+	if len(changed) != 1 || changed[0].worker.Diverging {
+		t.Fatalf("expected the bitaxe to match again, got %v", changed)
+	}
+	if want := "ckpool credits worker bitaxe with 1.10 TH/s again, 10.0% above the miner's 1.00 TH/s"; changed[0].message != want {
+		t.Errorf("expected %q, got %q", want, changed[0].message)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	base := fakePools(t)
+	cfg := config.DefaultConfig()
+	cfg.PoolStats.Enabled = true
+	cfg.PoolStats.DivergentChecks = 1
+	cfg.PoolStats.Accounts = []config.PoolAccountConfig{
+		{Pool: "public-pool", Address: testAddress, URL: base},
+		{Pool: "ckpool", Address: "bc1qother", URL: base},
+	}
+	miners := func() ([]*storage.Miner, error) {
+		return []*storage.Miner{{IP: "10.0.0.1", Hostname: "bitaxe"}}, nil
+	}
+	snapshots := func() map[string]*storage.MinerSnapshot {
+		return map[string]*storage.MinerSnapshot{"10.0.0.1": {HashRate1h: 2000}}
+	}
+	var reported []string
+	m := New(func() *config.Config { return cfg }, miners, snapshots, func(pool string, w Worker, message string) {
+		reported = append(reported, pool+" "+w.MinerIP)
+	})
+
+	m.tick(time.Now())
+	m.wg.Wait()
+	accounts := m.Accounts()
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %+v", accounts)
+	}
+	if a := accounts[0]; a.LastSuccess == nil || a.LastError != "" || len(a.Workers) != 2 || a.Workers[1].DivergencePct != -50 {
+		t.Errorf("unexpected public-pool account %+v", a)
+	}
+	if a := accounts[1]; a.LastSuccess != nil || !strings.Contains(a.LastError, "unknown account") {
+		t.Errorf("expected the ckpool account to fail, got %+v", a)
+	}
+	if len(reported) != 1 || reported[0] != "public-pool 10.0.0.1" {
+		t.Errorf("expected the bitaxe to be reported, got %v", reported)
+	}
+
+	// Not due again before interval_secs
+	m.tick(time.Now())
+	m.wg.Wait()
+	if len(reported) != 1 {
+		t.Errorf("expected no fetch before the interval, got %v", reported)
+	}
+
+	// Removed accounts are dropped
+	cfg.PoolStats.Accounts = cfg.PoolStats.Accounts[:1]
+	m.tick(time.Now())
+	m.wg.Wait()
+	if accounts := m.Accounts(); len(accounts) != 1 {
+		t.Errorf("expected the removed account to be dropped, got %+v", accounts)
+	}
+}
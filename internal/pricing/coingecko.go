@@ -1,7 +1,6 @@
 package pricing
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -21,15 +20,17 @@ type Coin struct {
 	Icon        string  `json:"icon"`        // Icon URL
 	Binance     string  `json:"binance"`     // Binance trading pair (BTCUSDT, etc.) - empty if not on Binance
 	CoinGecko   string  `json:"coingecko"`   // CoinGecko ID for fallback
+	Kraken      string  `json:"kraken"`      // Kraken USD pair - empty if not on Kraken
+	CoinPaprika string  `json:"coinpaprika"` // CoinPaprika ID - empty if not listed
 	BlockReward float64 `json:"blockReward"` // Current block reward (updated from letsmine.it)
 }
 
 // SupportedCoins lists all available coins
 var SupportedCoins = []Coin{
-	{ID: "btc", Name: "Bitcoin", Symbol: "BTC", Icon: "https://assets.coingecko.com/coins/images/1/small/bitcoin.png", Binance: "BTCUSDT", CoinGecko: "bitcoin", BlockReward: 3.125},
-	{ID: "bch", Name: "Bitcoin Cash", Symbol: "BCH", Icon: "https://assets.coingecko.com/coins/images/780/small/bitcoin-cash-circle.png", Binance: "BCHUSDT", CoinGecko: "bitcoin-cash", BlockReward: 3.125},
-	{ID: "dgb", Name: "DigiByte", Symbol: "DGB", Icon: "https://assets.coingecko.com/coins/images/63/small/digibyte.png", Binance: "DGBUSDT", CoinGecko: "digibyte", BlockReward: 274.28},
-	{ID: "xec", Name: "eCash", Symbol: "XEC", Icon: "https://assets.coingecko.com/coins/images/16646/small/Logo_final-22.png", Binance: "XECUSDT", CoinGecko: "ecash", BlockReward: 1812500},
+	{ID: "btc", Name: "Bitcoin", Symbol: "BTC", Icon: "https://assets.coingecko.com/coins/images/1/small/bitcoin.png", Binance: "BTCUSDT", CoinGecko: "bitcoin", Kraken: "XBTUSD", CoinPaprika: "btc-bitcoin", BlockReward: 3.125},
+	{ID: "bch", Name: "Bitcoin Cash", Symbol: "BCH", Icon: "https://assets.coingecko.com/coins/images/780/small/bitcoin-cash-circle.png", Binance: "BCHUSDT", CoinGecko: "bitcoin-cash", Kraken: "BCHUSD", CoinPaprika: "bch-bitcoin-cash", BlockReward: 3.125},
+	{ID: "dgb", Name: "DigiByte", Symbol: "DGB", Icon: "https://assets.coingecko.com/coins/images/63/small/digibyte.png", Binance: "DGBUSDT", CoinGecko: "digibyte", CoinPaprika: "dgb-digibyte", BlockReward: 274.28},
+	{ID: "xec", Name: "eCash", Symbol: "XEC", Icon: "https://assets.coingecko.com/coins/images/16646/small/Logo_final-22.png", Binance: "XECUSDT", CoinGecko: "ecash", Kraken: "XECUSD", CoinPaprika: "xec-ecash", BlockReward: 1812500},
 	{ID: "bc2", Name: "BitcoinII", Symbol: "BC2", Icon: "https://bitcoin-ii.org/logo.png", Binance: "", CoinGecko: "bitcoinii", BlockReward: 50},
 	{ID: "btcs", Name: "Fractal Bitcoin", Symbol: "BTCS", Icon: "https://fractalbitcoin.io/img/logo/fractal.svg", Binance: "", CoinGecko: "fractal-bitcoin", BlockReward: 50},
 }
@@ -38,9 +39,20 @@ var SupportedCoins = []Coin{
 var blockRewards = make(map[string]float64)
 var blockRewardsMu sync.RWMutex
 
-// PriceService fetches and caches coin prices from Binance/CoinGecko
+// PriceService fetches and caches coin prices from the configured providers
 type PriceService struct {
-	client *http.Client
+	client   *http.Client
+	recorder PriceRecorder
+
+	providersMu sync.Mutex
+	providers   []PriceProvider            // In order of priority
+	backoff     map[string]providerBackoff // Rate-limited providers by name
+
+	// Outcome of the latest price fetches, see Health
+	healthMu    sync.Mutex
+	lastSuccess time.Time
+	lastError   string
+	lastErrorAt time.Time
 }
 
 // BinanceResponse represents the Binance API response
@@ -49,13 +61,16 @@ type BinanceResponse struct {
 	Price  string `json:"price"`
 }
 
-// NewPriceService creates a new price service
+// NewPriceService creates a new price service using the default providers
 func NewPriceService() *PriceService {
-	return &PriceService{
+	p := &PriceService{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		backoff: make(map[string]providerBackoff),
 	}
+	p.SetProviders(ProviderConfig{})
+	return p
 }
 
 // GetCoinInfoByID returns info about a specific coin by its ID
@@ -104,27 +119,22 @@ func (p *PriceService) GetPriceForCoin(coinID string) float64 {
 	}
 
 	// Fetch fresh price
-	var fetchedPrice float64
-	var err error
-
-	if coin.Binance != "" {
-		fetchedPrice, err = p.fetchFromBinance(coin.Binance)
-	}
-	if fetchedPrice == 0 && coin.CoinGecko != "" {
-		fetchedPrice, err = p.fetchFromCoinGecko(coin.CoinGecko)
-	}
-
-	if err != nil || fetchedPrice == 0 {
+	fetchedPrice, err := p.fetchPrice(coin)
+	p.recordFetch(err)
+	if err != nil {
 		// Return cached price even if stale
 		return price
 	}
 
 	// Update cache
+	now := time.Now()
 	priceCacheMu.Lock()
 	priceCache[coinID] = fetchedPrice
-	priceCacheTime = time.Now()
+	priceCacheTime = now
 	priceCacheMu.Unlock()
 
+	p.recordPrice(coinID, fetchedPrice, now)
+
 	return fetchedPrice
 }
 
@@ -137,58 +147,6 @@ func (p *PriceService) GetAllCoinPrices() map[string]float64 {
 	return prices
 }
 
-// fetchFromBinance fetches price from Binance API
-func (p *PriceService) fetchFromBinance(symbol string) (float64, error) {
-	url := fmt.Sprintf("https://api.binance.com/api/v3/ticker/price?symbol=%s", symbol)
-
-	resp, err := p.client.Get(url)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch from Binance: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("Binance returned status %d", resp.StatusCode)
-	}
-
-	var data BinanceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, fmt.Errorf("failed to decode Binance response: %w", err)
-	}
-
-	var price float64
-	fmt.Sscanf(data.Price, "%f", &price)
-	return price, nil
-}
-
-// fetchFromCoinGecko fetches price from CoinGecko API
-func (p *PriceService) fetchFromCoinGecko(coinGeckoID string) (float64, error) {
-	url := fmt.Sprintf("https://api.coingecko.com/api/v3/simple/price?ids=%s&vs_currencies=usd", coinGeckoID)
-
-	resp, err := p.client.Get(url)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch from CoinGecko: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("CoinGecko returned status %d", resp.StatusCode)
-	}
-
-	var data map[string]map[string]float64
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, fmt.Errorf("failed to decode CoinGecko response: %w", err)
-	}
-
-	if coinData, ok := data[coinGeckoID]; ok {
-		if price, ok := coinData["usd"]; ok {
-			return price, nil
-		}
-	}
-
-	return 0, fmt.Errorf("price not found in CoinGecko response")
-}
-
 // FetchBlockRewards fetches block rewards from letsmine.it
 func (p *PriceService) FetchBlockRewards() error {
 	resp, err := p.client.Get("https://letsmine.it/solo")
@@ -0,0 +1,158 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// difficultySource is a chain API reporting a coin's network difficulty and
+// block height
+type difficultySource struct {
+	URL  string
+	Path string // Dot-separated path to the difficulty in the JSON response
+
+	// HeightURL is fetched for the block height, or URL when empty.
+	// HeightPath is empty when the response is the bare height.
+	HeightURL  string
+	HeightPath string
+}
+
+// difficultySources lists the chain APIs per coin. Coins without one rely on
+// the difficulty reported by the miners themselves, including DigiByte whose
+// explorers report the difficulty of another of its five algorithms.
+var difficultySources = map[string]difficultySource{
+	"btc":  {URL: "https://mempool.space/api/v1/mining/hashrate/3d", Path: "currentDifficulty", HeightURL: "https://mempool.space/api/blocks/tip/height"},
+	"btcs": {URL: "https://mempool.fractalbitcoin.io/api/v1/mining/hashrate/3d", Path: "currentDifficulty", HeightURL: "https://mempool.fractalbitcoin.io/api/blocks/tip/height"},
+	"bch":  {URL: "https://api.blockchair.com/bitcoin-cash/stats", Path: "data.difficulty", HeightPath: "data.best_block_height"},
+	"xec":  {URL: "https://api.blockchair.com/ecash/stats", Path: "data.difficulty", HeightPath: "data.best_block_height"},
+}
+
+// difficultyTTL is how long a fetched difficulty is reused. Bitcoin retargets
+// every two weeks, but Bitcoin Cash and eCash adjust every block.
+const difficultyTTL = 10 * time.Minute
+
+// NetworkInfo is a coin's network state as reported by its chain API
+type NetworkInfo struct {
+	Difficulty  float64   `json:"difficulty"`
+	BlockHeight int64     `json:"blockHeight"` // 0 if unknown
+	Fetched     time.Time `json:"fetched"`
+}
+
+// difficultyCache stores network info per coin
+var difficultyCache = make(map[string]NetworkInfo)
+var difficultyCacheMu sync.RWMutex
+
+// GetNetworkDifficulty returns the coin's network difficulty from its chain
+// API. ok is false when the coin has no chain API or it can't be reached and
+// nothing was fetched before.
+func (p *PriceService) GetNetworkDifficulty(coinID string) (difficulty float64, ok bool) {
+	info, ok := p.GetNetworkInfo(coinID)
+	return info.Difficulty, ok
+}
+
+// GetNetworkInfo returns the coin's network difficulty and block height from
+// its chain API, with the same ok as GetNetworkDifficulty
+func (p *PriceService) GetNetworkInfo(coinID string) (info NetworkInfo, ok bool) {
+	difficultyCacheMu.RLock()
+	cached, found := difficultyCache[coinID]
+	difficultyCacheMu.RUnlock()
+
+	if found && time.Since(cached.Fetched) < difficultyTTL {
+		return cached, true
+	}
+
+	source, exists := difficultySources[coinID]
+	if !exists {
+		return NetworkInfo{}, false
+	}
+
+	fetched, err := p.fetchNetworkInfo(source)
+	if err != nil || fetched.Difficulty <= 0 {
+		// Return cached info even if stale
+		return cached, found
+	}
+
+	difficultyCacheMu.Lock()
+	difficultyCache[coinID] = fetched
+	difficultyCacheMu.Unlock()
+
+	return fetched, true
+}
+
+// fetchNetworkInfo fetches a network difficulty and block height from a chain
+// API. The height is optional: without it the difficulty is still returned.
+func (p *PriceService) fetchNetworkInfo(source difficultySource) (NetworkInfo, error) {
+	data, err := p.fetchChainJSON(source.URL)
+	if err != nil {
+		return NetworkInfo{}, err
+	}
+	difficulty, err := lookupNumber(data, source.Path)
+	if err != nil {
+		return NetworkInfo{}, err
+	}
+	info := NetworkInfo{Difficulty: difficulty, Fetched: time.Now()}
+
+	if source.HeightURL != "" {
+		if data, err = p.fetchChainJSON(source.HeightURL); err != nil {
+			return info, nil
+		}
+	}
+	if source.HeightURL != "" || source.HeightPath != "" {
+		if height, err := lookupNumber(data, source.HeightPath); err == nil {
+			info.BlockHeight = int64(height)
+		}
+	}
+	return info, nil
+}
+
+// fetchChainJSON fetches and decodes a chain API response
+func (p *PriceService) fetchChainJSON(url string) (interface{}, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("chain API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chain API returned status %d", resp.StatusCode)
+	}
+
+	var data interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode chain API response: %w", err)
+	}
+	return data, nil
+}
+
+// lookupNumber follows a dot-separated path of object keys to a number. An
+// empty path expects the number itself.
+func lookupNumber(data interface{}, path string) (float64, error) {
+	value, err := lookupPath(data, path)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s is not a number in difficulty response", path)
+	}
+	return n, nil
+}
+
+// lookupPath follows a dot-separated path of object keys
+func lookupPath(data interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return data, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s not found in response", path)
+		}
+		data = obj[key]
+	}
+	return data, nil
+}
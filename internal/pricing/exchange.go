@@ -0,0 +1,144 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exchangeRates stores fiat exchange rates as units of currency per 1 USD
+var exchangeRates = map[string]float64{"USD": 1}
+var exchangeRatesMu sync.RWMutex
+var exchangeRatesTime time.Time
+
+// exchangeRateTTL is how long fetched rates are reused. The ECB publishes
+// reference rates once per working day, so refreshing more often is pointless.
+const exchangeRateTTL = 6 * time.Hour
+
+// ExchangeRatesResponse represents the Frankfurter and exchangerate.host
+// "latest" responses, which share the same rates object
+type ExchangeRatesResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// GetExchangeRate returns how many units of currency `to` one unit of `from`
+// buys. ok is false when either currency has no known rate.
+func (p *PriceService) GetExchangeRate(from, to string) (rate float64, ok bool) {
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	if from == to {
+		return 1, true
+	}
+
+	exchangeRatesMu.RLock()
+	fetched := exchangeRatesTime
+	exchangeRatesMu.RUnlock()
+
+	if fetched.IsZero() || time.Since(fetched) >= exchangeRateTTL {
+		// Keep using stale rates if the refresh fails
+		p.refreshExchangeRates()
+	}
+
+	exchangeRatesMu.RLock()
+	fromPerUSD, ok1 := exchangeRates[from]
+	toPerUSD, ok2 := exchangeRates[to]
+	exchangeRatesMu.RUnlock()
+
+	if !ok1 || !ok2 || fromPerUSD == 0 {
+		return 0, false
+	}
+	return toPerUSD / fromPerUSD, true
+}
+
+// Convert converts an amount between fiat currencies. ok is false when no
+// rate is known, in which case the amount is returned unchanged.
+func (p *PriceService) Convert(amount float64, from, to string) (float64, bool) {
+	rate, ok := p.GetExchangeRate(from, to)
+	if !ok {
+		return amount, false
+	}
+	return amount * rate, true
+}
+
+// refreshExchangeRates fetches USD-based rates, trying the ECB reference
+// rates (via Frankfurter) first and exchangerate.host as fallback
+func (p *PriceService) refreshExchangeRates() error {
+	rates, err := p.fetchExchangeRates("https://api.frankfurter.app/latest?from=USD")
+	if err != nil {
+		rates, err = p.fetchExchangeRates("https://api.exchangerate.host/latest?base=USD")
+	}
+	if err != nil {
+		// Retry at the next TTL instead of on every request
+		exchangeRatesMu.Lock()
+		exchangeRatesTime = time.Now()
+		exchangeRatesMu.Unlock()
+		return err
+	}
+
+	setExchangeRates(rates)
+	return nil
+}
+
+// setExchangeRates merges rates (per 1 USD) into the cache
+func setExchangeRates(rates map[string]float64) {
+	exchangeRatesMu.Lock()
+	defer exchangeRatesMu.Unlock()
+	for currency, rate := range rates {
+		if rate > 0 {
+			exchangeRates[strings.ToUpper(currency)] = rate
+		}
+	}
+	exchangeRates["USD"] = 1
+	exchangeRatesTime = time.Now()
+}
+
+// fetchExchangeRates fetches a USD-based rates table from url
+func (p *PriceService) fetchExchangeRates(url string) (map[string]float64, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rate API returned status %d", resp.StatusCode)
+	}
+
+	var data ExchangeRatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange rates: %w", err)
+	}
+	if len(data.Rates) == 0 {
+		return nil, fmt.Errorf("exchange rate response contained no rates")
+	}
+	if data.Base != "" && !strings.EqualFold(data.Base, "USD") {
+		return nil, fmt.Errorf("exchange rates are based on %s, expected USD", data.Base)
+	}
+
+	return data.Rates, nil
+}
+
+// StartExchangeRateUpdater starts a background goroutine that refreshes
+// exchange rates periodically
+func (p *PriceService) StartExchangeRateUpdater(interval time.Duration) {
+	go func() {
+		if err := p.refreshExchangeRates(); err != nil {
+			log.Printf("Initial exchange rate fetch error: %v", err)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := p.refreshExchangeRates(); err != nil {
+				log.Printf("Exchange rate fetch error: %v", err)
+			}
+		}
+	}()
+}
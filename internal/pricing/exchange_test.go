@@ -0,0 +1,31 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExchangeRates(t *testing.T) {
+	setExchangeRates(map[string]float64{"EUR": 0.8, "BRL": 5.0})
+	p := NewPriceService()
+
+	tests := []struct {
+		from, to string
+		want     float64
+	}{
+		{"USD", "USD", 1},
+		{"USD", "EUR", 0.8},
+		{"EUR", "USD", 1.25},
+		{"eur", "brl", 6.25},
+	}
+	for _, tt := range tests {
+		got, ok := p.GetExchangeRate(tt.from, tt.to)
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("GetExchangeRate(%s, %s) = %v, %v; want %v", tt.from, tt.to, got, ok, tt.want)
+		}
+	}
+
+	if amount, ok := p.Convert(10, "EUR", "XYZ"); ok || amount != 10 {
+		t.Errorf("Convert to unknown currency = %v, %v; want 10, false", amount, ok)
+	}
+}
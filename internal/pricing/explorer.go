@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// explorerSource is a block explorer API returning the hash of the block at a
+// height
+type explorerSource struct {
+	URL  string // %d is replaced with the height
+	Path string // Dot-separated path to the hash in the JSON response, %d as in URL. Empty when the response is the bare hash.
+}
+
+// explorerSources lists the block explorers per coin, for the coins whose
+// block heights are known from their chain API
+var explorerSources = map[string]explorerSource{
+	"btc":  {URL: "https://mempool.space/api/block-height/%d"},
+	"btcs": {URL: "https://mempool.fractalbitcoin.io/api/block-height/%d"},
+	"bch":  {URL: "https://api.blockchair.com/bitcoin-cash/dashboards/block/%d", Path: "data.%d.block.hash"},
+	"xec":  {URL: "https://api.blockchair.com/ecash/dashboards/block/%d", Path: "data.%d.block.hash"},
+}
+
+// HasExplorer reports whether blocks of the coin can be looked up on chain
+func HasExplorer(coinID string) bool {
+	_, ok := explorerSources[coinID]
+	return ok
+}
+
+// BlockHashAt returns the hash of the coin's block at a height
+func (p *PriceService) BlockHashAt(coinID string, height int64) (string, error) {
+	source, ok := explorerSources[coinID]
+	if !ok {
+		return "", fmt.Errorf("no block explorer for %s", coinID)
+	}
+
+	if source.Path == "" {
+		resp, err := p.client.Get(fmt.Sprintf(source.URL, height))
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch block %d: %w", height, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("block explorer returned status %d", resp.StatusCode)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if err != nil {
+			return "", fmt.Errorf("failed to read block %d: %w", height, err)
+		}
+		return strings.TrimSpace(string(body)), nil
+	}
+
+	data, err := p.fetchChainJSON(fmt.Sprintf(source.URL, height))
+	if err != nil {
+		return "", err
+	}
+	value, err := lookupPath(data, fmt.Sprintf(source.Path, height))
+	if err != nil {
+		return "", err
+	}
+	hash, ok := value.(string)
+	if !ok || hash == "" {
+		return "", fmt.Errorf("no hash for block %d", height)
+	}
+	return hash, nil
+}
+
+// diff1Target is the target of a difficulty 1 block
+var diff1Target = new(big.Int).Lsh(big.NewInt(0xFFFF), 208)
+
+// HashDifficulty returns the difficulty a block hash meets, as miners report
+// it for their shares, or 0 if the hash isn't valid hex
+func HashDifficulty(hash string) float64 {
+	n, ok := new(big.Int).SetString(hash, 16)
+	if !ok || n.Sign() <= 0 {
+		return 0
+	}
+	diff, _ := new(big.Float).Quo(new(big.Float).SetInt(diff1Target), new(big.Float).SetInt(n)).Float64()
+	return diff
+}
@@ -0,0 +1,53 @@
+package pricing
+
+import "time"
+
+// chainParams are a coin's consensus parameters
+type chainParams struct {
+	BlockTime       time.Duration // Target time between blocks
+	HalvingInterval int64         // Blocks between block reward halvings
+}
+
+// chainParamsByCoin lists the coins whose chain API reports a block height.
+// Bitcoin Cash and eCash kept Bitcoin's schedule; Fractal Bitcoin halves
+// every 2.1 million 30-second blocks, also about every four years.
+var chainParamsByCoin = map[string]chainParams{
+	"btc":  {BlockTime: 10 * time.Minute, HalvingInterval: 210000},
+	"bch":  {BlockTime: 10 * time.Minute, HalvingInterval: 210000},
+	"xec":  {BlockTime: 10 * time.Minute, HalvingInterval: 210000},
+	"btcs": {BlockTime: 30 * time.Second, HalvingInterval: 2100000},
+}
+
+// Halving is the countdown to a coin's next block reward halving
+type Halving struct {
+	Height     int64     `json:"height"`
+	BlocksLeft int64     `json:"blocksLeft"`
+	Estimated  time.Time `json:"estimated"` // At the target block time
+}
+
+// NextHalving returns the next halving after the given block height, reached
+// at, or nil when the coin's schedule or the height is unknown
+func NextHalving(coinID string, height int64, at time.Time) *Halving {
+	params, ok := chainParamsByCoin[coinID]
+	if !ok || height <= 0 {
+		return nil
+	}
+
+	next := (height/params.HalvingInterval + 1) * params.HalvingInterval
+	left := next - height
+	return &Halving{
+		Height:     next,
+		BlocksLeft: left,
+		Estimated:  at.Add(time.Duration(left) * params.BlockTime),
+	}
+}
+
+// NetworkHashrate estimates a coin's network hashrate in GH/s from its
+// difficulty, 0 when the coin's block time is unknown
+func NetworkHashrate(coinID string, difficulty float64) float64 {
+	params, ok := chainParamsByCoin[coinID]
+	if !ok {
+		return 0
+	}
+	return difficulty * hashesPerDifficulty / params.BlockTime.Seconds() / 1e9
+}
@@ -0,0 +1,38 @@
+package pricing
+
+import "time"
+
+// Health reports whether the price APIs answered the last time prices were
+// fetched. Prices are fetched on demand, so nothing is known until then.
+type Health struct {
+	Status      string    `json:"status"` // ok, unreachable or unknown
+	LastSuccess time.Time `json:"lastSuccess,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// recordFetch notes the outcome of a price fetch
+func (p *PriceService) recordFetch(err error) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	if err != nil {
+		p.lastError, p.lastErrorAt = err.Error(), time.Now()
+		return
+	}
+	p.lastSuccess = time.Now()
+}
+
+// Health returns the outcome of the latest price fetches
+func (p *PriceService) Health() Health {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+
+	h := Health{Status: "unknown", LastSuccess: p.lastSuccess, LastError: p.lastError, LastErrorAt: p.lastErrorAt}
+	switch {
+	case !p.lastSuccess.IsZero() && !p.lastSuccess.Before(p.lastErrorAt):
+		h.Status = "ok"
+	case !p.lastErrorAt.IsZero():
+		h.Status = "unreachable"
+	}
+	return h
+}
@@ -0,0 +1,43 @@
+package pricing
+
+import (
+	"log"
+	"time"
+)
+
+// PriceRecorder keeps a history of fetched prices
+type PriceRecorder interface {
+	InsertPrice(coinID string, priceUSD float64, at time.Time) error
+}
+
+// SetRecorder makes every freshly fetched price get recorded. It must be
+// called before prices are fetched.
+func (p *PriceService) SetRecorder(r PriceRecorder) {
+	p.recorder = r
+}
+
+// recordPrice passes a freshly fetched price to the recorder, if any
+func (p *PriceService) recordPrice(coinID string, price float64, at time.Time) {
+	if p.recorder == nil {
+		return
+	}
+	if err := p.recorder.InsertPrice(coinID, price, at); err != nil {
+		log.Printf("Failed to record %s price: %v", coinID, err)
+	}
+}
+
+// StartPriceUpdater starts a background goroutine that refreshes the prices
+// of all supported coins periodically, so the price history has no gaps when
+// nobody looks at the dashboard
+func (p *PriceService) StartPriceUpdater(interval time.Duration) {
+	go func() {
+		p.GetAllCoinPrices()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			p.GetAllCoinPrices()
+		}
+	}()
+}
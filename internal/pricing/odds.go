@@ -0,0 +1,32 @@
+package pricing
+
+import "math"
+
+// hashesPerDifficulty is the expected number of hashes to find a block at
+// difficulty 1
+const hashesPerDifficulty = 1 << 32
+
+// BlockOdds are the chances of a solo miner finding a block
+type BlockOdds struct {
+	BlocksPerDay  float64 `json:"blocksPerDay"`
+	BlocksPerYear float64 `json:"blocksPerYear"`
+	OddsPerDay    float64 `json:"oddsPerDay"`  // Probability of at least one block in a day, 0-1
+	DaysToBlock   float64 `json:"daysToBlock"` // Expected days until a block, 0 without hashrate
+}
+
+// Odds calculates block odds for a hashrate (GH/s) at a network difficulty.
+// Blocks are found as a Poisson process, so the chance of finding at least
+// one in a day is 1 - e^(-expected blocks per day).
+func Odds(hashrateGHs, difficulty float64) BlockOdds {
+	if hashrateGHs <= 0 || difficulty <= 0 {
+		return BlockOdds{}
+	}
+
+	perDay := hashrateGHs * 1e9 * 86400 / (difficulty * hashesPerDifficulty)
+	return BlockOdds{
+		BlocksPerDay:  perDay,
+		BlocksPerYear: perDay * 365.25,
+		OddsPerDay:    -math.Expm1(-perDay),
+		DaysToBlock:   1 / perDay,
+	}
+}
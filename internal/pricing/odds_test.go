@@ -0,0 +1,108 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOdds(t *testing.T) {
+	// 1 TH/s at difficulty 100T: 1e12 * 86400 / (1e14 * 2^32)
+	odds := Odds(1000, 1e14)
+	wantPerDay := 1e12 * 86400 / (1e14 * 4294967296)
+	if math.Abs(odds.BlocksPerDay-wantPerDay) > 1e-15 {
+		t.Errorf("BlocksPerDay = %v, want %v", odds.BlocksPerDay, wantPerDay)
+	}
+	if math.Abs(odds.BlocksPerYear-wantPerDay*365.25) > 1e-12 {
+		t.Errorf("BlocksPerYear = %v, want %v", odds.BlocksPerYear, wantPerDay*365.25)
+	}
+	if math.Abs(odds.OddsPerDay-(1-math.Exp(-wantPerDay))) > 1e-15 {
+		t.Errorf("OddsPerDay = %v, want %v", odds.OddsPerDay, 1-math.Exp(-wantPerDay))
+	}
+	if math.Abs(odds.DaysToBlock*odds.BlocksPerDay-1) > 1e-9 {
+		t.Errorf("DaysToBlock = %v, want %v", odds.DaysToBlock, 1/wantPerDay)
+	}
+
+	// A hashrate far above the network finds a block almost surely
+	if odds := Odds(1e12, 1); odds.OddsPerDay != 1 {
+		t.Errorf("OddsPerDay = %v, want 1", odds.OddsPerDay)
+	}
+	if odds := Odds(0, 1e14); odds != (BlockOdds{}) {
+		t.Errorf("Odds without hashrate = %+v, want zero", odds)
+	}
+}
+
+func TestGetNetworkDifficulty(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/height" {
+			fmt.Fprint(w, "900001")
+			return
+		}
+		calls++
+		fmt.Fprint(w, `{"data": {"blocks": 900000, "difficulty": 126.5e12}}`)
+	}))
+	defer srv.Close()
+
+	difficultySources["test"] = difficultySource{URL: srv.URL, Path: "data.difficulty", HeightPath: "data.blocks"}
+	defer delete(difficultySources, "test")
+	defer delete(difficultyCache, "test")
+
+	p := NewPriceService()
+	for i := 0; i < 2; i++ {
+		if difficulty, ok := p.GetNetworkDifficulty("test"); !ok || difficulty != 126.5e12 {
+			t.Errorf("GetNetworkDifficulty = %v, %v; want 126.5e12, true", difficulty, ok)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the second lookup to be cached, got %d requests", calls)
+	}
+	if info, ok := p.GetNetworkInfo("test"); !ok || info.BlockHeight != 900000 {
+		t.Errorf("GetNetworkInfo = %+v, %v; want height 900000", info, ok)
+	}
+
+	difficultySources["tip"] = difficultySource{URL: srv.URL, Path: "data.difficulty", HeightURL: srv.URL + "/height"}
+	defer delete(difficultySources, "tip")
+	defer delete(difficultyCache, "tip")
+	if info, ok := p.GetNetworkInfo("tip"); !ok || info.BlockHeight != 900001 {
+		t.Errorf("GetNetworkInfo = %+v, %v; want the bare tip height 900001", info, ok)
+	}
+
+	if _, ok := p.GetNetworkDifficulty("dgb"); ok {
+		t.Error("expected no chain difficulty for DigiByte")
+	}
+}
+
+func TestNextHalving(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	h := NextHalving("btc", 939000, at)
+	if h == nil || h.Height != 1050000 || h.BlocksLeft != 111000 {
+		t.Fatalf("expected the 5th halving 111000 blocks away, got %+v", h)
+	}
+	if want := at.Add(111000 * 10 * time.Minute); !h.Estimated.Equal(want) {
+		t.Errorf("Estimated = %v, want %v", h.Estimated, want)
+	}
+	if h := NextHalving("btc", 840000, at); h == nil || h.Height != 1050000 {
+		t.Errorf("expected a halving block to count down to the next one, got %+v", h)
+	}
+	if h := NextHalving("btcs", 1000000, at); h == nil || h.Height != 2100000 {
+		t.Errorf("expected Fractal Bitcoin's own schedule, got %+v", h)
+	}
+	if NextHalving("dgb", 20000000, at) != nil || NextHalving("btc", 0, at) != nil {
+		t.Error("expected no halving without a schedule or a height")
+	}
+}
+
+func TestHashDifficulty(t *testing.T) {
+	genesis := HashDifficulty("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")
+	if math.Abs(genesis-2536.4263) > 0.001 {
+		t.Errorf("HashDifficulty(genesis) = %v, want 2536.4263", genesis)
+	}
+	if HashDifficulty("not hex") != 0 {
+		t.Error("expected 0 for an invalid hash")
+	}
+}
@@ -0,0 +1,316 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Price provider names, see ProviderConfig
+const (
+	ProviderBinance     = "binance"
+	ProviderCoinGecko   = "coingecko"
+	ProviderKraken      = "kraken"
+	ProviderCoinPaprika = "coinpaprika"
+)
+
+// DefaultProviders is the order price providers are tried in by default
+var DefaultProviders = []string{ProviderBinance, ProviderCoinGecko, ProviderKraken, ProviderCoinPaprika}
+
+// PriceProvider fetches coin prices in USD from an exchange or aggregator
+type PriceProvider interface {
+	Name() string
+	// Price returns the coin's USD price, errNotListed if the provider
+	// doesn't list the coin, or a *RateLimitError
+	Price(coin *Coin) (float64, error)
+}
+
+// errNotListed is returned by providers for coins they don't list
+var errNotListed = errors.New("coin not listed")
+
+// RateLimitError is returned by a provider that answered 429 Too Many
+// Requests. RetryAfter is 0 when the provider didn't say how long to wait.
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limit exceeded", e.Provider)
+}
+
+// ProviderConfig selects and configures the price providers
+type ProviderConfig struct {
+	Names           []string // In order of priority, DefaultProviders when empty
+	CoinGeckoAPIKey string
+	CoinGeckoPro    bool // The key is for the paid Pro API rather than a free demo key
+}
+
+const (
+	// minRateLimitBackoff is how long a rate-limited provider is skipped
+	// when it doesn't say, doubling while it stays rate-limited
+	minRateLimitBackoff = time.Minute
+	maxRateLimitBackoff = 30 * time.Minute
+)
+
+// providerBackoff tracks a rate-limited provider
+type providerBackoff struct {
+	until   time.Time
+	strikes int // 429s in a row
+}
+
+// SetProviders replaces the price providers. Unknown names are ignored.
+func (p *PriceService) SetProviders(cfg ProviderConfig) {
+	names := cfg.Names
+	if len(names) == 0 {
+		names = DefaultProviders
+	}
+
+	var providers []PriceProvider
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case ProviderBinance:
+			providers = append(providers, &binanceProvider{client: p.client, baseURL: "https://api.binance.com"})
+		case ProviderCoinGecko:
+			gecko := &coinGeckoProvider{client: p.client, baseURL: "https://api.coingecko.com/api/v3", apiKey: cfg.CoinGeckoAPIKey, keyHeader: "x-cg-demo-api-key"}
+			if cfg.CoinGeckoPro {
+				gecko.baseURL, gecko.keyHeader = "https://pro-api.coingecko.com/api/v3", "x-cg-pro-api-key"
+			}
+			providers = append(providers, gecko)
+		case ProviderKraken:
+			providers = append(providers, &krakenProvider{client: p.client, baseURL: "https://api.kraken.com"})
+		case ProviderCoinPaprika:
+			providers = append(providers, &coinPaprikaProvider{client: p.client, baseURL: "https://api.coinpaprika.com"})
+		}
+	}
+
+	p.providersMu.Lock()
+	p.providers = providers
+	p.providersMu.Unlock()
+}
+
+// fetchPrice asks the providers for a coin's price in order of priority,
+// skipping rate-limited ones, and returns the first price found
+func (p *PriceService) fetchPrice(coin *Coin) (float64, error) {
+	p.providersMu.Lock()
+	providers := p.providers
+	p.providersMu.Unlock()
+
+	var lastErr error
+	for _, provider := range providers {
+		if wait := p.rateLimited(provider.Name()); wait > 0 {
+			lastErr = fmt.Errorf("%s rate limited for another %s", provider.Name(), wait.Round(time.Second))
+			continue
+		}
+
+		price, err := provider.Price(coin)
+		var rateLimit *RateLimitError
+		switch {
+		case errors.Is(err, errNotListed):
+			continue
+		case errors.As(err, &rateLimit):
+			p.backOff(provider.Name(), rateLimit)
+			lastErr = err
+			continue
+		case err != nil:
+			lastErr = err
+			continue
+		case price <= 0:
+			lastErr = fmt.Errorf("%s returned no price for %s", provider.Name(), coin.ID)
+			continue
+		}
+
+		p.providersMu.Lock()
+		delete(p.backoff, provider.Name())
+		p.providersMu.Unlock()
+		return price, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no price provider lists %s", coin.ID)
+	}
+	return 0, lastErr
+}
+
+// rateLimited returns how much longer a provider is skipped
+func (p *PriceService) rateLimited(name string) time.Duration {
+	p.providersMu.Lock()
+	defer p.providersMu.Unlock()
+	if b, ok := p.backoff[name]; ok {
+		if wait := time.Until(b.until); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// backOff skips a rate-limited provider for as long as it asked, or for a
+// doubling interval while it keeps answering 429
+func (p *PriceService) backOff(name string, e *RateLimitError) {
+	p.providersMu.Lock()
+	defer p.providersMu.Unlock()
+
+	b := p.backoff[name]
+	wait := e.RetryAfter
+	if wait <= 0 {
+		wait = minRateLimitBackoff << b.strikes
+		if wait > maxRateLimitBackoff || wait <= 0 {
+			wait = maxRateLimitBackoff
+		}
+	}
+	b.strikes++
+	b.until = time.Now().Add(wait)
+	p.backoff[name] = b
+	log.Printf("%s price API rate limit exceeded, skipping it for %s", e.Provider, wait)
+}
+
+// getJSON fetches and decodes a price API response. A 429 returns a
+// *RateLimitError honoring the Retry-After header.
+func getJSON(client *http.Client, provider, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch from %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		e := &RateLimitError{Provider: provider}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		}
+		return e
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", provider, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", provider, err)
+	}
+	return nil
+}
+
+// binanceProvider reads Binance's last trade price against USDT
+type binanceProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (b *binanceProvider) Name() string { return ProviderBinance }
+
+func (b *binanceProvider) Price(coin *Coin) (float64, error) {
+	if coin.Binance == "" {
+		return 0, errNotListed
+	}
+
+	var data BinanceResponse
+	if err := getJSON(b.client, "Binance", b.baseURL+"/api/v3/ticker/price?symbol="+coin.Binance, nil, &data); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(data.Price, 64)
+}
+
+// coinGeckoProvider reads CoinGecko's aggregated price, with a free demo or
+// a paid Pro API key if configured
+type coinGeckoProvider struct {
+	client    *http.Client
+	baseURL   string
+	apiKey    string
+	keyHeader string
+}
+
+func (g *coinGeckoProvider) Name() string { return ProviderCoinGecko }
+
+func (g *coinGeckoProvider) Price(coin *Coin) (float64, error) {
+	if coin.CoinGecko == "" {
+		return 0, errNotListed
+	}
+
+	var header http.Header
+	if g.apiKey != "" {
+		header = http.Header{}
+		header.Set(g.keyHeader, g.apiKey)
+	}
+	var data map[string]map[string]float64
+	if err := getJSON(g.client, "CoinGecko", g.baseURL+"/simple/price?vs_currencies=usd&ids="+coin.CoinGecko, header, &data); err != nil {
+		return 0, err
+	}
+	if price, ok := data[coin.CoinGecko]["usd"]; ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("price not found in CoinGecko response")
+}
+
+// krakenProvider reads Kraken's last trade price against USD
+type krakenProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (k *krakenProvider) Name() string { return ProviderKraken }
+
+func (k *krakenProvider) Price(coin *Coin) (float64, error) {
+	if coin.Kraken == "" {
+		return 0, errNotListed
+	}
+
+	var data struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			Last []string `json:"c"` // Price and volume of the last trade
+		} `json:"result"`
+	}
+	if err := getJSON(k.client, "Kraken", k.baseURL+"/0/public/Ticker?pair="+coin.Kraken, nil, &data); err != nil {
+		return 0, err
+	}
+	if len(data.Error) > 0 {
+		return 0, fmt.Errorf("Kraken returned %s", strings.Join(data.Error, ", "))
+	}
+	// The result is keyed by Kraken's own name for the pair
+	for _, ticker := range data.Result {
+		if len(ticker.Last) > 0 {
+			return strconv.ParseFloat(ticker.Last[0], 64)
+		}
+	}
+	return 0, fmt.Errorf("price not found in Kraken response")
+}
+
+// coinPaprikaProvider reads CoinPaprika's aggregated price
+type coinPaprikaProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (c *coinPaprikaProvider) Name() string { return ProviderCoinPaprika }
+
+func (c *coinPaprikaProvider) Price(coin *Coin) (float64, error) {
+	if coin.CoinPaprika == "" {
+		return 0, errNotListed
+	}
+
+	var data struct {
+		Quotes map[string]struct {
+			Price float64 `json:"price"`
+		} `json:"quotes"`
+	}
+	if err := getJSON(c.client, "CoinPaprika", c.baseURL+"/v1/tickers/"+coin.CoinPaprika, nil, &data); err != nil {
+		return 0, err
+	}
+	if quote, ok := data.Quotes["USD"]; ok {
+		return quote.Price, nil
+	}
+	return 0, fmt.Errorf("price not found in CoinPaprika response")
+}
@@ -0,0 +1,109 @@
+package pricing
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	name  string
+	price float64
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Price(coin *Coin) (float64, error) {
+	f.calls++
+	return f.price, f.err
+}
+
+func TestFetchPrice(t *testing.T) {
+	coin := &Coin{ID: "btc"}
+	limited := &fakeProvider{name: "limited", err: &RateLimitError{Provider: "Limited", RetryAfter: time.Hour}}
+	unlisted := &fakeProvider{name: "unlisted", err: errNotListed}
+	fallback := &fakeProvider{name: "fallback", price: 65000}
+
+	p := NewPriceService()
+	p.providers = []PriceProvider{limited, unlisted, fallback}
+
+	for i := 0; i < 2; i++ {
+		if price, err := p.fetchPrice(coin); err != nil || price != 65000 {
+			t.Fatalf("fetchPrice = %v, %v; want the fallback's price", price, err)
+		}
+	}
+	if limited.calls != 1 {
+		t.Errorf("expected the rate-limited provider to be skipped, called %d times", limited.calls)
+	}
+	if wait := p.rateLimited("limited"); wait < 59*time.Minute {
+		t.Errorf("expected Retry-After to be honored, waiting %s", wait)
+	}
+
+	p.providers = []PriceProvider{limited, unlisted}
+	if price, err := p.fetchPrice(coin); err == nil || price != 0 {
+		t.Errorf("expected an error when every provider fails, got %v", price)
+	}
+}
+
+func TestRateLimitBackoff(t *testing.T) {
+	p := NewPriceService()
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		p.backOff("coingecko", &RateLimitError{Provider: "CoinGecko"})
+		if wait := p.rateLimited("coingecko"); wait > want || wait < want-time.Second {
+			t.Errorf("429 #%d: waiting %s, want %s", i+1, wait, want)
+		}
+	}
+}
+
+func TestProviders(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		switch r.URL.Path {
+		case "/api/v3/ticker/price":
+			fmt.Fprint(w, `{"symbol": "BTCUSDT", "price": "65000.50"}`)
+		case "/simple/price":
+			fmt.Fprint(w, `{"bitcoin": {"usd": 65001}}`)
+		case "/0/public/Ticker":
+			fmt.Fprint(w, `{"error": [], "result": {"XXBTZUSD": {"c": ["65002.10", "0.01"]}}}`)
+		case "/v1/tickers/btc-bitcoin":
+			fmt.Fprint(w, `{"quotes": {"USD": {"price": 65003}}}`)
+		default:
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	coin := &Coin{ID: "btc", Binance: "BTCUSDT", CoinGecko: "bitcoin", Kraken: "XBTUSD", CoinPaprika: "btc-bitcoin"}
+	gecko := &coinGeckoProvider{client: srv.Client(), baseURL: srv.URL, apiKey: "CG-key", keyHeader: "x-cg-pro-api-key"}
+	for _, tc := range []struct {
+		provider PriceProvider
+		want     float64
+	}{
+		{&binanceProvider{client: srv.Client(), baseURL: srv.URL}, 65000.50},
+		{gecko, 65001},
+		{&krakenProvider{client: srv.Client(), baseURL: srv.URL}, 65002.10},
+		{&coinPaprikaProvider{client: srv.Client(), baseURL: srv.URL}, 65003},
+	} {
+		if price, err := tc.provider.Price(coin); err != nil || price != tc.want {
+			t.Errorf("%s: Price = %v, %v; want %v", tc.provider.Name(), price, err, tc.want)
+		}
+	}
+
+	if _, err := gecko.Price(coin); err != nil || header.Get("x-cg-pro-api-key") != "CG-key" {
+		t.Errorf("expected the CoinGecko API key to be sent, got headers %v (%v)", header, err)
+	}
+
+	_, err := (&coinPaprikaProvider{client: srv.Client(), baseURL: srv.URL}).Price(&Coin{CoinPaprika: "unknown"})
+	if e, ok := err.(*RateLimitError); !ok || e.RetryAfter != 2*time.Minute {
+		t.Errorf("expected a rate limit error waiting 2m, got %v", err)
+	}
+	if _, err := (&krakenProvider{client: srv.Client(), baseURL: srv.URL}).Price(&Coin{ID: "dgb"}); err != errNotListed {
+		t.Errorf("expected unlisted coins to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package retention
+
+import (
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// Retention defaults for settings left at zero
+const (
+	defaultMetricsDays = 30
+	defaultSharesDays  = 7
+	defaultAlertsDays  = 90
+	defaultLogDays     = 3
+	defaultAuditDays   = 365
+)
+
+// Policies returns MinerHQ's retention policies. beforeSnapshotPurge runs
+// ahead of every snapshot purge, to roll up and export the snapshots first.
+func Policies(beforeSnapshotPurge func(cutoff time.Time)) []Policy {
+	return []Policy{
+		{
+			// Only the last hour is kept for real-time display; history
+			// comes from the rollups
+			Name:    "snapshots",
+			Tables:  []string{"miner_snapshots"},
+			Every:   time.Hour,
+			AtStart: true,
+			Cutoff: func(cfg *config.Config, now time.Time) time.Time {
+				return now.Add(-time.Hour)
+			},
+			Before: beforeSnapshotPurge,
+		},
+		{
+			Name:   "metrics",
+			Tables: []string{"snapshots_hourly", "pool_difficulty_changes", "failed_deliveries"},
+			Every:  24 * time.Hour,
+			Cutoff: func(cfg *config.Config, now time.Time) time.Time {
+				return daysBefore(now, cfg.Retention.MetricsRetentionDays, defaultMetricsDays)
+			},
+			Full: true,
+		},
+		{
+			Name:   "shares",
+			Tables: []string{"shares"},
+			Every:  24 * time.Hour,
+			Cutoff: sharesCutoff,
+			Full:   true,
+		},
+		{
+			Name:   "alerts",
+			Tables: []string{"alerts"},
+			Every:  24 * time.Hour,
+			Cutoff: func(cfg *config.Config, now time.Time) time.Time {
+				return daysBefore(now, cfg.Retention.AlertsRetentionDays, defaultAlertsDays)
+			},
+			Full: true,
+		},
+		{
+			Name:   "audit",
+			Tables: []string{"audit_log"},
+			Every:  24 * time.Hour,
+			Cutoff: func(cfg *config.Config, now time.Time) time.Time {
+				return daysBefore(now, cfg.Retention.AuditRetentionDays, defaultAuditDays)
+			},
+			Full: true,
+		},
+		{
+			Name:   "miner_logs",
+			Tables: []string{"miner_logs"},
+			Every:  24 * time.Hour,
+			Cutoff: func(cfg *config.Config, now time.Time) time.Time {
+				return daysBefore(now, cfg.MinerLogs.RetentionDays, defaultLogDays)
+			},
+			Full: true,
+		},
+	}
+}
+
+// sharesCutoff keeps shares for the configured number of days, but never
+// purges the current or previous competition period, whose standings are
+// ranked from them
+func sharesCutoff(cfg *config.Config, now time.Time) time.Time {
+	cutoff := daysBefore(now, cfg.Retention.SharesRetentionDays, defaultSharesDays)
+	if previous := cfg.Competition.CompetitionPeriod().Previous(now); previous.Before(cutoff) {
+		return previous
+	}
+	return cutoff
+}
+
+// daysBefore returns the time the given number of days before now, or the
+// default number if it isn't set
+func daysBefore(now time.Time, days, defaultDays int) time.Time {
+	if days <= 0 {
+		days = defaultDays
+	}
+	return now.AddDate(0, 0, -days)
+}
@@ -0,0 +1,229 @@
+// Package retention purges each kind of stored data on its own schedule,
+// after the retention set for it in the config.
+package retention
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// checkInterval is how often the manager looks for policies that are due
+const checkInterval = time.Minute
+
+// Store is the storage the policies purge
+type Store interface {
+	PurgeTable(table string, cutoff time.Time) (int64, error)
+	EstimatePurge(cutoff time.Time, tables ...string) ([]storage.PurgeEstimate, error)
+}
+
+// Policy is how long one kind of data is kept
+type Policy struct {
+	Name    string
+	Tables  []string
+	Every   time.Duration // How often the purge runs
+	AtStart bool          // Run once right away instead of after Every
+
+	// Cutoff returns the time before which rows are purged
+	Cutoff func(cfg *config.Config, now time.Time) time.Time
+
+	// Before runs ahead of every purge, also in dry-run mode, e.g. to roll
+	// snapshots up before they are deleted. Optional.
+	Before func(cutoff time.Time)
+
+	// Full allows a full VACUUM after the purge; otherwise a full vacuum
+	// strategy only truncates the WAL
+	Full bool
+}
+
+// Status reports the schedule and last run of a policy
+type Status struct {
+	Name      string     `json:"name"`
+	Tables    []string   `json:"tables"`
+	Cutoff    time.Time  `json:"cutoff"` // Rows older than this are purged, with the current settings
+	EverySecs int64      `json:"everySecs"`
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	NextRun   time.Time  `json:"nextRun"`
+	Deleted   int64      `json:"deleted"` // Rows deleted by the last run, or that it would have deleted in dry-run mode
+	DryRun    bool       `json:"dryRun"`  // The last run only estimated
+	Error     string     `json:"error,omitempty"`
+}
+
+type policyState struct {
+	next    time.Time
+	lastRun time.Time
+	deleted int64
+	dryRun  bool
+	err     string
+}
+
+// Manager runs the retention policies on their schedules. The settings are
+// read on every run, so changed retention periods apply on the next one;
+// Retune runs every policy right away.
+type Manager struct {
+	store    Store
+	settings func() *config.Config
+	compact  func(allowFull bool) // Reclaims the space freed by purges
+	policies []Policy
+
+	mu     sync.Mutex
+	state  map[string]*policyState
+	retune chan struct{}
+}
+
+// NewManager creates a manager for the given policies. compact is called
+// after every round of purges that deleted rows.
+func NewManager(store Store, settings func() *config.Config, compact func(allowFull bool), policies []Policy) *Manager {
+	m := &Manager{
+		store:    store,
+		settings: settings,
+		compact:  compact,
+		policies: policies,
+		state:    make(map[string]*policyState, len(policies)),
+		retune:   make(chan struct{}, 1),
+	}
+	now := time.Now()
+	for _, p := range policies {
+		next := now.Add(p.Every)
+		if p.AtStart {
+			next = now
+		}
+		m.state[p.Name] = &policyState{next: next}
+	}
+	return m
+}
+
+// Start runs the policies in the background as they fall due
+func (m *Manager) Start() {
+	go func() {
+		m.runDue(time.Now())
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-m.retune:
+			}
+			m.runDue(time.Now())
+		}
+	}()
+}
+
+// Retune runs every policy right away with the current settings, e.g. after
+// a retention period was shortened
+func (m *Manager) Retune() {
+	now := time.Now()
+	m.mu.Lock()
+	for _, st := range m.state {
+		st.next = now
+	}
+	m.mu.Unlock()
+
+	select {
+	case m.retune <- struct{}{}:
+	default:
+	}
+}
+
+// Status reports every policy's cutoff with the current settings, its
+// schedule and the outcome of its last run
+func (m *Manager) Status(now time.Time) []Status {
+	cfg := m.settings()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.policies))
+	for _, p := range m.policies {
+		st := m.state[p.Name]
+		s := Status{
+			Name:      p.Name,
+			Tables:    p.Tables,
+			Cutoff:    p.Cutoff(cfg, now),
+			EverySecs: int64(p.Every.Seconds()),
+			NextRun:   st.next,
+			Deleted:   st.deleted,
+			DryRun:    st.dryRun,
+			Error:     st.err,
+		}
+		if !st.lastRun.IsZero() {
+			lastRun := st.lastRun
+			s.LastRun = &lastRun
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// runDue runs the policies that are due, then reclaims the space they freed
+func (m *Manager) runDue(now time.Time) {
+	cfg := m.settings()
+	var purged, full bool
+	for _, p := range m.policies {
+		m.mu.Lock()
+		st := m.state[p.Name]
+		due := !now.Before(st.next)
+		if due {
+			st.next = now.Add(p.Every)
+		}
+		m.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		deleted, err := m.run(p, cfg, now)
+		m.mu.Lock()
+		st.lastRun, st.deleted, st.dryRun, st.err = now, deleted, cfg.Retention.DryRun, ""
+		if err != nil {
+			st.err = err.Error()
+		}
+		m.mu.Unlock()
+
+		if deleted > 0 && !cfg.Retention.DryRun {
+			purged = true
+			full = full || p.Full
+		}
+	}
+	if purged && m.compact != nil {
+		m.compact(full)
+	}
+}
+
+// run purges a policy's tables, or estimates the purge in dry-run mode, and
+// returns how many rows it deleted or would delete
+func (m *Manager) run(p Policy, cfg *config.Config, now time.Time) (int64, error) {
+	cutoff := p.Cutoff(cfg, now)
+	if p.Before != nil {
+		p.Before(cutoff)
+	}
+
+	if cfg.Retention.DryRun {
+		estimates, err := m.store.EstimatePurge(cutoff, p.Tables...)
+		if err != nil {
+			log.Printf("Retention %s preview error: %v", p.Name, err)
+			return 0, err
+		}
+		var rows int64
+		for _, e := range estimates {
+			log.Printf("Retention %s [dry run]: would delete %d rows from %s (~%d bytes)", p.Name, e.Rows, e.Table, e.Bytes)
+			rows += e.Rows
+		}
+		return rows, nil
+	}
+
+	var deleted int64
+	for _, table := range p.Tables {
+		n, err := m.store.PurgeTable(table, cutoff)
+		if err != nil {
+			log.Printf("Retention %s purge error: %v", p.Name, err)
+			return deleted, err
+		}
+		deleted += n
+	}
+	if deleted > 0 {
+		log.Printf("Retention %s: deleted %d rows older than %s", p.Name, deleted, cutoff.Format("2006-01-02 15:04"))
+	}
+	return deleted, nil
+}
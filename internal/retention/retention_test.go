@@ -0,0 +1,168 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+type fakeStore struct {
+	rows   map[string]int64 // Rows purged per table
+	purged []string
+}
+
+func (f *fakeStore) PurgeTable(table string, cutoff time.Time) (int64, error) {
+	f.purged = append(f.purged, table)
+	n := f.rows[table]
+	f.rows[table] = 0
+	return n, nil
+}
+
+func (f *fakeStore) EstimatePurge(cutoff time.Time, tables ...string) ([]storage.PurgeEstimate, error) {
+	var estimates []storage.PurgeEstimate
+	for _, table := range tables {
+		estimates = append(estimates, storage.PurgeEstimate{Table: table, Rows: f.rows[table]})
+	}
+	return estimates, nil
+}
+
+func testPolicies() []Policy {
+	return []Policy{
+		{
+			Name:    "hourly",
+			Tables:  []string{"a"},
+			Every:   time.Hour,
+			AtStart: true,
+			Cutoff:  func(cfg *config.Config, now time.Time) time.Time { return now.Add(-time.Hour) },
+		},
+		{
+			Name:   "daily",
+			Tables: []string{"b", "c"},
+			Every:  24 * time.Hour,
+			Cutoff: func(cfg *config.Config, now time.Time) time.Time { return now.AddDate(0, 0, -7) },
+			Full:   true,
+		},
+	}
+}
+
+func TestManagerRunDue(t *testing.T) {
+	store := &fakeStore{rows: map[string]int64{"a": 5, "b": 2, "c": 1}}
+	cfg := &config.Config{}
+	var compacts []bool
+	m := NewManager(store, func() *config.Config { return cfg }, func(allowFull bool) {
+		compacts = append(compacts, allowFull)
+	}, testPolicies())
+
+	now := time.Now()
+	m.runDue(now)
+	if len(store.purged) != 1 || store.purged[0] != "a" {
+		t.Fatalf("expected only the policy run at start purged, got %v", store.purged)
+	}
+	if len(compacts) != 1 || compacts[0] {
+		t.Fatalf("expected one compaction without a full vacuum, got %v", compacts)
+	}
+
+	m.runDue(now.Add(25 * time.Hour))
+	if len(store.purged) != 4 {
+		t.Fatalf("expected both policies purged once due, got %v", store.purged)
+	}
+	if len(compacts) != 2 || !compacts[1] {
+		t.Fatalf("expected a full vacuum allowed after the daily purge, got %v", compacts)
+	}
+
+	// Nothing deleted, nothing to compact
+	m.runDue(now.Add(26 * time.Hour))
+	if len(compacts) != 2 {
+		t.Fatalf("expected no compaction when nothing was deleted, got %v", compacts)
+	}
+
+	statuses := m.Status(now.Add(26 * time.Hour))
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 statuses, got %d", len(statuses))
+	}
+	daily := statuses[1]
+	if daily.LastRun == nil || daily.Deleted != 3 || daily.DryRun || daily.EverySecs != 86400 {
+		t.Errorf("unexpected daily status %+v", daily)
+	}
+	if want := now.Add(26*time.Hour).AddDate(0, 0, -7); !daily.Cutoff.Equal(want) {
+		t.Errorf("expected cutoff %v, got %v", want, daily.Cutoff)
+	}
+}
+
+func TestManagerDryRun(t *testing.T) {
+	store := &fakeStore{rows: map[string]int64{"a": 5}}
+	cfg := &config.Config{}
+	cfg.Retention.DryRun = true
+	compacted := false
+	var before time.Time
+	policies := testPolicies()
+	policies[0].Before = func(cutoff time.Time) { before = cutoff }
+	m := NewManager(store, func() *config.Config { return cfg }, func(bool) { compacted = true }, policies)
+
+	now := time.Now()
+	m.runDue(now)
+	if len(store.purged) != 0 || compacted {
+		t.Fatalf("expected nothing purged or compacted in dry-run mode, got %v", store.purged)
+	}
+	if !before.Equal(now.Add(-time.Hour)) {
+		t.Errorf("expected Before called with the cutoff, got %v", before)
+	}
+	if st := m.Status(now)[0]; st.Deleted != 5 || !st.DryRun {
+		t.Errorf("expected the estimate reported, got %+v", st)
+	}
+}
+
+func TestManagerRetune(t *testing.T) {
+	store := &fakeStore{rows: map[string]int64{}}
+	cfg := &config.Config{}
+	m := NewManager(store, func() *config.Config { return cfg }, nil, testPolicies())
+
+	m.Retune()
+	m.runDue(time.Now())
+	if len(store.purged) != 3 {
+		t.Fatalf("expected every policy run after Retune, got %v", store.purged)
+	}
+}
+
+func TestSharesCutoff(t *testing.T) {
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC) // Wednesday
+	cfg := &config.Config{}
+	cfg.Competition.Period = "weekly"
+	cfg.Competition.ResetDay = "sun"
+	cfg.Competition.Timezone = "UTC"
+
+	// 7 days would reach into the previous week, which is kept
+	previous := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	if got := sharesCutoff(cfg, now); !got.Equal(previous) {
+		t.Errorf("expected the previous period start %v, got %v", previous, got)
+	}
+
+	cfg.Retention.SharesRetentionDays = 30
+	if got, want := sharesCutoff(cfg, now), now.AddDate(0, 0, -30); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAuditPolicy(t *testing.T) {
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+	cfg := &config.Config{}
+	for _, p := range Policies(nil) {
+		if p.Name != "audit" {
+			continue
+		}
+		if len(p.Tables) != 1 || p.Tables[0] != "audit_log" {
+			t.Fatalf("expected the audit policy to purge audit_log, got %v", p.Tables)
+		}
+		if got, want := p.Cutoff(cfg, now), now.AddDate(-1, 0, 0); !got.Equal(want) {
+			t.Errorf("expected a year kept by default, got %v", got)
+		}
+		cfg.Retention.AuditRetentionDays = 30
+		if got, want := p.Cutoff(cfg, now), now.AddDate(0, 0, -30); !got.Equal(want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		return
+	}
+	t.Fatal("expected an audit log retention policy")
+}
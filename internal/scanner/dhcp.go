@@ -0,0 +1,306 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// Lease is an active DHCP lease read from the router
+type Lease struct {
+	IP       string `json:"ip"`
+	MAC      string `json:"mac"`
+	Hostname string `json:"hostname"`
+}
+
+// Hostnames NerdQAxe and AxeOS/Zyber firmware use out of the box
+var defaultHostnamePrefixes = []string{
+	"nerdqaxe",
+	"nerdaxe",
+	"nerdoctaxe",
+	"bitaxe",
+	"zyber",
+	"avalon",
+	"lucky",
+}
+
+// Espressif OUIs; the AxeOS miners and the Lucky Miner run on an ESP32
+var defaultMACPrefixes = []string{
+	"24:0A:C4", "24:6F:28", "30:AE:A4", "34:85:18", "3C:71:BF",
+	"48:27:E2", "68:B6:B3", "7C:DF:A1", "84:F7:03", "A0:76:4E",
+	"DC:54:75", "EC:DA:3B", "F0:9E:9E", "F4:12:FA",
+}
+
+// ubusNullSession is the session ID OpenWrt expects for the login call
+const ubusNullSession = "00000000000000000000000000000000"
+
+// LeaseReader reads DHCP leases from a router API or a dnsmasq lease file
+type LeaseReader struct {
+	cfg    config.DHCPConfig
+	client *http.Client
+}
+
+// NewLeaseReader creates a LeaseReader for the configured source
+func NewLeaseReader(cfg config.DHCPConfig) *LeaseReader {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &LeaseReader{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// Leases returns the active IPv4 leases
+func (l *LeaseReader) Leases(ctx context.Context) ([]Lease, error) {
+	switch l.cfg.Source {
+	case "opnsense":
+		return l.opnsenseLeases(ctx)
+	case "pfsense":
+		return l.pfsenseLeases(ctx)
+	case "openwrt":
+		return l.openwrtLeases(ctx)
+	case "dnsmasq":
+		return readDnsmasqLeases(l.cfg.LeaseFile)
+	}
+	return nil, fmt.Errorf("unknown DHCP lease source %q", l.cfg.Source)
+}
+
+// Candidates returns the addresses of leases whose hostname or MAC address
+// matches the configured (or default) prefixes, i.e. the ones worth probing
+func (l *LeaseReader) Candidates(leases []Lease) []string {
+	hostnames := l.cfg.HostnamePrefixes
+	if len(hostnames) == 0 {
+		hostnames = defaultHostnamePrefixes
+	}
+	macs := l.cfg.MACPrefixes
+	if len(macs) == 0 {
+		macs = defaultMACPrefixes
+	}
+
+	seen := make(map[string]bool)
+	var ips []string
+	for _, lease := range leases {
+		if seen[lease.IP] || net.ParseIP(lease.IP).To4() == nil {
+			continue
+		}
+		if hasPrefix(strings.ToLower(lease.Hostname), hostnames, strings.ToLower) || hasPrefix(normalizeMAC(lease.MAC), macs, normalizeMAC) {
+			seen[lease.IP] = true
+			ips = append(ips, lease.IP)
+		}
+	}
+	return ips
+}
+
+// hasPrefix reports whether s starts with any of the prefixes after normalize
+func hasPrefix(s string, prefixes []string, normalize func(string) string) bool {
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(s, normalize(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMAC writes MAC addresses (and prefixes) as AA:BB:CC
+func normalizeMAC(mac string) string {
+	return strings.ToUpper(strings.ReplaceAll(mac, "-", ":"))
+}
+
+// getJSON fetches a router API endpoint into v
+func (l *LeaseReader) getJSON(ctx context.Context, path string, v interface{}, auth func(*http.Request)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(l.cfg.URL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	auth(req)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// opnsenseLeases reads leases from OPNsense's ISC DHCP API, falling back to
+// the Kea API used by newer releases
+func (l *LeaseReader) opnsenseLeases(ctx context.Context) ([]Lease, error) {
+	auth := func(req *http.Request) { req.SetBasicAuth(l.cfg.Username, l.cfg.Password) }
+
+	var lastErr error
+	for _, path := range []string{"/api/dhcpv4/leases/searchLease", "/api/kea/leases4/search"} {
+		var body struct {
+			Rows []struct {
+				Address  string `json:"address"`
+				MAC      string `json:"mac"`
+				HWAddr   string `json:"hwaddr"`
+				Hostname string `json:"hostname"`
+				State    string `json:"state"`
+			} `json:"rows"`
+		}
+		if err := l.getJSON(ctx, path, &body, auth); err != nil {
+			lastErr = err
+			continue
+		}
+
+		leases := make([]Lease, 0, len(body.Rows))
+		for _, row := range body.Rows {
+			if row.State != "" && row.State != "active" {
+				continue
+			}
+			mac := row.MAC
+			if mac == "" {
+				mac = row.HWAddr
+			}
+			leases = append(leases, Lease{IP: row.Address, MAC: mac, Hostname: row.Hostname})
+		}
+		return leases, nil
+	}
+	return nil, fmt.Errorf("opnsense: %w", lastErr)
+}
+
+// pfsenseLeases reads leases from the pfSense REST API package (v2)
+func (l *LeaseReader) pfsenseLeases(ctx context.Context) ([]Lease, error) {
+	var body struct {
+		Data []struct {
+			IP           string `json:"ip"`
+			MAC          string `json:"mac"`
+			Hostname     string `json:"hostname"`
+			ActiveStatus string `json:"active_status"`
+		} `json:"data"`
+	}
+	auth := func(req *http.Request) { req.Header.Set("X-API-Key", l.cfg.Password) }
+	if err := l.getJSON(ctx, "/api/v2/status/dhcp_server/leases", &body, auth); err != nil {
+		return nil, fmt.Errorf("pfsense: %w", err)
+	}
+
+	leases := make([]Lease, 0, len(body.Data))
+	for _, row := range body.Data {
+		if row.ActiveStatus != "" && row.ActiveStatus != "active" {
+			continue
+		}
+		leases = append(leases, Lease{IP: row.IP, MAC: row.MAC, Hostname: row.Hostname})
+	}
+	return leases, nil
+}
+
+// ubusCall makes a JSON-RPC call to OpenWrt's ubus HTTP endpoint and decodes
+// the data of a successful reply into v
+func (l *LeaseReader) ubusCall(ctx context.Context, session, object, method string, args, v interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "call",
+		"params":  []interface{}{session, object, method, args},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(l.cfg.URL, "/")+"/ubus", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Result []json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("%s.%s: %w", object, method, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("%s.%s: %s", object, method, reply.Error.Message)
+	}
+
+	// Result is [status] or [status, data]; 0 is success
+	var status int
+	if len(reply.Result) == 0 || json.Unmarshal(reply.Result[0], &status) != nil {
+		return fmt.Errorf("%s.%s: malformed reply", object, method)
+	}
+	if status != 0 {
+		return fmt.Errorf("%s.%s: ubus status %d", object, method, status)
+	}
+	if len(reply.Result) < 2 {
+		return nil
+	}
+	return json.Unmarshal(reply.Result[1], v)
+}
+
+// openwrtLeases logs in to ubus and reads leases through luci-rpc
+func (l *LeaseReader) openwrtLeases(ctx context.Context) ([]Lease, error) {
+	var login struct {
+		Session string `json:"ubus_rpc_session"`
+	}
+	creds := map[string]string{"username": l.cfg.Username, "password": l.cfg.Password}
+	if err := l.ubusCall(ctx, ubusNullSession, "session", "login", creds, &login); err != nil {
+		return nil, fmt.Errorf("openwrt: %w", err)
+	}
+
+	var body struct {
+		Leases []struct {
+			IPAddr   string `json:"ipaddr"`
+			MACAddr  string `json:"macaddr"`
+			Hostname string `json:"hostname"`
+		} `json:"dhcp_leases"`
+	}
+	if err := l.ubusCall(ctx, login.Session, "luci-rpc", "getDHCPLeases", map[string]string{}, &body); err != nil {
+		return nil, fmt.Errorf("openwrt: %w", err)
+	}
+
+	leases := make([]Lease, 0, len(body.Leases))
+	for _, row := range body.Leases {
+		leases = append(leases, Lease{IP: row.IPAddr, MAC: row.MACAddr, Hostname: row.Hostname})
+	}
+	return leases, nil
+}
+
+// readDnsmasqLeases parses a dnsmasq lease file, whose lines are
+// "<expiry> <mac> <ip> <hostname|*> <client-id>"
+func readDnsmasqLeases(path string) ([]Lease, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var leases []Lease
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "duid" {
+			continue
+		}
+		hostname := fields[3]
+		if hostname == "*" {
+			hostname = ""
+		}
+		leases = append(leases, Lease{IP: fields[2], MAC: fields[1], Hostname: hostname})
+	}
+	return leases, scanner.Err()
+}
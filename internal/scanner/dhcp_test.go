@@ -0,0 +1,127 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+func TestReadDnsmasqLeases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dnsmasq.leases")
+	content := "1718000000 24:0a:c4:11:22:33 192.168.1.50 nerdqaxe 01:24:0a:c4:11:22:33\n" +
+		"1718000000 aa:bb:cc:dd:ee:ff 192.168.1.51 * *\n" +
+		"duid 00:01:00:01:2c:3d:4e:5f\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	leases, err := readDnsmasqLeases(path)
+	if err != nil {
+		t.Fatalf("readDnsmasqLeases failed: %v", err)
+	}
+	want := []Lease{
+		{IP: "192.168.1.50", MAC: "24:0a:c4:11:22:33", Hostname: "nerdqaxe"},
+		{IP: "192.168.1.51", MAC: "aa:bb:cc:dd:ee:ff", Hostname: ""},
+	}
+	if !reflect.DeepEqual(leases, want) {
+		t.Errorf("got %+v, want %+v", leases, want)
+	}
+}
+
+func TestLeaseCandidates(t *testing.T) {
+	leases := []Lease{
+		{IP: "192.168.1.10", MAC: "aa:bb:cc:00:00:01", Hostname: "NerdQAxe-Garage"},
+		{IP: "192.168.1.11", MAC: "7c-df-a1-00-00-02", Hostname: "esp"},
+		{IP: "192.168.1.12", MAC: "aa:bb:cc:00:00:03", Hostname: "laptop"},
+		{IP: "192.168.1.10", MAC: "aa:bb:cc:00:00:01", Hostname: "nerdqaxe-garage"},
+		{IP: "fd00::10", MAC: "24:0a:c4:00:00:04", Hostname: "bitaxe"},
+	}
+
+	defaults := NewLeaseReader(config.DHCPConfig{Source: "dnsmasq"})
+	if got, want := defaults.Candidates(leases), []string{"192.168.1.10", "192.168.1.11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("default prefixes: got %v, want %v", got, want)
+	}
+
+	custom := NewLeaseReader(config.DHCPConfig{
+		Source:           "dnsmasq",
+		HostnamePrefixes: []string{"lap"},
+		MACPrefixes:      []string{"aa-bb-cc-00-00-03"},
+	})
+	if got, want := custom.Candidates(leases), []string{"192.168.1.12"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("custom prefixes: got %v, want %v", got, want)
+	}
+}
+
+func TestOPNsenseLeasesFallsBackToKea(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/kea/leases4/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rows": []map[string]string{
+				{"address": "192.168.1.20", "hwaddr": "24:0a:c4:00:00:20", "hostname": "bitaxe"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	reader := NewLeaseReader(config.DHCPConfig{Source: "opnsense", URL: srv.URL, Username: "key", Password: "secret"})
+	leases, err := reader.Leases(context.Background())
+	if err != nil {
+		t.Fatalf("Leases failed: %v", err)
+	}
+	want := []Lease{{IP: "192.168.1.20", MAC: "24:0a:c4:00:00:20", Hostname: "bitaxe"}}
+	if !reflect.DeepEqual(leases, want) {
+		t.Errorf("got %+v, want %+v", leases, want)
+	}
+}
+
+func TestOpenWrtLeases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var session, object string
+		json.Unmarshal(req.Params[0], &session)
+		json.Unmarshal(req.Params[1], &object)
+
+		var result []interface{}
+		switch {
+		case object == "session" && session == ubusNullSession:
+			result = []interface{}{0, map[string]string{"ubus_rpc_session": "abc"}}
+		case object == "luci-rpc" && session == "abc":
+			result = []interface{}{0, map[string]interface{}{
+				"dhcp_leases": []map[string]string{
+					{"ipaddr": "192.168.1.30", "macaddr": "7c:df:a1:00:00:30", "hostname": "nerdaxe"},
+				},
+			}}
+		default:
+			result = []interface{}{6} // Permission denied
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	defer srv.Close()
+
+	reader := NewLeaseReader(config.DHCPConfig{Source: "openwrt", URL: srv.URL, Username: "root", Password: "pw"})
+	leases, err := reader.Leases(context.Background())
+	if err != nil {
+		t.Fatalf("Leases failed: %v", err)
+	}
+	want := []Lease{{IP: "192.168.1.30", MAC: "7c:df:a1:00:00:30", Hostname: "nerdaxe"}}
+	if !reflect.DeepEqual(leases, want) {
+		t.Errorf("got %+v, want %+v", leases, want)
+	}
+}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+// Discovery is the outcome of a Discover run
+type Discovery struct {
+	Source  string   // "dhcp" or "subnet"
+	Subnets []string // Subnets swept, when Source is "subnet"
+	Leases  int      // DHCP leases read, when Source is "dhcp"
+	Results []ScanResult
+}
+
+// Discover finds miners as cfg describes: from the router's DHCP leases when
+// a lease source is set (falling back to a sweep if they can't be read),
+// otherwise by sweeping cfg.Networks, or every local subnet if none are set
+func (s *Scanner) Discover(ctx context.Context, cfg config.ScannerConfig) (*Discovery, error) {
+	if cfg.DHCP.Source != "" {
+		reader := NewLeaseReader(cfg.DHCP)
+		leases, err := reader.Leases(ctx)
+		if err == nil {
+			ips := reader.Candidates(leases)
+			log.Printf("Probing %d of %d DHCP leases from %s", len(ips), len(leases), cfg.DHCP.Source)
+
+			results, err := s.ScanIPs(ctx, ips)
+			return &Discovery{Source: "dhcp", Subnets: []string{}, Leases: len(leases), Results: results}, err
+		}
+		log.Printf("Reading DHCP leases from %s failed, sweeping subnets instead: %v", cfg.DHCP.Source, err)
+	}
+
+	subnets := cfg.Networks
+	if len(subnets) == 0 {
+		subnets = s.DetectAllSubnets()
+	}
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("no network interfaces found")
+	}
+
+	log.Printf("Scanning subnets: %v", subnets)
+
+	d := &Discovery{Source: "subnet", Subnets: subnets}
+	seen := make(map[string]bool)
+	for _, subnet := range subnets {
+		results, err := s.Scan(ctx, subnet)
+		if err != nil {
+			log.Printf("Error scanning subnet %s: %v", subnet, err)
+			continue
+		}
+
+		for _, result := range results {
+			// Avoid duplicates (in case same miner appears on multiple interfaces)
+			if !seen[result.Miner.IP] {
+				seen[result.Miner.IP] = true
+				d.Results = append(d.Results, result)
+			}
+		}
+	}
+
+	return d, nil
+}
@@ -0,0 +1,28 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/camarigor/miner-hq/internal/config"
+)
+
+func TestDiscoverUsesDHCPLeases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dnsmasq.leases")
+	if err := os.WriteFile(path, []byte("1718000000 aa:bb:cc:dd:ee:ff 192.168.1.51 laptop *\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewScanner().Discover(context.Background(), config.ScannerConfig{
+		Networks: []string{"192.168.1.0/24"},
+		DHCP:     config.DHCPConfig{Source: "dnsmasq", LeaseFile: path},
+	})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if d.Source != "dhcp" || d.Leases != 1 || len(d.Results) != 0 {
+		t.Errorf("got source %q, %d leases, %d results; want dhcp, 1, 0", d.Source, d.Leases, len(d.Results))
+	}
+}
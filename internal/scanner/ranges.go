@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxNetworkSize is the most addresses one network may expand to, a /16
+const maxNetworkSize = 65536
+
+// ExpandNetwork converts a network to scan into its addresses. A network is
+// a CIDR ("192.168.20.0/24", without its network and broadcast addresses),
+// a range ("192.168.20.10-192.168.20.50", or "192.168.20.10-50" within the
+// last octet) or a single address.
+func (s *Scanner) ExpandNetwork(network string) ([]string, error) {
+	network = strings.TrimSpace(network)
+	if strings.Contains(network, "/") {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet CIDR: %w", err)
+		}
+		if ones, bits := ipNet.Mask.Size(); bits == 32 && bits-ones > 16 {
+			return nil, fmt.Errorf("subnet %s is larger than a /16", network)
+		}
+		return s.expandSubnet(network)
+	}
+
+	from, to, found := strings.Cut(network, "-")
+	first := net.ParseIP(strings.TrimSpace(from)).To4()
+	if first == nil {
+		return nil, fmt.Errorf("%q is not an IPv4 address, range or CIDR", network)
+	}
+	if !found {
+		return []string{first.String()}, nil
+	}
+
+	to = strings.TrimSpace(to)
+	last := net.ParseIP(to).To4()
+	if last == nil {
+		// Short form: the last octet of the range's end
+		octet, err := strconv.Atoi(to)
+		if err != nil || octet < 0 || octet > 255 {
+			return nil, fmt.Errorf("invalid range end %q", to)
+		}
+		last = net.IPv4(first[0], first[1], first[2], byte(octet)).To4()
+	}
+
+	start, end := binary.BigEndian.Uint32(first), binary.BigEndian.Uint32(last)
+	if end < start {
+		return nil, fmt.Errorf("range %s ends before it starts", network)
+	}
+	if end-start >= maxNetworkSize {
+		return nil, fmt.Errorf("range %s has more than %d addresses", network, maxNetworkSize)
+	}
+
+	ips := make([]string, 0, end-start+1)
+	for n := start; ; n++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, n)
+		ips = append(ips, ip.String())
+		if n == end {
+			break
+		}
+	}
+	return ips, nil
+}
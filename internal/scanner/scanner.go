@@ -33,12 +33,14 @@ var knownASICModels = []string{
 // ScanResult represents a discovered miner
 type ScanResult struct {
 	Miner *storage.Miner
-	Info  *collector.MinerAPIResponse
+	Info  *collector.MinerAPIResponse // nil for miners found on the cgminer API
 }
 
-// Scanner scans networks for supported miners (NerdQAxe, AxeOS/Zyber)
+// Scanner scans networks for supported miners (NerdQAxe, AxeOS/Zyber, and
+// cgminer API devices such as the Avalon Nano)
 type Scanner struct {
 	client      *collector.MinerClient
+	cgminer     *collector.CGMinerClient
 	concurrency int
 	timeout     time.Duration
 }
@@ -47,6 +49,7 @@ type Scanner struct {
 func NewScanner() *Scanner {
 	return &Scanner{
 		client:      collector.NewMinerClient(),
+		cgminer:     collector.NewCGMinerClient(),
 		concurrency: 50,
 		timeout:     2 * time.Second,
 	}
@@ -56,6 +59,7 @@ func NewScanner() *Scanner {
 func NewScannerWithOptions(concurrency int, timeout time.Duration) *Scanner {
 	return &Scanner{
 		client:      collector.NewMinerClient(),
+		cgminer:     collector.NewCGMinerClient(),
 		concurrency: concurrency,
 		timeout:     timeout,
 	}
@@ -130,13 +134,19 @@ func (s *Scanner) DetectAllSubnets() []string {
 	return subnets
 }
 
-// Scan scans the given subnet for supported miners
+// Scan scans the given network (a subnet, range or address, see
+// ExpandNetwork) for supported miners
 func (s *Scanner) Scan(ctx context.Context, subnet string) ([]ScanResult, error) {
-	ips, err := s.expandSubnet(subnet)
+	ips, err := s.ExpandNetwork(subnet)
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand subnet: %w", err)
 	}
 
+	return s.ScanIPs(ctx, ips)
+}
+
+// ScanIPs probes the given addresses for supported miners
+func (s *Scanner) ScanIPs(ctx context.Context, ips []string) ([]ScanResult, error) {
 	var (
 		results []ScanResult
 		mu      sync.Mutex
@@ -174,15 +184,19 @@ func (s *Scanner) Scan(ctx context.Context, subnet string) ([]ScanResult, error)
 	return results, nil
 }
 
-// ScanSingle checks a single IP for a supported miner (NerdQAxe or AxeOS/Zyber)
+// ScanSingle checks a single IP for a supported miner (NerdQAxe or
+// AxeOS/Zyber, else one answering the cgminer API)
 func (s *Scanner) ScanSingle(ip string) (*ScanResult, error) {
 	info, err := s.client.FetchInfo(ip)
-	if err != nil {
-		return nil, err
+	if err == nil && !s.isSupportedMiner(info) {
+		err = fmt.Errorf("device at %s is not a supported miner", ip)
 	}
-
-	if !s.isSupportedMiner(info) {
-		return nil, fmt.Errorf("device at %s is not a supported miner", ip)
+	if err != nil {
+		cgInfo, cgErr := s.cgminer.FetchInfo(ip)
+		if cgErr != nil {
+			return nil, err
+		}
+		return &ScanResult{Miner: s.cgminer.ToMiner(ip, cgInfo)}, nil
 	}
 
 	miner := s.client.ToMiner(ip, info)
@@ -382,3 +382,43 @@ func TestScanResultStructure(t *testing.T) {
 
 // Ensure unused imports are used
 var _ = fmt.Sprintf
+
+func TestExpandNetwork(t *testing.T) {
+	s := NewScanner()
+
+	tests := []struct {
+		network   string
+		wantCount int
+		wantFirst string
+		wantLast  string
+		wantErr   bool
+	}{
+		{network: "192.168.20.0/28", wantCount: 14, wantFirst: "192.168.20.1", wantLast: "192.168.20.14"},
+		{network: "192.168.20.10-192.168.20.50", wantCount: 41, wantFirst: "192.168.20.10", wantLast: "192.168.20.50"},
+		{network: "192.168.20.250-192.168.21.5", wantCount: 12, wantFirst: "192.168.20.250", wantLast: "192.168.21.5"},
+		{network: "192.168.20.10-50", wantCount: 41, wantFirst: "192.168.20.10", wantLast: "192.168.20.50"},
+		{network: "192.168.20.7", wantCount: 1, wantFirst: "192.168.20.7", wantLast: "192.168.20.7"},
+		{network: "192.168.20.50-10", wantErr: true},
+		{network: "10.0.0.0/8", wantErr: true},
+		{network: "10.0.0.1-10.2.0.0", wantErr: true},
+		{network: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ips, err := s.ExpandNetwork(tt.network)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ExpandNetwork(%q) expected error, got %d IPs", tt.network, len(ips))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExpandNetwork(%q) unexpected error: %v", tt.network, err)
+			continue
+		}
+		if len(ips) != tt.wantCount || ips[0] != tt.wantFirst || ips[len(ips)-1] != tt.wantLast {
+			t.Errorf("ExpandNetwork(%q) = %d IPs from %s to %s, want %d from %s to %s",
+				tt.network, len(ips), ips[0], ips[len(ips)-1], tt.wantCount, tt.wantFirst, tt.wantLast)
+		}
+	}
+}
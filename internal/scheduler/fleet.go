@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/plugs"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// MinerStore is what the fleet needs of the database
+type MinerStore interface {
+	GetAllMiners() ([]*storage.Miner, error)
+	SetMinerPaused(ip string, paused bool) error
+}
+
+// fleet pauses miners through their smart plugs, underclocks them through
+// their API, and prices energy at their location
+type fleet struct {
+	settings func() *config.Config
+	store    MinerStore
+	coll     *collector.Collector
+	plugs    *plugs.Manager
+	client   *collector.MinerClient
+	control  *collector.MinerControl
+}
+
+// NewFleet returns the Fleet of the local miners
+func NewFleet(settings func() *config.Config, store MinerStore, coll *collector.Collector, plugMgr *plugs.Manager) Fleet {
+	return &fleet{
+		settings: settings,
+		store:    store,
+		coll:     coll,
+		plugs:    plugMgr,
+		client:   collector.NewMinerClient(),
+		control:  collector.NewMinerControl(),
+	}
+}
+
+// miner looks up a miner, enabled or not
+func (f *fleet) miner(ip string) (*storage.Miner, error) {
+	miners, err := f.store.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range miners {
+		if m.IP == ip {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("miner not found")
+}
+
+// Pause switches the plug off, then pauses collection like the miner's
+// Disable button, so the miner isn't reported offline
+func (f *fleet) Pause(ip string) error {
+	if err := f.plugs.SetPower(ip, false); err != nil {
+		return err
+	}
+	f.coll.RemoveMiner(ip)
+	return f.store.SetMinerPaused(ip, true)
+}
+
+// Resume switches the plug on and collects from the miner again
+func (f *fleet) Resume(ip string) error {
+	if err := f.plugs.SetPower(ip, true); err != nil {
+		return err
+	}
+	m, err := f.miner(ip)
+	if err != nil {
+		return nil // Removed meanwhile, powered again all the same
+	}
+	if err := f.store.SetMinerPaused(ip, false); err != nil {
+		return err
+	}
+	f.coll.SetPowerCalibration(ip, m.PowerCalibration())
+	f.coll.SetEnergyLocation(ip, m.Location)
+	f.coll.AddMiner(ip)
+	return nil
+}
+
+func (f *fleet) Settings(ip string) (int, int, error) {
+	info, err := f.client.FetchInfo(ip)
+	if err != nil {
+		return 0, 0, err
+	}
+	return info.Frequency, info.CoreVoltage, nil
+}
+
+// Apply changes the settings, which the firmware applies on a restart
+func (f *fleet) Apply(ip string, frequency, coreVoltage int) error {
+	settings := collector.MinerSettings{Frequency: &frequency}
+	if coreVoltage != 0 {
+		settings.CoreVoltage = &coreVoltage
+	}
+	if err := f.control.UpdateSettings(ip, settings); err != nil {
+		return err
+	}
+	return f.control.Restart(ip)
+}
+
+func (f *fleet) Rate(ip string, at time.Time) float64 {
+	var location string
+	if m, err := f.miner(ip); err == nil {
+		location = m.Location
+	}
+	return f.settings().Energy.RateAt(location, at)
+}
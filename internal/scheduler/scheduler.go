@@ -0,0 +1,644 @@
+// Package scheduler pauses or underclocks miners on a schedule: in time
+// windows, while electricity is expensive, or on an external signal such as
+// a solar inverter's surplus, and reports the energy and cost that saved.
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+	"github.com/camarigor/miner-hq/internal/units"
+)
+
+const (
+	// checkInterval is how often schedules are evaluated
+	checkInterval = time.Minute
+
+	// signalMaxAge is how long a signal value stays in use. A schedule on a
+	// signal that stopped arriving no longer applies.
+	signalMaxAge = 10 * time.Minute
+
+	// powerMaxAge is how recent a power reading must be to count as a
+	// miner's baseline or current power
+	powerMaxAge = 5 * time.Minute
+
+	// maxAccrual caps the time between two checks counted for savings, so
+	// MinerHQ being stopped isn't counted
+	maxAccrual = 2 * checkInterval
+)
+
+// Schedule actions
+const (
+	ActionPause      = "pause"      // Power off through the miner's smart plug
+	ActionUnderclock = "underclock" // Lower frequency and core voltage
+)
+
+// Schedule triggers
+const (
+	TriggerTime   = "time"
+	TriggerTariff = "tariff"
+	TriggerSignal = "signal"
+)
+
+// Override modes
+const (
+	OverrideRun   = "run"   // Keep mining whatever the schedules say
+	OverridePause = "pause" // Keep paused whatever the schedules say
+)
+
+var weekdays = map[string]bool{"sun": true, "mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true}
+
+// Store persists schedules, overrides and runs
+type Store interface {
+	GetSchedules() ([]*storage.Schedule, error)
+	GetScheduleOverrides() ([]*storage.ScheduleOverride, error)
+	DeleteScheduleOverride(minerIP string) error
+	GetOpenScheduleRuns() ([]*storage.ScheduleRun, error)
+	InsertScheduleRun(run *storage.ScheduleRun) error
+	UpdateScheduleRun(run *storage.ScheduleRun) error
+}
+
+// Fleet carries out the scheduler's decisions on the miners, see NewFleet
+type Fleet interface {
+	Pause(ip string) error                                      // Switches the miner's plug off and stops collecting from it
+	Resume(ip string) error                                     // Reverses Pause
+	Settings(ip string) (frequency, coreVoltage int, err error) // Current frequency in MHz and core voltage in mV
+	Apply(ip string, frequency, coreVoltage int) error          // Sets frequency and core voltage (0 = unchanged) and restarts the miner
+	Rate(ip string, at time.Time) float64                       // Electricity rate of the miner's location
+}
+
+// Validate checks a schedule sent to the API
+func Validate(sc *storage.Schedule) error {
+	if strings.TrimSpace(sc.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(sc.Miners) == 0 {
+		return fmt.Errorf("at least one miner is required")
+	}
+	for _, ip := range sc.Miners {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("miner %q is not an IP address", ip)
+		}
+	}
+
+	switch sc.Action {
+	case ActionPause:
+	case ActionUnderclock:
+		if sc.Frequency < 100 || sc.Frequency > 1200 {
+			return fmt.Errorf("frequency %d MHz must be between 100 and 1200", sc.Frequency)
+		}
+		if sc.CoreVoltage != 0 && (sc.CoreVoltage < 900 || sc.CoreVoltage > 1400) {
+			return fmt.Errorf("core voltage %d mV must be 0 (unchanged) or between 900 and 1400", sc.CoreVoltage)
+		}
+	default:
+		return fmt.Errorf("action %q must be pause or underclock", sc.Action)
+	}
+
+	switch sc.Trigger {
+	case TriggerTime:
+		for _, clock := range []string{sc.Start, sc.End} {
+			if _, err := time.Parse("15:04", clock); err != nil {
+				return fmt.Errorf("start and end must be HH:MM, got %q", clock)
+			}
+		}
+		for _, d := range sc.Days {
+			if !weekdays[strings.ToLower(d)] {
+				return fmt.Errorf("day %q must be one of sun, mon, tue, wed, thu, fri, sat", d)
+			}
+		}
+	case TriggerTariff:
+		if sc.MaxRate < 0 {
+			return fmt.Errorf("max rate must not be negative")
+		}
+	case TriggerSignal:
+		if sc.Signal == "" {
+			return fmt.Errorf("signal is required")
+		}
+		if sc.Compare != "below" && sc.Compare != "above" {
+			return fmt.Errorf("compare %q must be below or above", sc.Compare)
+		}
+	default:
+		return fmt.Errorf("trigger %q must be time, tariff or signal", sc.Trigger)
+	}
+	return nil
+}
+
+// MinerStatus reports what the scheduler does with a miner
+type MinerStatus struct {
+	IP         string                    `json:"ip"`
+	State      string                    `json:"state"`                // "mining", "paused" or "underclocked"
+	ScheduleID int64                     `json:"scheduleId,omitempty"` // Schedule of the pause or underclock, 0 for a manual override
+	Since      *time.Time                `json:"since,omitempty"`
+	SavedKWh   float64                   `json:"savedKwh"` // Saved since Since
+	SavedCost  float64                   `json:"savedCost"`
+	Override   *storage.ScheduleOverride `json:"override,omitempty"`
+	Error      string                    `json:"error,omitempty"` // Last failed pause, underclock or restore, retried every minute
+}
+
+// SignalStatus reports the last value of a signal
+type SignalStatus struct {
+	Name  string    `json:"name"`
+	Value float64   `json:"value"`
+	At    time.Time `json:"at"`
+	Fresh bool      `json:"fresh"` // Recent enough for schedules to use
+}
+
+// Report is the scheduler's state
+type Report struct {
+	Enabled bool           `json:"enabled"`
+	Miners  []MinerStatus  `json:"miners"`
+	Signals []SignalStatus `json:"signals"`
+}
+
+// reading is a timestamped value
+type reading struct {
+	value float64
+	at    time.Time
+}
+
+// target is what a miner should be doing
+type target struct {
+	scheduleID  int64 // 0 for a manual pause
+	name        string
+	action      string
+	frequency   int
+	coreVoltage int
+}
+
+// Scheduler evaluates the schedules every minute and pauses, underclocks and
+// restores miners accordingly. Pauses beat underclocks; between schedules
+// with the same action the lowest ID wins; manual overrides beat both.
+// Runs are stored as they start, so pauses and underclocks in progress are
+// picked up again after a restart.
+type Scheduler struct {
+	settings func() *config.Config
+	store    Store
+	fleet    Fleet
+
+	mu        sync.Mutex
+	schedules []*storage.Schedule
+	overrides map[string]*storage.ScheduleOverride
+	runs      map[string]*storage.ScheduleRun // Runs in progress by miner IP
+	ended     map[string]time.Time            // End of each miner's last run
+	errors    map[string]string               // Last failed action by miner IP
+	signals   map[string]reading
+	power     map[string]reading // Last polled power by miner IP
+	lastCheck time.Time
+
+	refresh chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New creates a scheduler and loads the runs left in progress by the last
+// shutdown
+func New(settings func() *config.Config, store Store, fleet Fleet) *Scheduler {
+	s := &Scheduler{
+		settings:  settings,
+		store:     store,
+		fleet:     fleet,
+		overrides: make(map[string]*storage.ScheduleOverride),
+		runs:      make(map[string]*storage.ScheduleRun),
+		ended:     make(map[string]time.Time),
+		errors:    make(map[string]string),
+		signals:   make(map[string]reading),
+		power:     make(map[string]reading),
+		refresh:   make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	runs, err := store.GetOpenScheduleRuns()
+	if err != nil {
+		log.Printf("Scheduler: failed to load runs in progress: %v", err)
+	}
+	for _, run := range runs {
+		s.runs[run.MinerIP] = run
+	}
+	return s
+}
+
+// Start evaluates the schedules in the background
+func (s *Scheduler) Start() {
+	go func() {
+		defer close(s.done)
+		s.reload()
+		s.check(time.Now())
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.refresh:
+			}
+			s.reload()
+			s.check(time.Now())
+		}
+	}()
+}
+
+// Stop stops evaluating the schedules. Miners stay as they are; the runs in
+// progress are resumed by the next start.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Refresh re-reads the schedules and overrides and applies them right away,
+// rather than at the next minute
+func (s *Scheduler) Refresh() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// reload reads the schedules and overrides, keeping the previous ones if
+// that fails
+func (s *Scheduler) reload() {
+	schedules, err := s.store.GetSchedules()
+	if err != nil {
+		log.Printf("Scheduler: failed to load schedules: %v", err)
+		return
+	}
+	overrides, err := s.store.GetScheduleOverrides()
+	if err != nil {
+		log.Printf("Scheduler: failed to load overrides: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules = schedules
+	s.overrides = make(map[string]*storage.ScheduleOverride, len(overrides))
+	for _, o := range overrides {
+		s.overrides[o.MinerIP] = o
+	}
+}
+
+// Observe records a miner's polled power, the baseline of its savings
+func (s *Scheduler) Observe(snap *storage.MinerSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.power[snap.MinerIP] = reading{value: snap.Power, at: snap.Timestamp}
+}
+
+// SetSignal records a signal's value. Schedules on it apply at the next
+// check, within a minute.
+func (s *Scheduler) SetSignal(name string, value float64, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals[name] = reading{value: value, at: at}
+}
+
+// HandleMessage records the value of the signals read from an MQTT topic
+func (s *Scheduler) HandleMessage(topic string, payload []byte) {
+	for _, sig := range s.settings().Scheduler.Signals {
+		if sig.Topic != topic {
+			continue
+		}
+		value, err := signalValue(payload, sig.Field)
+		if err != nil {
+			log.Printf("Scheduler: ignoring signal %s from %s: %v", sig.Name, topic, err)
+			continue
+		}
+		s.SetSignal(sig.Name, value, time.Now())
+	}
+}
+
+// check accrues the savings of the runs in progress, then ends the runs
+// that no longer apply and starts the ones that do
+func (s *Scheduler) check(now time.Time) {
+	s.accrue(now)
+	s.expireOverrides(now)
+
+	cfg := s.settings()
+	s.mu.Lock()
+	want := s.plan(now, cfg)
+	current := make(map[string]*storage.ScheduleRun, len(s.runs))
+	ended := make([]string, 0, len(s.runs))
+	for ip, run := range s.runs {
+		current[ip] = run
+		if t, ok := want[ip]; !ok || t.scheduleID != run.ScheduleID || t.action != run.Action {
+			ended = append(ended, ip)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(ended)
+	for _, ip := range ended {
+		s.end(ip, current[ip], now)
+	}
+	started := make([]string, 0, len(want))
+	for ip := range want {
+		started = append(started, ip)
+	}
+	sort.Strings(started)
+	for _, ip := range started {
+		s.mu.Lock()
+		_, running := s.runs[ip] // Still running if it couldn't be ended
+		s.mu.Unlock()
+		if !running {
+			s.start(ip, want[ip], now)
+		}
+	}
+}
+
+// plan returns what each miner should be doing, leaving out the miners that
+// should mine normally. The caller must hold mu.
+func (s *Scheduler) plan(now time.Time, cfg *config.Config) map[string]target {
+	want := make(map[string]target)
+	if !cfg.Scheduler.Enabled {
+		return want
+	}
+	hold := time.Duration(cfg.Scheduler.MinRunMinutes) * time.Minute
+
+	for _, sc := range s.schedules {
+		if !sc.Enabled {
+			continue
+		}
+		for _, ip := range sc.Miners {
+			if cur, ok := want[ip]; ok && (cur.action == ActionPause || sc.Action != ActionPause) {
+				continue
+			}
+
+			active := s.active(sc, ip, now)
+			if sc.Trigger == TriggerSignal {
+				// Signals hovering around their threshold don't flap miners
+				// on and off: runs last at least min_run_minutes, and don't
+				// start again sooner than that after ending
+				run := s.runs[ip]
+				if run != nil && run.ScheduleID == sc.ID && now.Sub(run.Start) < hold {
+					active = true
+				}
+				if run == nil && now.Sub(s.ended[ip]) < hold {
+					active = false
+				}
+			}
+			if active {
+				want[ip] = target{scheduleID: sc.ID, name: sc.Name, action: sc.Action, frequency: sc.Frequency, coreVoltage: sc.CoreVoltage}
+			}
+		}
+	}
+
+	for ip, o := range s.overrides {
+		switch o.Mode {
+		case OverrideRun:
+			delete(want, ip)
+		case OverridePause:
+			want[ip] = target{name: "manual override", action: ActionPause}
+		}
+	}
+	return want
+}
+
+// active reports whether a schedule's trigger holds for a miner. The caller
+// must hold mu.
+func (s *Scheduler) active(sc *storage.Schedule, ip string, now time.Time) bool {
+	switch sc.Trigger {
+	case TriggerTime:
+		window := config.TariffPeriod{Start: sc.Start, End: sc.End, Days: sc.Days}
+		return window.Matches(now)
+	case TriggerTariff:
+		return s.fleet.Rate(ip, now) > sc.MaxRate
+	case TriggerSignal:
+		sig, ok := s.signals[sc.Signal]
+		if !ok || now.Sub(sig.at) > signalMaxAge {
+			return false
+		}
+		if sc.Compare == "above" {
+			return sig.value > sc.Threshold
+		}
+		return sig.value < sc.Threshold
+	}
+	return false
+}
+
+// expireOverrides deletes the overrides that have run out
+func (s *Scheduler) expireOverrides(now time.Time) {
+	s.mu.Lock()
+	var expired []string
+	for ip, o := range s.overrides {
+		if o.Until != nil && !now.Before(*o.Until) {
+			expired = append(expired, ip)
+			delete(s.overrides, ip)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ip := range expired {
+		if err := s.store.DeleteScheduleOverride(ip); err != nil {
+			log.Printf("Scheduler: failed to delete the expired override of %s: %v", ip, err)
+		}
+	}
+}
+
+// start pauses or underclocks a miner, recording the power it drew before
+func (s *Scheduler) start(ip string, t target, now time.Time) {
+	run := &storage.ScheduleRun{ScheduleID: t.scheduleID, MinerIP: ip, Action: t.action, Start: now}
+	s.mu.Lock()
+	if p, ok := s.power[ip]; ok && now.Sub(p.at) < powerMaxAge {
+		run.BaselineWatts = p.value
+	}
+	s.mu.Unlock()
+
+	var err error
+	switch t.action {
+	case ActionPause:
+		err = s.fleet.Pause(ip)
+	case ActionUnderclock:
+		run.RestoreFrequency, run.RestoreCoreVoltage, err = s.fleet.Settings(ip)
+		if err == nil && run.RestoreFrequency == 0 {
+			err = fmt.Errorf("miner doesn't report its frequency")
+		}
+		if err == nil {
+			err = s.fleet.Apply(ip, t.frequency, t.coreVoltage)
+		}
+	}
+	if err != nil {
+		s.fail(ip, fmt.Errorf("failed to %s for %s: %w", t.action, t.name, err))
+		return
+	}
+
+	if err := s.store.InsertScheduleRun(run); err != nil {
+		log.Printf("Scheduler: failed to record the %s of %s: %v", t.action, ip, err)
+	}
+	log.Printf("Scheduler: %s %s for %s", pastTense(t.action), ip, t.name)
+	s.mu.Lock()
+	s.runs[ip] = run
+	delete(s.errors, ip)
+	s.mu.Unlock()
+}
+
+// end restores a paused or underclocked miner
+func (s *Scheduler) end(ip string, run *storage.ScheduleRun, now time.Time) {
+	var err error
+	switch run.Action {
+	case ActionPause:
+		err = s.fleet.Resume(ip)
+	case ActionUnderclock:
+		err = s.fleet.Apply(ip, run.RestoreFrequency, run.RestoreCoreVoltage)
+	}
+	if err != nil {
+		s.fail(ip, fmt.Errorf("failed to end the %s: %w", run.Action, err))
+		return
+	}
+
+	s.mu.Lock()
+	end := now
+	run.End = &end
+	ended := *run
+	delete(s.runs, ip)
+	delete(s.errors, ip)
+	s.ended[ip] = now
+	s.mu.Unlock()
+
+	if err := s.store.UpdateScheduleRun(&ended); err != nil {
+		log.Printf("Scheduler: failed to record the end of the %s of %s: %v", run.Action, ip, err)
+	}
+	log.Printf("Scheduler: restored %s after %s, saving %.3f kWh", ip, now.Sub(run.Start).Round(time.Minute), run.SavedKWh)
+}
+
+// fail records a failed action, logging it unless it failed the same way
+// the last time
+func (s *Scheduler) fail(ip string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errors[ip] != err.Error() {
+		log.Printf("Scheduler: %s: %v", ip, err)
+	}
+	s.errors[ip] = err.Error()
+}
+
+// accrue adds the energy saved since the last check to the runs in
+// progress: the baseline power less what a miner draws now, which is nothing
+// while paused, at the rate of the moment
+func (s *Scheduler) accrue(now time.Time) {
+	s.mu.Lock()
+	elapsed := now.Sub(s.lastCheck)
+	first := s.lastCheck.IsZero()
+	s.lastCheck = now
+	runs := make([]*storage.ScheduleRun, 0, len(s.runs))
+	drawn := make(map[string]reading, len(s.runs))
+	for ip, run := range s.runs {
+		runs = append(runs, run)
+		drawn[ip] = s.power[ip]
+	}
+	s.mu.Unlock()
+
+	if first || elapsed <= 0 {
+		return
+	}
+	if elapsed > maxAccrual {
+		elapsed = maxAccrual
+	}
+	for _, run := range runs {
+		var watts float64
+		if run.Action == ActionUnderclock {
+			p := drawn[run.MinerIP]
+			if p.at.Before(run.Start) || now.Sub(p.at) > powerMaxAge {
+				continue // No reading at the lower clock yet
+			}
+			watts = p.value
+		}
+		saved := run.BaselineWatts - watts
+		if saved <= 0 {
+			continue
+		}
+		kwh := units.KWh(saved, elapsed.Hours())
+		cost := kwh * s.fleet.Rate(run.MinerIP, now)
+
+		s.mu.Lock()
+		run.SavedKWh += kwh
+		run.SavedCost += cost
+		updated := *run
+		s.mu.Unlock()
+		if err := s.store.UpdateScheduleRun(&updated); err != nil {
+			log.Printf("Scheduler: failed to record the savings of %s: %v", run.MinerIP, err)
+		}
+	}
+}
+
+// Status reports every scheduled, overridden or paused miner and every
+// signal received
+func (s *Scheduler) Status(now time.Time) Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := Report{Enabled: s.settings().Scheduler.Enabled, Miners: []MinerStatus{}, Signals: []SignalStatus{}}
+	ips := make(map[string]bool)
+	for _, sc := range s.schedules {
+		for _, ip := range sc.Miners {
+			ips[ip] = true
+		}
+	}
+	for ip := range s.overrides {
+		ips[ip] = true
+	}
+	for ip := range s.runs {
+		ips[ip] = true
+	}
+	sorted := make([]string, 0, len(ips))
+	for ip := range ips {
+		sorted = append(sorted, ip)
+	}
+	sort.Strings(sorted)
+	for _, ip := range sorted {
+		st := MinerStatus{IP: ip, State: "mining", Override: s.overrides[ip], Error: s.errors[ip]}
+		if run := s.runs[ip]; run != nil {
+			start := run.Start
+			st.State, st.ScheduleID, st.Since = pastTense(run.Action), run.ScheduleID, &start
+			st.SavedKWh, st.SavedCost = run.SavedKWh, run.SavedCost
+		}
+		report.Miners = append(report.Miners, st)
+	}
+	for name, sig := range s.signals {
+		report.Signals = append(report.Signals, SignalStatus{Name: name, Value: sig.value, At: sig.at, Fresh: now.Sub(sig.at) <= signalMaxAge})
+	}
+	sort.Slice(report.Signals, func(i, j int) bool { return report.Signals[i].Name < report.Signals[j].Name })
+	return report
+}
+
+// pastTense names a miner's state after an action
+func pastTense(action string) string {
+	if action == ActionUnderclock {
+		return "underclocked"
+	}
+	return "paused"
+}
+
+// signalValue reads a number from an MQTT payload: the whole payload, or a
+// field of a JSON payload, dotted for nested fields
+func signalValue(payload []byte, field string) (float64, error) {
+	if field == "" {
+		return strconv.ParseFloat(strings.TrimSpace(string(payload)), 64)
+	}
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return 0, err
+	}
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("no field %q", field)
+		}
+		v = obj[key]
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(n), 64)
+	}
+	return 0, fmt.Errorf("field %q is not a number", field)
+}
@@ -0,0 +1,289 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+type fakeStore struct {
+	schedules []*storage.Schedule
+	overrides []*storage.ScheduleOverride
+	runs      []*storage.ScheduleRun
+}
+
+func (f *fakeStore) GetSchedules() ([]*storage.Schedule, error) { return f.schedules, nil }
+
+func (f *fakeStore) GetScheduleOverrides() ([]*storage.ScheduleOverride, error) {
+	return f.overrides, nil
+}
+
+func (f *fakeStore) DeleteScheduleOverride(ip string) error {
+	for i, o := range f.overrides {
+		if o.MinerIP == ip {
+			f.overrides = append(f.overrides[:i], f.overrides[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) GetOpenScheduleRuns() ([]*storage.ScheduleRun, error) {
+	var open []*storage.ScheduleRun
+	for _, run := range f.runs {
+		if run.End == nil {
+			open = append(open, run)
+		}
+	}
+	return open, nil
+}
+
+func (f *fakeStore) InsertScheduleRun(run *storage.ScheduleRun) error {
+	stored := *run
+	stored.ID = int64(len(f.runs) + 1)
+	run.ID = stored.ID
+	f.runs = append(f.runs, &stored)
+	return nil
+}
+
+func (f *fakeStore) UpdateScheduleRun(run *storage.ScheduleRun) error {
+	stored := *run
+	f.runs[run.ID-1] = &stored
+	return nil
+}
+
+// fakeFleet records the actions taken, and fails them while failing is set
+type fakeFleet struct {
+	actions []string
+	failing error
+	rate    float64
+}
+
+func (f *fakeFleet) do(action string) error {
+	if f.failing != nil {
+		return f.failing
+	}
+	f.actions = append(f.actions, action)
+	return nil
+}
+
+func (f *fakeFleet) Pause(ip string) error  { return f.do("pause " + ip) }
+func (f *fakeFleet) Resume(ip string) error { return f.do("resume " + ip) }
+
+func (f *fakeFleet) Settings(ip string) (int, int, error) { return 525, 1200, nil }
+
+func (f *fakeFleet) Apply(ip string, frequency, coreVoltage int) error {
+	return f.do(fmt.Sprintf("apply %s %d %d", ip, frequency, coreVoltage))
+}
+
+func (f *fakeFleet) Rate(ip string, at time.Time) float64 { return f.rate }
+
+func newTestScheduler(store *fakeStore) (*Scheduler, *fakeFleet, *config.Config) {
+	cfg := &config.Config{}
+	cfg.Scheduler = config.SchedulerConfig{Enabled: true, MinRunMinutes: 10}
+	fleet := &fakeFleet{rate: 0.3}
+	return New(func() *config.Config { return cfg }, store, fleet), fleet, cfg
+}
+
+// run observes ip drawing watts and checks every minute from start up to end
+func run(s *Scheduler, ip string, watts float64, start, end time.Time) {
+	for t := start; !t.After(end); t = t.Add(time.Minute) {
+		s.Observe(&storage.MinerSnapshot{MinerIP: ip, Timestamp: t, Power: watts})
+		s.reload()
+		s.check(t)
+	}
+}
+
+func TestTimeWindowUnderclock(t *testing.T) {
+	store := &fakeStore{schedules: []*storage.Schedule{{ID: 1, Name: "Peak", Enabled: true, Miners: []string{"10.0.0.2"},
+		Action: ActionUnderclock, Frequency: 400, Trigger: TriggerTime, Start: "17:00", End: "21:00"}}}
+	s, fleet, _ := newTestScheduler(store)
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local)
+
+	run(s, "10.0.0.2", 15, day.Add(16*time.Hour+50*time.Minute), day.Add(16*time.Hour+59*time.Minute))
+	if len(fleet.actions) != 0 {
+		t.Fatalf("expected nothing before 17:00, got %v", fleet.actions)
+	}
+	run(s, "10.0.0.2", 15, day.Add(17*time.Hour), day.Add(17*time.Hour))
+	if len(fleet.actions) != 1 || fleet.actions[0] != "apply 10.0.0.2 400 0" {
+		t.Fatalf("expected the miner underclocked at 17:00, got %v", fleet.actions)
+	}
+	if st := s.Status(day.Add(17 * time.Hour)).Miners; len(st) != 1 || st[0].State != "underclocked" || st[0].ScheduleID != 1 {
+		t.Errorf("unexpected status %+v", st)
+	}
+
+	// 10 W instead of 15 W for 4 hours saves 20 Wh
+	run(s, "10.0.0.2", 10, day.Add(17*time.Hour+time.Minute), day.Add(21*time.Hour))
+	if len(fleet.actions) != 2 || fleet.actions[1] != "apply 10.0.0.2 525 1200" {
+		t.Fatalf("expected the settings restored at 21:00, got %v", fleet.actions)
+	}
+	if len(store.runs) != 1 || store.runs[0].End == nil || store.runs[0].BaselineWatts != 15 {
+		t.Fatalf("expected one ended run, got %+v", store.runs)
+	}
+	if got := store.runs[0].SavedKWh; math.Abs(got-0.02) > 1e-9 {
+		t.Errorf("saved %v kWh, want 0.02", got)
+	}
+	if got := store.runs[0].SavedCost; math.Abs(got-0.006) > 1e-9 {
+		t.Errorf("saved %v, want 0.006", got)
+	}
+}
+
+func TestPrecedenceAndOverrides(t *testing.T) {
+	store := &fakeStore{schedules: []*storage.Schedule{
+		{ID: 1, Name: "Always slow", Enabled: true, Miners: []string{"10.0.0.2"}, Action: ActionUnderclock, Frequency: 400,
+			Trigger: TriggerTime, Start: "00:00", End: "00:00"},
+		{ID: 2, Name: "No sun", Enabled: true, Miners: []string{"10.0.0.2"}, Action: ActionPause,
+			Trigger: TriggerSignal, Signal: "solar", Compare: "below", Threshold: 500},
+		{ID: 3, Name: "Expensive", Enabled: true, Miners: []string{"10.0.0.3"}, Action: ActionPause,
+			Trigger: TriggerTariff, MaxRate: 0.25},
+	}}
+	s, fleet, _ := newTestScheduler(store)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+
+	// Pausing beats underclocking, and the tariff is above 0.25
+	s.SetSignal("solar", 100, now)
+	run(s, "10.0.0.2", 15, now, now)
+	if strings.Join(fleet.actions, ", ") != "pause 10.0.0.2, pause 10.0.0.3" {
+		t.Fatalf("unexpected actions %v", fleet.actions)
+	}
+
+	// A run override resumes the miner, a pause override pauses another
+	until := now.Add(30 * time.Minute)
+	store.overrides = []*storage.ScheduleOverride{
+		{MinerIP: "10.0.0.3", Mode: OverrideRun, Until: &until},
+		{MinerIP: "10.0.0.4", Mode: OverridePause},
+	}
+	fleet.actions = nil
+	run(s, "10.0.0.2", 0, now.Add(time.Minute), now.Add(time.Minute))
+	if strings.Join(fleet.actions, ", ") != "resume 10.0.0.3, pause 10.0.0.4" {
+		t.Fatalf("unexpected actions %v", fleet.actions)
+	}
+	if st := s.Status(now).Miners; len(st) != 3 || st[2].IP != "10.0.0.4" || st[2].ScheduleID != 0 || st[2].State != "paused" {
+		t.Errorf("unexpected status %+v", st)
+	}
+
+	// The expired override is deleted and the tariff applies again
+	fleet.actions = nil
+	s.SetSignal("solar", 100, until)
+	run(s, "10.0.0.2", 0, until, until)
+	if strings.Join(fleet.actions, ", ") != "pause 10.0.0.3" || len(store.overrides) != 1 {
+		t.Fatalf("expected the override expired, got %v and %d overrides", fleet.actions, len(store.overrides))
+	}
+}
+
+func TestSignalHold(t *testing.T) {
+	store := &fakeStore{schedules: []*storage.Schedule{{ID: 1, Name: "Surplus", Enabled: true, Miners: []string{"10.0.0.2"},
+		Action: ActionPause, Trigger: TriggerSignal, Signal: "grid", Compare: "above", Threshold: 0}}}
+	s, fleet, _ := newTestScheduler(store)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+
+	s.SetSignal("grid", 300, now)
+	run(s, "10.0.0.2", 15, now, now)
+	s.SetSignal("grid", -200, now.Add(time.Minute))
+	run(s, "10.0.0.2", 15, now.Add(time.Minute), now.Add(9*time.Minute))
+	if len(fleet.actions) != 1 {
+		t.Fatalf("expected the pause held for 10 minutes, got %v", fleet.actions)
+	}
+	run(s, "10.0.0.2", 15, now.Add(10*time.Minute), now.Add(10*time.Minute))
+	if len(fleet.actions) != 2 || fleet.actions[1] != "resume 10.0.0.2" {
+		t.Fatalf("expected the miner resumed after 10 minutes, got %v", fleet.actions)
+	}
+
+	// Nor does it pause again right away
+	s.SetSignal("grid", 300, now.Add(11*time.Minute))
+	run(s, "10.0.0.2", 15, now.Add(11*time.Minute), now.Add(19*time.Minute))
+	if len(fleet.actions) != 2 {
+		t.Fatalf("expected no pause within 10 minutes of resuming, got %v", fleet.actions)
+	}
+	s.SetSignal("grid", 300, now.Add(20*time.Minute))
+	run(s, "10.0.0.2", 15, now.Add(20*time.Minute), now.Add(20*time.Minute))
+	if len(fleet.actions) != 3 {
+		t.Fatalf("expected the miner paused again, got %v", fleet.actions)
+	}
+
+	// A signal that stops arriving no longer applies
+	run(s, "10.0.0.2", 15, now.Add(21*time.Minute), now.Add(31*time.Minute))
+	if len(fleet.actions) != 4 || fleet.actions[3] != "resume 10.0.0.2" {
+		t.Fatalf("expected the miner resumed once the signal went stale, got %v", fleet.actions)
+	}
+}
+
+func TestFailuresAndRestart(t *testing.T) {
+	store := &fakeStore{schedules: []*storage.Schedule{{ID: 1, Name: "Night", Enabled: true, Miners: []string{"10.0.0.2"},
+		Action: ActionPause, Trigger: TriggerTime, Start: "22:00", End: "06:00"}}}
+	s, fleet, cfg := newTestScheduler(store)
+	now := time.Date(2026, 5, 1, 23, 0, 0, 0, time.Local)
+
+	fleet.failing = errors.New("no smart plug")
+	run(s, "10.0.0.2", 15, now, now)
+	if st := s.Status(now).Miners; len(st) != 1 || st[0].State != "mining" || !strings.Contains(st[0].Error, "no smart plug") {
+		t.Fatalf("expected the failure reported, got %+v", st)
+	}
+	fleet.failing = nil
+	run(s, "10.0.0.2", 15, now.Add(time.Minute), now.Add(time.Minute))
+	if len(fleet.actions) != 1 || s.Status(now).Miners[0].Error != "" {
+		t.Fatalf("expected the pause retried, got %v", fleet.actions)
+	}
+
+	// A new scheduler picks the run up where the last one stopped, and
+	// disabling the scheduler resumes the miner
+	s, fleet, cfg = newTestScheduler(store)
+	if st := s.Status(now).Miners; len(st) != 1 || st[0].State != "paused" {
+		t.Fatalf("expected the run in progress loaded, got %+v", st)
+	}
+	cfg.Scheduler.Enabled = false
+	run(s, "10.0.0.2", 0, now.Add(2*time.Minute), now.Add(2*time.Minute))
+	if len(fleet.actions) != 1 || fleet.actions[0] != "resume 10.0.0.2" || store.runs[0].End == nil {
+		t.Fatalf("expected the miner resumed, got %v", fleet.actions)
+	}
+}
+
+func TestSignalValue(t *testing.T) {
+	tests := []struct {
+		payload, field string
+		want           float64
+	}{
+		{" 1234.5\n", "", 1234.5},
+		{`{"power":{"surplus":-80}}`, "power.surplus", -80},
+		{`{"surplus":"42"}`, "surplus", 42},
+	}
+	for _, tt := range tests {
+		if got, err := signalValue([]byte(tt.payload), tt.field); err != nil || got != tt.want {
+			t.Errorf("signalValue(%q, %q) = %v, %v; want %v", tt.payload, tt.field, got, err, tt.want)
+		}
+	}
+	if _, err := signalValue([]byte(`{"power":"on"}`), "power.surplus"); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := storage.Schedule{Name: "Peak", Miners: []string{"10.0.0.2"}, Action: ActionUnderclock, Frequency: 400,
+		Trigger: TriggerTime, Start: "17:00", End: "21:00", Days: []string{"Mon"}}
+	if err := Validate(&valid); err != nil {
+		t.Fatalf("expected a valid schedule, got %v", err)
+	}
+
+	broken := []func(sc *storage.Schedule){
+		func(sc *storage.Schedule) { sc.Miners = []string{"miner-1"} },
+		func(sc *storage.Schedule) { sc.Frequency = 50 },
+		func(sc *storage.Schedule) { sc.CoreVoltage = 2000 },
+		func(sc *storage.Schedule) { sc.End = "25:00" },
+		func(sc *storage.Schedule) { sc.Days = []string{"monday"} },
+		func(sc *storage.Schedule) { sc.Trigger = TriggerSignal },
+		func(sc *storage.Schedule) { sc.Action = "stop" },
+	}
+	for i, breakIt := range broken {
+		sc := valid
+		breakIt(&sc)
+		if err := Validate(&sc); err == nil {
+			t.Errorf("case %d: expected an error for %+v", i, sc)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import "time"
+
+// InsertAlert records an alert in the alert history
+func (s *SQLiteStorage) InsertAlert(a *AlertEntry) error {
+	result, err := s.db.Exec(`
+	INSERT INTO alerts (timestamp, alert_type, miner_ip, miner_name, message, value)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`,
+		a.Timestamp.UTC().Format("2006-01-02 15:04:05"),
+		a.Type, a.MinerIP, a.MinerName, a.Message, a.Value,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err == nil {
+		a.ID = id
+	}
+	return nil
+}
+
+// GetAlerts retrieves alerts raised since a given time, newest first.
+// Empty alertType or minerIP match all alerts.
+func (s *SQLiteStorage) GetAlerts(since time.Time, alertType, minerIP string, limit int) ([]*AlertEntry, error) {
+	rows, err := s.read.Query(`
+	SELECT id, timestamp, alert_type, miner_ip, miner_name, message, value
+	FROM alerts
+	WHERE timestamp >= ?
+	  AND (? = '' OR alert_type = ?)
+	  AND (? = '' OR miner_ip = ?)
+	ORDER BY timestamp DESC, id DESC
+	LIMIT ?
+	`, since.UTC().Format("2006-01-02 15:04:05"), alertType, alertType, minerIP, minerIP, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*AlertEntry
+	for rows.Next() {
+		a := &AlertEntry{}
+		var timestamp string
+		if err := rows.Scan(&a.ID, &timestamp, &a.Type, &a.MinerIP, &a.MinerName, &a.Message, &a.Value); err != nil {
+			return nil, err
+		}
+		a.Timestamp = parseTimestamp(timestamp)
+		alerts = append(alerts, a)
+	}
+
+	return alerts, rows.Err()
+}
+
+// PurgeOldAlerts removes alerts older than the specified number of days
+func (s *SQLiteStorage) PurgeOldAlerts(retentionDays int) (int64, error) {
+	return s.PurgeTable("alerts", time.Now().AddDate(0, 0, -retentionDays))
+}
@@ -0,0 +1,102 @@
+package storage
+
+import "database/sql"
+
+// AlertOverrides replaces the global alert settings for one miner. Nil fields
+// use the global setting; a threshold of 0 turns that alert off for the miner.
+type AlertOverrides struct {
+	TempAbove           *float64 `json:"tempAbove,omitempty"`
+	HashrateDropPercent *float64 `json:"hashrateDropPercent,omitempty"`
+	PoolDiffChangePct   *float64 `json:"poolDiffChangePct,omitempty"`
+	FanRPMBelow         *int     `json:"fanRpmBelow,omitempty"`
+	WifiSignalBelow     *int     `json:"wifiSignalBelow,omitempty"`
+	MinerOfflineSeconds *int     `json:"minerOfflineSeconds,omitempty"`
+	MinerFrozenSeconds  *int     `json:"minerFrozenSeconds,omitempty"`
+	CooldownMinutes     *int     `json:"cooldownMinutes,omitempty"` // How long an alert type stays quiet after firing
+	Discord             *bool    `json:"discord,omitempty"`         // Send to the Discord webhook
+	Matrix              *bool    `json:"matrix,omitempty"`          // Send to the Matrix room
+}
+
+// IsEmpty reports whether the overrides change nothing
+func (o *AlertOverrides) IsEmpty() bool {
+	return o.TempAbove == nil && o.HashrateDropPercent == nil && o.PoolDiffChangePct == nil &&
+		o.FanRPMBelow == nil && o.WifiSignalBelow == nil && o.MinerOfflineSeconds == nil &&
+		o.MinerFrozenSeconds == nil && o.CooldownMinutes == nil && o.Discord == nil && o.Matrix == nil
+}
+
+// GetAlertOverrides returns the alert overrides of every miner that has any,
+// keyed by IP
+func (s *SQLiteStorage) GetAlertOverrides() (map[string]*AlertOverrides, error) {
+	rows, err := s.read.Query(`
+	SELECT miner_ip, temp_above, hashrate_drop_percent, pool_diff_change_pct, fan_rpm_below, wifi_signal_below,
+		miner_offline_seconds, miner_frozen_seconds, cooldown_minutes, discord, matrix
+	FROM miner_alert_overrides
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]*AlertOverrides)
+	for rows.Next() {
+		var ip string
+		var temp, hashDrop, poolDiff sql.NullFloat64
+		var fan, wifi, offline, frozen, cooldown sql.NullInt64
+		var discord, matrix sql.NullBool
+		if err := rows.Scan(&ip, &temp, &hashDrop, &poolDiff, &fan, &wifi, &offline, &frozen, &cooldown, &discord, &matrix); err != nil {
+			return nil, err
+		}
+		overrides[ip] = &AlertOverrides{
+			TempAbove:           nullFloat(temp),
+			HashrateDropPercent: nullFloat(hashDrop),
+			PoolDiffChangePct:   nullFloat(poolDiff),
+			FanRPMBelow:         nullInt(fan),
+			WifiSignalBelow:     nullInt(wifi),
+			MinerOfflineSeconds: nullInt(offline),
+			MinerFrozenSeconds:  nullInt(frozen),
+			CooldownMinutes:     nullInt(cooldown),
+			Discord:             nullBool(discord),
+			Matrix:              nullBool(matrix),
+		}
+	}
+	return overrides, rows.Err()
+}
+
+// SetAlertOverrides replaces a miner's alert overrides. Empty overrides
+// remove them, so the miner follows the global settings again.
+func (s *SQLiteStorage) SetAlertOverrides(minerIP string, o *AlertOverrides) error {
+	if o == nil || o.IsEmpty() {
+		_, err := s.db.Exec("DELETE FROM miner_alert_overrides WHERE miner_ip = ?", minerIP)
+		return err
+	}
+
+	_, err := s.db.Exec(`
+	INSERT OR REPLACE INTO miner_alert_overrides (miner_ip, temp_above, hashrate_drop_percent, pool_diff_change_pct,
+		fan_rpm_below, wifi_signal_below, miner_offline_seconds, miner_frozen_seconds, cooldown_minutes, discord, matrix)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, minerIP, o.TempAbove, o.HashrateDropPercent, o.PoolDiffChangePct, o.FanRPMBelow, o.WifiSignalBelow,
+		o.MinerOfflineSeconds, o.MinerFrozenSeconds, o.CooldownMinutes, o.Discord, o.Matrix)
+	return err
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func nullBool(v sql.NullBool) *bool {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Bool
+}
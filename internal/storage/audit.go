@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"time"
+)
+
+// InsertAuditEntry records a state-changing API call
+func (s *SQLiteStorage) InsertAuditEntry(e *AuditEntry) error {
+	query := `
+	INSERT INTO audit_log (timestamp, username, remote_addr, method, path, action, target, status)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := s.db.Exec(query,
+		e.Timestamp.UTC().Format("2006-01-02 15:04:05"),
+		e.Username, e.RemoteAddr, e.Method, e.Path, e.Action, e.Target, e.Status,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err == nil {
+		e.ID = id
+	}
+	return nil
+}
+
+// GetAuditEntries retrieves audit entries since a given time, newest first.
+// Empty username or target match all entries.
+func (s *SQLiteStorage) GetAuditEntries(since time.Time, username, target string, limit int) ([]*AuditEntry, error) {
+	query := `
+	SELECT id, timestamp, username, remote_addr, method, path, action, target, status
+	FROM audit_log
+	WHERE timestamp >= ?
+	  AND (? = '' OR username = ?)
+	  AND (? = '' OR target = ?)
+	ORDER BY timestamp DESC, id DESC
+	LIMIT ?
+	`
+
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"), username, username, target, target, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		e := &AuditEntry{}
+		var timestamp string
+		err := rows.Scan(&e.ID, &timestamp, &e.Username, &e.RemoteAddr, &e.Method, &e.Path, &e.Action, &e.Target, &e.Status)
+		if err != nil {
+			return nil, err
+		}
+		e.Timestamp = parseTimestamp(timestamp)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
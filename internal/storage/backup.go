@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Backup writes a consistent copy of the database to path, which must not
+// exist yet. The copy is compacted and can be made while MinerHQ is running.
+func (s *SQLiteStorage) Backup(path string) error {
+	if _, err := s.db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// CheckBackup verifies that path holds a healthy MinerHQ database and
+// migrates it to the current schema, so it can be restored. Backups made by
+// older versions are accepted.
+func CheckBackup(path string) error {
+	// Migrating would turn any SQLite file into an empty MinerHQ database,
+	// so check for the miners table first
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("not a valid backup: %w", err)
+	}
+	var tables int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'miners'").Scan(&tables)
+	db.Close()
+	if err != nil {
+		return fmt.Errorf("not a valid backup: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("not a MinerHQ backup")
+	}
+
+	backup, err := NewSQLiteStorage(path)
+	if err != nil {
+		return fmt.Errorf("not a valid backup: %w", err)
+	}
+	defer backup.Close()
+
+	problems, err := backup.IntegrityCheck()
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup is corrupt: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Restore replaces all MinerHQ data with the contents of the backup at path,
+// which must have passed CheckBackup. Every table is swapped in one
+// transaction, so readers see either the old or the restored data and a
+// failed restore changes nothing.
+func (s *SQLiteStorage) Restore(path string) error {
+	// Attached databases belong to a connection, so the whole restore runs
+	// on one
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS backup", path); err != nil {
+		return fmt.Errorf("failed to attach backup: %w", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE backup")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range dataTables {
+		rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+		if err != nil {
+			return err
+		}
+		var columns []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			columns = append(columns, name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Columns are named since tables migrated with ALTER TABLE may
+		// order them differently than freshly created ones
+		list := strings.Join(columns, ", ")
+		if _, err := tx.ExecContext(ctx, "DELETE FROM main."+table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM backup.%s", table, list, list, table)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// BestSharesKept is how many of its best shares each miner keeps in the
+// best_shares table. Unlike the shares table, it is never purged.
+const BestSharesKept = 100
+
+// bestShareColumns are the columns best_shares copies from shares
+const bestShareColumns = "miner_ip, hostname, timestamp, asic_num, difficulty, job_id, nonce, version, network_difficulty"
+
+// keepBestShareQuery copies a share into best_shares if fewer than the
+// number kept of its miner's best shares beat it
+const keepBestShareQuery = `
+	INSERT OR IGNORE INTO best_shares (share_id, ` + bestShareColumns + `)
+	SELECT id, ` + bestShareColumns + ` FROM shares
+	WHERE id = ? AND (SELECT COUNT(*) FROM best_shares WHERE miner_ip = ? AND difficulty >= ?) < ?
+	`
+
+// backfillBestShareQuery copies a miner's best share not yet kept into
+// best_shares if it keeps fewer than the number kept, e.g. after one of its
+// kept shares was rejected
+const backfillBestShareQuery = `
+	INSERT OR IGNORE INTO best_shares (share_id, ` + bestShareColumns + `)
+	SELECT id, ` + bestShareColumns + ` FROM shares
+	WHERE miner_ip = ? AND rejected = 0 AND id NOT IN (SELECT share_id FROM best_shares WHERE miner_ip = ?)
+		AND (SELECT COUNT(*) FROM best_shares WHERE miner_ip = ?) < ?
+	ORDER BY difficulty DESC, id
+	LIMIT 1
+	`
+
+// trimBestSharesQuery drops a miner's best shares beyond the number kept
+const trimBestSharesQuery = `
+	DELETE FROM best_shares
+	WHERE miner_ip = ? AND id NOT IN (
+		SELECT id FROM best_shares WHERE miner_ip = ? ORDER BY difficulty DESC, id LIMIT ?
+	)
+	`
+
+// keepBestShare copies a just-inserted share into best_shares if it is among
+// its miner's BestSharesKept best, dropping the share it displaces. It runs
+// in the transaction that inserted the share.
+func (s *SQLiteStorage) keepBestShare(tx *sql.Tx, share *Share) error {
+	result, err := tx.Stmt(s.stmts.keepBestShare).Exec(share.ID, share.MinerIP, share.Difficulty, BestSharesKept)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	_, err = tx.Stmt(s.stmts.trimBestShares).Exec(share.MinerIP, share.MinerIP, BestSharesKept)
+	return err
+}
+
+// dropBestShare removes a rejected share from best_shares and backfills the
+// miner's next best share still in the database. It runs in the transaction
+// that marked the share rejected.
+func (s *SQLiteStorage) dropBestShare(tx *sql.Tx, shareID int64) error {
+	var minerIP string
+	err := tx.QueryRow("DELETE FROM best_shares WHERE share_id = ? RETURNING miner_ip", shareID).Scan(&minerIP)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(backfillBestShareQuery, minerIP, minerIP, minerIP, BestSharesKept)
+	return err
+}
+
+// seedBestShares fills an empty best_shares table from the shares still in
+// the database, so an upgraded install keeps the records it has
+func (s *SQLiteStorage) seedBestShares() error {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM best_shares").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	_, err := s.db.Exec(`
+	INSERT OR IGNORE INTO best_shares (share_id, `+bestShareColumns+`)
+	SELECT id, `+bestShareColumns+` FROM (
+		SELECT *, ROW_NUMBER() OVER (PARTITION BY miner_ip ORDER BY difficulty DESC, id) AS rank
+		FROM shares
+		WHERE rejected = 0
+	)
+	WHERE rank <= ?
+	`, BestSharesKept)
+	return err
+}
+
+// GetBestShares returns the highest difficulty shares ever kept, of one miner
+// or of every miner when minerIP is empty
+func (s *SQLiteStorage) GetBestShares(minerIP string, limit int) ([]*Share, error) {
+	query := `
+	SELECT share_id, ` + bestShareColumns + `
+	FROM best_shares
+	WHERE ? = '' OR miner_ip = ?
+	ORDER BY difficulty DESC, timestamp
+	LIMIT ?
+	`
+
+	rows, err := s.read.Query(query, minerIP, minerIP, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBestShares(rows)
+}
+
+// GetBestShare retrieves the best (highest difficulty) share for a miner.
+// If sessionOnly is true, only considers shares from the current session
+// (last 24h); otherwise it comes from best_shares, which survives purges.
+func (s *SQLiteStorage) GetBestShare(minerIP string, sessionOnly bool) (*Share, error) {
+	if sessionOnly {
+		return s.GetBestShareInRange(minerIP, time.Now().Add(-24*time.Hour), time.Now())
+	}
+
+	shares, err := s.GetBestShares(minerIP, 1)
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return shares[0], nil
+}
+
+// GetBestShareInRange retrieves the best share for a miner within a time
+// range. Kept best shares count too, so a range reaching back past the share
+// retention still finds the miner's best shares from then.
+func (s *SQLiteStorage) GetBestShareInRange(minerIP string, start, end time.Time) (*Share, error) {
+	query := `
+	SELECT id, ` + bestShareColumns + ` FROM (
+		SELECT id, ` + bestShareColumns + ` FROM shares
+		WHERE miner_ip = ? AND timestamp >= ? AND timestamp <= ? AND rejected = 0
+		UNION ALL
+		SELECT share_id, ` + bestShareColumns + ` FROM best_shares
+		WHERE miner_ip = ? AND timestamp >= ? AND timestamp <= ?
+	)
+	ORDER BY difficulty DESC
+	LIMIT 1
+	`
+
+	from, to := start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.read.Query(query, minerIP, from, to, minerIP, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares, err := scanBestShares(rows)
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return shares[0], nil
+}
+
+// scanBestShares reads share rows selected with bestShareColumns after the
+// share's ID. Rejected shares are never kept, so they have no reject columns.
+func scanBestShares(rows *sql.Rows) ([]*Share, error) {
+	var shares []*Share
+	for rows.Next() {
+		share := &Share{}
+		var timestamp string
+		var networkDiff float64
+		err := rows.Scan(&share.ID, &share.MinerIP, &share.Hostname, &timestamp, &share.AsicNum, &share.Difficulty, &share.JobID, &share.Nonce, &share.Version, &networkDiff)
+		if err != nil {
+			return nil, err
+		}
+		share.Timestamp = parseTimestamp(timestamp)
+		share.SetNetworkDifficulty(networkDiff)
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
@@ -0,0 +1,37 @@
+package storage
+
+// Block statuses recorded by on-chain verification
+const (
+	BlockPending   = "pending"   // Waiting to be looked up on the coin's explorer
+	BlockConfirmed = "confirmed" // In the chain at Height with Hash
+	BlockOrphaned  = "orphaned"  // Not in the chain, so it earned nothing
+)
+
+// GetPendingBlocks returns the blocks waiting for on-chain verification,
+// oldest first
+func (s *SQLiteStorage) GetPendingBlocks() ([]*Block, error) {
+	rows, err := s.read.Query(`
+	SELECT id, miner_ip, hostname, timestamp, difficulty, network_difficulty,
+	       COALESCE(coin_id, ''), COALESCE(coin_symbol, ''), COALESCE(block_reward, 0),
+	       COALESCE(coin_price, 0), COALESCE(value_usd, 0),
+	       block_height, block_hash, status
+	FROM blocks
+	WHERE status = ?
+	ORDER BY timestamp, id
+	`, BlockPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBlocks(rows)
+}
+
+// SetBlockVerification records the outcome of a block's on-chain verification
+func (s *SQLiteStorage) SetBlockVerification(id int64, status string, height int64, hash string) error {
+	_, err := s.db.Exec(`
+	UPDATE blocks SET status = ?, block_height = ?, block_hash = ?
+	WHERE id = ?
+	`, status, height, hash, id)
+	return err
+}
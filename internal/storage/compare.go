@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// PeriodMetric describes how a comparable metric is aggregated per miner
+type PeriodMetric struct {
+	Table   string
+	Expr    string // Per-miner aggregate over the rows in a period
+	Average bool   // Fleet value is the mean of miners rather than the sum
+	Max     bool   // Fleet value is the highest miner value
+}
+
+// PeriodMetrics lists the metrics GetPeriodAggregates understands.
+// Snapshot metrics only reach back as far as snapshots are retained.
+var PeriodMetrics = map[string]PeriodMetric{
+	"hashrate":    {Table: "miner_snapshots", Expr: "AVG(hash_rate)"},
+	"power":       {Table: "miner_snapshots", Expr: "AVG(power)"},
+	"temperature": {Table: "miner_snapshots", Expr: "AVG(temperature)", Average: true},
+	"shares":      {Table: "shares", Expr: "COUNT(*)"},
+	"best_share":  {Table: "shares", Expr: "MAX(difficulty)", Max: true},
+	"blocks":      {Table: "blocks", Expr: "COUNT(*)"},
+	"earnings":    {Table: "blocks", Expr: "SUM(value_usd)"}, // USD
+}
+
+// PeriodAggregate is one miner's value of a metric over a period
+type PeriodAggregate struct {
+	MinerIP string
+	Value   float64
+	Samples int // Rows the value was computed from
+}
+
+// GetPeriodAggregates returns each miner's value of metric over [start, end),
+// keyed by miner IP. Miners without rows in the period are absent.
+func (s *SQLiteStorage) GetPeriodAggregates(metric string, start, end time.Time) (map[string]*PeriodAggregate, error) {
+	m, ok := PeriodMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric: %s", metric)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT miner_ip, COALESCE(%s, 0), COUNT(*)
+	FROM %s
+	WHERE timestamp >= ? AND timestamp < ?
+	GROUP BY miner_ip
+	`, m.Expr, m.Table)
+
+	rows, err := s.read.Query(query, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aggregates := make(map[string]*PeriodAggregate)
+	for rows.Next() {
+		a := &PeriodAggregate{}
+		if err := rows.Scan(&a.MinerIP, &a.Value, &a.Samples); err != nil {
+			return nil, err
+		}
+		aggregates[a.MinerIP] = a
+	}
+
+	return aggregates, rows.Err()
+}
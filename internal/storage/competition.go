@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/competition"
+)
+
+// CompetitionResult is a miner's final standing in one competition period
+type CompetitionResult struct {
+	Period      string    `json:"period"` // "daily", "weekly" or "monthly"
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Rank        int       `json:"rank"`
+	MinerIP     string    `json:"minerIp"`
+	Hostname    string    `json:"hostname"`
+	BestDiff    float64   `json:"bestDiff"`
+	ShareCount  int       `json:"shareCount"`
+	Blocks      int       `json:"blocks"`
+}
+
+// Trophies counts the podium places a miner has finished on
+type Trophies struct {
+	MinerIP  string `json:"minerIp"`
+	Hostname string `json:"hostname"`
+	Gold     int    `json:"gold"`
+	Silver   int    `json:"silver"`
+	Bronze   int    `json:"bronze"`
+	Periods  int    `json:"periods"` // Periods the miner competed in
+}
+
+// FinalizeCompetition stores the final standings of the period from start to
+// end, ranked by scorer, and returns how many miners placed. Kept best shares
+// count, so standings can still be taken after a purge. Miners with equal
+// scores share a rank. A period is only stored once; finalizing it again adds
+// nothing.
+func (s *SQLiteStorage) FinalizeCompetition(period string, start, end time.Time, scorer competition.Scorer) (int, error) {
+	from, to := start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var stored bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM competition_results WHERE period_start = ?)", from).Scan(&stored); err != nil || stored {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`
+	SELECT miner_ip, hostname, best, counted,
+		(SELECT COUNT(*) FROM blocks b WHERE b.miner_ip = t.miner_ip AND b.timestamp >= ? AND b.timestamp < ?)
+	FROM (
+		SELECT miner_ip, MAX(hostname) AS hostname, MAX(difficulty) AS best, SUM(counted) AS counted FROM (
+			SELECT miner_ip, hostname, difficulty, 1 AS counted FROM shares
+			WHERE timestamp >= ? AND timestamp < ? AND rejected = 0
+			UNION ALL
+			SELECT miner_ip, hostname, difficulty, 0 FROM best_shares
+			WHERE timestamp >= ? AND timestamp < ?
+		)
+		GROUP BY miner_ip
+	) t
+	WHERE best > 0
+	`, from, to, from, to, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var standings []*CompetitionResult
+	var scores []float64
+	for rows.Next() {
+		r := &CompetitionResult{}
+		if err := rows.Scan(&r.MinerIP, &r.Hostname, &r.BestDiff, &r.ShareCount, &r.Blocks); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		standings = append(standings, r)
+		scores = append(scores, scorer.Score(r.MinerIP, r.BestDiff))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	order := make([]int, len(standings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if scores[order[a]] != scores[order[b]] {
+			return scores[order[a]] > scores[order[b]]
+		}
+		return standings[order[a]].MinerIP < standings[order[b]].MinerIP
+	})
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO competition_results
+		(period, period_start, period_end, rank, miner_ip, hostname, best_diff, share_count, blocks)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	rank := 0
+	for pos, i := range order {
+		if pos == 0 || scores[i] != scores[order[pos-1]] {
+			rank = pos + 1
+		}
+		r := standings[i]
+		if _, err := stmt.Exec(period, from, to, rank, r.MinerIP, r.Hostname, r.BestDiff, r.ShareCount, r.Blocks); err != nil {
+			return 0, err
+		}
+	}
+	return len(standings), tx.Commit()
+}
+
+// CompetitionScorer returns the scorer of the competition period from start
+// to end under mode, with the miners' configured expected hashrates. Average
+// hashrates are only looked up for handicapped scoring.
+func (s *SQLiteStorage) CompetitionScorer(mode string, expected map[string]float64, start, end time.Time) (competition.Scorer, error) {
+	scorer := competition.Scorer{Mode: mode, Expected: expected}
+	if !scorer.Handicapped() {
+		return scorer, nil
+	}
+	averages, err := s.GetAverageHashrates(start, end)
+	scorer.Average = averages
+	return scorer, err
+}
+
+// GetAverageHashrates returns each miner's average hashrate in GH/s over
+// [start, end), by IP, for "average" competition scoring. Miners without
+// snapshots in the period are absent.
+func (s *SQLiteStorage) GetAverageHashrates(start, end time.Time) (map[string]float64, error) {
+	aggregates, err := s.GetPeriodAggregates("hashrate", start, end)
+	if err != nil {
+		return nil, err
+	}
+	averages := make(map[string]float64, len(aggregates))
+	for ip, a := range aggregates {
+		averages[ip] = a.Value
+	}
+	return averages, nil
+}
+
+// GetCompetitionHistory returns the final standings of the last periods
+// stored, newest period first and by rank within each
+func (s *SQLiteStorage) GetCompetitionHistory(periods int) ([]*CompetitionResult, error) {
+	rows, err := s.read.Query(`
+	SELECT period, period_start, period_end, rank, miner_ip, hostname, best_diff, share_count, blocks
+	FROM competition_results
+	WHERE period_start IN (
+		SELECT DISTINCT period_start FROM competition_results ORDER BY period_start DESC LIMIT ?
+	)
+	ORDER BY period_start DESC, rank, miner_ip
+	`, periods)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*CompetitionResult
+	for rows.Next() {
+		r := &CompetitionResult{}
+		var start, end string
+		if err := rows.Scan(&r.Period, &start, &end, &r.Rank, &r.MinerIP, &r.Hostname, &r.BestDiff, &r.ShareCount, &r.Blocks); err != nil {
+			return nil, err
+		}
+		r.PeriodStart = parseTimestamp(start)
+		r.PeriodEnd = parseTimestamp(end)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetTrophies returns each miner's podium finishes over every stored period,
+// most decorated first
+func (s *SQLiteStorage) GetTrophies() ([]*Trophies, error) {
+	rows, err := s.read.Query(`
+	SELECT miner_ip,
+		(SELECT hostname FROM competition_results latest WHERE latest.miner_ip = c.miner_ip ORDER BY period_start DESC LIMIT 1),
+		SUM(rank = 1), SUM(rank = 2), SUM(rank = 3), COUNT(*)
+	FROM competition_results c
+	GROUP BY miner_ip
+	ORDER BY SUM(rank = 1) DESC, SUM(rank = 2) DESC, SUM(rank = 3) DESC, miner_ip
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trophies []*Trophies
+	for rows.Next() {
+		t := &Trophies{}
+		if err := rows.Scan(&t.MinerIP, &t.Hostname, &t.Gold, &t.Silver, &t.Bronze, &t.Periods); err != nil {
+			return nil, err
+		}
+		trophies = append(trophies, t)
+	}
+	return trophies, rows.Err()
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// InsertFailedDelivery stores a notification that exhausted its retries
+func (s *SQLiteStorage) InsertFailedDelivery(d *FailedDelivery) error {
+	result, err := s.db.Exec(`
+	INSERT INTO failed_deliveries (timestamp, channel, alert_type, miner_ip, payload, attempts, last_error, last_attempt)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		d.Timestamp.UTC().Format("2006-01-02 15:04:05"),
+		d.Channel, d.AlertType, d.MinerIP, d.Payload, d.Attempts, d.LastError,
+		d.LastAttempt.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err == nil {
+		d.ID = id
+	}
+	return nil
+}
+
+// GetFailedDeliveries returns undelivered notifications, newest first
+func (s *SQLiteStorage) GetFailedDeliveries(limit int) ([]*FailedDelivery, error) {
+	rows, err := s.read.Query(`
+	SELECT id, timestamp, channel, alert_type, miner_ip, payload, attempts, last_error, last_attempt
+	FROM failed_deliveries
+	ORDER BY timestamp DESC, id DESC
+	LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*FailedDelivery
+	for rows.Next() {
+		d, err := scanFailedDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// GetFailedDelivery returns one undelivered notification, or nil if it doesn't exist
+func (s *SQLiteStorage) GetFailedDelivery(id int64) (*FailedDelivery, error) {
+	row := s.read.QueryRow(`
+	SELECT id, timestamp, channel, alert_type, miner_ip, payload, attempts, last_error, last_attempt
+	FROM failed_deliveries
+	WHERE id = ?
+	`, id)
+
+	d, err := scanFailedDelivery(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+// scanFailedDelivery reads a failed_deliveries row from rows or a single row
+func scanFailedDelivery(row interface{ Scan(...interface{}) error }) (*FailedDelivery, error) {
+	d := &FailedDelivery{}
+	var timestamp, lastAttempt string
+	if err := row.Scan(&d.ID, &timestamp, &d.Channel, &d.AlertType, &d.MinerIP, &d.Payload, &d.Attempts, &d.LastError, &lastAttempt); err != nil {
+		return nil, err
+	}
+	d.Timestamp = parseTimestamp(timestamp)
+	d.LastAttempt = parseTimestamp(lastAttempt)
+	return d, nil
+}
+
+// RecordFailedDeliveryAttempt counts another unsuccessful replay of a notification
+func (s *SQLiteStorage) RecordFailedDeliveryAttempt(id int64, lastError string, at time.Time) error {
+	_, err := s.db.Exec(`
+	UPDATE failed_deliveries
+	SET attempts = attempts + 1, last_error = ?, last_attempt = ?
+	WHERE id = ?
+	`, lastError, at.UTC().Format("2006-01-02 15:04:05"), id)
+	return err
+}
+
+// DeleteFailedDelivery removes a notification once it is delivered or discarded.
+// It reports whether the notification existed.
+func (s *SQLiteStorage) DeleteFailedDelivery(id int64) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM failed_deliveries WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// AddEnergy adds measured kWh and their cost (at the tariff in effect when
+// they were used) to a miner's counters and to its energy_daily row. The day
+// and month buckets are keyed by the local calendar date of at and restart
+// from zero when it changes.
+func (s *SQLiteStorage) AddEnergy(minerIP string, kwh, cost float64, at time.Time) error {
+	day := at.Format("2006-01-02")
+	month := at.Format("2006-01")
+
+	query := `
+	INSERT INTO energy_counters (miner_ip, total_kwh, total_cost, day, day_kwh, day_cost, month, month_kwh, month_cost, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(miner_ip) DO UPDATE SET
+		total_kwh = total_kwh + excluded.total_kwh,
+		total_cost = total_cost + excluded.total_cost,
+		day_kwh = CASE WHEN day = excluded.day THEN day_kwh + excluded.day_kwh ELSE excluded.day_kwh END,
+		day_cost = CASE WHEN day = excluded.day THEN day_cost + excluded.day_cost ELSE excluded.day_cost END,
+		day = excluded.day,
+		month_kwh = CASE WHEN month = excluded.month THEN month_kwh + excluded.month_kwh ELSE excluded.month_kwh END,
+		month_cost = CASE WHEN month = excluded.month THEN month_cost + excluded.month_cost ELSE excluded.month_cost END,
+		month = excluded.month,
+		updated_at = excluded.updated_at
+	`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, minerIP, kwh, cost, day, kwh, cost, month, kwh, cost, at.UTC().Format("2006-01-02 15:04:05")); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+	INSERT INTO energy_daily (miner_ip, day, kwh, cost)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(miner_ip, day) DO UPDATE SET
+		kwh = kwh + excluded.kwh,
+		cost = cost + excluded.cost
+	`, minerIP, day, kwh, cost)
+	if err != nil {
+		return fmt.Errorf("failed to update daily energy: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetEnergyDaily returns the daily energy usage of all miners from the local
+// calendar day since onwards, oldest first
+func (s *SQLiteStorage) GetEnergyDaily(since time.Time) ([]*EnergyDay, error) {
+	rows, err := s.read.Query(`
+	SELECT miner_ip, day, kwh, cost
+	FROM energy_daily
+	WHERE day >= ?
+	ORDER BY day, miner_ip
+	`, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []*EnergyDay
+	for rows.Next() {
+		d := &EnergyDay{}
+		if err := rows.Scan(&d.MinerIP, &d.Day, &d.KWh, &d.Cost); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
+
+// GetEnergyCounters returns the energy counters for all miners as of now.
+// Day/month values from a previous calendar period are reported as zero.
+func (s *SQLiteStorage) GetEnergyCounters(now time.Time) ([]*EnergyCounter, error) {
+	day := now.Format("2006-01-02")
+	month := now.Format("2006-01")
+
+	query := `
+	SELECT miner_ip, total_kwh, total_cost,
+		?, CASE WHEN day = ? THEN day_kwh ELSE 0 END, CASE WHEN day = ? THEN day_cost ELSE 0 END,
+		?, CASE WHEN month = ? THEN month_kwh ELSE 0 END, CASE WHEN month = ? THEN month_cost ELSE 0 END,
+		updated_at
+	FROM energy_counters
+	ORDER BY miner_ip
+	`
+
+	rows, err := s.read.Query(query, day, day, day, month, month, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counters []*EnergyCounter
+	for rows.Next() {
+		c := &EnergyCounter{}
+		var updatedAt string
+		if err := rows.Scan(&c.MinerIP, &c.TotalKWh, &c.TotalCost, &c.Day, &c.DayKWh, &c.DayCost,
+			&c.Month, &c.MonthKWh, &c.MonthCost, &updatedAt); err != nil {
+			return nil, err
+		}
+		c.UpdatedAt = parseTimestamp(updatedAt)
+		counters = append(counters, c)
+	}
+
+	return counters, rows.Err()
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SnapshotRollup is an hourly average of a miner's snapshots
+type SnapshotRollup struct {
+	MinerIP     string    `json:"minerIp"`
+	Hour        time.Time `json:"hour"`
+	Samples     int       `json:"samples"`
+	HashRate    float64   `json:"hashRate"`    // GH/s, average
+	Temperature float64   `json:"temperature"` // Celsius, average
+	Power       float64   `json:"power"`       // Watts, average
+	BestDiff    float64   `json:"bestDiff"`    // Highest best difficulty reported in the hour
+}
+
+// GetSnapshotRollups returns hourly per-miner averages of snapshots in [start, end)
+func (s *SQLiteStorage) GetSnapshotRollups(start, end time.Time) ([]*SnapshotRollup, error) {
+	query := `
+	SELECT miner_ip, strftime('%Y-%m-%d %H:00:00', timestamp) AS hour, COUNT(*),
+	       AVG(hash_rate), AVG(temperature), AVG(power), MAX(best_diff)
+	FROM miner_snapshots
+	WHERE timestamp >= ? AND timestamp < ?
+	GROUP BY miner_ip, hour
+	ORDER BY hour, miner_ip
+	`
+
+	rows, err := s.read.Query(query, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []*SnapshotRollup
+	for rows.Next() {
+		r := &SnapshotRollup{}
+		var hour string
+		if err := rows.Scan(&r.MinerIP, &hour, &r.Samples, &r.HashRate, &r.Temperature, &r.Power, &r.BestDiff); err != nil {
+			return nil, err
+		}
+		r.Hour = parseTimestamp(hour)
+		rollups = append(rollups, r)
+	}
+
+	return rollups, rows.Err()
+}
+
+// GetExportCursor returns the time up to which snapshots have been staged for
+// export, or the zero time if none have
+func (s *SQLiteStorage) GetExportCursor() (time.Time, error) {
+	var captured string
+	err := s.read.QueryRow("SELECT captured_until FROM export_cursor WHERE id = 1").Scan(&captured)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseTimestamp(captured), nil
+}
+
+// SetExportCursor records that snapshots before t have been staged for export
+func (s *SQLiteStorage) SetExportCursor(t time.Time) error {
+	_, err := s.db.Exec(`
+	INSERT INTO export_cursor (id, captured_until) VALUES (1, ?)
+	ON CONFLICT(id) DO UPDATE SET captured_until = excluded.captured_until
+	`, t.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetSharesInRange returns all shares in [start, end), oldest first
+func (s *SQLiteStorage) GetSharesInRange(start, end time.Time) ([]*Share, error) {
+	query := `
+	SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id, nonce, version, network_difficulty, rejected, reject_reason
+	FROM shares
+	WHERE timestamp >= ? AND timestamp < ?
+	ORDER BY timestamp, id
+	`
+
+	rows, err := s.read.Query(query, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*Share
+	for rows.Next() {
+		share := &Share{}
+		var timestamp string
+		var networkDiff float64
+		err := rows.Scan(&share.ID, &share.MinerIP, &share.Hostname, &timestamp, &share.AsicNum, &share.Difficulty, &share.JobID, &share.Nonce, &share.Version, &networkDiff, &share.Rejected, &share.RejectReason)
+		if err != nil {
+			return nil, err
+		}
+		share.Timestamp = parseTimestamp(timestamp)
+		share.SetNetworkDifficulty(networkDiff)
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// GetBlocksInRange returns all blocks found in [start, end), oldest first
+func (s *SQLiteStorage) GetBlocksInRange(start, end time.Time) ([]*Block, error) {
+	query := `
+	SELECT id, miner_ip, hostname, timestamp, difficulty, network_difficulty,
+	       COALESCE(coin_id, ''), COALESCE(coin_symbol, ''), COALESCE(block_reward, 0),
+	       COALESCE(coin_price, 0), COALESCE(value_usd, 0),
+	       block_height, block_hash, status
+	FROM blocks
+	WHERE timestamp >= ? AND timestamp < ?
+	ORDER BY timestamp, id
+	`
+
+	rows, err := s.read.Query(query, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*Block
+	for rows.Next() {
+		block := &Block{}
+		var timestamp string
+		err := rows.Scan(&block.ID, &block.MinerIP, &block.Hostname, &timestamp,
+			&block.Difficulty, &block.NetworkDifficulty,
+			&block.CoinID, &block.CoinSymbol, &block.BlockReward,
+			&block.CoinPrice, &block.ValueUSD,
+			&block.Height, &block.Hash, &block.Status)
+		if err != nil {
+			return nil, err
+		}
+		block.Timestamp = parseTimestamp(timestamp)
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FleetSample sums the miners' snapshots over one bucket. Each miner counts
+// with its last snapshot in the bucket.
+type FleetSample struct {
+	Timestamp   time.Time // Start of the bucket
+	HashRate1m  float64   // GH/s, summed
+	HashRate10m float64
+	HashRate1h  float64
+	Temperature float64 // °C, averaged
+	Temp2       float64 // °C, averaged over the miners that report it
+	VRTemp      float64
+	Power       float64 // W, summed
+	Miners      int
+}
+
+// GetFleetSamples buckets the snapshots of the given miners from start to end
+// and aggregates each bucket across them in one query, oldest first
+func (s *SQLiteStorage) GetFleetSamples(minerIPs []string, start, end time.Time, bucket time.Duration) ([]*FleetSample, error) {
+	if len(minerIPs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+	SELECT bucket, SUM(hash_rate_1m), SUM(hash_rate_10m), SUM(hash_rate_1h),
+		AVG(temperature), COALESCE(AVG(NULLIF(temperature2, 0)), 0), AVG(vr_temp),
+		SUM(power), COUNT(*)
+	FROM (
+		SELECT (CAST(strftime('%s', timestamp) AS INTEGER) / ?) * ? AS bucket,
+			hash_rate_1m, hash_rate_10m, hash_rate_1h, temperature,
+			COALESCE(temperature2, 0) AS temperature2, vr_temp, power,
+			ROW_NUMBER() OVER (
+				PARTITION BY miner_ip, CAST(strftime('%s', timestamp) AS INTEGER) / ?
+				ORDER BY timestamp DESC, id DESC
+			) AS latest
+		FROM miner_snapshots
+		WHERE timestamp >= ? AND timestamp <= ? AND miner_ip IN (` + placeholders(len(minerIPs)) + `)
+	)
+	WHERE latest = 1
+	GROUP BY bucket
+	ORDER BY bucket
+	`
+
+	size := int64(bucket / time.Second)
+	if size <= 0 {
+		size = 1
+	}
+	args := []interface{}{size, size, size, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")}
+	for _, ip := range minerIPs {
+		args = append(args, ip)
+	}
+
+	rows, err := s.read.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*FleetSample
+	for rows.Next() {
+		f := &FleetSample{}
+		var bucketStart int64
+		if err := rows.Scan(&bucketStart, &f.HashRate1m, &f.HashRate10m, &f.HashRate1h,
+			&f.Temperature, &f.Temp2, &f.VRTemp, &f.Power, &f.Miners); err != nil {
+			return nil, err
+		}
+		f.Timestamp = time.Unix(bucketStart, 0).UTC()
+		samples = append(samples, f)
+	}
+	return samples, rows.Err()
+}
+
+// GetFleetRollups sums the miners' hourly or daily rollups from start to end
+// into fleet samples in one query, oldest first. The rollups only hold the
+// average hashrate, which fills all hashrate fields. A nil minerIPs includes
+// every miner, even removed ones.
+func (s *SQLiteStorage) GetFleetRollups(resolution string, minerIPs []string, start, end time.Time) ([]*FleetSample, error) {
+	table, ok := rollupTables[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unknown resolution: %s", resolution)
+	}
+	if minerIPs != nil && len(minerIPs) == 0 {
+		return nil, nil
+	}
+
+	filter := ""
+	if minerIPs != nil {
+		filter = " AND miner_ip IN (" + placeholders(len(minerIPs)) + ")"
+	}
+	query := fmt.Sprintf(`
+	SELECT timestamp, SUM(hash_rate_avg), AVG(temperature_avg), SUM(power_avg), COUNT(*)
+	FROM %s
+	WHERE timestamp >= ? AND timestamp <= ?%s
+	GROUP BY timestamp
+	ORDER BY timestamp
+	`, table, filter)
+
+	args := []interface{}{start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")}
+	for _, ip := range minerIPs {
+		args = append(args, ip)
+	}
+
+	rows, err := s.read.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*FleetSample
+	for rows.Next() {
+		f := &FleetSample{}
+		var ts string
+		if err := rows.Scan(&ts, &f.HashRate1m, &f.Temperature, &f.Power, &f.Miners); err != nil {
+			return nil, err
+		}
+		f.Timestamp = parseTimestamp(ts)
+		f.HashRate10m = f.HashRate1m
+		f.HashRate1h = f.HashRate1m
+		samples = append(samples, f)
+	}
+	return samples, rows.Err()
+}
+
+// placeholders returns n comma separated query placeholders for an IN list
+func placeholders(n int) string {
+	return "?" + strings.Repeat(", ?", n-1)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RecordHostname stores a miner's hostname if it differs from the last one
+// recorded. It returns the previous hostname ("" if none) and whether a
+// change was recorded.
+func (s *SQLiteStorage) RecordHostname(minerIP, hostname string, at time.Time) (string, bool, error) {
+	var previous string
+	err := s.read.QueryRow(`
+	SELECT hostname FROM hostname_history
+	WHERE miner_ip = ?
+	ORDER BY first_seen DESC, id DESC
+	LIMIT 1
+	`, minerIP).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return "", false, err
+	}
+
+	if previous == hostname {
+		return previous, false, nil
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO hostname_history (miner_ip, hostname, first_seen) VALUES (?, ?, ?)",
+		minerIP, hostname, at.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return previous, false, err
+	}
+	return previous, true, nil
+}
+
+// GetHostnameHistory returns every hostname a miner has reported, newest first
+func (s *SQLiteStorage) GetHostnameHistory(minerIP string) ([]*HostnameChange, error) {
+	query := `
+	SELECT miner_ip, hostname, first_seen
+	FROM hostname_history
+	WHERE miner_ip = ?
+	ORDER BY first_seen DESC, id DESC
+	`
+
+	rows, err := s.read.Query(query, minerIP)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*HostnameChange
+	for rows.Next() {
+		c := &HostnameChange{}
+		var firstSeen string
+		if err := rows.Scan(&c.MinerIP, &c.Hostname, &firstSeen); err != nil {
+			return nil, err
+		}
+		c.FirstSeen = parseTimestamp(firstSeen)
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
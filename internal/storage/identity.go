@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// minerIPTables lists the tables keyed by a miner's IP, which MoveMiner
+// rewrites when a miner changes address
+var minerIPTables = []string{
+	"miner_snapshots", "shares", "best_shares", "blocks", "energy_counters",
+	"hostname_history", "pool_difficulty_changes", "records", "competition_results",
+	"miner_logs", "failed_deliveries", "alerts", "snapshots_hourly", "snapshots_daily",
+	"energy_daily", "miner_tags", "miner_alert_overrides", "uptime_events",
+	"schedule_runs", "schedule_overrides", "maintenance_windows",
+	"luck_daily",
+}
+
+// GetMinerIPByMAC returns the IP of the miner registered with a MAC address
+// at any IP other than exceptIP, or "" if there is none. MAC addresses are
+// compared ignoring case and separators.
+func (s *SQLiteStorage) GetMinerIPByMAC(mac, exceptIP string) (string, error) {
+	mac = normalizeMAC(mac)
+	if mac == "" {
+		return "", nil
+	}
+
+	var ip string
+	err := s.read.QueryRow(`
+	SELECT ip FROM miners
+	WHERE REPLACE(REPLACE(UPPER(mac_addr), '-', ':'), '.', ':') = ? AND ip != ?
+	ORDER BY enabled DESC, last_seen DESC
+	LIMIT 1
+	`, mac, exceptIP).Scan(&ip)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ip, err
+}
+
+// normalizeMAC writes a MAC address as upper case with colons
+func normalizeMAC(mac string) string {
+	return strings.NewReplacer("-", ":", ".", ":").Replace(strings.ToUpper(strings.TrimSpace(mac)))
+}
+
+// MoveMiner moves a miner that changed IP, along with all of its history and
+// settings, from oldIP to newIP in one transaction. A record already at newIP,
+// e.g. from a scan that found the miner before it was recognized, is replaced:
+// the old record is the canonical one. The moved miner is enabled unless it
+// was paused. A record at newIP with a different MAC address belongs to
+// another miner and is not replaced.
+func (s *SQLiteStorage) MoveMiner(oldIP, newIP string) error {
+	if oldIP == newIP {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldMAC, newMAC string
+	err = tx.QueryRow("SELECT COALESCE(mac_addr, '') FROM miners WHERE ip = ?", oldIP).Scan(&oldMAC)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no miner at %s", oldIP)
+	} else if err != nil {
+		return err
+	}
+	err = tx.QueryRow("SELECT COALESCE(mac_addr, '') FROM miners WHERE ip = ?", newIP).Scan(&newMAC)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if newMAC != "" && normalizeMAC(newMAC) != normalizeMAC(oldMAC) {
+		return fmt.Errorf("%s is registered to another miner (%s)", newIP, newMAC)
+	}
+
+	// Rows of newIP that clash with the old miner's, such as its energy
+	// counters, give way to the old miner's
+	for _, table := range minerIPTables {
+		if _, err := tx.Exec("UPDATE OR REPLACE "+table+" SET miner_ip = ? WHERE miner_ip = ?", newIP, oldIP); err != nil {
+			return fmt.Errorf("failed to move %s: %w", table, err)
+		}
+	}
+	if err := moveScheduledMiner(tx, oldIP, newIP); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM miners WHERE ip = ?", newIP); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE miners SET ip = ?, enabled = 1 - paused WHERE ip = ?", newIP, oldIP); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// moveScheduledMiner replaces oldIP with newIP in the schedules' miner
+// lists, which are JSON arrays rather than rows keyed by IP
+func moveScheduledMiner(tx *sql.Tx, oldIP, newIP string) error {
+	rows, err := tx.Query("SELECT id, miners FROM schedules")
+	if err != nil {
+		return err
+	}
+	moved := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var list string
+		if err := rows.Scan(&id, &list); err != nil {
+			rows.Close()
+			return err
+		}
+		var miners []string
+		json.Unmarshal([]byte(list), &miners)
+
+		// A list naming both IPs keeps newIP once
+		found := false
+		seen := make(map[string]bool, len(miners))
+		updated := make([]string, 0, len(miners))
+		for _, ip := range miners {
+			if ip == oldIP {
+				found = true
+				ip = newIP
+			}
+			if seen[ip] {
+				continue
+			}
+			seen[ip] = true
+			updated = append(updated, ip)
+		}
+		if found {
+			moved[id] = updated
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, miners := range moved {
+		list, _ := json.Marshal(miners)
+		if _, err := tx.Exec("UPDATE schedules SET miners = ? WHERE id = ?", string(list), id); err != nil {
+			return fmt.Errorf("failed to move schedule %d: %w", id, err)
+		}
+	}
+	return nil
+}
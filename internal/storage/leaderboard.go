@@ -0,0 +1,156 @@
+package storage
+
+import "time"
+
+// LeaderboardEntry is a miner's standing in a running competition period
+type LeaderboardEntry struct {
+	MinerIP      string
+	Hostname     string
+	BestDiff     float64 // Best accepted share within the period
+	ShareCount   int     // Shares submitted within the period
+	PersonalBest float64 // Best kept share of all time
+	Blocks       int     // Blocks found within the period
+}
+
+// BlockLeaderboardEntry is a miner's block count within a period and overall
+type BlockLeaderboardEntry struct {
+	MinerIP       string
+	Hostname      string
+	BlocksInRange int
+	BlocksAllTime int
+	Streak        int // Consecutive weeks with a block, up to the current one
+}
+
+// GetWeeklyLeaderboard returns every enabled miner with an accepted share
+// from start to end, best share first, in one query. Kept best shares count,
+// like in FinalizeCompetition.
+func (s *SQLiteStorage) GetWeeklyLeaderboard(start, end time.Time) ([]*LeaderboardEntry, error) {
+	query := `
+	SELECT m.ip, m.hostname, best.diff,
+		(SELECT COUNT(*) FROM shares c WHERE c.miner_ip = m.ip AND c.timestamp >= ? AND c.timestamp <= ?),
+		COALESCE((SELECT MAX(difficulty) FROM best_shares a WHERE a.miner_ip = m.ip), 0),
+		(SELECT COUNT(*) FROM blocks b WHERE b.miner_ip = m.ip AND b.timestamp >= ? AND b.timestamp <= ?)
+	FROM miners m
+	JOIN (
+		SELECT miner_ip, MAX(difficulty) AS diff FROM (
+			SELECT miner_ip, difficulty FROM shares
+			WHERE timestamp >= ? AND timestamp <= ? AND rejected = 0
+			UNION ALL
+			SELECT miner_ip, difficulty FROM best_shares
+			WHERE timestamp >= ? AND timestamp <= ?
+		)
+		GROUP BY miner_ip
+	) best ON best.miner_ip = m.ip
+	WHERE m.enabled = 1 AND best.diff > 0
+	ORDER BY best.diff DESC, m.ip
+	`
+
+	from, to := start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.read.Query(query, from, to, from, to, from, to, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*LeaderboardEntry
+	for rows.Next() {
+		e := &LeaderboardEntry{}
+		if err := rows.Scan(&e.MinerIP, &e.Hostname, &e.BestDiff, &e.ShareCount, &e.PersonalBest, &e.Blocks); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetBlockLeaderboard returns every enabled miner that ever found a block,
+// most blocks from start to end first and then most blocks overall
+func (s *SQLiteStorage) GetBlockLeaderboard(start, end time.Time) ([]*BlockLeaderboardEntry, error) {
+	query := `
+	SELECT m.ip, m.hostname,
+		SUM(b.timestamp >= ? AND b.timestamp <= ?) AS in_range,
+		COUNT(*) AS all_time
+	FROM miners m
+	JOIN blocks b ON b.miner_ip = m.ip
+	WHERE m.enabled = 1
+	GROUP BY m.ip
+	ORDER BY in_range DESC, all_time DESC, m.ip
+	`
+
+	from, to := start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.read.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*BlockLeaderboardEntry
+	for rows.Next() {
+		e := &BlockLeaderboardEntry{}
+		if err := rows.Scan(&e.MinerIP, &e.Hostname, &e.BlocksInRange, &e.BlocksAllTime); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	streaks, err := s.getBlockStreaks()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		e.Streak = streaks[e.MinerIP]
+	}
+	return entries, nil
+}
+
+// getBlockStreaks returns each miner's block streak, keyed by miner IP
+func (s *SQLiteStorage) getBlockStreaks() (map[string]int, error) {
+	rows, err := s.read.Query(`SELECT miner_ip, timestamp FROM blocks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timestamps := make(map[string][]time.Time)
+	for rows.Next() {
+		var ip, ts string
+		if err := rows.Scan(&ip, &ts); err != nil {
+			return nil, err
+		}
+		timestamps[ip] = append(timestamps[ip], parseTimestamp(ts))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	streaks := make(map[string]int, len(timestamps))
+	for ip, ts := range timestamps {
+		streaks[ip] = blockStreak(ts, now)
+	}
+	return streaks, nil
+}
+
+// blockStreak counts the consecutive weeks, starting Sunday, with at least
+// one of the timestamps, going back from the week of now
+func blockStreak(timestamps []time.Time, now time.Time) int {
+	weekOf := func(t time.Time) string {
+		return time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday()), 0, 0, 0, 0, t.Location()).Format("2006-01-02")
+	}
+
+	weeksWithBlocks := make(map[string]bool)
+	for _, ts := range timestamps {
+		weeksWithBlocks[weekOf(ts)] = true
+	}
+
+	streak := 0
+	week := time.Date(now.Year(), now.Month(), now.Day()-int(now.Weekday()), 0, 0, 0, 0, now.Location())
+	for weeksWithBlocks[week.Format("2006-01-02")] {
+		streak++
+		week = week.AddDate(0, 0, -7)
+	}
+	return streak
+}
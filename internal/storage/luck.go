@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// hashesPerDifficulty is the expected number of hashes per share of
+// difficulty 1
+const hashesPerDifficulty = 1 << 32
+
+// LuckDay compares the shares a miner found on a local day with what its
+// hashrate should have found
+type LuckDay struct {
+	MinerIP        string  `json:"minerIp"`
+	Day            string  `json:"day"`            // Local date, YYYY-MM-DD
+	Hashes         float64 `json:"hashes"`         // Work done, from the hourly hashrate averages
+	Threshold      float64 `json:"threshold"`      // Difficulty counted shares reach: the day's highest pool difficulty
+	ExpectedShares float64 `json:"expectedShares"` // Shares at or above Threshold the work should find
+	Shares         int64   `json:"shares"`         // Shares found at or above Threshold
+	BestDiff       float64 `json:"bestDiff"`       // Best accepted share of the day
+	ExpectedBest   float64 `json:"expectedBest"`   // Difficulty the work should reach once
+}
+
+// UpdateLuck records each miner's luck on the local day before now. Miners
+// log every ASIC result, including those below the pool difficulty, so only
+// shares at or above the day's highest pool difficulty are counted; below
+// it some results may not be logged. Called hourly, it settles the day as
+// its last hours are rolled up. A day is only rewritten with more work or,
+// for the same work, more shares, so the share purge can't shrink it.
+func (s *SQLiteStorage) UpdateLuck(now time.Time) error {
+	local := now.Local()
+	end := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	start := end.AddDate(0, 0, -1)
+	from, to := start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")
+
+	rows, err := s.read.Query(`
+	SELECT miner_ip, SUM(hash_rate_avg) * 3600e9 FROM snapshots_hourly
+	WHERE timestamp >= ? AND timestamp < ? AND samples > 0
+	GROUP BY miner_ip
+	`, from, to)
+	if err != nil {
+		return err
+	}
+	var days []*LuckDay
+	for rows.Next() {
+		d := &LuckDay{Day: start.Format("2006-01-02")}
+		if err := rows.Scan(&d.MinerIP, &d.Hashes); err != nil {
+			rows.Close()
+			return err
+		}
+		days = append(days, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range days {
+		// The pool difficulty in effect at the start of the day and every
+		// one it changed to during the day
+		var threshold sql.NullFloat64
+		err := s.read.QueryRow(`
+		SELECT MAX(difficulty) FROM (
+			SELECT new_difficulty AS difficulty FROM pool_difficulty_changes
+			WHERE miner_ip = ? AND timestamp >= ? AND timestamp < ?
+			UNION ALL
+			SELECT * FROM (
+				SELECT new_difficulty FROM pool_difficulty_changes
+				WHERE miner_ip = ? AND timestamp < ?
+				ORDER BY timestamp DESC, id DESC
+				LIMIT 1
+			)
+		)
+		`, d.MinerIP, from, to, d.MinerIP, from).Scan(&threshold)
+		if err != nil {
+			return err
+		}
+		if !threshold.Valid || threshold.Float64 <= 0 || d.Hashes <= 0 {
+			continue
+		}
+		d.Threshold = threshold.Float64
+
+		err = s.read.QueryRow(`
+		SELECT COALESCE(SUM(difficulty >= ?), 0), COALESCE(MAX(CASE WHEN rejected = 0 THEN difficulty END), 0)
+		FROM shares
+		WHERE miner_ip = ? AND timestamp >= ? AND timestamp < ?
+		`, d.Threshold, d.MinerIP, from, to).Scan(&d.Shares, &d.BestDiff)
+		if err != nil {
+			return err
+		}
+		d.ExpectedShares = d.Hashes / (d.Threshold * hashesPerDifficulty)
+		d.ExpectedBest = d.Hashes / hashesPerDifficulty
+
+		_, err = s.db.Exec(`
+		INSERT INTO luck_daily (miner_ip, day, hashes, threshold, expected_shares, shares, best_diff, expected_best)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(miner_ip, day) DO UPDATE SET
+			hashes = excluded.hashes, threshold = excluded.threshold, expected_shares = excluded.expected_shares,
+			shares = excluded.shares, best_diff = excluded.best_diff, expected_best = excluded.expected_best
+		WHERE excluded.hashes > luck_daily.hashes
+			OR (excluded.hashes = luck_daily.hashes AND excluded.shares > luck_daily.shares)
+		`, d.MinerIP, d.Day, d.Hashes, d.Threshold, d.ExpectedShares, d.Shares, d.BestDiff, d.ExpectedBest)
+		if err != nil {
+			return fmt.Errorf("failed to update luck of %s: %w", d.MinerIP, err)
+		}
+	}
+	return nil
+}
+
+// GetLuckDays returns the recorded luck from the local day since onwards,
+// oldest first. An empty minerIP returns every miner.
+func (s *SQLiteStorage) GetLuckDays(minerIP string, since time.Time) ([]*LuckDay, error) {
+	rows, err := s.read.Query(`
+	SELECT miner_ip, day, hashes, threshold, expected_shares, shares, best_diff, expected_best
+	FROM luck_daily
+	WHERE day >= ? AND (? = '' OR miner_ip = ?)
+	ORDER BY day, miner_ip
+	`, since.Format("2006-01-02"), minerIP, minerIP)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []*LuckDay
+	for rows.Next() {
+		d := &LuckDay{}
+		if err := rows.Scan(&d.MinerIP, &d.Day, &d.Hashes, &d.Threshold, &d.ExpectedShares, &d.Shares, &d.BestDiff, &d.ExpectedBest); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
@@ -0,0 +1,67 @@
+package storage
+
+import "time"
+
+// MaintenanceWindow silences a miner's alerts while it is being worked on.
+// Block found alerts are still sent.
+type MaintenanceWindow struct {
+	ID      int64     `json:"id"`
+	MinerIP string    `json:"minerIp"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// Active reports whether the window covers a given time
+func (w *MaintenanceWindow) Active(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// GetMaintenanceWindows returns every maintenance window, latest start first
+func (s *SQLiteStorage) GetMaintenanceWindows() ([]*MaintenanceWindow, error) {
+	rows, err := s.read.Query(`
+	SELECT id, miner_ip, start_at, end_at, note FROM maintenance_windows
+	ORDER BY start_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []*MaintenanceWindow
+	for rows.Next() {
+		w := &MaintenanceWindow{}
+		var start, end string
+		if err := rows.Scan(&w.ID, &w.MinerIP, &start, &end, &w.Note); err != nil {
+			return nil, err
+		}
+		w.Start = parseTimestamp(start)
+		w.End = parseTimestamp(end)
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// InsertMaintenanceWindow stores a maintenance window, setting its ID
+func (s *SQLiteStorage) InsertMaintenanceWindow(w *MaintenanceWindow) error {
+	result, err := s.db.Exec(`
+	INSERT INTO maintenance_windows (miner_ip, start_at, end_at, note)
+	VALUES (?, ?, ?, ?)
+	`, w.MinerIP, w.Start.UTC().Format("2006-01-02 15:04:05"), w.End.UTC().Format("2006-01-02 15:04:05"), w.Note)
+	if err != nil {
+		return err
+	}
+	w.ID, err = result.LastInsertId()
+	return err
+}
+
+// DeleteMaintenanceWindow removes a maintenance window. It reports false if
+// there is no such window.
+func (s *SQLiteStorage) DeleteMaintenanceWindow(id int64) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM maintenance_windows WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// MinerLogLine is one raw log line streamed by a miner over its WebSocket
+type MinerLogLine struct {
+	MinerIP   string    `json:"minerIp"`
+	Timestamp time.Time `json:"timestamp"`
+	Line      string    `json:"line"`
+}
+
+// InsertMinerLogs stores a batch of miner log lines in one transaction
+func (s *SQLiteStorage) InsertMinerLogs(lines []*MinerLogLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO miner_logs (miner_ip, timestamp, line) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, l := range lines {
+		if _, err := stmt.Exec(l.MinerIP, l.Timestamp.UTC().Format("2006-01-02 15:04:05"), l.Line); err != nil {
+			return fmt.Errorf("failed to insert log line of %s: %w", l.MinerIP, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetMinerLogs returns a miner's last stored log lines, oldest first
+func (s *SQLiteStorage) GetMinerLogs(minerIP string, limit int) ([]*MinerLogLine, error) {
+	rows, err := s.read.Query(`
+	SELECT miner_ip, timestamp, line FROM (
+		SELECT id, miner_ip, timestamp, line FROM miner_logs
+		WHERE miner_ip = ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	)
+	ORDER BY timestamp, id
+	`, minerIP, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*MinerLogLine
+	for rows.Next() {
+		l := &MinerLogLine{}
+		var timestamp string
+		if err := rows.Scan(&l.MinerIP, &timestamp, &l.Line); err != nil {
+			return nil, err
+		}
+		l.Timestamp = parseTimestamp(timestamp)
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// PurgeOldMinerLogs removes miner log lines older than the specified number
+// of days
+func (s *SQLiteStorage) PurgeOldMinerLogs(retentionDays int) (int64, error) {
+	return s.PurgeTable("miner_logs", time.Now().AddDate(0, 0, -retentionDays))
+}
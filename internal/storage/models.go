@@ -14,10 +14,13 @@ type MinerSnapshot struct {
 	HashRate1h    float64   `json:"hashRate1h"`    // 1 hour average
 	HashRate1d    float64   `json:"hashRate1d"`    // 1 day average
 	Temperature   float64   `json:"temperature"`   // Celsius
+	Temperature2  float64   `json:"temperature2"`  // Secondary sensor (multi-board units), 0 if absent
 	VRTemp        float64   `json:"vrTemp"`
-	Power         float64   `json:"power"`         // Watts
+	Power         float64   `json:"power"`         // Watts, after per-miner calibration
+	PowerRaw      float64   `json:"powerRaw"`      // Watts as reported by the firmware
 	Voltage       float64   `json:"voltage"`
 	FanRPM        int       `json:"fanRpm"`
+	Fan2RPM       int       `json:"fan2Rpm"` // Secondary fan, 0 if absent
 	FanPercent    int       `json:"fanPercent"`
 	SharesAccept  int64     `json:"sharesAccepted"`
 	SharesReject  int64     `json:"sharesRejected"`
@@ -29,6 +32,8 @@ type MinerSnapshot struct {
 	WifiRSSI         int   `json:"wifiRssi"`
 	FoundBlocks      int   `json:"foundBlocks"`
 	TotalFoundBlocks int   `json:"totalFoundBlocks"`
+	FrozenSecs       int64 `json:"frozenSeconds"` // How long the miner has repeated identical data, not stored
+	PlugPower        bool  `json:"plugPower,omitempty"` // Power was read from the miner's smart plug, not stored
 }
 
 type Share struct {
@@ -39,6 +44,24 @@ type Share struct {
 	AsicNum    int       `json:"asicNum"`
 	Difficulty float64   `json:"difficulty"`
 	JobID      string    `json:"jobId"`
+	Nonce      uint32    `json:"nonce"`   // Winning nonce reported by the ASIC
+	Version    uint32    `json:"version"` // Block version (with rolled bits)
+
+	Rejected     bool   `json:"rejected"`               // The pool rejected the share
+	RejectReason string `json:"rejectReason,omitempty"` // The pool's reason, e.g. "Stale"
+
+	NetworkDifficulty float64 `json:"networkDifficulty"` // Network difficulty at submission, 0 if unknown
+	NetworkPct        float64 `json:"networkPct"`        // Difficulty as a percentage of NetworkDifficulty ("0.8% of a block")
+}
+
+// SetNetworkDifficulty records the network difficulty at submission time and
+// the share's percentage of it
+func (s *Share) SetNetworkDifficulty(networkDiff float64) {
+	s.NetworkDifficulty = networkDiff
+	s.NetworkPct = 0
+	if networkDiff > 0 {
+		s.NetworkPct = s.Difficulty / networkDiff * 100
+	}
 }
 
 type Miner struct {
@@ -47,9 +70,68 @@ type Miner struct {
 	DeviceModel string    `json:"deviceModel"`
 	ASICModel   string    `json:"asicModel"`
 	Enabled     bool      `json:"enabled"`
+	Paused      bool      `json:"paused"` // Disabled with the enable/disable API rather than removed
 	LastSeen    time.Time `json:"lastSeen"`
 	Online      bool      `json:"online"`
 	CoinID      string    `json:"coinId"` // Per-miner coin override ("", "btc", "dgb", etc)
+
+	FirmwareVersion string `json:"firmwareVersion"` // AxeOS or NerdQAxe firmware version, empty if unknown
+	MacAddr         string `json:"macAddr"`         // Network MAC address, empty if unknown
+	Location        string `json:"location"`        // Energy location for per-meter rates, empty = default
+	Site            string `json:"site,omitempty"`  // Site of a miner reported by a remote agent, empty = local
+	PoolURL         string `json:"poolUrl"`         // Stratum URL the miner is configured for, empty if unknown
+	PoolPort        int    `json:"poolPort"`        // Stratum port, 0 if unknown
+	Driver          string `json:"driver"`          // API the miner is polled with, "axeos" or "cgminer"; empty if not polled yet
+
+	// Power calibration against a wall meter: watts = reported*PowerMultiplier + PowerOffset
+	PowerMultiplier float64 `json:"powerMultiplier"`
+	PowerOffset     float64 `json:"powerOffset"`
+
+	MinerDetails
+}
+
+// MinerDetails are what the user tells about a miner, beyond what it reports
+type MinerDetails struct {
+	DisplayName  string `json:"displayName"`  // Shown instead of the hostname, empty = hostname
+	PurchaseDate string `json:"purchaseDate"` // "2006-01-02", empty if unknown
+	Notes        string `json:"notes"`
+}
+
+// Name returns what the miner is called: its display name, or else its
+// hostname
+func (m *Miner) Name() string {
+	if m.DisplayName != "" {
+		return m.DisplayName
+	}
+	return m.Hostname
+}
+
+// PowerCalibration converts firmware-reported wattage to measured wattage
+type PowerCalibration struct {
+	Multiplier float64 `json:"multiplier"` // 1.0 = no correction
+	Offset     float64 `json:"offset"`     // Watts added after scaling
+}
+
+// Apply returns the calibrated power for a reported reading. A zero
+// multiplier is treated as 1 so an unset calibration is a no-op.
+func (c PowerCalibration) Apply(watts float64) float64 {
+	if watts <= 0 {
+		return watts
+	}
+	mult := c.Multiplier
+	if mult == 0 {
+		mult = 1
+	}
+	calibrated := watts*mult + c.Offset
+	if calibrated < 0 {
+		return 0
+	}
+	return calibrated
+}
+
+// PowerCalibration returns the miner's calibration settings
+func (m *Miner) PowerCalibration() PowerCalibration {
+	return PowerCalibration{Multiplier: m.PowerMultiplier, Offset: m.PowerOffset}
 }
 
 // Block represents a found block event
@@ -66,4 +148,97 @@ type Block struct {
 	BlockReward float64 `json:"blockReward"` // Coins earned (e.g., 274.28 DGB)
 	CoinPrice   float64 `json:"coinPrice"`   // USD price at time of block
 	ValueUSD    float64 `json:"valueUsd"`    // Total USD value (reward * price)
+	// On-chain verification, for coins with a block explorer
+	Height int64  `json:"height"` // Block height, 0 if unknown
+	Hash   string `json:"hash"`   // Block hash once confirmed
+	Status string `json:"status"` // "pending", "confirmed", "orphaned", or "" if not verified
+}
+
+// AuditEntry records a state-changing API call
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Username   string    `json:"username"`   // Authenticated user, empty when auth is disabled
+	RemoteAddr string    `json:"remoteAddr"` // Client address
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Action     string    `json:"action"` // Route pattern, e.g. "DELETE /api/miners/{ip}"
+	Target     string    `json:"target"` // Affected miner IP, if any
+	Status     int       `json:"status"` // HTTP response status
+}
+
+// AlertEntry is an alert raised by the alert engine, kept as alert history
+type AlertEntry struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"` // e.g. "temp_high", "miner_offline"
+	MinerIP   string    `json:"minerIp"`
+	MinerName string    `json:"minerName"`
+	Message   string    `json:"message"`
+	Value     float64   `json:"value,omitempty"` // Measurement that triggered the alert, if any
+}
+
+// FailedDelivery is an alert notification that could not be delivered after
+// all retries, kept so it can be inspected and replayed
+type FailedDelivery struct {
+	ID          int64     `json:"id"`
+	Timestamp   time.Time `json:"timestamp"` // When the alert was raised
+	Channel     string    `json:"channel"`   // "discord", "matrix" or a notifier name
+	AlertType   string    `json:"alertType"`
+	MinerIP     string    `json:"minerIp"`
+	Payload     string    `json:"payload"` // Request body, sent again as-is on replay
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError"`
+	LastAttempt time.Time `json:"lastAttempt"`
+}
+
+// EnergyCounter holds measured energy usage and cost for a miner. Day and
+// month are local calendar keys ("2006-01-02", "2006-01"); their values reset
+// when the calendar rolls over. Costs use the tariff in effect at the time.
+type EnergyCounter struct {
+	MinerIP   string    `json:"minerIp"`
+	TotalKWh  float64   `json:"totalKwh"`
+	TotalCost float64   `json:"totalCost"`
+	Day       string    `json:"day"`
+	DayKWh    float64   `json:"dayKwh"`
+	DayCost   float64   `json:"dayCost"`
+	Month     string    `json:"month"`
+	MonthKWh  float64   `json:"monthKwh"`
+	MonthCost float64   `json:"monthCost"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// EnergyDay is a miner's measured energy usage and cost on one local
+// calendar day ("2006-01-02")
+type EnergyDay struct {
+	MinerIP string  `json:"minerIp"`
+	Day     string  `json:"day"`
+	KWh     float64 `json:"kwh"`
+	Cost    float64 `json:"cost"`
+}
+
+// PoolDifficultyChange records a pool difficulty adjustment seen in a miner's
+// snapshots. OldDifficulty is 0 for the first difficulty recorded for a miner.
+type PoolDifficultyChange struct {
+	MinerIP       string    `json:"minerIp"`
+	Hostname      string    `json:"hostname"`
+	Timestamp     time.Time `json:"timestamp"`
+	OldDifficulty float64   `json:"oldDifficulty"`
+	NewDifficulty float64   `json:"newDifficulty"`
+}
+
+// ChangePercent returns the relative size of the adjustment, e.g. 100 for a
+// doubling and -50 for a halving. It is 0 for a miner's first record.
+func (c *PoolDifficultyChange) ChangePercent() float64 {
+	if c.OldDifficulty <= 0 {
+		return 0
+	}
+	return (c.NewDifficulty - c.OldDifficulty) / c.OldDifficulty * 100
+}
+
+// HostnameChange records a hostname a miner started reporting at FirstSeen
+type HostnameChange struct {
+	MinerIP   string    `json:"minerIp"`
+	Hostname  string    `json:"hostname"`
+	FirstSeen time.Time `json:"firstSeen"`
 }
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageCursor marks the last row of a page of newest-first results; the next
+// page starts after it. Keying on the row rather than an offset keeps pages
+// stable while new rows arrive. The zero PageCursor starts at the first page.
+type PageCursor struct {
+	Timestamp time.Time
+	ID        int64  // Tie-breaker for rows in the same second
+	MinerIP   string // Tie-breaker for rollups, which have no id
+}
+
+// IsZero reports whether the cursor starts at the first page
+func (c PageCursor) IsZero() bool {
+	return c.Timestamp.IsZero()
+}
+
+// String encodes the cursor for use in a URL
+func (c PageCursor) String() string {
+	raw := fmt.Sprintf("%d|%d|%s", c.Timestamp.Unix(), c.ID, c.MinerIP)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParsePageCursor decodes a cursor encoded with String. An empty string is
+// the first page.
+func ParsePageCursor(s string) (PageCursor, error) {
+	if s == "" {
+		return PageCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return PageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return PageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || unix <= 0 {
+		return PageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return PageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	return PageCursor{Timestamp: time.Unix(unix, 0).UTC(), ID: id, MinerIP: parts[2]}, nil
+}
+
+// timestamp returns the cursor's timestamp as stored, or "" for the first page
+func (c PageCursor) timestamp() string {
+	if c.IsZero() {
+		return ""
+	}
+	return c.Timestamp.UTC().Format("2006-01-02 15:04:05")
+}
+
+// GetSharesPage returns up to limit shares since a given time, newest first,
+// starting after the cursor, and how many shares there are since then in all
+func (s *SQLiteStorage) GetSharesPage(since time.Time, after PageCursor, limit int) ([]*Share, int64, error) {
+	shares, err := s.getSharesAfter(since, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = s.read.QueryRow("SELECT COUNT(*) FROM shares WHERE timestamp >= ?", since.UTC().Format("2006-01-02 15:04:05")).Scan(&total)
+	return shares, total, err
+}
+
+// GetBlocksPage returns up to limit blocks since a given time, newest first,
+// starting after the cursor, and how many blocks there are since then in all
+func (s *SQLiteStorage) GetBlocksPage(since time.Time, after PageCursor, limit int) ([]*Block, int64, error) {
+	blocks, err := s.getBlocksAfter(since, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = s.read.QueryRow("SELECT COUNT(*) FROM blocks WHERE timestamp >= ?", since.UTC().Format("2006-01-02 15:04:05")).Scan(&total)
+	return blocks, total, err
+}
+
+// GetSnapshotsPage returns up to limit of a miner's snapshots since a given
+// time, newest first, starting after the cursor, and how many there are since
+// then in all
+func (s *SQLiteStorage) GetSnapshotsPage(minerIP string, since time.Time, after PageCursor, limit int) ([]*MinerSnapshot, int64, error) {
+	snapshots, err := s.getSnapshotsAfter(minerIP, since, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = s.read.QueryRow("SELECT COUNT(*) FROM miner_snapshots WHERE miner_ip = ? AND timestamp >= ?", minerIP, since.UTC().Format("2006-01-02 15:04:05")).Scan(&total)
+	return snapshots, total, err
+}
+
+// GetHistoryRollupsPage returns up to limit rollups at the given resolution
+// since a given time, newest first, starting after the cursor, and how many
+// there are since then in all. An empty minerIP returns all miners.
+func (s *SQLiteStorage) GetHistoryRollupsPage(resolution, minerIP string, since time.Time, after PageCursor, limit int) ([]*HistoryRollup, int64, error) {
+	rollups, err := s.getHistoryRollupsAfter(resolution, minerIP, since, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE timestamp >= ? AND (? = '' OR miner_ip = ?)", rollupTables[resolution])
+	err = s.read.QueryRow(query, since.UTC().Format("2006-01-02 15:04:05"), minerIP, minerIP).Scan(&total)
+	return rollups, total, err
+}
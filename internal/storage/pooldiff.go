@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RecordPoolDifficulty stores a miner's pool difficulty if it differs from the
+// last one recorded. It returns the recorded change, or nil if the difficulty
+// is unchanged.
+func (s *SQLiteStorage) RecordPoolDifficulty(minerIP, hostname string, difficulty float64, at time.Time) (*PoolDifficultyChange, error) {
+	var previous float64
+	err := s.read.QueryRow(`
+	SELECT new_difficulty FROM pool_difficulty_changes
+	WHERE miner_ip = ?
+	ORDER BY timestamp DESC, id DESC
+	LIMIT 1
+	`, minerIP).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if previous == difficulty {
+		return nil, nil
+	}
+
+	_, err = s.db.Exec(`
+	INSERT INTO pool_difficulty_changes (miner_ip, hostname, timestamp, old_difficulty, new_difficulty)
+	VALUES (?, ?, ?, ?, ?)
+	`, minerIP, hostname, at.UTC().Format("2006-01-02 15:04:05"), previous, difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PoolDifficultyChange{
+		MinerIP:       minerIP,
+		Hostname:      hostname,
+		Timestamp:     at,
+		OldDifficulty: previous,
+		NewDifficulty: difficulty,
+	}, nil
+}
+
+// GetPoolDifficultyChanges returns pool difficulty changes since the given
+// time, newest first. An empty minerIP returns changes for every miner.
+func (s *SQLiteStorage) GetPoolDifficultyChanges(minerIP string, since time.Time) ([]*PoolDifficultyChange, error) {
+	query := `
+	SELECT miner_ip, hostname, timestamp, old_difficulty, new_difficulty
+	FROM pool_difficulty_changes
+	WHERE timestamp >= ? AND (? = '' OR miner_ip = ?)
+	ORDER BY timestamp DESC, id DESC
+	`
+
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"), minerIP, minerIP)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*PoolDifficultyChange
+	for rows.Next() {
+		c := &PoolDifficultyChange{}
+		var timestamp string
+		if err := rows.Scan(&c.MinerIP, &c.Hostname, &timestamp, &c.OldDifficulty, &c.NewDifficulty); err != nil {
+			return nil, err
+		}
+		c.Timestamp = parseTimestamp(timestamp)
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
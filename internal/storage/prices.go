@@ -0,0 +1,77 @@
+package storage
+
+import "time"
+
+// PricePoint is a coin's USD price as fetched at one point in time
+type PricePoint struct {
+	CoinID    string    `json:"coinId"`
+	Timestamp time.Time `json:"timestamp"`
+	PriceUSD  float64   `json:"priceUsd"`
+}
+
+// InsertPrice records a fetched coin price
+func (s *SQLiteStorage) InsertPrice(coinID string, priceUSD float64, at time.Time) error {
+	_, err := s.db.Exec(`
+	INSERT INTO price_history (coin_id, timestamp, price_usd)
+	VALUES (?, ?, ?)
+	`, coinID, at.UTC().Format("2006-01-02 15:04:05"), priceUSD)
+	return err
+}
+
+// GetPriceHistory returns the prices recorded since the given time, oldest
+// first. An empty coinID returns the prices of every coin.
+func (s *SQLiteStorage) GetPriceHistory(coinID string, since time.Time) ([]*PricePoint, error) {
+	rows, err := s.read.Query(`
+	SELECT coin_id, timestamp, price_usd
+	FROM price_history
+	WHERE timestamp >= ? AND (? = '' OR coin_id = ?)
+	ORDER BY timestamp, id
+	`, since.UTC().Format("2006-01-02 15:04:05"), coinID, coinID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []*PricePoint
+	for rows.Next() {
+		p := &PricePoint{}
+		var timestamp string
+		if err := rows.Scan(&p.CoinID, &timestamp, &p.PriceUSD); err != nil {
+			return nil, err
+		}
+		p.Timestamp = parseTimestamp(timestamp)
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// GetLastPricesBefore returns each coin's last price recorded before the
+// given time, keyed by coin ID
+func (s *SQLiteStorage) GetLastPricesBefore(before time.Time) (map[string]float64, error) {
+	rows, err := s.read.Query(`
+	SELECT p.coin_id, p.price_usd
+	FROM price_history p
+	JOIN (
+		SELECT coin_id, MAX(id) AS id FROM price_history
+		WHERE timestamp < ?
+		GROUP BY coin_id
+	) last ON last.id = p.id
+	`, before.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[string]float64)
+	for rows.Next() {
+		var coinID string
+		var price float64
+		if err := rows.Scan(&coinID, &price); err != nil {
+			return nil, err
+		}
+		prices[coinID] = price
+	}
+
+	return prices, rows.Err()
+}
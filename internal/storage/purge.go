@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// PurgeEstimate reports what a purge would delete from one table
+type PurgeEstimate struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+	Bytes int64  `json:"bytes"` // Approximate space reclaimed after VACUUM, including indexes
+}
+
+// EstimatePurge reports how many rows older than cutoff each table holds and
+// roughly how much disk deleting them would reclaim, without deleting anything
+func (s *SQLiteStorage) EstimatePurge(cutoff time.Time, tables ...string) ([]PurgeEstimate, error) {
+	estimates := make([]PurgeEstimate, 0, len(tables))
+	for _, table := range tables {
+		var total, old int64
+		query := fmt.Sprintf("SELECT COUNT(*), COALESCE(SUM(timestamp < ?), 0) FROM %s", table)
+		if err := s.read.QueryRow(query, cutoff.UTC().Format("2006-01-02 15:04:05")).Scan(&total, &old); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+
+		est := PurgeEstimate{Table: table, Rows: old}
+		if old > 0 {
+			est.Bytes = int64(float64(s.tableBytes(table)) * float64(old) / float64(total))
+		}
+		estimates = append(estimates, est)
+	}
+	return estimates, nil
+}
+
+// tableBytes returns the on-disk size of a table and its indexes. It uses the
+// dbstat virtual table when available and otherwise apportions the database
+// size by row count.
+func (s *SQLiteStorage) tableBytes(table string) int64 {
+	var size int64
+	err := s.read.QueryRow(`
+	SELECT COALESCE(SUM(pgsize), 0) FROM dbstat
+	WHERE name IN (SELECT name FROM sqlite_master WHERE tbl_name = ?)
+	`, table).Scan(&size)
+	if err == nil {
+		return size
+	}
+
+	var pageCount, pageSize int64
+	if err := s.read.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0
+	}
+	if err := s.read.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0
+	}
+	stats, err := s.GetTableStats()
+	if err != nil {
+		return 0
+	}
+	var rows, all int64
+	for _, t := range stats {
+		all += t.Rows
+		if t.Name == table {
+			rows = t.Rows
+		}
+	}
+	if all == 0 {
+		return 0
+	}
+	return pageCount * pageSize * rows / all
+}
+
+// oldDataTables lists the tables PurgeOldData deletes from. Daily rollups are
+// kept for long-term charts, and failed_deliveries holds the alert
+// deliveries nobody replayed.
+var oldDataTables = []string{"miner_snapshots", "shares", "pool_difficulty_changes", "snapshots_hourly", "failed_deliveries"}
+
+// PreviewPurgeOldData estimates what PurgeOldData would delete
+func (s *SQLiteStorage) PreviewPurgeOldData(retentionDays int) ([]PurgeEstimate, error) {
+	return s.EstimatePurge(time.Now().AddDate(0, 0, -retentionDays), oldDataTables...)
+}
+
+// PurgeTable deletes the rows of a table older than cutoff and returns how
+// many it deleted. The table must have a timestamp column.
+func (s *SQLiteStorage) PurgeTable(table string, cutoff time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM "+table+" WHERE timestamp < ?", cutoff.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old %s: %w", table, err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Record kinds kept in the hall of fame
+const (
+	RecordBestShare       = "best_share"        // Highest share difficulty ever submitted
+	RecordBlockStreak     = "block_streak"      // Most consecutive weeks with at least one block
+	RecordBestDayEarnings = "best_day_earnings" // Highest fleet block value (USD) in one local day
+	RecordLongestUptime   = "longest_uptime"    // Longest uptime reported by a miner, in seconds
+)
+
+// Record is a permanent all-time record. Records live in their own table so
+// they survive retention purges of the shares and snapshots behind them.
+type Record struct {
+	Kind       string    `json:"kind"`
+	Value      float64   `json:"value"`
+	MinerIP    string    `json:"minerIp,omitempty"` // Empty for fleet-wide records
+	Hostname   string    `json:"hostname,omitempty"`
+	AchievedAt time.Time `json:"achievedAt"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// UpdateRecord stores r if it beats the current record of its kind and
+// reports whether it did
+func (s *SQLiteStorage) UpdateRecord(r *Record) (bool, error) {
+	result, err := s.db.Exec(`
+	INSERT INTO records (kind, value, miner_ip, hostname, achieved_at, detail)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(kind) DO UPDATE SET
+		value = excluded.value,
+		miner_ip = excluded.miner_ip,
+		hostname = excluded.hostname,
+		achieved_at = excluded.achieved_at,
+		detail = excluded.detail
+	WHERE excluded.value > records.value
+	`, r.Kind, r.Value, r.MinerIP, r.Hostname, r.AchievedAt.UTC().Format("2006-01-02 15:04:05"), r.Detail)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetRecords returns every record held, ordered by kind
+func (s *SQLiteStorage) GetRecords() ([]*Record, error) {
+	rows, err := s.read.Query(`
+	SELECT kind, value, miner_ip, hostname, achieved_at, detail
+	FROM records
+	ORDER BY kind
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		r := &Record{}
+		var achievedAt string
+		if err := rows.Scan(&r.Kind, &r.Value, &r.MinerIP, &r.Hostname, &achievedAt, &r.Detail); err != nil {
+			return nil, err
+		}
+		r.AchievedAt = parseTimestamp(achievedAt)
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// GetDayEarnings returns the fleet's total block value (USD) for the local
+// calendar day containing day
+func (s *SQLiteStorage) GetDayEarnings(day time.Time) (float64, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	var total float64
+	err := s.read.QueryRow(`
+	SELECT COALESCE(SUM(value_usd), 0) FROM blocks
+	WHERE timestamp >= ? AND timestamp < ?
+	`, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")).Scan(&total)
+	return total, err
+}
+
+// GetLongestBlockStreak returns the most consecutive weeks (Sunday to
+// Saturday) in which the miner found at least one block, and the start of the
+// last week of that streak
+func (s *SQLiteStorage) GetLongestBlockStreak(minerIP string) (int, time.Time, error) {
+	rows, err := s.read.Query("SELECT timestamp FROM blocks WHERE miner_ip = ?", minerIP)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer rows.Close()
+
+	var timestamps []time.Time
+	for rows.Next() {
+		var ts string
+		if err := rows.Scan(&ts); err != nil {
+			return 0, time.Time{}, err
+		}
+		timestamps = append(timestamps, parseTimestamp(ts).Local())
+	}
+	if err := rows.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	streak, ended := longestWeekStreak(timestamps)
+	return streak, ended, nil
+}
+
+// longestWeekStreak returns the longest run of consecutive weeks containing
+// at least one timestamp, and the start of the run's last week
+func longestWeekStreak(timestamps []time.Time) (int, time.Time) {
+	weeks := make(map[time.Time]bool)
+	for _, ts := range timestamps {
+		weeks[time.Date(ts.Year(), ts.Month(), ts.Day()-int(ts.Weekday()), 0, 0, 0, 0, ts.Location())] = true
+	}
+
+	starts := make([]time.Time, 0, len(weeks))
+	for w := range weeks {
+		starts = append(starts, w)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	var best, run int
+	var bestEnd time.Time
+	for i, w := range starts {
+		if i > 0 && starts[i-1].AddDate(0, 0, 7).Equal(w) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > best {
+			best, bestEnd = run, w
+		}
+	}
+	return best, bestEnd
+}
+
+// BackfillRecords raises records from the data still in the database, so an
+// upgraded install starts with its existing history. Existing records are
+// only replaced when beaten.
+func (s *SQLiteStorage) BackfillRecords() error {
+	var candidates []*Record
+
+	// Best share, counting kept best shares and blocks (which are shares too) since they are never purged
+	r := &Record{Kind: RecordBestShare}
+	var ts string
+	err := s.read.QueryRow(`
+	SELECT miner_ip, hostname, difficulty, timestamp FROM (
+		SELECT miner_ip, hostname, difficulty, timestamp FROM shares WHERE rejected = 0
+		UNION ALL
+		SELECT miner_ip, hostname, difficulty, timestamp FROM best_shares
+		UNION ALL
+		SELECT miner_ip, hostname, difficulty, timestamp FROM blocks
+	)
+	ORDER BY difficulty DESC
+	LIMIT 1
+	`).Scan(&r.MinerIP, &r.Hostname, &r.Value, &ts)
+	if err == nil {
+		r.AchievedAt = parseTimestamp(ts)
+		candidates = append(candidates, r)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("best share: %w", err)
+	}
+
+	// Longest uptime among retained snapshots
+	r = &Record{Kind: RecordLongestUptime}
+	var uptime int64
+	err = s.read.QueryRow(`
+	SELECT miner_ip, hostname, uptime_seconds, timestamp FROM miner_snapshots
+	ORDER BY uptime_seconds DESC
+	LIMIT 1
+	`).Scan(&r.MinerIP, &r.Hostname, &uptime, &ts)
+	if err == nil {
+		r.Value = float64(uptime)
+		r.AchievedAt = parseTimestamp(ts)
+		candidates = append(candidates, r)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("longest uptime: %w", err)
+	}
+
+	// Best day of block earnings
+	r = &Record{Kind: RecordBestDayEarnings}
+	var day string
+	err = s.read.QueryRow(`
+	SELECT date(timestamp, 'localtime') AS day, SUM(value_usd) AS total FROM blocks
+	GROUP BY day
+	ORDER BY total DESC
+	LIMIT 1
+	`).Scan(&day, &r.Value)
+	if err == nil {
+		r.AchievedAt, _ = time.ParseInLocation("2006-01-02", day, time.Local)
+		r.Detail = day
+		candidates = append(candidates, r)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("best day earnings: %w", err)
+	}
+
+	// Longest block streak per miner
+	rows, err := s.read.Query(`
+	SELECT miner_ip, (SELECT hostname FROM blocks latest WHERE latest.miner_ip = b.miner_ip ORDER BY timestamp DESC LIMIT 1)
+	FROM blocks b
+	GROUP BY miner_ip
+	`)
+	if err != nil {
+		return fmt.Errorf("block streak: %w", err)
+	}
+	var streakers []*Record
+	for rows.Next() {
+		r := &Record{Kind: RecordBlockStreak}
+		if err := rows.Scan(&r.MinerIP, &r.Hostname); err != nil {
+			rows.Close()
+			return fmt.Errorf("block streak: %w", err)
+		}
+		streakers = append(streakers, r)
+	}
+	rows.Close()
+	for _, r := range streakers {
+		streak, ended, err := s.GetLongestBlockStreak(r.MinerIP)
+		if err != nil {
+			return fmt.Errorf("block streak: %w", err)
+		}
+		r.Value = float64(streak)
+		r.AchievedAt = ended
+		candidates = append(candidates, r)
+	}
+
+	for _, r := range candidates {
+		if r.Value <= 0 {
+			continue
+		}
+		if _, err := s.UpdateRecord(r); err != nil {
+			return fmt.Errorf("%s: %w", r.Kind, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Rollup resolutions, named after their tables
+const (
+	ResolutionHour = "hour"
+	ResolutionDay  = "day"
+)
+
+var rollupTables = map[string]string{
+	ResolutionHour: "snapshots_hourly",
+	ResolutionDay:  "snapshots_daily",
+}
+
+// HistoryRollup summarizes a miner's snapshots over an hour or a (local) day
+type HistoryRollup struct {
+	MinerIP        string    `json:"minerIp"`
+	Timestamp      time.Time `json:"timestamp"` // Start of the hour or day
+	Samples        int       `json:"samples"`
+	HashRateAvg    float64   `json:"hashRateAvg"` // GH/s
+	HashRateMin    float64   `json:"hashRateMin"`
+	HashRateMax    float64   `json:"hashRateMax"`
+	TemperatureAvg float64   `json:"temperatureAvg"` // °C
+	TemperatureMin float64   `json:"temperatureMin"`
+	TemperatureMax float64   `json:"temperatureMax"`
+	PowerAvg       float64   `json:"powerAvg"` // W
+	PowerMin       float64   `json:"powerMin"`
+	PowerMax       float64   `json:"powerMax"`
+}
+
+// UpdateRollups rolls snapshots up into the hourly and daily tables. It
+// covers the two complete hours before now, which are still fully retained
+// when called just before the hourly snapshot purge, and recomputes the
+// local days they fall in. Running it more than once is harmless: an hour is
+// only rewritten from more samples than it already holds. The hours and days
+// are written in one transaction.
+func (s *SQLiteStorage) UpdateRollups(now time.Time) error {
+	end := now.Truncate(time.Hour)
+	start := end.Add(-2 * time.Hour)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+	INSERT INTO snapshots_hourly (miner_ip, timestamp, samples,
+		hash_rate_avg, hash_rate_min, hash_rate_max,
+		temperature_avg, temperature_min, temperature_max,
+		power_avg, power_min, power_max)
+	SELECT miner_ip, strftime('%Y-%m-%d %H:00:00', timestamp) AS hour, COUNT(*),
+		AVG(hash_rate), MIN(hash_rate), MAX(hash_rate),
+		AVG(temperature), MIN(temperature), MAX(temperature),
+		AVG(power), MIN(power), MAX(power)
+	FROM miner_snapshots
+	WHERE timestamp >= ? AND timestamp < ?
+	GROUP BY miner_ip, hour
+	ON CONFLICT(miner_ip, timestamp) DO UPDATE SET
+		samples = excluded.samples,
+		hash_rate_avg = excluded.hash_rate_avg, hash_rate_min = excluded.hash_rate_min, hash_rate_max = excluded.hash_rate_max,
+		temperature_avg = excluded.temperature_avg, temperature_min = excluded.temperature_min, temperature_max = excluded.temperature_max,
+		power_avg = excluded.power_avg, power_min = excluded.power_min, power_max = excluded.power_max
+	WHERE excluded.samples > snapshots_hourly.samples
+	`, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to roll up hourly snapshots: %w", err)
+	}
+
+	local := start.Local()
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	for !day.After(end) {
+		if err := rollupDay(tx, day); err != nil {
+			return err
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return tx.Commit()
+}
+
+// rollupDay recomputes the daily rollups of the local day starting at day
+// from its hourly rollups
+func rollupDay(tx *sql.Tx, day time.Time) error {
+	_, err := tx.Exec(`
+	INSERT INTO snapshots_daily (miner_ip, timestamp, samples,
+		hash_rate_avg, hash_rate_min, hash_rate_max,
+		temperature_avg, temperature_min, temperature_max,
+		power_avg, power_min, power_max)
+	SELECT miner_ip, ?, SUM(samples),
+		SUM(hash_rate_avg * samples) / SUM(samples), MIN(hash_rate_min), MAX(hash_rate_max),
+		SUM(temperature_avg * samples) / SUM(samples), MIN(temperature_min), MAX(temperature_max),
+		SUM(power_avg * samples) / SUM(samples), MIN(power_min), MAX(power_max)
+	FROM snapshots_hourly
+	WHERE timestamp >= ? AND timestamp < ? AND samples > 0
+	GROUP BY miner_ip
+	ON CONFLICT(miner_ip, timestamp) DO UPDATE SET
+		samples = excluded.samples,
+		hash_rate_avg = excluded.hash_rate_avg, hash_rate_min = excluded.hash_rate_min, hash_rate_max = excluded.hash_rate_max,
+		temperature_avg = excluded.temperature_avg, temperature_min = excluded.temperature_min, temperature_max = excluded.temperature_max,
+		power_avg = excluded.power_avg, power_min = excluded.power_min, power_max = excluded.power_max
+	`,
+		day.UTC().Format("2006-01-02 15:04:05"),
+		day.UTC().Format("2006-01-02 15:04:05"),
+		day.AddDate(0, 0, 1).UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to roll up daily snapshots: %w", err)
+	}
+	return nil
+}
+
+// GetHistoryRollups returns rollups at the given resolution since a given
+// time, newest first. An empty minerIP returns all miners.
+func (s *SQLiteStorage) GetHistoryRollups(resolution, minerIP string, since time.Time, limit int) ([]*HistoryRollup, error) {
+	return s.getHistoryRollupsAfter(resolution, minerIP, since, PageCursor{}, limit)
+}
+
+// getHistoryRollupsAfter returns rollups at the given resolution since a
+// given time, newest first, starting after the cursor
+func (s *SQLiteStorage) getHistoryRollupsAfter(resolution, minerIP string, since time.Time, after PageCursor, limit int) ([]*HistoryRollup, error) {
+	table, ok := rollupTables[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unknown resolution: %s", resolution)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT miner_ip, timestamp, samples,
+		hash_rate_avg, hash_rate_min, hash_rate_max,
+		temperature_avg, temperature_min, temperature_max,
+		power_avg, power_min, power_max
+	FROM %s
+	WHERE timestamp >= ? AND (? = '' OR miner_ip = ?)
+		AND (? = '' OR timestamp < ? OR (timestamp = ? AND miner_ip > ?))
+	ORDER BY timestamp DESC, miner_ip
+	LIMIT ?
+	`, table)
+
+	ts := after.timestamp()
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"), minerIP, minerIP, ts, ts, ts, after.MinerIP, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []*HistoryRollup
+	for rows.Next() {
+		r := &HistoryRollup{}
+		var timestamp string
+		err := rows.Scan(&r.MinerIP, &timestamp, &r.Samples,
+			&r.HashRateAvg, &r.HashRateMin, &r.HashRateMax,
+			&r.TemperatureAvg, &r.TemperatureMin, &r.TemperatureMax,
+			&r.PowerAvg, &r.PowerMin, &r.PowerMax)
+		if err != nil {
+			return nil, err
+		}
+		r.Timestamp = parseTimestamp(timestamp)
+		rollups = append(rollups, r)
+	}
+
+	return rollups, rows.Err()
+}
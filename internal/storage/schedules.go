@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Schedule pauses or underclocks a set of miners while its trigger holds:
+// a time window, an expensive electricity tariff, or an external signal such
+// as a solar inverter's surplus
+type Schedule struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	Miners  []string `json:"miners"` // IPs of the scheduled miners
+
+	Action      string `json:"action"`                // "pause" (smart plug off) or "underclock"
+	Frequency   int    `json:"frequency,omitempty"`   // MHz while underclocked
+	CoreVoltage int    `json:"coreVoltage,omitempty"` // mV while underclocked, 0 = unchanged
+
+	Trigger string `json:"trigger"` // "time", "tariff" or "signal"
+
+	// Time windows: local "HH:MM", End <= Start wraps past midnight. Empty
+	// Days match every day.
+	Start string   `json:"start,omitempty"`
+	End   string   `json:"end,omitempty"`
+	Days  []string `json:"days,omitempty"`
+
+	// Tariffs: active while the miner's electricity rate is above MaxRate
+	MaxRate float64 `json:"maxRate,omitempty"`
+
+	// Signals: active while the named signal is below or above Threshold
+	Signal    string  `json:"signal,omitempty"`
+	Compare   string  `json:"compare,omitempty"` // "below" or "above"
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// ScheduleRun is one miner paused or underclocked by a schedule, with the
+// energy it saved against its power before
+type ScheduleRun struct {
+	ID            int64      `json:"id"`
+	ScheduleID    int64      `json:"scheduleId"`
+	MinerIP       string     `json:"minerIp"`
+	Action        string     `json:"action"`
+	Start         time.Time  `json:"start"`
+	End           *time.Time `json:"end,omitempty"` // Unset while the run lasts
+	BaselineWatts float64    `json:"baselineWatts"` // Power before the run
+	SavedKWh      float64    `json:"savedKwh"`
+	SavedCost     float64    `json:"savedCost"` // In the energy currency, at the tariff of each moment
+
+	// Settings to restore after an underclock
+	RestoreFrequency   int `json:"restoreFrequency,omitempty"`
+	RestoreCoreVoltage int `json:"restoreCoreVoltage,omitempty"`
+}
+
+// ScheduleOverride keeps a miner mining ("run") or paused ("pause")
+// whatever its schedules say, until Until or until removed
+type ScheduleOverride struct {
+	MinerIP string     `json:"minerIp"`
+	Mode    string     `json:"mode"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+// ScheduleSavings totals the runs of one schedule and miner
+type ScheduleSavings struct {
+	ScheduleID int64   `json:"scheduleId"`
+	MinerIP    string  `json:"minerIp"`
+	Runs       int     `json:"runs"`
+	Hours      float64 `json:"hours"` // Paused or underclocked
+	SavedKWh   float64 `json:"savedKwh"`
+	SavedCost  float64 `json:"savedCost"`
+}
+
+// GetSchedules returns every schedule by ID
+func (s *SQLiteStorage) GetSchedules() ([]*Schedule, error) {
+	rows, err := s.read.Query(`
+	SELECT id, name, enabled, miners, action, frequency, core_voltage, trigger_type,
+		start_time, end_time, days, max_rate, signal, compare, threshold
+	FROM schedules
+	ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*Schedule
+	for rows.Next() {
+		sc := &Schedule{}
+		var miners, days string
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Enabled, &miners, &sc.Action, &sc.Frequency, &sc.CoreVoltage,
+			&sc.Trigger, &sc.Start, &sc.End, &days, &sc.MaxRate, &sc.Signal, &sc.Compare, &sc.Threshold); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(miners), &sc.Miners)
+		json.Unmarshal([]byte(days), &sc.Days)
+		schedules = append(schedules, sc)
+	}
+	return schedules, rows.Err()
+}
+
+// SaveSchedule inserts a schedule without an ID, setting it, or replaces the
+// schedule with its ID. It reports false if there is no such schedule.
+func (s *SQLiteStorage) SaveSchedule(sc *Schedule) (bool, error) {
+	miners, _ := json.Marshal(sc.Miners)
+	days, _ := json.Marshal(sc.Days)
+	args := []interface{}{sc.Name, sc.Enabled, string(miners), sc.Action, sc.Frequency, sc.CoreVoltage,
+		sc.Trigger, sc.Start, sc.End, string(days), sc.MaxRate, sc.Signal, sc.Compare, sc.Threshold}
+
+	if sc.ID == 0 {
+		result, err := s.db.Exec(`
+		INSERT INTO schedules (name, enabled, miners, action, frequency, core_voltage, trigger_type,
+			start_time, end_time, days, max_rate, signal, compare, threshold)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, args...)
+		if err != nil {
+			return false, err
+		}
+		sc.ID, err = result.LastInsertId()
+		return err == nil, err
+	}
+
+	result, err := s.db.Exec(`
+	UPDATE schedules SET name = ?, enabled = ?, miners = ?, action = ?, frequency = ?, core_voltage = ?,
+		trigger_type = ?, start_time = ?, end_time = ?, days = ?, max_rate = ?, signal = ?, compare = ?, threshold = ?
+	WHERE id = ?
+	`, append(args, sc.ID)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteSchedule deletes a schedule, keeping its runs for the savings
+// report. It reports false if there is no such schedule.
+func (s *SQLiteStorage) DeleteSchedule(id int64) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM schedules WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// InsertScheduleRun records the start of a run, setting its ID
+func (s *SQLiteStorage) InsertScheduleRun(run *ScheduleRun) error {
+	result, err := s.db.Exec(`
+	INSERT INTO schedule_runs (schedule_id, miner_ip, action, start_time, baseline_watts, saved_kwh, saved_cost,
+		restore_frequency, restore_core_voltage)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.ScheduleID, run.MinerIP, run.Action, run.Start.UTC().Format("2006-01-02 15:04:05"),
+		run.BaselineWatts, run.SavedKWh, run.SavedCost, run.RestoreFrequency, run.RestoreCoreVoltage)
+	if err != nil {
+		return err
+	}
+	run.ID, err = result.LastInsertId()
+	return err
+}
+
+// UpdateScheduleRun stores a run's savings so far, and its end once set
+func (s *SQLiteStorage) UpdateScheduleRun(run *ScheduleRun) error {
+	var end interface{}
+	if run.End != nil {
+		end = run.End.UTC().Format("2006-01-02 15:04:05")
+	}
+	_, err := s.db.Exec(`
+	UPDATE schedule_runs SET saved_kwh = ?, saved_cost = ?, end_time = ? WHERE id = ?
+	`, run.SavedKWh, run.SavedCost, end, run.ID)
+	return err
+}
+
+// GetOpenScheduleRuns returns the runs that haven't ended, e.g. because
+// MinerHQ stopped during them
+func (s *SQLiteStorage) GetOpenScheduleRuns() ([]*ScheduleRun, error) {
+	rows, err := s.read.Query(`
+	SELECT id, schedule_id, miner_ip, action, start_time, baseline_watts, saved_kwh, saved_cost,
+		restore_frequency, restore_core_voltage
+	FROM schedule_runs
+	WHERE end_time IS NULL
+	ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*ScheduleRun
+	for rows.Next() {
+		run := &ScheduleRun{}
+		var start string
+		if err := rows.Scan(&run.ID, &run.ScheduleID, &run.MinerIP, &run.Action, &start, &run.BaselineWatts,
+			&run.SavedKWh, &run.SavedCost, &run.RestoreFrequency, &run.RestoreCoreVoltage); err != nil {
+			return nil, err
+		}
+		run.Start = parseTimestamp(start)
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetScheduleSavings totals the runs since a given time per schedule and
+// miner. Runs still going count up to now.
+func (s *SQLiteStorage) GetScheduleSavings(since time.Time) ([]*ScheduleSavings, error) {
+	rows, err := s.read.Query(`
+	SELECT schedule_id, miner_ip, COUNT(*),
+		SUM((julianday(COALESCE(end_time, datetime('now'))) - julianday(start_time)) * 24),
+		SUM(saved_kwh), SUM(saved_cost)
+	FROM schedule_runs
+	WHERE start_time >= ?
+	GROUP BY schedule_id, miner_ip
+	ORDER BY schedule_id, miner_ip
+	`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var savings []*ScheduleSavings
+	for rows.Next() {
+		sv := &ScheduleSavings{}
+		if err := rows.Scan(&sv.ScheduleID, &sv.MinerIP, &sv.Runs, &sv.Hours, &sv.SavedKWh, &sv.SavedCost); err != nil {
+			return nil, err
+		}
+		savings = append(savings, sv)
+	}
+	return savings, rows.Err()
+}
+
+// GetScheduleOverrides returns every miner's manual override
+func (s *SQLiteStorage) GetScheduleOverrides() ([]*ScheduleOverride, error) {
+	rows, err := s.read.Query("SELECT miner_ip, mode, until FROM schedule_overrides ORDER BY miner_ip")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*ScheduleOverride
+	for rows.Next() {
+		o := &ScheduleOverride{}
+		var until sql.NullString
+		if err := rows.Scan(&o.MinerIP, &o.Mode, &until); err != nil {
+			return nil, err
+		}
+		if until.Valid {
+			t := parseTimestamp(until.String)
+			o.Until = &t
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetScheduleOverride replaces a miner's manual override
+func (s *SQLiteStorage) SetScheduleOverride(o *ScheduleOverride) error {
+	var until interface{}
+	if o.Until != nil {
+		until = o.Until.UTC().Format("2006-01-02 15:04:05")
+	}
+	_, err := s.db.Exec("INSERT OR REPLACE INTO schedule_overrides (miner_ip, mode, until) VALUES (?, ?, ?)",
+		o.MinerIP, o.Mode, until)
+	return err
+}
+
+// DeleteScheduleOverride removes a miner's manual override
+func (s *SQLiteStorage) DeleteScheduleOverride(minerIP string) error {
+	_, err := s.db.Exec("DELETE FROM schedule_overrides WHERE miner_ip = ?", minerIP)
+	return err
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ShareLink grants read-only access to the fleet's stats, competitions and
+// blocks through a secret URL. Only the hash of its token is kept.
+type ShareLink struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	TokenHash string     `json:"-"`
+	HideIPs   bool       `json:"hideIps"` // Replace miner IPs with pseudonyms
+	Created   time.Time  `json:"created"`
+	Expires   *time.Time `json:"expires,omitempty"` // Unset for links that don't expire
+}
+
+// Expired reports whether the link no longer grants access at a given time
+func (l *ShareLink) Expired(now time.Time) bool {
+	return l.Expires != nil && !now.Before(*l.Expires)
+}
+
+const shareLinkColumns = "id, name, token_hash, hide_ips, created_at, expires_at"
+
+// scanShareLink reads a row of shareLinkColumns
+func scanShareLink(row interface{ Scan(...interface{}) error }) (*ShareLink, error) {
+	l := &ShareLink{}
+	var created string
+	var expires sql.NullString
+	if err := row.Scan(&l.ID, &l.Name, &l.TokenHash, &l.HideIPs, &created, &expires); err != nil {
+		return nil, err
+	}
+	l.Created = parseTimestamp(created)
+	if expires.Valid {
+		t := parseTimestamp(expires.String)
+		l.Expires = &t
+	}
+	return l, nil
+}
+
+// GetShareLinks returns every share link, newest first
+func (s *SQLiteStorage) GetShareLinks() ([]*ShareLink, error) {
+	rows, err := s.read.Query("SELECT " + shareLinkColumns + " FROM share_links ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*ShareLink
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// GetShareLinkByTokenHash returns the share link with a token hash, or nil
+// if there is none
+func (s *SQLiteStorage) GetShareLinkByTokenHash(hash string) (*ShareLink, error) {
+	l, err := scanShareLink(s.read.QueryRow("SELECT "+shareLinkColumns+" FROM share_links WHERE token_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// InsertShareLink stores a share link, setting its ID
+func (s *SQLiteStorage) InsertShareLink(l *ShareLink) error {
+	var expires interface{}
+	if l.Expires != nil {
+		expires = l.Expires.UTC().Format("2006-01-02 15:04:05")
+	}
+	result, err := s.db.Exec(`
+	INSERT INTO share_links (name, token_hash, hide_ips, created_at, expires_at)
+	VALUES (?, ?, ?, ?, ?)
+	`, l.Name, l.TokenHash, l.HideIPs, l.Created.UTC().Format("2006-01-02 15:04:05"), expires)
+	if err != nil {
+		return err
+	}
+	l.ID, err = result.LastInsertId()
+	return err
+}
+
+// DeleteShareLink revokes a share link. It reports false if there is no
+// such link.
+func (s *SQLiteStorage) DeleteShareLink(id int64) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM share_links WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,175 @@
+package storage
+
+import "time"
+
+// ShareRate is a miner's logged shares over a period
+type ShareRate struct {
+	MinerIP        string  `json:"minerIp"`
+	Hostname       string  `json:"hostname"`
+	Shares         int64   `json:"shares"`
+	BestDifficulty float64 `json:"bestDifficulty"`
+	AvgDifficulty  float64 `json:"avgDifficulty"`
+}
+
+// AcceptanceBucket is the shares a miner had accepted and rejected in one
+// time bucket, from the increase of its snapshot counters
+type AcceptanceBucket struct {
+	MinerIP  string    `json:"minerIp"`
+	Start    time.Time `json:"start"`
+	Accepted int64     `json:"accepted"`
+	Rejected int64     `json:"rejected"`
+}
+
+// DifficultyBucket counts a miner's shares with a difficulty in
+// [10^Decade, 10^(Decade+1)); shares below 10 fall in decade 0
+type DifficultyBucket struct {
+	MinerIP string `json:"minerIp"`
+	Decade  int    `json:"decade"`
+	Count   int64  `json:"count"`
+}
+
+// GetShareRates returns each miner's share count and difficulties since a
+// given time, busiest first
+func (s *SQLiteStorage) GetShareRates(since time.Time) ([]*ShareRate, error) {
+	query := `
+	SELECT miner_ip, MAX(hostname), COUNT(*), MAX(difficulty), AVG(difficulty)
+	FROM shares
+	WHERE timestamp >= ?
+	GROUP BY miner_ip
+	ORDER BY COUNT(*) DESC, miner_ip
+	`
+
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*ShareRate
+	for rows.Next() {
+		r := &ShareRate{}
+		if err := rows.Scan(&r.MinerIP, &r.Hostname, &r.Shares, &r.BestDifficulty, &r.AvgDifficulty); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// GetShareAcceptance returns the shares each miner had accepted and rejected
+// since a given time, per bucket of the given size, oldest first. The
+// counters are per session, so a counter that went down means the miner
+// restarted and its whole value is new.
+func (s *SQLiteStorage) GetShareAcceptance(since time.Time, bucket time.Duration) ([]*AcceptanceBucket, error) {
+	query := `
+	WITH deltas AS (
+		SELECT miner_ip, timestamp,
+			shares_accepted - LAG(shares_accepted) OVER w AS accepted,
+			shares_rejected - LAG(shares_rejected) OVER w AS rejected,
+			shares_accepted AS session_accepted,
+			shares_rejected AS session_rejected
+		FROM miner_snapshots
+		WHERE timestamp >= ?
+		WINDOW w AS (PARTITION BY miner_ip ORDER BY timestamp)
+	)
+	SELECT miner_ip,
+		(CAST(strftime('%s', timestamp) AS INTEGER) / ?) * ? AS bucket,
+		SUM(CASE WHEN accepted < 0 THEN session_accepted ELSE accepted END),
+		SUM(CASE WHEN rejected < 0 THEN session_rejected ELSE rejected END)
+	FROM deltas
+	WHERE accepted IS NOT NULL
+	GROUP BY miner_ip, bucket
+	ORDER BY bucket, miner_ip
+	`
+
+	size := int64(bucket / time.Second)
+	if size <= 0 {
+		size = 3600
+	}
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"), size, size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []*AcceptanceBucket
+	for rows.Next() {
+		b := &AcceptanceBucket{}
+		var start int64
+		if err := rows.Scan(&b.MinerIP, &start, &b.Accepted, &b.Rejected); err != nil {
+			return nil, err
+		}
+		b.Start = time.Unix(start, 0).UTC()
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// GetDifficultyHistogram counts each miner's shares since a given time by
+// order of magnitude of their difficulty. The decade is the number of digits
+// of the integer difficulty less one, which needs no SQLite math functions.
+func (s *SQLiteStorage) GetDifficultyHistogram(since time.Time) ([]*DifficultyBucket, error) {
+	query := `
+	SELECT miner_ip,
+		MAX(LENGTH(CAST(CAST(difficulty AS INTEGER) AS TEXT)) - 1, 0) AS decade,
+		COUNT(*)
+	FROM shares
+	WHERE timestamp >= ? AND difficulty >= 0
+	GROUP BY miner_ip, decade
+	ORDER BY miner_ip, decade
+	`
+
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []*DifficultyBucket
+	for rows.Next() {
+		b := &DifficultyBucket{}
+		if err := rows.Scan(&b.MinerIP, &b.Decade, &b.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// AsicShares is one ASIC chip's logged shares over a period
+type AsicShares struct {
+	AsicNum        int       `json:"asicNum"`
+	Shares         int64     `json:"shares"`
+	BestDifficulty float64   `json:"bestDifficulty"`
+	LastShare      time.Time `json:"lastShare"`
+}
+
+// GetAsicShares returns a miner's share count, best difficulty and latest
+// share per ASIC chip since a given time. Chips without shares are absent.
+func (s *SQLiteStorage) GetAsicShares(minerIP string, since time.Time) ([]*AsicShares, error) {
+	query := `
+	SELECT asic_num, COUNT(*), MAX(difficulty), MAX(timestamp)
+	FROM shares
+	WHERE miner_ip = ? AND timestamp >= ?
+	GROUP BY asic_num
+	ORDER BY asic_num
+	`
+
+	rows, err := s.read.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var asics []*AsicShares
+	for rows.Next() {
+		a := &AsicShares{}
+		var last string
+		if err := rows.Scan(&a.AsicNum, &a.Shares, &a.BestDifficulty, &last); err != nil {
+			return nil, err
+		}
+		a.LastShare = parseTimestamp(last)
+		asics = append(asics, a)
+	}
+	return asics, rows.Err()
+}
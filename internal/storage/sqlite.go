@@ -10,9 +10,15 @@ import (
 
 // SQLiteStorage provides SQLite-based storage for miner data
 type SQLiteStorage struct {
-	db *sql.DB
+	db    *sql.DB // Writes, on a single connection
+	read  *sql.DB // Reads, on a pool of read-only connections
+	stmts *statements
 }
 
+// readConns is the size of the read pool. With WAL enabled, readers don't
+// block the writer or each other.
+const readConns = 4
+
 // parseTimestamp parses a timestamp string from SQLite in multiple formats.
 // All timestamps are stored in UTC.
 func parseTimestamp(s string) time.Time {
@@ -30,18 +36,27 @@ func parseTimestamp(s string) time.Time {
 // NewSQLiteStorage opens a SQLite database at the given path,
 // runs migrations, and enables WAL mode
 func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// Pragmas in the DSN apply to every connection the pools open. The busy
+	// timeout of 5 seconds handles a checkpoint or backup holding the lock.
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Limit to single connection to avoid SQLite locking issues
+	// Limit writes to a single connection to avoid SQLite locking issues
 	db.SetMaxOpenConns(1)
 
-	// Set busy timeout to 5 seconds to handle concurrent writes
-	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
+	// Check the connection before it is relied on
+	if err := db.Ping(); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// New databases can return free pages without a full VACUUM, see
+	// EnableIncrementalVacuum. This must come before any table is created.
+	if _, err := db.Exec("PRAGMA auto_vacuum=INCREMENTAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set auto_vacuum: %w", err)
 	}
 
 	// Enable WAL mode for better concurrent performance
@@ -50,19 +65,27 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys=ON"); err != nil {
+	read, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=query_only(1)")
+	if err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	read.SetMaxOpenConns(readConns)
+	read.SetMaxIdleConns(readConns)
 
-	s := &SQLiteStorage{db: db}
+	s := &SQLiteStorage{db: db, read: read}
 
 	if err := s.migrate(); err != nil {
-		db.Close()
+		s.closeDBs()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	s.stmts, err = prepareStatements(db)
+	if err != nil {
+		s.closeDBs()
+		return nil, fmt.Errorf("failed to prepare statements: %w", err)
+	}
+
 	return s, nil
 }
 
@@ -124,6 +147,22 @@ func (s *SQLiteStorage) migrate() error {
 	CREATE INDEX IF NOT EXISTS idx_shares_timestamp ON shares(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_shares_difficulty ON shares(difficulty);
 
+	CREATE TABLE IF NOT EXISTS best_shares (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		share_id INTEGER NOT NULL UNIQUE,
+		miner_ip TEXT NOT NULL,
+		hostname TEXT NOT NULL DEFAULT '',
+		timestamp DATETIME NOT NULL,
+		asic_num INTEGER NOT NULL DEFAULT 0,
+		difficulty REAL NOT NULL DEFAULT 0,
+		job_id TEXT NOT NULL DEFAULT '',
+		nonce INTEGER NOT NULL DEFAULT 0,
+		version INTEGER NOT NULL DEFAULT 0,
+		network_difficulty REAL NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_best_shares_miner_difficulty ON best_shares(miner_ip, difficulty);
+
 	CREATE TABLE IF NOT EXISTS blocks (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		miner_ip TEXT NOT NULL,
@@ -135,6 +174,266 @@ func (s *SQLiteStorage) migrate() error {
 
 	CREATE INDEX IF NOT EXISTS idx_blocks_miner_ip ON blocks(miner_ip);
 	CREATE INDEX IF NOT EXISTS idx_blocks_timestamp ON blocks(timestamp);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		username TEXT NOT NULL DEFAULT '',
+		remote_addr TEXT NOT NULL DEFAULT '',
+		method TEXT NOT NULL DEFAULT '',
+		path TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL DEFAULT '',
+		target TEXT NOT NULL DEFAULT '',
+		status INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+
+	CREATE TABLE IF NOT EXISTS energy_counters (
+		miner_ip TEXT PRIMARY KEY,
+		total_kwh REAL NOT NULL DEFAULT 0,
+		day TEXT NOT NULL DEFAULT '',
+		day_kwh REAL NOT NULL DEFAULT 0,
+		month TEXT NOT NULL DEFAULT '',
+		month_kwh REAL NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS hostname_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		miner_ip TEXT NOT NULL,
+		hostname TEXT NOT NULL,
+		first_seen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_hostname_history_miner ON hostname_history(miner_ip, first_seen);
+
+	CREATE TABLE IF NOT EXISTS pool_difficulty_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		miner_ip TEXT NOT NULL,
+		hostname TEXT NOT NULL DEFAULT '',
+		timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		old_difficulty REAL NOT NULL DEFAULT 0,
+		new_difficulty REAL NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_pool_difficulty_changes_miner ON pool_difficulty_changes(miner_ip, timestamp);
+
+	CREATE TABLE IF NOT EXISTS records (
+		kind TEXT PRIMARY KEY,
+		value REAL NOT NULL,
+		miner_ip TEXT NOT NULL DEFAULT '',
+		hostname TEXT NOT NULL DEFAULT '',
+		achieved_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		detail TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS competition_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		period TEXT NOT NULL,
+		period_start DATETIME NOT NULL,
+		period_end DATETIME NOT NULL,
+		rank INTEGER NOT NULL,
+		miner_ip TEXT NOT NULL,
+		hostname TEXT NOT NULL DEFAULT '',
+		best_diff REAL NOT NULL,
+		share_count INTEGER NOT NULL DEFAULT 0,
+		blocks INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(period_start, miner_ip)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_competition_results_miner ON competition_results(miner_ip, rank);
+
+	CREATE TABLE IF NOT EXISTS miner_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		miner_ip TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		line TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_miner_logs_miner ON miner_logs(miner_ip, timestamp);
+
+	CREATE TABLE IF NOT EXISTS failed_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		channel TEXT NOT NULL,
+		alert_type TEXT NOT NULL DEFAULT '',
+		miner_ip TEXT NOT NULL DEFAULT '',
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		last_attempt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS alerts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		alert_type TEXT NOT NULL,
+		miner_ip TEXT NOT NULL DEFAULT '',
+		miner_name TEXT NOT NULL DEFAULT '',
+		message TEXT NOT NULL DEFAULT '',
+		value REAL NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alerts_timestamp ON alerts(timestamp);
+
+	CREATE TABLE IF NOT EXISTS snapshots_hourly (
+		miner_ip TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		samples INTEGER NOT NULL DEFAULT 0,
+		hash_rate_avg REAL NOT NULL DEFAULT 0,
+		hash_rate_min REAL NOT NULL DEFAULT 0,
+		hash_rate_max REAL NOT NULL DEFAULT 0,
+		temperature_avg REAL NOT NULL DEFAULT 0,
+		temperature_min REAL NOT NULL DEFAULT 0,
+		temperature_max REAL NOT NULL DEFAULT 0,
+		power_avg REAL NOT NULL DEFAULT 0,
+		power_min REAL NOT NULL DEFAULT 0,
+		power_max REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (miner_ip, timestamp)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_snapshots_hourly_timestamp ON snapshots_hourly(timestamp);
+
+	CREATE TABLE IF NOT EXISTS snapshots_daily (
+		miner_ip TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		samples INTEGER NOT NULL DEFAULT 0,
+		hash_rate_avg REAL NOT NULL DEFAULT 0,
+		hash_rate_min REAL NOT NULL DEFAULT 0,
+		hash_rate_max REAL NOT NULL DEFAULT 0,
+		temperature_avg REAL NOT NULL DEFAULT 0,
+		temperature_min REAL NOT NULL DEFAULT 0,
+		temperature_max REAL NOT NULL DEFAULT 0,
+		power_avg REAL NOT NULL DEFAULT 0,
+		power_min REAL NOT NULL DEFAULT 0,
+		power_max REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (miner_ip, timestamp)
+	);
+
+	CREATE TABLE IF NOT EXISTS energy_daily (
+		miner_ip TEXT NOT NULL,
+		day TEXT NOT NULL,
+		kwh REAL NOT NULL DEFAULT 0,
+		cost REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (miner_ip, day)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_energy_daily_day ON energy_daily(day);
+
+	CREATE TABLE IF NOT EXISTS miner_tags (
+		miner_ip TEXT NOT NULL,
+		tag TEXT NOT NULL COLLATE NOCASE,
+		PRIMARY KEY (miner_ip, tag)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_miner_tags_tag ON miner_tags(tag);
+
+	CREATE TABLE IF NOT EXISTS miner_alert_overrides (
+		miner_ip TEXT PRIMARY KEY,
+		temp_above REAL,
+		hashrate_drop_percent REAL,
+		pool_diff_change_pct REAL,
+		fan_rpm_below INTEGER,
+		wifi_signal_below INTEGER,
+		miner_offline_seconds INTEGER,
+		miner_frozen_seconds INTEGER,
+		cooldown_minutes INTEGER,
+		discord INTEGER,
+		matrix INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS price_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		coin_id TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		price_usd REAL NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_price_history_coin ON price_history(coin_id, timestamp);
+
+	CREATE TABLE IF NOT EXISTS uptime_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		miner_ip TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		online INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_uptime_events_miner ON uptime_events(miner_ip, timestamp);
+
+	CREATE TABLE IF NOT EXISTS schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL DEFAULT '',
+		enabled INTEGER NOT NULL DEFAULT 1,
+		miners TEXT NOT NULL DEFAULT '[]',
+		action TEXT NOT NULL DEFAULT '',
+		frequency INTEGER NOT NULL DEFAULT 0,
+		core_voltage INTEGER NOT NULL DEFAULT 0,
+		trigger_type TEXT NOT NULL DEFAULT '',
+		start_time TEXT NOT NULL DEFAULT '',
+		end_time TEXT NOT NULL DEFAULT '',
+		days TEXT NOT NULL DEFAULT '[]',
+		max_rate REAL NOT NULL DEFAULT 0,
+		signal TEXT NOT NULL DEFAULT '',
+		compare TEXT NOT NULL DEFAULT '',
+		threshold REAL NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS schedule_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		schedule_id INTEGER NOT NULL,
+		miner_ip TEXT NOT NULL,
+		action TEXT NOT NULL DEFAULT '',
+		start_time DATETIME NOT NULL,
+		end_time DATETIME,
+		baseline_watts REAL NOT NULL DEFAULT 0,
+		saved_kwh REAL NOT NULL DEFAULT 0,
+		saved_cost REAL NOT NULL DEFAULT 0,
+		restore_frequency INTEGER NOT NULL DEFAULT 0,
+		restore_core_voltage INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_schedule_runs_start ON schedule_runs(start_time);
+
+	CREATE TABLE IF NOT EXISTS schedule_overrides (
+		miner_ip TEXT PRIMARY KEY,
+		mode TEXT NOT NULL,
+		until DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS share_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		hide_ips INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		miner_ip TEXT NOT NULL,
+		start_at DATETIME NOT NULL,
+		end_at DATETIME NOT NULL,
+		note TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS luck_daily (
+		miner_ip TEXT NOT NULL,
+		day TEXT NOT NULL,
+		hashes REAL NOT NULL,
+		threshold REAL NOT NULL,
+		expected_shares REAL NOT NULL,
+		shares INTEGER NOT NULL,
+		best_diff REAL NOT NULL,
+		expected_best REAL NOT NULL,
+		PRIMARY KEY (miner_ip, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS export_cursor (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		captured_until DATETIME NOT NULL
+	);
 	`
 
 	_, err := s.db.Exec(schema)
@@ -152,9 +451,51 @@ func (s *SQLiteStorage) migrate() error {
 	_, _ = s.db.Exec("ALTER TABLE miner_snapshots ADD COLUMN found_blocks INTEGER NOT NULL DEFAULT 0")
 	_, _ = s.db.Exec("ALTER TABLE miner_snapshots ADD COLUMN total_found_blocks INTEGER NOT NULL DEFAULT 0")
 
+	// Migration: add secondary temperature and fan sensors
+	_, _ = s.db.Exec("ALTER TABLE miner_snapshots ADD COLUMN temperature2 REAL NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE miner_snapshots ADD COLUMN fan2_rpm INTEGER NOT NULL DEFAULT 0")
+
+	// Migration: add per-miner power calibration
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN power_multiplier REAL NOT NULL DEFAULT 1")
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN power_offset REAL NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE miner_snapshots ADD COLUMN power_raw REAL NOT NULL DEFAULT 0")
+
+	// Migration: add tariff-aware energy cost counters
+	_, _ = s.db.Exec("ALTER TABLE energy_counters ADD COLUMN total_cost REAL NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE energy_counters ADD COLUMN day_cost REAL NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE energy_counters ADD COLUMN month_cost REAL NOT NULL DEFAULT 0")
+
+	// Migration: add energy location to miners
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN location TEXT NOT NULL DEFAULT ''")
+
+	// Migration: add firmware version to miners
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN firmware_version TEXT NOT NULL DEFAULT ''")
+
+	// Migration: add MAC address to miners
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN mac_addr TEXT NOT NULL DEFAULT ''")
+
 	// Migration: add per-miner coin override
 	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN coin_id TEXT NOT NULL DEFAULT ''")
 
+	// Migration: add user-set miner details
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN display_name TEXT NOT NULL DEFAULT ''")
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN purchase_date TEXT NOT NULL DEFAULT ''")
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN notes TEXT NOT NULL DEFAULT ''")
+
+	// Migration: add pausing miners without removing them
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN paused INTEGER NOT NULL DEFAULT 0")
+
+	// Migration: add nonce/version to shares for distribution analysis
+	_, _ = s.db.Exec("ALTER TABLE shares ADD COLUMN nonce INTEGER NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE shares ADD COLUMN version INTEGER NOT NULL DEFAULT 0")
+
+	// Migration: add network difficulty at submission time to shares
+	_, _ = s.db.Exec("ALTER TABLE shares ADD COLUMN network_difficulty REAL NOT NULL DEFAULT 0")
+
+	// Migration: add the pool's verdict to shares
+	_, _ = s.db.Exec("ALTER TABLE shares ADD COLUMN rejected INTEGER NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE shares ADD COLUMN reject_reason TEXT NOT NULL DEFAULT ''")
+
 	// Migration: add value tracking columns to blocks table
 	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN coin_id TEXT NOT NULL DEFAULT ''")
 	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN coin_symbol TEXT NOT NULL DEFAULT ''")
@@ -162,42 +503,95 @@ func (s *SQLiteStorage) migrate() error {
 	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN coin_price REAL NOT NULL DEFAULT 0")
 	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN value_usd REAL NOT NULL DEFAULT 0")
 
-	return nil
+	// Migration: add on-chain verification to blocks
+	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN block_height INTEGER NOT NULL DEFAULT 0")
+	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN block_hash TEXT NOT NULL DEFAULT ''")
+	_, _ = s.db.Exec("ALTER TABLE blocks ADD COLUMN status TEXT NOT NULL DEFAULT ''")
+
+	// Migration: add the site of miners reported by remote agents
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN site TEXT NOT NULL DEFAULT ''")
+
+	// Migration: add the pool miners are configured for
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN pool_url TEXT NOT NULL DEFAULT ''")
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN pool_port INTEGER NOT NULL DEFAULT 0")
+
+	// Migration: add the API miners are polled with
+	_, _ = s.db.Exec("ALTER TABLE miners ADD COLUMN driver TEXT NOT NULL DEFAULT ''")
+
+	// Migration: seed the permanent best shares from the retained shares
+	return s.seedBestShares()
 }
 
-// Close closes the database connection
+// Close closes the database connections
 func (s *SQLiteStorage) Close() error {
-	return s.db.Close()
+	if s.stmts != nil {
+		s.stmts.close()
+	}
+	return s.closeDBs()
 }
 
-// UpsertMiner inserts or updates a miner record
-func (s *SQLiteStorage) UpsertMiner(m *Miner) error {
-	query := `
-	INSERT INTO miners (ip, hostname, device_model, asic_model, enabled, last_seen, online)
-	VALUES (?, ?, ?, ?, ?, ?, ?)
+func (s *SQLiteStorage) closeDBs() error {
+	readErr := s.read.Close()
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return readErr
+}
+
+// Ping checks that the database answers a query
+func (s *SQLiteStorage) Ping() error {
+	var one int
+	return s.db.QueryRow("SELECT 1").Scan(&one)
+}
+
+// upsertMinerQuery inserts or updates a miner record. A paused miner stays
+// disabled.
+const upsertMinerQuery = `
+	INSERT INTO miners (ip, hostname, device_model, asic_model, enabled, last_seen, online, firmware_version, mac_addr, pool_url, pool_port, driver)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(ip) DO UPDATE SET
 		hostname = excluded.hostname,
 		device_model = excluded.device_model,
 		asic_model = excluded.asic_model,
-		enabled = excluded.enabled,
+		firmware_version = CASE WHEN excluded.firmware_version != '' THEN excluded.firmware_version ELSE miners.firmware_version END,
+		mac_addr = CASE WHEN excluded.mac_addr != '' THEN excluded.mac_addr ELSE miners.mac_addr END,
+		pool_url = CASE WHEN excluded.pool_url != '' THEN excluded.pool_url ELSE miners.pool_url END,
+		pool_port = CASE WHEN excluded.pool_url != '' THEN excluded.pool_port ELSE miners.pool_port END,
+		driver = CASE WHEN excluded.driver != '' THEN excluded.driver ELSE miners.driver END,
+		enabled = CASE WHEN miners.paused = 1 THEN 0 ELSE excluded.enabled END,
 		last_seen = excluded.last_seen,
 		online = excluded.online
 	`
 
-	_, err := s.db.Exec(query, m.IP, m.Hostname, m.DeviceModel, m.ASICModel, m.Enabled, m.LastSeen, m.Online)
+// UpsertMiner inserts or updates a miner record
+func (s *SQLiteStorage) UpsertMiner(m *Miner) error {
+	_, err := s.stmts.upsertMiner.Exec(m.IP, m.Hostname, m.DeviceModel, m.ASICModel, m.Enabled, m.LastSeen, m.Online, m.FirmwareVersion, m.MacAddr, m.PoolURL, m.PoolPort, m.Driver)
 	return err
 }
 
 // GetMiners returns all enabled miners
 func (s *SQLiteStorage) GetMiners() ([]*Miner, error) {
+	return s.queryMiners("WHERE enabled = 1")
+}
+
+// GetAllMiners returns every miner, including paused and removed ones
+func (s *SQLiteStorage) GetAllMiners() ([]*Miner, error) {
+	return s.queryMiners("")
+}
+
+func (s *SQLiteStorage) queryMiners(where string) ([]*Miner, error) {
 	query := `
-	SELECT ip, hostname, device_model, asic_model, enabled, last_seen, online, COALESCE(coin_id, '')
+	SELECT ip, hostname, device_model, asic_model, enabled, paused, last_seen, online, COALESCE(coin_id, ''),
+		COALESCE(power_multiplier, 1), COALESCE(power_offset, 0), COALESCE(firmware_version, ''),
+		COALESCE(location, ''), COALESCE(mac_addr, ''),
+		COALESCE(display_name, ''), COALESCE(purchase_date, ''), COALESCE(notes, ''), COALESCE(site, ''),
+		COALESCE(pool_url, ''), COALESCE(pool_port, 0), COALESCE(driver, '')
 	FROM miners
-	WHERE enabled = 1
+	` + where + `
 	ORDER BY ip
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.read.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +601,11 @@ func (s *SQLiteStorage) GetMiners() ([]*Miner, error) {
 	for rows.Next() {
 		m := &Miner{}
 		var lastSeen string
-		err := rows.Scan(&m.IP, &m.Hostname, &m.DeviceModel, &m.ASICModel, &m.Enabled, &lastSeen, &m.Online, &m.CoinID)
+		err := rows.Scan(&m.IP, &m.Hostname, &m.DeviceModel, &m.ASICModel, &m.Enabled, &m.Paused, &lastSeen, &m.Online, &m.CoinID,
+			&m.PowerMultiplier, &m.PowerOffset, &m.FirmwareVersion,
+			&m.Location, &m.MacAddr,
+			&m.DisplayName, &m.PurchaseDate, &m.Notes, &m.Site,
+			&m.PoolURL, &m.PoolPort, &m.Driver)
 		if err != nil {
 			return nil, err
 		}
@@ -220,20 +618,71 @@ func (s *SQLiteStorage) GetMiners() ([]*Miner, error) {
 
 // RemoveMiner sets enabled=false for the given miner IP
 func (s *SQLiteStorage) RemoveMiner(ip string) error {
-	query := `UPDATE miners SET enabled = 0 WHERE ip = ?`
+	query := `UPDATE miners SET enabled = 0, paused = 0 WHERE ip = ?`
 	_, err := s.db.Exec(query, ip)
 	return err
 }
 
+// SetMinerPaused pauses or resumes a miner. A paused miner is disabled like
+// a removed one, but keeps being disabled when polled or found by a scan.
+func (s *SQLiteStorage) SetMinerPaused(ip string, paused bool) error {
+	_, err := s.db.Exec("UPDATE miners SET enabled = ?, paused = ? WHERE ip = ?", !paused, paused, ip)
+	return err
+}
+
 // SetMinerCoin sets the coin override for a specific miner
 func (s *SQLiteStorage) SetMinerCoin(ip string, coinID string) error {
 	_, err := s.db.Exec("UPDATE miners SET coin_id = ? WHERE ip = ?", coinID, ip)
 	return err
 }
 
-// InsertSnapshot inserts a new miner snapshot
-func (s *SQLiteStorage) InsertSnapshot(snap *MinerSnapshot) error {
-	query := `
+// SetMinerLocation assigns a miner to an energy location ("" = default rate)
+func (s *SQLiteStorage) SetMinerLocation(ip string, location string) error {
+	_, err := s.db.Exec("UPDATE miners SET location = ? WHERE ip = ?", location, ip)
+	return err
+}
+
+// SetMinerSite records the site of a miner reported by a remote agent
+func (s *SQLiteStorage) SetMinerSite(ip string, site string) error {
+	_, err := s.db.Exec("UPDATE miners SET site = ? WHERE ip = ?", site, ip)
+	return err
+}
+
+// SetMinerDetails sets a miner's display name, purchase date and notes
+func (s *SQLiteStorage) SetMinerDetails(ip string, d MinerDetails) error {
+	_, err := s.db.Exec("UPDATE miners SET display_name = ?, purchase_date = ?, notes = ? WHERE ip = ?",
+		d.DisplayName, d.PurchaseDate, d.Notes, ip)
+	return err
+}
+
+// GetDisplayNames returns the display name of every miner that has one,
+// removed or not, keyed by IP
+func (s *SQLiteStorage) GetDisplayNames() (map[string]string, error) {
+	rows, err := s.read.Query("SELECT ip, display_name FROM miners WHERE display_name != ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var ip, name string
+		if err := rows.Scan(&ip, &name); err != nil {
+			return nil, err
+		}
+		names[ip] = name
+	}
+	return names, rows.Err()
+}
+
+// SetMinerPowerCalibration sets the power multiplier and offset for a specific miner
+func (s *SQLiteStorage) SetMinerPowerCalibration(ip string, cal PowerCalibration) error {
+	_, err := s.db.Exec("UPDATE miners SET power_multiplier = ?, power_offset = ? WHERE ip = ?", cal.Multiplier, cal.Offset, ip)
+	return err
+}
+
+// insertSnapshotQuery inserts one miner snapshot, with snapshotArgs
+const insertSnapshotQuery = `
 	INSERT INTO miner_snapshots (
 		miner_ip, timestamp, hostname, device_model,
 		hash_rate, hash_rate_1m, hash_rate_10m, hash_rate_1h, hash_rate_1d,
@@ -242,11 +691,14 @@ func (s *SQLiteStorage) InsertSnapshot(snap *MinerSnapshot) error {
 		shares_accepted, shares_rejected,
 		best_diff, best_diff_session, pool_difficulty, pool_connected,
 		uptime_seconds, wifi_rssi,
-		found_blocks, total_found_blocks
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		found_blocks, total_found_blocks,
+		temperature2, fan2_rpm, power_raw
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := s.db.Exec(query,
+// snapshotArgs returns the values insertSnapshotQuery inserts for a snapshot
+func snapshotArgs(snap *MinerSnapshot) []interface{} {
+	return []interface{}{
 		snap.MinerIP, snap.Timestamp.UTC().Format("2006-01-02 15:04:05"), snap.Hostname, snap.DeviceModel,
 		snap.HashRate, snap.HashRate1m, snap.HashRate10m, snap.HashRate1h, snap.HashRate1d,
 		snap.Temperature, snap.VRTemp, snap.Power, snap.Voltage,
@@ -255,7 +707,13 @@ func (s *SQLiteStorage) InsertSnapshot(snap *MinerSnapshot) error {
 		snap.BestDiff, snap.BestDiffSess, snap.PoolDiff, snap.PoolConnected,
 		snap.UptimeSecs, snap.WifiRSSI,
 		snap.FoundBlocks, snap.TotalFoundBlocks,
-	)
+		snap.Temperature2, snap.Fan2RPM, snap.PowerRaw,
+	}
+}
+
+// InsertSnapshot inserts a new miner snapshot
+func (s *SQLiteStorage) InsertSnapshot(snap *MinerSnapshot) error {
+	result, err := s.stmts.insertSnapshot.Exec(snapshotArgs(snap)...)
 	if err != nil {
 		return err
 	}
@@ -267,27 +725,44 @@ func (s *SQLiteStorage) InsertSnapshot(snap *MinerSnapshot) error {
 	return nil
 }
 
-// GetSnapshots retrieves snapshots for a miner since a given time
-func (s *SQLiteStorage) GetSnapshots(minerIP string, since time.Time, limit int) ([]*MinerSnapshot, error) {
-	query := `
-	SELECT id, miner_ip, timestamp, hostname, device_model,
+// InsertSnapshots inserts a batch of miner snapshots in one transaction:
+// either all of them are stored or none. Their IDs are not set, as the
+// snapshots may be shared with readers while they are written.
+func (s *SQLiteStorage) InsertSnapshots(snaps []*MinerSnapshot) error {
+	if len(snaps) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := tx.Stmt(s.stmts.insertSnapshot)
+	defer stmt.Close()
+
+	for _, snap := range snaps {
+		if _, err := stmt.Exec(snapshotArgs(snap)...); err != nil {
+			return fmt.Errorf("failed to insert snapshot of %s: %w", snap.MinerIP, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// snapshotColumns are the miner_snapshots columns scanSnapshots reads
+const snapshotColumns = `id, miner_ip, timestamp, hostname, device_model,
 		hash_rate, hash_rate_1m, hash_rate_10m, hash_rate_1h, hash_rate_1d,
 		temperature, vr_temp, power, voltage,
 		fan_rpm, fan_percent,
 		shares_accepted, shares_rejected,
 		best_diff, best_diff_session, pool_difficulty, pool_connected,
 		uptime_seconds, wifi_rssi,
-		COALESCE(found_blocks, 0), COALESCE(total_found_blocks, 0)
-	FROM miner_snapshots
-	WHERE miner_ip = ? AND timestamp >= ?
-	ORDER BY timestamp DESC
-	LIMIT ?
-	`
+		COALESCE(found_blocks, 0), COALESCE(total_found_blocks, 0),
+		COALESCE(temperature2, 0), COALESCE(fan2_rpm, 0), COALESCE(power_raw, 0)`
 
-	rows, err := s.db.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"), limit)
-	if err != nil {
-		return nil, err
-	}
+// scanSnapshots reads rows selected with snapshotColumns
+func scanSnapshots(rows *sql.Rows) ([]*MinerSnapshot, error) {
 	defer rows.Close()
 
 	var snapshots []*MinerSnapshot
@@ -303,6 +778,7 @@ func (s *SQLiteStorage) GetSnapshots(minerIP string, since time.Time, limit int)
 			&snap.BestDiff, &snap.BestDiffSess, &snap.PoolDiff, &snap.PoolConnected,
 			&snap.UptimeSecs, &snap.WifiRSSI,
 			&snap.FoundBlocks, &snap.TotalFoundBlocks,
+			&snap.Temperature2, &snap.Fan2RPM, &snap.PowerRaw,
 		)
 		if err != nil {
 			return nil, err
@@ -314,104 +790,215 @@ func (s *SQLiteStorage) GetSnapshots(minerIP string, since time.Time, limit int)
 	return snapshots, rows.Err()
 }
 
-// InsertShare inserts a new share record
-func (s *SQLiteStorage) InsertShare(share *Share) error {
+// GetSnapshots retrieves snapshots for a miner since a given time
+func (s *SQLiteStorage) GetSnapshots(minerIP string, since time.Time, limit int) ([]*MinerSnapshot, error) {
+	return s.getSnapshotsAfter(minerIP, since, PageCursor{}, limit)
+}
+
+// getSnapshotsAfter retrieves snapshots for a miner since a given time,
+// newest first, starting after the cursor
+func (s *SQLiteStorage) getSnapshotsAfter(minerIP string, since time.Time, after PageCursor, limit int) ([]*MinerSnapshot, error) {
 	query := `
-	INSERT INTO shares (miner_ip, hostname, timestamp, asic_num, difficulty, job_id)
-	VALUES (?, ?, ?, ?, ?, ?)
+	SELECT ` + snapshotColumns + `
+	FROM miner_snapshots
+	WHERE miner_ip = ? AND timestamp >= ?
+		AND (? = '' OR timestamp < ? OR (timestamp = ? AND id < ?))
+	ORDER BY timestamp DESC, id DESC
+	LIMIT ?
+	`
+
+	ts := after.timestamp()
+	rows, err := s.read.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"), ts, ts, ts, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSnapshots(rows)
+}
+
+// GetLatestSnapshots returns every miner's newest snapshot since a given
+// time in one query, keyed by miner IP. Miners without snapshots since then
+// are absent.
+func (s *SQLiteStorage) GetLatestSnapshots(since time.Time) (map[string]*MinerSnapshot, error) {
+	query := `
+	SELECT ` + snapshotColumns + `
+	FROM miner_snapshots
+	JOIN (
+		SELECT miner_ip AS latest_ip, MAX(timestamp) AS latest_ts
+		FROM miner_snapshots
+		WHERE timestamp >= ?
+		GROUP BY miner_ip
+	) ON miner_ip = latest_ip AND timestamp = latest_ts
+	ORDER BY id
+	`
+
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := scanSnapshots(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later rows win if a miner has two snapshots in the same second
+	latest := make(map[string]*MinerSnapshot, len(snapshots))
+	for _, snap := range snapshots {
+		latest[snap.MinerIP] = snap
+	}
+	return latest, nil
+}
+
+// insertShareQuery inserts a share record
+const insertShareQuery = `
+	INSERT INTO shares (miner_ip, hostname, timestamp, asic_num, difficulty, job_id, nonce, version, network_difficulty)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := s.db.Exec(query, share.MinerIP, share.Hostname, share.Timestamp.UTC().Format("2006-01-02 15:04:05"), share.AsicNum, share.Difficulty, share.JobID)
+// InsertShare inserts a new share record, and keeps it among its miner's
+// best shares if it is one, in one transaction
+func (s *SQLiteStorage) InsertShare(share *Share) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Stmt(s.stmts.insertShare).Exec(share.MinerIP, share.Hostname, share.Timestamp.UTC().Format("2006-01-02 15:04:05"), share.AsicNum, share.Difficulty, share.JobID, share.Nonce, share.Version, share.NetworkDifficulty)
 	if err != nil {
 		return err
 	}
 
 	id, err := result.LastInsertId()
-	if err == nil {
-		share.ID = id
+	if err != nil {
+		return tx.Commit()
 	}
-	return nil
+	share.ID = id
+	if err := s.keepBestShare(tx, share); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkShareRejected records that the pool rejected a share. A rejected share
+// doesn't count towards records or competitions, so it is also dropped from
+// the kept best shares, and the miner's next best share takes its place.
+func (s *SQLiteStorage) MarkShareRejected(id int64, reason string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE shares SET rejected = 1, reject_reason = ? WHERE id = ?", reason, id); err != nil {
+		return err
+	}
+	if err := s.dropBestShare(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetShares retrieves shares since a given time
 func (s *SQLiteStorage) GetShares(since time.Time, limit int) ([]*Share, error) {
+	return s.getSharesAfter(since, PageCursor{}, limit)
+}
+
+// getSharesAfter retrieves shares since a given time, newest first, starting
+// after the cursor
+func (s *SQLiteStorage) getSharesAfter(since time.Time, after PageCursor, limit int) ([]*Share, error) {
 	query := `
-	SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id
+	SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id, nonce, version, network_difficulty, rejected, reject_reason
 	FROM shares
-	WHERE timestamp >= ?
-	ORDER BY timestamp DESC
+	WHERE timestamp >= ? AND (? = '' OR timestamp < ? OR (timestamp = ? AND id < ?))
+	ORDER BY timestamp DESC, id DESC
 	LIMIT ?
 	`
 
-	rows, err := s.db.Query(query, since.UTC().Format("2006-01-02 15:04:05"), limit)
+	ts := after.timestamp()
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"), ts, ts, ts, after.ID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return scanShares(rows)
+}
+
+// GetMinerShares retrieves a miner's shares since a given time, newest first,
+// or highest difficulty first when byDifficulty is set
+func (s *SQLiteStorage) GetMinerShares(minerIP string, since time.Time, limit int, byDifficulty bool) ([]*Share, error) {
+	order := "timestamp DESC"
+	if byDifficulty {
+		order = "difficulty DESC"
+	}
+	query := `
+	SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id, nonce, version, network_difficulty, rejected, reject_reason
+	FROM shares
+	WHERE miner_ip = ? AND timestamp >= ?
+	ORDER BY ` + order + `
+	LIMIT ?
+	`
+
+	rows, err := s.read.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanShares(rows)
+}
+
+// scanShares reads share rows selected with the columns used by GetShares
+func scanShares(rows *sql.Rows) ([]*Share, error) {
 	var shares []*Share
 	for rows.Next() {
 		share := &Share{}
 		var timestamp string
-		err := rows.Scan(&share.ID, &share.MinerIP, &share.Hostname, &timestamp, &share.AsicNum, &share.Difficulty, &share.JobID)
+		var networkDiff float64
+		err := rows.Scan(&share.ID, &share.MinerIP, &share.Hostname, &timestamp, &share.AsicNum, &share.Difficulty, &share.JobID, &share.Nonce, &share.Version, &networkDiff, &share.Rejected, &share.RejectReason)
 		if err != nil {
 			return nil, err
 		}
 		share.Timestamp = parseTimestamp(timestamp)
+		share.SetNetworkDifficulty(networkDiff)
 		shares = append(shares, share)
 	}
 
 	return shares, rows.Err()
 }
 
-// GetBestShare retrieves the best (highest difficulty) share for a miner
-// If sessionOnly is true, only considers shares from the current session (last 24h)
-func (s *SQLiteStorage) GetBestShare(minerIP string, sessionOnly bool) (*Share, error) {
-	var query string
-	var args []interface{}
-
-	if sessionOnly {
-		since := time.Now().Add(-24 * time.Hour).UTC().Format("2006-01-02 15:04:05")
-		query = `
-		SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id
-		FROM shares
-		WHERE miner_ip = ? AND timestamp >= ?
-		ORDER BY difficulty DESC
-		LIMIT 1
-		`
-		args = []interface{}{minerIP, since}
-	} else {
-		query = `
-		SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id
-		FROM shares
-		WHERE miner_ip = ?
-		ORDER BY difficulty DESC
-		LIMIT 1
-		`
-		args = []interface{}{minerIP}
-	}
-
-	share := &Share{}
-	var timestamp string
-	err := s.db.QueryRow(query, args...).Scan(
-		&share.ID, &share.MinerIP, &share.Hostname, &timestamp, &share.AsicNum, &share.Difficulty, &share.JobID,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+// GetMinerShareNonces retrieves the ASIC number, nonce and version of a miner's
+// shares since a given time, for nonce distribution analysis
+func (s *SQLiteStorage) GetMinerShareNonces(minerIP string, since time.Time) ([]*Share, error) {
+	query := `
+	SELECT asic_num, nonce, version
+	FROM shares
+	WHERE miner_ip = ? AND timestamp >= ? AND (nonce != 0 OR version != 0)
+	`
+
+	rows, err := s.read.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var shares []*Share
+	for rows.Next() {
+		share := &Share{MinerIP: minerIP}
+		if err := rows.Scan(&share.AsicNum, &share.Nonce, &share.Version); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
 
-	share.Timestamp = parseTimestamp(timestamp)
-	return share, nil
+	return shares, rows.Err()
 }
 
 // InsertBlock inserts a new block record
 func (s *SQLiteStorage) InsertBlock(block *Block) error {
 	query := `
-	INSERT INTO blocks (miner_ip, hostname, timestamp, difficulty, network_difficulty, coin_id, coin_symbol, block_reward, coin_price, value_usd)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO blocks (miner_ip, hostname, timestamp, difficulty, network_difficulty, coin_id, coin_symbol, block_reward, coin_price, value_usd, block_height, status)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := s.db.Exec(query,
@@ -425,6 +1012,8 @@ func (s *SQLiteStorage) InsertBlock(block *Block) error {
 		block.BlockReward,
 		block.CoinPrice,
 		block.ValueUSD,
+		block.Height,
+		block.Status,
 	)
 	if err != nil {
 		return err
@@ -439,22 +1028,57 @@ func (s *SQLiteStorage) InsertBlock(block *Block) error {
 
 // GetBlocks retrieves blocks since a given time
 func (s *SQLiteStorage) GetBlocks(since time.Time, limit int) ([]*Block, error) {
+	return s.getBlocksAfter(since, PageCursor{}, limit)
+}
+
+// getBlocksAfter retrieves blocks since a given time, newest first, starting
+// after the cursor
+func (s *SQLiteStorage) getBlocksAfter(since time.Time, after PageCursor, limit int) ([]*Block, error) {
 	query := `
 	SELECT id, miner_ip, hostname, timestamp, difficulty, network_difficulty,
 	       COALESCE(coin_id, ''), COALESCE(coin_symbol, ''), COALESCE(block_reward, 0),
-	       COALESCE(coin_price, 0), COALESCE(value_usd, 0)
+	       COALESCE(coin_price, 0), COALESCE(value_usd, 0),
+	       block_height, block_hash, status
 	FROM blocks
-	WHERE timestamp >= ?
+	WHERE timestamp >= ? AND (? = '' OR timestamp < ? OR (timestamp = ? AND id < ?))
+	ORDER BY timestamp DESC, id DESC
+	LIMIT ?
+	`
+
+	ts := after.timestamp()
+	rows, err := s.read.Query(query, since.UTC().Format("2006-01-02 15:04:05"), ts, ts, ts, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBlocks(rows)
+}
+
+// GetMinerBlocks retrieves every block a miner has found, newest first
+func (s *SQLiteStorage) GetMinerBlocks(minerIP string, limit int) ([]*Block, error) {
+	query := `
+	SELECT id, miner_ip, hostname, timestamp, difficulty, network_difficulty,
+	       COALESCE(coin_id, ''), COALESCE(coin_symbol, ''), COALESCE(block_reward, 0),
+	       COALESCE(coin_price, 0), COALESCE(value_usd, 0),
+	       block_height, block_hash, status
+	FROM blocks
+	WHERE miner_ip = ?
 	ORDER BY timestamp DESC
 	LIMIT ?
 	`
 
-	rows, err := s.db.Query(query, since.UTC().Format("2006-01-02 15:04:05"), limit)
+	rows, err := s.read.Query(query, minerIP, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return scanBlocks(rows)
+}
+
+// scanBlocks reads block rows selected with the columns used by GetBlocks
+func scanBlocks(rows *sql.Rows) ([]*Block, error) {
 	var blocks []*Block
 	for rows.Next() {
 		block := &Block{}
@@ -462,7 +1086,8 @@ func (s *SQLiteStorage) GetBlocks(since time.Time, limit int) ([]*Block, error)
 		err := rows.Scan(&block.ID, &block.MinerIP, &block.Hostname, &timestamp,
 			&block.Difficulty, &block.NetworkDifficulty,
 			&block.CoinID, &block.CoinSymbol, &block.BlockReward,
-			&block.CoinPrice, &block.ValueUSD)
+			&block.CoinPrice, &block.ValueUSD,
+			&block.Height, &block.Hash, &block.Status)
 		if err != nil {
 			return nil, err
 		}
@@ -476,7 +1101,7 @@ func (s *SQLiteStorage) GetBlocks(since time.Time, limit int) ([]*Block, error)
 // GetBlockCount returns the total number of blocks found
 func (s *SQLiteStorage) GetBlockCount() (int64, error) {
 	var count int64
-	err := s.db.QueryRow("SELECT COUNT(*) FROM blocks").Scan(&count)
+	err := s.read.QueryRow("SELECT COUNT(*) FROM blocks").Scan(&count)
 	return count, err
 }
 
@@ -495,15 +1120,16 @@ func (s *SQLiteStorage) GetMoneyMakers() ([]*MoneyMaker, error) {
 	query := `
 	SELECT
 		miner_ip,
-		MAX(hostname) as hostname,
+		COALESCE((SELECT m.hostname FROM miners m WHERE m.ip = blocks.miner_ip), MAX(hostname)) as hostname,
 		COALESCE(SUM(value_usd), 0) as total_usd,
 		COUNT(*) as block_count
 	FROM blocks
+	WHERE status != 'orphaned'
 	GROUP BY miner_ip
 	ORDER BY total_usd DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.read.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -527,11 +1153,11 @@ func (s *SQLiteStorage) GetWeeklyEarnings(minerIP string, since time.Time) (floa
 	query := `
 	SELECT COALESCE(SUM(value_usd), 0), COUNT(*)
 	FROM blocks
-	WHERE miner_ip = ? AND timestamp >= ?
+	WHERE miner_ip = ? AND timestamp >= ? AND status != 'orphaned'
 	`
 	var totalUSD float64
 	var blockCount int
-	err := s.db.QueryRow(query, minerIP, since.UTC().Format("2006-01-02 15:04:05")).Scan(&totalUSD, &blockCount)
+	err := s.read.QueryRow(query, minerIP, since.UTC().Format("2006-01-02 15:04:05")).Scan(&totalUSD, &blockCount)
 	return totalUSD, blockCount, err
 }
 
@@ -563,12 +1189,12 @@ func (s *SQLiteStorage) GetTotalEarnings() ([]*CoinEarnings, error) {
 		COUNT(*) as block_count,
 		COALESCE(SUM(value_usd), 0) as historical_usd
 	FROM blocks
-	WHERE coin_id != ''
+	WHERE coin_id != '' AND status != 'orphaned'
 	GROUP BY coin_id
 	ORDER BY historical_usd DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.read.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -597,12 +1223,12 @@ func (s *SQLiteStorage) GetEarningsForCoin(coinID string) (*CoinEarnings, error)
 		COUNT(*) as block_count,
 		COALESCE(SUM(value_usd), 0) as historical_usd
 	FROM blocks
-	WHERE coin_id = ?
+	WHERE coin_id = ? AND status != 'orphaned'
 	GROUP BY coin_id
 	`
 
 	e := &CoinEarnings{}
-	err := s.db.QueryRow(query, coinID).Scan(&e.CoinID, &e.CoinSymbol, &e.TotalCoins, &e.BlockCount, &e.HistoricalUSD)
+	err := s.read.QueryRow(query, coinID).Scan(&e.CoinID, &e.CoinSymbol, &e.TotalCoins, &e.BlockCount, &e.HistoricalUSD)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -623,12 +1249,12 @@ func (s *SQLiteStorage) GetMinerCoinHoldings() ([]*CoinHolding, error) {
 		COALESCE(SUM(block_reward), 0) as total_coins,
 		COUNT(*) as block_count
 	FROM blocks
-	WHERE coin_id != ''
+	WHERE coin_id != '' AND status != 'orphaned'
 	GROUP BY miner_ip, coin_id
 	ORDER BY miner_ip, total_coins DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.read.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -657,11 +1283,11 @@ func (s *SQLiteStorage) GetWeeklyCoinHoldings(minerIP string, since time.Time) (
 		COALESCE(SUM(block_reward), 0) as total_coins,
 		COUNT(*) as block_count
 	FROM blocks
-	WHERE miner_ip = ? AND timestamp >= ? AND coin_id != ''
+	WHERE miner_ip = ? AND timestamp >= ? AND coin_id != '' AND status != 'orphaned'
 	GROUP BY miner_ip, coin_id
 	`
 
-	rows, err := s.db.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"))
+	rows, err := s.read.Query(query, minerIP, since.UTC().Format("2006-01-02 15:04:05"))
 	if err != nil {
 		return nil, err
 	}
@@ -680,180 +1306,94 @@ func (s *SQLiteStorage) GetWeeklyCoinHoldings(minerIP string, since time.Time) (
 	return holdings, rows.Err()
 }
 
-// GetBestShareInRange retrieves the best share for a miner within a time range
-func (s *SQLiteStorage) GetBestShareInRange(minerIP string, start, end time.Time) (*Share, error) {
-	query := `
-	SELECT id, miner_ip, hostname, timestamp, asic_num, difficulty, job_id
-	FROM shares
-	WHERE miner_ip = ? AND timestamp >= ? AND timestamp <= ?
-	ORDER BY difficulty DESC
-	LIMIT 1
-	`
-
-	share := &Share{}
-	var timestamp string
-	err := s.db.QueryRow(query, minerIP, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")).Scan(
-		&share.ID, &share.MinerIP, &share.Hostname, &timestamp, &share.AsicNum, &share.Difficulty, &share.JobID,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	share.Timestamp = parseTimestamp(timestamp)
-	return share, nil
-}
-
-// GetShareCountInRange counts shares for a miner within a time range
-func (s *SQLiteStorage) GetShareCountInRange(minerIP string, start, end time.Time) (int, error) {
-	query := `
-	SELECT COUNT(*) FROM shares
-	WHERE miner_ip = ? AND timestamp >= ? AND timestamp <= ?
-	`
-
-	var count int
-	err := s.db.QueryRow(query, minerIP, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")).Scan(&count)
-	return count, err
-}
-
-// GetBlockCountInRange counts blocks for a miner within a time range
-func (s *SQLiteStorage) GetBlockCountInRange(minerIP string, start, end time.Time) (int, error) {
-	query := `
-	SELECT COUNT(*) FROM blocks
-	WHERE miner_ip = ? AND timestamp >= ? AND timestamp <= ?
-	`
-
-	var count int
-	err := s.db.QueryRow(query, minerIP, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")).Scan(&count)
-	return count, err
-}
-
 // GetBlockCountAllTime counts all blocks for a miner
 func (s *SQLiteStorage) GetBlockCountAllTime(minerIP string) (int, error) {
 	query := `SELECT COUNT(*) FROM blocks WHERE miner_ip = ?`
 	var count int
-	err := s.db.QueryRow(query, minerIP).Scan(&count)
+	err := s.read.QueryRow(query, minerIP).Scan(&count)
 	return count, err
 }
 
-// GetBlockStreak calculates consecutive weeks with at least 1 block for a miner
-func (s *SQLiteStorage) GetBlockStreak(minerIP string) (int, error) {
-	// Get all blocks for this miner ordered by timestamp
-	query := `
-	SELECT timestamp FROM blocks
-	WHERE miner_ip = ?
-	ORDER BY timestamp DESC
-	`
-
-	rows, err := s.db.Query(query, minerIP)
-	if err != nil {
-		return 0, err
-	}
-	defer rows.Close()
-
-	// Collect all block timestamps
-	var timestamps []time.Time
-	for rows.Next() {
-		var ts string
-		if err := rows.Scan(&ts); err != nil {
-			continue
-		}
-		timestamps = append(timestamps, parseTimestamp(ts))
-	}
-
-	if len(timestamps) == 0 {
-		return 0, nil
-	}
-
-	// Calculate which weeks have blocks
-	weeksWithBlocks := make(map[string]bool)
-	for _, ts := range timestamps {
-		// Get the Sunday of that week
-		weekday := int(ts.Weekday())
-		weekStart := time.Date(ts.Year(), ts.Month(), ts.Day()-weekday, 0, 0, 0, 0, ts.Location())
-		weekKey := weekStart.Format("2006-01-02")
-		weeksWithBlocks[weekKey] = true
-	}
+// PurgeOldData removes data older than the specified retention period and
+// returns how many rows it deleted from each table. The space freed is
+// reclaimed by Compact.
+func (s *SQLiteStorage) PurgeOldData(retentionDays int) ([]TableStat, error) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
 
-	// Calculate streak from current week backwards
-	now := time.Now()
-	weekday := int(now.Weekday())
-	currentWeekStart := time.Date(now.Year(), now.Month(), now.Day()-weekday, 0, 0, 0, 0, now.Location())
-
-	streak := 0
-	for {
-		weekKey := currentWeekStart.Format("2006-01-02")
-		if weeksWithBlocks[weekKey] {
-			streak++
-			currentWeekStart = currentWeekStart.AddDate(0, 0, -7) // Go to previous week
-		} else {
-			break
+	// Note: We don't delete blocks - they are rare and historically valuable
+	purged := make([]TableStat, 0, len(oldDataTables))
+	for _, table := range oldDataTables {
+		deleted, err := s.PurgeTable(table, cutoff)
+		if err != nil {
+			return nil, err
 		}
+		purged = append(purged, TableStat{Name: table, Rows: deleted})
 	}
-
-	return streak, nil
+	return purged, nil
 }
 
-// PurgeOldData removes data older than the specified retention period
-func (s *SQLiteStorage) PurgeOldData(retentionDays int) error {
-	cutoff := time.Now().AddDate(0, 0, -retentionDays).UTC().Format("2006-01-02 15:04:05")
-
-	// Delete old snapshots
-	_, err := s.db.Exec("DELETE FROM miner_snapshots WHERE timestamp < ?", cutoff)
-	if err != nil {
-		return fmt.Errorf("failed to purge old snapshots: %w", err)
-	}
-
-	// Delete old shares
-	_, err = s.db.Exec("DELETE FROM shares WHERE timestamp < ?", cutoff)
-	if err != nil {
-		return fmt.Errorf("failed to purge old shares: %w", err)
-	}
+// PurgeOldShares removes shares older than the specified number of hours
+func (s *SQLiteStorage) PurgeOldShares(retentionHours int) (int64, error) {
+	return s.PurgeTable("shares", time.Now().Add(-time.Duration(retentionHours)*time.Hour))
+}
 
-	// Note: We don't delete blocks - they are rare and historically valuable
+// PurgeOldSnapshots removes snapshots older than the specified number of hours
+func (s *SQLiteStorage) PurgeOldSnapshots(retentionHours int) (int64, error) {
+	return s.PurgeTable("miner_snapshots", time.Now().Add(-time.Duration(retentionHours)*time.Hour))
+}
 
-	// Run VACUUM to reclaim space
-	_, err = s.db.Exec("VACUUM")
+// Vacuum compacts the database file to reclaim disk space after deletions
+func (s *SQLiteStorage) Vacuum() error {
+	_, err := s.db.Exec("VACUUM")
 	if err != nil {
 		return fmt.Errorf("failed to vacuum database: %w", err)
 	}
-
 	return nil
 }
 
-// PurgeOldShares removes shares older than the specified number of hours
-func (s *SQLiteStorage) PurgeOldShares(retentionHours int) (int64, error) {
-	cutoff := time.Now().Add(-time.Duration(retentionHours) * time.Hour).UTC().Format("2006-01-02 15:04:05")
+// TableStat holds the row count for a single table
+type TableStat struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
 
-	result, err := s.db.Exec("DELETE FROM shares WHERE timestamp < ?", cutoff)
-	if err != nil {
-		return 0, fmt.Errorf("failed to purge old shares: %w", err)
+// dataTables lists the tables managed by MinerHQ, in display order.
+// export_cursor is left out: it tracks the export staging files, which live
+// outside the database and aren't restored with it.
+var dataTables = []string{"miners", "miner_snapshots", "shares", "best_shares", "blocks", "audit_log", "energy_counters", "hostname_history", "pool_difficulty_changes", "records", "competition_results", "miner_logs", "failed_deliveries", "alerts", "snapshots_hourly", "snapshots_daily", "energy_daily", "miner_tags", "miner_alert_overrides", "price_history", "uptime_events", "schedules", "schedule_runs", "schedule_overrides", "share_links", "maintenance_windows", "luck_daily"}
+
+// GetTableStats returns row counts for every MinerHQ table
+func (s *SQLiteStorage) GetTableStats() ([]TableStat, error) {
+	stats := make([]TableStat, 0, len(dataTables))
+	for _, table := range dataTables {
+		var count int64
+		if err := s.read.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		stats = append(stats, TableStat{Name: table, Rows: count})
 	}
-
-	deleted, _ := result.RowsAffected()
-	return deleted, nil
+	return stats, nil
 }
 
-// PurgeOldSnapshots removes snapshots older than the specified number of hours
-func (s *SQLiteStorage) PurgeOldSnapshots(retentionHours int) (int64, error) {
-	cutoff := time.Now().Add(-time.Duration(retentionHours) * time.Hour).UTC().Format("2006-01-02 15:04:05")
-
-	result, err := s.db.Exec("DELETE FROM miner_snapshots WHERE timestamp < ?", cutoff)
+// IntegrityCheck runs SQLite's integrity check and returns the reported problems.
+// An empty result means the database is healthy.
+func (s *SQLiteStorage) IntegrityCheck() ([]string, error) {
+	rows, err := s.read.Query("PRAGMA integrity_check")
 	if err != nil {
-		return 0, fmt.Errorf("failed to purge old snapshots: %w", err)
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
 	}
+	defer rows.Close()
 
-	deleted, _ := result.RowsAffected()
-	return deleted, nil
-}
-
-// Vacuum compacts the database file to reclaim disk space after deletions
-func (s *SQLiteStorage) Vacuum() error {
-	_, err := s.db.Exec("VACUUM")
-	if err != nil {
-		return fmt.Errorf("failed to vacuum database: %w", err)
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
 	}
-	return nil
+
+	return problems, rows.Err()
 }
@@ -1,10 +1,16 @@
 package storage
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/camarigor/miner-hq/internal/competition"
 )
 
 func setupTestDB(t *testing.T) (*SQLiteStorage, func()) {
@@ -87,6 +93,50 @@ func TestSQLiteStorage(t *testing.T) {
 			t.Errorf("expected updated hostname, got %s", miners[0].Hostname)
 		}
 
+		// Power calibration defaults to a no-op and survives upserts
+		if miners[0].PowerMultiplier != 1 || miners[0].PowerOffset != 0 {
+			t.Errorf("expected default calibration 1/0, got %v/%v", miners[0].PowerMultiplier, miners[0].PowerOffset)
+		}
+		if err := storage.SetMinerPowerCalibration(miner.IP, PowerCalibration{Multiplier: 1.25, Offset: 2}); err != nil {
+			t.Fatalf("failed to set power calibration: %v", err)
+		}
+		if err := storage.UpsertMiner(miner); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+		miners, err = storage.GetMiners()
+		if err != nil {
+			t.Fatalf("failed to get miners after calibration: %v", err)
+		}
+		if got := miners[0].PowerCalibration().Apply(100); got != 127 {
+			t.Errorf("expected calibrated power 127W, got %v", got)
+		}
+
+		// The pool is kept when a poll doesn't report one
+		miner.PoolURL, miner.PoolPort = "public-pool.io", 21496
+		if err := storage.UpsertMiner(miner); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+		miner.PoolURL, miner.PoolPort = "", 0
+		if err := storage.UpsertMiner(miner); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+		if miners, _ = storage.GetMiners(); miners[0].PoolURL != "public-pool.io" || miners[0].PoolPort != 21496 {
+			t.Errorf("expected pool public-pool.io:21496, got %s:%d", miners[0].PoolURL, miners[0].PoolPort)
+		}
+
+		// So is the driver
+		miner.Driver = "cgminer"
+		if err := storage.UpsertMiner(miner); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+		miner.Driver = ""
+		if err := storage.UpsertMiner(miner); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+		if miners, _ = storage.GetMiners(); miners[0].Driver != "cgminer" {
+			t.Errorf("expected driver cgminer, got %q", miners[0].Driver)
+		}
+
 		// Remove the miner (soft delete)
 		err = storage.RemoveMiner(miner.IP)
 		if err != nil {
@@ -122,10 +172,12 @@ func TestSQLiteStorage(t *testing.T) {
 				HashRate1h:    498.0,
 				HashRate1d:    500.0,
 				Temperature:   45.5,
+				Temperature2:  47.0,
 				VRTemp:        50.0,
 				Power:         15.5,
 				Voltage:       5.0,
 				FanRPM:        3000,
+				Fan2RPM:       2800,
 				FanPercent:    75,
 				SharesAccept:  int64(100 + i),
 				SharesReject:  1,
@@ -158,6 +210,10 @@ func TestSQLiteStorage(t *testing.T) {
 			t.Fatalf("expected 5 snapshots, got %d", len(snapshots))
 		}
 
+		if snapshots[0].Temperature2 != 47.0 || snapshots[0].Fan2RPM != 2800 {
+			t.Errorf("expected secondary sensors 47.0°C/2800 RPM, got %.1f/%d", snapshots[0].Temperature2, snapshots[0].Fan2RPM)
+		}
+
 		// Verify order (should be newest first)
 		if snapshots[0].HashRate < snapshots[1].HashRate {
 			// The newest should have hash rate 500.0 (i=0)
@@ -192,6 +248,7 @@ func TestSQLiteStorage(t *testing.T) {
 				Difficulty: diff,
 				JobID:      "job-" + string(rune('A'+i)),
 			}
+			share.SetNetworkDifficulty(1e6)
 
 			err := storage.InsertShare(share)
 			if err != nil {
@@ -228,6 +285,10 @@ func TestSQLiteStorage(t *testing.T) {
 			t.Errorf("expected best difficulty 10000.0, got %f", bestShare.Difficulty)
 		}
 
+		if bestShare.NetworkDifficulty != 1e6 || bestShare.NetworkPct != 1.0 {
+			t.Errorf("expected 1%% of network difficulty 1e6, got %f%% of %f", bestShare.NetworkPct, bestShare.NetworkDifficulty)
+		}
+
 		// Get best share (session only - same result since all within 24h)
 		bestShareSession, err := storage.GetBestShare(minerIP, true)
 		if err != nil {
@@ -294,11 +355,54 @@ func TestSQLiteStorage(t *testing.T) {
 		}
 		storage.InsertShare(newShare)
 
+		// Preview first: nothing may be deleted
+		estimates, err := storage.PreviewPurgeOldData(7)
+		if err != nil {
+			t.Fatalf("failed to preview purge: %v", err)
+		}
+		expected := map[string]int64{
+			"miner_snapshots":         1,
+			"shares":                  1,
+			"pool_difficulty_changes": 0,
+			"snapshots_hourly":        0,
+			"failed_deliveries":       0,
+		}
+		if len(estimates) != len(expected) {
+			t.Errorf("expected preview of %d tables, got %+v", len(expected), estimates)
+		}
+		for _, e := range estimates {
+			want, ok := expected[e.Table]
+			if !ok {
+				t.Errorf("unexpected table %s in preview", e.Table)
+				continue
+			}
+			if e.Rows != want {
+				t.Errorf("expected preview to report %d old rows in %s, got %d", want, e.Table, e.Rows)
+			}
+			if want > 0 && e.Bytes <= 0 {
+				t.Errorf("expected preview to estimate reclaimed bytes for %s, got %d", e.Table, e.Bytes)
+			}
+			if want == 0 && e.Bytes != 0 {
+				t.Errorf("expected no reclaimed bytes for %s, got %d", e.Table, e.Bytes)
+			}
+		}
+		if shares, _ := storage.GetShares(now.AddDate(0, 0, -30), 100); len(shares) != 2 {
+			t.Errorf("expected dry run to keep both shares, got %d", len(shares))
+		}
+
 		// Purge data older than 7 days
-		err := storage.PurgeOldData(7)
+		purged, err := storage.PurgeOldData(7)
 		if err != nil {
 			t.Fatalf("failed to purge old data: %v", err)
 		}
+		if len(purged) != len(expected) {
+			t.Errorf("expected purge of %d tables, got %+v", len(expected), purged)
+		}
+		for _, p := range purged {
+			if p.Rows != expected[p.Name] {
+				t.Errorf("expected purge to report %d deleted rows in %s, got %d", expected[p.Name], p.Name, p.Rows)
+			}
+		}
 
 		// Check snapshots - should only have the new one
 		snapshots, err := storage.GetSnapshots(minerIP, now.AddDate(0, 0, -30), 100)
@@ -321,3 +425,1745 @@ func TestSQLiteStorage(t *testing.T) {
 		}
 	})
 }
+
+func TestMaintenance(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	storage.UpsertMiner(&Miner{IP: "192.168.1.100", Hostname: "miner-001", Enabled: true, LastSeen: time.Now()})
+	for i := 0; i < 3; i++ {
+		storage.InsertShare(&Share{MinerIP: "192.168.1.100", Timestamp: time.Now(), Difficulty: 1000})
+	}
+
+	stats, err := storage.GetTableStats()
+	if err != nil {
+		t.Fatalf("failed to get table stats: %v", err)
+	}
+
+	counts := make(map[string]int64)
+	for _, s := range stats {
+		counts[s.Name] = s.Rows
+	}
+	if counts["miners"] != 1 {
+		t.Errorf("expected 1 miner row, got %d", counts["miners"])
+	}
+	if counts["shares"] != 3 {
+		t.Errorf("expected 3 share rows, got %d", counts["shares"])
+	}
+
+	usage, err := storage.GetTableUsage()
+	if err != nil {
+		t.Fatalf("failed to get table usage: %v", err)
+	}
+	for _, u := range usage {
+		if u.Name != "shares" {
+			continue
+		}
+		if u.Rows != 3 || u.RowsPerDay != 3 {
+			t.Errorf("expected 3 share rows, all from today, got %d (%d/day)", u.Rows, u.RowsPerDay)
+		}
+		if u.Bytes <= 0 || u.BytesPerDay != u.Bytes {
+			t.Errorf("expected share bytes to be estimated and all recent, got %d (%d/day)", u.Bytes, u.BytesPerDay)
+		}
+	}
+
+	problems, err := storage.IntegrityCheck()
+	if err != nil {
+		t.Fatalf("integrity check failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected healthy database, got %v", problems)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	entries := []*AuditEntry{
+		{Timestamp: now.Add(-2 * time.Hour), Username: "admin", Method: "DELETE", Path: "/api/miners/10.0.0.5", Action: "DELETE /api/miners/{ip}", Target: "10.0.0.5", Status: 200},
+		{Timestamp: now.Add(-1 * time.Hour), Username: "kid", Method: "POST", Path: "/api/purge", Action: "POST /api/purge", Status: 403},
+		{Timestamp: now.AddDate(0, 0, -10), Username: "admin", Method: "POST", Path: "/api/settings", Action: "POST /api/settings", Status: 200},
+	}
+	for _, e := range entries {
+		if err := storage.InsertAuditEntry(e); err != nil {
+			t.Fatalf("failed to insert audit entry: %v", err)
+		}
+	}
+
+	recent, err := storage.GetAuditEntries(now.AddDate(0, 0, -7), "", "", 100)
+	if err != nil {
+		t.Fatalf("failed to get audit entries: %v", err)
+	}
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 recent entries, got %d", len(recent))
+	}
+	if recent[0].Username != "kid" {
+		t.Errorf("expected newest entry first, got %s", recent[0].Username)
+	}
+
+	byMiner, err := storage.GetAuditEntries(now.AddDate(0, 0, -30), "", "10.0.0.5", 100)
+	if err != nil {
+		t.Fatalf("failed to filter audit entries: %v", err)
+	}
+	if len(byMiner) != 1 || byMiner[0].Action != "DELETE /api/miners/{ip}" {
+		t.Errorf("expected the miner deletion entry, got %+v", byMiner)
+	}
+
+	byUser, _ := storage.GetAuditEntries(now.AddDate(0, 0, -30), "admin", "", 100)
+	if len(byUser) != 2 {
+		t.Errorf("expected 2 entries for admin, got %d", len(byUser))
+	}
+}
+
+func TestEnergyCounters(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	day1 := time.Date(2026, 1, 31, 22, 0, 0, 0, time.Local)
+	day2 := day1.Add(4 * time.Hour) // Feb 1st: new day and new month
+
+	if err := storage.AddEnergy("192.168.1.100", 0.5, 0.05, day1); err != nil {
+		t.Fatalf("failed to add energy: %v", err)
+	}
+	if err := storage.AddEnergy("192.168.1.100", 0.25, 0.05, day1.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to add energy: %v", err)
+	}
+
+	counters, err := storage.GetEnergyCounters(day1)
+	if err != nil {
+		t.Fatalf("failed to get energy counters: %v", err)
+	}
+	if len(counters) != 1 {
+		t.Fatalf("expected 1 counter, got %d", len(counters))
+	}
+	if c := counters[0]; c.TotalKWh != 0.75 || c.DayKWh != 0.75 || c.MonthKWh != 0.75 {
+		t.Errorf("expected 0.75 kWh total/day/month, got %v/%v/%v", c.TotalKWh, c.DayKWh, c.MonthKWh)
+	}
+
+	// Reading after rollover reports zero for the new period until data arrives
+	counters, err = storage.GetEnergyCounters(day2)
+	if err != nil {
+		t.Fatalf("failed to get energy counters: %v", err)
+	}
+	if c := counters[0]; c.TotalKWh != 0.75 || c.DayKWh != 0 || c.MonthKWh != 0 {
+		t.Errorf("expected rolled over day/month, got %v/%v/%v", c.TotalKWh, c.DayKWh, c.MonthKWh)
+	}
+
+	if err := storage.AddEnergy("192.168.1.100", 0.5, 0.2, day2); err != nil {
+		t.Fatalf("failed to add energy: %v", err)
+	}
+	counters, err = storage.GetEnergyCounters(day2)
+	if err != nil {
+		t.Fatalf("failed to get energy counters: %v", err)
+	}
+	if c := counters[0]; c.TotalKWh != 1.25 || c.DayKWh != 0.5 || c.MonthKWh != 0.5 {
+		t.Errorf("expected 1.25 total and 0.5 day/month, got %v/%v/%v", c.TotalKWh, c.DayKWh, c.MonthKWh)
+	}
+	if c := counters[0]; math.Abs(c.TotalCost-0.3) > 1e-9 || c.DayCost != 0.2 || c.MonthCost != 0.2 {
+		t.Errorf("expected cost 0.3 total and 0.2 day/month, got %v/%v/%v", c.TotalCost, c.DayCost, c.MonthCost)
+	}
+
+	days, err := storage.GetEnergyDaily(day1)
+	if err != nil {
+		t.Fatalf("failed to get daily energy: %v", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(days))
+	}
+	if days[0].Day != "2026-01-31" || days[0].KWh != 0.75 || days[1].Day != "2026-02-01" || days[1].Cost != 0.2 {
+		t.Errorf("expected Jan 31st with 0.75 kWh and Feb 1st costing 0.2, got %+v, %+v", days[0], days[1])
+	}
+	if days, _ := storage.GetEnergyDaily(day2); len(days) != 1 {
+		t.Errorf("expected only Feb 1st since day2, got %d days", len(days))
+	}
+}
+
+func TestHostnameHistory(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ip := "192.168.1.100"
+	now := time.Now()
+
+	steps := []struct {
+		hostname     string
+		wantChanged  bool
+		wantPrevious string
+	}{
+		{hostname: "bitaxe-1", wantChanged: true, wantPrevious: ""},
+		{hostname: "bitaxe-1", wantChanged: false, wantPrevious: "bitaxe-1"},
+		{hostname: "garage-gamma", wantChanged: true, wantPrevious: "bitaxe-1"},
+	}
+
+	for i, step := range steps {
+		previous, changed, err := storage.RecordHostname(ip, step.hostname, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("step %d: failed to record hostname: %v", i, err)
+		}
+		if changed != step.wantChanged || previous != step.wantPrevious {
+			t.Errorf("step %d: got changed=%v previous=%q, want %v %q", i, changed, previous, step.wantChanged, step.wantPrevious)
+		}
+	}
+
+	history, err := storage.GetHostnameHistory(ip)
+	if err != nil {
+		t.Fatalf("failed to get hostname history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 hostname entries, got %d", len(history))
+	}
+	if history[0].Hostname != "garage-gamma" || history[1].Hostname != "bitaxe-1" {
+		t.Errorf("unexpected history order: %s, %s", history[0].Hostname, history[1].Hostname)
+	}
+}
+
+func TestPoolDifficultyChanges(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ip := "192.168.1.100"
+	now := time.Now()
+
+	steps := []struct {
+		difficulty  float64
+		wantChange  bool
+		wantOld     float64
+		wantPercent float64
+	}{
+		{difficulty: 1024, wantChange: true, wantOld: 0, wantPercent: 0},
+		{difficulty: 1024, wantChange: false},
+		{difficulty: 4096, wantChange: true, wantOld: 1024, wantPercent: 300},
+		{difficulty: 2048, wantChange: true, wantOld: 4096, wantPercent: -50},
+	}
+
+	for i, step := range steps {
+		change, err := storage.RecordPoolDifficulty(ip, "bitaxe-1", step.difficulty, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("step %d: failed to record pool difficulty: %v", i, err)
+		}
+		if (change != nil) != step.wantChange {
+			t.Fatalf("step %d: got change=%v, want %v", i, change != nil, step.wantChange)
+		}
+		if change != nil && (change.OldDifficulty != step.wantOld || change.ChangePercent() != step.wantPercent) {
+			t.Errorf("step %d: got old=%v pct=%v, want %v %v", i, change.OldDifficulty, change.ChangePercent(), step.wantOld, step.wantPercent)
+		}
+	}
+
+	changes, err := storage.GetPoolDifficultyChanges(ip, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get pool difficulty changes: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 pool difficulty changes, got %d", len(changes))
+	}
+	if changes[0].NewDifficulty != 2048 || changes[2].NewDifficulty != 1024 {
+		t.Errorf("unexpected order: %v, %v", changes[0].NewDifficulty, changes[2].NewDifficulty)
+	}
+
+	all, err := storage.GetPoolDifficultyChanges("", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get fleet pool difficulty changes: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 fleet-wide changes, got %d", len(all))
+	}
+}
+
+func TestRecords(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	steps := []struct {
+		value       float64
+		wantUpdated bool
+	}{
+		{value: 1000, wantUpdated: true},
+		{value: 500, wantUpdated: false},
+		{value: 2500, wantUpdated: true},
+	}
+	for i, step := range steps {
+		updated, err := storage.UpdateRecord(&Record{Kind: RecordBestShare, Value: step.value, MinerIP: "192.168.1.100", Hostname: "bitaxe-1", AchievedAt: now})
+		if err != nil {
+			t.Fatalf("step %d: failed to update record: %v", i, err)
+		}
+		if updated != step.wantUpdated {
+			t.Errorf("step %d: updated = %v, want %v", i, updated, step.wantUpdated)
+		}
+	}
+
+	// Blocks in three consecutive weeks, a gap, then one more
+	week := time.Date(2026, 1, 4, 12, 0, 0, 0, time.Local) // A Sunday
+	for _, offset := range []int{0, 7, 15, 35} {
+		block := &Block{MinerIP: "192.168.1.101", Hostname: "nerd-1", Timestamp: week.AddDate(0, 0, offset), Difficulty: 5000, ValueUSD: 2}
+		if err := storage.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+	}
+	streak, _, err := storage.GetLongestBlockStreak("192.168.1.101")
+	if err != nil {
+		t.Fatalf("failed to get longest block streak: %v", err)
+	}
+	if streak != 3 {
+		t.Errorf("expected longest streak of 3 weeks, got %d", streak)
+	}
+
+	if err := storage.BackfillRecords(); err != nil {
+		t.Fatalf("failed to backfill records: %v", err)
+	}
+	records, err := storage.GetRecords()
+	if err != nil {
+		t.Fatalf("failed to get records: %v", err)
+	}
+	got := make(map[string]float64)
+	for _, r := range records {
+		got[r.Kind] = r.Value
+	}
+	if got[RecordBestShare] != 5000 || got[RecordBlockStreak] != 3 || got[RecordBestDayEarnings] != 2 {
+		t.Errorf("unexpected records after backfill: %v", got)
+	}
+}
+
+func TestFailedDeliveries(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	first := &FailedDelivery{Timestamp: now.Add(-time.Hour), Channel: "discord", AlertType: "temp_high", MinerIP: "192.168.1.100", Payload: `{"embeds":[]}`, Attempts: 4, LastError: "discord returned status 503", LastAttempt: now.Add(-time.Hour)}
+	second := &FailedDelivery{Timestamp: now, Channel: "matrix", AlertType: "block_found", MinerIP: "192.168.1.101", Payload: `{"msgtype":"m.notice"}`, Attempts: 1, LastError: "matrix returned status 403", LastAttempt: now}
+	for _, d := range []*FailedDelivery{first, second} {
+		if err := storage.InsertFailedDelivery(d); err != nil {
+			t.Fatalf("failed to insert failed delivery: %v", err)
+		}
+	}
+
+	deliveries, err := storage.GetFailedDeliveries(10)
+	if err != nil {
+		t.Fatalf("failed to get failed deliveries: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != second.ID {
+		t.Fatalf("expected 2 deliveries newest first, got %+v", deliveries)
+	}
+
+	if err := storage.RecordFailedDeliveryAttempt(first.ID, "timeout", now); err != nil {
+		t.Fatalf("failed to record attempt: %v", err)
+	}
+	got, err := storage.GetFailedDelivery(first.ID)
+	if err != nil {
+		t.Fatalf("failed to get failed delivery: %v", err)
+	}
+	if got == nil || got.Attempts != 5 || got.LastError != "timeout" || got.Payload != first.Payload {
+		t.Errorf("unexpected delivery after replay attempt: %+v", got)
+	}
+
+	found, err := storage.DeleteFailedDelivery(first.ID)
+	if err != nil || !found {
+		t.Fatalf("expected delivery to be deleted, found=%v err=%v", found, err)
+	}
+	if got, _ := storage.GetFailedDelivery(first.ID); got != nil {
+		t.Errorf("expected deleted delivery to be gone, got %+v", got)
+	}
+	if found, _ := storage.DeleteFailedDelivery(first.ID); found {
+		t.Error("expected second delete to report not found")
+	}
+}
+
+func TestMinerSharesAndBlocks(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i, diff := range []float64{500, 3000, 1200} {
+		for _, ip := range []string{"192.168.1.100", "192.168.1.101"} {
+			share := &Share{MinerIP: ip, Hostname: "miner", Timestamp: now.Add(time.Duration(i) * time.Minute), Difficulty: diff}
+			if err := storage.InsertShare(share); err != nil {
+				t.Fatalf("failed to insert share: %v", err)
+			}
+		}
+	}
+	if err := storage.InsertBlock(&Block{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now, Difficulty: 3000}); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+
+	recent, err := storage.GetMinerShares("192.168.1.100", now.Add(-time.Hour), 2, false)
+	if err != nil {
+		t.Fatalf("failed to get recent shares: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Difficulty != 1200 || recent[0].MinerIP != "192.168.1.100" {
+		t.Errorf("expected the 2 newest shares of the miner, got %+v", recent)
+	}
+
+	best, err := storage.GetMinerShares("192.168.1.100", now.Add(-time.Hour), 1, true)
+	if err != nil {
+		t.Fatalf("failed to get best shares: %v", err)
+	}
+	if len(best) != 1 || best[0].Difficulty != 3000 {
+		t.Errorf("expected best share 3000, got %+v", best)
+	}
+
+	blocks, err := storage.GetMinerBlocks("192.168.1.101", 10)
+	if err != nil {
+		t.Fatalf("failed to get blocks: %v", err)
+	}
+	if len(blocks) != 0 {
+		t.Errorf("expected no blocks for 192.168.1.101, got %d", len(blocks))
+	}
+}
+
+func TestPeriodAggregates(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i, diff := range []float64{500, 3000, 1200} {
+		share := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now.Add(-time.Duration(i+1) * time.Minute), Difficulty: diff}
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+	old := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now.Add(-2 * time.Hour), Difficulty: 9000}
+	if err := storage.InsertShare(old); err != nil {
+		t.Fatalf("failed to insert share: %v", err)
+	}
+
+	counts, err := storage.GetPeriodAggregates("shares", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("failed to get share counts: %v", err)
+	}
+	if a := counts["192.168.1.100"]; a == nil || a.Value != 3 || a.Samples != 3 {
+		t.Errorf("expected 3 shares in the last hour, got %+v", a)
+	}
+
+	best, err := storage.GetPeriodAggregates("best_share", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("failed to get best share: %v", err)
+	}
+	if a := best["192.168.1.100"]; a == nil || a.Value != 3000 {
+		t.Errorf("expected best share 3000, got %+v", a)
+	}
+
+	if _, err := storage.GetPeriodAggregates("uptime", now.Add(-time.Hour), now); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
+
+func TestAlertHistory(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	alerts := []*AlertEntry{
+		{Timestamp: now.Add(-2 * time.Hour), Type: "temp_high", MinerIP: "10.0.0.5", MinerName: "garage", Message: "Temperature high", Value: 82},
+		{Timestamp: now.Add(-1 * time.Hour), Type: "miner_offline", MinerIP: "10.0.0.6", MinerName: "attic", Message: "Miner offline"},
+		{Timestamp: now.AddDate(0, 0, -100), Type: "temp_high", MinerIP: "10.0.0.5", MinerName: "garage", Message: "Temperature high", Value: 85},
+	}
+	for _, a := range alerts {
+		if err := storage.InsertAlert(a); err != nil {
+			t.Fatalf("failed to insert alert: %v", err)
+		}
+	}
+
+	recent, err := storage.GetAlerts(now.Add(-24*time.Hour), "", "", 100)
+	if err != nil {
+		t.Fatalf("failed to get alerts: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Type != "miner_offline" {
+		t.Fatalf("expected 2 alerts, newest first, got %+v", recent)
+	}
+
+	filtered, err := storage.GetAlerts(now.AddDate(0, 0, -365), "temp_high", "10.0.0.5", 100)
+	if err != nil {
+		t.Fatalf("failed to filter alerts: %v", err)
+	}
+	if len(filtered) != 2 || filtered[0].Value != 82 {
+		t.Errorf("expected 2 high temperature alerts for 10.0.0.5, got %+v", filtered)
+	}
+
+	deleted, err := storage.PurgeOldAlerts(90)
+	if err != nil {
+		t.Fatalf("failed to purge alerts: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 purged alert, got %d", deleted)
+	}
+}
+
+func TestHistoryRollups(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	hour := now.Truncate(time.Hour).Add(-time.Hour) // Last complete hour
+	for i, hr := range []float64{400, 600, 500} {
+		snap := &MinerSnapshot{MinerIP: "192.168.1.100", Timestamp: hour.Add(time.Duration(i*10) * time.Minute), HashRate: hr, Temperature: 60 + float64(i), Power: 15}
+		if err := storage.InsertSnapshot(snap); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+	// The current hour is incomplete and must not be rolled up yet
+	if err := storage.InsertSnapshot(&MinerSnapshot{MinerIP: "192.168.1.100", Timestamp: now, HashRate: 900}); err != nil {
+		t.Fatalf("failed to insert snapshot: %v", err)
+	}
+
+	if err := storage.UpdateRollups(now); err != nil {
+		t.Fatalf("failed to update rollups: %v", err)
+	}
+	// Running again after the snapshots were purged must not lose the hour
+	if _, err := storage.PurgeOldSnapshots(0); err != nil {
+		t.Fatalf("failed to purge snapshots: %v", err)
+	}
+	if err := storage.UpdateRollups(now); err != nil {
+		t.Fatalf("failed to update rollups again: %v", err)
+	}
+
+	hourly, err := storage.GetHistoryRollups(ResolutionHour, "192.168.1.100", now.Add(-24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("failed to get hourly rollups: %v", err)
+	}
+	if len(hourly) != 1 {
+		t.Fatalf("expected 1 hourly rollup, got %d", len(hourly))
+	}
+	r := hourly[0]
+	if r.Samples != 3 || r.HashRateAvg != 500 || r.HashRateMin != 400 || r.HashRateMax != 600 || r.TemperatureMax != 62 {
+		t.Errorf("unexpected hourly rollup: %+v", r)
+	}
+
+	daily, err := storage.GetHistoryRollups(ResolutionDay, "", now.AddDate(0, 0, -2), 100)
+	if err != nil {
+		t.Fatalf("failed to get daily rollups: %v", err)
+	}
+	if len(daily) != 1 || daily[0].Samples != 3 || daily[0].HashRateAvg != 500 {
+		t.Errorf("expected one daily rollup of 3 samples, got %+v", daily)
+	}
+
+	if _, err := storage.GetHistoryRollups("minute", "", now, 10); err == nil {
+		t.Error("expected an error for an unknown resolution")
+	}
+}
+
+func TestLatestSnapshots(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for _, snap := range []*MinerSnapshot{
+		{MinerIP: "192.168.1.100", Timestamp: now.Add(-3 * time.Minute), HashRate: 400},
+		{MinerIP: "192.168.1.100", Timestamp: now.Add(-1 * time.Minute), HashRate: 500},
+		{MinerIP: "192.168.1.101", Timestamp: now.Add(-2 * time.Minute), HashRate: 600},
+		{MinerIP: "192.168.1.102", Timestamp: now.Add(-time.Hour), HashRate: 700},
+	} {
+		if err := storage.InsertSnapshot(snap); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+
+	latest, err := storage.GetLatestSnapshots(now.Add(-5 * time.Minute))
+	if err != nil {
+		t.Fatalf("failed to get latest snapshots: %v", err)
+	}
+	if len(latest) != 2 {
+		t.Fatalf("expected 2 miners, got %d", len(latest))
+	}
+	if latest["192.168.1.100"].HashRate != 500 || latest["192.168.1.101"].HashRate != 600 {
+		t.Errorf("unexpected latest snapshots: %+v, %+v", latest["192.168.1.100"], latest["192.168.1.101"])
+	}
+	if _, ok := latest["192.168.1.102"]; ok {
+		t.Error("expected miner without recent snapshots to be absent")
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := storage.UpsertMiner(&Miner{IP: "192.168.1.100", Hostname: "before", Enabled: true}); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+	if err := storage.InsertShare(&Share{MinerIP: "192.168.1.100", Timestamp: time.Now(), Difficulty: 1000}); err != nil {
+		t.Fatalf("failed to insert share: %v", err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if err := storage.Backup(backupPath); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	// Changes after the backup are undone by the restore
+	if err := storage.UpsertMiner(&Miner{IP: "192.168.1.101", Hostname: "after", Enabled: true}); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+
+	if err := CheckBackup(backupPath); err != nil {
+		t.Fatalf("expected backup to pass the check: %v", err)
+	}
+	if err := storage.Restore(backupPath); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	miners, err := storage.GetMiners()
+	if err != nil {
+		t.Fatalf("failed to get miners: %v", err)
+	}
+	if len(miners) != 1 || miners[0].Hostname != "before" {
+		t.Errorf("expected only the backed up miner, got %+v", miners)
+	}
+	shares, err := storage.GetShares(time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("failed to get shares: %v", err)
+	}
+	if len(shares) != 1 || shares[0].Difficulty != 1000 {
+		t.Errorf("expected the backed up share, got %+v", shares)
+	}
+
+	// A file that isn't a MinerHQ database is rejected
+	bogus := filepath.Join(t.TempDir(), "bogus.db")
+	os.WriteFile(bogus, []byte("not a database"), 0644)
+	if err := CheckBackup(bogus); err == nil {
+		t.Error("expected a non-database file to be rejected")
+	}
+}
+
+func TestMinerTags(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, ip := range []string{"192.168.1.100", "192.168.1.101"} {
+		if err := storage.UpsertMiner(&Miner{IP: ip, Enabled: true}); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+	}
+	if err := storage.SetMinerTags("192.168.1.100", []string{"Garage", "rack-1"}); err != nil {
+		t.Fatalf("failed to set tags: %v", err)
+	}
+	if err := storage.SetMinerTags("192.168.1.101", []string{"Office", "rack-1"}); err != nil {
+		t.Fatalf("failed to set tags: %v", err)
+	}
+
+	tags, err := storage.GetTags()
+	if err != nil {
+		t.Fatalf("failed to get tags: %v", err)
+	}
+	if len(tags) != 3 || tags[2].Name != "rack-1" || len(tags[2].Miners) != 2 {
+		t.Fatalf("unexpected tags: %+v", tags)
+	}
+
+	// Renaming into an existing tag merges them
+	if renamed, err := storage.RenameTag("office", "garage"); err != nil || renamed != 1 {
+		t.Fatalf("RenameTag = %d, %v; want 1", renamed, err)
+	}
+	minerTags, err := storage.GetMinerTags()
+	if err != nil {
+		t.Fatalf("failed to get miner tags: %v", err)
+	}
+	if got := minerTags["192.168.1.101"]; len(got) != 2 || got[0] != "garage" {
+		t.Errorf("expected office renamed to garage, got %v", got)
+	}
+
+	if deleted, err := storage.DeleteTag("RACK-1"); err != nil || deleted != 2 {
+		t.Errorf("DeleteTag = %d, %v; want 2", deleted, err)
+	}
+	if err := storage.SetMinerTags("192.168.1.100", nil); err != nil {
+		t.Fatalf("failed to clear tags: %v", err)
+	}
+	if tags, _ := storage.GetTags(); len(tags) != 1 || tags[0].Miners[0] != "192.168.1.101" {
+		t.Errorf("expected only the second miner's tag left, got %+v", tags)
+	}
+}
+
+func TestAlertOverrides(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	temp, off := 80.0, false
+	if err := storage.SetAlertOverrides("192.168.1.100", &AlertOverrides{TempAbove: &temp, Discord: &off}); err != nil {
+		t.Fatalf("failed to set overrides: %v", err)
+	}
+
+	overrides, err := storage.GetAlertOverrides()
+	if err != nil {
+		t.Fatalf("failed to get overrides: %v", err)
+	}
+	o := overrides["192.168.1.100"]
+	if o == nil || o.TempAbove == nil || *o.TempAbove != 80 || o.Discord == nil || *o.Discord {
+		t.Fatalf("unexpected overrides: %+v", o)
+	}
+	if o.FanRPMBelow != nil || o.Matrix != nil {
+		t.Errorf("unset overrides should stay nil: %+v", o)
+	}
+
+	// Empty overrides fall back to the global settings
+	if err := storage.SetAlertOverrides("192.168.1.100", &AlertOverrides{}); err != nil {
+		t.Fatalf("failed to clear overrides: %v", err)
+	}
+	if overrides, _ := storage.GetAlertOverrides(); len(overrides) != 0 {
+		t.Errorf("expected no overrides, got %v", overrides)
+	}
+}
+
+func TestShareStats(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	start := time.Now().Add(-90 * time.Minute).Truncate(time.Hour)
+	// The miner restarts between the 2nd and 3rd snapshot
+	for i, counts := range [][2]int64{{100, 1}, {150, 3}, {20, 0}, {60, 4}} {
+		snap := &MinerSnapshot{MinerIP: "192.168.1.100", Timestamp: start.Add(time.Duration(i) * 10 * time.Minute), SharesAccept: counts[0], SharesReject: counts[1]}
+		if err := storage.InsertSnapshot(snap); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+	for _, diff := range []float64{0.5, 12, 999, 1500} {
+		share := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: start, Difficulty: diff}
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+
+	buckets, err := storage.GetShareAcceptance(start.Add(-time.Minute), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to get acceptance: %v", err)
+	}
+	var accepted, rejected int64
+	for _, b := range buckets {
+		accepted += b.Accepted
+		rejected += b.Rejected
+	}
+	if accepted != 110 || rejected != 6 {
+		t.Errorf("expected 110 accepted and 6 rejected, got %d and %d", accepted, rejected)
+	}
+
+	histogram, err := storage.GetDifficultyHistogram(start.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("failed to get histogram: %v", err)
+	}
+	want := map[int]int64{0: 1, 1: 1, 2: 1, 3: 1}
+	if len(histogram) != len(want) {
+		t.Fatalf("expected %d decades, got %+v", len(want), histogram)
+	}
+	for _, b := range histogram {
+		if want[b.Decade] != b.Count {
+			t.Errorf("decade %d: expected %d shares, got %d", b.Decade, want[b.Decade], b.Count)
+		}
+	}
+
+	rates, err := storage.GetShareRates(start.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("failed to get share rates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Shares != 4 || rates[0].BestDifficulty != 1500 {
+		t.Errorf("unexpected share rates: %+v", rates)
+	}
+}
+
+func TestAsicShares(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().Add(-time.Minute)
+	for i, diff := range []float64{500, 3000, 1200, 800} {
+		share := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now.Add(time.Duration(i) * time.Second), AsicNum: i % 2 * 2, Difficulty: diff}
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+
+	asics, err := storage.GetAsicShares("192.168.1.100", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get ASIC shares: %v", err)
+	}
+	if len(asics) != 2 {
+		t.Fatalf("expected 2 chips, got %+v", asics)
+	}
+	if asics[0].AsicNum != 0 || asics[0].Shares != 2 || asics[0].BestDifficulty != 1200 {
+		t.Errorf("unexpected chip 0: %+v", asics[0])
+	}
+	if asics[1].AsicNum != 2 || asics[1].Shares != 2 || asics[1].BestDifficulty != 3000 || asics[1].LastShare.IsZero() {
+		t.Errorf("unexpected chip 2: %+v", asics[1])
+	}
+}
+
+func TestBestShares(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	old := time.Now().Add(-48 * time.Hour)
+	for i := 1; i <= BestSharesKept+5; i++ {
+		share := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: old.Add(time.Duration(i) * time.Second), Difficulty: float64(i)}
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+	if _, err := storage.PurgeOldShares(1); err != nil {
+		t.Fatalf("failed to purge shares: %v", err)
+	}
+
+	kept, err := storage.GetBestShares("192.168.1.100", BestSharesKept+5)
+	if err != nil {
+		t.Fatalf("failed to get best shares: %v", err)
+	}
+	if len(kept) != BestSharesKept || kept[0].Difficulty != BestSharesKept+5 || kept[len(kept)-1].Difficulty != 6 {
+		t.Fatalf("expected the %d best shares to survive the purge, got %d", BestSharesKept, len(kept))
+	}
+
+	best, err := storage.GetBestShare("192.168.1.100", false)
+	if err != nil || best == nil || best.Difficulty != BestSharesKept+5 {
+		t.Errorf("expected all-time best %d after purge, got %+v (%v)", BestSharesKept+5, best, err)
+	}
+	inRange, err := storage.GetBestShareInRange("192.168.1.100", old, time.Now())
+	if err != nil || inRange == nil || inRange.Difficulty != BestSharesKept+5 {
+		t.Errorf("expected best share in range from kept shares, got %+v (%v)", inRange, err)
+	}
+}
+
+func TestMarkShareRejected(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().Add(-time.Minute)
+	good := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now, Difficulty: 100}
+	stale := &Share{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now.Add(time.Second), Difficulty: 500}
+	for _, share := range []*Share{good, stale} {
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+	if err := storage.MarkShareRejected(stale.ID, "Stale"); err != nil {
+		t.Fatalf("failed to mark share rejected: %v", err)
+	}
+
+	shares, err := storage.GetMinerShares("192.168.1.100", now.Add(-time.Hour), 10, false)
+	if err != nil {
+		t.Fatalf("failed to get shares: %v", err)
+	}
+	if len(shares) != 2 || !shares[0].Rejected || shares[0].RejectReason != "Stale" || shares[1].Rejected {
+		t.Fatalf("expected the newest share rejected as stale, got %+v", shares)
+	}
+
+	best, err := storage.GetBestShare("192.168.1.100", false)
+	if err != nil || best == nil || best.Difficulty != 100 {
+		t.Errorf("expected the rejected share not to count as best, got %+v (%v)", best, err)
+	}
+	inRange, err := storage.GetBestShareInRange("192.168.1.100", now.Add(-time.Hour), time.Now())
+	if err != nil || inRange == nil || inRange.Difficulty != 100 {
+		t.Errorf("expected the rejected share not to count in range, got %+v (%v)", inRange, err)
+	}
+
+	// A miner with more shares than are kept: rejecting a kept one brings
+	// back its best share that wasn't kept
+	var top *Share
+	for i := 1; i <= BestSharesKept+1; i++ {
+		top = &Share{MinerIP: "192.168.1.101", Hostname: "other", Timestamp: now, Difficulty: float64(i)}
+		if err := storage.InsertShare(top); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+	if err := storage.MarkShareRejected(top.ID, "Duplicate"); err != nil {
+		t.Fatalf("failed to mark share rejected: %v", err)
+	}
+	kept, err := storage.GetBestShares("192.168.1.101", 2*BestSharesKept)
+	if err != nil {
+		t.Fatalf("failed to get best shares: %v", err)
+	}
+	if len(kept) != BestSharesKept || kept[0].Difficulty != float64(BestSharesKept) || kept[len(kept)-1].Difficulty != 1 {
+		t.Errorf("expected the %d best unrejected shares kept, got %d from %v", BestSharesKept, len(kept), kept[0].Difficulty)
+	}
+}
+
+func TestCompetitionResults(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	start := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Hour)
+	end := start.AddDate(0, 0, 7)
+	for i, share := range []*Share{
+		{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(time.Hour), Difficulty: 100},
+		{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(2 * time.Hour), Difficulty: 50},
+		{MinerIP: "192.168.1.101", Hostname: "beta", Timestamp: start.Add(3 * time.Hour), Difficulty: 300},
+		{MinerIP: "192.168.1.102", Hostname: "gamma", Timestamp: end.Add(time.Hour), Difficulty: 900},
+	} {
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share %d: %v", i, err)
+		}
+	}
+
+	placed, err := storage.FinalizeCompetition("weekly", start, end, competition.Scorer{})
+	if err != nil || placed != 2 {
+		t.Fatalf("expected 2 miners placed, got %d (%v)", placed, err)
+	}
+	if placed, _ := storage.FinalizeCompetition("weekly", start, end, competition.Scorer{}); placed != 0 {
+		t.Errorf("expected a finalized period to be stored once, got %d more rows", placed)
+	}
+
+	history, err := storage.GetCompetitionHistory(10)
+	if err != nil {
+		t.Fatalf("failed to get competition history: %v", err)
+	}
+	if len(history) != 2 || history[0].MinerIP != "192.168.1.101" || history[0].Rank != 1 || history[1].ShareCount != 2 {
+		t.Fatalf("expected beta first and alpha second with 2 shares, got %+v", history)
+	}
+
+	trophies, err := storage.GetTrophies()
+	if err != nil {
+		t.Fatalf("failed to get trophies: %v", err)
+	}
+	if len(trophies) != 2 || trophies[0].Hostname != "beta" || trophies[0].Gold != 1 || trophies[1].Silver != 1 {
+		t.Errorf("expected one gold for beta and one silver for alpha, got %+v", trophies)
+	}
+}
+
+func TestCompetitionScoring(t *testing.T) {
+	start := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Hour)
+	end := start.AddDate(0, 0, 7)
+
+	tests := []struct {
+		mode     string
+		expected map[string]float64
+		want     []string // Miner IP and rank, best first
+	}{
+		{competition.Raw, nil, []string{"192.168.1.101 1", "192.168.1.100 2", "192.168.1.102 2"}},
+		// alpha 100 / 0.1 TH/s average, beta 300 / 1 TH/s expected, gamma 100 / 2 TH/s average
+		{competition.Expected, map[string]float64{"192.168.1.101": 1000}, []string{"192.168.1.100 1", "192.168.1.101 2", "192.168.1.102 3"}},
+		// alpha 100 / 0.1 TH/s, beta 300 / 0.1 TH/s, gamma 100 / 2 TH/s, averaged over the period only
+		{competition.Average, map[string]float64{"192.168.1.101": 1000}, []string{"192.168.1.101 1", "192.168.1.100 2", "192.168.1.102 3"}},
+	}
+	for _, tt := range tests {
+		storage, cleanup := setupTestDB(t)
+		for i, share := range []*Share{
+			{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(time.Hour), Difficulty: 100},
+			{MinerIP: "192.168.1.101", Hostname: "beta", Timestamp: start.Add(2 * time.Hour), Difficulty: 300},
+			{MinerIP: "192.168.1.102", Hostname: "gamma", Timestamp: start.Add(3 * time.Hour), Difficulty: 100},
+		} {
+			if err := storage.InsertShare(share); err != nil {
+				t.Fatalf("failed to insert share %d: %v", i, err)
+			}
+		}
+		for _, snap := range []*MinerSnapshot{
+			{MinerIP: "192.168.1.100", Timestamp: start.Add(time.Hour), HashRate: 50},
+			{MinerIP: "192.168.1.100", Timestamp: start.Add(2 * time.Hour), HashRate: 150},
+			{MinerIP: "192.168.1.100", Timestamp: end.Add(time.Hour), HashRate: 100000},
+			{MinerIP: "192.168.1.101", Timestamp: start.Add(time.Hour), HashRate: 100},
+			{MinerIP: "192.168.1.102", Timestamp: start.Add(time.Hour), HashRate: 2000},
+		} {
+			if err := storage.InsertSnapshot(snap); err != nil {
+				t.Fatalf("failed to insert snapshot: %v", err)
+			}
+		}
+
+		scorer, err := storage.CompetitionScorer(tt.mode, tt.expected, start, end)
+		if err != nil {
+			t.Fatalf("%s: failed to get scorer: %v", tt.mode, err)
+		}
+		if _, err := storage.FinalizeCompetition("weekly", start, end, scorer); err != nil {
+			t.Fatalf("%s: failed to finalize: %v", tt.mode, err)
+		}
+		history, err := storage.GetCompetitionHistory(1)
+		if err != nil {
+			t.Fatalf("%s: failed to get competition history: %v", tt.mode, err)
+		}
+		var got []string
+		for _, r := range history {
+			got = append(got, fmt.Sprintf("%s %d", r.MinerIP, r.Rank))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected standings %v, got %v", tt.mode, tt.want, got)
+		}
+		cleanup()
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	c := PageCursor{Timestamp: time.Unix(1760600000, 0).UTC(), ID: 421, MinerIP: "192.168.1.100"}
+	parsed, err := ParsePageCursor(c.String())
+	if err != nil || parsed != c {
+		t.Errorf("expected cursor %+v to round-trip, got %+v (%v)", c, parsed, err)
+	}
+	if first, err := ParsePageCursor(""); err != nil || !first.IsZero() {
+		t.Errorf("expected an empty cursor to start at the first page, got %+v (%v)", first, err)
+	}
+	if _, err := ParsePageCursor("not a cursor"); err == nil {
+		t.Error("expected an invalid cursor to be rejected")
+	}
+}
+
+func TestSharesPage(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Three shares in the same second need the id to keep pages apart
+	now := time.Now().Add(-time.Minute)
+	for i := 0; i < 5; i++ {
+		ts := now.Add(time.Duration(i/3) * time.Second)
+		if err := storage.InsertShare(&Share{MinerIP: "192.168.1.100", Timestamp: ts, Difficulty: float64(i + 1)}); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+
+	since := now.Add(-time.Hour)
+	var seen []float64
+	var after PageCursor
+	for page := 0; page < 5; page++ {
+		shares, total, err := storage.GetSharesPage(since, after, 2)
+		if err != nil {
+			t.Fatalf("failed to get page: %v", err)
+		}
+		if total != 5 {
+			t.Errorf("expected a total of 5, got %d", total)
+		}
+		for _, s := range shares {
+			seen = append(seen, s.Difficulty)
+		}
+		if len(shares) < 2 {
+			break
+		}
+		last := shares[len(shares)-1]
+		after = PageCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+
+	if want := []float64{5, 4, 3, 2, 1}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("expected every share once, newest first: %v, got %v", want, seen)
+	}
+}
+
+func TestMoveMiner(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	old := &Miner{IP: "192.168.1.100", Hostname: "alpha", Enabled: true, LastSeen: time.Now(), MacAddr: "aa:bb:cc:dd:ee:ff"}
+	if err := storage.UpsertMiner(old); err != nil {
+		t.Fatalf("failed to insert miner: %v", err)
+	}
+	if err := storage.SetMinerTags(old.IP, []string{"Garage"}); err != nil {
+		t.Fatalf("failed to set tags: %v", err)
+	}
+	if err := storage.InsertShare(&Share{MinerIP: old.IP, Hostname: "alpha", Timestamp: time.Now(), Difficulty: 42}); err != nil {
+		t.Fatalf("failed to insert share: %v", err)
+	}
+
+	if err := storage.InsertScheduleRun(&ScheduleRun{ScheduleID: 1, MinerIP: old.IP, Action: "pause", Start: time.Now()}); err != nil {
+		t.Fatalf("failed to insert schedule run: %v", err)
+	}
+	if err := storage.SetScheduleOverride(&ScheduleOverride{MinerIP: old.IP, Mode: "run"}); err != nil {
+		t.Fatalf("failed to set schedule override: %v", err)
+	}
+	if err := storage.InsertMaintenanceWindow(&MaintenanceWindow{MinerIP: old.IP, Start: time.Now(), End: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("failed to insert maintenance window: %v", err)
+	}
+	for _, miners := range [][]string{{"192.168.1.50", old.IP}, {old.IP, "192.168.1.200"}} {
+		if _, err := storage.SaveSchedule(&Schedule{Name: "night", Miners: miners, Action: "pause", Trigger: "time", Start: "22:00", End: "06:00"}); err != nil {
+			t.Fatalf("failed to save schedule: %v", err)
+		}
+	}
+	day := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	if _, err := storage.db.Exec("INSERT INTO luck_daily VALUES (?, ?, 1, 1, 1, 1, 1, 1)", old.IP, day); err != nil {
+		t.Fatalf("failed to insert luck day: %v", err)
+	}
+
+	ip, err := storage.GetMinerIPByMAC("AA-BB-CC-DD-EE-FF", "192.168.1.200")
+	if err != nil || ip != old.IP {
+		t.Fatalf("expected MAC to match %s, got %q (%v)", old.IP, ip, err)
+	}
+	if ip, _ := storage.GetMinerIPByMAC(old.MacAddr, old.IP); ip != "" {
+		t.Errorf("expected no other miner with the MAC, got %s", ip)
+	}
+
+	if err := storage.MoveMiner(old.IP, "192.168.1.200"); err != nil {
+		t.Fatalf("failed to move miner: %v", err)
+	}
+	miners, err := storage.GetMiners()
+	if err != nil || len(miners) != 1 || miners[0].IP != "192.168.1.200" || miners[0].Hostname != "alpha" {
+		t.Fatalf("expected the miner at its new IP, got %+v (%v)", miners, err)
+	}
+	if best, _ := storage.GetBestShare("192.168.1.200", false); best == nil || best.Difficulty != 42 {
+		t.Errorf("expected shares to follow the miner, got %+v", best)
+	}
+	if tags, _ := storage.GetMinerTags(); len(tags["192.168.1.200"]) != 1 {
+		t.Errorf("expected tags to follow the miner, got %v", tags)
+	}
+	if runs, _ := storage.GetOpenScheduleRuns(); len(runs) != 1 || runs[0].MinerIP != "192.168.1.200" {
+		t.Errorf("expected schedule runs to follow the miner, got %+v", runs)
+	}
+	if overrides, _ := storage.GetScheduleOverrides(); len(overrides) != 1 || overrides[0].MinerIP != "192.168.1.200" {
+		t.Errorf("expected schedule overrides to follow the miner, got %+v", overrides)
+	}
+	if windows, _ := storage.GetMaintenanceWindows(); len(windows) != 1 || windows[0].MinerIP != "192.168.1.200" {
+		t.Errorf("expected maintenance windows to follow the miner, got %+v", windows)
+	}
+	if days, _ := storage.GetLuckDays("192.168.1.200", time.Now().AddDate(0, 0, -7)); len(days) != 1 {
+		t.Errorf("expected luck days to follow the miner, got %+v", days)
+	}
+	schedules, _ := storage.GetSchedules()
+	if len(schedules) != 2 || strings.Join(schedules[0].Miners, ",") != "192.168.1.50,192.168.1.200" || strings.Join(schedules[1].Miners, ",") != "192.168.1.200" {
+		t.Errorf("expected schedules to follow the miner once, got %+v %+v", schedules[0], schedules[1])
+	}
+
+	// A different miner already at the target IP is not replaced
+	other := &Miner{IP: "192.168.1.100", Hostname: "beta", Enabled: true, LastSeen: time.Now(), MacAddr: "11:22:33:44:55:66"}
+	if err := storage.UpsertMiner(other); err != nil {
+		t.Fatalf("failed to insert miner: %v", err)
+	}
+	if err := storage.MoveMiner("192.168.1.200", other.IP); err == nil {
+		t.Error("expected moving onto another miner's IP to fail")
+	}
+}
+
+func TestInsertSnapshots(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().Add(-time.Minute)
+	batch := []*MinerSnapshot{
+		{MinerIP: "192.168.1.100", Timestamp: now, HashRate: 400},
+		{MinerIP: "192.168.1.101", Timestamp: now, HashRate: 500},
+		{MinerIP: "192.168.1.100", Timestamp: now.Add(2 * time.Second), HashRate: 450},
+	}
+	if err := storage.InsertSnapshots(batch); err != nil {
+		t.Fatalf("failed to insert snapshots: %v", err)
+	}
+	if err := storage.InsertSnapshots(nil); err != nil {
+		t.Fatalf("failed to insert an empty batch: %v", err)
+	}
+
+	snapshots, err := storage.GetSnapshots("192.168.1.100", now.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("failed to get snapshots: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].HashRate != 450 {
+		t.Errorf("expected 2 snapshots, newest first, got %+v", snapshots)
+	}
+}
+
+func TestMinerLogs(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	var lines []*MinerLogLine
+	for i, text := range []string{"one", "two", "three"} {
+		lines = append(lines, &MinerLogLine{MinerIP: "192.168.1.100", Timestamp: now.Add(time.Duration(i) * time.Second), Line: text})
+	}
+	lines = append(lines, &MinerLogLine{MinerIP: "192.168.1.101", Timestamp: now, Line: "other"})
+	lines = append(lines, &MinerLogLine{MinerIP: "192.168.1.100", Timestamp: now.AddDate(0, 0, -10), Line: "old"})
+	if err := storage.InsertMinerLogs(lines); err != nil {
+		t.Fatalf("failed to insert log lines: %v", err)
+	}
+
+	last, err := storage.GetMinerLogs("192.168.1.100", 2)
+	if err != nil {
+		t.Fatalf("failed to get log lines: %v", err)
+	}
+	if len(last) != 2 || last[0].Line != "two" || last[1].Line != "three" {
+		t.Errorf("expected the last 2 lines, oldest first, got %+v", last)
+	}
+
+	deleted, err := storage.PurgeOldMinerLogs(3)
+	if err != nil {
+		t.Fatalf("failed to purge log lines: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 old line purged, got %d", deleted)
+	}
+}
+
+func TestPriceHistory(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for i, p := range []*PricePoint{
+		{CoinID: "dgb", Timestamp: start, PriceUSD: 0.01},
+		{CoinID: "btc", Timestamp: start.Add(time.Hour), PriceUSD: 60000},
+		{CoinID: "dgb", Timestamp: start.Add(25 * time.Hour), PriceUSD: 0.02},
+		{CoinID: "btc", Timestamp: start.Add(26 * time.Hour), PriceUSD: 61000},
+	} {
+		if err := storage.InsertPrice(p.CoinID, p.PriceUSD, p.Timestamp); err != nil {
+			t.Fatalf("failed to insert price %d: %v", i, err)
+		}
+	}
+
+	since := start.Add(24 * time.Hour)
+	dgb, err := storage.GetPriceHistory("dgb", since)
+	if err != nil {
+		t.Fatalf("failed to get price history: %v", err)
+	}
+	if len(dgb) != 1 || dgb[0].PriceUSD != 0.02 || !dgb[0].Timestamp.Equal(start.Add(25*time.Hour)) {
+		t.Errorf("expected the last DGB price, got %+v", dgb)
+	}
+	all, err := storage.GetPriceHistory("", since)
+	if err != nil || len(all) != 2 || all[0].CoinID != "dgb" || all[1].CoinID != "btc" {
+		t.Errorf("expected both coins oldest first, got %+v (%v)", all, err)
+	}
+
+	before, err := storage.GetLastPricesBefore(since)
+	if err != nil {
+		t.Fatalf("failed to get last prices: %v", err)
+	}
+	if before["dgb"] != 0.01 || before["btc"] != 60000 {
+		t.Errorf("expected the prices before the window, got %v", before)
+	}
+}
+
+func TestBlockVerification(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().Add(-time.Hour)
+	kept := &Block{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now, CoinID: "btc", BlockReward: 3.125, ValueUSD: 300000, Height: 900000, Status: BlockPending}
+	orphan := &Block{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now.Add(time.Minute), CoinID: "btc", BlockReward: 3.125, ValueUSD: 300000, Height: 900001, Status: BlockPending}
+	unverified := &Block{MinerIP: "192.168.1.100", Hostname: "miner", Timestamp: now.Add(2 * time.Minute), CoinID: "dgb", BlockReward: 274, ValueUSD: 3}
+	for _, block := range []*Block{kept, orphan, unverified} {
+		if err := storage.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+	}
+
+	pending, err := storage.GetPendingBlocks()
+	if err != nil {
+		t.Fatalf("failed to get pending blocks: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != kept.ID || pending[0].Height != 900000 {
+		t.Fatalf("expected both BTC blocks pending, oldest first, got %+v", pending)
+	}
+
+	if err := storage.SetBlockVerification(kept.ID, BlockConfirmed, 900001, "00000000abc"); err != nil {
+		t.Fatalf("failed to confirm block: %v", err)
+	}
+	if err := storage.SetBlockVerification(orphan.ID, BlockOrphaned, 900001, ""); err != nil {
+		t.Fatalf("failed to orphan block: %v", err)
+	}
+
+	blocks, err := storage.GetMinerBlocks("192.168.1.100", 10)
+	if err != nil || len(blocks) != 3 {
+		t.Fatalf("expected all blocks listed, got %d (%v)", len(blocks), err)
+	}
+	if blocks[2].Status != BlockConfirmed || blocks[2].Height != 900001 || blocks[2].Hash != "00000000abc" {
+		t.Errorf("expected the confirmed block's hash and height, got %+v", blocks[2])
+	}
+
+	earnings, err := storage.GetEarningsForCoin("btc")
+	if err != nil || earnings == nil || earnings.BlockCount != 1 || earnings.TotalCoins != 3.125 {
+		t.Errorf("expected the orphaned block not to earn anything, got %+v (%v)", earnings, err)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	end := time.Now()
+	for _, m := range []*Miner{
+		{IP: "192.168.1.100", Hostname: "alpha", Enabled: true},
+		{IP: "192.168.1.101", Hostname: "beta", Enabled: true},
+		{IP: "192.168.1.102", Hostname: "gamma", Enabled: true},
+	} {
+		if err := storage.UpsertMiner(m); err != nil {
+			t.Fatalf("failed to upsert miner: %v", err)
+		}
+	}
+	for i, share := range []*Share{
+		{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(-time.Hour), Difficulty: 5000},
+		{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(time.Hour), Difficulty: 100},
+		{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(2 * time.Hour), Difficulty: 50},
+		{MinerIP: "192.168.1.101", Hostname: "beta", Timestamp: start.Add(3 * time.Hour), Difficulty: 300},
+		{MinerIP: "192.168.1.102", Hostname: "gamma", Timestamp: start.Add(-2 * time.Hour), Difficulty: 900},
+	} {
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share %d: %v", i, err)
+		}
+	}
+	for _, block := range []*Block{
+		{MinerIP: "192.168.1.100", Hostname: "alpha", Timestamp: start.Add(time.Hour)},
+		{MinerIP: "192.168.1.102", Hostname: "gamma", Timestamp: start.Add(-time.Hour)},
+		{MinerIP: "192.168.1.102", Hostname: "gamma", Timestamp: start.Add(-2 * time.Hour)},
+	} {
+		if err := storage.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+	}
+
+	leaderboard, err := storage.GetWeeklyLeaderboard(start, end)
+	if err != nil {
+		t.Fatalf("failed to get leaderboard: %v", err)
+	}
+	if len(leaderboard) != 2 || leaderboard[0].MinerIP != "192.168.1.101" || leaderboard[1].MinerIP != "192.168.1.100" {
+		t.Fatalf("expected beta then alpha, gamma had no share in range, got %+v", leaderboard)
+	}
+	alpha := leaderboard[1]
+	if alpha.BestDiff != 100 || alpha.ShareCount != 2 || alpha.PersonalBest != 5000 || alpha.Blocks != 1 {
+		t.Errorf("unexpected standing for alpha: %+v", alpha)
+	}
+
+	blocks, err := storage.GetBlockLeaderboard(start, end)
+	if err != nil {
+		t.Fatalf("failed to get block leaderboard: %v", err)
+	}
+	if len(blocks) != 2 || blocks[0].Hostname != "alpha" || blocks[0].BlocksInRange != 1 || blocks[1].BlocksAllTime != 2 {
+		t.Errorf("expected alpha first with a block this period and gamma with 2 overall, got %+v", blocks)
+	}
+}
+
+func TestFleetSamples(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	base := time.Now().Add(-time.Minute).Truncate(time.Minute)
+	for _, snap := range []*MinerSnapshot{
+		{MinerIP: "192.168.1.100", Timestamp: base, HashRate1m: 400, Temperature: 50, Power: 10},
+		{MinerIP: "192.168.1.100", Timestamp: base.Add(2 * time.Second), HashRate1m: 500, Temperature: 60, Power: 12},
+		{MinerIP: "192.168.1.101", Timestamp: base.Add(time.Second), HashRate1m: 1000, Temperature: 40, Temperature2: 45, Power: 20},
+		{MinerIP: "192.168.1.101", Timestamp: base.Add(5 * time.Second), HashRate1m: 900, Temperature: 42, Power: 19},
+		{MinerIP: "192.168.1.102", Timestamp: base, HashRate1m: 9999},
+	} {
+		if err := storage.InsertSnapshot(snap); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+
+	samples, err := storage.GetFleetSamples([]string{"192.168.1.100", "192.168.1.101"}, base, time.Now(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to get fleet samples: %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(samples))
+	}
+	first := samples[0]
+	if !first.Timestamp.Equal(base) || first.HashRate1m != 1500 || first.Temperature != 50 || first.Temp2 != 45 || first.Power != 32 || first.Miners != 2 {
+		t.Errorf("expected each miner's last snapshot summed in the first bucket, got %+v", first)
+	}
+	if samples[1].HashRate1m != 900 || samples[1].Miners != 1 {
+		t.Errorf("expected only the second miner in the next bucket, got %+v", samples[1])
+	}
+
+	hour := time.Now().Truncate(time.Hour).Add(-2 * time.Hour)
+	for _, snap := range []*MinerSnapshot{
+		{MinerIP: "192.168.1.100", Timestamp: hour.Add(time.Minute), HashRate: 400, Temperature: 50, Power: 10},
+		{MinerIP: "192.168.1.101", Timestamp: hour.Add(time.Minute), HashRate: 1000, Temperature: 40, Power: 20},
+	} {
+		if err := storage.InsertSnapshot(snap); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+	if err := storage.UpdateRollups(time.Now()); err != nil {
+		t.Fatalf("failed to update rollups: %v", err)
+	}
+
+	rollups, err := storage.GetFleetRollups(ResolutionHour, nil, hour, hour.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to get fleet rollups: %v", err)
+	}
+	if len(rollups) != 1 || rollups[0].HashRate1h != 1400 || rollups[0].Temperature != 45 || rollups[0].Power != 30 {
+		t.Errorf("expected both miners summed into one hour, got %+v", rollups)
+	}
+	if only, _ := storage.GetFleetRollups(ResolutionHour, []string{"192.168.1.101"}, hour, hour.Add(time.Minute)); len(only) != 1 || only[0].HashRate1m != 1000 {
+		t.Errorf("expected only the tagged miner, got %+v", only)
+	}
+}
+
+func TestBlockStreak(t *testing.T) {
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC) // Wednesday
+	week := 7 * 24 * time.Hour
+	timestamps := []time.Time{now.Add(-time.Hour), now.Add(-week), now.Add(-week - time.Hour), now.Add(-3 * week)}
+	if streak := blockStreak(timestamps, now); streak != 2 {
+		t.Errorf("expected a 2 week streak, got %d", streak)
+	}
+	if streak := blockStreak(timestamps[1:], now); streak != 0 {
+		t.Errorf("expected no streak without a block this week, got %d", streak)
+	}
+}
+
+func TestUptimeEvents(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for _, e := range []*UptimeEvent{
+		{MinerIP: "192.168.1.100", Timestamp: start.Add(-2 * time.Hour), Online: true},
+		{MinerIP: "192.168.1.100", Timestamp: start.Add(-time.Hour), Online: false},
+		{MinerIP: "192.168.1.100", Timestamp: start.Add(time.Hour), Online: true},
+		{MinerIP: "192.168.1.101", Timestamp: start.Add(2 * time.Hour), Online: false},
+	} {
+		if err := storage.InsertUptimeEvent(e); err != nil {
+			t.Fatalf("failed to insert uptime event: %v", err)
+		}
+	}
+
+	events, err := storage.GetUptimeEvents("192.168.1.100", start)
+	if err != nil {
+		t.Fatalf("failed to get uptime events: %v", err)
+	}
+	if len(events) != 2 || events[0].Online || !events[0].Timestamp.Equal(start.Add(-time.Hour)) || !events[1].Online {
+		t.Errorf("expected the status at the start followed by the events since, got %+v", events)
+	}
+
+	last, err := storage.GetLastUptimeEvent("192.168.1.101")
+	if err != nil || last == nil || last.Online {
+		t.Errorf("expected the other miner's offline event, got %+v (%v)", last, err)
+	}
+	if none, err := storage.GetLastUptimeEvent("192.168.1.102"); err != nil || none != nil {
+		t.Errorf("expected no event for an untracked miner, got %+v (%v)", none, err)
+	}
+}
+
+func TestMinerDetails(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	miner := &Miner{IP: "192.168.1.100", Hostname: "bitaxe-3f", Enabled: true}
+	if err := storage.UpsertMiner(miner); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+	details := MinerDetails{DisplayName: "Garage Shelf", PurchaseDate: "2025-11-28", Notes: "Fan replaced in March"}
+	if err := storage.SetMinerDetails(miner.IP, details); err != nil {
+		t.Fatalf("failed to set miner details: %v", err)
+	}
+	// The miner reporting in again keeps what the user set
+	if err := storage.UpsertMiner(miner); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+
+	miners, err := storage.GetMiners()
+	if err != nil || len(miners) != 1 {
+		t.Fatalf("expected 1 miner, got %d (%v)", len(miners), err)
+	}
+	if miners[0].MinerDetails != details || miners[0].Name() != "Garage Shelf" {
+		t.Errorf("expected the details kept, got %+v", miners[0].MinerDetails)
+	}
+
+	names, err := storage.GetDisplayNames()
+	if err != nil || len(names) != 1 || names[miner.IP] != "Garage Shelf" {
+		t.Errorf("expected the display name keyed by IP, got %v (%v)", names, err)
+	}
+}
+
+func TestMinerPause(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	miner := &Miner{IP: "192.168.1.100", Hostname: "bitaxe-3f", Enabled: true, MacAddr: "AA:BB:CC:DD:EE:FF"}
+	if err := storage.UpsertMiner(miner); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+	if err := storage.SetMinerPaused(miner.IP, true); err != nil {
+		t.Fatalf("failed to pause miner: %v", err)
+	}
+	// A poll in flight or a scan reporting the miner doesn't resume it
+	if err := storage.UpsertMiner(miner); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+
+	miners, err := storage.GetMiners()
+	if err != nil || len(miners) != 0 {
+		t.Fatalf("expected no enabled miners, got %d (%v)", len(miners), err)
+	}
+	all, err := storage.GetAllMiners()
+	if err != nil || len(all) != 1 || all[0].Enabled || !all[0].Paused {
+		t.Fatalf("expected the miner listed as paused, got %+v (%v)", all, err)
+	}
+
+	// Moving to a new IP keeps it paused
+	if err := storage.MoveMiner(miner.IP, "192.168.1.101"); err != nil {
+		t.Fatalf("failed to move miner: %v", err)
+	}
+	if miners, _ := storage.GetMiners(); len(miners) != 0 {
+		t.Errorf("expected the moved miner still paused, got %d enabled", len(miners))
+	}
+
+	if err := storage.SetMinerPaused("192.168.1.101", false); err != nil {
+		t.Fatalf("failed to resume miner: %v", err)
+	}
+	miners, err = storage.GetMiners()
+	if err != nil || len(miners) != 1 || miners[0].Paused {
+		t.Fatalf("expected the miner enabled again, got %+v (%v)", miners, err)
+	}
+
+	// A removed miner isn't paused, so a scan adds it back
+	if err := storage.RemoveMiner("192.168.1.101"); err != nil {
+		t.Fatalf("failed to remove miner: %v", err)
+	}
+	miner.IP = "192.168.1.101"
+	if err := storage.UpsertMiner(miner); err != nil {
+		t.Fatalf("failed to upsert miner: %v", err)
+	}
+	if miners, _ := storage.GetMiners(); len(miners) != 1 {
+		t.Errorf("expected the removed miner added back, got %d enabled", len(miners))
+	}
+}
+
+func TestReadPool(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := storage.InsertShare(&Share{MinerIP: "192.168.1.100", Timestamp: time.Now(), Difficulty: 1000}); err != nil {
+		t.Fatalf("failed to insert share: %v", err)
+	}
+
+	// Reads don't wait for a write transaction, and see what was committed
+	tx, err := storage.db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO shares (miner_ip, timestamp, difficulty) VALUES ('192.168.1.100', ?, 2000)",
+		time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatalf("failed to insert share: %v", err)
+	}
+	shares, err := storage.GetShares(time.Now().Add(-time.Hour), 10)
+	if err != nil || len(shares) != 1 {
+		t.Errorf("expected the 1 committed share, got %d (%v)", len(shares), err)
+	}
+	best, err := storage.GetBestShares("192.168.1.100", 10)
+	if err != nil || len(best) != 1 {
+		t.Errorf("expected the committed share kept as a best share, got %d (%v)", len(best), err)
+	}
+
+	if _, err := storage.read.Exec("DELETE FROM shares"); err == nil {
+		t.Error("expected the read pool to refuse writes")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A new database is created with incremental vacuum
+	converted, err := storage.EnableIncrementalVacuum()
+	if err != nil || converted {
+		t.Fatalf("expected no conversion, got %v (%v)", converted, err)
+	}
+
+	old := time.Now().AddDate(0, 0, -60)
+	for i := 0; i < 2000; i++ {
+		share := &Share{MinerIP: "192.168.1.100", Timestamp: old, Difficulty: float64(i), JobID: strings.Repeat("a", 200)}
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+	if _, err := storage.PurgeOldData(30); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+
+	if freed, err := storage.Compact(VacuumOff); err != nil || freed != 0 {
+		t.Errorf("expected nothing reclaimed with vacuum off, got %d (%v)", freed, err)
+	}
+	freed, err := storage.Compact(VacuumIncremental)
+	if err != nil || freed <= 0 {
+		t.Errorf("expected the purged pages reclaimed, got %d (%v)", freed, err)
+	}
+	if freed, err := storage.Compact(VacuumFull); err != nil || freed < 0 {
+		t.Errorf("expected a full vacuum to succeed, got %d (%v)", freed, err)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	sc := &Schedule{Name: "Peak", Enabled: true, Miners: []string{"192.168.1.100"}, Action: "underclock",
+		Frequency: 400, Trigger: "time", Start: "17:00", End: "21:00", Days: []string{"mon", "fri"}}
+	if _, err := storage.SaveSchedule(sc); err != nil || sc.ID == 0 {
+		t.Fatalf("failed to insert schedule: %v", err)
+	}
+	sc.Frequency = 350
+	if found, err := storage.SaveSchedule(sc); err != nil || !found {
+		t.Fatalf("failed to update schedule: %v (found %v)", err, found)
+	}
+	if found, _ := storage.SaveSchedule(&Schedule{ID: 99, Name: "Missing"}); found {
+		t.Error("expected no schedule 99 to update")
+	}
+	schedules, err := storage.GetSchedules()
+	if err != nil || len(schedules) != 1 || schedules[0].Frequency != 350 || len(schedules[0].Days) != 2 || schedules[0].Miners[0] != "192.168.1.100" {
+		t.Fatalf("unexpected schedules %+v (%v)", schedules, err)
+	}
+
+	start := time.Now().Add(-2 * time.Hour)
+	run := &ScheduleRun{ScheduleID: sc.ID, MinerIP: "192.168.1.100", Action: "underclock", Start: start,
+		BaselineWatts: 15, RestoreFrequency: 525, RestoreCoreVoltage: 1200}
+	if err := storage.InsertScheduleRun(run); err != nil {
+		t.Fatalf("failed to insert run: %v", err)
+	}
+	open, err := storage.GetOpenScheduleRuns()
+	if err != nil || len(open) != 1 || open[0].RestoreFrequency != 525 || open[0].Start.Unix() != start.Unix() {
+		t.Fatalf("unexpected open runs %+v (%v)", open, err)
+	}
+	end := start.Add(time.Hour)
+	run.End, run.SavedKWh, run.SavedCost = &end, 0.006, 0.0018
+	if err := storage.UpdateScheduleRun(run); err != nil {
+		t.Fatalf("failed to end run: %v", err)
+	}
+	if open, _ := storage.GetOpenScheduleRuns(); len(open) != 0 {
+		t.Errorf("expected no open runs, got %d", len(open))
+	}
+
+	// Deleting the schedule keeps its savings
+	if found, err := storage.DeleteSchedule(sc.ID); err != nil || !found {
+		t.Fatalf("failed to delete schedule: %v", err)
+	}
+	savings, err := storage.GetScheduleSavings(time.Now().AddDate(0, 0, -1))
+	if err != nil || len(savings) != 1 || savings[0].Runs != 1 || savings[0].SavedKWh != 0.006 || savings[0].Hours < 0.99 || savings[0].Hours > 1.01 {
+		t.Fatalf("unexpected savings %+v (%v)", savings, err)
+	}
+
+	until := time.Now().Add(time.Hour)
+	if err := storage.SetScheduleOverride(&ScheduleOverride{MinerIP: "192.168.1.100", Mode: "run", Until: &until}); err != nil {
+		t.Fatalf("failed to set override: %v", err)
+	}
+	if err := storage.SetScheduleOverride(&ScheduleOverride{MinerIP: "192.168.1.100", Mode: "pause"}); err != nil {
+		t.Fatalf("failed to replace override: %v", err)
+	}
+	overrides, err := storage.GetScheduleOverrides()
+	if err != nil || len(overrides) != 1 || overrides[0].Mode != "pause" || overrides[0].Until != nil {
+		t.Fatalf("unexpected overrides %+v (%v)", overrides, err)
+	}
+	if err := storage.DeleteScheduleOverride("192.168.1.100"); err != nil {
+		t.Fatalf("failed to delete override: %v", err)
+	}
+	if overrides, _ := storage.GetScheduleOverrides(); len(overrides) != 0 {
+		t.Errorf("expected no overrides, got %d", len(overrides))
+	}
+}
+
+func TestShareLinks(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expires := time.Now().Add(24 * time.Hour)
+	discord := &ShareLink{Name: "Discord", TokenHash: "abc123", HideIPs: true, Created: time.Now(), Expires: &expires}
+	if err := storage.InsertShareLink(discord); err != nil || discord.ID == 0 {
+		t.Fatalf("failed to insert share link: %v", err)
+	}
+	if err := storage.InsertShareLink(&ShareLink{Name: "Forum", TokenHash: "def456", Created: time.Now()}); err != nil {
+		t.Fatalf("failed to insert share link: %v", err)
+	}
+	if err := storage.InsertShareLink(&ShareLink{Name: "Copy", TokenHash: "abc123", Created: time.Now()}); err == nil {
+		t.Error("expected duplicate token hash to be rejected")
+	}
+
+	links, err := storage.GetShareLinks()
+	if err != nil || len(links) != 2 || links[0].Name != "Forum" || links[0].Expires != nil {
+		t.Fatalf("unexpected share links %+v (%v)", links, err)
+	}
+
+	link, err := storage.GetShareLinkByTokenHash("abc123")
+	if err != nil || link == nil || !link.HideIPs || link.Expires == nil || link.Expires.Unix() != expires.Unix() {
+		t.Fatalf("unexpected share link %+v (%v)", link, err)
+	}
+	if link.Expired(time.Now()) || !link.Expired(expires.Add(time.Second)) {
+		t.Error("expected link to expire at its expiry time")
+	}
+	if link, err := storage.GetShareLinkByTokenHash("nope"); err != nil || link != nil {
+		t.Errorf("expected no link for an unknown hash, got %+v (%v)", link, err)
+	}
+
+	if found, err := storage.DeleteShareLink(discord.ID); err != nil || !found {
+		t.Fatalf("failed to delete share link: %v", err)
+	}
+	if found, _ := storage.DeleteShareLink(discord.ID); found {
+		t.Error("expected deleted share link to be gone")
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().Truncate(time.Second)
+	past := &MaintenanceWindow{MinerIP: "192.168.1.10", Start: now.Add(-48 * time.Hour), End: now.Add(-47 * time.Hour)}
+	current := &MaintenanceWindow{MinerIP: "192.168.1.11", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Note: "new fan"}
+	for _, w := range []*MaintenanceWindow{past, current} {
+		if err := storage.InsertMaintenanceWindow(w); err != nil || w.ID == 0 {
+			t.Fatalf("failed to insert maintenance window: %v", err)
+		}
+	}
+
+	windows, err := storage.GetMaintenanceWindows()
+	if err != nil || len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d (%v)", len(windows), err)
+	}
+	if w := windows[0]; w.ID != current.ID || w.Note != "new fan" || !w.Start.Equal(current.Start) || !w.End.Equal(current.End) {
+		t.Errorf("expected the latest window first, got %+v", w)
+	}
+	if !windows[0].Active(now) || windows[1].Active(now) || windows[0].Active(current.End) {
+		t.Error("expected only the current window to be active, up to its end")
+	}
+
+	if found, err := storage.DeleteMaintenanceWindow(past.ID); err != nil || !found {
+		t.Fatalf("failed to delete maintenance window: %v", err)
+	}
+	if found, _ := storage.DeleteMaintenanceWindow(past.ID); found {
+		t.Error("expected deleted window to be gone")
+	}
+}
+
+func TestLuckDays(t *testing.T) {
+	storage, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ip := "192.168.1.100"
+	now := time.Now()
+	y := now.AddDate(0, 0, -1)
+	noon := time.Date(y.Year(), y.Month(), y.Day(), 12, 0, 0, 0, time.Local)
+
+	// One hour at 1 TH/s
+	for _, min := range []int{10, 40} {
+		if err := storage.InsertSnapshot(&MinerSnapshot{MinerIP: ip, Timestamp: noon.Add(-time.Hour + time.Duration(min)*time.Minute), HashRate: 1000}); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+	if err := storage.UpdateRollups(noon.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to update rollups: %v", err)
+	}
+
+	// The pool difficulty rises during the day, so the higher one counts
+	for i, diff := range []float64{1e6, 2e6} {
+		if _, err := storage.RecordPoolDifficulty(ip, "bitaxe-1", diff, noon.Add(time.Duration(i*24-30)*time.Hour)); err != nil {
+			t.Fatalf("failed to record pool difficulty: %v", err)
+		}
+	}
+	shares := []*Share{
+		{MinerIP: ip, Timestamp: noon, Difficulty: 3e6},
+		{MinerIP: ip, Timestamp: noon, Difficulty: 1.5e6},
+		{MinerIP: ip, Timestamp: noon, Difficulty: 5e6},
+		{MinerIP: ip, Timestamp: now, Difficulty: 9e6}, // Today isn't complete
+	}
+	for _, share := range shares {
+		if err := storage.InsertShare(share); err != nil {
+			t.Fatalf("failed to insert share: %v", err)
+		}
+	}
+	// Rejected shares count, but aren't the best share
+	if err := storage.MarkShareRejected(shares[2].ID, "Stale"); err != nil {
+		t.Fatalf("failed to mark share rejected: %v", err)
+	}
+
+	if err := storage.UpdateLuck(now); err != nil {
+		t.Fatalf("failed to update luck: %v", err)
+	}
+	// Running again after the shares were purged must not lose them
+	if _, err := storage.PurgeOldShares(0); err != nil {
+		t.Fatalf("failed to purge shares: %v", err)
+	}
+	if err := storage.UpdateLuck(now); err != nil {
+		t.Fatalf("failed to update luck again: %v", err)
+	}
+
+	days, err := storage.GetLuckDays("", now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("failed to get luck: %v", err)
+	}
+	if len(days) != 1 {
+		t.Fatalf("expected 1 day, got %d", len(days))
+	}
+	d := days[0]
+	if d.Day != noon.Format("2006-01-02") || d.Threshold != 2e6 || d.Shares != 2 || d.BestDiff != 3e6 {
+		t.Errorf("unexpected luck day: %+v", d)
+	}
+	if d.Hashes != 3.6e15 || d.ExpectedBest != 3.6e15/(1<<32) || d.ExpectedShares != 3.6e15/(2e6*(1<<32)) {
+		t.Errorf("unexpected expectation: %+v", d)
+	}
+
+	if days, _ := storage.GetLuckDays("192.168.1.101", now.AddDate(0, 0, -7)); len(days) != 0 {
+		t.Errorf("expected no luck for another miner, got %d days", len(days))
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import "database/sql"
+
+// statements are the writes made on every poll or share, prepared once
+// instead of parsed on every call. Within a transaction, use tx.Stmt.
+type statements struct {
+	upsertMiner    *sql.Stmt
+	insertSnapshot *sql.Stmt
+	insertShare    *sql.Stmt
+	keepBestShare  *sql.Stmt
+	trimBestShares *sql.Stmt
+}
+
+func prepareStatements(db *sql.DB) (*statements, error) {
+	st := &statements{}
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&st.upsertMiner, upsertMinerQuery},
+		{&st.insertSnapshot, insertSnapshotQuery},
+		{&st.insertShare, insertShareQuery},
+		{&st.keepBestShare, keepBestShareQuery},
+		{&st.trimBestShares, trimBestSharesQuery},
+	}
+	for _, q := range queries {
+		stmt, err := db.Prepare(q.query)
+		if err != nil {
+			st.close()
+			return nil, err
+		}
+		*q.stmt = stmt
+	}
+	return st, nil
+}
+
+func (st *statements) close() {
+	for _, stmt := range []*sql.Stmt{st.upsertMiner, st.insertSnapshot, st.insertShare, st.keepBestShare, st.trimBestShares} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import "strings"
+
+// Tag is a group of miners, e.g. a room, rack or power circuit. Tags are
+// matched case-insensitively.
+type Tag struct {
+	Name   string   `json:"name"`
+	Miners []string `json:"miners"` // IPs of enabled miners with the tag
+}
+
+// GetTags returns every tag with the enabled miners that have it, by name
+func (s *SQLiteStorage) GetTags() ([]*Tag, error) {
+	rows, err := s.read.Query(`
+	SELECT t.tag, t.miner_ip
+	FROM miner_tags t
+	JOIN miners m ON m.ip = t.miner_ip
+	WHERE m.enabled = 1
+	ORDER BY t.tag, t.miner_ip
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		var name, ip string
+		if err := rows.Scan(&name, &ip); err != nil {
+			return nil, err
+		}
+		if n := len(tags); n == 0 || !strings.EqualFold(tags[n-1].Name, name) {
+			tags = append(tags, &Tag{Name: name})
+		}
+		tags[len(tags)-1].Miners = append(tags[len(tags)-1].Miners, ip)
+	}
+	return tags, rows.Err()
+}
+
+// GetMinerTags returns the tags of every miner that has any, keyed by IP
+func (s *SQLiteStorage) GetMinerTags() (map[string][]string, error) {
+	rows, err := s.read.Query("SELECT miner_ip, tag FROM miner_tags ORDER BY miner_ip, tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var ip, tag string
+		if err := rows.Scan(&ip, &tag); err != nil {
+			return nil, err
+		}
+		tags[ip] = append(tags[ip], tag)
+	}
+	return tags, rows.Err()
+}
+
+// SetMinerTags replaces a miner's tags
+func (s *SQLiteStorage) SetMinerTags(minerIP string, tags []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM miner_tags WHERE miner_ip = ?", minerIP); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO miner_tags (miner_ip, tag) VALUES (?, ?)", minerIP, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RenameTag renames a tag on every miner, merging it into newName where a
+// miner already has that. It returns the number of miners that had the tag.
+func (s *SQLiteStorage) RenameTag(oldName, newName string) (int64, error) {
+	// Only the case changes, which the primary key can't tell apart
+	if strings.EqualFold(oldName, newName) {
+		result, err := s.db.Exec("UPDATE miner_tags SET tag = ? WHERE tag = ?", newName, oldName)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO miner_tags (miner_ip, tag) SELECT miner_ip, ? FROM miner_tags WHERE tag = ?", newName, oldName); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("DELETE FROM miner_tags WHERE tag = ?", oldName)
+	if err != nil {
+		return 0, err
+	}
+	renamed, _ := result.RowsAffected()
+	return renamed, tx.Commit()
+}
+
+// DeleteTag removes a tag from every miner and returns how many had it
+func (s *SQLiteStorage) DeleteTag(name string) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM miner_tags WHERE tag = ?", name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
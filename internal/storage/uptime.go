@@ -0,0 +1,73 @@
+package storage
+
+import "time"
+
+// UptimeEvent records a miner going online or offline
+type UptimeEvent struct {
+	MinerIP   string    `json:"minerIp"`
+	Timestamp time.Time `json:"timestamp"`
+	Online    bool      `json:"online"`
+}
+
+// InsertUptimeEvent records a miner's online state changing
+func (s *SQLiteStorage) InsertUptimeEvent(e *UptimeEvent) error {
+	_, err := s.db.Exec(`INSERT INTO uptime_events (miner_ip, timestamp, online) VALUES (?, ?, ?)`,
+		e.MinerIP, e.Timestamp.UTC().Format("2006-01-02 15:04:05"), e.Online)
+	return err
+}
+
+// GetLastUptimeEvent returns a miner's latest uptime event, or nil if none
+// was recorded
+func (s *SQLiteStorage) GetLastUptimeEvent(minerIP string) (*UptimeEvent, error) {
+	events, err := s.queryUptimeEvents(`
+	SELECT miner_ip, timestamp, online FROM uptime_events
+	WHERE miner_ip = ?
+	ORDER BY timestamp DESC, id DESC
+	LIMIT 1
+	`, minerIP)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+// GetUptimeEvents returns a miner's uptime events since a given time, oldest
+// first, preceded by the last one before it, which holds the miner's state
+// at that time
+func (s *SQLiteStorage) GetUptimeEvents(minerIP string, since time.Time) ([]*UptimeEvent, error) {
+	from := since.UTC().Format("2006-01-02 15:04:05")
+	return s.queryUptimeEvents(`
+	SELECT miner_ip, timestamp, online FROM (
+		SELECT id, miner_ip, timestamp, online FROM (
+			SELECT id, miner_ip, timestamp, online FROM uptime_events
+			WHERE miner_ip = ? AND timestamp < ?
+			ORDER BY timestamp DESC, id DESC
+			LIMIT 1
+		)
+		UNION ALL
+		SELECT id, miner_ip, timestamp, online FROM uptime_events
+		WHERE miner_ip = ? AND timestamp >= ?
+	)
+	ORDER BY timestamp, id
+	`, minerIP, from, minerIP, from)
+}
+
+func (s *SQLiteStorage) queryUptimeEvents(query string, args ...interface{}) ([]*UptimeEvent, error) {
+	rows, err := s.read.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*UptimeEvent
+	for rows.Next() {
+		e := &UptimeEvent{}
+		var ts string
+		if err := rows.Scan(&e.MinerIP, &ts, &e.Online); err != nil {
+			return nil, err
+		}
+		e.Timestamp = parseTimestamp(ts)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// TableUsage describes how much space a table takes and how fast it grows
+type TableUsage struct {
+	Name        string `json:"name"`
+	Rows        int64  `json:"rows"`
+	Bytes       int64  `json:"bytes"`       // Table plus indexes; estimated from row counts without dbstat
+	RowsPerDay  int64  `json:"rowsPerDay"`  // Rows added in the last 24 hours that are still retained
+	BytesPerDay int64  `json:"bytesPerDay"` // RowsPerDay at the table's average row size
+}
+
+// timestampColumns maps tables to the column recording when a row was added.
+// Tables without one (miners, energy_counters) don't grow over time.
+var timestampColumns = map[string]string{
+	"miner_snapshots":         "timestamp",
+	"shares":                  "timestamp",
+	"blocks":                  "timestamp",
+	"audit_log":               "timestamp",
+	"hostname_history":        "first_seen",
+	"pool_difficulty_changes": "timestamp",
+	"competition_results":     "period_end",
+	"miner_logs":              "timestamp",
+	"failed_deliveries":       "timestamp",
+	"alerts":                  "timestamp",
+	"snapshots_hourly":        "timestamp",
+	"snapshots_daily":         "timestamp",
+	"energy_daily":            "day",
+}
+
+// GetTableUsage returns row counts, sizes and daily growth for every MinerHQ table
+func (s *SQLiteStorage) GetTableUsage() ([]TableUsage, error) {
+	stats, err := s.GetTableStats()
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-24 * time.Hour).UTC().Format("2006-01-02 15:04:05")
+	usage := make([]TableUsage, 0, len(stats))
+	for _, t := range stats {
+		u := TableUsage{Name: t.Name, Rows: t.Rows, Bytes: s.tableBytes(t.Name)}
+
+		if col, ok := timestampColumns[t.Name]; ok {
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s >= ?", t.Name, col)
+			if err := s.read.QueryRow(query, since).Scan(&u.RowsPerDay); err != nil {
+				return nil, fmt.Errorf("failed to count recent %s: %w", t.Name, err)
+			}
+			if u.Rows > 0 {
+				u.BytesPerDay = u.Bytes * u.RowsPerDay / u.Rows
+			}
+		}
+
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
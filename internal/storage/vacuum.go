@@ -0,0 +1,86 @@
+package storage
+
+import "fmt"
+
+// Vacuum strategies for Compact
+const (
+	VacuumIncremental = "incremental" // Return free pages to the filesystem without rewriting the file
+	VacuumFull        = "full"        // Rebuild the whole file, locking the database while it runs
+	VacuumOff         = "off"         // Keep free pages for reuse by later writes
+)
+
+// EnableIncrementalVacuum switches the database to incremental auto-vacuum,
+// which Compact needs to return free pages without rewriting the file. New
+// databases are created with it; an older one needs a single full VACUUM to
+// switch, which is run here. It reports whether that was needed.
+func (s *SQLiteStorage) EnableIncrementalVacuum() (bool, error) {
+	var mode int
+	if err := s.db.QueryRow("PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return false, fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	if mode == 2 {
+		return false, nil
+	}
+	if _, err := s.db.Exec("PRAGMA auto_vacuum=INCREMENTAL"); err != nil {
+		return false, fmt.Errorf("failed to set auto_vacuum: %w", err)
+	}
+	return true, s.Vacuum()
+}
+
+// Compact reclaims the disk space freed by deletions with the given vacuum
+// strategy ("" is incremental) and truncates the WAL. It returns how many
+// bytes the database file shrank by. An incremental vacuum only frees pages
+// once EnableIncrementalVacuum has been run.
+func (s *SQLiteStorage) Compact(strategy string) (int64, error) {
+	before, err := s.pageBytes()
+	if err != nil {
+		return 0, err
+	}
+
+	switch strategy {
+	case VacuumFull:
+		err = s.Vacuum()
+	case VacuumOff:
+	default:
+		if _, err = s.db.Exec("PRAGMA incremental_vacuum"); err != nil {
+			err = fmt.Errorf("failed to vacuum database: %w", err)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Checkpoint(); err != nil {
+		return 0, err
+	}
+
+	after, err := s.pageBytes()
+	if err != nil {
+		return 0, err
+	}
+	return before - after, nil
+}
+
+// Checkpoint copies the WAL into the database file and truncates it, so the
+// WAL doesn't stay the size of the largest burst of writes
+func (s *SQLiteStorage) Checkpoint() error {
+	var busy, walPages, checkpointed int
+	if err := s.db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &walPages, &checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	if busy != 0 {
+		return fmt.Errorf("failed to checkpoint WAL: %d of %d pages copied while readers held it", checkpointed, walPages)
+	}
+	return nil
+}
+
+// pageBytes returns the size of the database file from its page count
+func (s *SQLiteStorage) pageBytes() (int64, error) {
+	var pageCount, pageSize int64
+	if err := s.db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := s.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
@@ -0,0 +1,979 @@
+// Package stratumproxy relays miners to their pools through MinerHQ. Each
+// pool gets a single connection, shared by the miners pointed at the proxy's
+// port for it, and every share a miner submits is hashed and recorded with
+// the pool's answer, so shares, rejections and blocks don't depend on parsing
+// the miners' logs.
+package stratumproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/poolcheck"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const (
+	// reconnectDelay is how long to wait before connecting to a pool again
+	reconnectDelay = 5 * time.Second
+
+	// dialTimeout bounds connecting to a pool
+	dialTimeout = 10 * time.Second
+
+	// poolIdleTimeout is how long a pool may stay silent. Pools send a new
+	// job every minute or so, so one silent this long is hung.
+	poolIdleTimeout = 5 * time.Minute
+
+	// writeTimeout bounds a write to a pool or a miner
+	writeTimeout = 10 * time.Second
+
+	// maxMessageSize bounds a message from a miner
+	maxMessageSize = 64 * 1024
+
+	// keptJobs is how many of a pool's latest jobs are kept for shares
+	// submitted after the next job arrived
+	keptJobs = 8
+
+	// prefixSize is how many bytes of the pool's extranonce2 the proxy sets
+	// to tell the miners apart, leaving the rest to them. One byte fits 256
+	// miners per pool.
+	prefixSize = 1
+
+	// defaultMask is the version bits asked of the pool, per BIP 320
+	defaultMask = 0x1fffe000
+)
+
+// errNotConnected is returned while a pool's connection is down
+var errNotConnected = errors.New("not connected to the pool")
+
+// message is a stratum request, notification or response
+type message struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  json.RawMessage   `json:"error"`
+}
+
+// request is a stratum request, or a notification when ID is nil
+type request struct {
+	ID     interface{} `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// response answers a stratum request
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+	Error  interface{}     `json:"error"`
+}
+
+// stratumError is an error answered to a miner: a code, a message and no
+// traceback
+func stratumError(code int, message string) []interface{} {
+	return []interface{}{code, message, nil}
+}
+
+// MinerStatus reports a miner's shares through the proxy since MinerHQ
+// started
+type MinerStatus struct {
+	IP             string     `json:"ip"`
+	Hostname       string     `json:"hostname,omitempty"`
+	Worker         string     `json:"worker"` // Username the miner authorized with; its shares are submitted as the proxy's user
+	Connected      bool       `json:"connected"`
+	ConnectedSince *time.Time `json:"connectedSince,omitempty"` // Latest connection
+	Shares         int        `json:"shares"`                   // Shares recorded: at the miners' difficulty or above
+	Submitted      int        `json:"submitted"`                // Shares at the pool's difficulty, submitted to it
+	Accepted       int        `json:"accepted"`
+	Rejected       int        `json:"rejected"` // By the pool, or by the proxy for an unknown job or too low a difficulty
+	Blocks         int        `json:"blocks"`
+	LastShare      *time.Time `json:"lastShare,omitempty"`
+}
+
+// Status reports a pool the proxy relays to
+type Status struct {
+	Port              int           `json:"port"`
+	Pool              string        `json:"pool"`
+	Connected         bool          `json:"connected"` // Subscribed and authorized
+	ConnectedSince    *time.Time    `json:"connectedSince,omitempty"`
+	LastError         string        `json:"lastError,omitempty"`
+	PoolDifficulty    float64       `json:"poolDifficulty"`
+	MinerDifficulty   float64       `json:"minerDifficulty"`
+	NetworkDifficulty float64       `json:"networkDifficulty"` // Of the latest job
+	Miners            []MinerStatus `json:"miners"`            // By IP
+}
+
+// conn is a stratum connection, one JSON message per line
+type conn struct {
+	net.Conn
+	wmu sync.Mutex
+}
+
+// send writes a message
+func (c *conn) send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = c.Write(append(data, '\n'))
+	return err
+}
+
+// session is a miner connected to the proxy. Its fields are guarded by the
+// relay's mu.
+type session struct {
+	*conn
+	ip         string
+	prefix     byte   // Extranonce2 byte the miner's shares are submitted with
+	requested  uint32 // Version bits the miner asked to roll
+	mask       uint32 // Version bits the miner may roll
+	subscribed bool
+	authorized bool
+	working    bool // Sent the difficulty and the latest job
+}
+
+// reply answers a miner's request, unless it was a notification
+func (s *session) reply(id json.RawMessage, result, err interface{}) error {
+	if isNull(id) {
+		return nil
+	}
+	return s.send(response{ID: id, Result: result, Error: err})
+}
+
+// relay is the proxy of one pool: its connection to the pool and the miners
+// sharing it
+type relay struct {
+	cfg     config.ProxyPoolConfig
+	pool    poolcheck.Pool
+	dial    func(p poolcheck.Pool) (net.Conn, error)
+	onShare func(share *storage.Share, block bool)
+	ln      net.Listener
+
+	mu              sync.Mutex
+	upstream        *conn // nil while disconnected
+	ready           bool  // Subscribed and authorized, admitting miners
+	stopped         bool
+	extranonce1     []byte
+	extranonce2Size int
+	mask            uint32  // Version bits the pool allows rolling
+	difficulty      float64 // The pool's
+	jobs            []*job  // Latest last
+	notify          []json.RawMessage
+	nextID          int64
+	pending         map[int64]func(result, errRaw json.RawMessage) error // Callbacks of requests to the pool by ID
+	sessions        map[*session]bool
+	stats           map[string]*MinerStatus // By miner IP
+	status          Status
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newRelay(cfg config.ProxyPoolConfig, pool poolcheck.Pool, onShare func(share *storage.Share, block bool)) *relay {
+	return &relay{
+		cfg:      cfg,
+		pool:     pool,
+		dial:     dial,
+		onShare:  onShare,
+		sessions: make(map[*session]bool),
+		stats:    make(map[string]*MinerStatus),
+		status:   Status{Port: cfg.Port, Pool: pool.String()},
+		stop:     make(chan struct{}),
+	}
+}
+
+// Proxy relays miners to the pools of the stratum_proxy settings
+type Proxy struct {
+	relays []*relay
+}
+
+// New creates a proxy of the pools in cfg. onShare is called with every
+// share a miner submits, and whether it solved a block.
+func New(cfg config.StratumProxyConfig, onShare func(share *storage.Share, block bool)) (*Proxy, error) {
+	p := &Proxy{}
+	for _, pc := range cfg.Pools {
+		pool, ok := poolcheck.ParsePool(pc.URL, 0)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a pool URL with a port", pc.URL)
+		}
+		p.relays = append(p.relays, newRelay(pc, pool, onShare))
+	}
+	return p, nil
+}
+
+// Start listens for miners on every pool's port and connects to the pools
+func (p *Proxy) Start() error {
+	for i, r := range p.relays {
+		ln, err := net.Listen("tcp", ":"+strconv.Itoa(r.cfg.Port))
+		if err != nil {
+			for _, started := range p.relays[:i] {
+				started.close()
+			}
+			return err
+		}
+		r.start(ln)
+		log.Printf("Stratum proxy: relaying port %d to %s", r.cfg.Port, r.pool)
+	}
+	return nil
+}
+
+// Stop disconnects the miners and the pools
+func (p *Proxy) Stop() {
+	for _, r := range p.relays {
+		r.close()
+	}
+}
+
+// Status returns the state of every pool, ordered by port
+func (p *Proxy) Status() []Status {
+	statuses := make([]Status, 0, len(p.relays))
+	for _, r := range p.relays {
+		statuses = append(statuses, r.snapshot())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Port < statuses[j].Port })
+	return statuses
+}
+
+// Proxied reports whether a miner is connected through the proxy
+func (p *Proxy) Proxied(ip string) bool {
+	for _, r := range p.relays {
+		r.mu.Lock()
+		for s := range r.sessions {
+			if s.ip == ip {
+				r.mu.Unlock()
+				return true
+			}
+		}
+		r.mu.Unlock()
+	}
+	return false
+}
+
+// dial connects to a pool, over TLS for stratum+ssl
+func dial(p poolcheck.Pool) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	addr := net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+	if p.TLS {
+		return tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: p.Host})
+	}
+	return dialer.Dial("tcp", addr)
+}
+
+// start accepts miners on ln and keeps the pool's connection up
+func (r *relay) start(ln net.Listener) {
+	r.ln = ln
+	r.wg.Add(2)
+	go r.run()
+	go r.accept()
+}
+
+// close stops accepting miners and drops every connection
+func (r *relay) close() {
+	r.mu.Lock()
+	if r.ln == nil || r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	up := r.upstream
+	r.mu.Unlock()
+
+	close(r.stop)
+	r.ln.Close()
+	if up != nil {
+		up.Close()
+	}
+	r.wg.Wait()
+}
+
+// snapshot returns the relay's status
+func (r *relay) snapshot() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.status
+	s.PoolDifficulty = r.difficulty
+	s.MinerDifficulty = r.minerDifficulty()
+	connected := make(map[string]bool)
+	for sess := range r.sessions {
+		connected[sess.ip] = true
+	}
+	s.Miners = make([]MinerStatus, 0, len(r.stats))
+	for ip, st := range r.stats {
+		m := *st
+		m.Connected = connected[ip]
+		s.Miners = append(s.Miners, m)
+	}
+	sort.Slice(s.Miners, func(i, j int) bool { return s.Miners[i].IP < s.Miners[j].IP })
+	return s
+}
+
+// minerDifficulty returns the difficulty set on the miners: the configured
+// one, unless the pool's is lower. The caller must hold mu.
+func (r *relay) minerDifficulty() float64 {
+	if r.cfg.Difficulty > 0 && r.cfg.Difficulty < r.difficulty {
+		return r.cfg.Difficulty
+	}
+	return r.difficulty
+}
+
+// run connects to the pool, again after every disconnection
+func (r *relay) run() {
+	defer r.wg.Done()
+	for {
+		err := r.connect()
+		r.disconnected(err)
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// connect subscribes to the pool and handles its messages until the
+// connection drops
+func (r *relay) connect() error {
+	nc, err := r.dial(r.pool)
+	if err != nil {
+		return err
+	}
+	up := &conn{Conn: nc}
+
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		nc.Close()
+		return nil
+	}
+	r.upstream = up
+	r.extranonce1 = nil
+	r.mask = 0
+	r.difficulty = 1
+	r.jobs = nil
+	r.notify = nil
+	r.pending = make(map[int64]func(result, errRaw json.RawMessage) error)
+	r.mu.Unlock()
+
+	// Sent in a row, like a miner does
+	configure := []interface{}{
+		[]string{"version-rolling"},
+		map[string]interface{}{"version-rolling.mask": fmt.Sprintf("%08x", defaultMask), "version-rolling.min-bit-count": 2},
+	}
+	if err := r.call("mining.configure", configure, r.configured); err != nil {
+		return err
+	}
+	if err := r.call("mining.subscribe", []string{"MinerHQ"}, r.subscribed); err != nil {
+		return err
+	}
+	if err := r.call("mining.authorize", []string{r.cfg.User, r.cfg.Password}, r.authorized); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(up)
+	for {
+		up.SetReadDeadline(time.Now().Add(poolIdleTimeout))
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return fmt.Errorf("not a stratum server: %w", err)
+		}
+		if err := r.handlePool(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+// disconnected drops the miners with the pool's connection: their
+// extranonces were the connection's, so they have to subscribe again once
+// the pool is back
+func (r *relay) disconnected(err error) {
+	r.mu.Lock()
+	up := r.upstream
+	pending := r.pending
+	sessions := make([]*session, 0, len(r.sessions))
+	for s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.upstream = nil
+	r.ready = false
+	r.pending = nil
+	r.status.Connected = false
+	r.status.ConnectedSince = nil
+	stopped := r.stopped
+	if err != nil && !stopped {
+		r.status.LastError = err.Error()
+	}
+	r.mu.Unlock()
+
+	if up != nil {
+		up.Close()
+	}
+	if err != nil && !stopped {
+		log.Printf("Stratum proxy: %s: %v", r.pool, err)
+	}
+	for _, s := range sessions {
+		s.Close()
+	}
+	// Requests left unanswered
+	for _, callback := range pending {
+		callback(nil, nil)
+	}
+}
+
+// call sends a request to the pool; callback handles the answer. An error
+// returned by callback drops the connection.
+func (r *relay) call(method string, params interface{}, callback func(result, errRaw json.RawMessage) error) error {
+	r.mu.Lock()
+	up := r.upstream
+	if up == nil {
+		r.mu.Unlock()
+		return errNotConnected
+	}
+	r.nextID++
+	id := r.nextID
+	r.pending[id] = callback
+	r.mu.Unlock()
+
+	return up.send(request{ID: id, Method: method, Params: params})
+}
+
+// configured records the version bits the pool allows rolling. Pools that
+// don't know mining.configure allow none.
+func (r *relay) configured(result, errRaw json.RawMessage) error {
+	var answer map[string]interface{}
+	if json.Unmarshal(result, &answer) != nil || answer["version-rolling"] != true {
+		return nil
+	}
+	mask, _ := answer["version-rolling.mask"].(string)
+	if m, err := parseHex32(mask); err == nil {
+		r.mu.Lock()
+		r.mask = m
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// subscribed records the extranonce the pool gave the connection
+func (r *relay) subscribed(result, errRaw json.RawMessage) error {
+	if !isNull(errRaw) {
+		return fmt.Errorf("subscription refused: %s", errorMessage(errRaw))
+	}
+	var answer []json.RawMessage
+	var extranonce1 string
+	var size int
+	if json.Unmarshal(result, &answer) != nil || len(answer) < 3 ||
+		json.Unmarshal(answer[1], &extranonce1) != nil || json.Unmarshal(answer[2], &size) != nil {
+		return fmt.Errorf("unexpected subscription answer %s", result)
+	}
+	en1, err := hex.DecodeString(extranonce1)
+	if err != nil {
+		return fmt.Errorf("invalid extranonce1 %q", extranonce1)
+	}
+	if size < prefixSize+2 {
+		return fmt.Errorf("the pool's extranonce2 of %d bytes leaves the miners too few", size)
+	}
+
+	r.mu.Lock()
+	r.extranonce1 = en1
+	r.extranonce2Size = size
+	r.mu.Unlock()
+	return nil
+}
+
+// authorized starts admitting miners once the pool accepts the user
+func (r *relay) authorized(result, errRaw json.RawMessage) error {
+	if !isTrue(result) {
+		if isNull(errRaw) {
+			return fmt.Errorf("authorization of %s refused", r.cfg.User)
+		}
+		return fmt.Errorf("authorization of %s refused: %s", r.cfg.User, errorMessage(errRaw))
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	r.ready = true
+	r.status.Connected = true
+	r.status.ConnectedSince = &now
+	r.status.LastError = ""
+	r.mu.Unlock()
+	log.Printf("Stratum proxy: connected to %s", r.pool)
+	return nil
+}
+
+// handlePool handles a message from the pool
+func (r *relay) handlePool(msg *message) error {
+	if msg.Method == "" {
+		id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+		if err != nil {
+			return nil
+		}
+		r.mu.Lock()
+		callback := r.pending[id]
+		delete(r.pending, id)
+		r.mu.Unlock()
+		if callback == nil {
+			return nil
+		}
+		return callback(msg.Result, msg.Error)
+	}
+
+	switch msg.Method {
+	case "mining.set_difficulty":
+		var d float64
+		if len(msg.Params) == 0 || json.Unmarshal(msg.Params[0], &d) != nil || d <= 0 {
+			return nil
+		}
+		r.mu.Lock()
+		r.difficulty = d
+		minerDiff := r.minerDifficulty()
+		r.mu.Unlock()
+		r.broadcast(request{Method: "mining.set_difficulty", Params: []float64{minerDiff}})
+
+	case "mining.notify":
+		j, err := parseJob(msg.Params)
+		if err != nil {
+			log.Printf("Stratum proxy: %s: %v", r.pool, err)
+			return nil
+		}
+		r.mu.Lock()
+		j.poolDiff = r.difficulty
+		j.minerDiff = r.minerDifficulty()
+		r.jobs = append(r.jobs, j)
+		if len(r.jobs) > keptJobs {
+			r.jobs = r.jobs[len(r.jobs)-keptJobs:]
+		}
+		r.notify = msg.Params
+		r.status.NetworkDifficulty = networkDifficulty(j.nbits)
+		r.mu.Unlock()
+		r.broadcast(request{Method: "mining.notify", Params: msg.Params})
+
+	case "mining.set_version_mask":
+		var mask string
+		if len(msg.Params) == 0 || json.Unmarshal(msg.Params[0], &mask) != nil {
+			return nil
+		}
+		m, err := parseHex32(mask)
+		if err != nil {
+			return nil
+		}
+		r.mu.Lock()
+		r.mask = m
+		masks := make(map[*session]uint32)
+		for s := range r.sessions {
+			if s.requested != 0 {
+				s.mask = s.requested & m
+				masks[s] = s.mask
+			}
+		}
+		r.mu.Unlock()
+		for s, mask := range masks {
+			s.send(request{Method: "mining.set_version_mask", Params: []string{fmt.Sprintf("%08x", mask)}})
+		}
+
+	case "client.reconnect":
+		return errors.New("the pool asked to reconnect")
+
+	case "client.show_message":
+		var text string
+		if len(msg.Params) > 0 && json.Unmarshal(msg.Params[0], &text) == nil {
+			log.Printf("Stratum proxy: %s says: %s", r.pool, text)
+		}
+
+	case "client.get_version":
+		r.mu.Lock()
+		up := r.upstream
+		r.mu.Unlock()
+		if up != nil {
+			return up.send(response{ID: msg.ID, Result: "MinerHQ"})
+		}
+	}
+	return nil
+}
+
+// broadcast sends a notification to the miners working on the pool's jobs
+func (r *relay) broadcast(notification request) {
+	r.mu.Lock()
+	sessions := make([]*session, 0, len(r.sessions))
+	for s := range r.sessions {
+		if s.working {
+			sessions = append(sessions, s)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		if err := s.send(notification); err != nil {
+			s.Close()
+		}
+	}
+}
+
+// accept admits miners until the relay is closed
+func (r *relay) accept() {
+	defer r.wg.Done()
+	for {
+		nc, err := r.ln.Accept()
+		if err != nil {
+			select {
+			case <-r.stop:
+				return
+			default:
+			}
+			log.Printf("Stratum proxy: port %d: %v", r.cfg.Port, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		s, err := r.open(nc)
+		if err != nil {
+			// Refused while the pool is down, so the miner fails over to its
+			// fallback pool
+			nc.Close()
+			continue
+		}
+		r.wg.Add(1)
+		go r.serve(s)
+	}
+}
+
+// open admits a miner while the pool's connection is up, giving it a free
+// extranonce prefix
+func (r *relay) open(nc net.Conn) (*session, error) {
+	ip := nc.RemoteAddr().String()
+	if addr, ok := nc.RemoteAddr().(*net.TCPAddr); ok {
+		ip = addr.IP.String()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.ready {
+		return nil, errNotConnected
+	}
+	used := make(map[byte]bool, len(r.sessions))
+	for s := range r.sessions {
+		used[s.prefix] = true
+	}
+	for i := 0; i < 1<<(8*prefixSize); i++ {
+		if used[byte(i)] {
+			continue
+		}
+		s := &session{conn: &conn{Conn: nc}, ip: ip, prefix: byte(i)}
+		r.sessions[s] = true
+
+		now := time.Now()
+		st := r.stats[ip]
+		if st == nil {
+			st = &MinerStatus{IP: ip}
+			r.stats[ip] = st
+		}
+		st.ConnectedSince = &now
+		return s, nil
+	}
+	log.Printf("Stratum proxy: port %d: refusing %s, %d miners are connected already", r.cfg.Port, ip, len(r.sessions))
+	return nil, errors.New("no free extranonce")
+}
+
+// serve handles a miner's messages until it disconnects
+func (r *relay) serve(s *session) {
+	defer r.wg.Done()
+	defer func() {
+		r.mu.Lock()
+		delete(r.sessions, s)
+		r.mu.Unlock()
+		s.Close()
+	}()
+
+	scanner := bufio.NewScanner(s)
+	scanner.Buffer(make([]byte, 4096), maxMessageSize)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return
+		}
+		if err := r.handleMiner(s, &msg); err != nil {
+			return
+		}
+	}
+}
+
+// handleMiner handles a message from a miner
+func (r *relay) handleMiner(s *session, msg *message) error {
+	switch msg.Method {
+	case "mining.configure":
+		return s.reply(msg.ID, r.configure(s, msg.Params), nil)
+
+	case "mining.subscribe":
+		r.mu.Lock()
+		extranonce1 := hex.EncodeToString(append(append([]byte(nil), r.extranonce1...), s.prefix))
+		size := r.extranonce2Size - prefixSize
+		s.subscribed = true
+		r.mu.Unlock()
+		subscriptions := [][]string{{"mining.set_difficulty", "minerhq"}, {"mining.notify", "minerhq"}}
+		if err := s.reply(msg.ID, []interface{}{subscriptions, extranonce1, size}, nil); err != nil {
+			return err
+		}
+		return r.sendWork(s)
+
+	case "mining.authorize":
+		var worker string
+		if len(msg.Params) > 0 {
+			json.Unmarshal(msg.Params[0], &worker)
+		}
+		r.mu.Lock()
+		s.authorized = true
+		r.stats[s.ip].Worker = worker
+		r.mu.Unlock()
+		if err := s.reply(msg.ID, true, nil); err != nil {
+			return err
+		}
+		return r.sendWork(s)
+
+	case "mining.submit":
+		return r.submit(s, msg)
+
+	case "mining.suggest_difficulty":
+		// The pool's difficulty is shared by the miners
+		return s.reply(msg.ID, true, nil)
+
+	case "mining.extranonce.subscribe":
+		return s.reply(msg.ID, false, nil)
+	}
+	return s.reply(msg.ID, nil, stratumError(20, "Unsupported method"))
+}
+
+// configure negotiates version rolling with a miner: it may roll the bits it
+// asks for that the pool allows
+func (r *relay) configure(s *session, params []json.RawMessage) map[string]interface{} {
+	var extensions []string
+	var options map[string]interface{}
+	if len(params) > 0 {
+		json.Unmarshal(params[0], &extensions)
+	}
+	if len(params) > 1 {
+		json.Unmarshal(params[1], &options)
+	}
+
+	result := make(map[string]interface{})
+	for _, ext := range extensions {
+		if ext != "version-rolling" {
+			result[ext] = false
+			continue
+		}
+		requested := uint32(0xffffffff)
+		if mask, ok := options["version-rolling.mask"].(string); ok {
+			if m, err := parseHex32(mask); err == nil {
+				requested = m
+			}
+		}
+		r.mu.Lock()
+		s.requested = requested
+		s.mask = requested & r.mask
+		mask := s.mask
+		r.mu.Unlock()
+
+		result["version-rolling"] = mask != 0
+		if mask != 0 {
+			result["version-rolling.mask"] = fmt.Sprintf("%08x", mask)
+		}
+	}
+	return result
+}
+
+// sendWork sends a miner the difficulty and the latest job, once it has
+// subscribed and authorized
+func (r *relay) sendWork(s *session) error {
+	r.mu.Lock()
+	if !s.subscribed || !s.authorized || s.working {
+		r.mu.Unlock()
+		return nil
+	}
+	s.working = true
+	difficulty := r.minerDifficulty()
+	notify := r.notify
+	r.mu.Unlock()
+
+	if err := s.send(request{Method: "mining.set_difficulty", Params: []float64{difficulty}}); err != nil {
+		return err
+	}
+	if notify == nil {
+		return nil
+	}
+	return s.send(request{Method: "mining.notify", Params: notify})
+}
+
+// findJob returns a kept job by ID. The caller must hold mu.
+func (r *relay) findJob(id string) *job {
+	for i := len(r.jobs) - 1; i >= 0; i-- {
+		if r.jobs[i].id == id {
+			return r.jobs[i]
+		}
+	}
+	return nil
+}
+
+// submit hashes a miner's share and records it. Shares meeting the pool's
+// difficulty are submitted to the pool, and the miner gets the pool's
+// answer; the others are answered by the proxy.
+func (r *relay) submit(s *session, msg *message) error {
+	// worker, job ID, extranonce2, ntime, nonce and the optional version bits
+	var p [6]string
+	for i := 0; i < len(msg.Params) && i < len(p); i++ {
+		json.Unmarshal(msg.Params[i], &p[i])
+	}
+	jobID, extranonce2Hex, ntimeHex, nonceHex, versionHex := p[1], p[2], p[3], p[4], p[5]
+	now := time.Now()
+
+	r.mu.Lock()
+	j := r.findJob(jobID)
+	extranonce1 := append(append([]byte(nil), r.extranonce1...), s.prefix)
+	extranonce2Size := r.extranonce2Size - prefixSize
+	mask := s.mask
+	r.mu.Unlock()
+
+	if j == nil {
+		r.refused(s)
+		return s.reply(msg.ID, nil, stratumError(21, "Job not found"))
+	}
+	extranonce2, err := hex.DecodeString(extranonce2Hex)
+	ntime, ntimeErr := parseHex32(ntimeHex)
+	nonce, nonceErr := parseHex32(nonceHex)
+	version, versionErr := j.version, error(nil)
+	if versionHex != "" {
+		var bits uint32
+		bits, versionErr = parseHex32(versionHex)
+		version = j.version&^mask | bits&mask
+	}
+	if err != nil || len(extranonce2) != extranonce2Size || ntimeErr != nil || nonceErr != nil || versionErr != nil {
+		r.refused(s)
+		return s.reply(msg.ID, nil, stratumError(20, "Malformed share"))
+	}
+
+	hash := j.hash(extranonce1, extranonce2, version, ntime, nonce)
+	share := &storage.Share{
+		MinerIP:    s.ip,
+		Timestamp:  now,
+		Difficulty: hashDifficulty(hash),
+		JobID:      jobID,
+		Nonce:      nonce,
+		Version:    version,
+	}
+	share.SetNetworkDifficulty(networkDifficulty(j.nbits))
+	block := solvesBlock(hash, j.nbits)
+
+	switch {
+	case share.Difficulty < j.minerDiff:
+		share.Rejected = true
+		share.RejectReason = "Low difficulty share"
+		r.record(share, false, false, false)
+		return s.reply(msg.ID, nil, stratumError(23, "Low difficulty share"))
+	case share.Difficulty < j.poolDiff && !block:
+		// Of no use to the pool, only recorded
+		r.record(share, false, false, false)
+		return s.reply(msg.ID, true, nil)
+	}
+
+	params := []string{r.cfg.User, jobID, hex.EncodeToString([]byte{s.prefix}) + extranonce2Hex, ntimeHex, nonceHex}
+	if versionHex != "" {
+		params = append(params, versionHex)
+	}
+	return r.call("mining.submit", params, func(result, errRaw json.RawMessage) error {
+		switch {
+		case isTrue(result):
+			r.record(share, true, true, block)
+		case isNull(result) && isNull(errRaw):
+			// Lost with the pool's connection, it may have counted
+			r.record(share, true, false, block)
+			return nil
+		default:
+			share.Rejected = true
+			share.RejectReason = errorMessage(errRaw)
+			r.record(share, true, false, false)
+		}
+		s.reply(msg.ID, result, errRaw)
+		return nil
+	})
+}
+
+// refused counts a share the proxy couldn't hash
+func (r *relay) refused(s *session) {
+	r.mu.Lock()
+	r.stats[s.ip].Rejected++
+	r.mu.Unlock()
+}
+
+// record counts a hashed share and reports it
+func (r *relay) record(share *storage.Share, submitted, accepted, block bool) {
+	r.mu.Lock()
+	st := r.stats[share.MinerIP]
+	st.Shares++
+	if submitted {
+		st.Submitted++
+	}
+	if accepted {
+		st.Accepted++
+	}
+	if share.Rejected {
+		st.Rejected++
+	}
+	if block {
+		st.Blocks++
+	}
+	at := share.Timestamp
+	st.LastShare = &at
+	r.mu.Unlock()
+
+	if r.onShare != nil {
+		r.onShare(share, block)
+	}
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func isTrue(raw json.RawMessage) bool {
+	return string(raw) == "true"
+}
+
+// errorMessage returns the message of a stratum error: [code, message,
+// traceback], an object with a message, or a bare string
+func errorMessage(raw json.RawMessage) string {
+	var list []interface{}
+	if json.Unmarshal(raw, &list) == nil && len(list) > 1 {
+		if message, ok := list[1].(string); ok {
+			return message
+		}
+	}
+	var obj struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &obj) == nil && obj.Message != "" {
+		return obj.Message
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil && text != "" {
+		return text
+	}
+	if isNull(raw) {
+		return "Rejected"
+	}
+	return string(raw)
+}
@@ -0,0 +1,255 @@
+package stratumproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/poolcheck"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// staleNonce is the nonce the fake pool rejects
+const staleNonce = "002dd190"
+
+// fakePool serves one stratum connection: it allows version rolling, gives
+// the connection extranonce1 f000000a with 4 bytes of extranonce2, sends
+// testNotify at difficulty 1e-6 once authorized and accepts every share but
+// staleNonce's. Submissions are sent on submits; closing drop disconnects.
+func fakePool(t *testing.T) (addr string, submits chan []string, drop chan struct{}) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	submits = make(chan []string, 10)
+	drop = make(chan struct{})
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go func() {
+			<-drop
+			conn.Close()
+		}()
+
+		send := func(v interface{}) {
+			data, _ := json.Marshal(v)
+			conn.Write(append(data, '\n'))
+		}
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var req struct {
+				ID     int64    `json:"id"`
+				Method string   `json:"method"`
+				Params []string `json:"params"`
+			}
+			json.Unmarshal(line, &req)
+			switch req.Method {
+			case "mining.configure":
+				send(map[string]interface{}{"id": req.ID, "result": map[string]interface{}{"version-rolling": true, "version-rolling.mask": "1fffe000"}, "error": nil})
+			case "mining.subscribe":
+				send(map[string]interface{}{"id": req.ID, "result": []interface{}{[][]string{{"mining.notify", "1"}}, "f000000a", 4}, "error": nil})
+			case "mining.authorize":
+				send(map[string]interface{}{"id": req.ID, "result": true, "error": nil})
+				send(map[string]interface{}{"id": nil, "method": "mining.set_difficulty", "params": []float64{1e-6}})
+				send(map[string]interface{}{"id": nil, "method": "mining.notify", "params": testNotify})
+			case "mining.submit":
+				submits <- req.Params
+				if req.Params[4] == staleNonce {
+					send(map[string]interface{}{"id": req.ID, "result": nil, "error": []interface{}{21, "Stale", nil}})
+				} else {
+					send(map[string]interface{}{"id": req.ID, "result": true, "error": nil})
+				}
+			}
+		}
+	}()
+	return ln.Addr().String(), submits, drop
+}
+
+// testMiner talks stratum to the proxy
+type testMiner struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+// call sends a request and returns the answer, skipping notifications
+func (m *testMiner) call(method string, params ...interface{}) message {
+	m.t.Helper()
+	m.id++
+	data, _ := json.Marshal(request{ID: m.id, Method: method, Params: params})
+	if _, err := m.conn.Write(append(data, '\n')); err != nil {
+		m.t.Fatalf("%s: %v", method, err)
+	}
+	for {
+		msg := m.read()
+		if msg.Method == "" && string(msg.ID) == strconv.Itoa(m.id) {
+			return msg
+		}
+	}
+}
+
+// read returns the next message from the proxy
+func (m *testMiner) read() message {
+	m.t.Helper()
+	m.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := m.reader.ReadBytes('\n')
+	if err != nil {
+		m.t.Fatalf("no message from the proxy: %v", err)
+	}
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		m.t.Fatalf("invalid message %s: %v", line, err)
+	}
+	return msg
+}
+
+func TestRelay(t *testing.T) {
+	poolAddr, submits, drop := fakePool(t)
+
+	var mu sync.Mutex
+	var shares []*storage.Share
+	var blocks []bool
+	cfg := config.ProxyPoolConfig{Port: 3333, URL: "stratum+tcp://pool.example.com:3333", User: "bc1qtest.minerhq", Password: "x", Difficulty: 1e-7}
+	r := newRelay(cfg, poolcheck.Pool{Host: "pool.example.com", Port: 3333}, func(share *storage.Share, block bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		shares = append(shares, share)
+		blocks = append(blocks, block)
+	})
+	r.dial = func(p poolcheck.Pool) (net.Conn, error) {
+		return net.Dial("tcp", poolAddr)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	r.start(ln)
+	defer r.close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !r.snapshot().Connected {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the relay to connect to the pool")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to the proxy: %v", err)
+	}
+	defer conn.Close()
+	m := &testMiner{t: t, conn: conn, reader: bufio.NewReader(conn)}
+
+	if msg := m.call("mining.configure", []string{"version-rolling"}, map[string]string{"version-rolling.mask": "ffffffff"}); string(msg.Result) != `{"version-rolling":true,"version-rolling.mask":"1fffe000"}` {
+		t.Errorf("unexpected configure answer %s", msg.Result)
+	}
+	if msg := m.call("mining.subscribe", "bitaxe/BM1370/v2.5.0"); !strings.HasSuffix(string(msg.Result), `,"f000000a00",3]`) {
+		t.Errorf("expected the pool's extranonce1 with the miner's prefix, got %s", msg.Result)
+	}
+	if msg := m.call("mining.authorize", "bitaxe-1", "x"); string(msg.Result) != "true" {
+		t.Errorf("expected the miner to be authorized, got %s", msg.Result)
+	}
+	if msg := m.read(); msg.Method != "mining.set_difficulty" || string(msg.Params[0]) != "1e-7" {
+		t.Errorf("expected the miners' difficulty, got %s %s", msg.Method, msg.Params)
+	}
+	if msg := m.read(); msg.Method != "mining.notify" || string(msg.Params[0]) != `"job1"` {
+		t.Errorf("expected the pool's job, got %s %s", msg.Method, msg.Params)
+	}
+	if !(&Proxy{relays: []*relay{r}}).Proxied("127.0.0.1") {
+		t.Error("expected the miner to count as proxied")
+	}
+
+	tests := []struct {
+		name    string
+		params  []interface{}
+		answer  string // Result, or error message
+		forward bool
+	}{
+		{"below the miners' difficulty", []interface{}{"bitaxe-1", "job1", testExtranonce2, "66f0a3c0", "00000000"}, "Low difficulty share", false},
+		{"below the pool's difficulty", []interface{}{"bitaxe-1", "job1", testExtranonce2, "66f0a3c0", "000f4251"}, "true", false},
+		{"rolled version", []interface{}{"bitaxe-1", "job1", testExtranonce2, "66f0a3c0", "001e9137", "00002000"}, "true", true},
+		{"stale", []interface{}{"bitaxe-1", "job1", testExtranonce2, "66f0a3c0", staleNonce}, "Stale", true},
+		{"block", []interface{}{"bitaxe-1", "job1", testExtranonce2, "66f0a3c0", "004111dd"}, "true", true},
+		{"unknown job", []interface{}{"bitaxe-1", "job0", testExtranonce2, "66f0a3c0", "004111dd"}, "Job not found", false},
+	}
+	for _, tt := range tests {
+		msg := m.call("mining.submit", tt.params...)
+		if got := string(msg.Result); tt.answer == "true" && got != "true" {
+			t.Errorf("%s: expected the share to be accepted, got %s %s", tt.name, msg.Result, msg.Error)
+		} else if tt.answer != "true" && errorMessage(msg.Error) != tt.answer {
+			t.Errorf("%s: expected %q, got %s %s", tt.name, tt.answer, msg.Result, msg.Error)
+		}
+		if !tt.forward {
+			continue
+		}
+		select {
+		case params := <-submits:
+			want := []string{"bc1qtest.minerhq", "job1", "00" + testExtranonce2, "66f0a3c0", tt.params[4].(string)}
+			if len(tt.params) == 6 {
+				want = append(want, "00002000")
+			}
+			if strings.Join(params, ",") != strings.Join(want, ",") {
+				t.Errorf("%s: expected %v submitted, got %v", tt.name, want, params)
+			}
+		default:
+			t.Errorf("%s: expected the share to be submitted to the pool", tt.name)
+		}
+	}
+	if len(submits) > 0 {
+		t.Errorf("expected only the shares at the pool's difficulty to be submitted, got %v", <-submits)
+	}
+
+	mu.Lock()
+	if len(shares) != 5 {
+		t.Fatalf("expected 5 recorded shares, got %d", len(shares))
+	}
+	if s := shares[0]; !s.Rejected || s.RejectReason != "Low difficulty share" {
+		t.Errorf("expected a rejected low difficulty share, got %+v", s)
+	}
+	if s := shares[2]; s.Rejected || s.Version != 0x20002000 || !approx(s.Difficulty, 1.0424371389100442e-06) || s.MinerIP != "127.0.0.1" {
+		t.Errorf("unexpected share %+v", s)
+	}
+	if s := shares[3]; !s.Rejected || s.RejectReason != "Stale" {
+		t.Errorf("expected a stale share, got %+v", s)
+	}
+	if s := shares[4]; !blocks[4] || s.Rejected || s.NetworkDifficulty != 1.52587890625e-05 {
+		t.Errorf("expected a block, got %+v (block %v)", s, blocks[4])
+	}
+	mu.Unlock()
+
+	st := r.snapshot()
+	if st.PoolDifficulty != 1e-6 || st.MinerDifficulty != 1e-7 || len(st.Miners) != 1 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	if ms := st.Miners[0]; ms.Worker != "bitaxe-1" || !ms.Connected || ms.Shares != 5 || ms.Submitted != 3 || ms.Accepted != 2 || ms.Rejected != 3 || ms.Blocks != 1 {
+		t.Errorf("unexpected miner status %+v", ms)
+	}
+
+	// The miner's extranonce goes with the pool's connection
+	close(drop)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := m.reader.ReadBytes('\n'); err == nil {
+		t.Error("expected the miner to be disconnected with the pool")
+	}
+	if st := r.snapshot(); st.Connected || st.LastError == "" {
+		t.Errorf("expected the pool to be disconnected, got %+v", st)
+	}
+}
@@ -0,0 +1,165 @@
+package stratumproxy
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// diff1 is the target of a difficulty 1 share
+var diff1 = new(big.Int).Lsh(big.NewInt(0xffff), 208)
+
+// job is a pool's mining.notify, kept to hash the shares submitted for it
+type job struct {
+	id       string
+	prevHash []byte // As in the header: the notify's 4-byte words byte-swapped
+	coinb1   []byte
+	coinb2   []byte
+	branches [][]byte
+	version  uint32
+	nbits    uint32
+
+	poolDiff  float64 // The pool's difficulty when the job was sent
+	minerDiff float64 // The miners' difficulty when the job was sent
+}
+
+// parseJob reads the params of a mining.notify: job ID, previous block hash,
+// the coinbase around the extranonces, the merkle branches, version, nbits
+// and ntime
+func parseJob(params []json.RawMessage) (*job, error) {
+	if len(params) < 8 {
+		return nil, fmt.Errorf("mining.notify: %d params, expected 9", len(params))
+	}
+	var id, prevHash, coinb1, coinb2, version, nbits string
+	var branches []string
+	for i, v := range []interface{}{&id, &prevHash, &coinb1, &coinb2, &branches, &version, &nbits} {
+		if err := json.Unmarshal(params[i], v); err != nil {
+			return nil, fmt.Errorf("mining.notify: param %d: %w", i, err)
+		}
+	}
+
+	j := &job{id: id}
+	prev, err := hex.DecodeString(prevHash)
+	if err != nil || len(prev) != 32 {
+		return nil, fmt.Errorf("mining.notify: invalid previous block hash %q", prevHash)
+	}
+	j.prevHash = swapWords(prev)
+	if j.coinb1, err = hex.DecodeString(coinb1); err != nil {
+		return nil, fmt.Errorf("mining.notify: coinb1: %w", err)
+	}
+	if j.coinb2, err = hex.DecodeString(coinb2); err != nil {
+		return nil, fmt.Errorf("mining.notify: coinb2: %w", err)
+	}
+	for _, b := range branches {
+		branch, err := hex.DecodeString(b)
+		if err != nil || len(branch) != 32 {
+			return nil, fmt.Errorf("mining.notify: invalid merkle branch %q", b)
+		}
+		j.branches = append(j.branches, branch)
+	}
+	if j.version, err = parseHex32(version); err != nil {
+		return nil, fmt.Errorf("mining.notify: version: %w", err)
+	}
+	if j.nbits, err = parseHex32(nbits); err != nil {
+		return nil, fmt.Errorf("mining.notify: nbits: %w", err)
+	}
+	return j, nil
+}
+
+// hash returns the block header hash of a share of the job
+func (j *job) hash(extranonce1, extranonce2 []byte, version, ntime, nonce uint32) []byte {
+	coinbase := make([]byte, 0, len(j.coinb1)+len(extranonce1)+len(extranonce2)+len(j.coinb2))
+	coinbase = append(coinbase, j.coinb1...)
+	coinbase = append(coinbase, extranonce1...)
+	coinbase = append(coinbase, extranonce2...)
+	coinbase = append(coinbase, j.coinb2...)
+
+	root := sha256d(coinbase)
+	for _, branch := range j.branches {
+		root = sha256d(append(root, branch...))
+	}
+	return headerHash(version, j.prevHash, root, ntime, j.nbits, nonce)
+}
+
+// headerHash returns the hash of an 80-byte block header, in its internal
+// byte order
+func headerHash(version uint32, prevHash, merkleRoot []byte, ntime, nbits, nonce uint32) []byte {
+	header := make([]byte, 80)
+	binary.LittleEndian.PutUint32(header[0:], version)
+	copy(header[4:36], prevHash)
+	copy(header[36:68], merkleRoot)
+	binary.LittleEndian.PutUint32(header[68:], ntime)
+	binary.LittleEndian.PutUint32(header[72:], nbits)
+	binary.LittleEndian.PutUint32(header[76:], nonce)
+	return sha256d(header)
+}
+
+// hashDifficulty returns the difficulty a header hash meets
+func hashDifficulty(hash []byte) float64 {
+	n := hashInt(hash)
+	if n.Sign() == 0 {
+		return math.Inf(1)
+	}
+	d, _ := new(big.Float).Quo(new(big.Float).SetInt(diff1), new(big.Float).SetInt(n)).Float64()
+	return d
+}
+
+// compactTarget returns the target encoded in a header's nbits
+func compactTarget(nbits uint32) *big.Int {
+	exponent := uint(nbits >> 24)
+	target := big.NewInt(int64(nbits & 0x007fffff))
+	if exponent <= 3 {
+		return target.Rsh(target, 8*(3-exponent))
+	}
+	return target.Lsh(target, 8*(exponent-3))
+}
+
+// networkDifficulty returns the difficulty of the target encoded in nbits
+func networkDifficulty(nbits uint32) float64 {
+	target := compactTarget(nbits)
+	if target.Sign() == 0 {
+		return 0
+	}
+	d, _ := new(big.Float).Quo(new(big.Float).SetInt(diff1), new(big.Float).SetInt(target)).Float64()
+	return d
+}
+
+// solvesBlock reports whether a header hash meets the network target
+func solvesBlock(hash []byte, nbits uint32) bool {
+	return hashInt(hash).Cmp(compactTarget(nbits)) <= 0
+}
+
+// hashInt reads a hash in internal byte order as a number
+func hashInt(hash []byte) *big.Int {
+	reversed := make([]byte, len(hash))
+	for i, b := range hash {
+		reversed[len(hash)-1-i] = b
+	}
+	return new(big.Int).SetBytes(reversed)
+}
+
+func sha256d(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// swapWords byte-swaps every 4-byte word
+func swapWords(b []byte) []byte {
+	swapped := make([]byte, len(b))
+	for i := 0; i+4 <= len(b); i += 4 {
+		binary.BigEndian.PutUint32(swapped[i:], binary.LittleEndian.Uint32(b[i:]))
+	}
+	return swapped
+}
+
+// parseHex32 reads a 32-bit field as stratum sends it, big-endian hex
+func parseHex32(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 16, 32)
+	return uint32(n), err
+}
@@ -0,0 +1,108 @@
+package stratumproxy
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// Bitcoin block 125552, with the previous block hash as stratum sends it
+const (
+	blockPrevHash   = "ab02cd818b9e567ee21793cddef299feb29ad444a41b85b8000008a300000000"
+	blockMerkleRoot = "e320b6c2fffc8d750423db8b1eb942ae710e951ed797f7affc8892b0f1fc122b"
+	blockHash       = "00000000000000001e8d6829a8a21adc5d38d0a473b144b6765798e61f98bd1d"
+)
+
+// A job with an easy network target, and shares of it at the difficulties
+// the tests need
+var (
+	testNotify = []interface{}{
+		"job1",
+		blockPrevHash,
+		"01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff1e03a0e50c",
+		"ffffffff0100f2052a010000001976a914000000000000000000000000000000000000000088ac00000000",
+		[]string{"f38c764c8aa00b6578f4254a4dc6d9b50f88fa926e270ea7859bd1b707cd8662"},
+		"20000000",
+		"1f00ffff",
+		"66f0a3c0",
+		true,
+	}
+	testExtranonce1 = "f000000a00"
+	testExtranonce2 = "000001"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Abs(b)
+}
+
+func TestHeaderHash(t *testing.T) {
+	prev, _ := hex.DecodeString(blockPrevHash)
+	root, _ := hex.DecodeString(blockMerkleRoot)
+	hash := headerHash(1, swapWords(prev), root, 1305998791, 0x1a44b9f2, 2504433986)
+
+	reversed := make([]byte, len(hash))
+	for i, b := range hash {
+		reversed[len(hash)-1-i] = b
+	}
+	if got := hex.EncodeToString(reversed); got != blockHash {
+		t.Fatalf("expected hash %s, got %s", blockHash, got)
+	}
+	if d := hashDifficulty(hash); !approx(d, 35987218905.508736) {
+		t.Errorf("unexpected hash difficulty %f", d)
+	}
+	if d := networkDifficulty(0x1a44b9f2); !approx(d, 244112.48777433642) {
+		t.Errorf("unexpected network difficulty %f", d)
+	}
+	if !solvesBlock(hash, 0x1a44b9f2) {
+		t.Error("expected the block's hash to meet its target")
+	}
+	if solvesBlock(hash, 0x1800ffff) {
+		t.Error("expected the block's hash to miss a harder target")
+	}
+}
+
+func TestJobHash(t *testing.T) {
+	data, _ := json.Marshal(testNotify)
+	var params []json.RawMessage
+	json.Unmarshal(data, &params)
+	j, err := parseJob(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.id != "job1" || j.version != 0x20000000 || j.nbits != 0x1f00ffff || len(j.branches) != 1 {
+		t.Errorf("unexpected job %+v", j)
+	}
+	if d := networkDifficulty(j.nbits); d != 1.52587890625e-05 {
+		t.Errorf("unexpected network difficulty %g", d)
+	}
+
+	extranonce1, _ := hex.DecodeString(testExtranonce1)
+	extranonce2, _ := hex.DecodeString(testExtranonce2)
+	tests := []struct {
+		version, nonce uint32
+		difficulty     float64
+		block          bool
+	}{
+		{0x20000000, 0x000f4251, 3.683241157944261e-07, false},
+		{0x20002000, 0x001e9137, 1.0424371389100442e-06, false},
+		{0x20000000, 0x004111dd, 0.00019407632164020452, true},
+	}
+	for _, tt := range tests {
+		hash := j.hash(extranonce1, extranonce2, tt.version, 0x66f0a3c0, tt.nonce)
+		if d := hashDifficulty(hash); !approx(d, tt.difficulty) {
+			t.Errorf("nonce %08x: expected difficulty %g, got %g", tt.nonce, tt.difficulty, d)
+		}
+		if solvesBlock(hash, j.nbits) != tt.block {
+			t.Errorf("nonce %08x: expected block %v", tt.nonce, tt.block)
+		}
+	}
+
+	params[1] = json.RawMessage(`"abcd"`)
+	if _, err := parseJob(params); err == nil {
+		t.Error("expected a short previous block hash to fail")
+	}
+	if _, err := parseJob(params[:5]); err == nil {
+		t.Error("expected missing params to fail")
+	}
+}
@@ -0,0 +1,203 @@
+// Package tsdb forwards miner snapshots to a time series database, as
+// InfluxDB line protocol or Prometheus remote-write
+package tsdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// Write formats
+const (
+	FormatInflux      = "influx"
+	FormatRemoteWrite = "remote_write"
+)
+
+const (
+	// defaultFlushInterval is how often snapshots are written unless
+	// configured otherwise
+	defaultFlushInterval = 10 * time.Second
+
+	// maxPending bounds the snapshots kept while the database is unreachable;
+	// beyond it the oldest are dropped
+	maxPending = 10000
+)
+
+// Exporter batches snapshots and writes them to the database every flush
+// interval. Failed writes are retried on the next flush, so adding a
+// snapshot never blocks the caller.
+type Exporter struct {
+	cfg      config.TSDBConfig
+	client   *http.Client
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []*storage.MinerSnapshot
+	dropped int // Snapshots dropped since the last flush
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewExporter creates an exporter for the configured database
+func NewExporter(cfg config.TSDBConfig) *Exporter {
+	interval := time.Duration(cfg.FlushSecs) * time.Second
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	return &Exporter{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start writes batches in the background
+func (e *Exporter) Start() {
+	go e.run()
+}
+
+// Stop writes the remaining snapshots and stops the exporter
+func (e *Exporter) Stop() {
+	close(e.stop)
+	<-e.done
+}
+
+// Add queues a snapshot for the next write
+func (e *Exporter) Add(snap *storage.MinerSnapshot) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, snap)
+	e.trimLocked()
+}
+
+// trimLocked drops the oldest snapshots beyond maxPending. The caller must
+// hold mu.
+func (e *Exporter) trimLocked() {
+	if len(e.pending) > maxPending {
+		e.dropped += len(e.pending) - maxPending
+		e.pending = e.pending[len(e.pending)-maxPending:]
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			e.flush()
+			return
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+// flush writes the queued snapshots in one request. A failed batch is kept
+// and retried on the next flush.
+func (e *Exporter) flush() {
+	e.mu.Lock()
+	batch, dropped := e.pending, e.dropped
+	e.pending, e.dropped = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("TSDB: %s unreachable for too long, dropped %d snapshots", e.cfg.Format, dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if err := e.write(batch); err != nil {
+		log.Printf("TSDB: writing %d snapshots failed: %v", len(batch), err)
+		e.mu.Lock()
+		e.pending = append(batch, e.pending...)
+		e.trimLocked()
+		e.mu.Unlock()
+	}
+}
+
+// write sends a batch in the configured format
+func (e *Exporter) write(batch []*storage.MinerSnapshot) error {
+	var body []byte
+	var contentType, authScheme string
+	switch e.cfg.Format {
+	case FormatRemoteWrite:
+		body = snappyEncode(encodeWriteRequest(batch))
+		contentType, authScheme = "application/x-protobuf", "Bearer"
+	default:
+		body = encodeLineProtocol(batch)
+		contentType, authScheme = "text/plain; charset=utf-8", "Token"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.cfg.Format == FormatRemoteWrite {
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	}
+	if e.cfg.Token != "" {
+		req.Header.Set("Authorization", authScheme+" "+e.cfg.Token)
+	} else if e.cfg.Username != "" {
+		req.SetBasicAuth(e.cfg.Username, e.cfg.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// metric is one exported snapshot value
+type metric struct {
+	name  string
+	value float64
+}
+
+// metrics returns the values of a snapshot that are exported, named after
+// their unit
+func metrics(s *storage.MinerSnapshot) []metric {
+	connected := 0.0
+	if s.PoolConnected {
+		connected = 1
+	}
+	return []metric{
+		{"hashrate_ghs", s.HashRate},
+		{"hashrate_1h_ghs", s.HashRate1h},
+		{"temperature_celsius", s.Temperature},
+		{"vr_temperature_celsius", s.VRTemp},
+		{"power_watts", s.Power},
+		{"voltage", s.Voltage},
+		{"fan_rpm", float64(s.FanRPM)},
+		{"fan_percent", float64(s.FanPercent)},
+		{"shares_accepted", float64(s.SharesAccept)},
+		{"shares_rejected", float64(s.SharesReject)},
+		{"best_difficulty", s.BestDiff},
+		{"session_best_difficulty", s.BestDiffSess},
+		{"pool_difficulty", s.PoolDiff},
+		{"pool_connected", connected},
+		{"uptime_seconds", float64(s.UptimeSecs)},
+		{"wifi_rssi_dbm", float64(s.WifiRSSI)},
+	}
+}
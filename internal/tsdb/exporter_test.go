@@ -0,0 +1,177 @@
+package tsdb
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+var testSnapshot = &storage.MinerSnapshot{
+	MinerIP:       "192.168.1.50",
+	Hostname:      "shed miner,1",
+	DeviceModel:   "NerdQAxe++",
+	Timestamp:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	HashRate:      4800.5,
+	Temperature:   58,
+	PoolConnected: true,
+}
+
+func TestEncodeLineProtocol(t *testing.T) {
+	line := strings.TrimSuffix(string(encodeLineProtocol([]*storage.MinerSnapshot{testSnapshot})), "\n")
+	if strings.Count(line, "\n") != 0 {
+		t.Fatalf("expected one line, got %q", line)
+	}
+	if !strings.HasPrefix(line, `miner,ip=192.168.1.50,hostname=shed\ miner\,1,model=NerdQAxe++ hashrate_ghs=4800.5,`) {
+		t.Errorf("unexpected measurement, tags or first field: %q", line)
+	}
+	for _, want := range []string{",temperature_celsius=58,", ",pool_connected=1,", " 1772366400000000000"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected %q in %q", want, line)
+		}
+	}
+}
+
+// readField reads one protobuf field, returning its number and, for
+// length-delimited fields, its bytes, or for fixed64 fields, its 8 bytes
+func readField(t *testing.T, b []byte) (field int, v, rest []byte) {
+	t.Helper()
+	tag, n := binary.Uvarint(b)
+	b = b[n:]
+	switch tag & 7 {
+	case 2:
+		length, n := binary.Uvarint(b)
+		return int(tag >> 3), b[n : n+int(length)], b[n+int(length):]
+	case 1:
+		return int(tag >> 3), b[:8], b[8:]
+	case 0:
+		_, n := binary.Uvarint(b)
+		return int(tag >> 3), b[:n], b[n:]
+	}
+	t.Fatalf("unexpected wire type %d", tag&7)
+	return 0, nil, nil
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	req := encodeWriteRequest([]*storage.MinerSnapshot{testSnapshot})
+
+	var series [][]byte
+	for len(req) > 0 {
+		field, v, rest := readField(t, req)
+		if field != 1 {
+			t.Fatalf("unexpected WriteRequest field %d", field)
+		}
+		series, req = append(series, v), rest
+	}
+	if len(series) != len(metrics(testSnapshot)) {
+		t.Fatalf("expected one series per metric, got %d", len(series))
+	}
+
+	// The first series is the hashrate
+	var labels []string
+	var value float64
+	var ts uint64
+	for b := series[0]; len(b) > 0; {
+		field, v, rest := readField(t, b)
+		b = rest
+		switch field {
+		case 1:
+			_, name, more := readField(t, v)
+			_, val, _ := readField(t, more)
+			labels = append(labels, string(name)+"="+string(val))
+		case 2:
+			_, val, more := readField(t, v)
+			value = math.Float64frombits(binary.LittleEndian.Uint64(val))
+			_, tsBytes, _ := readField(t, more)
+			ts, _ = binary.Uvarint(tsBytes)
+		}
+	}
+	want := "__name__=minerhq_hashrate_ghs hostname=shed miner,1 ip=192.168.1.50 model=NerdQAxe++"
+	if got := strings.Join(labels, " "); got != want {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+	if value != 4800.5 || ts != uint64(testSnapshot.Timestamp.UnixMilli()) {
+		t.Errorf("sample = %v at %d, want 4800.5 at %d", value, ts, testSnapshot.Timestamp.UnixMilli())
+	}
+}
+
+func TestSnappyEncode(t *testing.T) {
+	for _, size := range []int{0, 1, 60, 61, 256, 257, 70000} {
+		src := make([]byte, size)
+		for i := range src {
+			src[i] = byte(i)
+		}
+		enc := snappyEncode(src)
+
+		// Decode the literals back
+		length, n := binary.Uvarint(enc)
+		if int(length) != size {
+			t.Fatalf("size %d: header says %d", size, length)
+		}
+		var dec []byte
+		for b := enc[n:]; len(b) > 0; {
+			tag := b[0]
+			if tag&3 != 0 {
+				t.Fatalf("size %d: expected only literals", size)
+			}
+			l, b2 := int(tag>>2), b[1:]
+			switch l {
+			case 60:
+				l, b2 = int(b2[0]), b2[1:]
+			case 61:
+				l, b2 = int(b2[0])|int(b2[1])<<8, b2[2:]
+			}
+			dec = append(dec, b2[:l+1]...)
+			b = b2[l+1:]
+		}
+		if string(dec) != string(src) {
+			t.Errorf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestExporter(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	var auth string
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	e := NewExporter(config.TSDBConfig{Format: FormatInflux, URL: srv.URL, Token: "secret", FlushSecs: 3600})
+	e.Start()
+
+	e.Add(testSnapshot)
+	e.flush()
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	e.Add(testSnapshot)
+	e.Stop()
+
+	if len(bodies) != 1 || strings.Count(bodies[0], "\n") != 2 {
+		t.Fatalf("expected the failed snapshot retried with the next one in one write, got %q", bodies)
+	}
+	if auth != "Token secret" {
+		t.Errorf("Authorization = %q, want the InfluxDB token", auth)
+	}
+}
@@ -0,0 +1,44 @@
+package tsdb
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// tagEscaper escapes tag keys and values in line protocol
+var tagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// encodeLineProtocol encodes snapshots as InfluxDB line protocol, one
+// "miner" point per snapshot tagged with the miner's IP, hostname and model,
+// with nanosecond timestamps
+func encodeLineProtocol(snaps []*storage.MinerSnapshot) []byte {
+	var b strings.Builder
+	for _, s := range snaps {
+		b.WriteString("miner,ip=")
+		b.WriteString(tagEscaper.Replace(s.MinerIP))
+		if s.Hostname != "" {
+			b.WriteString(",hostname=")
+			b.WriteString(tagEscaper.Replace(s.Hostname))
+		}
+		if s.DeviceModel != "" {
+			b.WriteString(",model=")
+			b.WriteString(tagEscaper.Replace(s.DeviceModel))
+		}
+		for i, m := range metrics(s) {
+			if i == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(m.name)
+			b.WriteByte('=')
+			b.WriteString(strconv.FormatFloat(m.value, 'f', -1, 64))
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(s.Timestamp.UnixNano(), 10))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
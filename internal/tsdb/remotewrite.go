@@ -0,0 +1,89 @@
+package tsdb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+// metricPrefix starts the name of every remote-write series
+const metricPrefix = "minerhq_"
+
+// encodeWriteRequest encodes snapshots as a Prometheus remote-write
+// WriteRequest protobuf: one series per exported value and miner, labeled
+// with the miner's IP, hostname and model, at millisecond timestamps.
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(snaps []*storage.MinerSnapshot) []byte {
+	var req []byte
+	for _, s := range snaps {
+		// Receivers require labels sorted by name; __name__ sorts first
+		var labels []byte
+		if s.Hostname != "" {
+			labels = appendLabel(labels, "hostname", s.Hostname)
+		}
+		labels = appendLabel(labels, "ip", s.MinerIP)
+		if s.DeviceModel != "" {
+			labels = appendLabel(labels, "model", s.DeviceModel)
+		}
+
+		ts := uint64(s.Timestamp.UnixMilli())
+		for _, m := range metrics(s) {
+			series := appendLabel(nil, "__name__", metricPrefix+m.name)
+			series = append(series, labels...)
+
+			var sample []byte
+			sample = binary.AppendUvarint(sample, 1<<3|1) // value, fixed64
+			sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(m.value))
+			sample = binary.AppendUvarint(sample, 2<<3|0) // timestamp, varint
+			sample = binary.AppendUvarint(sample, ts)
+			series = appendBytes(series, 2, sample)
+
+			req = appendBytes(req, 1, series)
+		}
+	}
+	return req
+}
+
+// appendLabel appends a Label message as field 1 of a TimeSeries
+func appendLabel(b []byte, name, value string) []byte {
+	var label []byte
+	label = appendBytes(label, 1, []byte(name))
+	label = appendBytes(label, 2, []byte(value))
+	return appendBytes(b, 1, label)
+}
+
+// appendBytes appends a length-delimited protobuf field
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// snappyEncode wraps src in the snappy block format that remote-write
+// requires, as uncompressed literals. Snapshots are small and sent every few
+// seconds, so compressing them isn't worth a dependency.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > 1<<16 {
+			n = 1 << 16
+		}
+		switch {
+		case n <= 60:
+			dst = append(dst, byte(n-1)<<2)
+		case n <= 1<<8:
+			dst = append(dst, 60<<2, byte(n-1))
+		default:
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,98 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Display holds the user's display preferences for values formatted on the
+// server side (alert messages, API summaries, CSV exports). Stored values stay
+// in base units; only their presentation changes.
+type Display struct {
+	TemperatureUnit string // "C" (default) or "F"
+	TimeFormat      string // "24h" (default) or "12h"
+	HashrateUnit    string // "auto" (default) to scale per value, or a fixed unit such as "TH/s"
+}
+
+// hashUnitNames maps hashrate multipliers to their display names
+var hashUnitNames = map[float64]string{
+	HashPerSecond: "H/s",
+	KiloHash:      "KH/s",
+	MegaHash:      "MH/s",
+	GigaHash:      "GH/s",
+	TeraHash:      "TH/s",
+	PetaHash:      "PH/s",
+}
+
+// CelsiusToFahrenheit converts a temperature from °C to °F
+func CelsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// ValidHashrateUnit reports whether u is "auto" (or empty) or a unit ToGHs understands
+func ValidHashrateUnit(u string) bool {
+	if u == "" || strings.EqualFold(u, "auto") {
+		return true
+	}
+	_, err := ToGHs(1, u)
+	return err == nil
+}
+
+// fahrenheit reports whether temperatures are shown in °F
+func (d Display) fahrenheit() bool {
+	return strings.EqualFold(d.TemperatureUnit, "F")
+}
+
+// Temperature converts a °C value to the preferred unit
+func (d Display) Temperature(celsius float64) float64 {
+	if d.fahrenheit() {
+		return CelsiusToFahrenheit(celsius)
+	}
+	return celsius
+}
+
+// TemperatureSymbol returns "°C" or "°F"
+func (d Display) TemperatureSymbol() string {
+	if d.fahrenheit() {
+		return "°F"
+	}
+	return "°C"
+}
+
+// FormatTemperature formats a °C value in the preferred unit, e.g. "72.5°F"
+func (d Display) FormatTemperature(celsius float64) string {
+	return fmt.Sprintf("%.1f%s", d.Temperature(celsius), d.TemperatureSymbol())
+}
+
+// Hashrate converts a GH/s value to the preferred fixed unit and returns it
+// with the unit's name. With automatic scaling it returns GH/s unchanged.
+func (d Display) Hashrate(ghs float64) (float64, string) {
+	if d.HashrateUnit == "" || strings.EqualFold(d.HashrateUnit, "auto") {
+		return ghs, "GH/s"
+	}
+	mult, err := ToGHs(1, d.HashrateUnit)
+	if err != nil {
+		return ghs, "GH/s"
+	}
+	return ghs / mult, hashUnitNames[mult]
+}
+
+// FormatHashrate formats a GH/s value in the preferred unit, scaling
+// automatically unless a fixed unit is set
+func (d Display) FormatHashrate(ghs float64) string {
+	if d.HashrateUnit == "" || strings.EqualFold(d.HashrateUnit, "auto") {
+		return FormatHashrate(ghs)
+	}
+	v, unit := d.Hashrate(ghs)
+	return fmt.Sprintf("%.2f %s", v, unit)
+}
+
+// FormatTime formats a timestamp in local time using the preferred clock,
+// e.g. "2024-06-01 15:04:05" or "2024-06-01 03:04:05 PM"
+func (d Display) FormatTime(t time.Time) string {
+	if strings.EqualFold(d.TimeFormat, "12h") {
+		return t.Local().Format("2006-01-02 03:04:05 PM")
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
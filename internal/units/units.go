@@ -0,0 +1,91 @@
+// Package units defines the base units MinerHQ stores and aggregates in,
+// plus helpers to convert device-reported values and format them for display.
+//
+// Hashrate is always stored in GH/s and power in Watts, regardless of device
+// class, so sums and efficiency math stay correct across a mixed fleet.
+package units
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hashrate unit multipliers relative to the base unit (GH/s)
+const (
+	HashPerSecond = 1e-9
+	KiloHash      = 1e-6
+	MegaHash      = 1e-3
+	GigaHash      = 1.0
+	TeraHash      = 1e3
+	PetaHash      = 1e6
+)
+
+// hashUnits maps unit names (lowercase, without "/s") to their multiplier
+var hashUnits = map[string]float64{
+	"h":  HashPerSecond,
+	"kh": KiloHash,
+	"mh": MegaHash,
+	"gh": GigaHash,
+	"th": TeraHash,
+	"ph": PetaHash,
+}
+
+// ToGHs converts a hashrate reported in the given unit (e.g. "MH/s", "TH",
+// "th/s") to GH/s.
+func ToGHs(value float64, unit string) (float64, error) {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	u = strings.TrimSuffix(u, "/s")
+	u = strings.TrimSuffix(u, "s")
+	mult, ok := hashUnits[u]
+	if !ok {
+		return 0, fmt.Errorf("unknown hashrate unit %q", unit)
+	}
+	return value * mult, nil
+}
+
+// FormatHashrate formats a GH/s value using the largest unit that keeps it >= 1,
+// e.g. 9000 -> "9.00 TH/s", 480 -> "480.00 GH/s".
+func FormatHashrate(ghs float64) string {
+	switch {
+	case ghs >= PetaHash:
+		return fmt.Sprintf("%.2f PH/s", ghs/PetaHash)
+	case ghs >= TeraHash:
+		return fmt.Sprintf("%.2f TH/s", ghs/TeraHash)
+	case ghs >= GigaHash || ghs == 0:
+		return fmt.Sprintf("%.2f GH/s", ghs)
+	case ghs >= MegaHash:
+		return fmt.Sprintf("%.2f MH/s", ghs/MegaHash)
+	case ghs >= KiloHash:
+		return fmt.Sprintf("%.2f KH/s", ghs/KiloHash)
+	default:
+		return fmt.Sprintf("%.2f H/s", ghs/HashPerSecond)
+	}
+}
+
+// FormatBytes formats a byte count as B/KB/MB/GB, e.g. 1536 -> "1.50 KB"
+func FormatBytes(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.2f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.2f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// EfficiencyJTH returns power efficiency in J/TH for a power draw in Watts
+// and a hashrate in GH/s. Returns 0 when hashrate is not positive.
+func EfficiencyJTH(watts, ghs float64) float64 {
+	if ghs <= 0 {
+		return 0
+	}
+	return watts / (ghs / TeraHash)
+}
+
+// KWh converts a constant power draw in Watts over the given hours to kWh
+func KWh(watts, hours float64) float64 {
+	return watts / 1000 * hours
+}
@@ -0,0 +1,121 @@
+package units
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestToGHs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   float64
+		unit    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "GH/s passthrough", value: 480, unit: "GH/s", want: 480},
+		{name: "TH/s", value: 9.2, unit: "TH/s", want: 9200},
+		{name: "MH/s (cgminer)", value: 13500000, unit: "MH/s", want: 13500},
+		{name: "lowercase without /s", value: 1, unit: "th", want: 1000},
+		{name: "PH/s", value: 0.5, unit: "PH/s", want: 500000},
+		{name: "H/s", value: 2e9, unit: "H/s", want: 2},
+		{name: "unknown unit", value: 1, unit: "EH/s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToGHs(tt.value, tt.unit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToGHs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ToGHs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatHashrate(t *testing.T) {
+	tests := []struct {
+		ghs  float64
+		want string
+	}{
+		{0, "0.00 GH/s"},
+		{480, "480.00 GH/s"},
+		{9000, "9.00 TH/s"},
+		{1250000, "1.25 PH/s"},
+		{0.5, "500.00 MH/s"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatHashrate(tt.ghs); got != tt.want {
+			t.Errorf("FormatHashrate(%v) = %q, want %q", tt.ghs, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1536, "1.50 KB"},
+		{5 << 20, "5.00 MB"},
+		{3 << 30, "3.00 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.size); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestEfficiencyJTH(t *testing.T) {
+	// 150 W at 9 TH/s = 16.67 J/TH
+	if got := EfficiencyJTH(150, 9000); math.Abs(got-16.6667) > 0.001 {
+		t.Errorf("EfficiencyJTH(150, 9000) = %v, want ~16.67", got)
+	}
+	if got := EfficiencyJTH(150, 0); got != 0 {
+		t.Errorf("EfficiencyJTH with zero hashrate = %v, want 0", got)
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	metric := Display{}
+	imperial := Display{TemperatureUnit: "F", TimeFormat: "12h", HashrateUnit: "TH/s"}
+
+	if got := metric.FormatTemperature(65); got != "65.0°C" {
+		t.Errorf("metric FormatTemperature(65) = %q", got)
+	}
+	if got := imperial.FormatTemperature(65); got != "149.0°F" {
+		t.Errorf("imperial FormatTemperature(65) = %q", got)
+	}
+
+	if got := metric.FormatHashrate(9000); got != "9.00 TH/s" {
+		t.Errorf("auto FormatHashrate(9000) = %q", got)
+	}
+	if got := imperial.FormatHashrate(480); got != "0.48 TH/s" {
+		t.Errorf("fixed FormatHashrate(480) = %q", got)
+	}
+	if v, unit := (Display{HashrateUnit: "mh"}).Hashrate(0.5); math.Abs(v-500) > 1e-9 || unit != "MH/s" {
+		t.Errorf("Hashrate(0.5) in MH/s = %v %s", v, unit)
+	}
+
+	ts := time.Date(2024, 6, 1, 15, 4, 5, 0, time.Local)
+	if got := metric.FormatTime(ts); got != "2024-06-01 15:04:05" {
+		t.Errorf("24h FormatTime = %q", got)
+	}
+	if got := imperial.FormatTime(ts); got != "2024-06-01 03:04:05 PM" {
+		t.Errorf("12h FormatTime = %q", got)
+	}
+
+	for unit, want := range map[string]bool{"": true, "auto": true, "TH/s": true, "gh": true, "EH/s": false} {
+		if got := ValidHashrateUnit(unit); got != want {
+			t.Errorf("ValidHashrateUnit(%q) = %v, want %v", unit, got, want)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+// Package watchdog restarts miners that still answer but have stopped
+// hashing or submitting shares, with a backoff and a daily restart budget.
+package watchdog
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/collector"
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+const (
+	// checkInterval is how often the watchdog looks for stuck miners
+	checkInterval = 30 * time.Second
+
+	// reachableWindow is how recent a miner's last snapshot must be for the
+	// miner to count as reachable. Offline miners are the alert engine's
+	// business; restarting them through their API can't work.
+	reachableWindow = 2 * time.Minute
+
+	// budgetWindow is the period max_restarts applies to
+	budgetWindow = 24 * time.Hour
+
+	// maxBackoffSteps caps the doubling of the backoff
+	maxBackoffSteps = 6
+)
+
+// Auditor records the watchdog's restarts in the audit log
+type Auditor interface {
+	InsertAuditEntry(e *storage.AuditEntry) error
+}
+
+// Status reports what the watchdog knows about a miner
+type Status struct {
+	IP          string     `json:"ip"`
+	Hostname    string     `json:"hostname"`
+	Watched     bool       `json:"watched"`         // In watchdog.miners, or that list is empty
+	Reachable   bool       `json:"reachable"`       // Polled in the last 2 minutes
+	Stuck       string     `json:"stuck,omitempty"` // Why the miner counts as stuck, empty if it doesn't
+	Restarts    int        `json:"restarts"`        // Restarts in the last 24 hours, failed ones included
+	Consecutive int        `json:"consecutive"`     // Restarts without the miner recovering in between
+	LastRestart *time.Time `json:"lastRestart,omitempty"`
+	NextAllowed *time.Time `json:"nextAllowed,omitempty"` // End of the backoff after the last restart
+	GaveUp      bool       `json:"gaveUp"`                // The restart budget is spent and the miner is still stuck
+	LastError   string     `json:"lastError,omitempty"`   // Error of the last restart, if it failed
+}
+
+// minerState is what the watchdog tracks of one miner. Stuck conditions are
+// only judged from since on, which moves to the time of every restart.
+type minerState struct {
+	hostname   string
+	since      time.Time // First snapshot, or the last restart
+	lastSnap   time.Time
+	zeroSince  time.Time // First of the current run of 0 GH/s snapshots, zero while hashing
+	frozenSecs int64     // How long the miner has repeated identical data, see collector.stale
+	lastShare  time.Time
+	hasShares  bool // The miner reports shares at all, so their absence means something
+
+	restarts    []time.Time // Restarts in the budget window
+	consecutive int
+	gaveUp      bool
+	lastError   string
+}
+
+// Watchdog watches the snapshots and shares of the local miners and restarts
+// those that stay reachable but stuck for stuck_minutes. The settings are read
+// on every check, so changes apply without a restart.
+type Watchdog struct {
+	settings func() *config.Config
+	restart  func(ip string) error // Restarts a miner through its API
+	audit    Auditor
+	onAction func(ip, hostname, message string, restarts int) // Alerts on a restart or on giving up
+
+	mu     sync.Mutex
+	miners map[string]*minerState
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a watchdog. restart reboots a miner, e.g.
+// collector.MinerControl.Restart; every restart is written to audit and
+// reported to onAction, which may be nil.
+func New(settings func() *config.Config, restart func(ip string) error, audit Auditor, onAction func(ip, hostname, message string, restarts int)) *Watchdog {
+	return &Watchdog{
+		settings: settings,
+		restart:  restart,
+		audit:    audit,
+		onAction: onAction,
+		miners:   make(map[string]*minerState),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start checks for stuck miners in the background
+func (w *Watchdog) Start() {
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.check(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks
+func (w *Watchdog) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+// Observe records a polled snapshot. Snapshots of remote sites' miners are
+// ignored: their own site restarts them.
+func (w *Watchdog) Observe(snap *storage.MinerSnapshot) {
+	if collector.IsRemote(snap.MinerIP) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	st := w.state(snap.MinerIP, snap.Timestamp)
+	st.hostname = snap.Hostname
+	st.lastSnap = snap.Timestamp
+	st.frozenSecs = snap.FrozenSecs
+	if snap.HashRate > 0 {
+		st.zeroSince = time.Time{}
+	} else if st.zeroSince.IsZero() {
+		st.zeroSince = snap.Timestamp
+	}
+}
+
+// ObserveShare records a share submitted by a miner
+func (w *Watchdog) ObserveShare(share *storage.Share) {
+	if collector.IsRemote(share.MinerIP) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	st := w.state(share.MinerIP, share.Timestamp)
+	st.hasShares = true
+	if share.Timestamp.After(st.lastShare) {
+		st.lastShare = share.Timestamp
+	}
+}
+
+// state returns a miner's state, tracking it from now on if it is new. The
+// caller must hold mu.
+func (w *Watchdog) state(ip string, now time.Time) *minerState {
+	st := w.miners[ip]
+	if st == nil {
+		st = &minerState{since: now}
+		w.miners[ip] = st
+	}
+	return st
+}
+
+// watched reports whether the settings have the watchdog restart ip
+func watched(cfg *config.WatchdogConfig, ip string) bool {
+	if len(cfg.Miners) == 0 {
+		return true
+	}
+	for _, m := range cfg.Miners {
+		if m == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// stuckReason returns why a miner counts as stuck at now, or "" if it doesn't:
+// 0 GH/s, frozen data or, for miners that report shares, no share for the
+// whole of stuck
+func (st *minerState) stuckReason(now time.Time, stuck time.Duration) string {
+	if !st.zeroSince.IsZero() && !st.zeroSince.Before(st.since) && now.Sub(st.zeroSince) >= stuck {
+		return fmt.Sprintf("%v at 0 GH/s", now.Sub(st.zeroSince).Round(time.Second))
+	}
+	if frozen := time.Duration(st.frozenSecs) * time.Second; frozen >= stuck {
+		return fmt.Sprintf("%v of frozen data", frozen)
+	}
+	if st.hasShares {
+		last := st.lastShare
+		if last.Before(st.since) {
+			last = st.since
+		}
+		if now.Sub(last) >= stuck {
+			return fmt.Sprintf("%v without shares", now.Sub(last).Round(time.Second))
+		}
+	}
+	return ""
+}
+
+// backoff returns how long to wait after the last restart before the next,
+// doubling with every restart in a row
+func (st *minerState) backoff(base time.Duration) time.Duration {
+	steps := st.consecutive - 1
+	if steps < 0 {
+		steps = 0
+	}
+	if steps > maxBackoffSteps {
+		steps = maxBackoffSteps
+	}
+	return base << steps
+}
+
+// lastRestart returns the time of the latest restart, zero if none in the
+// budget window
+func (st *minerState) lastRestart() time.Time {
+	if len(st.restarts) == 0 {
+		return time.Time{}
+	}
+	return st.restarts[len(st.restarts)-1]
+}
+
+// pending is a restart decided by check
+type pending struct {
+	ip, hostname, reason string
+	attempt              int // Restarts in the budget window, this one included
+}
+
+// check restarts the watched miners that are reachable and stuck, unless they
+// are in their backoff or have spent their restart budget
+func (w *Watchdog) check(now time.Time) {
+	cfg := w.settings().Watchdog
+	if !cfg.Enabled {
+		return
+	}
+	stuck := time.Duration(cfg.StuckMinutes) * time.Minute
+	base := time.Duration(cfg.BackoffMinutes) * time.Minute
+
+	var restarts []pending
+	var gaveUp []pending
+	w.mu.Lock()
+	for ip, st := range w.miners {
+		w.expire(st, now)
+		if len(st.restarts) == 0 && now.Sub(st.lastSnap) > budgetWindow {
+			delete(w.miners, ip) // Removed or long offline
+			continue
+		}
+		if !watched(&cfg, ip) || now.Sub(st.lastSnap) > reachableWindow {
+			continue
+		}
+
+		reason := st.stuckReason(now, stuck)
+		if reason == "" {
+			st.gaveUp = false
+			// Healthy for stuck_minutes since the last restart: the next hang
+			// starts the backoff over
+			if st.consecutive > 0 && st.zeroSince.IsZero() && now.Sub(st.lastRestart()) >= stuck {
+				st.consecutive = 0
+			}
+			continue
+		}
+		if last := st.lastRestart(); !last.IsZero() && now.Sub(last) < st.backoff(base) {
+			continue
+		}
+		if len(st.restarts) >= cfg.MaxRestarts {
+			if !st.gaveUp {
+				st.gaveUp = true
+				gaveUp = append(gaveUp, pending{ip: ip, hostname: st.hostname, reason: reason, attempt: len(st.restarts)})
+			}
+			continue
+		}
+
+		st.restarts = append(st.restarts, now)
+		st.consecutive++
+		st.since = now
+		st.zeroSince = time.Time{}
+		st.frozenSecs = 0
+		restarts = append(restarts, pending{ip: ip, hostname: st.hostname, reason: reason, attempt: len(st.restarts)})
+	}
+	w.mu.Unlock()
+
+	for _, p := range gaveUp {
+		msg := fmt.Sprintf("Still stuck (%s) after %d restarts in 24h, giving up until one expires", p.reason, p.attempt)
+		log.Printf("Watchdog: %s (%s): %s", p.hostname, p.ip, msg)
+		if w.onAction != nil {
+			w.onAction(p.ip, p.hostname, msg, p.attempt)
+		}
+	}
+
+	// Restarts call the miners' APIs, so they run without the lock
+	for _, p := range restarts {
+		w.restartMiner(p, cfg.MaxRestarts, now)
+	}
+}
+
+// expire drops restarts that left the budget window. The caller must hold mu.
+func (w *Watchdog) expire(st *minerState, now time.Time) {
+	n := 0
+	for _, t := range st.restarts {
+		if now.Sub(t) < budgetWindow {
+			st.restarts[n] = t
+			n++
+		}
+	}
+	st.restarts = st.restarts[:n]
+}
+
+// restartMiner restarts a stuck miner and records the outcome in the audit
+// log and as an alert
+func (w *Watchdog) restartMiner(p pending, budget int, now time.Time) {
+	err := w.restart(p.ip)
+
+	status := http.StatusOK
+	msg := fmt.Sprintf("Restarted after %s (restart %d of %d in 24h)", p.reason, p.attempt, budget)
+	if err != nil {
+		status = http.StatusBadGateway
+		msg = fmt.Sprintf("Restart after %s failed: %v (attempt %d of %d in 24h)", p.reason, err, p.attempt, budget)
+	}
+	log.Printf("Watchdog: %s (%s): %s", p.hostname, p.ip, msg)
+
+	w.mu.Lock()
+	if st := w.miners[p.ip]; st != nil {
+		st.lastError = ""
+		if err != nil {
+			st.lastError = err.Error()
+		}
+	}
+	w.mu.Unlock()
+
+	if w.audit != nil {
+		entry := &storage.AuditEntry{
+			Timestamp: now,
+			Username:  "watchdog",
+			Method:    http.MethodPost,
+			Path:      "/api/system/restart",
+			Action:    "watchdog restart",
+			Target:    p.ip,
+			Status:    status,
+		}
+		if err := w.audit.InsertAuditEntry(entry); err != nil {
+			log.Printf("Failed to write audit log: %v", err)
+		}
+	}
+	if w.onAction != nil {
+		w.onAction(p.ip, p.hostname, msg, p.attempt)
+	}
+}
+
+// Status reports every tracked miner at now, by IP
+func (w *Watchdog) Status(now time.Time) []Status {
+	cfg := w.settings().Watchdog
+	stuck := time.Duration(cfg.StuckMinutes) * time.Minute
+	base := time.Duration(cfg.BackoffMinutes) * time.Minute
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	statuses := make([]Status, 0, len(w.miners))
+	for ip, st := range w.miners {
+		w.expire(st, now)
+		s := Status{
+			IP:          ip,
+			Hostname:    st.hostname,
+			Watched:     watched(&cfg, ip),
+			Reachable:   now.Sub(st.lastSnap) <= reachableWindow,
+			Restarts:    len(st.restarts),
+			Consecutive: st.consecutive,
+			GaveUp:      st.gaveUp,
+			LastError:   st.lastError,
+		}
+		if stuck > 0 {
+			s.Stuck = st.stuckReason(now, stuck)
+		}
+		if last := st.lastRestart(); !last.IsZero() {
+			next := last.Add(st.backoff(base))
+			s.LastRestart = &last
+			s.NextAllowed = &next
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].IP < statuses[j].IP })
+	return statuses
+}
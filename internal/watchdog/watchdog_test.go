@@ -0,0 +1,175 @@
+package watchdog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/camarigor/miner-hq/internal/config"
+	"github.com/camarigor/miner-hq/internal/storage"
+)
+
+type fakeAudit struct {
+	entries []*storage.AuditEntry
+}
+
+func (f *fakeAudit) InsertAuditEntry(e *storage.AuditEntry) error {
+	f.entries = append(f.entries, e)
+	return nil
+}
+
+type action struct {
+	ip, msg string
+}
+
+func newTestWatchdog(cfg *config.Config) (*Watchdog, *[]string, *fakeAudit, *[]action) {
+	var restarted []string
+	var actions []action
+	audit := &fakeAudit{}
+	w := New(func() *config.Config { return cfg }, func(ip string) error {
+		restarted = append(restarted, ip)
+		return nil
+	}, audit, func(ip, hostname, msg string, restarts int) {
+		actions = append(actions, action{ip, msg})
+	})
+	return w, &restarted, audit, &actions
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Watchdog = config.WatchdogConfig{Enabled: true, StuckMinutes: 15, BackoffMinutes: 10, MaxRestarts: 2}
+	return cfg
+}
+
+// poll feeds snapshots of ip every minute from start up to end and runs a
+// check after each
+func poll(w *Watchdog, ip string, hashRate float64, start, end time.Time) {
+	for t := start; !t.After(end); t = t.Add(time.Minute) {
+		w.Observe(&storage.MinerSnapshot{MinerIP: ip, Hostname: "axe", Timestamp: t, HashRate: hashRate})
+		w.check(t)
+	}
+}
+
+func TestWatchdogRestartsStuckMiner(t *testing.T) {
+	w, restarted, audit, actions := newTestWatchdog(testConfig())
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	poll(w, "10.0.0.2", 500, start, start.Add(10*time.Minute))
+	poll(w, "10.0.0.2", 0, start.Add(11*time.Minute), start.Add(25*time.Minute))
+	if len(*restarted) != 0 {
+		t.Fatalf("expected no restart before 15 minutes at 0 GH/s, got %v", *restarted)
+	}
+	poll(w, "10.0.0.2", 0, start.Add(26*time.Minute), start.Add(26*time.Minute))
+	if len(*restarted) != 1 {
+		t.Fatalf("expected a restart after 15 minutes at 0 GH/s, got %v", *restarted)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Target != "10.0.0.2" || audit.entries[0].Username != "watchdog" || audit.entries[0].Status != 200 {
+		t.Errorf("expected the restart audited, got %+v", audit.entries)
+	}
+	if len(*actions) != 1 || !strings.Contains((*actions)[0].msg, "at 0 GH/s") {
+		t.Errorf("expected the restart alerted, got %+v", *actions)
+	}
+
+	// Still stuck: the next restart waits for stuck_minutes and the 10 minute
+	// backoff, then the one after for the doubled backoff
+	poll(w, "10.0.0.2", 0, start.Add(27*time.Minute), start.Add(41*time.Minute))
+	if len(*restarted) != 1 {
+		t.Fatalf("expected no restart within stuck_minutes of the last, got %v", *restarted)
+	}
+	poll(w, "10.0.0.2", 0, start.Add(42*time.Minute), start.Add(42*time.Minute))
+	if len(*restarted) != 2 {
+		t.Fatalf("expected a second restart, got %v", *restarted)
+	}
+	st := w.Status(start.Add(42 * time.Minute))[0]
+	if st.Consecutive != 2 || st.NextAllowed == nil || !st.NextAllowed.Equal(start.Add(62*time.Minute)) {
+		t.Errorf("expected the backoff doubled, got %+v", st)
+	}
+
+	// Budget of 2 spent: give up once, without restarting
+	poll(w, "10.0.0.2", 0, start.Add(43*time.Minute), start.Add(80*time.Minute))
+	if len(*restarted) != 2 {
+		t.Fatalf("expected no restart beyond the budget, got %v", *restarted)
+	}
+	if len(*actions) != 3 || !strings.Contains((*actions)[2].msg, "giving up") {
+		t.Fatalf("expected one giving-up alert, got %+v", *actions)
+	}
+	if st := w.Status(start.Add(80 * time.Minute))[0]; !st.GaveUp || st.Stuck == "" {
+		t.Errorf("expected the status to show the watchdog gave up, got %+v", st)
+	}
+
+	// The budget frees up a day after the first restart
+	poll(w, "10.0.0.2", 0, start.Add(24*time.Hour+26*time.Minute), start.Add(24*time.Hour+26*time.Minute))
+	if len(*restarted) != 3 {
+		t.Fatalf("expected a restart once the first left the budget window, got %v", *restarted)
+	}
+}
+
+func TestWatchdogNoShares(t *testing.T) {
+	w, restarted, _, _ := newTestWatchdog(testConfig())
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// A miner that never reports shares isn't judged by them
+	poll(w, "10.0.0.3", 500, start, start.Add(30*time.Minute))
+	if len(*restarted) != 0 {
+		t.Fatalf("expected no restart for a miner without share reports, got %v", *restarted)
+	}
+
+	w.ObserveShare(&storage.Share{MinerIP: "10.0.0.3", Timestamp: start.Add(30 * time.Minute)})
+	poll(w, "10.0.0.3", 500, start.Add(31*time.Minute), start.Add(44*time.Minute))
+	if len(*restarted) != 0 {
+		t.Fatalf("expected no restart within 15 minutes of a share, got %v", *restarted)
+	}
+	poll(w, "10.0.0.3", 500, start.Add(45*time.Minute), start.Add(45*time.Minute))
+	if len(*restarted) != 1 {
+		t.Fatalf("expected a restart after 15 minutes without shares, got %v", *restarted)
+	}
+}
+
+func TestWatchdogSkips(t *testing.T) {
+	cfg := testConfig()
+	cfg.Watchdog.Miners = []string{"10.0.0.2"}
+	w, restarted, _, _ := newTestWatchdog(cfg)
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Unwatched, remote, and unreachable miners are left alone
+	poll(w, "10.0.0.4", 0, start, start.Add(20*time.Minute))
+	poll(w, "10.0.0.9@barn", 0, start, start.Add(20*time.Minute))
+	w.Observe(&storage.MinerSnapshot{MinerIP: "10.0.0.2", Timestamp: start})
+	w.Observe(&storage.MinerSnapshot{MinerIP: "10.0.0.2", Timestamp: start.Add(time.Minute)})
+	w.check(start.Add(20 * time.Minute))
+	if len(*restarted) != 0 {
+		t.Fatalf("expected no restarts, got %v", *restarted)
+	}
+	if statuses := w.Status(start.Add(20 * time.Minute)); len(statuses) != 2 || statuses[1].Watched || statuses[0].Reachable {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+
+	// Disabled: nothing happens
+	cfg.Watchdog.Enabled = false
+	poll(w, "10.0.0.2", 0, start.Add(21*time.Minute), start.Add(40*time.Minute))
+	if len(*restarted) != 0 {
+		t.Fatalf("expected no restarts while disabled, got %v", *restarted)
+	}
+}
+
+func TestWatchdogFailedRestart(t *testing.T) {
+	audit := &fakeAudit{}
+	var msgs []string
+	w := New(func() *config.Config { return testConfig() }, func(ip string) error {
+		return errors.New("failed to reach miner")
+	}, audit, func(ip, hostname, msg string, restarts int) { msgs = append(msgs, msg) })
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	w.Observe(&storage.MinerSnapshot{MinerIP: "10.0.0.2", Timestamp: start, HashRate: 500, FrozenSecs: 16 * 60})
+	w.check(start)
+	if len(audit.entries) != 1 || audit.entries[0].Status != 502 {
+		t.Fatalf("expected the failed restart audited, got %+v", audit.entries)
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "frozen") || !strings.Contains(msgs[0], "failed") {
+		t.Errorf("expected the failure alerted, got %v", msgs)
+	}
+	if st := w.Status(start)[0]; st.LastError == "" || st.Restarts != 1 {
+		t.Errorf("expected the failed attempt counted, got %+v", st)
+	}
+}
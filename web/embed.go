@@ -0,0 +1,11 @@
+// Package web holds the web UI: HTML templates, styles and the frontend
+// application. They are embedded in the binary, so it serves the UI wherever
+// it runs.
+package web
+
+import "embed"
+
+// Files holds the templates/ and static/ directories
+//
+//go:embed templates static
+var Files embed.FS
@@ -2,6 +2,17 @@
  * MinerHQ Dashboard Application
  * Frontend UI for managing and monitoring ASIC miners
  */
+
+// An expired or missing login sends the browser back to the login page
+const nativeFetch = window.fetch.bind(window);
+window.fetch = async (...args) => {
+    const response = await nativeFetch(...args);
+    if (response.status === 401) {
+        window.location = '/login?next=' + encodeURIComponent(window.location.pathname);
+    }
+    return response;
+};
+
 class MinerHQ {
     constructor() {
         this.miners = [];
@@ -14,9 +25,11 @@ class MinerHQ {
         this.hashrateChart = null;
         this.sharesScatterChart = null;
         this.sharesHistogramChart = null;
+        this.luckChart = null;
         this.minerDetailChart = null;
         this.currentPage = 'dashboard';
         this.settings = {};
+        this.canEdit = true; // Admin on a writable instance, see fetchMe
         this.currentMiner = null;
         this.modalRefreshInterval = null;
         this.sharesChartInterval = null;
@@ -29,13 +42,12 @@ class MinerHQ {
 
     async init() {
         this.bindEvents();
+        this.fetchMe();
         await this.fetchSettings(); // Load settings first for energy cost calculation
-        await this.fetchStats();
-        await this.fetchMiners();
+        await this.fetchSummary(); // Stats, miners and earnings in one request
         await this.fetchCompetition();
         await this.fetchMoneyMakers();
         await this.loadCoins();
-        await this.loadEarnings();
         this.connectWebSocket();
         this.initHashrateChart();
 
@@ -48,6 +60,31 @@ class MinerHQ {
         setInterval(() => this.loadEarnings(), 60000); // Update earnings every minute
     }
 
+    async fetchMe() {
+        try {
+            const response = await fetch('/api/me');
+            const me = await response.json();
+
+            // Viewers and read-only instances get the dashboards without the controls
+            this.canEdit = me.role === 'admin' && !me.readOnly;
+            document.body.classList.toggle('viewer', !this.canEdit);
+            if (!this.canEdit && this.currentPage === 'settings') this.switchPage('dashboard');
+
+            const logoutBtn = document.getElementById('logout-btn');
+            if (logoutBtn && me.authEnabled) {
+                logoutBtn.title = 'Logged in as ' + me.username;
+                logoutBtn.hidden = false;
+            }
+        } catch (error) {
+            console.error('Failed to fetch current user:', error);
+        }
+    }
+
+    async logout() {
+        await fetch('/api/logout', { method: 'POST' });
+        window.location = '/login';
+    }
+
     bindEvents() {
         document.querySelectorAll('.nav-tab').forEach(tab => {
             tab.addEventListener('click', () => this.switchPage(tab.dataset.page));
@@ -59,6 +96,9 @@ class MinerHQ {
         const modal = document.getElementById('scan-modal');
 
         if (scanBtn) scanBtn.addEventListener('click', () => this.openScanModal());
+
+        const logoutBtn = document.getElementById('logout-btn');
+        if (logoutBtn) logoutBtn.addEventListener('click', () => this.logout());
         if (modalClose) modalClose.addEventListener('click', () => this.closeScanModal());
         if (startScanBtn) startScanBtn.addEventListener('click', () => this.scan());
         if (modal) modal.addEventListener('click', (e) => {
@@ -112,6 +152,27 @@ class MinerHQ {
         else if (page === 'settings') this.loadSettingsPage();
     }
 
+    async fetchSummary() {
+        try {
+            const response = await fetch('/api/summary');
+            if (!response.ok) throw new Error('Failed to fetch summary');
+            const summary = await response.json();
+
+            this.updateSummaryCards(summary.stats);
+            this.updateBlockCount(summary.blockCount);
+            this.miners = summary.miners || [];
+            this.renderMiners();
+            this.updateMinerFilter();
+            if (summary.earnings) this.renderEarnings(summary.earnings);
+        } catch (error) {
+            console.error('Error fetching summary:', error);
+            // Fall back to the individual endpoints
+            await this.fetchStats();
+            await this.fetchMiners();
+            await this.loadEarnings();
+        }
+    }
+
     async fetchStats() {
         try {
             const [statsRes, blocksRes] = await Promise.all([
@@ -695,6 +756,19 @@ class MinerHQ {
 
         card.appendChild(header);
         card.appendChild(ipDiv);
+
+        if (miner.tags && miner.tags.length > 0) {
+            const tags = document.createElement('div');
+            tags.className = 'miner-tags';
+            miner.tags.forEach(tag => {
+                const chip = document.createElement('span');
+                chip.className = 'miner-tag';
+                chip.textContent = tag;
+                tags.appendChild(chip);
+            });
+            card.appendChild(tags);
+        }
+
         card.appendChild(stats);
 
         return card;
@@ -875,6 +949,7 @@ class MinerHQ {
             });
         }
         coinSelect.value = miner.coinId || '';
+        coinSelect.disabled = !this.canEdit;
 
         coinSelect.addEventListener('change', async () => {
             try {
@@ -1783,6 +1858,7 @@ class MinerHQ {
         // This ensures they render correctly after being hidden
         this.initSharesCharts();
         this.renderSharesHistoryFeed();
+        this.loadLuck();
 
         // Start periodic refresh for sliding window effect
         this.startSharesChartRefresh();
@@ -1906,6 +1982,7 @@ class MinerHQ {
             minerFilter.onchange = () => {
                 this.updateSharesCharts();
                 this.renderSharesHistoryFeed();
+                this.loadLuck();
             };
         }
 
@@ -2118,6 +2195,60 @@ class MinerHQ {
         });
     }
 
+    async loadLuck() {
+        const ctx = document.getElementById('luck-chart');
+        if (!ctx) return;
+
+        // The selected miner's daily luck, or the fleet's
+        const filter = document.getElementById('shares-miner-filter');
+        const selectedMiner = filter ? filter.value : '';
+        const url = selectedMiner ? `/api/miners/${selectedMiner}/luck` : '/api/luck';
+
+        try {
+            const response = await fetch(url);
+            if (!response.ok) return;
+
+            const data = await response.json();
+            const days = data.daily || [];
+
+            if (this.luckChart) this.luckChart.destroy();
+            this.luckChart = new Chart(ctx, {
+                type: 'bar',
+                data: {
+                    labels: days.map(d => d.day.slice(5)),
+                    datasets: [{
+                        label: 'Luck %',
+                        data: days.map(d => d.expectedShares > 0 ? d.luck : null),
+                        backgroundColor: days.map(d => d.luck >= 100 ? 'rgba(0, 255, 136, 0.7)' : 'rgba(255, 68, 68, 0.7)'),
+                        borderColor: '#00d4ff',
+                        borderWidth: 1
+                    }]
+                },
+                options: {
+                    responsive: true,
+                    maintainAspectRatio: false,
+                    plugins: {
+                        legend: { display: false },
+                        tooltip: {
+                            callbacks: {
+                                afterLabel: (item) => {
+                                    const d = days[item.dataIndex];
+                                    return `${d.shares} shares, ${d.expectedShares.toFixed(1)} expected`;
+                                }
+                            }
+                        }
+                    },
+                    scales: {
+                        x: { grid: { color: 'rgba(255,255,255,0.1)' }, ticks: { color: '#8892a0' } },
+                        y: { grid: { color: 'rgba(255,255,255,0.1)' }, ticks: { color: '#8892a0' }, beginAtZero: true }
+                    }
+                }
+            });
+        } catch (error) {
+            console.error('Error loading luck:', error);
+        }
+    }
+
     updateMinerFilter() {
         const filter = document.getElementById('shares-miner-filter');
         if (!filter) return;
@@ -2167,9 +2298,12 @@ class MinerHQ {
             this.setInputValue('alert-wifi', s.alerts.wifi_signal_below || -70);
             this.setCheckboxValue('alert-rejected', s.alerts.on_share_rejected);
             this.setCheckboxValue('alert-pool-disconnect', s.alerts.on_pool_disconnected);
+            this.setCheckboxValue('alert-pool-unreachable', s.alerts.on_pool_unreachable);
+            this.setCheckboxValue('alert-pool-mismatch', s.alerts.on_pool_mismatch);
             this.setCheckboxValue('alert-best-diff', s.alerts.on_new_best_diff);
             this.setCheckboxValue('alert-block-found', s.alerts.on_block_found);
             this.setCheckboxValue('alert-new-leader', s.alerts.on_new_leader);
+            this.setCheckboxValue('alert-digest', s.alerts.on_digest);
         }
         if (s.energy) {
             this.setInputValue('energy-cost', s.energy.cost_per_kwh || 0.12);
@@ -2285,68 +2419,71 @@ class MinerHQ {
             const response = await fetch('/api/earnings');
             if (!response.ok) return;
 
-            const data = await response.json();
-            const coins = data.coins || [];
+            this.renderEarnings(await response.json());
+        } catch (error) {
+            console.error('Error loading earnings:', error);
+        }
+    }
 
-            // Store for carousel
-            this.earningsCoins = coins;
-            this.earningsTotalCurrentUsd = data.totalCurrentUsd || 0;
+    renderEarnings(data) {
+        const coins = data.coins || [];
 
-            // If no blocks mined yet, show empty state
-            if (coins.length === 0) {
-                const totalCoinsEl = document.getElementById('earnings-total-coins');
-                if (totalCoinsEl) totalCoinsEl.textContent = '0';
+        // Store for carousel
+        this.earningsCoins = coins;
+        this.earningsTotalCurrentUsd = data.totalCurrentUsd || 0;
 
-                const rewardEl = document.getElementById('earnings-block-reward');
-                if (rewardEl) rewardEl.textContent = '0';
+        // If no blocks mined yet, show empty state
+        if (coins.length === 0) {
+            const totalCoinsEl = document.getElementById('earnings-total-coins');
+            if (totalCoinsEl) totalCoinsEl.textContent = '0';
 
-                const blocksEl = document.getElementById('earnings-blocks-found');
-                if (blocksEl) blocksEl.textContent = '0';
+            const rewardEl = document.getElementById('earnings-block-reward');
+            if (rewardEl) rewardEl.textContent = '0';
 
-                const totalUsdEl = document.getElementById('earnings-total-usd');
-                if (totalUsdEl) totalUsdEl.textContent = '≈ $0.00';
+            const blocksEl = document.getElementById('earnings-blocks-found');
+            if (blocksEl) blocksEl.textContent = '0';
 
-                const iconEl = document.getElementById('earnings-coin-icon');
-                if (iconEl) iconEl.style.display = 'none';
+            const totalUsdEl = document.getElementById('earnings-total-usd');
+            if (totalUsdEl) totalUsdEl.textContent = '≈ $0.00';
 
-                const dotsEl = document.getElementById('earnings-dots');
-                if (dotsEl) dotsEl.textContent = '';
+            const iconEl = document.getElementById('earnings-coin-icon');
+            if (iconEl) iconEl.style.display = 'none';
 
-                // Stop carousel if running
-                if (this.earningsCycleInterval) {
-                    clearInterval(this.earningsCycleInterval);
-                    this.earningsCycleInterval = null;
-                }
-                return;
-            }
+            const dotsEl = document.getElementById('earnings-dots');
+            if (dotsEl) dotsEl.textContent = '';
 
-            // Clamp index if coins changed
-            if (this.earningsCoinIndex >= coins.length) {
-                this.earningsCoinIndex = 0;
+            // Stop carousel if running
+            if (this.earningsCycleInterval) {
+                clearInterval(this.earningsCycleInterval);
+                this.earningsCycleInterval = null;
             }
+            return;
+        }
+
+        // Clamp index if coins changed
+        if (this.earningsCoinIndex >= coins.length) {
+            this.earningsCoinIndex = 0;
+        }
 
-            // Render current coin (no animation on data refresh)
-            this.renderEarningsCoin(this.earningsCoinIndex, false);
+        // Render current coin (no animation on data refresh)
+        this.renderEarningsCoin(this.earningsCoinIndex, false);
 
-            // Render dots
-            this.renderEarningsDots();
+        // Render dots
+        this.renderEarningsDots();
 
-            // Start carousel if multiple coins and not already running
-            if (coins.length > 1 && !this.earningsCycleInterval) {
-                this.earningsCycleInterval = setInterval(() => {
-                    this.earningsCoinIndex = (this.earningsCoinIndex + 1) % this.earningsCoins.length;
-                    this.renderEarningsCoin(this.earningsCoinIndex, true);
-                    this.renderEarningsDots();
-                }, 5000);
-            }
+        // Start carousel if multiple coins and not already running
+        if (coins.length > 1 && !this.earningsCycleInterval) {
+            this.earningsCycleInterval = setInterval(() => {
+                this.earningsCoinIndex = (this.earningsCoinIndex + 1) % this.earningsCoins.length;
+                this.renderEarningsCoin(this.earningsCoinIndex, true);
+                this.renderEarningsDots();
+            }, 5000);
+        }
 
-            // Stop carousel if only 1 coin left
-            if (coins.length <= 1 && this.earningsCycleInterval) {
-                clearInterval(this.earningsCycleInterval);
-                this.earningsCycleInterval = null;
-            }
-        } catch (error) {
-            console.error('Error loading earnings:', error);
+        // Stop carousel if only 1 coin left
+        if (coins.length <= 1 && this.earningsCycleInterval) {
+            clearInterval(this.earningsCycleInterval);
+            this.earningsCycleInterval = null;
         }
     }
 
@@ -2449,9 +2586,12 @@ class MinerHQ {
                 wifi_signal_below: parseInt(document.getElementById('alert-wifi')?.value) || -70,
                 on_share_rejected: document.getElementById('alert-rejected')?.checked || false,
                 on_pool_disconnected: document.getElementById('alert-pool-disconnect')?.checked || false,
+                on_pool_unreachable: document.getElementById('alert-pool-unreachable')?.checked || false,
+                on_pool_mismatch: document.getElementById('alert-pool-mismatch')?.checked || false,
                 on_new_best_diff: document.getElementById('alert-best-diff')?.checked || false,
                 on_block_found: document.getElementById('alert-block-found')?.checked || false,
-                on_new_leader: document.getElementById('alert-new-leader')?.checked || false
+                on_new_leader: document.getElementById('alert-new-leader')?.checked || false,
+                on_digest: document.getElementById('alert-digest')?.checked || false
             },
             energy: {
                 cost_per_kwh: parseFloat(document.getElementById('energy-cost')?.value) || 0.12,